@@ -38,6 +38,7 @@ func main() {
 		Long:  `Jaeger agent is a daemon program that runs on every host and receives tracing data submitted by Jaeger client libraries.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			flags.TryLoadConfigFile(v, logger)
+			flags.WarnDeprecatedEnvVars(v, logger)
 
 			builder := &app.Builder{}
 			builder.InitFromViper(v)
@@ -15,11 +15,16 @@
 package builder
 
 import (
+	"time"
+
+	"github.com/opentracing/opentracing-go"
 	"github.com/uber/jaeger-lib/metrics"
 	"go.uber.org/zap"
 
 	cascfg "github.com/uber/jaeger/pkg/cassandra/config"
+	"github.com/uber/jaeger/pkg/clock"
 	escfg "github.com/uber/jaeger/pkg/es/config"
+	kafkacfg "github.com/uber/jaeger/pkg/kafka/config"
 	"github.com/uber/jaeger/storage/spanstore/memory"
 )
 
@@ -29,12 +34,26 @@ type BasicOptions struct {
 	Logger *zap.Logger
 	// MetricsFactory is the basic metrics factory used by most executables
 	MetricsFactory metrics.Factory
+	// Clock tells the time to the components that depend on it, it is the system clock unless tests replace it
+	Clock clock.Clock
+	// Tracer traces the work of the executable itself, it is nil unless self-tracing is enabled
+	Tracer opentracing.Tracer
 	// MemoryStore is the memory store (as reader and writer) that will be used if required
 	MemoryStore *memory.Store
 	// CassandraSessionBuilder is the cassandra session builder
 	CassandraSessionBuilder cascfg.SessionBuilder
+	// CassandraTenantTag is the span tag used to route spans to CassandraTenantSessionBuilders
+	CassandraTenantTag string
+	// CassandraTenantSessionBuilders are the cassandra session builders for each tenant
+	CassandraTenantSessionBuilders map[string]cascfg.SessionBuilder
+	// CassandraSpanTTL is the TTL of the spans written to Cassandra of services not in CassandraServiceTTLFile
+	CassandraSpanTTL time.Duration
+	// CassandraServiceTTLFile is the path of a file with the TTL of the spans written to Cassandra of each service
+	CassandraServiceTTLFile string
 	// ElasticClientBuilder is the elasticsearch client builder
 	ElasticClientBuilder escfg.ClientBuilder
+	// KafkaProducerBuilder is the kafka producer builder
+	KafkaProducerBuilder kafkacfg.ProducerBuilder
 }
 
 // Option is a function that sets some option on StorageBuilder.
@@ -57,6 +76,20 @@ func (BasicOptions) MetricsFactoryOption(metricsFactory metrics.Factory) Option
 	}
 }
 
+// ClockOption creates an Option that initializes the Clock
+func (BasicOptions) ClockOption(clock clock.Clock) Option {
+	return func(b *BasicOptions) {
+		b.Clock = clock
+	}
+}
+
+// TracerOption creates an Option that initializes the Tracer
+func (BasicOptions) TracerOption(tracer opentracing.Tracer) Option {
+	return func(b *BasicOptions) {
+		b.Tracer = tracer
+	}
+}
+
 // CassandraSessionOption creates an Option that adds Cassandra session builder.
 func (BasicOptions) CassandraSessionOption(sessionBuilder cascfg.SessionBuilder) Option {
 	return func(b *BasicOptions) {
@@ -64,6 +97,24 @@ func (BasicOptions) CassandraSessionOption(sessionBuilder cascfg.SessionBuilder)
 	}
 }
 
+// CassandraTenantsOption creates an Option that adds a Cassandra session builder for each tenant.
+// Spans are routed to a tenant by the value of tag.
+func (BasicOptions) CassandraTenantsOption(tag string, sessionBuilders map[string]cascfg.SessionBuilder) Option {
+	return func(b *BasicOptions) {
+		b.CassandraTenantTag = tag
+		b.CassandraTenantSessionBuilders = sessionBuilders
+	}
+}
+
+// CassandraSpanTTLOption creates an Option that sets the TTL of the spans written to Cassandra, per
+// service from serviceTTLFile if it is set, and defaultTTL for the other services.
+func (BasicOptions) CassandraSpanTTLOption(defaultTTL time.Duration, serviceTTLFile string) Option {
+	return func(b *BasicOptions) {
+		b.CassandraSpanTTL = defaultTTL
+		b.CassandraServiceTTLFile = serviceTTLFile
+	}
+}
+
 // ElasticClientOption creates an Option that adds ElasticSearch client builder.
 func (BasicOptions) ElasticClientOption(clientBuilder escfg.ClientBuilder) Option {
 	return func(b *BasicOptions) {
@@ -71,6 +122,13 @@ func (BasicOptions) ElasticClientOption(clientBuilder escfg.ClientBuilder) Optio
 	}
 }
 
+// KafkaProducerOption creates an Option that adds Kafka producer builder.
+func (BasicOptions) KafkaProducerOption(producerBuilder kafkacfg.ProducerBuilder) Option {
+	return func(b *BasicOptions) {
+		b.KafkaProducerBuilder = producerBuilder
+	}
+}
+
 // MemoryStoreOption creates an Option that adds a memory store
 func (BasicOptions) MemoryStoreOption(memoryStore *memory.Store) Option {
 	return func(b *BasicOptions) {
@@ -90,5 +148,8 @@ func ApplyOptions(opts ...Option) BasicOptions {
 	if o.MetricsFactory == nil {
 		o.MetricsFactory = metrics.NullFactory
 	}
+	if o.Clock == nil {
+		o.Clock = clock.System
+	}
 	return o
 }
@@ -16,13 +16,17 @@ package builder
 
 import (
 	"testing"
+	"time"
 
+	"github.com/opentracing/opentracing-go"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
 
 	"github.com/uber/jaeger-lib/metrics"
 	cascfg "github.com/uber/jaeger/pkg/cassandra/config"
+	"github.com/uber/jaeger/pkg/clock"
 	escfg "github.com/uber/jaeger/pkg/es/config"
+	kafkacfg "github.com/uber/jaeger/pkg/kafka/config"
 	"github.com/uber/jaeger/storage/spanstore/memory"
 )
 
@@ -31,19 +35,39 @@ func TestApplyOptions(t *testing.T) {
 		Options.CassandraSessionOption(&cascfg.Configuration{}),
 		Options.LoggerOption(zap.NewNop()),
 		Options.MetricsFactoryOption(metrics.NullFactory),
+		Options.TracerOption(opentracing.NoopTracer{}),
 		Options.MemoryStoreOption(memory.NewStore()),
 		Options.ElasticClientOption(&escfg.Configuration{
 			Servers: []string{"127.0.0.1"},
 		}),
+		Options.KafkaProducerOption(&kafkacfg.Configuration{}),
+		Options.CassandraTenantsOption("tenant", map[string]cascfg.SessionBuilder{"acme": &cascfg.Configuration{}}),
+		Options.CassandraSpanTTLOption(time.Hour, "service_ttls.json"),
+		Options.ClockOption(testClock{}),
 	)
 	assert.NotNil(t, opts.CassandraSessionBuilder)
 	assert.NotNil(t, opts.ElasticClientBuilder)
+	assert.NotNil(t, opts.KafkaProducerBuilder)
+	assert.Equal(t, "tenant", opts.CassandraTenantTag)
+	assert.Len(t, opts.CassandraTenantSessionBuilders, 1)
+	assert.Equal(t, time.Hour, opts.CassandraSpanTTL)
+	assert.Equal(t, "service_ttls.json", opts.CassandraServiceTTLFile)
 	assert.NotNil(t, opts.Logger)
 	assert.NotNil(t, opts.MetricsFactory)
+	assert.NotNil(t, opts.Tracer)
+	assert.Equal(t, testClock{}, opts.Clock)
+}
+
+type testClock struct{}
+
+func (testClock) Now() time.Time {
+	return time.Unix(1000, 0)
 }
 
 func TestApplyNoOptions(t *testing.T) {
 	opts := ApplyOptions()
 	assert.NotNil(t, opts.Logger)
 	assert.NotNil(t, opts.MetricsFactory)
+	assert.Nil(t, opts.Tracer)
+	assert.Equal(t, clock.System, opts.Clock)
 }
@@ -0,0 +1,176 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/uber/jaeger/model"
+)
+
+const (
+	// DefaultAllowedServicesReloadInterval is the default interval at which the allowed services file is
+	// checked for changes
+	DefaultAllowedServicesReloadInterval = 10 * time.Second
+
+	rejectReasonUnknownService = "unknown-service"
+)
+
+// ServiceAllowlist rejects the spans of the services that are not listed in the allowed services file,
+// which has one service name per line; blank lines and lines starting with # are ignored. All services
+// are allowed while the file is empty or absent. The file is reloaded when it changes, and the rejected
+// spans are counted in the spans.rejected counter tagged with reason=unknown-service.
+type ServiceAllowlist struct {
+	path           string
+	reloadInterval time.Duration
+	logger         *zap.Logger
+	rejected       metrics.Counter
+
+	lock     sync.RWMutex
+	modTime  time.Time
+	services map[string]struct{}
+
+	stop chan struct{}
+	done sync.WaitGroup
+}
+
+// NewServiceAllowlist creates a ServiceAllowlist from the file at path and checks the file for changes
+// every reloadInterval. It returns an error if the file exists but cannot be read.
+func NewServiceAllowlist(path string, reloadInterval time.Duration, metricsFactory metrics.Factory, logger *zap.Logger) (*ServiceAllowlist, error) {
+	l := &ServiceAllowlist{
+		path:           path,
+		reloadInterval: reloadInterval,
+		logger:         logger,
+		rejected:       metricsFactory.Counter("spans.rejected", map[string]string{"reason": rejectReasonUnknownService}),
+		stop:           make(chan struct{}),
+	}
+	info, err := os.Stat(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		if err := l.load(info.ModTime()); err != nil {
+			return nil, err
+		}
+	}
+	l.done.Add(1)
+	go l.watch()
+	return l, nil
+}
+
+// Filter returns false if the service of the span is not allowed, counting the rejection.
+// It can be used as a FilterSpan.
+func (l *ServiceAllowlist) Filter(span *model.Span) bool {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+	if len(l.services) == 0 {
+		return true
+	}
+	if span.Process != nil {
+		if _, ok := l.services[span.Process.ServiceName]; ok {
+			return true
+		}
+	}
+	l.rejected.Inc(1)
+	return false
+}
+
+// Close stops watching the allowed services file.
+func (l *ServiceAllowlist) Close() error {
+	close(l.stop)
+	l.done.Wait()
+	return nil
+}
+
+func (l *ServiceAllowlist) watch() {
+	defer l.done.Done()
+	ticker := time.NewTicker(l.reloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			l.reloadIfChanged()
+		case <-l.stop:
+			return
+		}
+	}
+}
+
+func (l *ServiceAllowlist) reloadIfChanged() {
+	info, err := os.Stat(l.path)
+	if os.IsNotExist(err) {
+		l.lock.Lock()
+		removed := !l.modTime.IsZero()
+		l.modTime = time.Time{}
+		l.services = nil
+		l.lock.Unlock()
+		if removed {
+			l.logger.Warn("Allowed services file was removed, all services are allowed", zap.String("file", l.path))
+		}
+		return
+	}
+	if err != nil {
+		l.logger.Error("Failed to check allowed services file", zap.String("file", l.path), zap.Error(err))
+		return
+	}
+	l.lock.RLock()
+	modTime := l.modTime
+	l.lock.RUnlock()
+	if info.ModTime().Equal(modTime) {
+		return
+	}
+	if err := l.load(info.ModTime()); err != nil {
+		l.logger.Error("Failed to reload allowed services, keeping the previous ones",
+			zap.String("file", l.path), zap.Error(err))
+		return
+	}
+	l.logger.Info("Reloaded allowed services", zap.String("file", l.path))
+}
+
+func (l *ServiceAllowlist) load(modTime time.Time) error {
+	data, err := ioutil.ReadFile(l.path)
+	if err != nil {
+		return err
+	}
+	services := parseAllowedServices(data)
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.modTime = modTime
+	l.services = services
+	return nil
+}
+
+func parseAllowedServices(data []byte) map[string]struct{} {
+	services := make(map[string]struct{})
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		services[line] = struct{}{}
+	}
+	return services
+}
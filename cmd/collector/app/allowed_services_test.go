@@ -0,0 +1,123 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/uber/jaeger/model"
+)
+
+func writeAllowedServices(t *testing.T, content string) (string, func()) {
+	dir, err := ioutil.TempDir("", "allowed-services")
+	require.NoError(t, err)
+	path := filepath.Join(dir, "allowed-services.txt")
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+	return path, func() { os.RemoveAll(dir) }
+}
+
+// updateAllowedServices writes content and sets an explicit modification time, since writes within
+// the file system's timestamp resolution would otherwise be indistinguishable.
+func updateAllowedServices(t *testing.T, path, content string, modTime time.Time) {
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+func serviceSpan(serviceName string) *model.Span {
+	return &model.Span{Process: &model.Process{ServiceName: serviceName}}
+}
+
+func TestServiceAllowlist(t *testing.T) {
+	path, cleanup := writeAllowedServices(t, "# registered services\nfrontend\n\n  backend  \n")
+	defer cleanup()
+	mb := metrics.NewLocalFactory(0)
+	allowlist, err := NewServiceAllowlist(path, time.Hour, mb, zap.NewNop())
+	require.NoError(t, err)
+	defer allowlist.Close()
+
+	assert.True(t, allowlist.Filter(serviceSpan("frontend")))
+	assert.True(t, allowlist.Filter(serviceSpan("backend")))
+	assert.False(t, allowlist.Filter(serviceSpan("redis")))
+	assert.False(t, allowlist.Filter(serviceSpan("# registered services")))
+	assert.False(t, allowlist.Filter(&model.Span{}))
+
+	counters, _ := mb.Snapshot()
+	assert.EqualValues(t, 3, counters["spans.rejected|reason=unknown-service"])
+}
+
+func TestServiceAllowlistAllowsAll(t *testing.T) {
+	path, cleanup := writeAllowedServices(t, "# no services yet\n")
+	defer cleanup()
+	for _, path := range []string{path, filepath.Join(filepath.Dir(path), "missing.txt")} {
+		mb := metrics.NewLocalFactory(0)
+		allowlist, err := NewServiceAllowlist(path, time.Hour, mb, zap.NewNop())
+		require.NoError(t, err)
+		assert.True(t, allowlist.Filter(serviceSpan("redis")), path)
+		assert.True(t, allowlist.Filter(&model.Span{}), path)
+		require.NoError(t, allowlist.Close())
+
+		counters, _ := mb.Snapshot()
+		assert.EqualValues(t, 0, counters["spans.rejected|reason=unknown-service"])
+	}
+}
+
+func TestNewServiceAllowlistError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "allowed-services")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	_, err = NewServiceAllowlist(dir, time.Hour, metrics.NullFactory, zap.NewNop())
+	assert.Error(t, err, "a directory cannot be read")
+}
+
+func TestServiceAllowlistReload(t *testing.T) {
+	path, cleanup := writeAllowedServices(t, "frontend\n")
+	defer cleanup()
+	allowlist, err := NewServiceAllowlist(path, time.Millisecond, metrics.NullFactory, zap.NewNop())
+	require.NoError(t, err)
+	defer allowlist.Close()
+	assert.False(t, allowlist.Filter(serviceSpan("backend")))
+
+	updateAllowedServices(t, path, "frontend\nbackend\n", time.Now().Add(time.Minute))
+	waitForFilter(t, allowlist, serviceSpan("backend"), true)
+	assert.True(t, allowlist.Filter(serviceSpan("frontend")))
+
+	updateAllowedServices(t, path, "backend\n", time.Now().Add(2*time.Minute))
+	waitForFilter(t, allowlist, serviceSpan("frontend"), false)
+
+	require.NoError(t, os.Remove(path))
+	waitForFilter(t, allowlist, serviceSpan("frontend"), true)
+
+	updateAllowedServices(t, path, "backend\n", time.Now().Add(3*time.Minute))
+	waitForFilter(t, allowlist, serviceSpan("frontend"), false)
+}
+
+func waitForFilter(t *testing.T, allowlist *ServiceAllowlist, span *model.Span, expected bool) {
+	for i := 0; i < 1000; i++ {
+		if allowlist.Filter(span) == expected {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, expected, allowlist.Filter(span), "the allowed services file was not reloaded")
+}
@@ -0,0 +1,138 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	zipkinS "github.com/uber/jaeger/cmd/collector/app/sanitizer/zipkin"
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/storage/spanstore"
+)
+
+// Benchmark measures how fast the collector decodes and validates spans, for load tests that leave the
+// storage out. The time spent converting each batch into model spans is reported in the benchmark.decode-time
+// timer, the time spent validating and filtering each span in benchmark.validate-time, and the spans that
+// would have been written are discarded and counted in benchmark.spans-discarded.
+type Benchmark struct {
+	start        time.Time
+	decodeTime   metrics.Timer
+	validateTime metrics.Timer
+	discarded    metrics.Counter
+
+	// the totals of the summary, updated atomically
+	decodedSpans   int64
+	decodeNanos    int64
+	validatedSpans int64
+	validateNanos  int64
+	discardedSpans int64
+}
+
+// NewBenchmark creates a Benchmark whose summary counts the time from now.
+func NewBenchmark(metricsFactory metrics.Factory) *Benchmark {
+	return &Benchmark{
+		start:        time.Now(),
+		decodeTime:   metricsFactory.Timer("benchmark.decode-time", nil),
+		validateTime: metricsFactory.Timer("benchmark.validate-time", nil),
+		discarded:    metricsFactory.Counter("benchmark.spans-discarded", nil),
+	}
+}
+
+// JaegerSpanHandler returns a JaegerBatchesHandler like NewJaegerSpanHandler that measures the decoding of the batches.
+func (b *Benchmark) JaegerSpanHandler(logger *zap.Logger, modelProcessor SpanProcessor) JaegerBatchesHandler {
+	return &jaegerBatchesHandler{
+		logger:         logger,
+		modelProcessor: modelProcessor,
+		benchmark:      b,
+	}
+}
+
+// ZipkinSpanHandler returns a ZipkinSpansHandler like NewZipkinSpanHandler that measures the decoding of the batches.
+func (b *Benchmark) ZipkinSpanHandler(logger *zap.Logger, modelProcessor SpanProcessor, sanitizer zipkinS.Sanitizer) ZipkinSpansHandler {
+	return &zipkinSpanHandler{
+		logger:         logger,
+		modelProcessor: modelProcessor,
+		sanitizer:      sanitizer,
+		benchmark:      b,
+	}
+}
+
+// SpanFilter returns a FilterSpan that measures the time filter takes to validate each span.
+func (b *Benchmark) SpanFilter(filter FilterSpan) FilterSpan {
+	return func(span *model.Span) bool {
+		start := time.Now()
+		ok := filter(span)
+		elapsed := time.Since(start)
+		b.validateTime.Record(elapsed)
+		atomic.AddInt64(&b.validatedSpans, 1)
+		atomic.AddInt64(&b.validateNanos, int64(elapsed))
+		return ok
+	}
+}
+
+// SpanWriter returns a spanstore.Writer that counts and discards the spans.
+func (b *Benchmark) SpanWriter() spanstore.Writer {
+	return benchmarkWriter{benchmark: b}
+}
+
+// recordDecode records that spans were decoded from a batch since start. A nil Benchmark records nothing.
+func (b *Benchmark) recordDecode(start time.Time, spans int) {
+	if b == nil {
+		return
+	}
+	elapsed := time.Since(start)
+	b.decodeTime.Record(elapsed)
+	atomic.AddInt64(&b.decodedSpans, int64(spans))
+	atomic.AddInt64(&b.decodeNanos, int64(elapsed))
+}
+
+// LogSummary logs the number of spans decoded, validated and discarded since the Benchmark was created,
+// the average time it took to decode and validate a span, and the number of spans discarded per second.
+func (b *Benchmark) LogSummary(logger *zap.Logger) {
+	elapsed := time.Since(b.start)
+	decoded := atomic.LoadInt64(&b.decodedSpans)
+	validated := atomic.LoadInt64(&b.validatedSpans)
+	discarded := atomic.LoadInt64(&b.discardedSpans)
+	logger.Info("Benchmark summary",
+		zap.Duration("elapsed", elapsed),
+		zap.Int64("spans-decoded", decoded),
+		zap.Duration("decode-time-per-span", average(atomic.LoadInt64(&b.decodeNanos), decoded)),
+		zap.Int64("spans-validated", validated),
+		zap.Duration("validate-time-per-span", average(atomic.LoadInt64(&b.validateNanos), validated)),
+		zap.Int64("spans-discarded", discarded),
+		zap.Float64("spans-per-second", float64(discarded)/elapsed.Seconds()))
+}
+
+func average(nanos int64, count int64) time.Duration {
+	if count == 0 {
+		return 0
+	}
+	return time.Duration(nanos / count)
+}
+
+type benchmarkWriter struct {
+	benchmark *Benchmark
+}
+
+func (w benchmarkWriter) WriteSpan(ctx context.Context, span *model.Span) error {
+	w.benchmark.discarded.Inc(1)
+	atomic.AddInt64(&w.benchmark.discardedSpans, 1)
+	return nil
+}
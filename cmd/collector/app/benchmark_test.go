@@ -0,0 +1,91 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics"
+	"github.com/uber/tchannel-go/thrift"
+	"go.uber.org/zap"
+
+	zipkinS "github.com/uber/jaeger/cmd/collector/app/sanitizer/zipkin"
+	"github.com/uber/jaeger/pkg/clock"
+	"github.com/uber/jaeger/pkg/testutils"
+	"github.com/uber/jaeger/thrift-gen/jaeger"
+	"github.com/uber/jaeger/thrift-gen/zipkincore"
+)
+
+func TestBenchmark(t *testing.T) {
+	mb := metrics.NewLocalFactory(time.Hour)
+	benchmark := NewBenchmark(mb)
+	validator := NewSpanValidator(0, clock.System, mb)
+	processor := NewSpanProcessor(
+		benchmark.SpanWriter(),
+		Options.SpanFilter(benchmark.SpanFilter(validator.Validate)),
+		Options.NumWorkers(1),
+		Options.ServiceMetrics(mb),
+		Options.HostMetrics(mb),
+	)
+	jHandler := benchmark.JaegerSpanHandler(zap.NewNop(), processor)
+	zHandler := benchmark.ZipkinSpanHandler(zap.NewNop(), processor, zipkinS.NewChainedSanitizer())
+
+	ctx, cancel := thrift.NewContext(time.Minute)
+	defer cancel()
+	_, err := jHandler.SubmitBatches(ctx, []*jaeger.Batch{{
+		Process: &jaeger.Process{ServiceName: "service"},
+		// the span without an ID is decoded but rejected by the validator
+		Spans: []*jaeger.Span{{TraceIdLow: 1, SpanId: 1}, {TraceIdLow: 1, SpanId: 2, ParentSpanId: 1}, {TraceIdLow: 1}},
+	}})
+	require.NoError(t, err)
+	_, err = zHandler.SubmitZipkinBatch(ctx, []*zipkincore.Span{{TraceID: 2, ID: 3, Name: "foo"}})
+	require.NoError(t, err)
+	require.NoError(t, processor.Close())
+
+	counters, gauges := mb.Snapshot()
+	assert.EqualValues(t, 3, counters["benchmark.spans-discarded"])
+	assert.EqualValues(t, 1, counters["spans.rejected|reason=zero-span-id"])
+	assert.Contains(t, gauges, "benchmark.decode-time.P99")
+	assert.Contains(t, gauges, "benchmark.validate-time.P99")
+
+	logger, logBuf := testutils.NewLogger()
+	benchmark.LogSummary(logger)
+	for _, field := range []string{
+		`"msg":"Benchmark summary"`,
+		`"spans-decoded":4`,
+		`"spans-validated":4`,
+		`"spans-discarded":3`,
+		`"decode-time-per-span":`,
+		`"validate-time-per-span":`,
+		`"spans-per-second":`,
+	} {
+		assert.Contains(t, logBuf.String(), field)
+	}
+}
+
+func TestBenchmarkSummaryWithoutSpans(t *testing.T) {
+	logger, logBuf := testutils.NewLogger()
+	NewBenchmark(metrics.NullFactory).LogSummary(logger)
+	assert.Contains(t, logBuf.String(), `"spans-decoded":0`)
+	assert.Contains(t, logBuf.String(), `"decode-time-per-span":0`)
+}
+
+func TestNilBenchmarkRecordsNothing(t *testing.T) {
+	var benchmark *Benchmark
+	benchmark.recordDecode(time.Now(), 1)
+}
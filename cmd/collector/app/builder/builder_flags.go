@@ -16,60 +16,624 @@ package builder
 
 import (
 	"flag"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
 
 	"github.com/uber/jaeger/cmd/collector/app"
+	"github.com/uber/jaeger/cmd/collector/app/sampling/adaptive"
+	"github.com/uber/jaeger/cmd/collector/app/sanitizer"
+	"github.com/uber/jaeger/cmd/collector/app/wal"
+	"github.com/uber/jaeger/cmd/collector/app/zipkin"
+	"github.com/uber/jaeger/pkg/tlscfg"
+	kafkaSpanstore "github.com/uber/jaeger/plugin/storage/kafka"
+	"github.com/uber/jaeger/storage/spanstore"
 )
 
 const (
+	// QueueFullPolicyBlock makes span submission wait for space in a full queue
+	QueueFullPolicyBlock = "block"
+	// QueueFullPolicyDrop makes span submission drop spans that do not fit in a full queue
+	QueueFullPolicyDrop = "drop"
+	// SpanStoreNoop makes the collector discard spans instead of saving them to the span storage
+	SpanStoreNoop = "noop"
+	// SpanStoreStdout makes the collector print spans to stdout instead of saving them to the span storage
+	SpanStoreStdout = "stdout"
+	// SamplingStrategyNone disables sampling strategies in the collector
+	SamplingStrategyNone = "none"
+	// SamplingStrategyAdaptive makes the collector calculate sampling probabilities from observed throughput
+	SamplingStrategyAdaptive = "adaptive"
+	// RequiredTagsPolicyDrop makes the collector drop the spans whose process lacks some of the required tags
+	RequiredTagsPolicyDrop = "drop"
+	// RequiredTagsPolicyTag makes the collector tag the spans whose process lacks some of the required tags
+	RequiredTagsPolicyTag = "tag"
+	// TimestampSourceClient keeps the start times of the spans as reported by the clients
+	TimestampSourceClient = "client"
+	// TimestampSourceReceive starts the spans at the time the collector received them
+	TimestampSourceReceive = "receive"
+	// TimestampSourceClamp moves the spans starting too far in the future back to the maximum clock skew
+	TimestampSourceClamp = "clamp"
+	// PortProfileDefault is the profile of the ports the collector listens on by default
+	PortProfileDefault = "default"
+	// PortProfileLegacy is the profile of the ports of the collectors that predate the gRPC API, which
+	// served the Zipkin HTTP API on its standard port
+	PortProfileLegacy = "legacy"
+	// HTTPAuthNone accepts the requests to the collector's HTTP API without credentials
+	HTTPAuthNone = "none"
+	// HTTPAuthBasic requires the requests to the collector's HTTP API to carry the basic auth credentials
+	HTTPAuthBasic = "basic"
+	// HTTPAuthBearer requires the requests to the collector's HTTP API to carry one of the bearer tokens
+	HTTPAuthBearer = "bearer"
+	// DefaultServiceName is the name the collector reports itself as in metrics and TChannel
+	DefaultServiceName = "jaeger-collector"
+
+	collectorServiceName         = "collector.service-name"
+	collectorTChannelServiceName = "collector.tchannel-service-name"
+	collectorHyperbahnNodes      = "collector.hyperbahn-nodes"
 	collectorQueueSize           = "collector.queue-size"
+	collectorQueueFullPolicy     = "collector.queue-full-policy"
 	collectorNumWorkers          = "collector.num-workers"
+	collectorBackpressure        = "collector.backpressure-threshold"
 	collectorWriteCacheTTL       = "collector.write-cache-ttl"
+	collectorWriteRetries        = "collector.write-retries"
+	collectorWriteRetryBackoff   = "collector.write-retry-backoff"
+	collectorWriteRetryWorkers   = "collector.write-retry-workers"
+	collectorMaxConcurrentWrites = "collector.max-concurrent-writes"
+	collectorTraceBufferWindow   = "collector.trace-buffer-window"
+	collectorTraceBufferMaxSpans = "collector.trace-buffer-max-spans"
+	collectorTraceBufferWorkers  = "collector.trace-buffer-workers"
+	collectorWALDir              = "collector.wal.dir"
+	collectorWALSyncInterval     = "collector.wal.sync-interval"
+	collectorPortProfile         = "collector.port-profile"
 	collectorPort                = "collector.port"
 	collectorHTTPPort            = "collector.http-port"
+	collectorHTTPEnabled         = "collector.http-enabled"
+	collectorHTTPSocket          = "collector.http-socket"
+	collectorHTTPBasePath        = "collector.http-base-path"
+	collectorHTTPReadTimeout     = "collector.http-read-timeout"
+	collectorHTTPWriteTimeout    = "collector.http-write-timeout"
+	collectorHTTPIdleTimeout     = "collector.http-idle-timeout"
+	collectorListenBacklog       = "collector.listen-backlog"
+	collectorReusePort           = "collector.reuse-port"
+	collectorGRPCPort            = "collector.grpc-port"
 	collectorZipkinHTTPort       = "collector.zipkin.http-port"
+	collectorZipkinRequired      = "collector.zipkin.required"
+	collectorZipkinCORSOrigins   = "collector.zipkin.cors-allowed-origins"
+	collectorZipkinCORSHeaders   = "collector.zipkin.cors-allowed-headers"
+	collectorZipkinStrictIDs     = "collector.zipkin.strict-ids"
 	collectorHealthCheckHTTPPort = "collector.health-check-http-port"
+	collectorHealthCheckInterval = "collector.health-check-probe-interval"
+	collectorReadinessFile       = "collector.readiness-file"
+	collectorStorageBufferSize   = "collector.storage-buffer-size"
+	collectorStorageCompression  = "collector.storage.compression"
+	collectorShutdownTimeout     = "collector.shutdown-timeout"
+	collectorStartupRetries      = "collector.startup-retries"
+	collectorStartupRetryBackoff = "collector.startup-retry-backoff"
+	collectorMaxClockSkew        = "collector.max-clock-skew"
+	collectorTimestampSource     = "collector.timestamp-source"
+	collectorMinSpanDuration     = "collector.min-span-duration"
+	collectorDropOperations      = "collector.drop-operation-patterns"
+	collectorDropMatchURL        = "collector.drop-operation-patterns.match-http-url"
+	collectorDownsamplingRatio   = "collector.downsampling.ratio"
+	collectorDownsamplingSalt    = "collector.downsampling.hashsalt"
+	collectorMirrorFraction      = "collector.mirror.fraction"
+	collectorMirrorTarget        = "collector.mirror.target"
+	collectorHTTPAuth            = "collector.http.auth"
+	collectorHTTPAuthUsername    = "collector.http.auth.username"
+	collectorHTTPAuthPassword    = "collector.http.auth.password"
+	collectorHTTPAuthToken       = "collector.http.auth.token"
+	collectorHTTPAuthTokenFile   = "collector.http.auth.token-file"
+	collectorMetricsMaxServices  = "collector.metrics-max-services"
+	collectorOperationLatencies  = "collector.emit-operation-latencies"
+	collectorMaxOperations       = "collector.metrics-max-operations"
+	collectorRequiredProcessTags = "collector.required-process-tags"
+	collectorRequiredTagsPolicy  = "collector.required-tags-policy"
+	collectorHTTPAccessLog       = "collector.http-access-log"
+	collectorLogLevelEndpoint    = "collector.log-level-endpoint"
+	collectorFlushEndpoint       = "collector.flush-endpoint"
+	collectorExposeConfig        = "collector.expose-config"
+	collectorSelfTracing         = "collector.self-tracing"
+	collectorSelfTracingEndpoint = "collector.self-tracing.endpoint"
+	collectorSelfTracingSampling = "collector.self-tracing.sampling-rate"
+	collectorSpanStore           = "collector.span-store"
+	collectorNoopLogFraction     = "collector.noop-log-fraction"
+	collectorBenchmarkMode       = "collector.benchmark-mode"
+	collectorTagRulesFile        = "collector.tag-rules-file"
+	collectorAllowedServices     = "collector.allowed-services-file"
+	collectorTagSpansWithHost    = "collector.tag-spans-with-host"
+	collectorNormalizeServices   = "collector.normalize-service-names"
+	collectorBaggageToTagKeys    = "collector.baggage-to-tag-keys"
+	collectorOperationNameTag    = "collector.operation-name-tag"
+	collectorMaxLogBytesPerSpan  = "collector.max-log-bytes-per-span"
+	collectorMaxTagsPerSpan      = "collector.max-tags-per-span"
+	collectorMaxLogsPerSpan      = "collector.max-logs-per-span"
+	collectorEnrichDNS           = "collector.enrich-dns"
+	collectorEnrichDNSCacheSize  = "collector.enrich-dns-cache-size"
+	collectorEnrichDNSTimeout    = "collector.enrich-dns-timeout"
+	collectorMaxBatchBytes       = "collector.max-batch-bytes"
+	collectorMaxSpansPerBatch    = "collector.max-spans-per-batch"
+	collectorRateLimitQPS        = "collector.rate-limit-qps"
+	collectorRateLimitBurst      = "collector.rate-limit-burst"
+	collectorRateLimitKeyHeader  = "collector.rate-limit-key-header"
+	collectorTagCardinality      = "collector.measure-tag-cardinality"
+	collectorTagCardinalityKeys  = "collector.tag-cardinality-max-keys"
+	collectorCardinalityWindow   = "collector.tag-cardinality-window"
+	collectorDedupWindow         = "collector.dedup-window"
+	collectorDedupMaxSpans       = "collector.dedup-max-spans"
+	collectorTLSCert             = "collector.tls.cert"
+	collectorTLSKey              = "collector.tls.key"
+	collectorTLSClientCA         = "collector.tls.client-ca"
+	samplingStrategy             = "sampling.strategy"
+	samplingTargetQPS            = "sampling.target-qps"
+	samplingAggregationInterval  = "sampling.aggregation-interval"
+	samplingStrategiesFile       = "sampling.strategies-file"
 )
 
 // CollectorOptions holds configuration for collector
 type CollectorOptions struct {
+	// ServiceName is the metrics namespace and TChannel service name of the collector
+	ServiceName string
+	// TChannelServiceName is the service name the collector's TChannel advertises, empty uses ServiceName
+	TChannelServiceName string
+	// HyperbahnNodes are the host:port addresses of the Hyperbahn nodes the collector advertises itself to,
+	// empty does not advertise the collector
+	HyperbahnNodes []string
 	// QueueSize is the size of collector's queue
 	QueueSize int
 	// NumWorkers is the number of internal workers in a collector
 	NumWorkers int
+	// QueueFullPolicy denotes whether to block or drop spans when the collector's queue is full
+	QueueFullPolicy string
+	// BackpressureThreshold is the fraction of the queue size above which new spans are rejected as busy, 0 disables it
+	BackpressureThreshold float64
 	// WriteCacheTTL denotes how often to check and re-write a service or operation name
 	WriteCacheTTL time.Duration
+	// WriteRetries is how many times a span that failed to be saved is retried, 0 disables retries
+	WriteRetries int
+	// WriteRetryBackoff is how long to wait before the first retry, it doubles with every next retry
+	WriteRetryBackoff time.Duration
+	// WriteRetryWorkers is the number of workers retrying failed writes
+	WriteRetryWorkers int
+	// MaxConcurrentWrites is the maximum number of span writes in flight to the storage, 0 does not limit them
+	MaxConcurrentWrites int
+	// TraceBufferWindow is how long spans are held to be written together with the other spans of their trace, 0 disables it
+	TraceBufferWindow time.Duration
+	// TraceBufferMaxSpans is the largest number of spans held by the trace buffer
+	TraceBufferMaxSpans int
+	// TraceBufferWorkers is the number of goroutines writing the traces held by the trace buffer
+	TraceBufferWorkers int
+	// WALDir is the directory of the write-ahead log that keeps queued spans across restarts, empty disables it
+	WALDir string
+	// WALSyncInterval is how often the write-ahead log is synced to disk, 0 syncs every span
+	WALSyncInterval time.Duration
+	// PortProfile is the name of the set of ports used by the port flags left to their defaults
+	PortProfile string
 	// CollectorPort is the port that the collector service listens in on for tchannel requests
 	CollectorPort int
 	// CollectorHTTPPort is the port that the collector service listens in on for http requests
 	CollectorHTTPPort int
+	// CollectorHTTPEnabled denotes whether the collector serves its http API, TChannel and the health check are served regardless
+	CollectorHTTPEnabled bool
+	// CollectorHTTPSocket is the path of a Unix domain socket that the collector also serves http requests on
+	CollectorHTTPSocket string
+	// HTTPBasePath is the path prefix that the collector's http API and health check are served under, e.g. /jaeger
+	HTTPBasePath string
+	// HTTPReadTimeout is how long the collector's HTTP servers wait for a whole request to be read, 0 disables the timeout
+	HTTPReadTimeout time.Duration
+	// HTTPWriteTimeout is how long the collector's HTTP servers take to write a response once the request is read, 0 disables the timeout
+	HTTPWriteTimeout time.Duration
+	// HTTPIdleTimeout is how long the collector's HTTP servers keep an idle keep-alive connection open, 0 disables the timeout
+	HTTPIdleTimeout time.Duration
+	// ListenBacklog is the maximum number of pending connections of the TChannel and HTTP listeners, 0 uses the system default
+	ListenBacklog int
+	// ReusePort denotes whether the TChannel and HTTP listeners set SO_REUSEPORT, so that several collectors can share their ports
+	ReusePort bool
+	// CollectorGRPCPort is the port that the collector service listens in on for gRPC requests
+	CollectorGRPCPort int
 	// CollectorZipkinHTTPPort is the port that the Zipkin collector service listens in on for http requests
 	CollectorZipkinHTTPPort int
+	// CollectorZipkinRequired denotes whether the collector exits when the Zipkin HTTP server cannot be started
+	CollectorZipkinRequired bool
+	// CollectorZipkinAllowedOrigins are the origins of the web pages that may post spans to the Zipkin HTTP server, * allows any
+	CollectorZipkinAllowedOrigins []string
+	// CollectorZipkinAllowedHeaders are the request headers that web pages may send to the Zipkin HTTP server
+	CollectorZipkinAllowedHeaders []string
+	// CollectorZipkinStrictIDs denotes whether the Zipkin HTTP server rejects the JSON spans with truncated trace or span IDs
+	CollectorZipkinStrictIDs bool
 	// CollectorHealthCheckHTTPPort is the port that the health check service listens in on for http requests
 	CollectorHealthCheckHTTPPort int
+	// HealthCheckProbeInterval is how often the health check verifies that the span storage is reachable
+	HealthCheckProbeInterval time.Duration
+	// ReadinessFile is the path of the file that is created once the collector is ready and removed when it shuts down
+	ReadinessFile string
+	// StorageBufferSize is how many spans are buffered while the span storage is unreachable, 0 disables buffering
+	StorageBufferSize int
+	// StorageCompression is how the spans written to Kafka are compressed after they are marshalled
+	StorageCompression string
+	// ShutdownTimeout is how long the collector waits for queued spans to be written when shutting down
+	ShutdownTimeout time.Duration
+	// StartupRetries is how many times creating the TChannel, listening on its port and advertising on Hyperbahn
+	// are retried before the collector exits, 0 exits on the first failure
+	StartupRetries int
+	// StartupRetryBackoff is how long the collector waits before the first startup retry, doubling after every retry
+	StartupRetryBackoff time.Duration
+	// MaxClockSkew is how far in the future a span may start before it is rejected, 0 disables the check
+	MaxClockSkew time.Duration
+	// TimestampSource denotes whether the spans start at the time reported by the clients, the time they were
+	// received, or the time reported by the clients clamped to MaxClockSkew
+	TimestampSource string
+	// MinSpanDuration is the duration below which spans are dropped unless they are errors, 0 disables the filter
+	MinSpanDuration time.Duration
+	// DropOperationPatterns are the regular expressions matching the operation names of the spans that are dropped
+	DropOperationPatterns []string
+	// DropOperationMatchURL denotes whether DropOperationPatterns are also matched against the http.url tag of the spans
+	DropOperationMatchURL bool
+	// DownsamplingRatio is the fraction of traces that are saved, 1 disables downsampling
+	DownsamplingRatio float64
+	// DownsamplingHashSalt is hashed with the trace IDs to decide which traces are saved when downsampling
+	DownsamplingHashSalt string
+	// MirrorFraction is the fraction of traces whose incoming spans are copied to MirrorTarget, 0 disables mirroring
+	MirrorFraction float64
+	// MirrorTarget is the file path or http URL the mirrored spans are written to
+	MirrorTarget string
+	// HTTPAuth is the authentication required by the collector's HTTP API and Zipkin HTTP port, one of none, basic or bearer
+	HTTPAuth string
+	// HTTPAuthUsername is the username of the basic auth credentials
+	HTTPAuthUsername string
+	// HTTPAuthPassword is the password of the basic auth credentials
+	HTTPAuthPassword string
+	// HTTPAuthToken is a bearer token accepted by the collector's HTTP API
+	HTTPAuthToken string
+	// HTTPAuthTokenFile is the path to a file of bearer tokens accepted by the collector's HTTP API, one per line
+	HTTPAuthTokenFile string
+	// MetricsMaxServices is the number of services with their own spans.received counter, the others are counted as svc=other
+	MetricsMaxServices int
+	// EmitOperationLatencies denotes whether the durations of the saved spans are recorded in the spans.duration timers
+	// tagged by service and operation
+	EmitOperationLatencies bool
+	// MetricsMaxOperations is the number of (service, operation) pairs with their own spans.duration timer, the others
+	// are recorded as svc=other and operation=other
+	MetricsMaxOperations int
+	// RequiredProcessTags are the keys of the tags that the process of every span must have, the spans without them are counted
+	RequiredProcessTags []string
+	// RequiredTagsPolicy denotes whether to drop or tag the spans whose process lacks some of the RequiredProcessTags
+	RequiredTagsPolicy string
+	// HTTPAccessLog denotes whether every request to the collector's HTTP servers is logged
+	HTTPAccessLog bool
+	// LogLevelEndpoint denotes whether the log level can be read and changed at /log-level on the collector's HTTP API
+	LogLevelEndpoint bool
+	// FlushEndpoint denotes whether the spans held by the trace buffer and the ElasticSearch bulk processor can be flushed at /flush on the collector's HTTP API
+	FlushEndpoint bool
+	// ExposeConfig denotes whether the resolved configuration, with secrets redacted, is served at /config on the collector's HTTP API
+	ExposeConfig bool
+	// SelfTracing denotes whether the collector traces the batches it processes and the spans it writes to storage
+	SelfTracing bool
+	// SelfTracingEndpoint is the URL of the collector HTTP API the self-traces are reported to, the collector's own by default
+	SelfTracingEndpoint string
+	// SelfTracingSamplingRate is the probability that the processing of a batch or the write of a span is traced
+	SelfTracingSamplingRate float64
+	// SpanStore overrides the span storage, SpanStoreNoop discards spans after they are processed and
+	// SpanStoreStdout prints them
+	SpanStore string
+	// NoopLogFraction is the fraction of spans that are logged when they are discarded by SpanStoreNoop
+	NoopLogFraction float64
+	// BenchmarkMode discards the spans instead of saving them, measuring how long decoding and validating them takes
+	BenchmarkMode bool
+	// TagRulesFile is the path of a JSON file with rules that drop, truncate, or rename span tags
+	TagRulesFile string
+	// AllowedServicesFile is the path of a file listing the services whose spans are accepted, one per line
+	AllowedServicesFile string
+	// TagSpansWithHost denotes whether every span is tagged with the hostname of the collector that ingested it
+	TagSpansWithHost bool
+	// NormalizeServiceNames denotes whether the service names are lowercased and trimmed when the spans are received
+	NormalizeServiceNames bool
+	// BaggageToTagKeys are the keys of the baggage items that are copied into span tags, so that they can be searched
+	BaggageToTagKeys []string
+	// OperationNameTag is the key of the tag whose value replaces the operation name of the spans that have it, empty
+	// keeps the operation names as reported
+	OperationNameTag string
+	// MaxLogBytesPerSpan is the largest total size of the log fields of a span, larger logs are truncated, 0 disables the limit
+	MaxLogBytesPerSpan int
+	// MaxTagsPerSpan is the largest number of tags kept of a span, 0 disables the limit
+	MaxTagsPerSpan int
+	// MaxLogsPerSpan is the largest number of logs kept of a span, 0 disables the limit
+	MaxLogsPerSpan int
+	// EnrichDNS denotes whether spans with a peer IP address are tagged with the peer's hostname found by reverse DNS
+	EnrichDNS bool
+	// EnrichDNSCacheSize is the largest number of IP addresses whose hostnames are remembered
+	EnrichDNSCacheSize int
+	// EnrichDNSTimeout is how long the collector waits for a reverse DNS lookup
+	EnrichDNSTimeout time.Duration
+	// MaxBatchBytes is the largest HTTP request body the collector accepts, 0 disables the check
+	MaxBatchBytes int64
+	// MaxSpansPerBatch is the largest number of spans the collector accepts in a batch, 0 disables the check
+	MaxSpansPerBatch int
+	// RateLimitQPS is the number of HTTP requests per second the collector accepts from each client, 0 disables rate limiting
+	RateLimitQPS float64
+	// RateLimitBurst is the number of HTTP requests a client can send at once above RateLimitQPS
+	RateLimitBurst int
+	// RateLimitKeyHeader is the request header that clients are rate limited by, clients are rate limited by IP address when empty
+	RateLimitKeyHeader string
+	// MeasureTagCardinality denotes whether the number of distinct values of every span tag key is estimated
+	MeasureTagCardinality bool
+	// TagCardinalityMaxKeys is the largest number of tag keys whose cardinality is estimated
+	TagCardinalityMaxKeys int
+	// TagCardinalityWindow is the sliding window over which the distinct values of a tag key are counted
+	TagCardinalityWindow time.Duration
+	// DedupWindow is how long a span is remembered to drop duplicates of it, 0 disables deduplication
+	DedupWindow time.Duration
+	// DedupMaxSpans is the largest number of spans remembered to drop duplicates of them
+	DedupMaxSpans int
+	// TLS holds the certificates for the collector's HTTP and Zipkin HTTP servers, which serve plaintext when it is not enabled
+	TLS tlscfg.Options
+	// SamplingStrategy denotes how the collector computes the sampling strategies served to agents
+	SamplingStrategy string
+	// SamplingTargetQPS is the number of traces per second that adaptive sampling aims for on every operation
+	SamplingTargetQPS float64
+	// SamplingAggregationInterval is how often throughput is aggregated and sampling probabilities recalculated
+	SamplingAggregationInterval time.Duration
+	// SamplingStrategiesFile is the path of a JSON file with static sampling strategies
+	SamplingStrategiesFile string
 }
 
 // AddFlags adds flags for CollectorOptions
 func AddFlags(flags *flag.FlagSet) {
+	flags.String(collectorServiceName, DefaultServiceName, "The service name of the collector, used as the metrics namespace and TChannel service name")
+	flags.String(collectorTChannelServiceName, "", "The service name advertised by the collector's TChannel, which clients discover it by (defaults to the service name)")
+	flags.String(collectorHyperbahnNodes, "", "The comma-separated list of host:port addresses of the Hyperbahn nodes the collector advertises its TChannel service to")
 	flags.Int(collectorQueueSize, app.DefaultQueueSize, "The queue size of the collector")
 	flags.Int(collectorNumWorkers, app.DefaultNumWorkers, "The number of workers pulling items from the queue")
+	flags.String(collectorQueueFullPolicy, QueueFullPolicyDrop, fmt.Sprintf("What to do with new spans when the queue is full, options are [%v,%v]", QueueFullPolicyBlock, QueueFullPolicyDrop))
+	flags.Float64(collectorBackpressure, 0, "The fraction of the queue size, between 0 and 1, above which clients are told to back off (0 disables backpressure)")
 	flags.Duration(collectorWriteCacheTTL, time.Hour*12, "The duration to wait before rewriting an existing service or operation name")
-	flags.Int(collectorPort, 14267, "The tchannel port for the collector service")
-	flags.Int(collectorHTTPPort, 14268, "The http port for the collector service")
-	flags.Int(collectorZipkinHTTPort, 0, "The http port for the Zipkin collector service e.g. 9411")
-	flags.Int(collectorHealthCheckHTTPPort, 14269, "The http port for the health check service")
+	flags.Int(collectorWriteRetries, 0, "The number of times a span that failed to be saved is retried before it is given up on (0 disables retries)")
+	flags.Duration(collectorWriteRetryBackoff, 100*time.Millisecond, "The duration to wait before retrying a failed write, doubled with every next retry")
+	flags.Int(collectorWriteRetryWorkers, 10, "The number of workers retrying failed writes, up to queue-size spans wait to be retried")
+	flags.Int(collectorMaxConcurrentWrites, 0, "The maximum number of span writes in flight to the storage, further writes wait for one to finish (0 is unlimited)")
+	flags.Duration(collectorTraceBufferWindow, 0, "The duration for which spans are held before they are saved, so that the spans of a trace received within it are saved together (0 disables the trace buffer)")
+	flags.Int(collectorTraceBufferMaxSpans, spanstore.DefaultTraceBufferMaxSpans, "The maximum number of spans held by the trace buffer, the oldest traces are saved early when it is full")
+	flags.Int(collectorTraceBufferWorkers, spanstore.DefaultTraceBufferWorkers, "The number of goroutines saving the traces held by the trace buffer, each trace is saved by one of them")
+	flags.String(collectorWALDir, "", "The directory of a write-ahead log that keeps the queued spans until they are saved, they are replayed when the collector restarts after a crash (empty disables the write-ahead log). It cannot be used with write retries, trace buffering, storage buffering, ElasticSearch bulk requests or Kafka storage")
+	flags.Duration(collectorWALSyncInterval, wal.DefaultSyncInterval, "How often the write-ahead log is synced to disk, the spans appended since the last sync can be lost in a crash of the host (0 syncs every span)")
+	flags.String(collectorPortProfile, PortProfileDefault, fmt.Sprintf("The set of ports used by the port flags that are left to their defaults, options are [%v,%v]", PortProfileDefault, PortProfileLegacy))
+	flags.Int(collectorPort, defaultPorts.tchannel, "The tchannel port for the collector service")
+	flags.Int(collectorHTTPPort, defaultPorts.http, "The http port for the collector service")
+	flags.Bool(collectorHTTPEnabled, true, "Serve the collector's http API on the http port and socket, disable it to only accept spans on TChannel, gRPC, and Zipkin HTTP")
+	flags.String(collectorHTTPSocket, "", "The path of a Unix domain socket to serve the collector's http API on, in addition to the http port (set the http port to 0 to only serve it on the socket)")
+	flags.String(collectorHTTPBasePath, "", "The path prefix that the collector's http API and health check are served under, e.g. /jaeger when running behind a path-routing gateway")
+	flags.Duration(collectorHTTPReadTimeout, 30*time.Second, "The maximum duration for reading a whole request, including its body, on the collector's HTTP servers (0 disables the timeout)")
+	flags.Duration(collectorHTTPWriteTimeout, 30*time.Second, "The maximum duration before timing out the write of a response on the collector's HTTP servers (0 disables the timeout)")
+	flags.Duration(collectorHTTPIdleTimeout, 2*time.Minute, "The maximum duration to wait for the next request on a keep-alive connection to the collector's HTTP servers (0 disables the timeout)")
+	flags.Int(collectorListenBacklog, 0, "The maximum number of pending connections of the TChannel and HTTP listeners, capped by the OS (0 uses the system default)")
+	flags.Bool(collectorReusePort, false, "Set SO_REUSEPORT on the TChannel and HTTP listeners, so that several collector processes can listen on the same ports")
+	flags.Int(collectorGRPCPort, defaultPorts.grpc, "The gRPC port for the collector service")
+	flags.Int(collectorZipkinHTTPort, defaultPorts.zipkinHTTP, "The http port for the Zipkin collector service e.g. 9411")
+	flags.Bool(collectorZipkinRequired, false, "Exit if the Zipkin HTTP server cannot be started, instead of reporting the collector unhealthy")
+	flags.String(collectorZipkinCORSOrigins, "", "Comma-separated list of origins allowed to post spans to the Zipkin HTTP server from browsers, * allows any origin (empty disables CORS)")
+	flags.String(collectorZipkinCORSHeaders, strings.Join(zipkin.DefaultCORSAllowedHeaders, ","), "Comma-separated list of request headers browsers may send to the Zipkin HTTP server")
+	flags.Bool(collectorZipkinStrictIDs, false, "Reject the Zipkin JSON spans whose trace or span IDs are not 16 or 32 hex characters long")
+	flags.Int(collectorHealthCheckHTTPPort, defaultPorts.healthCheck, "The http port for the health check service")
+	flags.Duration(collectorHealthCheckInterval, 10*time.Second, "How often the health check verifies that the span storage is reachable")
+	flags.String(collectorReadinessFile, "", "The path of a file the collector creates once it accepts spans and removes when it shuts down, for probes that watch the filesystem (default is no file)")
+	flags.Int(collectorStorageBufferSize, 0, "The number of spans buffered while the Cassandra or ElasticSearch span storage is unreachable, they are written once it is reachable again (0 disables buffering)")
+	flags.String(collectorStorageCompression, kafkaSpanstore.CompressionNone, fmt.Sprintf("How the spans written to the Kafka span storage are compressed after they are marshalled, options are %v; the consumers of the topic must decompress them with the same codec", kafkaSpanstore.Compressions))
+	flags.Duration(collectorMaxClockSkew, 0, "The maximum amount of time a span's start time may be in the future before the span is rejected (0 disables the check)")
+	flags.String(collectorTimestampSource, TimestampSourceClient, fmt.Sprintf("Where the start times of the spans come from, options are [%v,%v,%v]; %v uses the time the collector received the span, %v moves the start times beyond the max clock skew back to it and adds the %v tag", TimestampSourceClient, TimestampSourceReceive, TimestampSourceClamp, TimestampSourceReceive, TimestampSourceClamp, app.ClockSkewAdjustedTagKey))
+	flags.Duration(collectorMinSpanDuration, 0, "The minimum duration of the spans that are saved, shorter spans are dropped unless they are tagged as errors (0 disables the filter)")
+	flags.String(collectorDropOperations, "", "A comma-separated list of regular expressions, e.g. ^/health$,^/metrics$; the spans whose operation name matches one of them are dropped")
+	flags.Bool(collectorDropMatchURL, false, "Also drop the spans whose http.url tag matches one of the --collector.drop-operation-patterns")
+	flags.Float64(collectorDownsamplingRatio, 1, "The fraction of traces, between 0 and 1, that are saved; the spans of the other traces are dropped, except debug spans (1 disables downsampling)")
+	flags.String(collectorDownsamplingSalt, "", "The salt hashed with the trace IDs to decide which traces are saved when downsampling, all collectors must use the same salt")
+	flags.Float64(collectorMirrorFraction, 0, "The fraction of traces, between 0 and 1, whose incoming spans are copied to the mirror target on a best-effort basis (0 disables mirroring)")
+	flags.String(collectorMirrorTarget, "", "The file the mirrored spans are appended to as JSON lines, or the http(s) URL they are posted to one at a time, e.g. the /api/span endpoint of another collector")
+	flags.String(collectorHTTPAuth, HTTPAuthNone, fmt.Sprintf("The authentication required by all the routes of the collector's HTTP API and Zipkin HTTP port, options are [%v,%v,%v]; requests without valid credentials get 401 Unauthorized", HTTPAuthNone, HTTPAuthBasic, HTTPAuthBearer))
+	flags.String(collectorHTTPAuthUsername, "", "The username of the basic auth credentials of the collector's HTTP API")
+	flags.String(collectorHTTPAuthPassword, "", "The password of the basic auth credentials of the collector's HTTP API")
+	flags.String(collectorHTTPAuthToken, "", "A bearer token accepted by the collector's HTTP API")
+	flags.String(collectorHTTPAuthTokenFile, "", "The path to a file of bearer tokens accepted by the collector's HTTP API, one per line, in addition to the --collector.http.auth.token")
+	flags.Int(collectorMetricsMaxServices, app.DefaultMaxServicesInMetrics, "The number of services with their own spans.received counter, the spans of the services past the limit are counted with svc=other")
+	flags.Bool(collectorOperationLatencies, false, "Record the durations of the saved spans in the spans.duration timers tagged by service and operation")
+	flags.Int(collectorMaxOperations, app.DefaultMaxOperationsInMetrics, "The number of (service, operation) pairs with their own spans.duration timer, the spans of the pairs past the limit are recorded with svc=other and operation=other")
+	flags.String(collectorRequiredProcessTags, "", "The comma-separated list of tag keys that the process of every span must have, the spans without them are counted in spans.missing-required-tags")
+	flags.String(collectorRequiredTagsPolicy, RequiredTagsPolicyTag, fmt.Sprintf("What to do with the spans whose process lacks some of the required tags, options are [%v,%v], %v adds the %v tag", RequiredTagsPolicyDrop, RequiredTagsPolicyTag, RequiredTagsPolicyTag, app.MissingRequiredTagsKey))
+	flags.Duration(collectorShutdownTimeout, app.DefaultShutdownTimeout, "The duration to wait for queued spans to be written when the collector is shutting down")
+	flags.Int(collectorStartupRetries, 0, "How many times the TChannel setup, listening on the TChannel port and advertising on Hyperbahn are retried before the collector exits")
+	flags.Duration(collectorStartupRetryBackoff, time.Second, "The duration to wait before the first startup retry, doubled after every retry up to one minute")
+	flags.Bool(collectorHTTPAccessLog, false, "Log every request to the collector's HTTP servers")
+	flags.Bool(collectorLogLevelEndpoint, false, `Serve the log level at /log-level on the collector's http port, GET returns it and PUT with a body like {"level":"debug"} changes it`)
+	flags.Bool(collectorFlushEndpoint, false, "Serve /flush on the collector's http port, POST writes the spans held by the trace buffer and the ElasticSearch bulk processor and returns how many there were")
+	flags.Bool(collectorExposeConfig, false, "Serve the configuration resolved from flags, environment variables, and config files as JSON at /config on the http port, with passwords and other secrets redacted")
+	flags.Bool(collectorSelfTracing, false, "Trace the batches the collector processes and the spans it writes to storage, the self-traces are tagged with "+app.SelfTraceTagKey+" and are not traced themselves")
+	flags.String(collectorSelfTracingEndpoint, "", "The URL of the collector HTTP API the self-traces are reported to, e.g. http://jaeger-collector:14268/api/traces?format=jaeger.thrift (default is this collector's http port)")
+	flags.Float64(collectorSelfTracingSampling, 0.001, "The probability between 0 and 1 that the processing of a batch or the write of a span is traced")
+	flags.String(collectorSpanStore, "", fmt.Sprintf("Overrides the span storage, set to %v to discard spans after they are processed or to %v to print them to stdout as JSON (default is to use the span storage)", SpanStoreNoop, SpanStoreStdout))
+	flags.Float64(collectorNoopLogFraction, 0, fmt.Sprintf("The fraction of spans, between 0 and 1, that are logged when they are discarded by the %v span store", SpanStoreNoop))
+	flags.Bool(collectorBenchmarkMode, false, "Decode and validate the spans as usual but discard them instead of saving them, reporting the decode and validate times in the benchmark.* metrics and logging a summary on shutdown")
+	flags.String(collectorTagRulesFile, "", "The path of a JSON file with rules that drop, truncate, or rename span tags before spans are saved")
+	flags.String(collectorAllowedServices, "", "The path of a file listing the services whose spans are accepted, one per line, the spans of other services are rejected; reloaded when it changes, an empty or absent file allows all services")
+	flags.Bool(collectorTagSpansWithHost, false, fmt.Sprintf("Tag every span with the hostname of the collector that ingested it, as %v", sanitizer.CollectorHostTagKey))
+	flags.Bool(collectorNormalizeServices, false, fmt.Sprintf("Lowercase the service names of the received spans and trim the whitespace around them, the original names are kept in the %v process tag", app.OriginalServiceNameTagKey))
+	flags.String(collectorBaggageToTagKeys, "", "The comma-separated list of baggage keys whose baggage items are copied into span tags, so that they can be searched")
+	flags.String(collectorOperationNameTag, "", fmt.Sprintf("The key of the tag whose value replaces the operation name of the spans that have it, the original operation name is kept in the %v tag", sanitizer.OriginalOperationNameTagKey))
+	flags.Int(collectorMaxTagsPerSpan, 0, "The maximum number of tags of a span, the tags past it are dropped and the span tagged with "+app.TruncatedTagKey+" (0 disables the limit)")
+	flags.Int(collectorMaxLogsPerSpan, 0, "The maximum number of logs of a span, the logs past it are dropped and the span tagged with "+app.TruncatedTagKey+" (0 disables the limit)")
+	flags.Int(collectorMaxLogBytesPerSpan, 0, "The maximum total size in bytes of the keys and values of the log fields of a span, the logs of larger spans are truncated and the spans tagged with "+sanitizer.LogsTruncatedTagKey+" (0 disables the limit)")
+	flags.Bool(collectorEnrichDNS, false, fmt.Sprintf("Tag spans that have a peer.ipv4 tag but no hostname with the hostname of the peer found by reverse DNS, as %v", sanitizer.PeerHostnameTagKey))
+	flags.Int(collectorEnrichDNSCacheSize, sanitizer.DefaultDNSCacheSize, "The maximum number of IP addresses whose hostnames are remembered when enriching spans with reverse DNS")
+	flags.Duration(collectorEnrichDNSTimeout, sanitizer.DefaultDNSTimeout, "The time to wait for a reverse DNS lookup when enriching spans, spans are never held up by lookups")
+	flags.Int64(collectorMaxBatchBytes, 0, "The maximum size in bytes of a batch posted to the collector's HTTP servers (0 disables the check)")
+	flags.Int(collectorMaxSpansPerBatch, 0, "The maximum number of spans in a batch submitted to the collector (0 disables the check)")
+	flags.Float64(collectorRateLimitQPS, 0, "The maximum average number of requests per second accepted from each client by the collector's HTTP servers, requests over it are rejected with 429 (0 disables rate limiting)")
+	flags.Int(collectorRateLimitBurst, 10, "The maximum number of requests accepted at once from each client by the collector's HTTP servers when rate limiting")
+	flags.String(collectorRateLimitKeyHeader, "", "The request header whose value clients are rate limited by, e.g. a service name header, requests without it are rate limited by client IP (default is to rate limit by client IP)")
+	flags.Bool(collectorTagCardinality, false, "Estimate the number of distinct values of every span tag key, reported in the tag-cardinality gauge tagged with the key, to detect the tags with runaway cardinality")
+	flags.Int(collectorTagCardinalityKeys, app.DefaultTagCardinalityMaxKeys, "The maximum number of span tag keys whose cardinality is estimated, the other keys are counted in tag-cardinality.untracked-tags")
+	flags.Duration(collectorCardinalityWindow, app.DefaultTagCardinalityWindow, "The sliding window over which the distinct values of a span tag key are counted, the estimates are reported every half window")
+	flags.Duration(collectorDedupWindow, 0, "The duration within which spans with the same trace and span IDs are dropped as duplicates, e.g. of batches retried by agents (0 disables deduplication)")
+	flags.Int(collectorDedupMaxSpans, app.DefaultDedupMaxSpans, "The maximum number of recently seen spans remembered for deduplication")
+	flags.String(collectorTLSCert, "", "Path to a TLS certificate file for the collector's HTTP servers, enables TLS when set. The certificate and key are reloaded when they change")
+	flags.String(collectorTLSKey, "", "Path to the TLS private key file for the collector's HTTP servers")
+	flags.String(collectorTLSClientCA, "", "Path to a TLS CA file used to verify client certificates, enables mutual TLS when set")
+	flags.String(samplingStrategy, SamplingStrategyNone, fmt.Sprintf("The sampling strategy served to agents, options are [%v,%v]", SamplingStrategyNone, SamplingStrategyAdaptive))
+	flags.Float64(samplingTargetQPS, adaptive.DefaultTargetQPS, "The number of traces per second to sample for every operation when using adaptive sampling")
+	flags.Duration(samplingAggregationInterval, adaptive.DefaultAggregationInterval, "The interval at which throughput is aggregated and sampling probabilities are recalculated when using adaptive sampling")
+	flags.String(samplingStrategiesFile, "", "The path of a JSON file with static sampling strategies served to agents, reloaded when it changes")
 }
 
 // InitFromViper initializes CollectorOptions with properties from viper
 func (cOpts *CollectorOptions) InitFromViper(v *viper.Viper) *CollectorOptions {
+	cOpts.ServiceName = v.GetString(collectorServiceName)
+	cOpts.TChannelServiceName = v.GetString(collectorTChannelServiceName)
+	cOpts.HyperbahnNodes = splitList(v.GetString(collectorHyperbahnNodes))
 	cOpts.QueueSize = v.GetInt(collectorQueueSize)
 	cOpts.NumWorkers = v.GetInt(collectorNumWorkers)
+	cOpts.QueueFullPolicy = v.GetString(collectorQueueFullPolicy)
+	cOpts.BackpressureThreshold = v.GetFloat64(collectorBackpressure)
 	cOpts.WriteCacheTTL = v.GetDuration(collectorWriteCacheTTL)
-	cOpts.CollectorPort = v.GetInt(collectorPort)
-	cOpts.CollectorHTTPPort = v.GetInt(collectorHTTPPort)
-	cOpts.CollectorZipkinHTTPPort = v.GetInt(collectorZipkinHTTPort)
-	cOpts.CollectorHealthCheckHTTPPort = v.GetInt(collectorHealthCheckHTTPPort)
+	cOpts.WriteRetries = v.GetInt(collectorWriteRetries)
+	cOpts.WriteRetryBackoff = v.GetDuration(collectorWriteRetryBackoff)
+	cOpts.WriteRetryWorkers = v.GetInt(collectorWriteRetryWorkers)
+	cOpts.MaxConcurrentWrites = v.GetInt(collectorMaxConcurrentWrites)
+	cOpts.TraceBufferWindow = v.GetDuration(collectorTraceBufferWindow)
+	cOpts.TraceBufferMaxSpans = v.GetInt(collectorTraceBufferMaxSpans)
+	cOpts.TraceBufferWorkers = v.GetInt(collectorTraceBufferWorkers)
+	cOpts.WALDir = v.GetString(collectorWALDir)
+	cOpts.WALSyncInterval = v.GetDuration(collectorWALSyncInterval)
+	cOpts.PortProfile = v.GetString(collectorPortProfile)
+	// an unknown profile, which fails validation, leaves the ports as they are
+	ports, ok := portProfiles[cOpts.PortProfile]
+	if !ok {
+		ports = defaultPorts
+	}
+	cOpts.CollectorPort = profilePort(v, collectorPort, defaultPorts.tchannel, ports.tchannel)
+	cOpts.CollectorHTTPPort = profilePort(v, collectorHTTPPort, defaultPorts.http, ports.http)
+	cOpts.CollectorHTTPEnabled = v.GetBool(collectorHTTPEnabled)
+	cOpts.CollectorHTTPSocket = v.GetString(collectorHTTPSocket)
+	cOpts.HTTPBasePath = normalizeBasePath(v.GetString(collectorHTTPBasePath))
+	cOpts.HTTPReadTimeout = v.GetDuration(collectorHTTPReadTimeout)
+	cOpts.HTTPWriteTimeout = v.GetDuration(collectorHTTPWriteTimeout)
+	cOpts.HTTPIdleTimeout = v.GetDuration(collectorHTTPIdleTimeout)
+	cOpts.ListenBacklog = v.GetInt(collectorListenBacklog)
+	cOpts.ReusePort = v.GetBool(collectorReusePort)
+	cOpts.CollectorGRPCPort = profilePort(v, collectorGRPCPort, defaultPorts.grpc, ports.grpc)
+	cOpts.CollectorZipkinHTTPPort = profilePort(v, collectorZipkinHTTPort, defaultPorts.zipkinHTTP, ports.zipkinHTTP)
+	cOpts.CollectorZipkinRequired = v.GetBool(collectorZipkinRequired)
+	cOpts.CollectorZipkinAllowedOrigins = splitList(v.GetString(collectorZipkinCORSOrigins))
+	cOpts.CollectorZipkinAllowedHeaders = splitList(v.GetString(collectorZipkinCORSHeaders))
+	cOpts.CollectorZipkinStrictIDs = v.GetBool(collectorZipkinStrictIDs)
+	cOpts.CollectorHealthCheckHTTPPort = profilePort(v, collectorHealthCheckHTTPPort, defaultPorts.healthCheck, ports.healthCheck)
+	cOpts.HealthCheckProbeInterval = v.GetDuration(collectorHealthCheckInterval)
+	cOpts.ReadinessFile = v.GetString(collectorReadinessFile)
+	cOpts.StorageBufferSize = v.GetInt(collectorStorageBufferSize)
+	cOpts.StorageCompression = v.GetString(collectorStorageCompression)
+	cOpts.ShutdownTimeout = v.GetDuration(collectorShutdownTimeout)
+	cOpts.StartupRetries = v.GetInt(collectorStartupRetries)
+	cOpts.StartupRetryBackoff = v.GetDuration(collectorStartupRetryBackoff)
+	cOpts.MaxClockSkew = v.GetDuration(collectorMaxClockSkew)
+	cOpts.TimestampSource = v.GetString(collectorTimestampSource)
+	cOpts.MinSpanDuration = v.GetDuration(collectorMinSpanDuration)
+	cOpts.DropOperationPatterns = splitList(v.GetString(collectorDropOperations))
+	cOpts.DropOperationMatchURL = v.GetBool(collectorDropMatchURL)
+	cOpts.DownsamplingRatio = v.GetFloat64(collectorDownsamplingRatio)
+	cOpts.DownsamplingHashSalt = v.GetString(collectorDownsamplingSalt)
+	cOpts.MirrorFraction = v.GetFloat64(collectorMirrorFraction)
+	cOpts.MirrorTarget = v.GetString(collectorMirrorTarget)
+	cOpts.HTTPAuth = v.GetString(collectorHTTPAuth)
+	cOpts.HTTPAuthUsername = v.GetString(collectorHTTPAuthUsername)
+	cOpts.HTTPAuthPassword = v.GetString(collectorHTTPAuthPassword)
+	cOpts.HTTPAuthToken = v.GetString(collectorHTTPAuthToken)
+	cOpts.HTTPAuthTokenFile = v.GetString(collectorHTTPAuthTokenFile)
+	cOpts.MetricsMaxServices = v.GetInt(collectorMetricsMaxServices)
+	cOpts.EmitOperationLatencies = v.GetBool(collectorOperationLatencies)
+	cOpts.MetricsMaxOperations = v.GetInt(collectorMaxOperations)
+	cOpts.RequiredProcessTags = splitList(v.GetString(collectorRequiredProcessTags))
+	cOpts.RequiredTagsPolicy = v.GetString(collectorRequiredTagsPolicy)
+	cOpts.HTTPAccessLog = v.GetBool(collectorHTTPAccessLog)
+	cOpts.LogLevelEndpoint = v.GetBool(collectorLogLevelEndpoint)
+	cOpts.FlushEndpoint = v.GetBool(collectorFlushEndpoint)
+	cOpts.ExposeConfig = v.GetBool(collectorExposeConfig)
+	cOpts.SelfTracing = v.GetBool(collectorSelfTracing)
+	cOpts.SelfTracingEndpoint = v.GetString(collectorSelfTracingEndpoint)
+	cOpts.SelfTracingSamplingRate = v.GetFloat64(collectorSelfTracingSampling)
+	cOpts.SpanStore = v.GetString(collectorSpanStore)
+	cOpts.NoopLogFraction = v.GetFloat64(collectorNoopLogFraction)
+	cOpts.BenchmarkMode = v.GetBool(collectorBenchmarkMode)
+	cOpts.TagRulesFile = v.GetString(collectorTagRulesFile)
+	cOpts.AllowedServicesFile = v.GetString(collectorAllowedServices)
+	cOpts.TagSpansWithHost = v.GetBool(collectorTagSpansWithHost)
+	cOpts.NormalizeServiceNames = v.GetBool(collectorNormalizeServices)
+	cOpts.BaggageToTagKeys = splitList(v.GetString(collectorBaggageToTagKeys))
+	cOpts.OperationNameTag = strings.TrimSpace(v.GetString(collectorOperationNameTag))
+	cOpts.MaxLogBytesPerSpan = v.GetInt(collectorMaxLogBytesPerSpan)
+	cOpts.MaxTagsPerSpan = v.GetInt(collectorMaxTagsPerSpan)
+	cOpts.MaxLogsPerSpan = v.GetInt(collectorMaxLogsPerSpan)
+	cOpts.EnrichDNS = v.GetBool(collectorEnrichDNS)
+	cOpts.EnrichDNSCacheSize = v.GetInt(collectorEnrichDNSCacheSize)
+	cOpts.EnrichDNSTimeout = v.GetDuration(collectorEnrichDNSTimeout)
+	cOpts.MaxBatchBytes = v.GetInt64(collectorMaxBatchBytes)
+	cOpts.MaxSpansPerBatch = v.GetInt(collectorMaxSpansPerBatch)
+	cOpts.RateLimitQPS = v.GetFloat64(collectorRateLimitQPS)
+	cOpts.RateLimitBurst = v.GetInt(collectorRateLimitBurst)
+	cOpts.RateLimitKeyHeader = v.GetString(collectorRateLimitKeyHeader)
+	cOpts.MeasureTagCardinality = v.GetBool(collectorTagCardinality)
+	cOpts.TagCardinalityMaxKeys = v.GetInt(collectorTagCardinalityKeys)
+	cOpts.TagCardinalityWindow = v.GetDuration(collectorCardinalityWindow)
+	cOpts.DedupWindow = v.GetDuration(collectorDedupWindow)
+	cOpts.DedupMaxSpans = v.GetInt(collectorDedupMaxSpans)
+	cOpts.TLS.CertPath = v.GetString(collectorTLSCert)
+	cOpts.TLS.KeyPath = v.GetString(collectorTLSKey)
+	cOpts.TLS.ClientCAPath = v.GetString(collectorTLSClientCA)
+	cOpts.SamplingStrategy = v.GetString(samplingStrategy)
+	cOpts.SamplingTargetQPS = v.GetFloat64(samplingTargetQPS)
+	cOpts.SamplingAggregationInterval = v.GetDuration(samplingAggregationInterval)
+	cOpts.SamplingStrategiesFile = v.GetString(samplingStrategiesFile)
 	return cOpts
 }
+
+// normalizeBasePath returns the path prefix with a leading slash and without a trailing slash, or "" for the root
+func normalizeBasePath(basePath string) string {
+	basePath = strings.TrimRight(strings.TrimSpace(basePath), "/")
+	if basePath != "" && !strings.HasPrefix(basePath, "/") {
+		basePath = "/" + basePath
+	}
+	return basePath
+}
+
+// splitList returns the non-empty items of a comma-separated list
+// portProfile is a named set of ports of the collector's servers
+type portProfile struct {
+	tchannel    int
+	http        int
+	grpc        int
+	zipkinHTTP  int
+	healthCheck int
+}
+
+var (
+	defaultPorts = portProfile{tchannel: 14267, http: 14268, grpc: 14250, zipkinHTTP: 0, healthCheck: 14269}
+	portProfiles = map[string]portProfile{
+		PortProfileDefault: defaultPorts,
+		PortProfileLegacy:  {tchannel: 14267, http: 14268, grpc: 0, zipkinHTTP: 9411, healthCheck: 14269},
+	}
+)
+
+// profilePort returns the port of the key flag, or the port of the profile when the flag has its default
+// value, since viper cannot tell a flag set to its default from one left unset
+func profilePort(v *viper.Viper, key string, defaultPort int, profilePort int) int {
+	if port := v.GetInt(key); port != defaultPort {
+		return port
+	}
+	return profilePort
+}
+
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -15,130 +15,913 @@
 package builder
 
 import (
+	"context"
 	"errors"
+	"io"
+	"net"
 	"os"
+	"time"
 
 	"github.com/uber/jaeger-lib/metrics"
 	"go.uber.org/zap"
 
 	basicB "github.com/uber/jaeger/cmd/builder"
 	"github.com/uber/jaeger/cmd/collector/app"
+	"github.com/uber/jaeger/cmd/collector/app/sampling/adaptive"
+	"github.com/uber/jaeger/cmd/collector/app/sampling/static"
+	"github.com/uber/jaeger/cmd/collector/app/sanitizer"
 	zs "github.com/uber/jaeger/cmd/collector/app/sanitizer/zipkin"
+	"github.com/uber/jaeger/cmd/collector/app/wal"
 	"github.com/uber/jaeger/cmd/flags"
-	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/pkg/cassandra"
 	cascfg "github.com/uber/jaeger/pkg/cassandra/config"
+	"github.com/uber/jaeger/pkg/clock"
+	"github.com/uber/jaeger/pkg/es"
 	escfg "github.com/uber/jaeger/pkg/es/config"
+	kafkacfg "github.com/uber/jaeger/pkg/kafka/config"
+	"github.com/uber/jaeger/pkg/multierror"
+	casSamplingstore "github.com/uber/jaeger/plugin/storage/cassandra/samplingstore"
 	casSpanstore "github.com/uber/jaeger/plugin/storage/cassandra/spanstore"
 	esSpanstore "github.com/uber/jaeger/plugin/storage/es/spanstore"
+	kafkaSpanstore "github.com/uber/jaeger/plugin/storage/kafka"
 	"github.com/uber/jaeger/storage/spanstore"
+	"github.com/uber/jaeger/thrift-gen/sampling"
 )
 
+const (
+	cassandraProbeQuery = "SELECT now() FROM system.local"
+	// esProbeIndex does not need to exist, the request only checks that the cluster responds
+	esProbeIndex        = "jaeger-health-check"
+	storageProbeTimeout = 5 * time.Second
+)
+
+// stdout is where the SpanStoreStdout span store prints spans, tests replace it
+var stdout io.Writer = os.Stdout
+
 var (
-	errMissingCassandraConfig     = errors.New("Cassandra not configured")
-	errMissingMemoryStore         = errors.New("MemoryStore is not provided")
-	errMissingElasticSearchConfig = errors.New("ElasticSearch not configured")
+	errMissingCassandraConfig      = errors.New("Cassandra not configured")
+	errMissingMemoryStore          = errors.New("MemoryStore is not provided")
+	errMissingElasticSearchConfig  = errors.New("ElasticSearch not configured")
+	errMissingKafkaConfig          = errors.New("Kafka not configured")
+	errUnsupportedKafkaEncoding    = errors.New("Kafka encoding is not supported")
+	errInvalidKafkaMaxTopics       = errors.New("Routing spans to Kafka topics by tag requires at least one topic")
+	errUnsupportedIndexRotation    = errors.New("ElasticSearch index rotation is not supported")
+	errUnsupportedQueueFullPolicy  = errors.New("Queue full policy is not supported")
+	errUnsupportedRequiredTags     = errors.New("Required tags policy is not supported")
+	errUnsupportedTimestampSource  = errors.New("Timestamp source is not supported")
+	errUnsupportedPortProfile      = errors.New("Port profile is not supported")
+	errClampWithoutMaxClockSkew    = errors.New("Clamping timestamps requires a positive max clock skew")
+	errInvalidBackpressure         = errors.New("Backpressure threshold must be between 0 and 1")
+	errInvalidDownsamplingRatio    = errors.New("Downsampling ratio must be above 0 and at most 1")
+	errInvalidMirrorFraction       = errors.New("Mirror fraction must be between 0 and 1")
+	errMissingMirrorTarget         = errors.New("Mirroring spans requires a mirror target")
+	errInvalidDedupMaxSpans        = errors.New("Deduplication requires remembering at least one span")
+	errInvalidTagCardinalityKeys   = errors.New("Measuring tag cardinality requires tracking at least one tag key")
+	errInvalidTagCardinalityWindow = errors.New("Tag cardinality window must be positive")
+	errInvalidDNSCacheSize         = errors.New("Reverse DNS enrichment requires remembering at least one IP address")
+	errInvalidWriteRetryWorkers    = errors.New("Write retries require at least one worker")
+	errInvalidMaxConcurrentWrites  = errors.New("Maximum concurrent writes must not be negative")
+	errInvalidTraceBufferMaxSpans  = errors.New("Trace buffering requires holding at least one span")
+	errInvalidTraceBufferWorkers   = errors.New("Trace buffering requires at least one worker")
+	errInvalidCassandraSpanTTL     = errors.New("Cassandra span TTL must be 0 or at least 1s")
+	errInvalidWALSyncInterval      = errors.New("Write-ahead log sync interval must not be negative")
+	errWALAsyncWrites              = errors.New("Write-ahead log cannot be used with write retries, trace buffering, storage buffering, ElasticSearch bulk requests or Kafka storage, which store spans after they are acknowledged")
+	errInvalidMaxLogBytesPerSpan   = errors.New("Maximum log bytes per span must not be negative")
+	errInvalidMaxEntriesPerSpan    = errors.New("Maximum tags and logs per span must not be negative")
+	errInvalidStartupRetries       = errors.New("Startup retries must not be negative")
+	errInvalidStartupRetryBackoff  = errors.New("Startup retry backoff must not be negative")
+	errStorageBufferProbe          = errors.New("Buffering spans while the storage is down requires Cassandra or ElasticSearch storage")
+	errStorageCompressionKafka     = errors.New("Compressing spans requires Kafka storage, Cassandra and ElasticSearch store them as queryable rows and documents")
+	errInvalidProbeInterval        = errors.New("Health check probe interval must be positive")
+	errUnsupportedSpanStore        = errors.New("Span store is not supported")
+	errDuplicateStorageType        = errors.New("Span storage type is listed more than once")
+	errUnsupportedSamplingStrategy = errors.New("Sampling strategy is not supported")
+	errAdaptiveSamplingStorage     = errors.New("Adaptive sampling requires Cassandra storage")
+	errAdaptiveSamplingStaticFile  = errors.New("Adaptive sampling cannot be used with a sampling strategies file")
 )
 
 // SpanHandlerBuilder holds configuration required for handlers
 type SpanHandlerBuilder struct {
 	logger         *zap.Logger
 	metricsFactory metrics.Factory
+	clock          clock.Clock
 	collectorOpts  *CollectorOptions
 	spanWriter     spanstore.Writer
+	spanProcessor  app.SpanProcessor
+	// apiProcessor is spanProcessor wrapped with the same limits as the span handlers
+	apiProcessor app.SpanProcessor
+
+	cassandraSession   cassandra.Session
+	tenantSessions     []cassandra.Session
+	esClient           es.Client
+	esWriter           *esSpanstore.SpanWriter
+	samplingAggregator *adaptive.Aggregator
+	samplingProcessor  *adaptive.Processor
+	staticStrategies   *static.Store
+	allowedServices    *app.ServiceAllowlist
+	operationFilter    *app.OperationFilter
+	spanMirror         *app.SpanMirror
+	tagCardinality     *app.TagCardinality
+	benchmark          *app.Benchmark
+	tagRules           []sanitizer.TagRule
+	selfTracer         *app.SelfTracer
+	writeAheadLog      *wal.Log
+	healthWriter       *spanstore.HealthAwareWriter
+	traceBuffer        *spanstore.TraceBufferWriter
 }
 
 // NewSpanHandlerBuilder returns new SpanHandlerBuilder with configured span storage.
 func NewSpanHandlerBuilder(cOpts *CollectorOptions, sFlags *flags.SharedFlags, opts ...basicB.Option) (*SpanHandlerBuilder, error) {
 	options := basicB.ApplyOptions(opts...)
 
+	switch cOpts.QueueFullPolicy {
+	case "", QueueFullPolicyBlock, QueueFullPolicyDrop:
+	default:
+		return nil, errUnsupportedQueueFullPolicy
+	}
+
+	switch cOpts.RequiredTagsPolicy {
+	case "", RequiredTagsPolicyDrop, RequiredTagsPolicyTag:
+	default:
+		return nil, errUnsupportedRequiredTags
+	}
+
+	if _, ok := portProfiles[cOpts.PortProfile]; !ok && cOpts.PortProfile != "" {
+		return nil, errUnsupportedPortProfile
+	}
+
+	switch cOpts.TimestampSource {
+	case "", TimestampSourceClient, TimestampSourceReceive:
+	case TimestampSourceClamp:
+		if cOpts.MaxClockSkew <= 0 {
+			return nil, errClampWithoutMaxClockSkew
+		}
+	default:
+		return nil, errUnsupportedTimestampSource
+	}
+
+	if cOpts.BackpressureThreshold < 0 || cOpts.BackpressureThreshold > 1 {
+		return nil, errInvalidBackpressure
+	}
+
+	if cOpts.DownsamplingRatio <= 0 || cOpts.DownsamplingRatio > 1 {
+		return nil, errInvalidDownsamplingRatio
+	}
+
+	if cOpts.MirrorFraction < 0 || cOpts.MirrorFraction > 1 {
+		return nil, errInvalidMirrorFraction
+	}
+
+	if cOpts.MirrorFraction > 0 && cOpts.MirrorTarget == "" {
+		return nil, errMissingMirrorTarget
+	}
+
+	if cOpts.DedupWindow > 0 && cOpts.DedupMaxSpans <= 0 {
+		return nil, errInvalidDedupMaxSpans
+	}
+
+	if cOpts.MeasureTagCardinality && cOpts.TagCardinalityMaxKeys <= 0 {
+		return nil, errInvalidTagCardinalityKeys
+	}
+
+	if cOpts.MeasureTagCardinality && cOpts.TagCardinalityWindow <= 0 {
+		return nil, errInvalidTagCardinalityWindow
+	}
+
+	if cOpts.EnrichDNS && cOpts.EnrichDNSCacheSize <= 0 {
+		return nil, errInvalidDNSCacheSize
+	}
+
+	if cOpts.WriteRetries > 0 && cOpts.WriteRetryWorkers <= 0 {
+		return nil, errInvalidWriteRetryWorkers
+	}
+
+	if cOpts.MaxConcurrentWrites < 0 {
+		return nil, errInvalidMaxConcurrentWrites
+	}
+
+	if cOpts.TraceBufferWindow > 0 && cOpts.TraceBufferMaxSpans <= 0 {
+		return nil, errInvalidTraceBufferMaxSpans
+	}
+
+	if cOpts.TraceBufferWindow > 0 && cOpts.TraceBufferWorkers <= 0 {
+		return nil, errInvalidTraceBufferWorkers
+	}
+
+	if cOpts.WALSyncInterval < 0 {
+		return nil, errInvalidWALSyncInterval
+	}
+
+	if cOpts.WALDir != "" && writesAfterAck(cOpts, sFlags.SpanStorage.Types(), options) {
+		return nil, errWALAsyncWrites
+	}
+
+	if cOpts.MaxLogBytesPerSpan < 0 {
+		return nil, errInvalidMaxLogBytesPerSpan
+	}
+
+	if cOpts.MaxTagsPerSpan < 0 || cOpts.MaxLogsPerSpan < 0 {
+		return nil, errInvalidMaxEntriesPerSpan
+	}
+
+	if cOpts.StartupRetries < 0 {
+		return nil, errInvalidStartupRetries
+	}
+
+	if cOpts.StartupRetryBackoff < 0 {
+		return nil, errInvalidStartupRetryBackoff
+	}
+
+	if cOpts.StorageCompression != "" && cOpts.StorageCompression != kafkaSpanstore.CompressionNone &&
+		!hasStorageType(sFlags.SpanStorage.Types(), flags.KafkaStorageType) {
+		return nil, errStorageCompressionKafka
+	}
+
+	var operationFilter *app.OperationFilter
+	if len(cOpts.DropOperationPatterns) > 0 {
+		var err error
+		operationFilter, err = app.NewOperationFilter(cOpts.DropOperationPatterns, cOpts.DropOperationMatchURL, options.MetricsFactory)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch cOpts.SpanStore {
+	case "", SpanStoreNoop, SpanStoreStdout:
+	default:
+		return nil, errUnsupportedSpanStore
+	}
+
 	spanHb := &SpanHandlerBuilder{
-		collectorOpts:  cOpts,
-		logger:         options.Logger,
-		metricsFactory: options.MetricsFactory,
+		collectorOpts:   cOpts,
+		logger:          options.Logger,
+		metricsFactory:  options.MetricsFactory,
+		clock:           options.Clock,
+		operationFilter: operationFilter,
 	}
 
 	var err error
-	if sFlags.SpanStorage.Type == flags.CassandraStorageType {
+	switch {
+	case cOpts.BenchmarkMode:
+		spanHb.benchmark = app.NewBenchmark(spanHb.metricsFactory)
+		spanHb.spanWriter = spanHb.benchmark.SpanWriter()
+	case cOpts.SpanStore == SpanStoreNoop:
+		spanHb.spanWriter = spanstore.NewNoopWriter(spanHb.logger, cOpts.NoopLogFraction)
+	case cOpts.SpanStore == SpanStoreStdout:
+		spanHb.spanWriter = spanstore.NewStdoutWriter(stdout)
+	default:
+		spanHb.spanWriter, err = spanHb.initSpanWriters(sFlags.SpanStorage.Types(), options)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if cOpts.MaxConcurrentWrites > 0 {
+		spanHb.spanWriter = spanstore.NewConcurrencyLimitWriter(spanHb.spanWriter, cOpts.MaxConcurrentWrites, spanHb.metricsFactory)
+	}
+
+	switch cOpts.SamplingStrategy {
+	case "", SamplingStrategyNone:
+	case SamplingStrategyAdaptive:
+		if spanHb.cassandraSession == nil {
+			return nil, errAdaptiveSamplingStorage
+		}
+		if cOpts.SamplingStrategiesFile != "" {
+			return nil, errAdaptiveSamplingStaticFile
+		}
+		spanHb.initAdaptiveSampling()
+	default:
+		return nil, errUnsupportedSamplingStrategy
+	}
+	if cOpts.TagRulesFile != "" {
+		if spanHb.tagRules, err = sanitizer.LoadTagRules(cOpts.TagRulesFile); err != nil {
+			return nil, err
+		}
+	}
+	if cOpts.SamplingStrategiesFile != "" {
+		if spanHb.staticStrategies, err = static.NewStore(cOpts.SamplingStrategiesFile, static.DefaultReloadInterval, spanHb.logger); err != nil {
+			return nil, err
+		}
+	}
+	if cOpts.AllowedServicesFile != "" {
+		if spanHb.allowedServices, err = app.NewServiceAllowlist(
+			cOpts.AllowedServicesFile,
+			app.DefaultAllowedServicesReloadInterval,
+			spanHb.metricsFactory,
+			spanHb.logger,
+		); err != nil {
+			return nil, err
+		}
+	}
+	if cOpts.MirrorFraction > 0 {
+		sink, err := app.NewMirrorSink(cOpts.MirrorTarget)
+		if err != nil {
+			return nil, err
+		}
+		spanHb.spanMirror = app.NewSpanMirror(cOpts.MirrorFraction, sink, app.DefaultMirrorQueueSize, spanHb.metricsFactory, spanHb.logger)
+	}
+	if cOpts.MeasureTagCardinality {
+		spanHb.tagCardinality = app.NewTagCardinality(cOpts.TagCardinalityWindow, cOpts.TagCardinalityMaxKeys, spanHb.metricsFactory)
+	}
+	if cOpts.StorageBufferSize > 0 {
+		ping := spanHb.storagePing()
+		if ping == nil {
+			return nil, errStorageBufferProbe
+		}
+		if cOpts.HealthCheckProbeInterval <= 0 {
+			return nil, errInvalidProbeInterval
+		}
+		spanHb.healthWriter = spanstore.NewHealthAwareWriter(
+			spanHb.spanWriter,
+			ping,
+			cOpts.HealthCheckProbeInterval,
+			cOpts.StorageBufferSize,
+			spanHb.metricsFactory,
+			spanHb.logger,
+		)
+		spanHb.spanWriter = spanHb.healthWriter
+	}
+	if cOpts.WriteRetries > 0 {
+		spanHb.spanWriter = spanstore.NewRetryWriter(
+			spanHb.spanWriter,
+			cOpts.WriteRetries,
+			cOpts.WriteRetryBackoff,
+			cOpts.WriteRetryWorkers,
+			cOpts.QueueSize,
+			spanHb.metricsFactory,
+			spanHb.logger,
+		)
+	}
+	if options.Tracer != nil {
+		spanHb.selfTracer = app.NewSelfTracer(options.Tracer)
+		spanHb.spanWriter = spanHb.selfTracer.SpanWriter(spanHb.spanWriter)
+	}
+	if cOpts.TraceBufferWindow > 0 {
+		spanHb.traceBuffer = spanstore.NewTraceBufferWriter(
+			spanHb.spanWriter,
+			cOpts.TraceBufferWindow,
+			cOpts.TraceBufferMaxSpans,
+			cOpts.TraceBufferWorkers,
+			spanHb.clock,
+			spanHb.metricsFactory,
+			spanHb.logger,
+		)
+		spanHb.spanWriter = spanHb.traceBuffer
+	}
+	if cOpts.WALDir != "" {
+		if spanHb.writeAheadLog, err = wal.Open(cOpts.WALDir, cOpts.WALSyncInterval, wal.DefaultMaxSegmentBytes, spanHb.logger); err != nil {
+			return nil, err
+		}
+	}
+
+	return spanHb, nil
+}
+
+// initSpanWriters creates the span writer of each storage type. When there are several, spans are
+// written to all of them and only the writes to the first, primary storage can fail.
+func (spanHb *SpanHandlerBuilder) initSpanWriters(storageTypes []string, options basicB.BasicOptions) (spanstore.Writer, error) {
+	if len(storageTypes) == 0 {
+		return nil, flags.ErrUnsupportedStorageType
+	}
+	writers := make(map[string]spanstore.Writer, len(storageTypes))
+	for _, storageType := range storageTypes {
+		if _, ok := writers[storageType]; ok {
+			return nil, errDuplicateStorageType
+		}
+		writer, err := spanHb.initSpanWriter(storageType, options)
+		if err != nil {
+			return nil, err
+		}
+		writers[storageType] = writer
+	}
+	primary := writers[storageTypes[0]]
+	if len(writers) == 1 {
+		return primary, nil
+	}
+	delete(writers, storageTypes[0])
+	return spanstore.NewFanoutWriter(primary, writers, spanHb.metricsFactory, spanHb.logger), nil
+}
+
+func (spanHb *SpanHandlerBuilder) initSpanWriter(storageType string, options basicB.BasicOptions) (spanstore.Writer, error) {
+	switch storageType {
+	case flags.CassandraStorageType:
 		if options.CassandraSessionBuilder == nil {
 			return nil, errMissingCassandraConfig
 		}
-		spanHb.spanWriter, err = spanHb.initCassStore(options.CassandraSessionBuilder)
-	} else if sFlags.SpanStorage.Type == flags.MemoryStorageType {
+		writerOpts, err := cassandraWriterOptions(options)
+		if err != nil {
+			return nil, err
+		}
+		writer, err := spanHb.initCassStore(options.CassandraSessionBuilder, writerOpts...)
+		if err != nil || len(options.CassandraTenantSessionBuilders) == 0 {
+			return writer, err
+		}
+		return spanHb.initCassTenantStores(
+			options.CassandraTenantTag,
+			options.CassandraTenantSessionBuilders,
+			writer,
+			writerOpts...,
+		)
+	case flags.MemoryStorageType:
 		if options.MemoryStore == nil {
 			return nil, errMissingMemoryStore
 		}
-		spanHb.spanWriter = options.MemoryStore
-	} else if sFlags.SpanStorage.Type == flags.ESStorageType {
+		return options.MemoryStore, nil
+	case flags.ESStorageType:
 		if options.ElasticClientBuilder == nil {
 			return nil, errMissingElasticSearchConfig
 		}
-		spanHb.spanWriter, err = spanHb.initElasticStore(options.ElasticClientBuilder)
-	} else {
+		return spanHb.initElasticStore(options.ElasticClientBuilder)
+	case flags.KafkaStorageType:
+		if options.KafkaProducerBuilder == nil {
+			return nil, errMissingKafkaConfig
+		}
+		return spanHb.initKafkaStore(options.KafkaProducerBuilder)
+	default:
 		return nil, flags.ErrUnsupportedStorageType
 	}
+}
 
-	if err != nil {
-		return nil, err
+// cassandraWriterOptions returns the options of the Cassandra span writers, with the span TTLs
+func cassandraWriterOptions(options basicB.BasicOptions) ([]casSpanstore.Option, error) {
+	if options.CassandraSpanTTL < 0 || (options.CassandraSpanTTL > 0 && options.CassandraSpanTTL < time.Second) {
+		return nil, errInvalidCassandraSpanTTL
 	}
-
-	return spanHb, nil
+	var serviceTTLs map[string]time.Duration
+	if options.CassandraServiceTTLFile != "" {
+		var err error
+		if serviceTTLs, err = casSpanstore.LoadServiceTTLs(options.CassandraServiceTTLFile); err != nil {
+			return nil, err
+		}
+	}
+	if options.CassandraSpanTTL == 0 && len(serviceTTLs) == 0 {
+		return nil, nil
+	}
+	return []casSpanstore.Option{casSpanstore.ServiceTTLs(options.CassandraSpanTTL, serviceTTLs)}, nil
 }
 
-func (spanHb *SpanHandlerBuilder) initCassStore(builder cascfg.SessionBuilder) (spanstore.Writer, error) {
-	session, err := builder.NewSession()
+func (spanHb *SpanHandlerBuilder) initCassStore(builder cascfg.SessionBuilder, writerOpts ...casSpanstore.Option) (spanstore.Writer, error) {
+	session, err := spanHb.newCassSession(builder, spanHb.logger)
 	if err != nil {
 		return nil, err
 	}
+	spanHb.cassandraSession = session
 
 	return casSpanstore.NewSpanWriter(
 		session,
 		spanHb.collectorOpts.WriteCacheTTL,
 		spanHb.metricsFactory,
 		spanHb.logger,
+		writerOpts...,
 	), nil
 }
 
+// initCassTenantStores creates a span writer for each tenant's session and routes spans to them by
+// the value of tag, falling back to primary for spans without a known tenant.
+func (spanHb *SpanHandlerBuilder) initCassTenantStores(
+	tag string,
+	builders map[string]cascfg.SessionBuilder,
+	primary spanstore.Writer,
+	writerOpts ...casSpanstore.Option,
+) (spanstore.Writer, error) {
+	writers := make(map[string]spanstore.Writer, len(builders))
+	for tenant, builder := range builders {
+		logger := spanHb.logger.With(zap.String("tenant", tenant))
+		session, err := spanHb.newCassSession(builder, logger)
+		if err != nil {
+			spanHb.closeCassTenantSessions()
+			return nil, err
+		}
+		spanHb.tenantSessions = append(spanHb.tenantSessions, session)
+		writers[tenant] = casSpanstore.NewSpanWriter(
+			session,
+			spanHb.collectorOpts.WriteCacheTTL,
+			spanHb.metricsFactory.Namespace("tenant-"+tenant, nil),
+			logger,
+			writerOpts...,
+		)
+	}
+	return spanstore.NewTagRoutingWriter(tag, writers, primary), nil
+}
+
+func (spanHb *SpanHandlerBuilder) closeCassTenantSessions() {
+	for _, session := range spanHb.tenantSessions {
+		session.Close()
+	}
+	spanHb.tenantSessions = nil
+}
+
+// newCassSession creates a session and checks its schema when the builder is a cascfg.SchemaChecker
+func (spanHb *SpanHandlerBuilder) newCassSession(builder cascfg.SessionBuilder, logger *zap.Logger) (cassandra.Session, error) {
+	session, err := builder.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	if checker, ok := builder.(cascfg.SchemaChecker); ok {
+		if err := checker.CheckSchema(session, logger); err != nil {
+			session.Close()
+			return nil, err
+		}
+	}
+	return session, nil
+}
+
 func (spanHb *SpanHandlerBuilder) initElasticStore(esBuilder escfg.ClientBuilder) (spanstore.Writer, error) {
+	switch esBuilder.GetIndexRotation() {
+	case "", escfg.IndexRotationDaily, escfg.IndexRotationMonthly:
+	default:
+		return nil, errUnsupportedIndexRotation
+	}
 	client, err := esBuilder.NewClient()
 	if err != nil {
 		return nil, err
 	}
+	spanHb.esClient = client
 
-	return esSpanstore.NewSpanWriter(
+	writer := esSpanstore.NewSpanWriter(
 		client,
 		spanHb.logger,
 		spanHb.metricsFactory,
 		esBuilder.GetNumShards(),
 		esBuilder.GetNumReplicas(),
+		esSpanstore.IndexPrefix(esBuilder.GetIndexPrefix()),
+		esSpanstore.IndexRotation(esBuilder.GetIndexRotation()),
+		esSpanstore.CreateTemplates(esBuilder.GetCreateIndexTemplates()),
+		esSpanstore.BulkProcessing(
+			esBuilder.GetBulkWorkers(),
+			esBuilder.GetBulkActions(),
+			esBuilder.GetBulkSize(),
+			esBuilder.GetBulkFlushInterval(),
+		),
+		esSpanstore.TraceIDRouting(esBuilder.GetUseTraceIDRouting()),
+	)
+	if esBuilder.GetCreateIndexTemplates() {
+		if err := writer.CreateTemplates(); err != nil {
+			return nil, err
+		}
+	}
+	spanHb.esWriter = writer
+	return writer, nil
+}
+
+func (spanHb *SpanHandlerBuilder) initKafkaStore(kafkaBuilder kafkacfg.ProducerBuilder) (spanstore.Writer, error) {
+	var marshaller kafkaSpanstore.Marshaller
+	switch kafkaBuilder.GetEncoding() {
+	case kafkacfg.EncodingJSON:
+		marshaller = kafkaSpanstore.NewJSONMarshaller()
+	case kafkacfg.EncodingThrift:
+		marshaller = kafkaSpanstore.NewThriftMarshaller()
+	case kafkacfg.EncodingProtobuf:
+		marshaller = kafkaSpanstore.NewProtobufMarshaller()
+	default:
+		return nil, errUnsupportedKafkaEncoding
+	}
+	marshaller, err := kafkaSpanstore.NewCompressingMarshaller(marshaller, spanHb.collectorOpts.StorageCompression, spanHb.metricsFactory)
+	if err != nil {
+		return nil, err
+	}
+	var writerOpts []kafkaSpanstore.Option
+	if kafkaBuilder.GetTopicTag() != "" {
+		if kafkaBuilder.GetMaxTopics() < 1 {
+			return nil, errInvalidKafkaMaxTopics
+		}
+		writerOpts = append(writerOpts, kafkaSpanstore.TopicTag(kafkaBuilder.GetTopicTag(), kafkaBuilder.GetMaxTopics()))
+	}
+
+	producer, err := kafkaBuilder.NewProducer()
+	if err != nil {
+		return nil, err
+	}
+
+	return kafkaSpanstore.NewSpanWriter(
+		producer,
+		marshaller,
+		kafkaBuilder.GetTopic(),
+		spanHb.metricsFactory,
+		spanHb.logger,
+		writerOpts...,
 	), nil
 }
 
+// writesAfterAck reports whether the span writer can return before the span is stored, in which case the
+// write-ahead log would delete the spans that are still to be written. Only the primary storage is checked
+// since the writes to the other storages can not fail.
+func writesAfterAck(cOpts *CollectorOptions, storageTypes []string, options basicB.BasicOptions) bool {
+	if cOpts.WriteRetries > 0 || cOpts.TraceBufferWindow > 0 || cOpts.StorageBufferSize > 0 {
+		return true
+	}
+	if cOpts.BenchmarkMode || cOpts.SpanStore != "" || len(storageTypes) == 0 {
+		return false
+	}
+	switch storageTypes[0] {
+	case flags.KafkaStorageType:
+		return true
+	case flags.ESStorageType:
+		return options.ElasticClientBuilder != nil && options.ElasticClientBuilder.GetBulkWorkers() > 0
+	}
+	return false
+}
+
+func hasStorageType(types []string, storageType string) bool {
+	for _, t := range types {
+		if t == storageType {
+			return true
+		}
+	}
+	return false
+}
+
+func (spanHb *SpanHandlerBuilder) initAdaptiveSampling() {
+	hostname, _ := os.Hostname()
+	store := casSamplingstore.New(spanHb.cassandraSession, spanHb.metricsFactory, spanHb.logger)
+	spanHb.samplingAggregator = adaptive.NewAggregator(
+		store,
+		spanHb.collectorOpts.SamplingAggregationInterval,
+		spanHb.logger,
+	)
+	spanHb.samplingProcessor = adaptive.NewProcessor(
+		store,
+		hostname,
+		spanHb.collectorOpts.SamplingTargetQPS,
+		spanHb.collectorOpts.SamplingAggregationInterval,
+		spanHb.logger,
+	)
+}
+
 // BuildHandlers builds span handlers (Zipkin, Jaeger)
 func (spanHb *SpanHandlerBuilder) BuildHandlers() (app.ZipkinSpansHandler, app.JaegerBatchesHandler) {
 	hostname, _ := os.Hostname()
 	hostMetrics := spanHb.metricsFactory.Namespace(hostname, nil)
 
-	zSanitizer := zs.NewChainedSanitizer(
-		zs.NewSpanDurationSanitizer(),
-		zs.NewSpanStartTimeSanitizer(),
-		zs.NewParentIDSanitizer(),
-		zs.NewErrorTagSanitizer(),
-	)
+	zSanitizer := zs.NewChainedSanitizer(zs.NewStandardSanitizers()...)
 
-	spanProcessor := app.NewSpanProcessor(
-		spanHb.spanWriter,
+	spanFilters := []app.FilterSpan{app.NewSpanValidator(spanHb.collectorOpts.MaxClockSkew, spanHb.clock, hostMetrics).Validate}
+	if spanHb.allowedServices != nil {
+		spanFilters = append(spanFilters, spanHb.allowedServices.Filter)
+	}
+	if spanHb.operationFilter != nil {
+		spanFilters = append(spanFilters, spanHb.operationFilter.Filter)
+	}
+	if spanHb.collectorOpts.MinSpanDuration > 0 {
+		spanFilters = append(spanFilters, app.NewDurationFilter(spanHb.collectorOpts.MinSpanDuration, spanHb.metricsFactory).Filter)
+	}
+	if spanHb.collectorOpts.DownsamplingRatio < 1 {
+		sampler := app.NewProbabilisticSampler(spanHb.collectorOpts.DownsamplingRatio, spanHb.collectorOpts.DownsamplingHashSalt)
+		spanFilters = append(spanFilters, app.NewDownsamplingFilter(sampler, spanHb.metricsFactory).Filter)
+	}
+	var requiredTags *app.RequiredTagsChecker
+	if len(spanHb.collectorOpts.RequiredProcessTags) > 0 {
+		requiredTags = app.NewRequiredTagsChecker(spanHb.collectorOpts.RequiredProcessTags, spanHb.metricsFactory)
+		if spanHb.collectorOpts.RequiredTagsPolicy == RequiredTagsPolicyDrop {
+			spanFilters = append(spanFilters, requiredTags.Filter)
+		}
+	}
+
+	var preProcessSpans []app.ProcessSpans
+	// the service names are normalized first, so that the spans are counted and filtered by the normalized names
+	if spanHb.collectorOpts.NormalizeServiceNames {
+		preProcessSpans = append(preProcessSpans, app.NewServiceNameNormalizer().ProcessSpans)
+	}
+	preProcessSpans = append(preProcessSpans, app.NewReceivedSpansCounter(spanHb.collectorOpts.MetricsMaxServices, spanHb.metricsFactory).ProcessSpans)
+	if spanHb.spanMirror != nil {
+		preProcessSpans = append(preProcessSpans, spanHb.spanMirror.ProcessSpans)
+	}
+	if spanHb.tagCardinality != nil {
+		preProcessSpans = append(preProcessSpans, spanHb.tagCardinality.ProcessSpans)
+	}
+	switch spanHb.collectorOpts.TimestampSource {
+	case TimestampSourceReceive:
+		preProcessSpans = append(preProcessSpans, app.NewReceiveTimeAdjuster(spanHb.clock).ProcessSpans)
+	case TimestampSourceClamp:
+		preProcessSpans = append(preProcessSpans, app.NewClockSkewClamp(spanHb.collectorOpts.MaxClockSkew, spanHb.clock).ProcessSpans)
+	}
+
+	spanFilter := app.ChainedFilterSpan(spanFilters...)
+	if spanHb.benchmark != nil {
+		spanFilter = spanHb.benchmark.SpanFilter(spanFilter)
+	}
+
+	processorOpts := []app.Option{
 		app.Options.ServiceMetrics(spanHb.metricsFactory),
 		app.Options.HostMetrics(hostMetrics),
 		app.Options.Logger(spanHb.logger),
-		app.Options.SpanFilter(defaultSpanFilter),
+		app.Options.PreProcessSpans(app.ChainedProcessSpans(preProcessSpans...)),
+		app.Options.SpanFilter(spanFilter),
 		app.Options.NumWorkers(spanHb.collectorOpts.NumWorkers),
 		app.Options.QueueSize(spanHb.collectorOpts.QueueSize),
-	)
+		app.Options.BlockingSubmit(spanHb.collectorOpts.QueueFullPolicy == QueueFullPolicyBlock),
+		app.Options.ShutdownTimeout(spanHb.collectorOpts.ShutdownTimeout),
+		app.Options.BackpressureThreshold(spanHb.collectorOpts.BackpressureThreshold),
+		app.Options.Clock(spanHb.clock),
+	}
+	if spanHb.writeAheadLog != nil {
+		processorOpts = append(processorOpts, app.Options.WriteAheadLog(spanHb.writeAheadLog))
+	}
+	var sanitizers []sanitizer.SanitizeSpan
+	// the limits apply to the tags and logs reported by the clients, not to the ones added by the collector
+	if spanHb.collectorOpts.MaxTagsPerSpan > 0 || spanHb.collectorOpts.MaxLogsPerSpan > 0 {
+		limiter := app.NewSpanEntryLimiter(spanHb.collectorOpts.MaxTagsPerSpan, spanHb.collectorOpts.MaxLogsPerSpan, spanHb.metricsFactory)
+		sanitizers = append(sanitizers, limiter.Sanitize)
+	}
+	if requiredTags != nil && spanHb.collectorOpts.RequiredTagsPolicy != RequiredTagsPolicyDrop {
+		sanitizers = append(sanitizers, requiredTags.Sanitize)
+	}
+	if len(spanHb.tagRules) > 0 {
+		sanitizers = append(sanitizers, sanitizer.NewTagRulesSanitizer(spanHb.tagRules))
+	}
+	if len(spanHb.collectorOpts.BaggageToTagKeys) > 0 {
+		sanitizers = append(sanitizers, sanitizer.NewBaggageSanitizer(spanHb.collectorOpts.BaggageToTagKeys))
+	}
+	if spanHb.collectorOpts.OperationNameTag != "" {
+		sanitizers = append(sanitizers, sanitizer.NewOperationNameSanitizer(spanHb.collectorOpts.OperationNameTag))
+	}
+	if spanHb.collectorOpts.MaxLogBytesPerSpan > 0 {
+		sanitizers = append(sanitizers, sanitizer.NewLogTruncationSanitizer(spanHb.collectorOpts.MaxLogBytesPerSpan))
+	}
+	if spanHb.collectorOpts.EnrichDNS {
+		dnsEnricher := sanitizer.NewDNSEnricher(net.DefaultResolver, spanHb.collectorOpts.EnrichDNSCacheSize, spanHb.collectorOpts.EnrichDNSTimeout)
+		sanitizers = append(sanitizers, dnsEnricher.Sanitize)
+	}
+	if spanHb.collectorOpts.TagSpansWithHost {
+		sanitizers = append(sanitizers, sanitizer.NewHostTagSanitizer(hostname))
+	}
+	if len(sanitizers) > 0 {
+		processorOpts = append(processorOpts, app.Options.Sanitizer(sanitizer.NewChainedSanitizer(sanitizers...)))
+	}
+	var preSave []app.ProcessSpan
+	if spanHb.collectorOpts.DownsamplingRatio < 1 {
+		preSave = append(preSave, app.NewSamplingRateTagger(spanHb.collectorOpts.DownsamplingRatio))
+	}
+	if spanHb.samplingAggregator != nil {
+		preSave = append(preSave, spanHb.samplingAggregator.RecordSpan)
+		spanHb.samplingAggregator.Start()
+		spanHb.samplingProcessor.Start()
+	}
+	if spanHb.collectorOpts.EmitOperationLatencies {
+		latencies := app.NewOperationLatencies(spanHb.collectorOpts.MetricsMaxOperations, spanHb.metricsFactory)
+		preSave = append(preSave, latencies.RecordSpan)
+	}
+	if len(preSave) > 0 {
+		processorOpts = append(processorOpts, app.Options.PreSave(app.ChainedProcessSpan(preSave...)))
+	}
+	spanHb.spanProcessor = app.NewSpanProcessor(spanHb.spanWriter, processorOpts...)
+	spanProcessor := spanHb.spanProcessor
+	if spanHb.collectorOpts.DedupWindow > 0 {
+		dedup := app.NewSpanDeduplicator(spanHb.collectorOpts.DedupWindow, spanHb.collectorOpts.DedupMaxSpans, spanHb.clock, spanHb.metricsFactory)
+		spanProcessor = dedup.SpanProcessor(spanProcessor)
+	}
+	if spanHb.selfTracer != nil {
+		spanProcessor = spanHb.selfTracer.SpanProcessor(spanProcessor)
+	}
+	spanHb.apiProcessor = spanProcessor
+
+	var zipkinSpansHandler app.ZipkinSpansHandler
+	var jaegerBatchesHandler app.JaegerBatchesHandler
+	if spanHb.benchmark != nil {
+		zipkinSpansHandler = spanHb.benchmark.ZipkinSpanHandler(spanHb.logger, spanProcessor, zSanitizer)
+		jaegerBatchesHandler = spanHb.benchmark.JaegerSpanHandler(spanHb.logger, spanProcessor)
+	} else {
+		zipkinSpansHandler = app.NewZipkinSpanHandler(spanHb.logger, spanProcessor, zSanitizer)
+		jaegerBatchesHandler = app.NewJaegerSpanHandler(spanHb.logger, spanProcessor)
+	}
+	if spanHb.collectorOpts.MaxSpansPerBatch > 0 {
+		limiter := app.NewBatchLimiter(spanHb.collectorOpts.MaxSpansPerBatch, spanHb.metricsFactory)
+		zipkinSpansHandler = limiter.ZipkinSpansHandler(zipkinSpansHandler)
+		jaegerBatchesHandler = limiter.JaegerBatchesHandler(jaegerBatchesHandler)
+		spanHb.apiProcessor = limiter.SpanProcessor(spanProcessor)
+	}
+	return zipkinSpansHandler, jaegerBatchesHandler
+}
+
+// SpanProcessor returns the processor for spans decoded by the HTTP API, subject to the same
+// limits as the span handlers. It is nil until BuildHandlers is called.
+func (spanHb *SpanHandlerBuilder) SpanProcessor() app.SpanProcessor {
+	return spanHb.apiProcessor
+}
+
+// StorageProbe returns a function that checks whether the span storage is reachable, or nil if
+// the storage cannot be checked. When spans are buffered while the storage is down, it reports
+// the state of the storage as last pinged by the buffering writer.
+func (spanHb *SpanHandlerBuilder) StorageProbe() func() error {
+	if spanHb.healthWriter != nil {
+		return spanHb.healthWriter.Probe
+	}
+	return spanHb.storagePing()
+}
+
+// storagePing returns a function that queries the span storage, or nil if the storage cannot be queried.
+func (spanHb *SpanHandlerBuilder) storagePing() func() error {
+	if spanHb.cassandraSession != nil {
+		return func() error {
+			return spanHb.cassandraSession.Query(cassandraProbeQuery).Exec()
+		}
+	}
+	if spanHb.esClient != nil {
+		return func() error {
+			ctx, cancel := context.WithTimeout(context.Background(), storageProbeTimeout)
+			defer cancel()
+			_, err := spanHb.esClient.IndexExists(esProbeIndex).Do(ctx)
+			return err
+		}
+	}
+	return nil
+}
+
+// SamplingManager returns the handler serving sampling strategies to agents, or nil if the
+// collector is not configured to compute sampling strategies.
+func (spanHb *SpanHandlerBuilder) SamplingManager() sampling.TChanSamplingManager {
+	if spanHb.samplingProcessor != nil {
+		return spanHb.samplingProcessor
+	}
+	if spanHb.staticStrategies != nil {
+		return spanHb.staticStrategies
+	}
+	return nil
+}
 
-	return app.NewZipkinSpanHandler(spanHb.logger, spanProcessor, zSanitizer),
-		app.NewJaegerSpanHandler(spanHb.logger, spanProcessor)
+// StaticStrategies returns the store of the sampling strategies read from the strategies file, or nil if
+// the collector is not configured with one.
+func (spanHb *SpanHandlerBuilder) StaticStrategies() *static.Store {
+	return spanHb.staticStrategies
 }
 
-func defaultSpanFilter(*model.Span) bool {
-	return true
+// Flush writes the spans held by the trace buffer, then sends the spans waiting for an ElasticSearch bulk
+// request and waits until it is done. It returns the number of spans flushed by each of them, keyed by
+// trace-buffer when the trace buffer is enabled and elasticsearch-bulk when spans are written to
+// ElasticSearch. The spans still queued for the span processor are not flushed.
+func (spanHb *SpanHandlerBuilder) Flush() map[string]int {
+	flushed := make(map[string]int)
+	if spanHb.traceBuffer != nil {
+		flushed["trace-buffer"] = spanHb.traceBuffer.Flush()
+	}
+	if spanHb.esWriter != nil {
+		flushed["elasticsearch-bulk"] = spanHb.esWriter.Flush()
+	}
+	return flushed
+}
+
+// shutdowner is implemented by the span processors that can drain their queue until the deadline of a context
+type shutdowner interface {
+	Shutdown(ctx context.Context) error
+}
+
+// Close drains the span processor created by BuildHandlers, closes the write-ahead log and closes the span
+// writer if it supports it. The span handlers must not be used after Close is called.
+func (spanHb *SpanHandlerBuilder) Close() error {
+	return spanHb.close(func(processor app.SpanProcessor) error {
+		return processor.Close()
+	})
+}
+
+// Shutdown is like Close, but the span processor drains its queue until ctx is done instead of for the
+// shutdown timeout, so that the time spent stopping the listeners is not waited for a second time.
+func (spanHb *SpanHandlerBuilder) Shutdown(ctx context.Context) error {
+	return spanHb.close(func(processor app.SpanProcessor) error {
+		if s, ok := processor.(shutdowner); ok {
+			return s.Shutdown(ctx)
+		}
+		return processor.Close()
+	})
+}
+
+func (spanHb *SpanHandlerBuilder) close(closeProcessor func(app.SpanProcessor) error) error {
+	var errors []error
+	if spanHb.spanProcessor != nil {
+		if err := closeProcessor(spanHb.spanProcessor); err != nil {
+			errors = append(errors, err)
+		}
+	}
+	if spanHb.writeAheadLog != nil {
+		if err := spanHb.writeAheadLog.Close(); err != nil {
+			errors = append(errors, err)
+		}
+	}
+	if spanHb.samplingAggregator != nil {
+		spanHb.samplingAggregator.Close()
+		spanHb.samplingProcessor.Close()
+	}
+	if spanHb.staticStrategies != nil {
+		spanHb.staticStrategies.Close()
+	}
+	if spanHb.allowedServices != nil {
+		spanHb.allowedServices.Close()
+	}
+	if spanHb.spanMirror != nil {
+		if err := spanHb.spanMirror.Close(); err != nil {
+			errors = append(errors, err)
+		}
+	}
+	if spanHb.tagCardinality != nil {
+		spanHb.tagCardinality.Close()
+	}
+	if closer, ok := spanHb.spanWriter.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			errors = append(errors, err)
+		}
+	}
+	spanHb.closeCassTenantSessions()
+	if spanHb.benchmark != nil {
+		// the queue has been drained, so the summary includes all the spans received
+		spanHb.benchmark.LogSummary(spanHb.logger)
+	}
+	return multierror.Wrap(errors)
 }
@@ -15,15 +15,39 @@
 package builder
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/Shopify/sarama"
+	saramaMocks "github.com/Shopify/sarama/mocks"
+	"github.com/opentracing/opentracing-go/mocktracer"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"github.com/uber/jaeger-lib/metrics"
+	metricsTest "github.com/uber/jaeger-lib/metrics/testutils"
+	tchanThrift "github.com/uber/tchannel-go/thrift"
 	"go.uber.org/zap"
 
 	"github.com/uber/jaeger/cmd/builder"
+	"github.com/uber/jaeger/cmd/collector/app"
+	"github.com/uber/jaeger/cmd/collector/app/sanitizer"
+	"github.com/uber/jaeger/cmd/collector/app/wal"
 	"github.com/uber/jaeger/cmd/flags"
+	"github.com/uber/jaeger/model"
 	"github.com/uber/jaeger/pkg/cassandra"
 	cascfg "github.com/uber/jaeger/pkg/cassandra/config"
 	"github.com/uber/jaeger/pkg/cassandra/mocks"
@@ -31,9 +55,17 @@ import (
 	"github.com/uber/jaeger/pkg/es"
 	escfg "github.com/uber/jaeger/pkg/es/config"
 	esMocks "github.com/uber/jaeger/pkg/es/mocks"
+	kafkacfg "github.com/uber/jaeger/pkg/kafka/config"
+	pMetrics "github.com/uber/jaeger/pkg/metrics"
+	"github.com/uber/jaeger/pkg/testutils"
+	"github.com/uber/jaeger/storage/spanstore"
 	"github.com/uber/jaeger/storage/spanstore/memory"
+	"github.com/uber/jaeger/thrift-gen/jaeger"
+	"github.com/uber/jaeger/thrift-gen/zipkincore"
 )
 
+const serviceTTLsFixture = "../../../../plugin/storage/cassandra/spanstore/fixtures/service_ttls.json"
+
 type mockSessionBuilder struct {
 }
 
@@ -41,6 +73,19 @@ func (*mockSessionBuilder) NewSession() (cassandra.Session, error) {
 	return &mocks.Session{}, nil
 }
 
+type mockSchemaCheckingSessionBuilder struct {
+	session *mocks.Session
+	err     error
+}
+
+func (mck *mockSchemaCheckingSessionBuilder) NewSession() (cassandra.Session, error) {
+	return mck.session, nil
+}
+
+func (mck *mockSchemaCheckingSessionBuilder) CheckSchema(session cassandra.Session, logger *zap.Logger) error {
+	return mck.err
+}
+
 type mockEsBuilder struct {
 	escfg.Configuration
 }
@@ -49,6 +94,15 @@ func (mck *mockEsBuilder) NewClient() (es.Client, error) {
 	return &esMocks.Client{}, nil
 }
 
+type mockKafkaBuilder struct {
+	kafkacfg.Configuration
+	t *testing.T
+}
+
+func (mck *mockKafkaBuilder) NewProducer() (sarama.AsyncProducer, error) {
+	return saramaMocks.NewAsyncProducer(mck.t, nil), nil
+}
+
 func TestNewSpanHandlerBuilder(t *testing.T) {
 	v, command := config.Viperize(flags.AddFlags)
 
@@ -68,6 +122,21 @@ func TestNewSpanHandlerBuilder(t *testing.T) {
 	zipkin, jaeger := handler.BuildHandlers()
 	assert.NotNil(t, zipkin)
 	assert.NotNil(t, jaeger)
+	assert.NoError(t, handler.Close())
+}
+
+func TestSpanHandlerBuilderShutdown(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--span-storage.type=memory"})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+
+	handler, err := NewSpanHandlerBuilder(cOpts, sFlags, builder.Options.MemoryStoreOption(memory.NewStore()))
+	require.NoError(t, err)
+	handler.BuildHandlers()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, handler.Shutdown(ctx))
 }
 
 func TestNewSpanHandlerBuilderCassandraNoSession(t *testing.T) {
@@ -153,6 +222,41 @@ func TestNewSpanHandlerBuilderElasticSearch(t *testing.T) {
 	assert.NotNil(t, jaeger)
 }
 
+func TestNewSpanHandlerBuilderMultipleStorageTypes(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--span-storage.type=cassandra,elasticsearch"})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+
+	handler, err := NewSpanHandlerBuilder(
+		cOpts,
+		sFlags,
+		builder.Options.LoggerOption(zap.NewNop()),
+		builder.Options.CassandraSessionOption(&mockSessionBuilder{}),
+		builder.Options.ElasticClientOption(&mockEsBuilder{}),
+	)
+	require.NoError(t, err)
+	assert.IsType(t, &spanstore.FanoutWriter{}, handler.spanWriter)
+	assert.NotNil(t, handler.cassandraSession)
+	assert.NotNil(t, handler.esClient)
+
+	for _, storageType := range []string{"cassandra,elasticsearch,cassandra", "cassandra,sneh", "cassandra,kafka"} {
+		v, command := config.Viperize(AddFlags, flags.AddFlags)
+		command.ParseFlags([]string{"test", "--span-storage.type=" + storageType})
+		sFlags := new(flags.SharedFlags).InitFromViper(v)
+		cOpts := new(CollectorOptions).InitFromViper(v)
+
+		handler, err := NewSpanHandlerBuilder(
+			cOpts,
+			sFlags,
+			builder.Options.CassandraSessionOption(&mockSessionBuilder{}),
+			builder.Options.ElasticClientOption(&mockEsBuilder{}),
+		)
+		assert.Error(t, err, storageType)
+		assert.Nil(t, handler, storageType)
+	}
+}
+
 func TestNewSpanHandlerBuilderElasticSearchNoClient(t *testing.T) {
 	v, command := config.Viperize(AddFlags, flags.AddFlags)
 	command.ParseFlags([]string{"test", "--span-storage.type=elasticsearch"})
@@ -169,6 +273,22 @@ func TestNewSpanHandlerBuilderElasticSearchNoClient(t *testing.T) {
 	assert.Nil(t, handler)
 }
 
+func TestNewSpanHandlerBuilderElasticSearchBadIndexRotation(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--span-storage.type=elasticsearch"})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+
+	handler, err := NewSpanHandlerBuilder(
+		cOpts,
+		sFlags,
+		builder.Options.LoggerOption(zap.NewNop()),
+		builder.Options.ElasticClientOption(&mockEsBuilder{Configuration: escfg.Configuration{IndexRotation: "hourly"}}),
+	)
+	assert.Equal(t, errUnsupportedIndexRotation, err)
+	assert.Nil(t, handler)
+}
+
 func TestNewSpanHandlerBuilderElasticSearchFailure(t *testing.T) {
 	v, command := config.Viperize(AddFlags, flags.AddFlags)
 	command.ParseFlags([]string{"test", "--span-storage.type=elasticsearch"})
@@ -179,6 +299,2229 @@ func TestNewSpanHandlerBuilderElasticSearchFailure(t *testing.T) {
 	assert.Nil(t, handler)
 }
 
-func TestDefaultSpanFilter(t *testing.T) {
-	assert.True(t, defaultSpanFilter(nil))
+func TestNewSpanHandlerBuilderQueueFullPolicy(t *testing.T) {
+	testCases := []struct {
+		policy string
+		err    error
+	}{
+		{policy: QueueFullPolicyBlock},
+		{policy: QueueFullPolicyDrop},
+		{policy: "sneh", err: errUnsupportedQueueFullPolicy},
+	}
+	for _, tc := range testCases {
+		v, command := config.Viperize(AddFlags, flags.AddFlags)
+		command.ParseFlags([]string{"test", "--span-storage.type=memory", "--collector.queue-full-policy=" + tc.policy})
+		sFlags := new(flags.SharedFlags).InitFromViper(v)
+		cOpts := new(CollectorOptions).InitFromViper(v)
+		assert.Equal(t, tc.policy, cOpts.QueueFullPolicy)
+
+		handler, err := NewSpanHandlerBuilder(cOpts, sFlags, builder.Options.MemoryStoreOption(memory.NewStore()))
+		if tc.err != nil {
+			assert.Equal(t, tc.err, err)
+			assert.Nil(t, handler)
+		} else {
+			require.NoError(t, err)
+			assert.NotNil(t, handler)
+		}
+	}
+}
+
+func TestNewSpanHandlerBuilderKafka(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--span-storage.type=kafka"})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+
+	handler, err := NewSpanHandlerBuilder(
+		cOpts,
+		sFlags,
+		builder.Options.LoggerOption(zap.NewNop()),
+		builder.Options.KafkaProducerOption(&mockKafkaBuilder{
+			Configuration: kafkacfg.Configuration{Topic: "jaeger-spans", Encoding: kafkacfg.EncodingThrift},
+			t:             t,
+		}),
+	)
+	require.NoError(t, err)
+	assert.NotNil(t, handler)
+	zipkin, jaeger := handler.BuildHandlers()
+	assert.NotNil(t, zipkin)
+	assert.NotNil(t, jaeger)
+	assert.NoError(t, handler.Close())
+}
+
+func TestNewSpanHandlerBuilderKafkaProtobuf(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--span-storage.type=kafka"})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+
+	handler, err := NewSpanHandlerBuilder(
+		cOpts,
+		sFlags,
+		builder.Options.KafkaProducerOption(&mockKafkaBuilder{
+			Configuration: kafkacfg.Configuration{Topic: "jaeger-spans", Encoding: kafkacfg.EncodingProtobuf},
+			t:             t,
+		}),
+	)
+	require.NoError(t, err)
+	assert.NoError(t, handler.Close())
+}
+
+func TestNewSpanHandlerBuilderKafkaCompression(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--span-storage.type=kafka", "--collector.storage.compression=gzip"})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+	assert.Equal(t, "gzip", cOpts.StorageCompression)
+
+	handler, err := NewSpanHandlerBuilder(
+		cOpts,
+		sFlags,
+		builder.Options.LoggerOption(zap.NewNop()),
+		builder.Options.KafkaProducerOption(&mockKafkaBuilder{
+			Configuration: kafkacfg.Configuration{Topic: "jaeger-spans", Encoding: kafkacfg.EncodingJSON},
+			t:             t,
+		}),
+	)
+	require.NoError(t, err)
+	assert.NoError(t, handler.Close())
+
+	cOpts.StorageCompression = "zstd"
+	_, err = NewSpanHandlerBuilder(
+		cOpts,
+		sFlags,
+		builder.Options.KafkaProducerOption(&mockKafkaBuilder{
+			Configuration: kafkacfg.Configuration{Topic: "jaeger-spans", Encoding: kafkacfg.EncodingJSON},
+			t:             t,
+		}),
+	)
+	assert.EqualError(t, err, `unsupported compression "zstd", options are [none gzip]`)
+}
+
+func TestNewSpanHandlerBuilderKafkaTopicTag(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--span-storage.type=kafka"})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+
+	handler, err := NewSpanHandlerBuilder(
+		cOpts,
+		sFlags,
+		builder.Options.KafkaProducerOption(&mockKafkaBuilder{
+			Configuration: kafkacfg.Configuration{
+				Topic:     "jaeger-spans",
+				Encoding:  kafkacfg.EncodingJSON,
+				TopicTag:  "tenant",
+				MaxTopics: 10,
+			},
+			t: t,
+		}),
+	)
+	require.NoError(t, err)
+	assert.NoError(t, handler.Close())
+
+	handler, err = NewSpanHandlerBuilder(
+		cOpts,
+		sFlags,
+		builder.Options.KafkaProducerOption(&mockKafkaBuilder{
+			Configuration: kafkacfg.Configuration{
+				Topic:    "jaeger-spans",
+				Encoding: kafkacfg.EncodingJSON,
+				TopicTag: "tenant",
+			},
+			t: t,
+		}),
+	)
+	assert.Equal(t, errInvalidKafkaMaxTopics, err)
+	assert.Nil(t, handler)
+}
+
+func TestNewSpanHandlerBuilderCompressionWithoutKafka(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--span-storage.type=memory", "--collector.storage.compression=gzip"})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+
+	_, err := NewSpanHandlerBuilder(cOpts, sFlags, builder.Options.MemoryStoreOption(memory.NewStore()))
+	assert.Equal(t, errStorageCompressionKafka, err)
+}
+
+func TestNewSpanHandlerBuilderKafkaBadEncoding(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--span-storage.type=kafka"})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+
+	handler, err := NewSpanHandlerBuilder(
+		cOpts,
+		sFlags,
+		builder.Options.KafkaProducerOption(&mockKafkaBuilder{
+			Configuration: kafkacfg.Configuration{Encoding: "sneh"},
+			t:             t,
+		}),
+	)
+	assert.Equal(t, errUnsupportedKafkaEncoding, err)
+	assert.Nil(t, handler)
+}
+
+func TestNewSpanHandlerBuilderKafkaFailure(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--span-storage.type=kafka"})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+	handler, err := NewSpanHandlerBuilder(cOpts, sFlags)
+	assert.EqualError(t, err, "Kafka not configured")
+	assert.Nil(t, handler)
+}
+
+func TestNewSpanHandlerBuilderSamplingStrategy(t *testing.T) {
+	testCases := []struct {
+		storage  string
+		strategy string
+		err      error
+	}{
+		{storage: "cassandra", strategy: SamplingStrategyNone},
+		{storage: "cassandra", strategy: SamplingStrategyAdaptive},
+		{storage: "memory", strategy: SamplingStrategyAdaptive, err: errAdaptiveSamplingStorage},
+		{storage: "cassandra", strategy: "sneh", err: errUnsupportedSamplingStrategy},
+	}
+	for _, tc := range testCases {
+		v, command := config.Viperize(AddFlags, flags.AddFlags)
+		command.ParseFlags([]string{"test", "--span-storage.type=" + tc.storage, "--sampling.strategy=" + tc.strategy})
+		sFlags := new(flags.SharedFlags).InitFromViper(v)
+		cOpts := new(CollectorOptions).InitFromViper(v)
+
+		handler, err := NewSpanHandlerBuilder(
+			cOpts,
+			sFlags,
+			builder.Options.LoggerOption(zap.NewNop()),
+			builder.Options.MetricsFactoryOption(metrics.NullFactory),
+			builder.Options.CassandraSessionOption(&mockSessionBuilder{}),
+			builder.Options.MemoryStoreOption(memory.NewStore()),
+		)
+		if tc.err != nil {
+			assert.Equal(t, tc.err, err)
+			assert.Nil(t, handler)
+			continue
+		}
+		require.NoError(t, err)
+		if tc.strategy == SamplingStrategyAdaptive {
+			assert.NotNil(t, handler.SamplingManager())
+		} else {
+			assert.Nil(t, handler.SamplingManager())
+		}
+		assert.NoError(t, handler.Close())
+	}
+}
+
+func TestSpanHandlerBuilderStorageProbe(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test"})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+
+	handler, err := NewSpanHandlerBuilder(cOpts, sFlags, builder.Options.CassandraSessionOption(&mockSessionBuilder{}))
+	require.NoError(t, err)
+	query := &mocks.Query{}
+	query.On("Exec").Return(nil).Once()
+	query.On("Exec").Return(errors.New("no hosts available"))
+	handler.cassandraSession.(*mocks.Session).On("Query", cassandraProbeQuery, mock.Anything).Return(query)
+	probe := handler.StorageProbe()
+	require.NotNil(t, probe)
+	assert.NoError(t, probe())
+	assert.EqualError(t, probe(), "no hosts available")
+
+	command.ParseFlags([]string{"test", "--span-storage.type=elasticsearch"})
+	sFlags = new(flags.SharedFlags).InitFromViper(v)
+	handler, err = NewSpanHandlerBuilder(cOpts, sFlags, builder.Options.ElasticClientOption(&mockEsBuilder{}))
+	require.NoError(t, err)
+	existsService := &esMocks.IndicesExistsService{}
+	existsService.On("Do", mock.Anything).Return(false, errors.New("connection refused"))
+	handler.esClient.(*esMocks.Client).On("IndexExists", esProbeIndex).Return(existsService)
+	probe = handler.StorageProbe()
+	require.NotNil(t, probe)
+	assert.EqualError(t, probe(), "connection refused")
+
+	command.ParseFlags([]string{"test", "--span-storage.type=memory"})
+	sFlags = new(flags.SharedFlags).InitFromViper(v)
+	handler, err = NewSpanHandlerBuilder(cOpts, sFlags, builder.Options.MemoryStoreOption(memory.NewStore()))
+	require.NoError(t, err)
+	assert.Nil(t, handler.StorageProbe())
+}
+
+func TestNewSpanHandlerBuilderStorageBuffer(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--collector.storage-buffer-size=100", "--collector.health-check-probe-interval=1h"})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+	assert.Equal(t, 100, cOpts.StorageBufferSize)
+
+	handler, err := NewSpanHandlerBuilder(cOpts, sFlags, builder.Options.CassandraSessionOption(&mockSessionBuilder{}))
+	require.NoError(t, err)
+	require.NotNil(t, handler.healthWriter)
+	probe := handler.StorageProbe()
+	require.NotNil(t, probe)
+	assert.NoError(t, probe(), "the probe reports the state last pinged by the buffering writer")
+
+	command.ParseFlags([]string{"test", "--span-storage.type=memory"})
+	sFlags = new(flags.SharedFlags).InitFromViper(v)
+	handler, err = NewSpanHandlerBuilder(cOpts, sFlags, builder.Options.MemoryStoreOption(memory.NewStore()))
+	assert.Equal(t, errStorageBufferProbe, err)
+	assert.Nil(t, handler)
+
+	command.ParseFlags([]string{"test", "--span-storage.type=cassandra", "--collector.health-check-probe-interval=0s"})
+	sFlags = new(flags.SharedFlags).InitFromViper(v)
+	cOpts = new(CollectorOptions).InitFromViper(v)
+	handler, err = NewSpanHandlerBuilder(cOpts, sFlags, builder.Options.CassandraSessionOption(&mockSessionBuilder{}))
+	assert.Equal(t, errInvalidProbeInterval, err)
+	assert.Nil(t, handler)
+}
+
+func TestNewSpanHandlerBuilderStrategiesFile(t *testing.T) {
+	testCases := []struct {
+		file     string
+		strategy string
+		err      string
+	}{
+		{file: "../sampling/static/fixtures/strategies.json"},
+		{file: "../sampling/static/fixtures/strategies.json", strategy: SamplingStrategyAdaptive, err: errAdaptiveSamplingStaticFile.Error()},
+		{file: "../sampling/static/fixtures/missing.json", err: "stat ../sampling/static/fixtures/missing.json: no such file or directory"},
+	}
+	for _, tc := range testCases {
+		v, command := config.Viperize(AddFlags, flags.AddFlags)
+		command.ParseFlags([]string{"test", "--sampling.strategies-file=" + tc.file, "--sampling.strategy=" + tc.strategy})
+		sFlags := new(flags.SharedFlags).InitFromViper(v)
+		cOpts := new(CollectorOptions).InitFromViper(v)
+
+		handler, err := NewSpanHandlerBuilder(cOpts, sFlags, builder.Options.CassandraSessionOption(&mockSessionBuilder{}))
+		if tc.err != "" {
+			assert.EqualError(t, err, tc.err)
+			assert.Nil(t, handler)
+			continue
+		}
+		require.NoError(t, err)
+		samplingManager := handler.SamplingManager()
+		require.NotNil(t, samplingManager)
+		strategy, err := samplingManager.GetSamplingStrategy(nil, "bar")
+		require.NoError(t, err)
+		assert.EqualValues(t, 5, strategy.RateLimitingSampling.MaxTracesPerSecond)
+		assert.NoError(t, handler.Close())
+	}
+}
+
+func TestNewSpanHandlerBuilderCassandraSpanTTL(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test"})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+
+	handler, err := NewSpanHandlerBuilder(
+		cOpts,
+		sFlags,
+		builder.Options.CassandraSessionOption(&mockSessionBuilder{}),
+		builder.Options.CassandraSpanTTLOption(48*time.Hour, serviceTTLsFixture),
+	)
+	require.NoError(t, err)
+	assert.NotNil(t, handler)
+
+	handler, err = NewSpanHandlerBuilder(
+		cOpts,
+		sFlags,
+		builder.Options.CassandraSessionOption(&mockSessionBuilder{}),
+		builder.Options.CassandraSpanTTLOption(time.Millisecond, ""),
+	)
+	assert.Equal(t, errInvalidCassandraSpanTTL, err)
+	assert.Nil(t, handler)
+}
+
+func TestCassandraWriterOptions(t *testing.T) {
+	testCases := []struct {
+		caption        string
+		defaultTTL     time.Duration
+		serviceTTLFile string
+		numOptions     int
+		err            bool
+	}{
+		{caption: "no TTL"},
+		{caption: "default TTL", defaultTTL: time.Hour, numOptions: 1},
+		{caption: "service TTLs", serviceTTLFile: serviceTTLsFixture, numOptions: 1},
+		{caption: "negative TTL", defaultTTL: -time.Hour, err: true},
+		{caption: "TTL under a second", defaultTTL: time.Millisecond, err: true},
+		{caption: "missing file", serviceTTLFile: "missing.json", err: true},
+	}
+	for _, tc := range testCases {
+		writerOpts, err := cassandraWriterOptions(builder.ApplyOptions(builder.Options.CassandraSpanTTLOption(tc.defaultTTL, tc.serviceTTLFile)))
+		if tc.err {
+			assert.Error(t, err, tc.caption)
+		} else {
+			require.NoError(t, err, tc.caption)
+			assert.Len(t, writerOpts, tc.numOptions, tc.caption)
+		}
+	}
+}
+
+// closingSessionBuilder creates sessions that expect to be closed
+type closingSessionBuilder struct {
+	sessions []*mocks.Session
+}
+
+func (b *closingSessionBuilder) NewSession() (cassandra.Session, error) {
+	session := &mocks.Session{}
+	session.On("Close").Return()
+	b.sessions = append(b.sessions, session)
+	return session, nil
+}
+
+func TestNewSpanHandlerBuilderCassandraTenants(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test"})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+
+	handler, err := NewSpanHandlerBuilder(
+		cOpts,
+		sFlags,
+		builder.Options.MetricsFactoryOption(metrics.NewLocalFactory(0)),
+		builder.Options.CassandraSessionOption(&mockSessionBuilder{}),
+		builder.Options.CassandraTenantsOption("tenant", map[string]cascfg.SessionBuilder{
+			"acme":    &mockSessionBuilder{},
+			"initech": &mockSessionBuilder{},
+		}),
+	)
+	require.NoError(t, err)
+	assert.IsType(t, &spanstore.TagRoutingWriter{}, handler.spanWriter)
+
+	handler, err = NewSpanHandlerBuilder(
+		cOpts,
+		sFlags,
+		builder.Options.CassandraSessionOption(&mockSessionBuilder{}),
+		builder.Options.CassandraTenantsOption("tenant", map[string]cascfg.SessionBuilder{
+			"acme": &cascfg.Configuration{},
+		}),
+	)
+	assert.Error(t, err)
+	assert.Nil(t, handler)
+}
+
+func TestSpanHandlerBuilderClosesCassandraTenantSessions(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test"})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+
+	tenants := &closingSessionBuilder{}
+	handler, err := NewSpanHandlerBuilder(
+		cOpts,
+		sFlags,
+		builder.Options.CassandraSessionOption(&mockSessionBuilder{}),
+		builder.Options.CassandraTenantsOption("tenant", map[string]cascfg.SessionBuilder{
+			"acme":    tenants,
+			"initech": tenants,
+		}),
+	)
+	require.NoError(t, err)
+	require.Len(t, tenants.sessions, 2)
+	require.NoError(t, handler.Close())
+	for _, session := range tenants.sessions {
+		session.AssertExpectations(t)
+	}
+
+	// the sessions opened before a tenant fails are closed, whichever order the tenants are opened in
+	tenants = &closingSessionBuilder{}
+	_, err = NewSpanHandlerBuilder(
+		cOpts,
+		sFlags,
+		builder.Options.CassandraSessionOption(&mockSessionBuilder{}),
+		builder.Options.CassandraTenantsOption("tenant", map[string]cascfg.SessionBuilder{
+			"acme":    tenants,
+			"initech": tenants,
+			"hooli":   &cascfg.Configuration{},
+		}),
+	)
+	assert.Error(t, err)
+	for _, session := range tenants.sessions {
+		session.AssertExpectations(t)
+	}
+}
+
+func TestNewSpanHandlerBuilderCassandraSchemaCheck(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+
+	session := &mocks.Session{}
+	handler, err := NewSpanHandlerBuilder(
+		cOpts,
+		sFlags,
+		builder.Options.CassandraSessionOption(&mockSchemaCheckingSessionBuilder{session: session}),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, session, handler.cassandraSession)
+
+	session = &mocks.Session{}
+	session.On("Close").Return()
+	handler, err = NewSpanHandlerBuilder(
+		cOpts,
+		sFlags,
+		builder.Options.CassandraSessionOption(&mockSchemaCheckingSessionBuilder{
+			session: session,
+			err:     errors.New("Cassandra keyspace jaeger_v1_local is missing tables traces"),
+		}),
+	)
+	assert.EqualError(t, err, "Cassandra keyspace jaeger_v1_local is missing tables traces")
+	assert.Nil(t, handler)
+	session.AssertCalled(t, "Close")
+}
+
+func TestNewSpanHandlerBuilderMaxSpansPerBatch(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--span-storage.type=memory", "--collector.max-spans-per-batch=1"})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+	assert.Equal(t, 1, cOpts.MaxSpansPerBatch)
+
+	handler, err := NewSpanHandlerBuilder(cOpts, sFlags, builder.Options.MemoryStoreOption(memory.NewStore()))
+	require.NoError(t, err)
+	zHandler, jHandler := handler.BuildHandlers()
+	defer handler.Close()
+
+	ctx, cancel := tchanThrift.NewContext(time.Minute)
+	defer cancel()
+	process := &jaeger.Process{ServiceName: "service"}
+	_, err = jHandler.SubmitBatches(ctx, []*jaeger.Batch{{Process: process, Spans: []*jaeger.Span{{SpanId: 1}}}})
+	assert.NoError(t, err)
+	_, err = jHandler.SubmitBatches(ctx, []*jaeger.Batch{{Process: process, Spans: []*jaeger.Span{{SpanId: 1}, {SpanId: 2}}}})
+	assert.Equal(t, app.ErrBatchTooLarge, err)
+	_, err = zHandler.SubmitZipkinBatch(ctx, []*zipkincore.Span{{ID: 1}, {ID: 2}})
+	assert.Equal(t, app.ErrBatchTooLarge, err)
+	_, err = handler.SpanProcessor().ProcessSpans([]*model.Span{{}, {}}, app.JaegerFormatType)
+	assert.Equal(t, app.ErrBatchTooLarge, err)
+}
+
+func TestNewSpanHandlerBuilderNoopSpanStore(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--span-storage.type=memory", "--collector.span-store=noop", "--collector.noop-log-fraction=0.5"})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+	assert.Equal(t, SpanStoreNoop, cOpts.SpanStore)
+	assert.Equal(t, 0.5, cOpts.NoopLogFraction)
+
+	mb := metrics.NewLocalFactory(time.Hour)
+	store := memory.NewStore()
+	handler, err := NewSpanHandlerBuilder(
+		cOpts,
+		sFlags,
+		builder.Options.MemoryStoreOption(store),
+		builder.Options.MetricsFactoryOption(mb),
+	)
+	require.NoError(t, err)
+	_, jHandler := handler.BuildHandlers()
+
+	ctx, cancel := tchanThrift.NewContext(time.Minute)
+	defer cancel()
+	_, err = jHandler.SubmitBatches(ctx, []*jaeger.Batch{{
+		Process: &jaeger.Process{ServiceName: "service"},
+		Spans:   []*jaeger.Span{{TraceIdLow: 1, SpanId: 1}, {TraceIdLow: 1, SpanId: 2, ParentSpanId: 1}},
+	}})
+	require.NoError(t, err)
+	require.NoError(t, handler.Close())
+
+	metricsTest.AssertCounterMetrics(t, mb,
+		metricsTest.ExpectedMetric{Name: "jaeger.spans.recd", Value: 2},
+		metricsTest.ExpectedMetric{Name: "spans.saved-by-svc.service", Value: 2},
+	)
+	services, err := store.GetServices()
+	require.NoError(t, err)
+	assert.Empty(t, services)
+}
+
+func TestNewSpanHandlerBuilderBenchmarkMode(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--span-storage.type=memory", "--collector.benchmark-mode"})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+	assert.True(t, cOpts.BenchmarkMode)
+
+	mb := metrics.NewLocalFactory(time.Hour)
+	store := memory.NewStore()
+	logger, logBuf := testutils.NewLogger()
+	handler, err := NewSpanHandlerBuilder(
+		cOpts,
+		sFlags,
+		builder.Options.LoggerOption(logger),
+		builder.Options.MemoryStoreOption(store),
+		builder.Options.MetricsFactoryOption(mb),
+	)
+	require.NoError(t, err)
+	_, jHandler := handler.BuildHandlers()
+
+	ctx, cancel := tchanThrift.NewContext(time.Minute)
+	defer cancel()
+	_, err = jHandler.SubmitBatches(ctx, []*jaeger.Batch{{
+		Process: &jaeger.Process{ServiceName: "service"},
+		Spans:   []*jaeger.Span{{TraceIdLow: 1, SpanId: 1}, {TraceIdLow: 1, SpanId: 2, ParentSpanId: 1}},
+	}})
+	require.NoError(t, err)
+	require.NoError(t, handler.Close())
+
+	metricsTest.AssertCounterMetrics(t, mb,
+		metricsTest.ExpectedMetric{Name: "jaeger.spans.recd", Value: 2},
+		metricsTest.ExpectedMetric{Name: "benchmark.spans-discarded", Value: 2},
+	)
+	_, gauges := mb.Snapshot()
+	assert.Contains(t, gauges, "benchmark.decode-time.P99")
+	assert.Contains(t, gauges, "benchmark.validate-time.P99")
+	assert.Contains(t, logBuf.String(), `"spans-discarded":2`)
+	services, err := store.GetServices()
+	require.NoError(t, err)
+	assert.Empty(t, services)
+}
+
+func TestNewSpanHandlerBuilderStdoutSpanStore(t *testing.T) {
+	out := &bytes.Buffer{}
+	defer func(w io.Writer) { stdout = w }(stdout)
+	stdout = out
+
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--span-storage.type=memory", "--collector.span-store=stdout"})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+	assert.Equal(t, SpanStoreStdout, cOpts.SpanStore)
+
+	mb := metrics.NewLocalFactory(time.Hour)
+	store := memory.NewStore()
+	handler, err := NewSpanHandlerBuilder(
+		cOpts,
+		sFlags,
+		builder.Options.MemoryStoreOption(store),
+		builder.Options.MetricsFactoryOption(mb),
+	)
+	require.NoError(t, err)
+	_, jHandler := handler.BuildHandlers()
+
+	ctx, cancel := tchanThrift.NewContext(time.Minute)
+	defer cancel()
+	method := "GET"
+	_, err = jHandler.SubmitBatches(ctx, []*jaeger.Batch{{
+		Process: &jaeger.Process{ServiceName: "service"},
+		Spans: []*jaeger.Span{{
+			TraceIdLow:    1,
+			SpanId:        1,
+			OperationName: "GET /users",
+			Duration:      1500,
+			Tags:          []*jaeger.Tag{{Key: "http.method", VType: jaeger.TagType_STRING, VStr: &method}},
+		}},
+	}})
+	require.NoError(t, err)
+	require.NoError(t, handler.Close())
+
+	var printed map[string]interface{}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &printed))
+	assert.Equal(t, "service", printed["service"])
+	assert.Equal(t, "GET /users", printed["operationName"])
+	assert.Equal(t, "1.5ms", printed["duration"])
+	assert.Equal(t, map[string]interface{}{"http.method": "GET"}, printed["tags"])
+
+	metricsTest.AssertCounterMetrics(t, mb,
+		metricsTest.ExpectedMetric{Name: "jaeger.spans.recd", Value: 1},
+		metricsTest.ExpectedMetric{Name: "spans.saved-by-svc.service", Value: 1},
+	)
+	services, err := store.GetServices()
+	require.NoError(t, err)
+	assert.Empty(t, services)
+}
+
+func TestNewSpanHandlerBuilderBadSpanStore(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--span-storage.type=memory", "--collector.span-store=bad"})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+
+	handler, err := NewSpanHandlerBuilder(cOpts, sFlags, builder.Options.MemoryStoreOption(memory.NewStore()))
+	assert.Equal(t, errUnsupportedSpanStore, err)
+	assert.Nil(t, handler)
+}
+
+func TestNewSpanHandlerBuilderBadBackpressure(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--span-storage.type=memory", "--collector.backpressure-threshold=1.5"})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+
+	handler, err := NewSpanHandlerBuilder(cOpts, sFlags, builder.Options.MemoryStoreOption(memory.NewStore()))
+	assert.Equal(t, errInvalidBackpressure, err)
+	assert.Nil(t, handler)
+}
+
+func TestNewSpanHandlerBuilderEnrichDNS(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--span-storage.type=memory", "--collector.enrich-dns", "--collector.enrich-dns-timeout=100ms"})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+	assert.True(t, cOpts.EnrichDNS)
+	assert.Equal(t, sanitizer.DefaultDNSCacheSize, cOpts.EnrichDNSCacheSize)
+	assert.Equal(t, 100*time.Millisecond, cOpts.EnrichDNSTimeout)
+
+	handler, err := NewSpanHandlerBuilder(cOpts, sFlags, builder.Options.MemoryStoreOption(memory.NewStore()))
+	require.NoError(t, err)
+	handler.BuildHandlers()
+	require.NoError(t, handler.Close())
+}
+
+func TestNewSpanHandlerBuilderBadDNSCacheSize(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--span-storage.type=memory", "--collector.enrich-dns", "--collector.enrich-dns-cache-size=0"})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+
+	handler, err := NewSpanHandlerBuilder(cOpts, sFlags, builder.Options.MemoryStoreOption(memory.NewStore()))
+	assert.Equal(t, errInvalidDNSCacheSize, err)
+	assert.Nil(t, handler)
+}
+
+func TestNewSpanHandlerBuilderDedup(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--span-storage.type=memory", "--collector.dedup-window=1m"})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+	assert.Equal(t, time.Minute, cOpts.DedupWindow)
+
+	store := memory.NewStore()
+	handler, err := NewSpanHandlerBuilder(cOpts, sFlags, builder.Options.MemoryStoreOption(store))
+	require.NoError(t, err)
+	_, jHandler := handler.BuildHandlers()
+
+	ctx, cancel := tchanThrift.NewContext(time.Minute)
+	defer cancel()
+	batch := &jaeger.Batch{
+		Process: &jaeger.Process{ServiceName: "service"},
+		Spans:   []*jaeger.Span{{TraceIdLow: 1, SpanId: 1}},
+	}
+	for i := 0; i < 2; i++ {
+		_, err = jHandler.SubmitBatches(ctx, []*jaeger.Batch{batch})
+		require.NoError(t, err)
+	}
+	require.NoError(t, handler.Close())
+
+	trace, err := store.GetTrace(model.TraceID{Low: 1})
+	require.NoError(t, err)
+	assert.Len(t, trace.Spans, 1, "the retried span is only written once")
+}
+
+func TestNewSpanHandlerBuilderBadDedupMaxSpans(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--span-storage.type=memory", "--collector.dedup-window=1m", "--collector.dedup-max-spans=0"})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+
+	handler, err := NewSpanHandlerBuilder(cOpts, sFlags, builder.Options.MemoryStoreOption(memory.NewStore()))
+	assert.Equal(t, errInvalidDedupMaxSpans, err)
+	assert.Nil(t, handler)
+}
+
+func TestNewSpanHandlerBuilderWriteRetries(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--span-storage.type=memory", "--collector.write-retries=3", "--collector.write-retry-backoff=1s"})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+	assert.Equal(t, 3, cOpts.WriteRetries)
+	assert.Equal(t, time.Second, cOpts.WriteRetryBackoff)
+
+	handler, err := NewSpanHandlerBuilder(cOpts, sFlags, builder.Options.MemoryStoreOption(memory.NewStore()))
+	require.NoError(t, err)
+	assert.IsType(t, &spanstore.RetryWriter{}, handler.spanWriter)
+	assert.NoError(t, handler.Close())
+}
+
+func TestNewSpanHandlerBuilderBadWriteRetryWorkers(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--span-storage.type=memory", "--collector.write-retries=3", "--collector.write-retry-workers=0"})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+
+	handler, err := NewSpanHandlerBuilder(cOpts, sFlags, builder.Options.MemoryStoreOption(memory.NewStore()))
+	assert.Equal(t, errInvalidWriteRetryWorkers, err)
+	assert.Nil(t, handler)
+}
+
+func TestNewSpanHandlerBuilderMaxConcurrentWrites(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--span-storage.type=memory", "--collector.max-concurrent-writes=4"})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+	assert.Equal(t, 4, cOpts.MaxConcurrentWrites)
+
+	handler, err := NewSpanHandlerBuilder(cOpts, sFlags, builder.Options.MemoryStoreOption(memory.NewStore()))
+	require.NoError(t, err)
+	assert.IsType(t, &spanstore.ConcurrencyLimitWriter{}, handler.spanWriter)
+	assert.NoError(t, handler.Close())
+}
+
+func TestNewSpanHandlerBuilderBadMaxConcurrentWrites(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--span-storage.type=memory", "--collector.max-concurrent-writes=-1"})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+
+	handler, err := NewSpanHandlerBuilder(cOpts, sFlags, builder.Options.MemoryStoreOption(memory.NewStore()))
+	assert.Equal(t, errInvalidMaxConcurrentWrites, err)
+	assert.Nil(t, handler)
+}
+
+func TestNewSpanHandlerBuilderTraceBuffer(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--span-storage.type=memory", "--collector.trace-buffer-window=1h", "--collector.trace-buffer-max-spans=10"})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+	assert.Equal(t, time.Hour, cOpts.TraceBufferWindow)
+	assert.Equal(t, 10, cOpts.TraceBufferMaxSpans)
+	assert.Equal(t, spanstore.DefaultTraceBufferWorkers, cOpts.TraceBufferWorkers)
+
+	store := memory.NewStore()
+	handler, err := NewSpanHandlerBuilder(cOpts, sFlags, builder.Options.MemoryStoreOption(store))
+	require.NoError(t, err)
+	assert.IsType(t, &spanstore.TraceBufferWriter{}, handler.spanWriter)
+	_, jHandler := handler.BuildHandlers()
+
+	ctx, cancel := tchanThrift.NewContext(time.Minute)
+	defer cancel()
+	_, err = jHandler.SubmitBatches(ctx, []*jaeger.Batch{{
+		Process: &jaeger.Process{ServiceName: "service"},
+		Spans:   []*jaeger.Span{{TraceIdLow: 1, SpanId: 1}, {TraceIdLow: 2, SpanId: 1}, {TraceIdLow: 1, SpanId: 2, ParentSpanId: 1}},
+	}})
+	require.NoError(t, err)
+	// the spans are held for the window, Close writes them
+	require.NoError(t, handler.Close())
+
+	for traceID, spans := range map[uint64]int{1: 2, 2: 1} {
+		trace, err := store.GetTrace(model.TraceID{Low: traceID})
+		require.NoError(t, err)
+		assert.Len(t, trace.Spans, spans)
+	}
+}
+
+func TestNewSpanHandlerBuilderFlush(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--span-storage.type=memory", "--collector.trace-buffer-window=1h", "--collector.flush-endpoint"})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+	assert.True(t, cOpts.FlushEndpoint)
+
+	store := memory.NewStore()
+	handler, err := NewSpanHandlerBuilder(cOpts, sFlags, builder.Options.MemoryStoreOption(store))
+	require.NoError(t, err)
+	defer handler.Close()
+	_, jHandler := handler.BuildHandlers()
+
+	ctx, cancel := tchanThrift.NewContext(time.Minute)
+	defer cancel()
+	_, err = jHandler.SubmitBatches(ctx, []*jaeger.Batch{{
+		Process: &jaeger.Process{ServiceName: "service"},
+		Spans:   []*jaeger.Span{{TraceIdLow: 1, SpanId: 1}, {TraceIdLow: 1, SpanId: 2, ParentSpanId: 1}},
+	}})
+	require.NoError(t, err)
+
+	// the spans reach the trace buffer once the span processor has taken them off its queue
+	flushed := 0
+	for i := 0; i < 1000 && flushed < 2; i++ {
+		flushed += handler.Flush()["trace-buffer"]
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, 2, flushed)
+	trace, err := store.GetTrace(model.TraceID{Low: 1})
+	require.NoError(t, err)
+	assert.Len(t, trace.Spans, 2)
+}
+
+func TestNewSpanHandlerBuilderFlushWithoutBuffers(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--span-storage.type=memory"})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+
+	handler, err := NewSpanHandlerBuilder(cOpts, sFlags, builder.Options.MemoryStoreOption(memory.NewStore()))
+	require.NoError(t, err)
+	assert.Empty(t, handler.Flush())
+	require.NoError(t, handler.Close())
+}
+
+func TestNewSpanHandlerBuilderBadTraceBufferMaxSpans(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--span-storage.type=memory", "--collector.trace-buffer-window=1s", "--collector.trace-buffer-max-spans=0"})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+
+	handler, err := NewSpanHandlerBuilder(cOpts, sFlags, builder.Options.MemoryStoreOption(memory.NewStore()))
+	assert.Equal(t, errInvalidTraceBufferMaxSpans, err)
+	assert.Nil(t, handler)
+
+	v, command = config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--span-storage.type=memory", "--collector.trace-buffer-window=1s", "--collector.trace-buffer-workers=0"})
+	cOpts = new(CollectorOptions).InitFromViper(v)
+
+	handler, err = NewSpanHandlerBuilder(cOpts, sFlags, builder.Options.MemoryStoreOption(memory.NewStore()))
+	assert.Equal(t, errInvalidTraceBufferWorkers, err)
+	assert.Nil(t, handler)
+}
+
+func TestNewSpanHandlerBuilderReplaysWAL(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jaeger-wal")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// a collector that crashed after queueing a span, the log is left open with the span not acknowledged
+	crashed, err := wal.Open(dir, 0, wal.DefaultMaxSegmentBytes, zap.NewNop())
+	require.NoError(t, err)
+	_, err = crashed.Append(&model.Span{
+		TraceID: model.TraceID{Low: 1},
+		SpanID:  model.SpanID(1),
+		Process: &model.Process{ServiceName: "service"},
+	})
+	require.NoError(t, err)
+
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--span-storage.type=memory", "--collector.wal.dir=" + dir, "--collector.wal.sync-interval=0s"})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+	assert.Equal(t, dir, cOpts.WALDir)
+	assert.Equal(t, time.Duration(0), cOpts.WALSyncInterval)
+
+	store := memory.NewStore()
+	handler, err := NewSpanHandlerBuilder(cOpts, sFlags, builder.Options.MemoryStoreOption(store))
+	require.NoError(t, err)
+	_, jHandler := handler.BuildHandlers()
+
+	ctx, cancel := tchanThrift.NewContext(time.Minute)
+	defer cancel()
+	_, err = jHandler.SubmitBatches(ctx, []*jaeger.Batch{{
+		Process: &jaeger.Process{ServiceName: "service"},
+		Spans:   []*jaeger.Span{{TraceIdLow: 2, SpanId: 1}},
+	}})
+	require.NoError(t, err)
+	require.NoError(t, handler.Close())
+
+	for _, traceID := range []uint64{1, 2} {
+		_, err := store.GetTrace(model.TraceID{Low: traceID})
+		assert.NoError(t, err, "trace %d is saved", traceID)
+	}
+	segments, err := filepath.Glob(filepath.Join(dir, "*.wal"))
+	require.NoError(t, err)
+	assert.Empty(t, segments, "the segments are deleted once their spans are saved")
+}
+
+func TestNewSpanHandlerBuilderBadWAL(t *testing.T) {
+	file, err := ioutil.TempFile("", "jaeger-wal")
+	require.NoError(t, err)
+	file.Close()
+	defer os.Remove(file.Name())
+
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--span-storage.type=memory", "--collector.wal.dir=" + file.Name()})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+	assert.Equal(t, wal.DefaultSyncInterval, cOpts.WALSyncInterval)
+
+	handler, err := NewSpanHandlerBuilder(cOpts, sFlags, builder.Options.MemoryStoreOption(memory.NewStore()))
+	assert.Error(t, err, "the write-ahead log directory is a file")
+	assert.Nil(t, handler)
+
+	v, command = config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--span-storage.type=memory", "--collector.wal.dir=" + file.Name(), "--collector.wal.sync-interval=-1s"})
+	cOpts = new(CollectorOptions).InitFromViper(v)
+
+	handler, err = NewSpanHandlerBuilder(cOpts, sFlags, builder.Options.MemoryStoreOption(memory.NewStore()))
+	assert.Equal(t, errInvalidWALSyncInterval, err)
+	assert.Nil(t, handler)
+}
+
+func TestNewSpanHandlerBuilderWALAsyncWrites(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jaeger-wal")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	testCases := []struct {
+		flags []string
+		err   error
+	}{
+		{flags: []string{"--span-storage.type=memory"}},
+		{flags: []string{"--span-storage.type=memory", "--collector.write-retries=3"}, err: errWALAsyncWrites},
+		{flags: []string{"--span-storage.type=memory", "--collector.trace-buffer-window=1s"}, err: errWALAsyncWrites},
+		{flags: []string{"--span-storage.type=cassandra", "--collector.storage-buffer-size=100"}, err: errWALAsyncWrites},
+		{flags: []string{"--span-storage.type=kafka"}, err: errWALAsyncWrites},
+		{flags: []string{"--span-storage.type=elasticsearch"}, err: errWALAsyncWrites},
+		{flags: []string{"--span-storage.type=cassandra,kafka"}},
+	}
+	for _, tc := range testCases {
+		v, command := config.Viperize(AddFlags, flags.AddFlags)
+		command.ParseFlags(append([]string{"test", "--collector.wal.dir=" + dir}, tc.flags...))
+		sFlags := new(flags.SharedFlags).InitFromViper(v)
+		cOpts := new(CollectorOptions).InitFromViper(v)
+
+		handler, err := NewSpanHandlerBuilder(
+			cOpts,
+			sFlags,
+			builder.Options.MemoryStoreOption(memory.NewStore()),
+			builder.Options.CassandraSessionOption(&mockSessionBuilder{}),
+			builder.Options.ElasticClientOption(&mockEsBuilder{Configuration: escfg.Configuration{BulkWorkers: 1}}),
+			builder.Options.KafkaProducerOption(&mockKafkaBuilder{
+				Configuration: kafkacfg.Configuration{Topic: "jaeger-spans", Encoding: kafkacfg.EncodingJSON},
+				t:             t,
+			}),
+		)
+		if tc.err != nil {
+			assert.Equal(t, tc.err, err, "%v", tc.flags)
+			assert.Nil(t, handler, "%v", tc.flags)
+			continue
+		}
+		require.NoError(t, err, "%v", tc.flags)
+		require.NoError(t, handler.Close())
+	}
+
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--collector.wal.dir=" + dir, "--span-storage.type=elasticsearch"})
+	handler, err := NewSpanHandlerBuilder(
+		new(CollectorOptions).InitFromViper(v),
+		new(flags.SharedFlags).InitFromViper(v),
+		builder.Options.ElasticClientOption(&mockEsBuilder{}),
+	)
+	require.NoError(t, err, "spans are written to ElasticSearch one at a time without bulk workers")
+	require.NoError(t, handler.Close())
+}
+
+func TestNewSpanHandlerBuilderTagRules(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--span-storage.type=memory", "--collector.tag-rules-file=../sanitizer/fixtures/tag_rules.json"})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+
+	store := memory.NewStore()
+	handler, err := NewSpanHandlerBuilder(cOpts, sFlags, builder.Options.MemoryStoreOption(store))
+	require.NoError(t, err)
+	_, jHandler := handler.BuildHandlers()
+
+	ctx, cancel := tchanThrift.NewContext(time.Minute)
+	defer cancel()
+	userID, hostname := "42", "db1"
+	_, err = jHandler.SubmitBatches(ctx, []*jaeger.Batch{{
+		Process: &jaeger.Process{ServiceName: "service"},
+		Spans: []*jaeger.Span{{
+			TraceIdLow: 1,
+			SpanId:     1,
+			Tags: []*jaeger.Tag{
+				{Key: "user.id", VType: jaeger.TagType_STRING, VStr: &userID},
+				{Key: "peer.hostname", VType: jaeger.TagType_STRING, VStr: &hostname},
+			},
+		}},
+	}})
+	require.NoError(t, err)
+	require.NoError(t, handler.Close())
+
+	trace, err := store.GetTrace(model.TraceID{Low: 1})
+	require.NoError(t, err)
+	require.Len(t, trace.Spans, 1)
+	assert.Equal(t, model.KeyValues{model.String("peer.host", "db1")}, trace.Spans[0].Tags)
+}
+
+func TestNewSpanHandlerBuilderTagSpansWithHost(t *testing.T) {
+	hostname, _ := os.Hostname()
+	tests := []struct {
+		flags    []string
+		expected model.KeyValues
+	}{
+		{
+			flags:    []string{"test", "--span-storage.type=memory"},
+			expected: model.KeyValues{model.String("user.id", "42")},
+		},
+		{
+			flags: []string{"test", "--span-storage.type=memory", "--collector.tag-spans-with-host=true"},
+			expected: model.KeyValues{
+				model.String("user.id", "42"),
+				model.String(sanitizer.CollectorHostTagKey, hostname),
+			},
+		},
+	}
+	for _, test := range tests {
+		v, command := config.Viperize(AddFlags, flags.AddFlags)
+		command.ParseFlags(test.flags)
+		sFlags := new(flags.SharedFlags).InitFromViper(v)
+		cOpts := new(CollectorOptions).InitFromViper(v)
+
+		store := memory.NewStore()
+		handler, err := NewSpanHandlerBuilder(cOpts, sFlags, builder.Options.MemoryStoreOption(store))
+		require.NoError(t, err)
+		_, jHandler := handler.BuildHandlers()
+
+		ctx, cancel := tchanThrift.NewContext(time.Minute)
+		userID := "42"
+		_, err = jHandler.SubmitBatches(ctx, []*jaeger.Batch{{
+			Process: &jaeger.Process{ServiceName: "service"},
+			Spans: []*jaeger.Span{{
+				TraceIdLow: 1,
+				SpanId:     1,
+				Tags:       []*jaeger.Tag{{Key: "user.id", VType: jaeger.TagType_STRING, VStr: &userID}},
+			}},
+		}})
+		cancel()
+		require.NoError(t, err)
+		require.NoError(t, handler.Close())
+
+		trace, err := store.GetTrace(model.TraceID{Low: 1})
+		require.NoError(t, err)
+		require.Len(t, trace.Spans, 1)
+		assert.Equal(t, test.expected, trace.Spans[0].Tags, test.flags)
+	}
+}
+
+func TestNewSpanHandlerBuilderBaggageToTagKeys(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--span-storage.type=memory", "--collector.baggage-to-tag-keys=tenant, user.id"})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+	assert.Equal(t, []string{"tenant", "user.id"}, cOpts.BaggageToTagKeys)
+
+	store := memory.NewStore()
+	handler, err := NewSpanHandlerBuilder(cOpts, sFlags, builder.Options.MemoryStoreOption(store))
+	require.NoError(t, err)
+	_, jHandler := handler.BuildHandlers()
+
+	baggageLog := func(key, value string) *jaeger.Log {
+		event := "baggage"
+		return &jaeger.Log{Fields: []*jaeger.Tag{
+			{Key: "event", VType: jaeger.TagType_STRING, VStr: &event},
+			{Key: "key", VType: jaeger.TagType_STRING, VStr: &key},
+			{Key: "value", VType: jaeger.TagType_STRING, VStr: &value},
+		}}
+	}
+	ctx, cancel := tchanThrift.NewContext(time.Minute)
+	defer cancel()
+	_, err = jHandler.SubmitBatches(ctx, []*jaeger.Batch{{
+		Process: &jaeger.Process{ServiceName: "service"},
+		Spans: []*jaeger.Span{{
+			TraceIdLow: 1,
+			SpanId:     1,
+			Logs:       []*jaeger.Log{baggageLog("tenant", "acme"), baggageLog("session.id", "d0c5")},
+		}},
+	}})
+	require.NoError(t, err)
+	require.NoError(t, handler.Close())
+
+	trace, err := store.GetTrace(model.TraceID{Low: 1})
+	require.NoError(t, err)
+	require.Len(t, trace.Spans, 1)
+	assert.Equal(t, model.KeyValues{model.String("tenant", "acme")}, trace.Spans[0].Tags)
+}
+
+func TestNewSpanHandlerBuilderOperationNameTag(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--span-storage.type=memory", "--collector.operation-name-tag=rpc.method"})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+	assert.Equal(t, "rpc.method", cOpts.OperationNameTag)
+
+	store := memory.NewStore()
+	handler, err := NewSpanHandlerBuilder(cOpts, sFlags, builder.Options.MemoryStoreOption(store))
+	require.NoError(t, err)
+	_, jHandler := handler.BuildHandlers()
+
+	method := "GetDriver"
+	ctx, cancel := tchanThrift.NewContext(time.Minute)
+	defer cancel()
+	_, err = jHandler.SubmitBatches(ctx, []*jaeger.Batch{{
+		Process: &jaeger.Process{ServiceName: "service"},
+		Spans: []*jaeger.Span{
+			{
+				TraceIdLow:    1,
+				SpanId:        1,
+				OperationName: "POST",
+				Tags:          []*jaeger.Tag{{Key: "rpc.method", VType: jaeger.TagType_STRING, VStr: &method}},
+			},
+			{TraceIdLow: 1, SpanId: 2, OperationName: "GET"},
+		},
+	}})
+	require.NoError(t, err)
+	require.NoError(t, handler.Close())
+
+	trace, err := store.GetTrace(model.TraceID{Low: 1})
+	require.NoError(t, err)
+	require.Len(t, trace.Spans, 2)
+	spans := make(map[model.SpanID]*model.Span)
+	for _, span := range trace.Spans {
+		spans[span.SpanID] = span
+	}
+	assert.Equal(t, "GetDriver", spans[1].OperationName)
+	original, ok := spans[1].Tags.FindByKey(sanitizer.OriginalOperationNameTagKey)
+	require.True(t, ok)
+	assert.Equal(t, "POST", original.VStr)
+	assert.Equal(t, "GET", spans[2].OperationName, "the span without the tag keeps its operation name")
+	assert.Empty(t, spans[2].Tags)
+}
+
+func TestNewSpanHandlerBuilderMaxLogBytesPerSpan(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--span-storage.type=memory", "--collector.max-log-bytes-per-span=20"})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+	assert.Equal(t, 20, cOpts.MaxLogBytesPerSpan)
+
+	store := memory.NewStore()
+	handler, err := NewSpanHandlerBuilder(cOpts, sFlags, builder.Options.MemoryStoreOption(store))
+	require.NoError(t, err)
+	_, jHandler := handler.BuildHandlers()
+
+	stringLog := func(key, value string) *jaeger.Log {
+		return &jaeger.Log{Fields: []*jaeger.Tag{{Key: key, VType: jaeger.TagType_STRING, VStr: &value}}}
+	}
+	ctx, cancel := tchanThrift.NewContext(time.Minute)
+	defer cancel()
+	_, err = jHandler.SubmitBatches(ctx, []*jaeger.Batch{{
+		Process: &jaeger.Process{ServiceName: "service"},
+		Spans: []*jaeger.Span{
+			{TraceIdLow: 1, SpanId: 1, Logs: []*jaeger.Log{stringLog("event", "cache miss")}},
+			{TraceIdLow: 2, SpanId: 1, Logs: []*jaeger.Log{stringLog("event", "response"), stringLog("body", strings.Repeat("x", 4096))}},
+		},
+	}})
+	require.NoError(t, err)
+	require.NoError(t, handler.Close())
+
+	trace, err := store.GetTrace(model.TraceID{Low: 1})
+	require.NoError(t, err)
+	require.Len(t, trace.Spans, 1)
+	assert.Empty(t, trace.Spans[0].Tags)
+	require.Len(t, trace.Spans[0].Logs, 1)
+
+	trace, err = store.GetTrace(model.TraceID{Low: 2})
+	require.NoError(t, err)
+	require.Len(t, trace.Spans, 1)
+	assert.Equal(t, model.KeyValues{model.Bool(sanitizer.LogsTruncatedTagKey, true)}, trace.Spans[0].Tags)
+	require.Len(t, trace.Spans[0].Logs, 2)
+	assert.Equal(t, model.KeyValues{model.String("body", "xxx")}, trace.Spans[0].Logs[1].Fields)
+}
+
+func TestNewSpanHandlerBuilderMaxEntriesPerSpan(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{
+		"test",
+		"--span-storage.type=memory",
+		"--collector.max-tags-per-span=2",
+		"--collector.max-logs-per-span=1",
+	})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+
+	store := memory.NewStore()
+	metricsFactory := metrics.NewLocalFactory(0)
+	handler, err := NewSpanHandlerBuilder(
+		cOpts,
+		sFlags,
+		builder.Options.MemoryStoreOption(store),
+		builder.Options.MetricsFactoryOption(metricsFactory),
+	)
+	require.NoError(t, err)
+	_, jHandler := handler.BuildHandlers()
+
+	a, b, c := "a", "b", "c"
+	tags := []*jaeger.Tag{
+		{Key: "a", VType: jaeger.TagType_STRING, VStr: &a},
+		{Key: "b", VType: jaeger.TagType_STRING, VStr: &b},
+		{Key: "c", VType: jaeger.TagType_STRING, VStr: &c},
+	}
+	logs := []*jaeger.Log{{Timestamp: 1}, {Timestamp: 2}, {Timestamp: 3}}
+	ctx, cancel := tchanThrift.NewContext(time.Minute)
+	defer cancel()
+	_, err = jHandler.SubmitBatches(ctx, []*jaeger.Batch{{
+		Process: &jaeger.Process{ServiceName: "service"},
+		Spans: []*jaeger.Span{
+			{TraceIdLow: 1, SpanId: 1, Tags: tags},
+			{TraceIdLow: 2, SpanId: 2, Logs: logs},
+			{TraceIdLow: 3, SpanId: 3, Tags: tags[:2], Logs: logs[:1]},
+		},
+	}})
+	require.NoError(t, err)
+	require.NoError(t, handler.Close())
+
+	trace, err := store.GetTrace(model.TraceID{Low: 1})
+	require.NoError(t, err)
+	require.Len(t, trace.Spans, 1)
+	assert.Equal(t, model.KeyValues{model.String("a", "a"), model.String("b", "b"), model.Bool(app.TruncatedTagKey, true)}, trace.Spans[0].Tags)
+
+	trace, err = store.GetTrace(model.TraceID{Low: 2})
+	require.NoError(t, err)
+	require.Len(t, trace.Spans, 1)
+	assert.Len(t, trace.Spans[0].Logs, 1)
+	assert.Equal(t, model.KeyValues{model.Bool(app.TruncatedTagKey, true)}, trace.Spans[0].Tags)
+
+	trace, err = store.GetTrace(model.TraceID{Low: 3})
+	require.NoError(t, err)
+	require.Len(t, trace.Spans, 1)
+	assert.Len(t, trace.Spans[0].Tags, 2, "the spans within the limits are not tagged")
+	assert.Len(t, trace.Spans[0].Logs, 1)
+
+	counters, _ := metricsFactory.Snapshot()
+	assert.EqualValues(t, 1, counters["span-entries.dropped|entry=tags"])
+	assert.EqualValues(t, 2, counters["span-entries.dropped|entry=logs"])
+}
+
+func TestNewSpanHandlerBuilderBadMaxEntriesPerSpan(t *testing.T) {
+	for _, flag := range []string{"--collector.max-tags-per-span=-1", "--collector.max-logs-per-span=-1"} {
+		v, command := config.Viperize(AddFlags, flags.AddFlags)
+		command.ParseFlags([]string{"test", "--span-storage.type=memory", flag})
+		sFlags := new(flags.SharedFlags).InitFromViper(v)
+		cOpts := new(CollectorOptions).InitFromViper(v)
+
+		handler, err := NewSpanHandlerBuilder(cOpts, sFlags, builder.Options.MemoryStoreOption(memory.NewStore()))
+		assert.Equal(t, errInvalidMaxEntriesPerSpan, err, flag)
+		assert.Nil(t, handler, flag)
+	}
+}
+
+func TestNewSpanHandlerBuilderBadMaxLogBytesPerSpan(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--span-storage.type=memory", "--collector.max-log-bytes-per-span=-1"})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+
+	handler, err := NewSpanHandlerBuilder(cOpts, sFlags, builder.Options.MemoryStoreOption(memory.NewStore()))
+	assert.Equal(t, errInvalidMaxLogBytesPerSpan, err)
+	assert.Nil(t, handler)
+}
+
+func TestNewSpanHandlerBuilderBadStartupRetries(t *testing.T) {
+	testCases := []struct {
+		flag string
+		err  error
+	}{
+		{flag: "--collector.startup-retries=-1", err: errInvalidStartupRetries},
+		{flag: "--collector.startup-retry-backoff=-1s", err: errInvalidStartupRetryBackoff},
+	}
+	for _, testCase := range testCases {
+		v, command := config.Viperize(AddFlags, flags.AddFlags)
+		command.ParseFlags([]string{"test", "--span-storage.type=memory", testCase.flag})
+		sFlags := new(flags.SharedFlags).InitFromViper(v)
+		cOpts := new(CollectorOptions).InitFromViper(v)
+
+		handler, err := NewSpanHandlerBuilder(cOpts, sFlags, builder.Options.MemoryStoreOption(memory.NewStore()))
+		assert.Equal(t, testCase.err, err, testCase.flag)
+		assert.Nil(t, handler, testCase.flag)
+	}
+}
+
+func TestNewSpanHandlerBuilderSelfTracing(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--span-storage.type=memory", "--collector.self-tracing=true"})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+	assert.True(t, cOpts.SelfTracing)
+
+	store := memory.NewStore()
+	tracer := mocktracer.New()
+	handler, err := NewSpanHandlerBuilder(cOpts, sFlags, builder.Options.MemoryStoreOption(store), builder.Options.TracerOption(tracer))
+	require.NoError(t, err)
+	_, jHandler := handler.BuildHandlers()
+
+	ctx, cancel := tchanThrift.NewContext(time.Minute)
+	defer cancel()
+	isSelfTrace := true
+	_, err = jHandler.SubmitBatches(ctx, []*jaeger.Batch{
+		{
+			Process: &jaeger.Process{ServiceName: "frontend"},
+			Spans:   []*jaeger.Span{{TraceIdLow: 1, SpanId: 1}},
+		},
+		{
+			Process: &jaeger.Process{
+				ServiceName: DefaultServiceName,
+				Tags:        []*jaeger.Tag{{Key: app.SelfTraceTagKey, VType: jaeger.TagType_BOOL, VBool: &isSelfTrace}},
+			},
+			Spans: []*jaeger.Span{{TraceIdLow: 2, SpanId: 1}},
+		},
+	})
+	require.NoError(t, err)
+	require.NoError(t, handler.Close())
+
+	for _, traceID := range []uint64{1, 2} {
+		_, err := store.GetTrace(model.TraceID{Low: traceID})
+		assert.NoError(t, err, "trace %d is saved", traceID)
+	}
+	var operations []string
+	for _, span := range tracer.FinishedSpans() {
+		operations = append(operations, span.OperationName)
+	}
+	sort.Strings(operations)
+	assert.Equal(t, []string{"ProcessSpans", "WriteSpan"}, operations, "the self-traces are not traced")
+}
+
+func TestCollectorSelfTracingFlags(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test"})
+	cOpts := new(CollectorOptions).InitFromViper(v)
+	assert.False(t, cOpts.SelfTracing)
+	assert.Empty(t, cOpts.SelfTracingEndpoint)
+	assert.Equal(t, 0.001, cOpts.SelfTracingSamplingRate)
+
+	v, command = config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--collector.self-tracing=true", "--collector.self-tracing.endpoint=http://jaeger-collector:14268/api/traces", "--collector.self-tracing.sampling-rate=0.5"})
+	cOpts = new(CollectorOptions).InitFromViper(v)
+	assert.True(t, cOpts.SelfTracing)
+	assert.Equal(t, "http://jaeger-collector:14268/api/traces", cOpts.SelfTracingEndpoint)
+	assert.Equal(t, 0.5, cOpts.SelfTracingSamplingRate)
+}
+
+func TestNewSpanHandlerBuilderDownsampling(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--span-storage.type=memory", "--collector.downsampling.ratio=0.5", "--collector.downsampling.hashsalt=jaeger"})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+	assert.Equal(t, 0.5, cOpts.DownsamplingRatio)
+	assert.Equal(t, "jaeger", cOpts.DownsamplingHashSalt)
+
+	store := memory.NewStore()
+	handler, err := NewSpanHandlerBuilder(cOpts, sFlags, builder.Options.MemoryStoreOption(store))
+	require.NoError(t, err)
+	_, jHandler := handler.BuildHandlers()
+
+	ctx, cancel := tchanThrift.NewContext(time.Minute)
+	defer cancel()
+	const numTraces = 100
+	for traceID := int64(1); traceID <= numTraces; traceID++ {
+		_, err = jHandler.SubmitBatches(ctx, []*jaeger.Batch{
+			{
+				Process: &jaeger.Process{ServiceName: "frontend"},
+				Spans:   []*jaeger.Span{{TraceIdLow: traceID, SpanId: 1}},
+			},
+			{
+				Process: &jaeger.Process{ServiceName: "backend"},
+				Spans:   []*jaeger.Span{{TraceIdLow: traceID, SpanId: 2, ParentSpanId: 1}, {TraceIdLow: traceID, SpanId: 3, ParentSpanId: 1}},
+			},
+		})
+		require.NoError(t, err)
+	}
+	require.NoError(t, handler.Close())
+
+	saved := 0
+	for traceID := uint64(1); traceID <= numTraces; traceID++ {
+		trace, err := store.GetTrace(model.TraceID{Low: traceID})
+		if err != nil {
+			continue
+		}
+		saved++
+		assert.Len(t, trace.Spans, 3, "the spans of a trace are kept together")
+		for _, span := range trace.Spans {
+			rate, ok := span.Tags.FindByKey(app.SamplingRateTagKey)
+			require.True(t, ok)
+			assert.Equal(t, 0.5, rate.Float64())
+		}
+	}
+	assert.True(t, saved > 0 && saved < numTraces, "%d of %d traces were saved", saved, numTraces)
+}
+
+func TestNewSpanHandlerBuilderBadDownsamplingRatio(t *testing.T) {
+	for _, ratio := range []string{"0", "-0.5", "1.5"} {
+		v, command := config.Viperize(AddFlags, flags.AddFlags)
+		command.ParseFlags([]string{"test", "--span-storage.type=memory", "--collector.downsampling.ratio=" + ratio})
+		sFlags := new(flags.SharedFlags).InitFromViper(v)
+		cOpts := new(CollectorOptions).InitFromViper(v)
+
+		handler, err := NewSpanHandlerBuilder(cOpts, sFlags, builder.Options.MemoryStoreOption(memory.NewStore()))
+		assert.Equal(t, errInvalidDownsamplingRatio, err, ratio)
+		assert.Nil(t, handler)
+	}
+}
+
+func TestNewSpanHandlerBuilderMinSpanDuration(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--span-storage.type=memory", "--collector.min-span-duration=1ms"})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+	assert.Equal(t, time.Millisecond, cOpts.MinSpanDuration)
+
+	store := memory.NewStore()
+	handler, err := NewSpanHandlerBuilder(cOpts, sFlags, builder.Options.MemoryStoreOption(store))
+	require.NoError(t, err)
+	_, jHandler := handler.BuildHandlers()
+
+	ctx, cancel := tchanThrift.NewContext(time.Minute)
+	defer cancel()
+	isError := true
+	_, err = jHandler.SubmitBatches(ctx, []*jaeger.Batch{{
+		Process: &jaeger.Process{ServiceName: "service"},
+		Spans: []*jaeger.Span{
+			{TraceIdLow: 1, SpanId: 1, Duration: 2000},
+			{TraceIdLow: 2, SpanId: 2, Duration: 500},
+			{TraceIdLow: 3, SpanId: 3, Duration: 500, Tags: []*jaeger.Tag{{Key: "error", VType: jaeger.TagType_BOOL, VBool: &isError}}},
+		},
+	}})
+	require.NoError(t, err)
+	require.NoError(t, handler.Close())
+
+	_, err = store.GetTrace(model.TraceID{Low: 1})
+	assert.NoError(t, err, "the span above the minimum duration is saved")
+	_, err = store.GetTrace(model.TraceID{Low: 2})
+	assert.Error(t, err, "the span below the minimum duration is dropped")
+	_, err = store.GetTrace(model.TraceID{Low: 3})
+	assert.NoError(t, err, "the error span below the minimum duration is saved")
+}
+
+func TestNewSpanHandlerBuilderDropOperationPatterns(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{
+		"test",
+		"--span-storage.type=memory",
+		"--collector.drop-operation-patterns=^/health$, ^/metrics$",
+		"--collector.drop-operation-patterns.match-http-url=true",
+	})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+	assert.Equal(t, []string{"^/health$", "^/metrics$"}, cOpts.DropOperationPatterns)
+	assert.True(t, cOpts.DropOperationMatchURL)
+
+	metricsFactory := metrics.NewLocalFactory(0)
+	store := memory.NewStore()
+	handler, err := NewSpanHandlerBuilder(
+		cOpts,
+		sFlags,
+		builder.Options.MemoryStoreOption(store),
+		builder.Options.MetricsFactoryOption(metricsFactory),
+	)
+	require.NoError(t, err)
+	_, jHandler := handler.BuildHandlers()
+
+	ctx, cancel := tchanThrift.NewContext(time.Minute)
+	defer cancel()
+	url := "/metrics"
+	_, err = jHandler.SubmitBatches(ctx, []*jaeger.Batch{{
+		Process: &jaeger.Process{ServiceName: "service"},
+		Spans: []*jaeger.Span{
+			{TraceIdLow: 1, SpanId: 1, OperationName: "/api/orders"},
+			{TraceIdLow: 2, SpanId: 2, OperationName: "/health"},
+			{TraceIdLow: 3, SpanId: 3, OperationName: "HTTP GET", Tags: []*jaeger.Tag{{Key: "http.url", VType: jaeger.TagType_STRING, VStr: &url}}},
+		},
+	}})
+	require.NoError(t, err)
+	require.NoError(t, handler.Close())
+
+	_, err = store.GetTrace(model.TraceID{Low: 1})
+	assert.NoError(t, err, "the span of another operation is saved")
+	_, err = store.GetTrace(model.TraceID{Low: 2})
+	assert.Error(t, err, "the span of a matching operation is dropped")
+	_, err = store.GetTrace(model.TraceID{Low: 3})
+	assert.Error(t, err, "the span with a matching http.url is dropped")
+	counters, _ := metricsFactory.Snapshot()
+	assert.EqualValues(t, 2, counters["spans.dropped-by-pattern|service=service"])
+}
+
+func TestNewSpanHandlerBuilderBadDropOperationPattern(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--span-storage.type=memory", "--collector.drop-operation-patterns=^/health$,[a-"})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+
+	handler, err := NewSpanHandlerBuilder(cOpts, sFlags, builder.Options.MemoryStoreOption(memory.NewStore()))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `invalid operation pattern "[a-"`)
+	assert.Nil(t, handler)
+}
+
+func TestNewSpanHandlerBuilderMetricsMaxServices(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--span-storage.type=memory", "--collector.metrics-max-services=1"})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+	assert.Equal(t, 1, cOpts.MetricsMaxServices)
+
+	metricsFactory := metrics.NewLocalFactory(0)
+	handler, err := NewSpanHandlerBuilder(
+		cOpts,
+		sFlags,
+		builder.Options.MemoryStoreOption(memory.NewStore()),
+		builder.Options.MetricsFactoryOption(metricsFactory),
+	)
+	require.NoError(t, err)
+	_, jHandler := handler.BuildHandlers()
+
+	ctx, cancel := tchanThrift.NewContext(time.Minute)
+	defer cancel()
+	_, err = jHandler.SubmitBatches(ctx, []*jaeger.Batch{
+		{Process: &jaeger.Process{ServiceName: "frontend"}, Spans: []*jaeger.Span{{TraceIdLow: 1, SpanId: 1}, {TraceIdLow: 1, SpanId: 2}}},
+		{Process: &jaeger.Process{ServiceName: "backend"}, Spans: []*jaeger.Span{{TraceIdLow: 1, SpanId: 3}}},
+	})
+	require.NoError(t, err)
+	require.NoError(t, handler.Close())
+
+	counters, _ := metricsFactory.Snapshot()
+	assert.EqualValues(t, 2, counters["spans.received|svc=frontend"])
+	assert.EqualValues(t, 1, counters["spans.received|svc=other"])
+	_, ok := counters["spans.received|svc=backend"]
+	assert.False(t, ok)
+}
+
+func TestNewSpanHandlerBuilderNormalizeServiceNames(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{
+		"test",
+		"--span-storage.type=memory",
+		"--collector.normalize-service-names=true",
+		"--collector.metrics-max-services=1",
+	})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+	assert.True(t, cOpts.NormalizeServiceNames)
+
+	store := memory.NewStore()
+	metricsFactory := metrics.NewLocalFactory(0)
+	handler, err := NewSpanHandlerBuilder(
+		cOpts,
+		sFlags,
+		builder.Options.MemoryStoreOption(store),
+		builder.Options.MetricsFactoryOption(metricsFactory),
+	)
+	require.NoError(t, err)
+	_, jHandler := handler.BuildHandlers()
+
+	ctx, cancel := tchanThrift.NewContext(time.Minute)
+	defer cancel()
+	_, err = jHandler.SubmitBatches(ctx, []*jaeger.Batch{
+		{Process: &jaeger.Process{ServiceName: "MyService"}, Spans: []*jaeger.Span{{TraceIdLow: 1, SpanId: 1}}},
+		{Process: &jaeger.Process{ServiceName: " myservice "}, Spans: []*jaeger.Span{{TraceIdLow: 1, SpanId: 2}}},
+		{Process: &jaeger.Process{ServiceName: "myservice"}, Spans: []*jaeger.Span{{TraceIdLow: 1, SpanId: 3}}},
+	})
+	require.NoError(t, err)
+	require.NoError(t, handler.Close())
+
+	counters, _ := metricsFactory.Snapshot()
+	assert.EqualValues(t, 3, counters["spans.received|svc=myservice"], "the spans are counted by the normalized name")
+
+	trace, err := store.GetTrace(model.TraceID{Low: 1})
+	require.NoError(t, err)
+	require.Len(t, trace.Spans, 3)
+	originals := make(map[model.SpanID]model.KeyValues)
+	for _, span := range trace.Spans {
+		assert.Equal(t, "myservice", span.Process.ServiceName)
+		originals[span.SpanID] = span.Process.Tags
+	}
+	assert.Equal(t, model.KeyValues{model.String(app.OriginalServiceNameTagKey, "MyService")}, originals[1])
+	assert.Equal(t, model.KeyValues{model.String(app.OriginalServiceNameTagKey, " myservice ")}, originals[2])
+	assert.Empty(t, originals[3])
+}
+
+func TestNewSpanHandlerBuilderMeasureTagCardinality(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{
+		"test",
+		"--span-storage.type=memory",
+		"--collector.measure-tag-cardinality=true",
+		"--collector.tag-cardinality-window=10ms",
+	})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+	assert.Equal(t, app.DefaultTagCardinalityMaxKeys, cOpts.TagCardinalityMaxKeys)
+
+	metricsFactory := metrics.NewLocalFactory(0)
+	handler, err := NewSpanHandlerBuilder(
+		cOpts,
+		sFlags,
+		builder.Options.MemoryStoreOption(memory.NewStore()),
+		builder.Options.MetricsFactoryOption(metricsFactory),
+	)
+	require.NoError(t, err)
+	_, jHandler := handler.BuildHandlers()
+
+	var spans []*jaeger.Span
+	for i := 0; i < 100; i++ {
+		requestID := strconv.Itoa(i)
+		spans = append(spans, &jaeger.Span{
+			TraceIdLow: 1,
+			SpanId:     int64(i + 1),
+			Tags:       []*jaeger.Tag{{Key: "request.id", VType: jaeger.TagType_STRING, VStr: &requestID}},
+		})
+	}
+	ctx, cancel := tchanThrift.NewContext(time.Minute)
+	defer cancel()
+	_, err = jHandler.SubmitBatches(ctx, []*jaeger.Batch{{Process: &jaeger.Process{ServiceName: "service"}, Spans: spans}})
+	require.NoError(t, err)
+
+	var cardinality int64
+	for i := 0; i < 1000 && cardinality == 0; i++ {
+		time.Sleep(time.Millisecond)
+		_, gauges := metricsFactory.Snapshot()
+		cardinality = gauges["tag-cardinality|key=request.id"]
+	}
+	require.NoError(t, handler.Close())
+	assert.InDelta(t, 100, cardinality, 5)
+}
+
+func TestNewSpanHandlerBuilderBadTagCardinality(t *testing.T) {
+	testCases := []struct {
+		flag string
+		err  error
+	}{
+		{flag: "--collector.tag-cardinality-max-keys=0", err: errInvalidTagCardinalityKeys},
+		{flag: "--collector.tag-cardinality-window=0s", err: errInvalidTagCardinalityWindow},
+	}
+	for _, testCase := range testCases {
+		v, command := config.Viperize(AddFlags, flags.AddFlags)
+		command.ParseFlags([]string{"test", "--span-storage.type=memory", "--collector.measure-tag-cardinality=true", testCase.flag})
+		sFlags := new(flags.SharedFlags).InitFromViper(v)
+		cOpts := new(CollectorOptions).InitFromViper(v)
+
+		handler, err := NewSpanHandlerBuilder(cOpts, sFlags, builder.Options.MemoryStoreOption(memory.NewStore()))
+		assert.Equal(t, testCase.err, err, testCase.flag)
+		assert.Nil(t, handler, testCase.flag)
+	}
+}
+
+func TestNewSpanHandlerBuilderRequiredTagsPolicy(t *testing.T) {
+	testCases := []struct {
+		policy       string
+		savedWithout bool
+		err          error
+	}{
+		{policy: RequiredTagsPolicyTag, savedWithout: true},
+		{policy: RequiredTagsPolicyDrop},
+		{policy: "sneh", err: errUnsupportedRequiredTags},
+	}
+	for _, tc := range testCases {
+		v, command := config.Viperize(AddFlags, flags.AddFlags)
+		command.ParseFlags([]string{
+			"test",
+			"--span-storage.type=memory",
+			"--collector.required-process-tags=team, env",
+			"--collector.required-tags-policy=" + tc.policy,
+		})
+		sFlags := new(flags.SharedFlags).InitFromViper(v)
+		cOpts := new(CollectorOptions).InitFromViper(v)
+		assert.Equal(t, []string{"team", "env"}, cOpts.RequiredProcessTags)
+		assert.Equal(t, tc.policy, cOpts.RequiredTagsPolicy)
+
+		store := memory.NewStore()
+		metricsFactory := metrics.NewLocalFactory(0)
+		handler, err := NewSpanHandlerBuilder(
+			cOpts,
+			sFlags,
+			builder.Options.MemoryStoreOption(store),
+			builder.Options.MetricsFactoryOption(metricsFactory),
+		)
+		if tc.err != nil {
+			assert.Equal(t, tc.err, err)
+			assert.Nil(t, handler)
+			continue
+		}
+		require.NoError(t, err)
+		_, jHandler := handler.BuildHandlers()
+
+		team, env := "tracing", "prod"
+		ctx, cancel := tchanThrift.NewContext(time.Minute)
+		_, err = jHandler.SubmitBatches(ctx, []*jaeger.Batch{
+			{
+				Process: &jaeger.Process{ServiceName: "complete", Tags: []*jaeger.Tag{
+					{Key: "team", VType: jaeger.TagType_STRING, VStr: &team},
+					{Key: "env", VType: jaeger.TagType_STRING, VStr: &env},
+				}},
+				Spans: []*jaeger.Span{{TraceIdLow: 1, SpanId: 1}},
+			},
+			{
+				Process: &jaeger.Process{ServiceName: "incomplete", Tags: []*jaeger.Tag{
+					{Key: "env", VType: jaeger.TagType_STRING, VStr: &env},
+				}},
+				Spans: []*jaeger.Span{{TraceIdLow: 2, SpanId: 2}},
+			},
+		})
+		cancel()
+		require.NoError(t, err)
+		require.NoError(t, handler.Close())
+
+		trace, err := store.GetTrace(model.TraceID{Low: 1})
+		require.NoError(t, err, tc.policy)
+		_, ok := trace.Spans[0].Tags.FindByKey(app.MissingRequiredTagsKey)
+		assert.False(t, ok, tc.policy)
+
+		trace, err = store.GetTrace(model.TraceID{Low: 2})
+		if tc.savedWithout {
+			require.NoError(t, err, tc.policy)
+			tag, ok := trace.Spans[0].Tags.FindByKey(app.MissingRequiredTagsKey)
+			assert.True(t, ok, tc.policy)
+			assert.Equal(t, "team", tag.VStr, tc.policy)
+		} else {
+			assert.Error(t, err, tc.policy)
+		}
+
+		counters, _ := metricsFactory.Snapshot()
+		assert.EqualValues(t, 1, counters["spans.missing-required-tags|service=incomplete"], tc.policy)
+	}
+}
+
+func TestNewSpanHandlerBuilderBadTagRulesFile(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--span-storage.type=memory", "--collector.tag-rules-file=../sanitizer/fixtures/missing.json"})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+
+	handler, err := NewSpanHandlerBuilder(cOpts, sFlags, builder.Options.MemoryStoreOption(memory.NewStore()))
+	assert.EqualError(t, err, "open ../sanitizer/fixtures/missing.json: no such file or directory")
+	assert.Nil(t, handler)
+}
+
+func TestCollectorHTTPBasePath(t *testing.T) {
+	testCases := []struct {
+		flag     string
+		expected string
+	}{
+		{flag: "", expected: ""},
+		{flag: "/", expected: ""},
+		{flag: "/jaeger", expected: "/jaeger"},
+		{flag: "jaeger/", expected: "/jaeger"},
+		{flag: "/tracing/jaeger//", expected: "/tracing/jaeger"},
+	}
+	for _, tc := range testCases {
+		v, command := config.Viperize(AddFlags, flags.AddFlags)
+		command.ParseFlags([]string{"test", "--collector.http-base-path=" + tc.flag})
+		cOpts := new(CollectorOptions).InitFromViper(v)
+		assert.Equal(t, tc.expected, cOpts.HTTPBasePath, tc.flag)
+	}
+}
+
+func TestCollectorRateLimit(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test"})
+	cOpts := new(CollectorOptions).InitFromViper(v)
+	assert.Zero(t, cOpts.RateLimitQPS)
+	assert.Equal(t, 10, cOpts.RateLimitBurst)
+	assert.Empty(t, cOpts.RateLimitKeyHeader)
+
+	v, command = config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--collector.rate-limit-qps=2.5", "--collector.rate-limit-burst=5", "--collector.rate-limit-key-header=Jaeger-Service"})
+	cOpts = new(CollectorOptions).InitFromViper(v)
+	assert.Equal(t, 2.5, cOpts.RateLimitQPS)
+	assert.Equal(t, 5, cOpts.RateLimitBurst)
+	assert.Equal(t, "Jaeger-Service", cOpts.RateLimitKeyHeader)
+}
+
+func TestCollectorZipkinCORS(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test"})
+	cOpts := new(CollectorOptions).InitFromViper(v)
+	assert.Empty(t, cOpts.CollectorZipkinAllowedOrigins)
+	assert.Equal(t, []string{"Content-Type"}, cOpts.CollectorZipkinAllowedHeaders)
+
+	v, command = config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--collector.zipkin.cors-allowed-origins=http://a.com, http://b.com", "--collector.zipkin.cors-allowed-headers=Content-Type,X-B3-TraceId"})
+	cOpts = new(CollectorOptions).InitFromViper(v)
+	assert.Equal(t, []string{"http://a.com", "http://b.com"}, cOpts.CollectorZipkinAllowedOrigins)
+	assert.Equal(t, []string{"Content-Type", "X-B3-TraceId"}, cOpts.CollectorZipkinAllowedHeaders)
+}
+
+func TestCollectorZipkinStrictIDs(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test"})
+	cOpts := new(CollectorOptions).InitFromViper(v)
+	assert.False(t, cOpts.CollectorZipkinStrictIDs)
+
+	v, command = config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--collector.zipkin.strict-ids"})
+	cOpts = new(CollectorOptions).InitFromViper(v)
+	assert.True(t, cOpts.CollectorZipkinStrictIDs)
+}
+
+func TestCollectorServiceName(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test"})
+	cOpts := new(CollectorOptions).InitFromViper(v)
+	assert.Equal(t, DefaultServiceName, cOpts.ServiceName)
+
+	v, command = config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--collector.service-name=jaeger-collector-canary"})
+	cOpts = new(CollectorOptions).InitFromViper(v)
+	assert.Equal(t, "jaeger-collector-canary", cOpts.ServiceName)
+
+	mBldr := &pMetrics.Builder{Backend: "expvar"}
+	metricsFactory, err := mBldr.CreateMetricsFactory(cOpts.ServiceName)
+	require.NoError(t, err)
+	metricsFactory.Counter("service-name-test", nil).Inc(1)
+	assert.NotNil(t, expvar.Get("jaeger-collector-canary.service-name-test"))
+	assert.Nil(t, expvar.Get(DefaultServiceName+".service-name-test"))
+}
+
+func TestNewSpanHandlerBuilderOperationLatencies(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--span-storage.type=memory", "--collector.emit-operation-latencies=true", "--collector.metrics-max-operations=1"})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+	assert.True(t, cOpts.EmitOperationLatencies)
+	assert.Equal(t, 1, cOpts.MetricsMaxOperations)
+
+	metricsFactory := metrics.NewLocalFactory(0)
+	handler, err := NewSpanHandlerBuilder(
+		cOpts,
+		sFlags,
+		builder.Options.MemoryStoreOption(memory.NewStore()),
+		builder.Options.MetricsFactoryOption(metricsFactory),
+	)
+	require.NoError(t, err)
+	_, jHandler := handler.BuildHandlers()
+
+	ctx, cancel := tchanThrift.NewContext(time.Minute)
+	defer cancel()
+	_, err = jHandler.SubmitBatches(ctx, []*jaeger.Batch{
+		{
+			Process: &jaeger.Process{ServiceName: "frontend"},
+			Spans: []*jaeger.Span{
+				{TraceIdLow: 1, SpanId: 1, OperationName: "GET /dispatch", Duration: 30000},
+				{TraceIdLow: 1, SpanId: 2, OperationName: "GET /config", Duration: 5000},
+			},
+		},
+	})
+	require.NoError(t, err)
+	require.NoError(t, handler.Close())
+
+	_, gauges := metricsFactory.Snapshot()
+	assert.EqualValues(t, 30, gauges["spans.duration|operation=GET /dispatch|svc=frontend.P50"])
+	assert.EqualValues(t, 5, gauges["spans.duration|operation=other|svc=other.P50"])
+}
+
+func TestCollectorOptionsTChannelServiceName(t *testing.T) {
+	v, command := config.Viperize(AddFlags)
+	command.ParseFlags([]string{"test"})
+	cOpts := new(CollectorOptions).InitFromViper(v)
+	assert.Empty(t, cOpts.TChannelServiceName)
+	assert.Empty(t, cOpts.HyperbahnNodes)
+
+	v, command = config.Viperize(AddFlags)
+	command.ParseFlags([]string{
+		"test",
+		"--collector.tchannel-service-name=tracing-collector",
+		"--collector.hyperbahn-nodes=10.0.0.1:21300, 10.0.0.2:21300",
+	})
+	cOpts = new(CollectorOptions).InitFromViper(v)
+	assert.Equal(t, "tracing-collector", cOpts.TChannelServiceName)
+	assert.Equal(t, []string{"10.0.0.1:21300", "10.0.0.2:21300"}, cOpts.HyperbahnNodes)
+}
+
+func TestNewSpanHandlerBuilderAllowedServices(t *testing.T) {
+	dir, err := ioutil.TempDir("", "allowed-services")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "allowed-services.txt")
+	require.NoError(t, ioutil.WriteFile(path, []byte("frontend\n"), 0644))
+
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--span-storage.type=memory", "--collector.allowed-services-file=" + path})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+	assert.Equal(t, path, cOpts.AllowedServicesFile)
+
+	store := memory.NewStore()
+	metricsFactory := metrics.NewLocalFactory(0)
+	handler, err := NewSpanHandlerBuilder(
+		cOpts,
+		sFlags,
+		builder.Options.MemoryStoreOption(store),
+		builder.Options.MetricsFactoryOption(metricsFactory),
+	)
+	require.NoError(t, err)
+	_, jHandler := handler.BuildHandlers()
+
+	ctx, cancel := tchanThrift.NewContext(time.Minute)
+	defer cancel()
+	_, err = jHandler.SubmitBatches(ctx, []*jaeger.Batch{
+		{Process: &jaeger.Process{ServiceName: "frontend"}, Spans: []*jaeger.Span{{TraceIdLow: 1, SpanId: 1}}},
+		{Process: &jaeger.Process{ServiceName: "backend"}, Spans: []*jaeger.Span{{TraceIdLow: 2, SpanId: 2}}},
+	})
+	require.NoError(t, err)
+	require.NoError(t, handler.Close())
+
+	_, err = store.GetTrace(model.TraceID{Low: 1})
+	assert.NoError(t, err, "the span of the allowed service is saved")
+	_, err = store.GetTrace(model.TraceID{Low: 2})
+	assert.Error(t, err, "the span of the unknown service is rejected")
+	counters, _ := metricsFactory.Snapshot()
+	assert.EqualValues(t, 1, counters["spans.rejected|reason=unknown-service"])
+}
+
+// fakeClock is a clock.Clock that tells the time it is set to, the span processor's workers read it concurrently
+type fakeClock struct {
+	sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.Lock()
+	defer c.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestNewSpanHandlerBuilderTimestampSource(t *testing.T) {
+	received := time.Unix(1500000000, 0)
+	skewedStart := received.Add(time.Hour)
+	testCases := []struct {
+		flags    []string
+		expected time.Time
+		adjusted bool
+	}{
+		{
+			flags:    []string{"--collector.timestamp-source=client"},
+			expected: skewedStart,
+		},
+		{
+			flags:    []string{"--collector.timestamp-source=receive"},
+			expected: received,
+		},
+		{
+			flags:    []string{"--collector.timestamp-source=clamp", "--collector.max-clock-skew=1m"},
+			expected: received.Add(time.Minute),
+			adjusted: true,
+		},
+	}
+	for _, tc := range testCases {
+		v, command := config.Viperize(AddFlags, flags.AddFlags)
+		command.ParseFlags(append([]string{"test", "--span-storage.type=memory"}, tc.flags...))
+		sFlags := new(flags.SharedFlags).InitFromViper(v)
+		cOpts := new(CollectorOptions).InitFromViper(v)
+
+		store := memory.NewStore()
+		handler, err := NewSpanHandlerBuilder(
+			cOpts,
+			sFlags,
+			builder.Options.MemoryStoreOption(store),
+			builder.Options.ClockOption(&fakeClock{now: received}),
+		)
+		require.NoError(t, err, tc.flags[0])
+		_, jHandler := handler.BuildHandlers()
+
+		ctx, cancel := tchanThrift.NewContext(time.Minute)
+		_, err = jHandler.SubmitBatches(ctx, []*jaeger.Batch{{
+			Process: &jaeger.Process{ServiceName: "service"},
+			Spans:   []*jaeger.Span{{TraceIdLow: 1, SpanId: 1, StartTime: int64(model.TimeAsEpochMicroseconds(skewedStart))}},
+		}})
+		cancel()
+		require.NoError(t, err, tc.flags[0])
+		require.NoError(t, handler.Close())
+
+		trace, err := store.GetTrace(model.TraceID{Low: 1})
+		require.NoError(t, err, tc.flags[0])
+		require.Len(t, trace.Spans, 1, tc.flags[0])
+		span := trace.Spans[0]
+		assert.True(t, tc.expected.Equal(span.StartTime), "%s: expected %v, got %v", tc.flags[0], tc.expected, span.StartTime)
+		_, ok := span.Tags.FindByKey(app.ClockSkewAdjustedTagKey)
+		assert.Equal(t, tc.adjusted, ok, tc.flags[0])
+	}
+}
+
+func TestNewSpanHandlerBuilderClock(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--span-storage.type=memory", "--collector.dedup-window=1m", "--collector.max-clock-skew=10m"})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+
+	clock := &fakeClock{now: time.Unix(1500000000, 0)}
+	store := memory.NewStore()
+	handler, err := NewSpanHandlerBuilder(
+		cOpts,
+		sFlags,
+		builder.Options.MemoryStoreOption(store),
+		builder.Options.ClockOption(clock),
+	)
+	require.NoError(t, err)
+	_, jHandler := handler.BuildHandlers()
+
+	ctx, cancel := tchanThrift.NewContext(time.Minute)
+	defer cancel()
+	submit := func(spanID int64, startTime time.Time) {
+		_, err := jHandler.SubmitBatches(ctx, []*jaeger.Batch{{
+			Process: &jaeger.Process{ServiceName: "service"},
+			Spans:   []*jaeger.Span{{TraceIdLow: 1, SpanId: spanID, StartTime: int64(model.TimeAsEpochMicroseconds(startTime))}},
+		}})
+		require.NoError(t, err)
+	}
+	// the spans start beyond the max clock skew and are rejected until the clock catches up with them,
+	// after which the retries of span 2 are saved once per dedup window
+	future := clock.Now().Add(time.Hour)
+	submit(1, future)
+	submit(2, future)
+	clock.advance(55 * time.Minute)
+	submit(2, future)
+	clock.advance(30 * time.Second)
+	submit(2, future)
+	clock.advance(time.Minute)
+	submit(2, future)
+	require.NoError(t, handler.Close())
+
+	trace, err := store.GetTrace(model.TraceID{Low: 1})
+	require.NoError(t, err)
+	spanIDs := make([]model.SpanID, 0, len(trace.Spans))
+	for _, span := range trace.Spans {
+		spanIDs = append(spanIDs, span.SpanID)
+	}
+	assert.Equal(t, []model.SpanID{2, 2}, spanIDs)
+}
+
+func TestNewSpanHandlerBuilderBadTimestampSource(t *testing.T) {
+	testCases := []struct {
+		flags []string
+		err   error
+	}{
+		{flags: []string{"--collector.timestamp-source=sundial"}, err: errUnsupportedTimestampSource},
+		{flags: []string{"--collector.timestamp-source=clamp"}, err: errClampWithoutMaxClockSkew},
+	}
+	for _, tc := range testCases {
+		v, command := config.Viperize(AddFlags, flags.AddFlags)
+		command.ParseFlags(append([]string{"test", "--span-storage.type=memory"}, tc.flags...))
+		sFlags := new(flags.SharedFlags).InitFromViper(v)
+		cOpts := new(CollectorOptions).InitFromViper(v)
+
+		_, err := NewSpanHandlerBuilder(cOpts, sFlags, builder.Options.MemoryStoreOption(memory.NewStore()))
+		assert.Equal(t, tc.err, err, tc.flags[0])
+	}
+}
+
+func TestNewSpanHandlerBuilderMirror(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mirror")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "spans.json")
+
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--span-storage.type=memory", "--collector.mirror.fraction=1", "--collector.mirror.target=" + path})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+	assert.Equal(t, 1.0, cOpts.MirrorFraction)
+	assert.Equal(t, path, cOpts.MirrorTarget)
+
+	store := memory.NewStore()
+	handler, err := NewSpanHandlerBuilder(cOpts, sFlags, builder.Options.MemoryStoreOption(store))
+	require.NoError(t, err)
+	_, jHandler := handler.BuildHandlers()
+
+	ctx, cancel := tchanThrift.NewContext(time.Minute)
+	defer cancel()
+	_, err = jHandler.SubmitBatches(ctx, []*jaeger.Batch{{
+		Process: &jaeger.Process{ServiceName: "service"},
+		Spans:   []*jaeger.Span{{TraceIdLow: 1, SpanId: 1}, {TraceIdLow: 1, SpanId: 2}},
+	}})
+	require.NoError(t, err)
+	require.NoError(t, handler.Close())
+
+	trace, err := store.GetTrace(model.TraceID{Low: 1})
+	require.NoError(t, err)
+	assert.Len(t, trace.Spans, 2, "the mirrored spans are saved too")
+	mirrored, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(string(mirrored), "\n"))
+}
+
+func TestNewSpanHandlerBuilderBadMirror(t *testing.T) {
+	testCases := []struct {
+		flags []string
+		err   error
+	}{
+		{flags: []string{"--collector.mirror.fraction=2", "--collector.mirror.target=/tmp/spans.json"}, err: errInvalidMirrorFraction},
+		{flags: []string{"--collector.mirror.fraction=-0.5", "--collector.mirror.target=/tmp/spans.json"}, err: errInvalidMirrorFraction},
+		{flags: []string{"--collector.mirror.fraction=0.5"}, err: errMissingMirrorTarget},
+	}
+	for _, tc := range testCases {
+		v, command := config.Viperize(AddFlags, flags.AddFlags)
+		command.ParseFlags(append([]string{"test", "--span-storage.type=memory"}, tc.flags...))
+		sFlags := new(flags.SharedFlags).InitFromViper(v)
+		cOpts := new(CollectorOptions).InitFromViper(v)
+
+		_, err := NewSpanHandlerBuilder(cOpts, sFlags, builder.Options.MemoryStoreOption(memory.NewStore()))
+		assert.Equal(t, tc.err, err, tc.flags[0])
+	}
+}
+
+func TestCollectorOptionsPortProfile(t *testing.T) {
+	testCases := []struct {
+		flags       []string
+		tchannel    int
+		http        int
+		grpc        int
+		zipkinHTTP  int
+		healthCheck int
+	}{
+		{flags: []string{}, tchannel: 14267, http: 14268, grpc: 14250, zipkinHTTP: 0, healthCheck: 14269},
+		{flags: []string{"--collector.port-profile=default"}, tchannel: 14267, http: 14268, grpc: 14250, zipkinHTTP: 0, healthCheck: 14269},
+		{flags: []string{"--collector.port-profile=legacy"}, tchannel: 14267, http: 14268, grpc: 0, zipkinHTTP: 9411, healthCheck: 14269},
+		{
+			flags:    []string{"--collector.port-profile=legacy", "--collector.zipkin.http-port=9412", "--collector.grpc-port=15250", "--collector.port=15267"},
+			tchannel: 15267, http: 14268, grpc: 15250, zipkinHTTP: 9412, healthCheck: 14269,
+		},
+		{
+			flags:    []string{"--collector.http-port=15268", "--collector.health-check-http-port=15269"},
+			tchannel: 14267, http: 15268, grpc: 14250, zipkinHTTP: 0, healthCheck: 15269,
+		},
+	}
+	for _, tc := range testCases {
+		v, command := config.Viperize(AddFlags)
+		command.ParseFlags(append([]string{"test"}, tc.flags...))
+		cOpts := new(CollectorOptions).InitFromViper(v)
+		assert.Equal(t, tc.tchannel, cOpts.CollectorPort, "%v", tc.flags)
+		assert.Equal(t, tc.http, cOpts.CollectorHTTPPort, "%v", tc.flags)
+		assert.Equal(t, tc.grpc, cOpts.CollectorGRPCPort, "%v", tc.flags)
+		assert.Equal(t, tc.zipkinHTTP, cOpts.CollectorZipkinHTTPPort, "%v", tc.flags)
+		assert.Equal(t, tc.healthCheck, cOpts.CollectorHealthCheckHTTPPort, "%v", tc.flags)
+	}
+}
+
+func TestNewSpanHandlerBuilderBadPortProfile(t *testing.T) {
+	v, command := config.Viperize(AddFlags, flags.AddFlags)
+	command.ParseFlags([]string{"test", "--span-storage.type=memory", "--collector.port-profile=sneh"})
+	sFlags := new(flags.SharedFlags).InitFromViper(v)
+	cOpts := new(CollectorOptions).InitFromViper(v)
+	assert.Equal(t, 14267, cOpts.CollectorPort, "an unknown profile leaves the ports as they are")
+
+	_, err := NewSpanHandlerBuilder(cOpts, sFlags, builder.Options.MemoryStoreOption(memory.NewStore()))
+	assert.Equal(t, errUnsupportedPortProfile, err)
+}
+
+func TestCollectorOptionsStartupRetries(t *testing.T) {
+	v, command := config.Viperize(AddFlags)
+	command.ParseFlags([]string{"test"})
+	cOpts := new(CollectorOptions).InitFromViper(v)
+	assert.Equal(t, 0, cOpts.StartupRetries)
+	assert.Equal(t, time.Second, cOpts.StartupRetryBackoff)
+
+	v, command = config.Viperize(AddFlags)
+	command.ParseFlags([]string{"test", "--collector.startup-retries=5", "--collector.startup-retry-backoff=250ms"})
+	cOpts = new(CollectorOptions).InitFromViper(v)
+	assert.Equal(t, 5, cOpts.StartupRetries)
+	assert.Equal(t, 250*time.Millisecond, cOpts.StartupRetryBackoff)
+}
+
+func TestCollectorOptionsReadinessFile(t *testing.T) {
+	v, command := config.Viperize(AddFlags)
+	command.ParseFlags([]string{"test"})
+	cOpts := new(CollectorOptions).InitFromViper(v)
+	assert.Empty(t, cOpts.ReadinessFile)
+
+	v, command = config.Viperize(AddFlags)
+	command.ParseFlags([]string{"test", "--collector.readiness-file=/run/jaeger/ready"})
+	cOpts = new(CollectorOptions).InitFromViper(v)
+	assert.Equal(t, "/run/jaeger/ready", cOpts.ReadinessFile)
+}
+
+func TestCollectorOptionsHTTPAuth(t *testing.T) {
+	v, command := config.Viperize(AddFlags)
+	command.ParseFlags([]string{"test"})
+	cOpts := new(CollectorOptions).InitFromViper(v)
+	assert.Equal(t, HTTPAuthNone, cOpts.HTTPAuth)
+
+	v, command = config.Viperize(AddFlags)
+	command.ParseFlags([]string{
+		"test",
+		"--collector.http.auth=basic",
+		"--collector.http.auth.username=jaeger",
+		"--collector.http.auth.password=secret",
+		"--collector.http.auth.token=token",
+		"--collector.http.auth.token-file=/etc/jaeger/tokens",
+	})
+	cOpts = new(CollectorOptions).InitFromViper(v)
+	assert.Equal(t, HTTPAuthBasic, cOpts.HTTPAuth)
+	assert.Equal(t, "jaeger", cOpts.HTTPAuthUsername)
+	assert.Equal(t, "secret", cOpts.HTTPAuthPassword)
+	assert.Equal(t, "token", cOpts.HTTPAuthToken)
+	assert.Equal(t, "/etc/jaeger/tokens", cOpts.HTTPAuthTokenFile)
 }
@@ -0,0 +1,151 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"encoding/json"
+	"mime"
+	"sync"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/golang/protobuf/proto"
+
+	"github.com/uber/jaeger/model"
+	jsonConv "github.com/uber/jaeger/model/converter/json"
+	pConv "github.com/uber/jaeger/model/converter/proto/jaeger"
+	oConv "github.com/uber/jaeger/model/converter/proto/otlp"
+	jConv "github.com/uber/jaeger/model/converter/thrift/jaeger"
+	uiJSON "github.com/uber/jaeger/model/json"
+	pJaeger "github.com/uber/jaeger/proto-gen/jaeger"
+	"github.com/uber/jaeger/proto-gen/otlp"
+	tJaeger "github.com/uber/jaeger/thrift-gen/jaeger"
+)
+
+const (
+	// JaegerThriftContentType is the Content-Type of a Jaeger Thrift batch posted to the HTTP API
+	JaegerThriftContentType = "application/vnd.apache.thrift.binary"
+	// JaegerProtobufContentType is the Content-Type of a Protobuf Jaeger batch posted to the HTTP API
+	JaegerProtobufContentType = "application/x-protobuf"
+)
+
+// SpanDecoder converts the body of a request posted to the collector's HTTP API into spans
+type SpanDecoder interface {
+	// Decode returns the spans in the request body
+	Decode(body []byte) ([]*model.Span, error)
+	// SpanFormat is the format type the decoded spans are processed as, e.g. JaegerFormatType
+	SpanFormat() string
+}
+
+var decoders = struct {
+	sync.RWMutex
+	byContentType map[string]SpanDecoder
+}{byContentType: make(map[string]SpanDecoder)}
+
+func init() {
+	RegisterDecoder(JaegerThriftContentType, jaegerThriftDecoder{})
+	RegisterDecoder(JaegerProtobufContentType, jaegerProtobufDecoder{})
+}
+
+// RegisterDecoder makes the HTTP API decode the requests posted with contentType using decoder.
+// It replaces the decoder previously registered for contentType, if any.
+func RegisterDecoder(contentType string, decoder SpanDecoder) {
+	decoders.Lock()
+	defer decoders.Unlock()
+	decoders.byContentType[mediaType(contentType)] = decoder
+}
+
+// LookupDecoder returns the decoder registered for contentType, ignoring its parameters such as the charset.
+func LookupDecoder(contentType string) (SpanDecoder, bool) {
+	decoders.RLock()
+	defer decoders.RUnlock()
+	decoder, ok := decoders.byContentType[mediaType(contentType)]
+	return decoder, ok
+}
+
+func mediaType(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return contentType
+	}
+	return mediaType
+}
+
+type jaegerThriftDecoder struct{}
+
+func (jaegerThriftDecoder) Decode(body []byte) ([]*model.Span, error) {
+	batch := &tJaeger.Batch{}
+	if err := thrift.NewTDeserializer().Read(batch, body); err != nil {
+		return nil, err
+	}
+	return jConv.ToDomain(batch.Spans, batch.Process), nil
+}
+
+func (jaegerThriftDecoder) SpanFormat() string {
+	return JaegerFormatType
+}
+
+type jaegerProtobufDecoder struct{}
+
+func (jaegerProtobufDecoder) Decode(body []byte) ([]*model.Span, error) {
+	batch := &pJaeger.Batch{}
+	if err := proto.Unmarshal(body, batch); err != nil {
+		return nil, err
+	}
+	return pConv.ToDomain(batch), nil
+}
+
+func (jaegerProtobufDecoder) SpanFormat() string {
+	return JaegerFormatType
+}
+
+// otlpDecoder decodes the OTLP/HTTP binary Protobuf encoding of an ExportTraceServiceRequest. It is not
+// registered since OTLP shares its Content-Type with JaegerProtobufContentType, it is used by the /v1/traces route.
+type otlpDecoder struct{}
+
+func (otlpDecoder) Decode(body []byte) ([]*model.Span, error) {
+	req := &otlp.ExportTraceServiceRequest{}
+	if err := proto.Unmarshal(body, req); err != nil {
+		return nil, err
+	}
+	return oConv.ToDomain(req.ResourceSpans)
+}
+
+func (otlpDecoder) SpanFormat() string {
+	return OTLPFormatType
+}
+
+// jsonSpanDecoder decodes a single span in the JSON format of the query service, with its process embedded.
+// It is not registered since it does not decode batches, it is used by the JSONSpanPath route.
+type jsonSpanDecoder struct{}
+
+func (jsonSpanDecoder) Decode(body []byte) ([]*model.Span, error) {
+	var jsonSpan uiJSON.Span
+	if err := json.Unmarshal(body, &jsonSpan); err != nil {
+		return nil, err
+	}
+	// root spans may omit their parent, which the converter expects as a hex ID
+	if jsonSpan.ParentSpanID == "" {
+		jsonSpan.ParentSpanID = "0"
+	}
+	span, err := jsonConv.SpanToDomain(&jsonSpan)
+	if err != nil {
+		return nil, err
+	}
+	return []*model.Span{span}, nil
+}
+
+func (jsonSpanDecoder) SpanFormat() string {
+	return JaegerFormatType
+}
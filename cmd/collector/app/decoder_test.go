@@ -0,0 +1,397 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/golang/protobuf/proto"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics"
+
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/pkg/clock"
+	pJaeger "github.com/uber/jaeger/proto-gen/jaeger"
+	"github.com/uber/jaeger/storage/spanstore/memory"
+	"github.com/uber/jaeger/thrift-gen/jaeger"
+)
+
+const fakeContentType = "application/vnd.fake.spans"
+
+// fakeDecoder decodes a body made of a service name
+type fakeDecoder struct {
+	err error
+}
+
+func (d fakeDecoder) Decode(body []byte) ([]*model.Span, error) {
+	if d.err != nil {
+		return nil, d.err
+	}
+	return []*model.Span{{Process: &model.Process{ServiceName: string(body)}}}, nil
+}
+
+func (fakeDecoder) SpanFormat() string {
+	return "fake"
+}
+
+type recordingProcessor struct {
+	err    error
+	mux    sync.Mutex
+	spans  []*model.Span
+	format string
+}
+
+func (p *recordingProcessor) ProcessSpans(mSpans []*model.Span, spanFormat string) ([]bool, error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if p.err != nil {
+		return nil, p.err
+	}
+	p.spans = append(p.spans, mSpans...)
+	p.format = spanFormat
+	return make([]bool, len(mSpans)), nil
+}
+
+func (p *recordingProcessor) getSpans() ([]*model.Span, string) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	return p.spans, p.format
+}
+
+func (p *recordingProcessor) setErr(err error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	p.err = err
+}
+
+func (p *recordingProcessor) Close() error {
+	return nil
+}
+
+func postWithContentType(t *testing.T, urlStr string, contentType string, body []byte) (int, string) {
+	req, err := http.NewRequest(http.MethodPost, urlStr, bytes.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", contentType)
+	res, err := httpClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	resBody, err := ioutil.ReadAll(res.Body)
+	require.NoError(t, err)
+	return res.StatusCode, string(resBody)
+}
+
+func TestLookupDecoder(t *testing.T) {
+	RegisterDecoder(fakeContentType, fakeDecoder{})
+
+	decoder, ok := LookupDecoder(fakeContentType)
+	assert.True(t, ok)
+	assert.Equal(t, fakeDecoder{}, decoder)
+
+	decoder, ok = LookupDecoder("Application/Vnd.Fake.Spans; charset=utf-8")
+	assert.True(t, ok)
+	assert.Equal(t, fakeDecoder{}, decoder)
+
+	_, ok = LookupDecoder("application/vnd.unknown")
+	assert.False(t, ok)
+
+	decoder, ok = LookupDecoder(JaegerThriftContentType)
+	assert.True(t, ok)
+	assert.Equal(t, JaegerFormatType, decoder.SpanFormat())
+
+	decoder, ok = LookupDecoder(JaegerProtobufContentType)
+	assert.True(t, ok)
+	assert.Equal(t, JaegerFormatType, decoder.SpanFormat())
+}
+
+func TestJaegerThriftDecoder(t *testing.T) {
+	batch := &jaeger.Batch{
+		Process: &jaeger.Process{ServiceName: "serviceName"},
+		Spans:   []*jaeger.Span{{TraceIdLow: 1, SpanId: 2, OperationName: "op"}},
+	}
+	body, err := thrift.NewTSerializer().Write(batch)
+	require.NoError(t, err)
+
+	spans, err := jaegerThriftDecoder{}.Decode(body)
+	require.NoError(t, err)
+	require.Len(t, spans, 1)
+	assert.Equal(t, "serviceName", spans[0].Process.ServiceName)
+	assert.Equal(t, "op", spans[0].OperationName)
+	assert.Equal(t, model.SpanID(2), spans[0].SpanID)
+
+	_, err = jaegerThriftDecoder{}.Decode([]byte("not thrift"))
+	assert.Error(t, err)
+}
+
+func protobufBatch(t *testing.T) []byte {
+	body, err := proto.Marshal(&pJaeger.Batch{
+		Process: &pJaeger.Process{ServiceName: "serviceName"},
+		Spans: []*pJaeger.Span{{
+			TraceIdLow:    1,
+			SpanId:        2,
+			OperationName: "op",
+			StartTime:     1485467191639875,
+			Duration:      5,
+			Tags:          []*pJaeger.KeyValue{{Key: "retry", VType: pJaeger.ValueType_BOOL, VBool: true}},
+		}},
+	})
+	require.NoError(t, err)
+	return body
+}
+
+func TestJaegerProtobufDecoder(t *testing.T) {
+	spans, err := jaegerProtobufDecoder{}.Decode(protobufBatch(t))
+	require.NoError(t, err)
+	require.Len(t, spans, 1)
+	assert.Equal(t, "serviceName", spans[0].Process.ServiceName)
+	assert.Equal(t, "op", spans[0].OperationName)
+	assert.Equal(t, model.TraceID{Low: 1}, spans[0].TraceID)
+	assert.Equal(t, model.SpanID(2), spans[0].SpanID)
+	assert.Equal(t, model.EpochMicrosecondsAsTime(1485467191639875), spans[0].StartTime)
+	assert.Equal(t, model.KeyValues{model.Bool("retry", true)}, spans[0].Tags)
+
+	_, err = jaegerProtobufDecoder{}.Decode([]byte("not protobuf"))
+	assert.Error(t, err)
+}
+
+func TestSaveSpansV2(t *testing.T) {
+	processor := &recordingProcessor{}
+	r := mux.NewRouter()
+	NewAPIHandler(&mockJaegerHandler{}, HandlerOptions.SpanProcessor(processor)).RegisterRoutes(r)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	for _, contentType := range []string{JaegerProtobufContentType, ""} {
+		statusCode, body := postWithContentType(t, server.URL+"/api/v2/spans", contentType, protobufBatch(t))
+		assert.EqualValues(t, http.StatusAccepted, statusCode, contentType)
+		assert.Empty(t, body)
+	}
+	spans, format := processor.getSpans()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "op", spans[0].OperationName)
+	assert.Equal(t, "op", spans[1].OperationName)
+	assert.Equal(t, JaegerFormatType, format)
+
+	statusCode, body := postWithContentType(t, server.URL+"/api/traces", JaegerProtobufContentType, protobufBatch(t))
+	assert.EqualValues(t, http.StatusAccepted, statusCode)
+	assert.Empty(t, body)
+
+	statusCode, body = postWithContentType(t, server.URL+"/api/v2/spans", JaegerProtobufContentType, []byte("not protobuf"))
+	assert.EqualValues(t, http.StatusBadRequest, statusCode)
+	assert.Contains(t, body, "Unable to process request body")
+}
+
+func TestSaveSpansV2WithoutSpanProcessor(t *testing.T) {
+	server, _ := initializeTestServer(nil)
+	defer server.Close()
+
+	statusCode, _ := postWithContentType(t, server.URL+"/api/v2/spans", JaegerProtobufContentType, protobufBatch(t))
+	assert.EqualValues(t, http.StatusNotFound, statusCode)
+}
+
+// otlpFixture is an ExportTraceServiceRequest encoded by the OpenTelemetry Go protobuf bindings
+const otlpFixture = "../../../model/converter/proto/otlp/fixtures/otlp_01.pb"
+
+func TestOTLPDecoder(t *testing.T) {
+	body, err := ioutil.ReadFile(otlpFixture)
+	require.NoError(t, err)
+	spans, err := otlpDecoder{}.Decode(body)
+	require.NoError(t, err)
+	require.Len(t, spans, 1)
+	assert.Equal(t, "frontend", spans[0].Process.ServiceName)
+	assert.Equal(t, "GET /", spans[0].OperationName)
+	assert.Equal(t, model.TraceID{High: 2, Low: 1}, spans[0].TraceID)
+	assert.Equal(t, OTLPFormatType, otlpDecoder{}.SpanFormat())
+
+	_, err = otlpDecoder{}.Decode([]byte("not protobuf"))
+	assert.Error(t, err)
+}
+
+func TestSaveOTLPSpans(t *testing.T) {
+	body, err := ioutil.ReadFile(otlpFixture)
+	require.NoError(t, err)
+	processor := &recordingProcessor{}
+	r := mux.NewRouter()
+	NewAPIHandler(&mockJaegerHandler{}, HandlerOptions.SpanProcessor(processor)).RegisterRoutes(r)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	statusCode, resBody := postWithContentType(t, server.URL+OTLPTracesPath, OTLPProtobufContentType, body)
+	assert.EqualValues(t, http.StatusOK, statusCode)
+	assert.Empty(t, resBody)
+	spans, format := processor.getSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "GET /", spans[0].OperationName)
+	assert.Equal(t, OTLPFormatType, format)
+
+	statusCode, resBody = postWithContentType(t, server.URL+OTLPTracesPath, "application/json", body)
+	assert.EqualValues(t, http.StatusUnsupportedMediaType, statusCode)
+	assert.Equal(t, "Unsupported Content-Type: application/json\n", resBody)
+
+	statusCode, resBody = postWithContentType(t, server.URL+OTLPTracesPath, OTLPProtobufContentType, []byte("not protobuf"))
+	assert.EqualValues(t, http.StatusBadRequest, statusCode)
+	assert.Contains(t, resBody, "Unable to process request body")
+
+	processor.setErr(ErrBatchTooLarge)
+	statusCode, resBody = postWithContentType(t, server.URL+OTLPTracesPath, OTLPProtobufContentType, body)
+	assert.EqualValues(t, http.StatusRequestEntityTooLarge, statusCode)
+	assert.Equal(t, "Cannot submit spans: batch has too many spans\n", resBody)
+}
+
+func TestSaveOTLPSpansWithoutSpanProcessor(t *testing.T) {
+	server, _ := initializeTestServer(nil)
+	defer server.Close()
+
+	statusCode, _ := postWithContentType(t, server.URL+OTLPTracesPath, OTLPProtobufContentType, nil)
+	assert.EqualValues(t, http.StatusNotFound, statusCode)
+}
+
+const jsonSpan = `{
+	"traceID": "abc0",
+	"spanID": "1",
+	"operationName": "GET /",
+	"startTime": 1500000000000000,
+	"duration": 500,
+	"tags": [{"key": "http.status_code", "type": "int64", "value": 200}],
+	"process": {"serviceName": "frontend", "tags": [{"key": "hostname", "type": "string", "value": "web-1"}]}
+}`
+
+func TestJSONSpanDecoder(t *testing.T) {
+	spans, err := jsonSpanDecoder{}.Decode([]byte(jsonSpan))
+	require.NoError(t, err)
+	require.Len(t, spans, 1)
+	assert.Equal(t, model.TraceID{Low: 0xabc0}, spans[0].TraceID)
+	assert.Equal(t, model.SpanID(1), spans[0].SpanID)
+	assert.Equal(t, model.SpanID(0), spans[0].ParentSpanID)
+	assert.Equal(t, "GET /", spans[0].OperationName)
+	assert.Equal(t, model.KeyValues{model.Int64("http.status_code", 200)}, spans[0].Tags)
+	assert.Equal(t, "frontend", spans[0].Process.ServiceName)
+	assert.Equal(t, model.KeyValues{model.String("hostname", "web-1")}, spans[0].Process.Tags)
+	assert.Equal(t, JaegerFormatType, jsonSpanDecoder{}.SpanFormat())
+
+	_, err = jsonSpanDecoder{}.Decode([]byte("not json"))
+	assert.Error(t, err)
+	_, err = jsonSpanDecoder{}.Decode([]byte(`{"traceID": "abc0", "spanID": "1"}`))
+	assert.EqualError(t, err, "Process is nil")
+}
+
+func TestSaveJSONSpan(t *testing.T) {
+	processor := &recordingProcessor{}
+	r := mux.NewRouter()
+	NewAPIHandler(&mockJaegerHandler{}, HandlerOptions.SpanProcessor(processor)).RegisterRoutes(r)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	statusCode, body := postWithContentType(t, server.URL+JSONSpanPath, "application/json", []byte(jsonSpan))
+	assert.EqualValues(t, http.StatusAccepted, statusCode)
+	assert.Empty(t, body)
+	spans, format := processor.getSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "GET /", spans[0].OperationName)
+	assert.Equal(t, JaegerFormatType, format)
+
+	statusCode, body = postWithContentType(t, server.URL+JSONSpanPath, "application/json", []byte("not json"))
+	assert.EqualValues(t, http.StatusBadRequest, statusCode)
+	assert.Contains(t, body, "Unable to process request body")
+
+	processor.setErr(ErrBatchTooLarge)
+	statusCode, body = postWithContentType(t, server.URL+JSONSpanPath, "application/json", []byte(jsonSpan))
+	assert.EqualValues(t, http.StatusRequestEntityTooLarge, statusCode)
+	assert.Equal(t, "Cannot submit span: batch has too many spans\n", body)
+}
+
+func TestSaveJSONSpanStored(t *testing.T) {
+	store := memory.NewStore()
+	validator := NewSpanValidator(0, clock.System, metrics.NullFactory)
+	processor := NewSpanProcessor(store, Options.QueueSize(10), Options.SpanFilter(validator.Validate))
+	r := mux.NewRouter()
+	NewAPIHandler(&mockJaegerHandler{}, HandlerOptions.SpanProcessor(processor)).RegisterRoutes(r)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	statusCode, _ := postWithContentType(t, server.URL+JSONSpanPath, "application/json", []byte(jsonSpan))
+	assert.EqualValues(t, http.StatusAccepted, statusCode)
+	invalidSpan := strings.Replace(jsonSpan, `"traceID": "abc0"`, `"traceID": "abc1"`, 1)
+	invalidSpan = strings.Replace(invalidSpan, `"spanID": "1"`, `"spanID": "0"`, 1)
+	statusCode, _ = postWithContentType(t, server.URL+JSONSpanPath, "application/json", []byte(invalidSpan))
+	assert.EqualValues(t, http.StatusAccepted, statusCode)
+	require.NoError(t, processor.Close())
+
+	trace, err := store.GetTrace(model.TraceID{Low: 0xabc0})
+	require.NoError(t, err)
+	require.Len(t, trace.Spans, 1)
+	assert.Equal(t, "frontend", trace.Spans[0].Process.ServiceName)
+	_, err = store.GetTrace(model.TraceID{Low: 0xabc1})
+	assert.Error(t, err, "the span without a span ID is rejected by the validator")
+}
+
+func TestSaveJSONSpanWithoutSpanProcessor(t *testing.T) {
+	server, _ := initializeTestServer(nil)
+	defer server.Close()
+
+	statusCode, _ := postWithContentType(t, server.URL+JSONSpanPath, "application/json", []byte(jsonSpan))
+	assert.EqualValues(t, http.StatusNotFound, statusCode)
+}
+
+func TestSaveDecodedSpans(t *testing.T) {
+	RegisterDecoder(fakeContentType, fakeDecoder{})
+	RegisterDecoder("application/vnd.fake.broken", fakeDecoder{err: errors.New("bad spans")})
+
+	processor := &recordingProcessor{}
+	r := mux.NewRouter()
+	NewAPIHandler(&mockJaegerHandler{}, HandlerOptions.SpanProcessor(processor)).RegisterRoutes(r)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	statusCode, body := postWithContentType(t, server.URL+"/api/traces", fakeContentType, []byte("fake-service"))
+	assert.EqualValues(t, http.StatusAccepted, statusCode)
+	assert.Empty(t, body)
+	spans, format := processor.getSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "fake-service", spans[0].Process.ServiceName)
+	assert.Equal(t, "fake", format)
+
+	statusCode, body = postWithContentType(t, server.URL+"/api/traces", "application/vnd.unknown", []byte("fake-service"))
+	assert.EqualValues(t, http.StatusBadRequest, statusCode)
+	assert.Equal(t, "Unsupported Content-Type: application/vnd.unknown\n", body)
+
+	statusCode, body = postWithContentType(t, server.URL+"/api/traces", "application/vnd.fake.broken", []byte("fake-service"))
+	assert.EqualValues(t, http.StatusBadRequest, statusCode)
+	assert.Equal(t, "Unable to process request body: bad spans\n", body)
+
+	processor.setErr(ErrBatchTooLarge)
+	statusCode, body = postWithContentType(t, server.URL+"/api/traces", fakeContentType, []byte("fake-service"))
+	assert.EqualValues(t, http.StatusRequestEntityTooLarge, statusCode)
+	assert.Equal(t, "Cannot submit spans: batch has too many spans\n", body)
+}
+
+func TestSaveDecodedSpansWithoutSpanProcessor(t *testing.T) {
+	RegisterDecoder(fakeContentType, fakeDecoder{})
+	server, _ := initializeTestServer(nil)
+	defer server.Close()
+
+	statusCode, body := postWithContentType(t, server.URL+"/api/traces", fakeContentType, []byte("fake-service"))
+	assert.EqualValues(t, http.StatusBadRequest, statusCode)
+	assert.Equal(t, "Unsupported format type: \n", body)
+}
@@ -0,0 +1,112 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"time"
+
+	"github.com/uber/jaeger-lib/metrics"
+
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/pkg/cache"
+	"github.com/uber/jaeger/pkg/clock"
+)
+
+// DefaultDedupMaxSpans is the default number of recently seen spans remembered by a SpanDeduplicator
+const DefaultDedupMaxSpans = 100000
+
+// SpanDeduplicator drops spans whose trace and span IDs were already seen within a time window,
+// e.g. when an agent retries a batch that timed out but was processed
+type SpanDeduplicator struct {
+	seen         cache.Cache
+	deduplicated metrics.Counter
+}
+
+// NewSpanDeduplicator creates a SpanDeduplicator that remembers spans for window of clock, and at most
+// maxSpans of them, evicting the least recently seen first. Dropped spans are counted in the
+// spans.deduplicated counter.
+func NewSpanDeduplicator(window time.Duration, maxSpans int, clock clock.Clock, metricsFactory metrics.Factory) *SpanDeduplicator {
+	return &SpanDeduplicator{
+		seen:         cache.NewLRUWithOptions(maxSpans, &cache.Options{TTL: window, TimeNow: clock.Now}),
+		deduplicated: metricsFactory.Counter("spans.deduplicated", nil),
+	}
+}
+
+// SpanProcessor returns a SpanProcessor that passes the spans not seen before to processor.
+// Duplicate spans are reported as processed. Spans that processor does not accept are forgotten,
+// so that they can be retried.
+func (d *SpanDeduplicator) SpanProcessor(processor SpanProcessor) SpanProcessor {
+	return &dedupSpanProcessor{dedup: d, SpanProcessor: processor}
+}
+
+// markSeen returns false if the span was already seen, otherwise it remembers the span
+func (d *SpanDeduplicator) markSeen(key string) bool {
+	// Get evicts an expired entry, which CompareAndSwap would not
+	if d.seen.Get(key) != nil {
+		return false
+	}
+	_, swapped := d.seen.CompareAndSwap(key, nil, true)
+	return swapped
+}
+
+func (d *SpanDeduplicator) forget(key string) {
+	d.seen.Delete(key)
+}
+
+func dedupKey(span *model.Span) string {
+	return span.TraceID.String() + ":" + span.SpanID.String()
+}
+
+type dedupSpanProcessor struct {
+	dedup *SpanDeduplicator
+	SpanProcessor
+}
+
+func (p *dedupSpanProcessor) ProcessSpans(mSpans []*model.Span, spanFormat string) ([]bool, error) {
+	retMe := make([]bool, len(mSpans))
+	newSpans := make([]*model.Span, 0, len(mSpans))
+	var newIndexes []int
+	var newKeys []string
+	for i, span := range mSpans {
+		key := dedupKey(span)
+		if !p.dedup.markSeen(key) {
+			retMe[i] = true
+			continue
+		}
+		newSpans = append(newSpans, span)
+		newIndexes = append(newIndexes, i)
+		newKeys = append(newKeys, key)
+	}
+	if duplicates := len(mSpans) - len(newSpans); duplicates > 0 {
+		p.dedup.deduplicated.Inc(int64(duplicates))
+	}
+	if len(newSpans) == 0 {
+		return retMe, nil
+	}
+	oks, err := p.SpanProcessor.ProcessSpans(newSpans, spanFormat)
+	if err != nil {
+		for _, key := range newKeys {
+			p.dedup.forget(key)
+		}
+		return nil, err
+	}
+	for j, ok := range oks {
+		retMe[newIndexes[j]] = ok
+		if !ok {
+			p.dedup.forget(newKeys[j])
+		}
+	}
+	return retMe, nil
+}
@@ -0,0 +1,134 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics"
+
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/pkg/clock"
+)
+
+type countingSpanWriter struct {
+	writes int64
+}
+
+func (w *countingSpanWriter) WriteSpan(ctx context.Context, span *model.Span) error {
+	atomic.AddInt64(&w.writes, 1)
+	return nil
+}
+
+func dedupSpan(spanID uint64) *model.Span {
+	return &model.Span{
+		TraceID: model.TraceID{Low: 1},
+		SpanID:  model.SpanID(spanID),
+		Process: &model.Process{ServiceName: "service"},
+	}
+}
+
+func TestSpanDeduplicator(t *testing.T) {
+	mb := metrics.NewLocalFactory(time.Hour)
+	writer := &countingSpanWriter{}
+	processor := NewSpanDeduplicator(time.Minute, 10, clock.System, mb).SpanProcessor(NewSpanProcessor(writer))
+
+	oks, err := processor.ProcessSpans([]*model.Span{dedupSpan(1)}, JaegerFormatType)
+	require.NoError(t, err)
+	assert.Equal(t, []bool{true}, oks)
+	oks, err = processor.ProcessSpans([]*model.Span{dedupSpan(1), dedupSpan(2), dedupSpan(2)}, JaegerFormatType)
+	require.NoError(t, err)
+	assert.Equal(t, []bool{true, true, true}, oks, "duplicates are reported as processed")
+	require.NoError(t, processor.Close())
+
+	assert.EqualValues(t, 2, atomic.LoadInt64(&writer.writes))
+	counters, _ := mb.Snapshot()
+	assert.EqualValues(t, 2, counters["spans.deduplicated"])
+}
+
+func TestSpanDeduplicatorWindow(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	dedup := NewSpanDeduplicator(time.Minute, 10, clock, metrics.NullFactory)
+	recorder := &recordingProcessor{}
+	processor := dedup.SpanProcessor(&acceptingProcessor{recorder})
+
+	_, err := processor.ProcessSpans([]*model.Span{dedupSpan(1)}, JaegerFormatType)
+	require.NoError(t, err)
+	clock.now = clock.now.Add(30 * time.Second)
+	_, err = processor.ProcessSpans([]*model.Span{dedupSpan(1)}, JaegerFormatType)
+	require.NoError(t, err)
+	spans, _ := recorder.getSpans()
+	assert.Len(t, spans, 1, "the span is a duplicate within the window")
+
+	clock.now = clock.now.Add(time.Minute)
+	_, err = processor.ProcessSpans([]*model.Span{dedupSpan(1)}, JaegerFormatType)
+	require.NoError(t, err)
+	spans, _ = recorder.getSpans()
+	assert.Len(t, spans, 2, "the span is processed again after the window")
+}
+
+func TestSpanDeduplicatorMaxSpans(t *testing.T) {
+	recorder := &recordingProcessor{}
+	processor := NewSpanDeduplicator(time.Minute, 1, clock.System, metrics.NullFactory).SpanProcessor(&acceptingProcessor{recorder})
+
+	for _, spanID := range []uint64{1, 2, 1} {
+		_, err := processor.ProcessSpans([]*model.Span{dedupSpan(spanID)}, JaegerFormatType)
+		require.NoError(t, err)
+	}
+	spans, _ := recorder.getSpans()
+	assert.Len(t, spans, 3, "the first span was evicted when the second one was seen")
+}
+
+func TestSpanDeduplicatorForgetsUnprocessedSpans(t *testing.T) {
+	recorder := &recordingProcessor{}
+	processor := NewSpanDeduplicator(time.Minute, 10, clock.System, metrics.NullFactory).SpanProcessor(recorder)
+
+	// recordingProcessor reports the spans as not processed, e.g. because the queue is full
+	oks, err := processor.ProcessSpans([]*model.Span{dedupSpan(1)}, JaegerFormatType)
+	require.NoError(t, err)
+	assert.Equal(t, []bool{false}, oks)
+
+	recorder.setErr(errors.New("busy"))
+	_, err = processor.ProcessSpans([]*model.Span{dedupSpan(1)}, JaegerFormatType)
+	assert.EqualError(t, err, "busy")
+
+	recorder.setErr(nil)
+	_, err = processor.ProcessSpans([]*model.Span{dedupSpan(1)}, JaegerFormatType)
+	require.NoError(t, err)
+	spans, _ := recorder.getSpans()
+	assert.Len(t, spans, 2, "the span is retried until it is processed")
+}
+
+// acceptingProcessor reports every span as processed
+type acceptingProcessor struct {
+	*recordingProcessor
+}
+
+func (p *acceptingProcessor) ProcessSpans(mSpans []*model.Span, spanFormat string) ([]bool, error) {
+	if _, err := p.recordingProcessor.ProcessSpans(mSpans, spanFormat); err != nil {
+		return nil, err
+	}
+	oks := make([]bool, len(mSpans))
+	for i := range oks {
+		oks[i] = true
+	}
+	return oks, nil
+}
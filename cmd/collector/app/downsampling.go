@@ -0,0 +1,126 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"math"
+
+	"github.com/uber/jaeger-lib/metrics"
+
+	"github.com/uber/jaeger/model"
+)
+
+const (
+	fnvOffset64 = 14695981039346656037
+	fnvPrime64  = 1099511628211
+)
+
+// SamplingRateTagKey is the tag added to the spans kept by the downsampling, its value is the ratio of
+// the traces kept so that the metrics derived from the stored spans can be extrapolated
+const SamplingRateTagKey = "sampler.collector.rate"
+
+// Sampler decides which spans the collector keeps when it downsamples spans at ingestion,
+// regardless of how the clients sampled them
+type Sampler interface {
+	// ShouldSample returns true if the span is kept
+	ShouldSample(span *model.Span) bool
+}
+
+// ProbabilisticSampler keeps a fraction of the traces. It decides from a hash of the trace ID, so all the
+// spans of a trace are kept or dropped together, by every collector configured with the same ratio and salt.
+type ProbabilisticSampler struct {
+	threshold uint64
+	keepAll   bool
+	saltHash  uint64
+}
+
+// NewProbabilisticSampler creates a ProbabilisticSampler that keeps ratio of the traces, between 0 and 1.
+// The salt is hashed with the trace IDs, changing it keeps a different set of traces.
+func NewProbabilisticSampler(ratio float64, salt string) *ProbabilisticSampler {
+	saltHash := uint64(fnvOffset64)
+	for i := 0; i < len(salt); i++ {
+		saltHash = fnvStep(saltHash, salt[i])
+	}
+	return &ProbabilisticSampler{
+		threshold: uint64(ratio * math.MaxUint64),
+		keepAll:   ratio >= 1,
+		saltHash:  saltHash,
+	}
+}
+
+// ShouldSample returns true if the hash of the span's trace ID falls within the ratio of traces kept
+func (s *ProbabilisticSampler) ShouldSample(span *model.Span) bool {
+	return s.keepAll || s.hash(span.TraceID) < s.threshold
+}
+
+// hash continues the FNV-1a hash of the salt with the bytes of the trace ID. The last bytes of FNV-1a
+// barely change its high bits, so the result is mixed like in MurmurHash3 to spread trace IDs that
+// only differ in their last bytes, e.g. ones generated by a counter, evenly across the range.
+func (s *ProbabilisticSampler) hash(traceID model.TraceID) uint64 {
+	h := s.saltHash
+	for _, v := range [2]uint64{traceID.High, traceID.Low} {
+		for shift := uint(56); ; shift -= 8 {
+			h = fnvStep(h, byte(v>>shift))
+			if shift == 0 {
+				break
+			}
+		}
+	}
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
+
+func fnvStep(h uint64, b byte) uint64 {
+	return (h ^ uint64(b)) * fnvPrime64
+}
+
+// DownsamplingFilter drops the spans that a Sampler does not keep, except debug spans
+type DownsamplingFilter struct {
+	sampler Sampler
+	dropped *counterBySvc
+}
+
+// NewDownsamplingFilter creates a DownsamplingFilter. Dropped spans are counted in the spans.downsampled
+// counter tagged by service.
+func NewDownsamplingFilter(sampler Sampler, metricsFactory metrics.Factory) *DownsamplingFilter {
+	return &DownsamplingFilter{
+		sampler: sampler,
+		dropped: newCounterBySvc(metricsFactory, "spans.downsampled"),
+	}
+}
+
+// Filter returns false if the sampler does not keep the span and it is not a debug span.
+// It can be used as a FilterSpan.
+func (f *DownsamplingFilter) Filter(span *model.Span) bool {
+	if span.Flags.IsDebug() || f.sampler.ShouldSample(span) {
+		return true
+	}
+	f.dropped.inc(span.Process.ServiceName)
+	return false
+}
+
+// NewSamplingRateTagger returns a ProcessSpan that adds the SamplingRateTagKey tag with the downsampling ratio
+// to the spans. Debug spans are not downsampled, so they are not tagged.
+func NewSamplingRateTagger(ratio float64) ProcessSpan {
+	return func(span *model.Span) {
+		if !span.Flags.IsDebug() {
+			span.Tags = append(span.Tags, model.Float64(SamplingRateTagKey, ratio))
+		}
+	}
+}
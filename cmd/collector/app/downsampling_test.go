@@ -0,0 +1,119 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uber/jaeger-lib/metrics"
+
+	"github.com/uber/jaeger/model"
+)
+
+func randomTraceIDs(n int) []model.TraceID {
+	r := rand.New(rand.NewSource(42))
+	traceIDs := make([]model.TraceID, n)
+	for i := range traceIDs {
+		traceIDs[i] = model.TraceID{High: uint64(r.Int63()), Low: uint64(r.Int63())}
+	}
+	return traceIDs
+}
+
+func TestProbabilisticSamplerKeepsWholeTraces(t *testing.T) {
+	sampler := NewProbabilisticSampler(0.5, "salt")
+	// another collector with the same configuration makes the same decisions
+	otherSampler := NewProbabilisticSampler(0.5, "salt")
+	for _, traceID := range randomTraceIDs(1000) {
+		root := &model.Span{TraceID: traceID, SpanID: 1, Process: &model.Process{ServiceName: "frontend"}}
+		expected := sampler.ShouldSample(root)
+		for spanID := model.SpanID(2); spanID < 6; spanID++ {
+			span := &model.Span{TraceID: traceID, SpanID: spanID, ParentSpanID: 1, Process: &model.Process{ServiceName: "backend"}}
+			assert.Equal(t, expected, sampler.ShouldSample(span), traceID.String())
+			assert.Equal(t, expected, otherSampler.ShouldSample(span), traceID.String())
+		}
+	}
+}
+
+func TestProbabilisticSamplerRatio(t *testing.T) {
+	traceIDs := randomTraceIDs(10000)
+	for _, ratio := range []float64{0, 0.01, 0.25, 0.5, 0.9, 1} {
+		sampler := NewProbabilisticSampler(ratio, "")
+		kept := 0
+		for _, traceID := range traceIDs {
+			if sampler.ShouldSample(&model.Span{TraceID: traceID}) {
+				kept++
+			}
+		}
+		assert.InDelta(t, ratio, float64(kept)/float64(len(traceIDs)), 0.02, "ratio %v", ratio)
+	}
+}
+
+func TestProbabilisticSamplerSequentialTraceIDs(t *testing.T) {
+	sampler := NewProbabilisticSampler(0.1, "")
+	kept := 0
+	for i := 1; i <= 10000; i++ {
+		if sampler.ShouldSample(&model.Span{TraceID: model.TraceID{Low: uint64(i)}}) {
+			kept++
+		}
+	}
+	assert.InDelta(t, 1000, kept, 200)
+}
+
+func TestProbabilisticSamplerSalt(t *testing.T) {
+	sampler := NewProbabilisticSampler(0.5, "a")
+	otherSampler := NewProbabilisticSampler(0.5, "b")
+	different := 0
+	for _, traceID := range randomTraceIDs(1000) {
+		span := &model.Span{TraceID: traceID}
+		if sampler.ShouldSample(span) != otherSampler.ShouldSample(span) {
+			different++
+		}
+	}
+	assert.InDelta(t, 500, different, 100, "a different salt keeps a different set of traces")
+}
+
+func TestDownsamplingFilter(t *testing.T) {
+	metricsFactory := metrics.NewLocalFactory(0)
+	filter := NewDownsamplingFilter(NewProbabilisticSampler(0, ""), metricsFactory)
+	process := &model.Process{ServiceName: "noisy-service"}
+
+	assert.False(t, filter.Filter(&model.Span{TraceID: model.TraceID{Low: 1}, Process: process}))
+	debugSpan := &model.Span{TraceID: model.TraceID{Low: 1}, Process: process}
+	debugSpan.Flags.SetDebug()
+	assert.True(t, filter.Filter(debugSpan), "debug spans are kept")
+
+	counters, _ := metricsFactory.Snapshot()
+	assert.EqualValues(t, 1, counters["spans.downsampled|service=noisy-service"])
+
+	filter = NewDownsamplingFilter(NewProbabilisticSampler(1, ""), metricsFactory)
+	assert.True(t, filter.Filter(&model.Span{TraceID: model.TraceID{Low: 1}, Process: process}))
+}
+
+func TestSamplingRateTagger(t *testing.T) {
+	tagger := NewSamplingRateTagger(0.25)
+	span := &model.Span{TraceID: model.TraceID{Low: 1}, Tags: model.KeyValues{model.String("k", "v")}}
+	tagger(span)
+	rate, ok := span.Tags.FindByKey(SamplingRateTagKey)
+	assert.True(t, ok)
+	assert.Equal(t, 0.25, rate.Float64())
+	assert.Len(t, span.Tags, 2)
+
+	debugSpan := &model.Span{TraceID: model.TraceID{Low: 1}}
+	debugSpan.Flags.SetDebug()
+	tagger(debugSpan)
+	assert.Empty(t, debugSpan.Tags, "debug spans are not downsampled")
+}
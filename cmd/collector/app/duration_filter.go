@@ -0,0 +1,61 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"time"
+
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/uber/jaeger-lib/metrics"
+
+	"github.com/uber/jaeger/model"
+)
+
+// DurationFilter drops the spans that are shorter than a minimum duration, unless they are errors
+type DurationFilter struct {
+	minDuration time.Duration
+	dropped     *counterBySvc
+}
+
+// NewDurationFilter creates a DurationFilter. Dropped spans are counted in the spans.too-short counter
+// tagged by service.
+func NewDurationFilter(minDuration time.Duration, metricsFactory metrics.Factory) *DurationFilter {
+	return &DurationFilter{
+		minDuration: minDuration,
+		dropped:     newCounterBySvc(metricsFactory, "spans.too-short"),
+	}
+}
+
+// Filter returns false if the span is shorter than the minimum duration and is not tagged as an error.
+// It can be used as a FilterSpan.
+func (f *DurationFilter) Filter(span *model.Span) bool {
+	if span.Duration >= f.minDuration || isError(span) {
+		return true
+	}
+	f.dropped.inc(span.Process.ServiceName)
+	return false
+}
+
+func isError(span *model.Span) bool {
+	for _, tag := range span.Tags {
+		if tag.Key != string(ext.Error) {
+			continue
+		}
+		if (tag.VType == model.BoolType && tag.Bool()) || (tag.VType == model.StringType && tag.VStr == "true") {
+			return true
+		}
+	}
+	return false
+}
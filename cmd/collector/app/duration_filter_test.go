@@ -0,0 +1,74 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uber/jaeger-lib/metrics"
+
+	"github.com/uber/jaeger/model"
+)
+
+func TestDurationFilter(t *testing.T) {
+	metricsFactory := metrics.NewLocalFactory(0)
+	filter := NewDurationFilter(time.Millisecond, metricsFactory)
+	process := &model.Process{ServiceName: "noisy-service"}
+
+	testCases := []struct {
+		caption  string
+		span     *model.Span
+		expected bool
+	}{
+		{
+			caption:  "above the threshold",
+			span:     &model.Span{Duration: 2 * time.Millisecond, Process: process},
+			expected: true,
+		},
+		{
+			caption:  "at the threshold",
+			span:     &model.Span{Duration: time.Millisecond, Process: process},
+			expected: true,
+		},
+		{
+			caption:  "below the threshold",
+			span:     &model.Span{Duration: 500 * time.Microsecond, Process: process},
+			expected: false,
+		},
+		{
+			caption:  "below the threshold with an error tag",
+			span:     &model.Span{Duration: 500 * time.Microsecond, Process: process, Tags: model.KeyValues{model.Bool("error", true)}},
+			expected: true,
+		},
+		{
+			caption:  "below the threshold with a string error tag",
+			span:     &model.Span{Duration: 500 * time.Microsecond, Process: process, Tags: model.KeyValues{model.String("error", "true")}},
+			expected: true,
+		},
+		{
+			caption:  "below the threshold with a false error tag",
+			span:     &model.Span{Duration: 500 * time.Microsecond, Process: process, Tags: model.KeyValues{model.Bool("error", false)}},
+			expected: false,
+		},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, filter.Filter(tc.span), tc.caption)
+	}
+
+	counters, _ := metricsFactory.Snapshot()
+	assert.EqualValues(t, 2, counters["spans.too-short|service=noisy-service"])
+}
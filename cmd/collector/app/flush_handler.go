@@ -0,0 +1,43 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// FlushResponse is the JSON body returned by the /flush endpoint
+type FlushResponse struct {
+	// Flushed is the number of spans written by each buffer
+	Flushed map[string]int `json:"flushed"`
+}
+
+// RegisterFlushRoute registers a handler to /flush on the given router. POST requests call flush, which
+// writes the buffered spans synchronously and returns how many were written by each buffer, and return
+// the counts as JSON, e.g. {"flushed":{"trace-buffer":12}}.
+func RegisterFlushRoute(router *mux.Router, flush func() map[string]int) {
+	router.HandleFunc("/flush", func(w http.ResponseWriter, _ *http.Request) {
+		body, err := json.Marshal(FlushResponse{Flushed: flush()})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	}).Methods(http.MethodPost)
+}
@@ -0,0 +1,69 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/pkg/clock"
+	"github.com/uber/jaeger/storage/spanstore"
+	"github.com/uber/jaeger/storage/spanstore/memory"
+)
+
+func TestFlushHandler(t *testing.T) {
+	store := memory.NewStore()
+	traceBuffer := spanstore.NewTraceBufferWriter(store, time.Hour, spanstore.DefaultTraceBufferMaxSpans, 1, clock.System, metrics.NullFactory, zap.NewNop())
+	defer traceBuffer.Close()
+	router := mux.NewRouter()
+	RegisterFlushRoute(router, func() map[string]int {
+		return map[string]int{"trace-buffer": traceBuffer.Flush()}
+	})
+
+	process := &model.Process{ServiceName: "service"}
+	for _, spanID := range []model.SpanID{1, 2} {
+		span := &model.Span{TraceID: model.TraceID{Low: 1}, SpanID: spanID, Process: process}
+		require.NoError(t, traceBuffer.WriteSpan(context.Background(), span))
+	}
+	_, err := store.GetTrace(model.TraceID{Low: 1})
+	assert.Error(t, err, "the spans are held by the trace buffer")
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/flush", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	var res FlushResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &res))
+	assert.Equal(t, map[string]int{"trace-buffer": 2}, res.Flushed)
+
+	trace, err := store.GetTrace(model.TraceID{Low: 1})
+	require.NoError(t, err)
+	assert.Len(t, trace.Spans, 2)
+
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/flush", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+}
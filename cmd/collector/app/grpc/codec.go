@@ -0,0 +1,48 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpc
+
+import (
+	"fmt"
+
+	"github.com/apache/thrift/lib/go/thrift"
+)
+
+// ThriftCodec is a gRPC codec that encodes messages with the Thrift binary protocol,
+// which lets the collector accept the same jaeger.thrift model over gRPC as over TChannel.
+type ThriftCodec struct{}
+
+// Marshal returns the Thrift binary encoding of v
+func (ThriftCodec) Marshal(v interface{}) ([]byte, error) {
+	msg, ok := v.(thrift.TStruct)
+	if !ok {
+		return nil, fmt.Errorf("%T is not a thrift struct", v)
+	}
+	return thrift.NewTSerializer().Write(msg)
+}
+
+// Unmarshal parses the Thrift binary encoded data into v
+func (ThriftCodec) Unmarshal(data []byte, v interface{}) error {
+	msg, ok := v.(thrift.TStruct)
+	if !ok {
+		return fmt.Errorf("%T is not a thrift struct", v)
+	}
+	return thrift.NewTDeserializer().Read(msg, data)
+}
+
+// String returns the name of the codec
+func (ThriftCodec) String() string {
+	return "thrift"
+}
@@ -0,0 +1,121 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpc
+
+import (
+	tchanThrift "github.com/uber/tchannel-go/thrift"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+
+	"github.com/uber/jaeger/cmd/collector/app"
+	"github.com/uber/jaeger/thrift-gen/jaeger"
+)
+
+const (
+	serviceName     = "jaeger.Collector"
+	postSpansMethod = "/" + serviceName + "/PostSpans"
+)
+
+// CollectorServer is the gRPC counterpart of the TChannel Collector service
+type CollectorServer interface {
+	// PostSpans records a batch of spans in Jaeger Thrift format
+	PostSpans(ctx context.Context, batch *jaeger.Batch) (*jaeger.BatchSubmitResponse, error)
+}
+
+// Handler implements CollectorServer by feeding batches to a JaegerBatchesHandler
+type Handler struct {
+	jaegerBatchesHandler app.JaegerBatchesHandler
+}
+
+// NewHandler returns a new Handler
+func NewHandler(jaegerBatchesHandler app.JaegerBatchesHandler) *Handler {
+	return &Handler{
+		jaegerBatchesHandler: jaegerBatchesHandler,
+	}
+}
+
+// PostSpans submits the batch through the same processing pipeline as the TChannel and HTTP endpoints
+func (h *Handler) PostSpans(ctx context.Context, batch *jaeger.Batch) (*jaeger.BatchSubmitResponse, error) {
+	responses, err := h.jaegerBatchesHandler.SubmitBatches(tchanThrift.Wrap(ctx), []*jaeger.Batch{batch})
+	if err != nil {
+		return nil, err
+	}
+	if len(responses) == 0 {
+		return &jaeger.BatchSubmitResponse{Ok: true}, nil
+	}
+	return responses[0], nil
+}
+
+// NewServer creates a gRPC server that uses ThriftCodec and serves the given CollectorServer
+func NewServer(srv CollectorServer, opts ...grpc.ServerOption) *grpc.Server {
+	server := grpc.NewServer(append(opts, grpc.CustomCodec(ThriftCodec{}))...)
+	RegisterCollectorServer(server, srv)
+	return server
+}
+
+// RegisterCollectorServer registers the CollectorServer on the gRPC server
+func RegisterCollectorServer(s *grpc.Server, srv CollectorServer) {
+	s.RegisterService(&collectorServiceDesc, srv)
+}
+
+func postSpansHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	batch := &jaeger.Batch{}
+	if err := dec(batch); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CollectorServer).PostSpans(ctx, batch)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: postSpansMethod,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CollectorServer).PostSpans(ctx, req.(*jaeger.Batch))
+	}
+	return interceptor(ctx, batch, info, handler)
+}
+
+var collectorServiceDesc = grpc.ServiceDesc{
+	ServiceName: serviceName,
+	HandlerType: (*CollectorServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "PostSpans",
+			Handler:    postSpansHandler,
+		},
+	},
+	Streams: []grpc.StreamDesc{},
+}
+
+// CollectorClient submits spans to a collector's gRPC endpoint
+type CollectorClient struct {
+	cc *grpc.ClientConn
+}
+
+// NewCollectorClient creates a CollectorClient. The connection must be dialed with
+// grpc.WithCodec(ThriftCodec{}).
+func NewCollectorClient(cc *grpc.ClientConn) *CollectorClient {
+	return &CollectorClient{cc: cc}
+}
+
+// PostSpans submits a batch of spans to the collector
+func (c *CollectorClient) PostSpans(ctx context.Context, batch *jaeger.Batch, opts ...grpc.CallOption) (*jaeger.BatchSubmitResponse, error) {
+	out := &jaeger.BatchSubmitResponse{}
+	if err := grpc.Invoke(ctx, postSpansMethod, batch, out, c.cc, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,110 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpc
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	tchanThrift "github.com/uber/tchannel-go/thrift"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+
+	"github.com/uber/jaeger/thrift-gen/jaeger"
+)
+
+type mockJaegerHandler struct {
+	err     error
+	mux     sync.Mutex
+	batches []*jaeger.Batch
+}
+
+func (p *mockJaegerHandler) SubmitBatches(ctx tchanThrift.Context, batches []*jaeger.Batch) ([]*jaeger.BatchSubmitResponse, error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	p.batches = append(p.batches, batches...)
+	if p.err != nil {
+		return nil, p.err
+	}
+	return []*jaeger.BatchSubmitResponse{{Ok: true}}, nil
+}
+
+func (p *mockJaegerHandler) getBatches() []*jaeger.Batch {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	return p.batches
+}
+
+func initializeGRPCTestServer(t *testing.T, handler *mockJaegerHandler) (*grpc.Server, *CollectorClient) {
+	server := NewServer(NewHandler(handler))
+	lis, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	go server.Serve(lis)
+
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure(), grpc.WithCodec(ThriftCodec{}))
+	require.NoError(t, err)
+	return server, NewCollectorClient(conn)
+}
+
+func TestPostSpans(t *testing.T) {
+	handler := &mockJaegerHandler{}
+	server, client := initializeGRPCTestServer(t, handler)
+	defer server.Stop()
+
+	batch := &jaeger.Batch{
+		Process: &jaeger.Process{ServiceName: "serviceName"},
+		Spans:   []*jaeger.Span{{OperationName: "opName", SpanId: 1, TraceIdLow: 2}},
+	}
+	res, err := client.PostSpans(context.Background(), batch)
+	require.NoError(t, err)
+	assert.True(t, res.Ok)
+
+	batches := handler.getBatches()
+	require.Len(t, batches, 1)
+	assert.Equal(t, batch, batches[0])
+}
+
+func TestPostSpansError(t *testing.T) {
+	handler := &mockJaegerHandler{err: errors.New("Bad times ahead")}
+	server, client := initializeGRPCTestServer(t, handler)
+	defer server.Stop()
+
+	res, err := client.PostSpans(context.Background(), &jaeger.Batch{
+		Process: &jaeger.Process{ServiceName: "serviceName"},
+	})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Bad times ahead")
+	assert.Nil(t, res)
+}
+
+func TestThriftCodec(t *testing.T) {
+	codec := ThriftCodec{}
+	assert.Equal(t, "thrift", codec.String())
+
+	batch := &jaeger.Batch{Process: &jaeger.Process{ServiceName: "serviceName"}}
+	data, err := codec.Marshal(batch)
+	require.NoError(t, err)
+	newBatch := &jaeger.Batch{}
+	require.NoError(t, codec.Unmarshal(data, newBatch))
+	assert.Equal(t, batch, newBatch)
+
+	_, err = codec.Marshal("not thrift")
+	assert.EqualError(t, err, "string is not a thrift struct")
+	assert.EqualError(t, codec.Unmarshal(data, "not thrift"), "string is not a thrift struct")
+}
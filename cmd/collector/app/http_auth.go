@@ -0,0 +1,143 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// ErrNoBearerTokens is returned when a BearerAuthenticator would not accept any token
+var ErrNoBearerTokens = errors.New("bearer authentication requires at least one token")
+
+// Authenticator checks the credentials of the requests to the collector's HTTP API
+type Authenticator interface {
+	// Authenticate returns true if the request carries valid credentials
+	Authenticate(r *http.Request) bool
+	// Challenge returns the WWW-Authenticate header of the responses to the requests without them
+	Challenge() string
+}
+
+// BasicAuthenticator accepts the requests with the username and password in their basic auth header
+type BasicAuthenticator struct {
+	username string
+	password string
+}
+
+// NewBasicAuthenticator creates a BasicAuthenticator
+func NewBasicAuthenticator(username, password string) *BasicAuthenticator {
+	return &BasicAuthenticator{username: username, password: password}
+}
+
+// Authenticate implements Authenticator
+func (a *BasicAuthenticator) Authenticate(r *http.Request) bool {
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return false
+	}
+	// both are compared so that the time taken does not reveal which one is wrong
+	usernameOK := subtle.ConstantTimeCompare([]byte(username), []byte(a.username)) == 1
+	passwordOK := subtle.ConstantTimeCompare([]byte(password), []byte(a.password)) == 1
+	return usernameOK && passwordOK
+}
+
+// Challenge implements Authenticator
+func (a *BasicAuthenticator) Challenge() string {
+	return `Basic realm="jaeger-collector"`
+}
+
+// BearerAuthenticator accepts the requests with one of its tokens in their Authorization header
+type BearerAuthenticator struct {
+	tokens [][]byte
+}
+
+// NewBearerAuthenticator creates a BearerAuthenticator that accepts any of tokens
+func NewBearerAuthenticator(tokens []string) (*BearerAuthenticator, error) {
+	a := &BearerAuthenticator{}
+	for _, token := range tokens {
+		if token != "" {
+			a.tokens = append(a.tokens, []byte(token))
+		}
+	}
+	if len(a.tokens) == 0 {
+		return nil, ErrNoBearerTokens
+	}
+	return a, nil
+}
+
+// LoadBearerTokens reads the tokens from the file at path, one per line. Blank lines and lines starting
+// with # are ignored.
+func LoadBearerTokens(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var tokens []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		tokens = append(tokens, line)
+	}
+	return tokens, scanner.Err()
+}
+
+// Authenticate implements Authenticator
+func (a *BearerAuthenticator) Authenticate(r *http.Request) bool {
+	header := r.Header.Get("Authorization")
+	const prefix = "Bearer "
+	if len(header) <= len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return false
+	}
+	token := []byte(header[len(prefix):])
+	valid := false
+	for _, t := range a.tokens {
+		if subtle.ConstantTimeCompare(token, t) == 1 {
+			valid = true
+		}
+	}
+	return valid
+}
+
+// Challenge implements Authenticator
+func (a *BearerAuthenticator) Challenge() string {
+	return `Bearer realm="jaeger-collector"`
+}
+
+// Authenticate returns an http.Handler that authenticates all the requests to handler, whatever their route,
+// except for the CORS preflight OPTIONS requests that browsers send without credentials. Wrapping a server's
+// whole handler with it also protects the routes that are not part of the APIHandler, such as /flush,
+// /log-level, /config or the Zipkin routes. The requests it rejects with 401 Unauthorized do not reach
+// the routes, so they are not counted in the http.requests metrics. A nil authenticator accepts all requests.
+func Authenticate(authenticator Authenticator, handler http.Handler) http.Handler {
+	if authenticator == nil {
+		return handler
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodOptions && !authenticator.Authenticate(r) {
+			w.Header().Set("WWW-Authenticate", authenticator.Challenge())
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,166 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/jaeger/pkg/gzipfilter"
+	"github.com/uber/jaeger/thrift-gen/jaeger"
+)
+
+func TestBasicAuthenticator(t *testing.T) {
+	authenticator := NewBasicAuthenticator("jaeger", "secret")
+	tests := []struct {
+		name     string
+		username string
+		password string
+		setAuth  bool
+		accepted bool
+	}{
+		{name: "valid credentials", username: "jaeger", password: "secret", setAuth: true, accepted: true},
+		{name: "wrong password", username: "jaeger", password: "guess", setAuth: true},
+		{name: "wrong username", username: "zipkin", password: "secret", setAuth: true},
+		{name: "no credentials"},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/api/traces", nil)
+		if test.setAuth {
+			req.SetBasicAuth(test.username, test.password)
+		}
+		assert.Equal(t, test.accepted, authenticator.Authenticate(req), test.name)
+	}
+}
+
+func TestBearerAuthenticator(t *testing.T) {
+	authenticator, err := NewBearerAuthenticator([]string{"token-1", "", "token-2"})
+	require.NoError(t, err)
+	tests := []struct {
+		header   string
+		accepted bool
+	}{
+		{header: "Bearer token-1", accepted: true},
+		{header: "bearer token-2", accepted: true},
+		{header: "Bearer token-3"},
+		{header: "Bearer "},
+		{header: "Basic dG9rZW4tMTo="},
+		{header: ""},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/api/traces", nil)
+		if test.header != "" {
+			req.Header.Set("Authorization", test.header)
+		}
+		assert.Equal(t, test.accepted, authenticator.Authenticate(req), test.header)
+	}
+
+	_, err = NewBearerAuthenticator([]string{""})
+	assert.Equal(t, ErrNoBearerTokens, err)
+}
+
+func TestLoadBearerTokens(t *testing.T) {
+	file, err := ioutil.TempFile("", "jaeger-tokens")
+	require.NoError(t, err)
+	defer os.Remove(file.Name())
+	_, err = file.WriteString("# agents\ntoken-1\n\n  token-2  \n")
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	tokens, err := LoadBearerTokens(file.Name())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"token-1", "token-2"}, tokens)
+
+	_, err = LoadBearerTokens(file.Name() + ".missing")
+	assert.Error(t, err)
+}
+
+func TestAPIHandlerAuthentication(t *testing.T) {
+	authenticator, err := NewBearerAuthenticator([]string{"token"})
+	require.NoError(t, err)
+	jaegerHandler := &mockJaegerHandler{}
+	r := mux.NewRouter()
+	NewAPIHandler(jaegerHandler).RegisterRoutes(r)
+	// wired as in the collector's main
+	server := httptest.NewServer(Authenticate(authenticator, gzipfilter.NewGzipFilter(r, 0)))
+	defer server.Close()
+
+	batch, err := thrift.NewTSerializer().Write(&jaeger.Batch{Process: &jaeger.Process{ServiceName: "service"}})
+	require.NoError(t, err)
+	post := func(token string) *http.Response {
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/api/traces?format=jaeger.thrift", bytes.NewReader(batch))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/x-thrift")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		res, err := httpClient.Do(req)
+		require.NoError(t, err)
+		res.Body.Close()
+		return res
+	}
+
+	res := post("")
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	assert.Equal(t, `Bearer realm="jaeger-collector"`, res.Header.Get("WWW-Authenticate"))
+	assert.Equal(t, http.StatusUnauthorized, post("wrong").StatusCode)
+	assert.Empty(t, jaegerHandler.getBatches(), "the rejected requests are not processed")
+
+	assert.Equal(t, http.StatusAccepted, post("token").StatusCode)
+	assert.Len(t, jaegerHandler.getBatches(), 1)
+}
+
+func TestAuthenticate(t *testing.T) {
+	authenticator := NewBasicAuthenticator("jaeger", "secret")
+	r := mux.NewRouter()
+	RegisterFlushRoute(r, func() map[string]int { return nil })
+	r.HandleFunc("/api/v2/spans", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}).Methods(http.MethodOptions)
+	handler := Authenticate(authenticator, r)
+
+	tests := []struct {
+		method     string
+		path       string
+		username   string
+		statusCode int
+	}{
+		{method: http.MethodPost, path: "/flush", statusCode: http.StatusUnauthorized},
+		{method: http.MethodPost, path: "/flush", username: "intruder", statusCode: http.StatusUnauthorized},
+		{method: http.MethodPost, path: "/flush", username: "jaeger", statusCode: http.StatusOK},
+		{method: http.MethodGet, path: "/config", statusCode: http.StatusUnauthorized},
+		{method: http.MethodOptions, path: "/api/v2/spans", statusCode: http.StatusNoContent},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(test.method, test.path, nil)
+		if test.username != "" {
+			req.SetBasicAuth(test.username, "secret")
+		}
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+		assert.Equal(t, test.statusCode, res.Code, "%s %s", test.method, test.path)
+	}
+
+	assert.Equal(t, r, Authenticate(nil, r), "a nil authenticator accepts all requests")
+}
@@ -23,6 +23,8 @@ import (
 
 	"github.com/apache/thrift/lib/go/thrift"
 	"github.com/gorilla/mux"
+	"github.com/uber/jaeger-lib/metrics"
+	"github.com/uber/tchannel-go"
 	tchanThrift "github.com/uber/tchannel-go/thrift"
 
 	tJaeger "github.com/uber/jaeger/thrift-gen/jaeger"
@@ -32,50 +34,185 @@ const (
 	formatParam = "format"
 	// UnableToReadBodyErrFormat is an error message for invalid requests
 	UnableToReadBodyErrFormat = "Unable to process request body: %v"
+	// busyRetryAfter is the number of seconds clients are asked to wait when the collector is busy
+	busyRetryAfter = "1"
+	// OTLPTracesPath is the path OpenTelemetry exporters post OTLP/HTTP traces to
+	OTLPTracesPath = "/v1/traces"
+	// OTLPProtobufContentType is the Content-Type of the binary Protobuf encoding of OTLP/HTTP requests
+	OTLPProtobufContentType = "application/x-protobuf"
+	// JSONSpanPath is the path clients post a single span to, in the JSON format of the query service
+	JSONSpanPath = "/api/span"
 )
 
 // APIHandler handles all HTTP calls to the collector
 type APIHandler struct {
 	jaegerBatchesHandler JaegerBatchesHandler
+	bodyLimiter          *RequestBodyLimiter
+	spanProcessor        SpanProcessor
+	metricsFactory       metrics.Factory
+}
+
+// HandlerOption is a function that sets some option on the APIHandler
+type HandlerOption func(handler *APIHandler)
+
+// HandlerOptions is a factory for all available HandlerOptions
+var HandlerOptions handlerOptions
+
+type handlerOptions struct{}
+
+// RequestBodyLimiter creates a HandlerOption that limits the size of the request bodies accepted by the APIHandler
+func (handlerOptions) RequestBodyLimiter(bodyLimiter *RequestBodyLimiter) HandlerOption {
+	return func(apiHandler *APIHandler) {
+		apiHandler.bodyLimiter = bodyLimiter
+	}
+}
+
+// SpanProcessor creates a HandlerOption that makes the APIHandler accept the formats registered with
+// RegisterDecoder, the decoded spans are passed to spanProcessor
+func (handlerOptions) SpanProcessor(spanProcessor SpanProcessor) HandlerOption {
+	return func(apiHandler *APIHandler) {
+		apiHandler.spanProcessor = spanProcessor
+	}
+}
+
+// MetricsFactory creates a HandlerOption that counts the requests to the routes of the APIHandler in the
+// http.requests counter tagged by endpoint and status class
+func (handlerOptions) MetricsFactory(metricsFactory metrics.Factory) HandlerOption {
+	return func(apiHandler *APIHandler) {
+		apiHandler.metricsFactory = metricsFactory
+	}
 }
 
 // NewAPIHandler returns a new APIHandler
 func NewAPIHandler(
 	jaegerBatchesHandler JaegerBatchesHandler,
+	options ...HandlerOption,
 ) *APIHandler {
-	return &APIHandler{
+	aH := &APIHandler{
 		jaegerBatchesHandler: jaegerBatchesHandler,
 	}
+	for _, option := range options {
+		option(aH)
+	}
+	return aH
 }
 
-// RegisterRoutes registers routes for this handler on the given router
+// RegisterRoutes registers routes for this handler on the given router. The /api/v2/spans, the OTLP
+// /v1/traces and the single span /api/span routes are only registered when the handler has a SpanProcessor.
 func (aH *APIHandler) RegisterRoutes(router *mux.Router) {
-	router.HandleFunc("/api/traces", aH.saveSpan).Methods(http.MethodPost)
+	aH.handleFunc(router, "/api/traces", aH.saveSpan)
+	if aH.spanProcessor != nil {
+		aH.handleFunc(router, "/api/v2/spans", aH.saveSpansV2)
+		aH.handleFunc(router, OTLPTracesPath, aH.saveOTLPSpans)
+		aH.handleFunc(router, JSONSpanPath, aH.saveJSONSpan)
+	}
 }
 
-func (aH *APIHandler) saveSpan(w http.ResponseWriter, r *http.Request) {
+// handleFunc registers handler for the POST requests to path, with the request body limit and metrics
+func (aH *APIHandler) handleFunc(router *mux.Router, path string, handler http.HandlerFunc) {
+	router.HandleFunc(path, countRequests(path, aH.metricsFactory, aH.bodyLimiter.Limit(handler))).Methods(http.MethodPost)
+}
+
+// readBody reads the request body, it writes the error response and returns false if it cannot be read
+func readBody(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
 	bodyBytes, err := ioutil.ReadAll(r.Body)
 	r.Body.Close()
+	if err == ErrRequestBodyTooLarge {
+		http.Error(w, fmt.Sprintf(UnableToReadBodyErrFormat, err), http.StatusRequestEntityTooLarge)
+		return nil, false
+	}
 	if err != nil {
 		http.Error(w, fmt.Sprintf(UnableToReadBodyErrFormat, err), http.StatusInternalServerError)
+		return nil, false
+	}
+	return bodyBytes, true
+}
+
+// saveSpansV2 decodes the body with the decoder registered for its Content-Type, which defaults to
+// JaegerProtobufContentType when the request has none
+func (aH *APIHandler) saveSpansV2(w http.ResponseWriter, r *http.Request) {
+	bodyBytes, ok := readBody(w, r)
+	if !ok {
+		return
+	}
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = JaegerProtobufContentType
+	}
+	aH.saveDecodedSpans(w, contentType, bodyBytes)
+}
+
+// saveOTLPSpans accepts the spans exported by OpenTelemetry SDKs and collectors with OTLP/HTTP, which
+// expect a 200 response carrying an ExportTraceServiceResponse. Only the binary Protobuf encoding is supported.
+func (aH *APIHandler) saveOTLPSpans(w http.ResponseWriter, r *http.Request) {
+	bodyBytes, ok := readBody(w, r)
+	if !ok {
+		return
+	}
+	if contentType := r.Header.Get("Content-Type"); mediaType(contentType) != OTLPProtobufContentType {
+		http.Error(w, fmt.Sprintf("Unsupported Content-Type: %v", contentType), http.StatusUnsupportedMediaType)
+		return
+	}
+	decoder := otlpDecoder{}
+	spans, err := decoder.Decode(bodyBytes)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(UnableToReadBodyErrFormat, err), http.StatusBadRequest)
+		return
+	}
+	if _, err := aH.spanProcessor.ProcessSpans(spans, decoder.SpanFormat()); err != nil {
+		WriteSubmitError(w, "Cannot submit spans: %v", err)
+		return
+	}
+	// an empty ExportTraceServiceResponse is encoded as an empty body
+	w.Header().Set("Content-Type", OTLPProtobufContentType)
+	w.WriteHeader(http.StatusOK)
+}
+
+// saveJSONSpan accepts a single span without batch framing, for clients too simple to batch spans.
+// The span goes through the same processor, and so the same validation and metrics, as the batches.
+func (aH *APIHandler) saveJSONSpan(w http.ResponseWriter, r *http.Request) {
+	bodyBytes, ok := readBody(w, r)
+	if !ok {
+		return
+	}
+	decoder := jsonSpanDecoder{}
+	spans, err := decoder.Decode(bodyBytes)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(UnableToReadBodyErrFormat, err), http.StatusBadRequest)
+		return
+	}
+	if _, err := aH.spanProcessor.ProcessSpans(spans, decoder.SpanFormat()); err != nil {
+		WriteSubmitError(w, "Cannot submit span: %v", err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (aH *APIHandler) saveSpan(w http.ResponseWriter, r *http.Request) {
+	bodyBytes, ok := readBody(w, r)
+	if !ok {
 		return
 	}
 
 	format := r.FormValue(formatParam)
+	if format == "" && aH.spanProcessor != nil {
+		aH.saveDecodedSpans(w, r.Header.Get("Content-Type"), bodyBytes)
+		return
+	}
 	switch strings.ToLower(format) {
 	case "jaeger.thrift":
 		tdes := thrift.NewTDeserializer()
 		// (NB): We decided to use this struct instead of straight batches to be as consistent with tchannel intake as possible.
 		batch := &tJaeger.Batch{}
-		if err = tdes.Read(batch, bodyBytes); err != nil {
+		if err := tdes.Read(batch, bodyBytes); err != nil {
 			http.Error(w, fmt.Sprintf(UnableToReadBodyErrFormat, err), http.StatusBadRequest)
 			return
 		}
 		ctx, cancel := tchanThrift.NewContext(time.Minute)
 		defer cancel()
 		batches := []*tJaeger.Batch{batch}
-		if _, err = aH.jaegerBatchesHandler.SubmitBatches(ctx, batches); err != nil {
-			http.Error(w, fmt.Sprintf("Cannot submit Jaeger batch: %v", err), http.StatusInternalServerError)
+		if _, err := aH.jaegerBatchesHandler.SubmitBatches(ctx, batches); err != nil {
+			WriteSubmitError(w, "Cannot submit Jaeger batch: %v", err)
 			return
 		}
 
@@ -86,3 +223,36 @@ func (aH *APIHandler) saveSpan(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusAccepted)
 }
+
+// saveDecodedSpans decodes the body with the decoder registered for its Content-Type
+func (aH *APIHandler) saveDecodedSpans(w http.ResponseWriter, contentType string, bodyBytes []byte) {
+	decoder, ok := LookupDecoder(contentType)
+	if !ok {
+		http.Error(w, fmt.Sprintf("Unsupported Content-Type: %v", contentType), http.StatusBadRequest)
+		return
+	}
+	spans, err := decoder.Decode(bodyBytes)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(UnableToReadBodyErrFormat, err), http.StatusBadRequest)
+		return
+	}
+	if _, err := aH.spanProcessor.ProcessSpans(spans, decoder.SpanFormat()); err != nil {
+		WriteSubmitError(w, "Cannot submit spans: %v", err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// WriteSubmitError writes the response for an error returned by a span handler. Clients are asked
+// to retry later when the collector is too busy to accept spans.
+func WriteSubmitError(w http.ResponseWriter, format string, err error) {
+	status := http.StatusInternalServerError
+	switch err {
+	case ErrBatchTooLarge:
+		status = http.StatusRequestEntityTooLarge
+	case tchannel.ErrServerBusy:
+		w.Header().Set("Retry-After", busyRetryAfter)
+		status = http.StatusTooManyRequests
+	}
+	http.Error(w, fmt.Sprintf(format, err), status)
+}
@@ -16,6 +16,7 @@ package app
 
 import (
 	"bytes"
+	"compress/gzip"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -29,8 +30,11 @@ import (
 	"github.com/stretchr/testify/assert"
 	jaegerClient "github.com/uber/jaeger-client-go"
 	"github.com/uber/jaeger-client-go/transport"
+	"github.com/uber/jaeger-lib/metrics"
 	tchanThrift "github.com/uber/tchannel-go/thrift"
+	"go.uber.org/zap"
 
+	"github.com/uber/jaeger/pkg/gzipfilter"
 	"github.com/uber/jaeger/thrift-gen/jaeger"
 )
 
@@ -90,6 +94,38 @@ func TestThriftFormat(t *testing.T) {
 	assert.EqualValues(t, "Cannot submit Jaeger batch: Bad times ahead\n", resBodyStr)
 }
 
+func TestGzipThriftFormat(t *testing.T) {
+	batch := &jaeger.Batch{
+		Process: &jaeger.Process{ServiceName: "serviceName"},
+		Spans:   []*jaeger.Span{{OperationName: "opName"}},
+	}
+	someBytes, err := thrift.NewTSerializer().Write(batch)
+	assert.NoError(t, err)
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, err = zw.Write(someBytes)
+	assert.NoError(t, err)
+	assert.NoError(t, zw.Close())
+
+	r := mux.NewRouter()
+	handler := NewAPIHandler(&mockJaegerHandler{})
+	handler.RegisterRoutes(r)
+	server := httptest.NewServer(gzipfilter.NewGzipFilter(r, 0))
+	defer server.Close()
+
+	req, err := http.NewRequest(http.MethodPost, server.URL+`/api/traces?format=jaeger.thrift`, &buf)
+	assert.NoError(t, err)
+	req.Header.Set("Content-Encoding", "gzip")
+	res, err := httpClient.Do(req)
+	assert.NoError(t, err)
+	res.Body.Close()
+	assert.EqualValues(t, http.StatusAccepted, res.StatusCode)
+	batches := handler.jaegerBatchesHandler.(*mockJaegerHandler).getBatches()
+	if assert.Len(t, batches, 1) {
+		assert.Equal(t, "opName", batches[0].Spans[0].OperationName)
+	}
+}
+
 func TestViaClient(t *testing.T) {
 	server, handler := initializeTestServer(nil)
 	defer server.Close()
@@ -143,6 +179,66 @@ func TestWrongFormat(t *testing.T) {
 	assert.EqualValues(t, "Unsupported format type: nosoupforyou\n", resBodyStr)
 }
 
+func TestRequestBodyTooLarge(t *testing.T) {
+	mb := metrics.NewLocalFactory(time.Hour)
+	jaegerHandler := &mockJaegerHandler{}
+	r := mux.NewRouter()
+	NewAPIHandler(jaegerHandler, HandlerOptions.RequestBodyLimiter(NewRequestBodyLimiter(1024, mb))).RegisterRoutes(r)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	statusCode, resBodyStr, err := postBytes(server.URL+`/api/traces?format=jaeger.thrift`, make([]byte, 1025))
+	assert.NoError(t, err)
+	assert.EqualValues(t, http.StatusRequestEntityTooLarge, statusCode)
+	assert.EqualValues(t, "Unable to process request body: request body too large\n", resBodyStr)
+	assert.Empty(t, jaegerHandler.getBatches())
+
+	counters, _ := mb.Snapshot()
+	assert.EqualValues(t, 1, counters["batches.rejected|reason=too-many-bytes"])
+}
+
+func TestBatchTooLarge(t *testing.T) {
+	batch := jaeger.Batch{Process: &jaeger.Process{ServiceName: "serviceName"}}
+	someBytes, err := thrift.NewTSerializer().Write(&batch)
+	assert.NoError(t, err)
+	server, _ := initializeTestServer(ErrBatchTooLarge)
+	defer server.Close()
+
+	statusCode, resBodyStr, err := postBytes(server.URL+`/api/traces?format=jaeger.thrift`, someBytes)
+	assert.NoError(t, err)
+	assert.EqualValues(t, http.StatusRequestEntityTooLarge, statusCode)
+	assert.EqualValues(t, "Cannot submit Jaeger batch: batch has too many spans\n", resBodyStr)
+}
+
+func TestServerBusy(t *testing.T) {
+	// the consumers are not started, so that the queue only fills up
+	processor := newSpanProcessor(&fakeSpanWriter{}, Options.QueueSize(2), Options.BackpressureThreshold(0.5))
+	defer processor.Stop()
+	r := mux.NewRouter()
+	NewAPIHandler(NewJaegerSpanHandler(zap.NewNop(), processor)).RegisterRoutes(r)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	batch := jaeger.Batch{
+		Process: &jaeger.Process{ServiceName: "serviceName"},
+		Spans:   []*jaeger.Span{{TraceIdLow: 1, SpanId: 1}},
+	}
+	someBytes, err := thrift.NewTSerializer().Write(&batch)
+	assert.NoError(t, err)
+
+	statusCode, _, err := postBytes(server.URL+`/api/traces?format=jaeger.thrift`, someBytes)
+	assert.NoError(t, err)
+	assert.EqualValues(t, http.StatusAccepted, statusCode)
+
+	req, err := http.NewRequest(http.MethodPost, server.URL+`/api/traces?format=jaeger.thrift`, bytes.NewReader(someBytes))
+	assert.NoError(t, err)
+	res, err := httpClient.Do(req)
+	assert.NoError(t, err)
+	res.Body.Close()
+	assert.EqualValues(t, http.StatusTooManyRequests, res.StatusCode)
+	assert.Equal(t, busyRetryAfter, res.Header.Get("Retry-After"))
+}
+
 func TestCannotReadBodyFromRequest(t *testing.T) {
 	handler := NewAPIHandler(&mockJaegerHandler{})
 	req, err := http.NewRequest(http.MethodPost, "whatever", &errReader{})
@@ -0,0 +1,84 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/uber/jaeger-lib/metrics"
+)
+
+// requestCounters counts the requests to an endpoint in the http.requests counter tagged by the
+// endpoint and by the class of the response status, e.g. status=4xx
+type requestCounters struct {
+	byClass map[int]metrics.Counter
+}
+
+func newRequestCounters(endpoint string, metricsFactory metrics.Factory) *requestCounters {
+	byClass := make(map[int]metrics.Counter, 5)
+	for class := 1; class <= 5; class++ {
+		byClass[class] = metricsFactory.Counter("http.requests", map[string]string{
+			"endpoint": endpoint,
+			"status":   fmt.Sprintf("%dxx", class),
+		})
+	}
+	return &requestCounters{byClass: byClass}
+}
+
+// countRequests returns a handler function that counts the requests served by handler. A nil
+// metricsFactory does not count anything.
+func countRequests(endpoint string, metricsFactory metrics.Factory, handler http.HandlerFunc) http.HandlerFunc {
+	if metricsFactory == nil {
+		return handler
+	}
+	counters := newRequestCounters(endpoint, metricsFactory)
+	return func(w http.ResponseWriter, r *http.Request) {
+		recorder := &statusRecorder{ResponseWriter: w}
+		handler(recorder, r)
+		if counter, ok := counters.byClass[recorder.statusCode()/100]; ok {
+			counter.Inc(1)
+		}
+	}
+}
+
+// statusRecorder wraps an http.ResponseWriter and records the status of the response, whether the
+// handler calls WriteHeader or only Write
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// statusCode returns the status of the response, a handler that writes nothing responds with 200
+func (r *statusRecorder) statusCode() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
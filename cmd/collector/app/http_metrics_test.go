@@ -0,0 +1,86 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics"
+
+	"github.com/uber/jaeger/thrift-gen/jaeger"
+)
+
+func TestAPIHandlerRequestMetrics(t *testing.T) {
+	mb := metrics.NewLocalFactory(time.Hour)
+	r := mux.NewRouter()
+	NewAPIHandler(&mockJaegerHandler{}, HandlerOptions.MetricsFactory(mb)).RegisterRoutes(r)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	batch, err := thrift.NewTSerializer().Write(&jaeger.Batch{Process: &jaeger.Process{ServiceName: "service"}})
+	require.NoError(t, err)
+	statusCode, _, err := postBytes(server.URL+`/api/traces?format=jaeger.thrift`, batch)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusAccepted, statusCode)
+	statusCode, _, err = postBytes(server.URL+`/api/traces?format=nosoupforyou`, []byte{})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, statusCode)
+	statusCode, _, err = postBytes(server.URL+`/api/traces?format=nosoupforyou`, []byte{})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, statusCode)
+
+	counters, _ := mb.Snapshot()
+	assert.EqualValues(t, 1, counters["http.requests|endpoint=/api/traces|status=2xx"])
+	assert.EqualValues(t, 2, counters["http.requests|endpoint=/api/traces|status=4xx"])
+	assert.EqualValues(t, 0, counters["http.requests|endpoint=/api/traces|status=5xx"])
+}
+
+func TestCountRequests(t *testing.T) {
+	mb := metrics.NewLocalFactory(time.Hour)
+	handler := countRequests("/test", mb, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("fail") != "" {
+			http.Error(w, "failed", http.StatusServiceUnavailable)
+			return
+		}
+		if r.URL.Query().Get("write") != "" {
+			w.Write([]byte("ok"))
+		}
+	})
+
+	for _, url := range []string{"/test", "/test?write=true", "/test?fail=true"} {
+		handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, url, nil))
+	}
+
+	counters, _ := mb.Snapshot()
+	assert.EqualValues(t, 2, counters["http.requests|endpoint=/test|status=2xx"], "the responses without a status are counted as 200")
+	assert.EqualValues(t, 1, counters["http.requests|endpoint=/test|status=5xx"])
+}
+
+func TestCountRequestsWithoutMetricsFactory(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	countRequests("/test", nil, func(w http.ResponseWriter, r *http.Request) {
+		_, ok := w.(*statusRecorder)
+		assert.False(t, ok, "the handler is not wrapped")
+		w.WriteHeader(http.StatusAccepted)
+	})(recorder, httptest.NewRequest(http.MethodGet, "/test", nil))
+	assert.Equal(t, http.StatusAccepted, recorder.Code)
+}
@@ -0,0 +1,227 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/uber/jaeger-lib/metrics"
+	"github.com/uber/tchannel-go/thrift"
+
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/thrift-gen/jaeger"
+	"github.com/uber/jaeger/thrift-gen/zipkincore"
+)
+
+const (
+	// TruncatedTagKey is the key of the tag marking the spans whose tags or logs were truncated by a SpanEntryLimiter
+	TruncatedTagKey = "jaeger.truncated"
+
+	rejectReasonTooManySpans = "too-many-spans"
+	rejectReasonTooManyBytes = "too-many-bytes"
+)
+
+var (
+	// ErrBatchTooLarge is returned when a batch has more spans than the collector accepts
+	ErrBatchTooLarge = errors.New("batch has too many spans")
+	// ErrRequestBodyTooLarge is returned when reading a request body larger than the collector accepts
+	ErrRequestBodyTooLarge = errors.New("request body too large")
+)
+
+// BatchLimiter rejects batches with more spans than the collector accepts before they are processed
+type BatchLimiter struct {
+	maxSpans int
+	rejected metrics.Counter
+}
+
+// NewBatchLimiter creates a BatchLimiter that rejects batches with more than maxSpans spans.
+// Rejected batches are counted in the batches.rejected counter tagged by reason.
+func NewBatchLimiter(maxSpans int, metricsFactory metrics.Factory) *BatchLimiter {
+	return &BatchLimiter{
+		maxSpans: maxSpans,
+		rejected: metricsFactory.Counter("batches.rejected", map[string]string{"reason": rejectReasonTooManySpans}),
+	}
+}
+
+// JaegerBatchesHandler returns a JaegerBatchesHandler that fails with ErrBatchTooLarge if any of
+// the batches is too large, otherwise it passes the batches to handler.
+func (l *BatchLimiter) JaegerBatchesHandler(handler JaegerBatchesHandler) JaegerBatchesHandler {
+	return &limitedJaegerBatchesHandler{limiter: l, handler: handler}
+}
+
+// ZipkinSpansHandler returns a ZipkinSpansHandler that fails with ErrBatchTooLarge if the batch
+// is too large, otherwise it passes the spans to handler.
+func (l *BatchLimiter) ZipkinSpansHandler(handler ZipkinSpansHandler) ZipkinSpansHandler {
+	return &limitedZipkinSpansHandler{limiter: l, handler: handler}
+}
+
+// SpanProcessor returns a SpanProcessor that fails with ErrBatchTooLarge if there are too many
+// spans, otherwise it passes the spans to processor.
+func (l *BatchLimiter) SpanProcessor(processor SpanProcessor) SpanProcessor {
+	return &limitedSpanProcessor{limiter: l, SpanProcessor: processor}
+}
+
+func (l *BatchLimiter) allow(numSpans int) bool {
+	if numSpans > l.maxSpans {
+		l.rejected.Inc(1)
+		return false
+	}
+	return true
+}
+
+type limitedJaegerBatchesHandler struct {
+	limiter *BatchLimiter
+	handler JaegerBatchesHandler
+}
+
+func (h *limitedJaegerBatchesHandler) SubmitBatches(ctx thrift.Context, batches []*jaeger.Batch) ([]*jaeger.BatchSubmitResponse, error) {
+	for _, batch := range batches {
+		if !h.limiter.allow(len(batch.Spans)) {
+			return nil, ErrBatchTooLarge
+		}
+	}
+	return h.handler.SubmitBatches(ctx, batches)
+}
+
+type limitedZipkinSpansHandler struct {
+	limiter *BatchLimiter
+	handler ZipkinSpansHandler
+}
+
+func (h *limitedZipkinSpansHandler) SubmitZipkinBatch(ctx thrift.Context, spans []*zipkincore.Span) ([]*zipkincore.Response, error) {
+	if !h.limiter.allow(len(spans)) {
+		return nil, ErrBatchTooLarge
+	}
+	return h.handler.SubmitZipkinBatch(ctx, spans)
+}
+
+type limitedSpanProcessor struct {
+	limiter *BatchLimiter
+	SpanProcessor
+}
+
+func (p *limitedSpanProcessor) ProcessSpans(mSpans []*model.Span, spanFormat string) ([]bool, error) {
+	if !p.limiter.allow(len(mSpans)) {
+		return nil, ErrBatchTooLarge
+	}
+	return p.SpanProcessor.ProcessSpans(mSpans, spanFormat)
+}
+
+// SpanEntryLimiter truncates the spans with more tags or logs than the collector accepts
+type SpanEntryLimiter struct {
+	maxTags     int
+	maxLogs     int
+	droppedTags metrics.Counter
+	droppedLogs metrics.Counter
+}
+
+// NewSpanEntryLimiter creates a SpanEntryLimiter that keeps the first maxTags tags and the first maxLogs
+// logs of every span, 0 does not limit them. The dropped entries are counted in the span-entries.dropped
+// counter tagged with entry=tags or entry=logs.
+func NewSpanEntryLimiter(maxTags, maxLogs int, metricsFactory metrics.Factory) *SpanEntryLimiter {
+	return &SpanEntryLimiter{
+		maxTags:     maxTags,
+		maxLogs:     maxLogs,
+		droppedTags: metricsFactory.Counter("span-entries.dropped", map[string]string{"entry": "tags"}),
+		droppedLogs: metricsFactory.Counter("span-entries.dropped", map[string]string{"entry": "logs"}),
+	}
+}
+
+// Sanitize drops the tags and logs of the span past the limits and tags the truncated span with
+// TruncatedTagKey, which is added after the kept tags. It can be used as a SanitizeSpan.
+func (l *SpanEntryLimiter) Sanitize(span *model.Span) *model.Span {
+	truncated := false
+	if l.maxLogs > 0 && len(span.Logs) > l.maxLogs {
+		l.droppedLogs.Inc(int64(len(span.Logs) - l.maxLogs))
+		span.Logs = span.Logs[:l.maxLogs]
+		truncated = true
+	}
+	if l.maxTags > 0 && len(span.Tags) > l.maxTags {
+		l.droppedTags.Inc(int64(len(span.Tags) - l.maxTags))
+		span.Tags = span.Tags[:l.maxTags]
+		truncated = true
+	}
+	if truncated {
+		span.Tags = append(span.Tags, model.Bool(TruncatedTagKey, true))
+	}
+	return span
+}
+
+// RequestBodyLimiter rejects HTTP requests with bodies larger than the collector accepts
+type RequestBodyLimiter struct {
+	maxBytes int64
+	rejected metrics.Counter
+}
+
+// NewRequestBodyLimiter creates a RequestBodyLimiter that rejects request bodies larger than maxBytes.
+// Rejected requests are counted in the batches.rejected counter tagged by reason.
+func NewRequestBodyLimiter(maxBytes int64, metricsFactory metrics.Factory) *RequestBodyLimiter {
+	return &RequestBodyLimiter{
+		maxBytes: maxBytes,
+		rejected: metricsFactory.Counter("batches.rejected", map[string]string{"reason": rejectReasonTooManyBytes}),
+	}
+}
+
+// Limit returns a handler function whose request body fails with ErrRequestBodyTooLarge once more
+// than the allowed number of bytes are read from it. A nil RequestBodyLimiter does not limit anything.
+func (l *RequestBodyLimiter) Limit(handler http.HandlerFunc) http.HandlerFunc {
+	if l == nil {
+		return handler
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		underlying := &countingReader{ReadCloser: r.Body}
+		body := &limitedBody{
+			ReadCloser: http.MaxBytesReader(w, underlying, l.maxBytes),
+			underlying: underlying,
+			maxBytes:   l.maxBytes,
+		}
+		r.Body = body
+		handler(w, r)
+		if body.exceeded {
+			l.rejected.Inc(1)
+		}
+	}
+}
+
+// limitedBody tells the errors returned by http.MaxBytesReader when the limit is exceeded apart
+// from the errors of the underlying body by counting how many bytes were actually read.
+type limitedBody struct {
+	io.ReadCloser
+	underlying *countingReader
+	maxBytes   int64
+	exceeded   bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil && err != io.EOF && b.underlying.count > b.maxBytes {
+		b.exceeded = true
+		return n, ErrRequestBodyTooLarge
+	}
+	return n, err
+}
+
+type countingReader struct {
+	io.ReadCloser
+	count int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.count += int64(n)
+	return n, err
+}
@@ -0,0 +1,170 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics"
+	"github.com/uber/tchannel-go/thrift"
+
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/thrift-gen/jaeger"
+	"github.com/uber/jaeger/thrift-gen/zipkincore"
+)
+
+type mockZipkinSpansHandler struct {
+	spans []*zipkincore.Span
+}
+
+func (h *mockZipkinSpansHandler) SubmitZipkinBatch(ctx thrift.Context, spans []*zipkincore.Span) ([]*zipkincore.Response, error) {
+	h.spans = append(h.spans, spans...)
+	return nil, nil
+}
+
+func TestBatchLimiterJaeger(t *testing.T) {
+	mb := metrics.NewLocalFactory(time.Hour)
+	handler := &mockJaegerHandler{}
+	limited := NewBatchLimiter(2, mb).JaegerBatchesHandler(handler)
+	ctx, cancel := thrift.NewContext(time.Minute)
+	defer cancel()
+
+	small := &jaeger.Batch{Spans: []*jaeger.Span{{SpanId: 1}, {SpanId: 2}}}
+	_, err := limited.SubmitBatches(ctx, []*jaeger.Batch{small})
+	require.NoError(t, err)
+	assert.Len(t, handler.getBatches(), 1)
+
+	large := &jaeger.Batch{Spans: []*jaeger.Span{{SpanId: 1}, {SpanId: 2}, {SpanId: 3}}}
+	_, err = limited.SubmitBatches(ctx, []*jaeger.Batch{small, large})
+	assert.Equal(t, ErrBatchTooLarge, err)
+	assert.Len(t, handler.getBatches(), 1, "no batch of a rejected call is processed")
+
+	counters, _ := mb.Snapshot()
+	assert.EqualValues(t, 1, counters["batches.rejected|reason="+rejectReasonTooManySpans])
+}
+
+func TestBatchLimiterZipkin(t *testing.T) {
+	mb := metrics.NewLocalFactory(time.Hour)
+	handler := &mockZipkinSpansHandler{}
+	limited := NewBatchLimiter(1, mb).ZipkinSpansHandler(handler)
+	ctx, cancel := thrift.NewContext(time.Minute)
+	defer cancel()
+
+	_, err := limited.SubmitZipkinBatch(ctx, []*zipkincore.Span{{ID: 1}})
+	require.NoError(t, err)
+	_, err = limited.SubmitZipkinBatch(ctx, []*zipkincore.Span{{ID: 1}, {ID: 2}})
+	assert.Equal(t, ErrBatchTooLarge, err)
+	assert.Len(t, handler.spans, 1)
+
+	counters, _ := mb.Snapshot()
+	assert.EqualValues(t, 1, counters["batches.rejected|reason="+rejectReasonTooManySpans])
+}
+
+func TestBatchLimiterSpanProcessor(t *testing.T) {
+	mb := metrics.NewLocalFactory(time.Hour)
+	processor := &recordingProcessor{}
+	limited := NewBatchLimiter(1, mb).SpanProcessor(processor)
+
+	_, err := limited.ProcessSpans([]*model.Span{{SpanID: 1}}, UnknownFormatType)
+	require.NoError(t, err)
+	_, err = limited.ProcessSpans([]*model.Span{{SpanID: 1}, {SpanID: 2}}, UnknownFormatType)
+	assert.Equal(t, ErrBatchTooLarge, err)
+	assert.Len(t, processor.spans, 1)
+	assert.NoError(t, limited.Close())
+
+	counters, _ := mb.Snapshot()
+	assert.EqualValues(t, 1, counters["batches.rejected|reason="+rejectReasonTooManySpans])
+}
+
+func TestSpanEntryLimiter(t *testing.T) {
+	metricsFactory := metrics.NewLocalFactory(0)
+	limiter := NewSpanEntryLimiter(2, 1, metricsFactory)
+
+	span := limiter.Sanitize(&model.Span{
+		Tags: model.KeyValues{model.String("a", "1"), model.String("b", "2"), model.String("c", "3")},
+		Logs: []model.Log{{Timestamp: time.Unix(1, 0)}, {Timestamp: time.Unix(2, 0)}, {Timestamp: time.Unix(3, 0)}},
+	})
+	assert.Equal(t, model.KeyValues{model.String("a", "1"), model.String("b", "2"), model.Bool(TruncatedTagKey, true)}, span.Tags)
+	assert.Equal(t, []model.Log{{Timestamp: time.Unix(1, 0)}}, span.Logs)
+
+	span = limiter.Sanitize(&model.Span{
+		Tags: model.KeyValues{model.String("a", "1")},
+		Logs: []model.Log{{Timestamp: time.Unix(1, 0)}, {Timestamp: time.Unix(2, 0)}},
+	})
+	assert.Equal(t, model.KeyValues{model.String("a", "1"), model.Bool(TruncatedTagKey, true)}, span.Tags, "too many logs")
+
+	span = limiter.Sanitize(&model.Span{
+		Tags: model.KeyValues{model.String("a", "1"), model.String("b", "2")},
+		Logs: []model.Log{{Timestamp: time.Unix(1, 0)}},
+	})
+	assert.Equal(t, model.KeyValues{model.String("a", "1"), model.String("b", "2")}, span.Tags, "the spans within the limits are kept")
+	assert.Len(t, span.Logs, 1)
+
+	counters, _ := metricsFactory.Snapshot()
+	assert.EqualValues(t, 1, counters["span-entries.dropped|entry=tags"])
+	assert.EqualValues(t, 3, counters["span-entries.dropped|entry=logs"])
+}
+
+func TestSpanEntryLimiterUnlimited(t *testing.T) {
+	limiter := NewSpanEntryLimiter(0, 0, metrics.NullFactory)
+	tags := model.KeyValues{model.String("a", "1"), model.String("b", "2")}
+	span := limiter.Sanitize(&model.Span{Tags: tags, Logs: make([]model.Log, 100)})
+	assert.Equal(t, tags, span.Tags)
+	assert.Len(t, span.Logs, 100)
+}
+
+func TestRequestBodyLimiter(t *testing.T) {
+	mb := metrics.NewLocalFactory(time.Hour)
+	var readErr error
+	handler := NewRequestBodyLimiter(10, mb).Limit(func(w http.ResponseWriter, r *http.Request) {
+		_, readErr = ioutil.ReadAll(r.Body)
+	})
+
+	handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(make([]byte, 10))))
+	assert.NoError(t, readErr)
+	counters, _ := mb.Snapshot()
+	assert.EqualValues(t, 0, counters["batches.rejected|reason="+rejectReasonTooManyBytes])
+
+	handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(make([]byte, 11))))
+	assert.Equal(t, ErrRequestBodyTooLarge, readErr)
+	counters, _ = mb.Snapshot()
+	assert.EqualValues(t, 1, counters["batches.rejected|reason="+rejectReasonTooManyBytes])
+}
+
+func TestRequestBodyLimiterUnderlyingError(t *testing.T) {
+	var readErr error
+	handler := NewRequestBodyLimiter(10, metrics.NullFactory).Limit(func(w http.ResponseWriter, r *http.Request) {
+		_, readErr = ioutil.ReadAll(r.Body)
+	})
+	handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", &errReader{}))
+	assert.EqualError(t, readErr, "Simulated error reading body")
+}
+
+func TestNilRequestBodyLimiter(t *testing.T) {
+	var limiter *RequestBodyLimiter
+	var readErr error
+	handler := limiter.Limit(func(w http.ResponseWriter, r *http.Request) {
+		_, readErr = ioutil.ReadAll(r.Body)
+	})
+	handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(make([]byte, 1024))))
+	assert.NoError(t, readErr)
+}
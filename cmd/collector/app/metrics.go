@@ -37,8 +37,16 @@ type SpanProcessorMetrics struct { //TODO - initialize metrics in the traditiona
 	BatchSize metrics.Gauge // size of span batch
 	// QueueLength measures the size of the internal span queue
 	QueueLength metrics.Gauge
+	// QueueCapacity reports the maximum size of the internal span queue
+	QueueCapacity metrics.Gauge
+	// QueueUtilization reports the length of the internal span queue as a percentage of its capacity
+	QueueUtilization metrics.Gauge
+	// DrainRemaining reports the number of spans left in the queue while it is drained on shutdown
+	DrainRemaining metrics.Gauge
 	// ErrorBusy counts number of return ErrServerBusy
 	ErrorBusy metrics.Counter
+	// RejectedBusy counts the batches rejected because the queue is filled above the backpressure threshold
+	RejectedBusy metrics.Counter
 	// SavedBySvc contains span and trace counts by service
 	SavedBySvc   metricsBySvc  // spans actually saved
 	serviceNames metrics.Gauge // total number of unique service name metrics reported by this collector
@@ -72,26 +80,45 @@ func NewSpanProcessorMetrics(serviceMetrics metrics.Factory, hostMetrics metrics
 	spanCounts := map[string]CountsBySpanType{
 		ZipkinFormatType:  newCountsBySpanType(serviceMetrics.Namespace(ZipkinFormatType, nil)),
 		JaegerFormatType:  newCountsBySpanType(serviceMetrics.Namespace(JaegerFormatType, nil)),
+		OTLPFormatType:    newCountsBySpanType(serviceMetrics.Namespace(OTLPFormatType, nil)),
 		UnknownFormatType: newCountsBySpanType(serviceMetrics.Namespace(UnknownFormatType, nil)),
 	}
 	for _, otherFormatType := range otherFormatTypes {
 		spanCounts[otherFormatType] = newCountsBySpanType(serviceMetrics.Namespace(otherFormatType, nil))
 	}
 	m := &SpanProcessorMetrics{
-		SaveLatency:    hostMetrics.Timer("save-latency", nil),
-		InQueueLatency: hostMetrics.Timer("in-queue-latency", nil),
-		SpansDropped:   hostMetrics.Counter("spans.dropped", nil),
-		BatchSize:      hostMetrics.Gauge("batch-size", nil),
-		QueueLength:    hostMetrics.Gauge("queue-length", nil),
-		ErrorBusy:      hostMetrics.Counter("error.busy", nil),
-		SavedBySvc:     newMetricsBySvc(serviceMetrics, "saved-by-svc"),
-		spanCounts:     spanCounts,
-		serviceNames:   hostMetrics.Gauge("spans.serviceNames", nil),
+		SaveLatency:      hostMetrics.Timer("save-latency", nil),
+		InQueueLatency:   hostMetrics.Timer("in-queue-latency", nil),
+		SpansDropped:     hostMetrics.Counter("spans.dropped", nil),
+		BatchSize:        hostMetrics.Gauge("batch-size", nil),
+		QueueLength:      hostMetrics.Gauge("queue-length", nil),
+		QueueCapacity:    hostMetrics.Gauge("queue-capacity", nil),
+		QueueUtilization: hostMetrics.Gauge("queue-utilization", nil),
+		DrainRemaining:   hostMetrics.Gauge("shutdown.queue-remaining", nil),
+		ErrorBusy:        hostMetrics.Counter("error.busy", nil),
+		RejectedBusy:     hostMetrics.Counter("batches.rejected", map[string]string{"reason": "busy"}),
+		SavedBySvc:       newMetricsBySvc(serviceMetrics, "saved-by-svc"),
+		spanCounts:       spanCounts,
+		serviceNames:     hostMetrics.Gauge("spans.serviceNames", nil),
 	}
 
 	return m
 }
 
+// queueLengthGauge reports a queue length to both the queue length and the queue utilization gauges
+type queueLengthGauge struct {
+	length      metrics.Gauge
+	utilization metrics.Gauge
+	capacity    int
+}
+
+func (g queueLengthGauge) Update(length int64) {
+	g.length.Update(length)
+	if g.capacity > 0 {
+		g.utilization.Update(100 * length / int64(g.capacity))
+	}
+}
+
 func newMetricsBySvc(factory metrics.Factory, category string) metricsBySvc {
 	return metricsBySvc{
 		spans: countsBySvc{
@@ -187,3 +214,35 @@ func (m *countsBySvc) countByServiceName(serviceName string) {
 		counter.Inc(1)
 	}
 }
+
+// counterBySvc is a counter tagged by the service of the spans it counts. Like countsBySvc, it stops
+// creating counters for new services once there are maxServiceNames of them.
+type counterBySvc struct {
+	name    string
+	factory metrics.Factory
+
+	lock     sync.Mutex
+	counters map[string]metrics.Counter
+}
+
+func newCounterBySvc(factory metrics.Factory, name string) *counterBySvc {
+	return &counterBySvc{
+		name:     name,
+		factory:  factory,
+		counters: make(map[string]metrics.Counter),
+	}
+}
+
+func (c *counterBySvc) inc(serviceName string) {
+	serviceName = NormalizeServiceName(serviceName)
+	c.lock.Lock()
+	counter, ok := c.counters[serviceName]
+	if !ok && len(c.counters) < maxServiceNames {
+		counter = c.factory.Counter(c.name, map[string]string{"service": serviceName})
+		c.counters[serviceName] = counter
+	}
+	c.lock.Unlock()
+	if counter != nil {
+		counter.Inc(1)
+	}
+}
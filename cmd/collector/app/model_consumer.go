@@ -33,3 +33,25 @@ func ChainedProcessSpan(spanProcessors ...ProcessSpan) ProcessSpan {
 		}
 	}
 }
+
+// ChainedProcessSpans chains processors as a single ProcessSpans call
+func ChainedProcessSpans(processors ...ProcessSpans) ProcessSpans {
+	return func(spans []*model.Span) {
+		for _, processor := range processors {
+			processor(spans)
+		}
+	}
+}
+
+// ChainedFilterSpan chains filters as a single FilterSpan call, which disallows a span as soon as
+// one of the filters does
+func ChainedFilterSpan(filters ...FilterSpan) FilterSpan {
+	return func(span *model.Span) bool {
+		for _, filter := range filters {
+			if !filter(span) {
+				return false
+			}
+		}
+		return true
+	}
+}
@@ -32,3 +32,26 @@ func TestChainedProcessSpan(t *testing.T) {
 	assert.True(t, happened1)
 	assert.True(t, happened2)
 }
+
+func TestChainedProcessSpans(t *testing.T) {
+	var called []string
+	func1 := func(spans []*model.Span) { called = append(called, "func1") }
+	func2 := func(spans []*model.Span) { called = append(called, "func2") }
+	ChainedProcessSpans(func1, func2)([]*model.Span{{}})
+	assert.Equal(t, []string{"func1", "func2"}, called)
+}
+
+func TestChainedFilterSpan(t *testing.T) {
+	var called []string
+	allow := func(span *model.Span) bool { called = append(called, "allow"); return true }
+	deny := func(span *model.Span) bool { called = append(called, "deny"); return false }
+
+	assert.True(t, ChainedFilterSpan(allow, allow)(&model.Span{}))
+	assert.Equal(t, []string{"allow", "allow"}, called)
+
+	called = nil
+	assert.False(t, ChainedFilterSpan(deny, allow)(&model.Span{}))
+	assert.Equal(t, []string{"deny"}, called, "filters after the first that disallows the span are not called")
+
+	assert.True(t, ChainedFilterSpan()(&model.Span{}))
+}
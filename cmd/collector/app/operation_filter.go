@@ -0,0 +1,75 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/uber/jaeger-lib/metrics"
+
+	"github.com/uber/jaeger/model"
+)
+
+// OperationFilter drops the spans whose operation name matches one of a list of patterns, e.g. the spans
+// of the health check and metrics endpoints that services trace along with their real traffic
+type OperationFilter struct {
+	patterns []*regexp.Regexp
+	matchURL bool
+	dropped  *counterBySvc
+}
+
+// NewOperationFilter creates an OperationFilter from regular expressions, it returns an error if one of them
+// does not compile. When matchURL is true the patterns are also matched against the http.url tag of the spans.
+// Dropped spans are counted in the spans.dropped-by-pattern counter tagged by service.
+func NewOperationFilter(patterns []string, matchURL bool, metricsFactory metrics.Factory) (*OperationFilter, error) {
+	f := &OperationFilter{
+		matchURL: matchURL,
+		dropped:  newCounterBySvc(metricsFactory, "spans.dropped-by-pattern"),
+	}
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid operation pattern %q: %v", pattern, err)
+		}
+		f.patterns = append(f.patterns, re)
+	}
+	return f, nil
+}
+
+// Filter returns false if the operation name of the span, or its http.url tag, matches one of the patterns.
+// It can be used as a FilterSpan.
+func (f *OperationFilter) Filter(span *model.Span) bool {
+	if !f.matches(span.OperationName) && !(f.matchURL && f.matchesURL(span)) {
+		return true
+	}
+	f.dropped.inc(span.Process.ServiceName)
+	return false
+}
+
+func (f *OperationFilter) matchesURL(span *model.Span) bool {
+	tag, ok := span.Tags.FindByKey(string(ext.HTTPUrl))
+	return ok && tag.VType == model.StringType && f.matches(tag.VStr)
+}
+
+func (f *OperationFilter) matches(value string) bool {
+	for _, pattern := range f.patterns {
+		if pattern.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
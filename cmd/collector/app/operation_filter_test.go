@@ -0,0 +1,91 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics"
+
+	"github.com/uber/jaeger/model"
+)
+
+func TestOperationFilter(t *testing.T) {
+	process := &model.Process{ServiceName: "noisy-service"}
+	urlTag := func(url string) model.KeyValues {
+		return model.KeyValues{model.String("http.url", url)}
+	}
+	testCases := []struct {
+		caption  string
+		span     *model.Span
+		expected bool
+		matchURL bool
+	}{
+		{
+			caption:  "health check operation",
+			span:     &model.Span{OperationName: "/health", Process: process},
+			expected: false,
+		},
+		{
+			caption:  "metrics operation",
+			span:     &model.Span{OperationName: "GET /metrics", Process: process},
+			expected: false,
+		},
+		{
+			caption:  "other operation",
+			span:     &model.Span{OperationName: "/healthy-food", Process: process},
+			expected: true,
+		},
+		{
+			caption:  "url tag without matching the url",
+			span:     &model.Span{OperationName: "HTTP GET", Process: process, Tags: urlTag("http://10.0.0.1/health")},
+			expected: true,
+		},
+		{
+			caption:  "matching url tag",
+			span:     &model.Span{OperationName: "HTTP GET", Process: process, Tags: urlTag("http://10.0.0.1/health")},
+			matchURL: true,
+			expected: false,
+		},
+		{
+			caption:  "other url tag",
+			span:     &model.Span{OperationName: "HTTP GET", Process: process, Tags: urlTag("http://10.0.0.1/api/orders")},
+			matchURL: true,
+			expected: true,
+		},
+		{
+			caption:  "non-string url tag",
+			span:     &model.Span{OperationName: "HTTP GET", Process: process, Tags: model.KeyValues{model.Int64("http.url", 1)}},
+			matchURL: true,
+			expected: true,
+		},
+	}
+	metricsFactory := metrics.NewLocalFactory(0)
+	for _, tc := range testCases {
+		filter, err := NewOperationFilter([]string{`/health$`, `^GET /metrics`}, tc.matchURL, metricsFactory)
+		require.NoError(t, err)
+		assert.Equal(t, tc.expected, filter.Filter(tc.span), tc.caption)
+	}
+
+	counters, _ := metricsFactory.Snapshot()
+	assert.EqualValues(t, 3, counters["spans.dropped-by-pattern|service=noisy-service"])
+}
+
+func TestOperationFilterInvalidPattern(t *testing.T) {
+	_, err := NewOperationFilter([]string{`^/health$`, `/metrics(`}, false, metrics.NullFactory)
+	assert.EqualError(t, err, "invalid operation pattern \"/metrics(\": error parsing regexp: missing closing ): `/metrics(`")
+}
@@ -0,0 +1,91 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"sync"
+
+	"github.com/uber/jaeger-lib/metrics"
+
+	"github.com/uber/jaeger/model"
+)
+
+const (
+	// DefaultMaxOperationsInMetrics is the default number of (service, operation) pairs with their own
+	// spans.duration timer
+	DefaultMaxOperationsInMetrics = 1000
+	// OtherOperations is the operation tag of the spans.duration timer of the operations past the limit
+	OtherOperations = "other"
+)
+
+type serviceOperation struct {
+	service   string
+	operation string
+}
+
+// OperationLatencies records the duration of the saved spans in the spans.duration timer tagged by the
+// service of their process and by their operation. Once maxOperations (service, operation) pairs have a
+// timer, the spans of new pairs are recorded with svc=other and operation=other so that the number of
+// timers is bounded.
+type OperationLatencies struct {
+	maxOperations  int
+	metricsFactory metrics.Factory
+
+	lock   sync.Mutex
+	timers map[serviceOperation]metrics.Timer
+	other  metrics.Timer
+}
+
+// NewOperationLatencies creates an OperationLatencies
+func NewOperationLatencies(maxOperations int, metricsFactory metrics.Factory) *OperationLatencies {
+	return &OperationLatencies{
+		maxOperations:  maxOperations,
+		metricsFactory: metricsFactory,
+		timers:         make(map[serviceOperation]metrics.Timer),
+		other: metricsFactory.Timer("spans.duration", map[string]string{
+			"svc":       OtherServices,
+			"operation": OtherOperations,
+		}),
+	}
+}
+
+// RecordSpan records the duration of the span, it can be used as the PreSave option
+func (l *OperationLatencies) RecordSpan(span *model.Span) {
+	l.timer(span).Record(span.Duration)
+}
+
+func (l *OperationLatencies) timer(span *model.Span) metrics.Timer {
+	if span.Process == nil || span.Process.ServiceName == "" || span.OperationName == "" {
+		return l.other
+	}
+	key := serviceOperation{
+		service:   NormalizeServiceName(span.Process.ServiceName),
+		operation: span.OperationName,
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if timer, ok := l.timers[key]; ok {
+		return timer
+	}
+	if len(l.timers) >= l.maxOperations {
+		return l.other
+	}
+	timer := l.metricsFactory.Timer("spans.duration", map[string]string{
+		"svc":       key.service,
+		"operation": key.operation,
+	})
+	l.timers[key] = timer
+	return timer
+}
@@ -0,0 +1,64 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uber/jaeger-lib/metrics"
+
+	"github.com/uber/jaeger/model"
+)
+
+func operationSpan(serviceName, operationName string, duration time.Duration) *model.Span {
+	return &model.Span{
+		OperationName: operationName,
+		Duration:      duration,
+		Process:       &model.Process{ServiceName: serviceName},
+	}
+}
+
+func TestOperationLatencies(t *testing.T) {
+	metricsFactory := metrics.NewLocalFactory(0)
+	latencies := NewOperationLatencies(DefaultMaxOperationsInMetrics, metricsFactory)
+
+	for i := 0; i < 10; i++ {
+		latencies.RecordSpan(operationSpan("frontend", "GET /dispatch", 30*time.Millisecond))
+	}
+	latencies.RecordSpan(operationSpan("my service", "query", 5*time.Millisecond))
+
+	_, gauges := metricsFactory.Snapshot()
+	assert.EqualValues(t, 30, gauges["spans.duration|operation=GET /dispatch|svc=frontend.P50"])
+	assert.EqualValues(t, 30, gauges["spans.duration|operation=GET /dispatch|svc=frontend.P99"])
+	assert.EqualValues(t, 5, gauges["spans.duration|operation=query|svc=my_service.P50"])
+}
+
+func TestOperationLatenciesMaxOperations(t *testing.T) {
+	metricsFactory := metrics.NewLocalFactory(0)
+	latencies := NewOperationLatencies(1, metricsFactory)
+
+	latencies.RecordSpan(operationSpan("frontend", "GET /dispatch", 30*time.Millisecond))
+	latencies.RecordSpan(operationSpan("frontend", "GET /config", 20*time.Millisecond))
+	latencies.RecordSpan(operationSpan("", "query", 20*time.Millisecond))
+	latencies.RecordSpan(&model.Span{Duration: 20 * time.Millisecond})
+
+	_, gauges := metricsFactory.Snapshot()
+	assert.EqualValues(t, 30, gauges["spans.duration|operation=GET /dispatch|svc=frontend.P50"])
+	assert.EqualValues(t, 20, gauges["spans.duration|operation=other|svc=other.P50"])
+	_, ok := gauges["spans.duration|operation=GET /config|svc=frontend.P50"]
+	assert.False(t, ok)
+}
@@ -15,10 +15,13 @@
 package app
 
 import (
+	"time"
+
 	"go.uber.org/zap"
 
 	"github.com/uber/jaeger-lib/metrics"
 	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/pkg/clock"
 
 	"github.com/uber/jaeger/cmd/collector/app/sanitizer"
 )
@@ -28,6 +31,8 @@ const (
 	DefaultNumWorkers = 50
 	// DefaultQueueSize is the size of the processor's queue
 	DefaultQueueSize = 2000
+	// DefaultShutdownTimeout is the default time to wait for the processor's queue to drain on Close
+	DefaultShutdownTimeout = 5 * time.Second
 )
 
 type options struct {
@@ -43,6 +48,10 @@ type options struct {
 	queueSize        int
 	reportBusy       bool
 	extraFormatTypes []string
+	shutdownTimeout  time.Duration
+	backpressure     float64
+	writeAheadLog    WriteAheadLog
+	clock            clock.Clock
 }
 
 // Option is a function that sets some option on StorageBuilder.
@@ -135,6 +144,36 @@ func (options) ExtraFormatTypes(extraFormatTypes []string) Option {
 	}
 }
 
+// ShutdownTimeout creates an Option that initializes how long Close waits for queued spans to be saved
+func (options) ShutdownTimeout(shutdownTimeout time.Duration) Option {
+	return func(b *options) {
+		b.shutdownTimeout = shutdownTimeout
+	}
+}
+
+// BackpressureThreshold creates an Option that makes span submission fail with tchannel.ErrServerBusy
+// while the queue is filled above the given fraction of its size, 0 disables the check
+func (options) BackpressureThreshold(backpressure float64) Option {
+	return func(b *options) {
+		b.backpressure = backpressure
+	}
+}
+
+// WriteAheadLog creates an Option that records the queued spans in writeAheadLog until they are saved,
+// and queues the spans it replays when the span processor is created
+func (options) WriteAheadLog(writeAheadLog WriteAheadLog) Option {
+	return func(b *options) {
+		b.writeAheadLog = writeAheadLog
+	}
+}
+
+// Clock creates an Option that initializes the clock the time spent in the queue and saving spans is measured with
+func (options) Clock(clock clock.Clock) Option {
+	return func(b *options) {
+		b.clock = clock
+	}
+}
+
 func (o options) apply(opts ...Option) options {
 	ret := options{}
 	for _, opt := range opts {
@@ -164,5 +203,11 @@ func (o options) apply(opts ...Option) options {
 	if ret.numWorkers == 0 {
 		ret.numWorkers = DefaultNumWorkers
 	}
+	if ret.shutdownTimeout == 0 {
+		ret.shutdownTimeout = DefaultShutdownTimeout
+	}
+	if ret.clock == nil {
+		ret.clock = clock.System
+	}
 	return ret
 }
@@ -39,9 +39,11 @@ func TestAllOptionSet(t *testing.T) {
 		Options.Sanitizer(func(span *model.Span) *model.Span { return span }),
 		Options.QueueSize(10),
 		Options.PreSave(func(span *model.Span) {}),
+		Options.BackpressureThreshold(0.8),
 	)
 	assert.EqualValues(t, 5, opts.numWorkers)
 	assert.EqualValues(t, 10, opts.queueSize)
+	assert.EqualValues(t, 0.8, opts.backpressure)
 }
 
 func TestNoOptionsSet(t *testing.T) {
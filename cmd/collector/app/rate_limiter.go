@@ -0,0 +1,134 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/uber/jaeger-lib/metrics"
+
+	"github.com/uber/jaeger/pkg/cache"
+)
+
+// DefaultRateLimitMaxClients is the default number of clients whose request rate a RequestRateLimiter tracks
+const DefaultRateLimitMaxClients = 10000
+
+const rejectReasonRateLimited = "rate-limited"
+
+// RequestRateLimiter rejects HTTP requests from clients that send more requests per second than the
+// collector accepts. Every client gets a token bucket, clients are told apart by their IP address or
+// by the value of a request header.
+type RequestRateLimiter struct {
+	qps       float64
+	burst     float64
+	keyHeader string
+	rejected  metrics.Counter
+	timeNow   func() time.Time
+
+	lock    sync.Mutex
+	buckets cache.Cache
+}
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// NewRequestRateLimiter creates a RequestRateLimiter that allows every client qps requests per second
+// on average and bursts of up to burst requests. Clients are keyed by the value of the keyHeader request
+// header if it is set, otherwise by their IP address. At most maxClients clients are tracked, the least
+// recently seen are forgotten first, and so are the clients idle long enough for their bucket to refill.
+// Rejected requests are counted in the batches.rejected counter tagged by reason.
+func NewRequestRateLimiter(qps float64, burst int, keyHeader string, maxClients int, metricsFactory metrics.Factory) *RequestRateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	l := &RequestRateLimiter{
+		qps:       qps,
+		burst:     float64(burst),
+		keyHeader: keyHeader,
+		rejected:  metricsFactory.Counter("batches.rejected", map[string]string{"reason": rejectReasonRateLimited}),
+		timeNow:   time.Now,
+	}
+	// a client idle for longer than it takes to refill its bucket is no different from a new one
+	refill := time.Duration(float64(burst) / qps * float64(time.Second))
+	l.buckets = cache.NewLRUWithOptions(maxClients, &cache.Options{
+		TTL:     refill + time.Second,
+		TimeNow: func() time.Time { return l.timeNow() },
+	})
+	return l
+}
+
+// Limit returns a handler that responds with 429 Too Many Requests, and the number of seconds to wait
+// in the Retry-After header, to clients over their rate. A nil RequestRateLimiter does not limit anything.
+func (l *RequestRateLimiter) Limit(handler http.Handler) http.Handler {
+	if l == nil {
+		return handler
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if wait, ok := l.allow(l.clientKey(r)); !ok {
+			l.rejected.Inc(1)
+			retryAfter := int(math.Ceil(wait.Seconds()))
+			if retryAfter < 1 {
+				retryAfter = 1
+			}
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			http.Error(w, fmt.Sprintf("Too many requests, retry in %ds", retryAfter), http.StatusTooManyRequests)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+func (l *RequestRateLimiter) clientKey(r *http.Request) string {
+	if l.keyHeader != "" {
+		if key := r.Header.Get(l.keyHeader); key != "" {
+			return key
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		// e.g. requests on a Unix domain socket
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// allow takes a token from the client's bucket, if there is none it returns how long until there is one
+func (l *RequestRateLimiter) allow(key string) (time.Duration, bool) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	now := l.timeNow()
+	bucket, _ := l.buckets.Get(key).(*tokenBucket)
+	if bucket == nil {
+		bucket = &tokenBucket{tokens: l.burst, updated: now}
+	} else if elapsed := now.Sub(bucket.updated); elapsed > 0 {
+		bucket.tokens = math.Min(l.burst, bucket.tokens+elapsed.Seconds()*l.qps)
+		bucket.updated = now
+	}
+	// putting the bucket back marks the client as recently seen
+	l.buckets.Put(key, bucket)
+	if bucket.tokens < 1 {
+		return time.Duration((1 - bucket.tokens) / l.qps * float64(time.Second)), false
+	}
+	bucket.tokens--
+	return 0, true
+}
@@ -0,0 +1,145 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uber/jaeger-lib/metrics"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func newTestRateLimiter(qps float64, burst int, keyHeader string, maxClients int) (*RequestRateLimiter, *fakeClock, *metrics.LocalFactory) {
+	mb := metrics.NewLocalFactory(time.Hour)
+	limiter := NewRequestRateLimiter(qps, burst, keyHeader, maxClients, mb)
+	clock := &fakeClock{now: time.Unix(1500000000, 0)}
+	limiter.timeNow = clock.Now
+	return limiter, clock, mb
+}
+
+func rateLimitedRequest(handler http.Handler, remoteAddr string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/traces", nil)
+	req.RemoteAddr = remoteAddr
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusAccepted)
+})
+
+func TestRequestRateLimiter(t *testing.T) {
+	limiter, clock, mb := newTestRateLimiter(2, 3, "", DefaultRateLimitMaxClients)
+	handler := limiter.Limit(okHandler)
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusAccepted, rateLimitedRequest(handler, "10.0.0.1:1234", nil).Code)
+	}
+	w := rateLimitedRequest(handler, "10.0.0.1:5678", nil)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+	counters, _ := mb.Snapshot()
+	assert.EqualValues(t, 1, counters["batches.rejected|reason=rate-limited"])
+
+	// other clients have their own bucket
+	assert.Equal(t, http.StatusAccepted, rateLimitedRequest(handler, "10.0.0.2:1234", nil).Code)
+
+	// one token is added every half second
+	clock.now = clock.now.Add(500 * time.Millisecond)
+	assert.Equal(t, http.StatusAccepted, rateLimitedRequest(handler, "10.0.0.1:1234", nil).Code)
+	assert.Equal(t, http.StatusTooManyRequests, rateLimitedRequest(handler, "10.0.0.1:1234", nil).Code)
+
+	// the bucket refills up to the burst
+	clock.now = clock.now.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusAccepted, rateLimitedRequest(handler, "10.0.0.1:1234", nil).Code)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, rateLimitedRequest(handler, "10.0.0.1:1234", nil).Code)
+	counters, _ = mb.Snapshot()
+	assert.EqualValues(t, 3, counters["batches.rejected|reason=rate-limited"])
+}
+
+func TestRequestRateLimiterRetryAfter(t *testing.T) {
+	limiter, _, _ := newTestRateLimiter(0.25, 1, "", DefaultRateLimitMaxClients)
+	handler := limiter.Limit(okHandler)
+
+	assert.Equal(t, http.StatusAccepted, rateLimitedRequest(handler, "10.0.0.1:1234", nil).Code)
+	w := rateLimitedRequest(handler, "10.0.0.1:1234", nil)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "4", w.Header().Get("Retry-After"))
+}
+
+func TestRequestRateLimiterKeyHeader(t *testing.T) {
+	limiter, _, _ := newTestRateLimiter(1, 1, "Jaeger-Service", DefaultRateLimitMaxClients)
+	handler := limiter.Limit(okHandler)
+
+	svc1 := http.Header{"Jaeger-Service": {"svc1"}}
+	svc2 := http.Header{"Jaeger-Service": {"svc2"}}
+	assert.Equal(t, http.StatusAccepted, rateLimitedRequest(handler, "10.0.0.1:1234", svc1).Code)
+	assert.Equal(t, http.StatusTooManyRequests, rateLimitedRequest(handler, "10.0.0.2:1234", svc1).Code)
+	assert.Equal(t, http.StatusAccepted, rateLimitedRequest(handler, "10.0.0.1:1234", svc2).Code)
+	// requests without the header are keyed by client IP
+	assert.Equal(t, http.StatusAccepted, rateLimitedRequest(handler, "10.0.0.1:1234", nil).Code)
+	assert.Equal(t, http.StatusTooManyRequests, rateLimitedRequest(handler, "10.0.0.1:1234", nil).Code)
+}
+
+func TestRequestRateLimiterUnixSocket(t *testing.T) {
+	limiter, _, _ := newTestRateLimiter(1, 1, "", DefaultRateLimitMaxClients)
+	handler := limiter.Limit(okHandler)
+
+	assert.Equal(t, http.StatusAccepted, rateLimitedRequest(handler, "@", nil).Code)
+	assert.Equal(t, http.StatusTooManyRequests, rateLimitedRequest(handler, "@", nil).Code)
+}
+
+func TestRequestRateLimiterEvictsClients(t *testing.T) {
+	limiter, clock, _ := newTestRateLimiter(1, 1, "", 2)
+	handler := limiter.Limit(okHandler)
+
+	for _, addr := range []string{"10.0.0.1:1234", "10.0.0.2:1234", "10.0.0.3:1234"} {
+		assert.Equal(t, http.StatusAccepted, rateLimitedRequest(handler, addr, nil).Code)
+	}
+	assert.Equal(t, 2, limiter.buckets.Size())
+	// the least recently seen client was forgotten
+	assert.Equal(t, http.StatusAccepted, rateLimitedRequest(handler, "10.0.0.1:1234", nil).Code)
+	assert.Equal(t, http.StatusTooManyRequests, rateLimitedRequest(handler, "10.0.0.3:1234", nil).Code)
+
+	// idle clients are forgotten once their bucket would have refilled
+	clock.now = clock.now.Add(time.Minute)
+	assert.Nil(t, limiter.buckets.Get("10.0.0.1"))
+	assert.Nil(t, limiter.buckets.Get("10.0.0.3"))
+}
+
+func TestNilRequestRateLimiter(t *testing.T) {
+	var limiter *RequestRateLimiter
+	handler := limiter.Limit(okHandler)
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, http.StatusAccepted, rateLimitedRequest(handler, "10.0.0.1:1234", nil).Code)
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"sync"
+
+	"github.com/uber/jaeger-lib/metrics"
+
+	"github.com/uber/jaeger/model"
+)
+
+const (
+	// DefaultMaxServicesInMetrics is the default number of services with their own spans.received counter
+	DefaultMaxServicesInMetrics = maxServiceNames
+	// OtherServices is the svc tag of the spans.received counter of the services past the limit
+	OtherServices = "other"
+)
+
+// ReceivedSpansCounter counts the received spans in the spans.received counter tagged by the service of
+// their process. Once maxServices services have a counter, the spans of new services are counted with
+// svc=other so that the number of counters is bounded.
+type ReceivedSpansCounter struct {
+	maxServices    int
+	metricsFactory metrics.Factory
+
+	lock     sync.Mutex
+	counters map[string]metrics.Counter
+	other    metrics.Counter
+}
+
+// NewReceivedSpansCounter creates a ReceivedSpansCounter
+func NewReceivedSpansCounter(maxServices int, metricsFactory metrics.Factory) *ReceivedSpansCounter {
+	return &ReceivedSpansCounter{
+		maxServices:    maxServices,
+		metricsFactory: metricsFactory,
+		counters:       make(map[string]metrics.Counter),
+		other:          metricsFactory.Counter("spans.received", map[string]string{"svc": OtherServices}),
+	}
+}
+
+// ProcessSpans counts the spans by service, it can be used as the PreProcessSpans option
+func (c *ReceivedSpansCounter) ProcessSpans(spans []*model.Span) {
+	for _, span := range spans {
+		c.counter(span.Process).Inc(1)
+	}
+}
+
+func (c *ReceivedSpansCounter) counter(process *model.Process) metrics.Counter {
+	if process == nil || process.ServiceName == "" {
+		return c.other
+	}
+	serviceName := NormalizeServiceName(process.ServiceName)
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if counter, ok := c.counters[serviceName]; ok {
+		return counter
+	}
+	if serviceName == OtherServices || len(c.counters) >= c.maxServices {
+		return c.other
+	}
+	counter := c.metricsFactory.Counter("spans.received", map[string]string{"svc": serviceName})
+	c.counters[serviceName] = counter
+	return counter
+}
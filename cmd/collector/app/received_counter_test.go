@@ -0,0 +1,59 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uber/jaeger-lib/metrics"
+
+	"github.com/uber/jaeger/model"
+)
+
+func spansOf(serviceNames ...string) []*model.Span {
+	spans := make([]*model.Span, len(serviceNames))
+	for i, serviceName := range serviceNames {
+		spans[i] = &model.Span{Process: &model.Process{ServiceName: serviceName}}
+	}
+	return spans
+}
+
+func TestReceivedSpansCounter(t *testing.T) {
+	metricsFactory := metrics.NewLocalFactory(0)
+	counter := NewReceivedSpansCounter(2, metricsFactory)
+
+	counter.ProcessSpans(spansOf("frontend", "backend", "frontend"))
+	counter.ProcessSpans(spansOf("redis", "mysql", "backend", ""))
+	counter.ProcessSpans([]*model.Span{{}})
+
+	counters, _ := metricsFactory.Snapshot()
+	assert.Equal(t, map[string]int64{
+		"spans.received|svc=frontend": 2,
+		"spans.received|svc=backend":  2,
+		"spans.received|svc=other":    4,
+	}, counters)
+}
+
+func TestReceivedSpansCounterNormalizesServiceNames(t *testing.T) {
+	metricsFactory := metrics.NewLocalFactory(0)
+	counter := NewReceivedSpansCounter(DefaultMaxServicesInMetrics, metricsFactory)
+
+	counter.ProcessSpans(spansOf("my service", "my_service", "other"))
+
+	counters, _ := metricsFactory.Snapshot()
+	assert.EqualValues(t, 2, counters["spans.received|svc=my_service"])
+	assert.EqualValues(t, 1, counters["spans.received|svc=other"])
+}
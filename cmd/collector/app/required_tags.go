@@ -0,0 +1,77 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"strings"
+
+	"github.com/uber/jaeger-lib/metrics"
+
+	"github.com/uber/jaeger/model"
+)
+
+// MissingRequiredTagsKey is the tag added to the spans whose process lacks some of the required tags,
+// its value is the comma-separated list of the missing keys
+const MissingRequiredTagsKey = "jaeger.missing-required-tags"
+
+// RequiredTagsChecker checks that the process of each span has all the required tags. The spans that
+// do not are counted in the spans.missing-required-tags counter tagged by service.
+type RequiredTagsChecker struct {
+	keys    []string
+	missing *counterBySvc
+}
+
+// NewRequiredTagsChecker creates a RequiredTagsChecker for the given process tag keys
+func NewRequiredTagsChecker(keys []string, metricsFactory metrics.Factory) *RequiredTagsChecker {
+	return &RequiredTagsChecker{
+		keys:    keys,
+		missing: newCounterBySvc(metricsFactory, "spans.missing-required-tags"),
+	}
+}
+
+// Filter returns false if the process of the span lacks any of the required tags.
+// It can be used as a FilterSpan.
+func (c *RequiredTagsChecker) Filter(span *model.Span) bool {
+	return len(c.check(span)) == 0
+}
+
+// Sanitize adds the MissingRequiredTagsKey tag to the span if its process lacks any of the required tags.
+// It can be used as a SanitizeSpan.
+func (c *RequiredTagsChecker) Sanitize(span *model.Span) *model.Span {
+	if missing := c.check(span); len(missing) > 0 {
+		span.Tags = append(span.Tags, model.String(MissingRequiredTagsKey, strings.Join(missing, ",")))
+	}
+	return span
+}
+
+// check returns the required keys missing from the process of the span, and counts the span if there are any
+func (c *RequiredTagsChecker) check(span *model.Span) []string {
+	var missing []string
+	var serviceName string
+	var tags model.KeyValues
+	if span.Process != nil {
+		serviceName = span.Process.ServiceName
+		tags = span.Process.Tags
+	}
+	for _, key := range c.keys {
+		if _, ok := tags.FindByKey(key); !ok {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		c.missing.inc(serviceName)
+	}
+	return missing
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uber/jaeger-lib/metrics"
+
+	"github.com/uber/jaeger/model"
+)
+
+func requiredTagsTestSpans() (complete, incomplete, noProcess *model.Span) {
+	complete = &model.Span{Process: &model.Process{
+		ServiceName: "good-service",
+		Tags:        model.KeyValues{model.String("team", "a"), model.String("env", "prod")},
+	}}
+	incomplete = &model.Span{Process: &model.Process{
+		ServiceName: "bad-service",
+		Tags:        model.KeyValues{model.String("env", "prod")},
+	}}
+	noProcess = &model.Span{}
+	return
+}
+
+func TestRequiredTagsCheckerDropPolicy(t *testing.T) {
+	metricsFactory := metrics.NewLocalFactory(0)
+	checker := NewRequiredTagsChecker([]string{"team", "env"}, metricsFactory)
+	complete, incomplete, noProcess := requiredTagsTestSpans()
+
+	assert.True(t, checker.Filter(complete))
+	assert.False(t, checker.Filter(incomplete))
+	assert.False(t, checker.Filter(noProcess))
+
+	counters, _ := metricsFactory.Snapshot()
+	assert.EqualValues(t, 1, counters["spans.missing-required-tags|service=bad-service"])
+	assert.EqualValues(t, 1, counters["spans.missing-required-tags|service="+NormalizeServiceName("")])
+	assert.NotContains(t, counters, "spans.missing-required-tags|service=good-service")
+}
+
+func TestRequiredTagsCheckerTagPolicy(t *testing.T) {
+	metricsFactory := metrics.NewLocalFactory(0)
+	checker := NewRequiredTagsChecker([]string{"team", "env"}, metricsFactory)
+	complete, incomplete, noProcess := requiredTagsTestSpans()
+
+	assert.Empty(t, checker.Sanitize(complete).Tags)
+
+	tag, ok := checker.Sanitize(incomplete).Tags.FindByKey(MissingRequiredTagsKey)
+	assert.True(t, ok)
+	assert.Equal(t, "team", tag.VStr)
+
+	tag, ok = checker.Sanitize(noProcess).Tags.FindByKey(MissingRequiredTagsKey)
+	assert.True(t, ok)
+	assert.Equal(t, "team,env", tag.VStr)
+
+	counters, _ := metricsFactory.Snapshot()
+	assert.EqualValues(t, 1, counters["spans.missing-required-tags|service=bad-service"])
+}
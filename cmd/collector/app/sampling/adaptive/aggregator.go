@@ -0,0 +1,140 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package adaptive
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/uber/jaeger/cmd/collector/app/sampling/model"
+	jModel "github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/storage/samplingstore"
+)
+
+const (
+	samplerTypeKey           = "sampler.type"
+	samplerParamKey          = "sampler.param"
+	samplerTypeProbabilistic = "probabilistic"
+)
+
+// Aggregator counts the root spans received for every service and operation and periodically
+// flushes the counts to the sampling store.
+type Aggregator struct {
+	sync.Mutex
+
+	store      samplingstore.Store
+	logger     *zap.Logger
+	interval   time.Duration
+	throughput map[string]map[string]*model.Throughput
+	stop       chan struct{}
+	done       sync.WaitGroup
+}
+
+// NewAggregator creates an Aggregator that writes throughput to store every interval.
+func NewAggregator(store samplingstore.Store, interval time.Duration, logger *zap.Logger) *Aggregator {
+	return &Aggregator{
+		store:      store,
+		logger:     logger,
+		interval:   interval,
+		throughput: make(map[string]map[string]*model.Throughput),
+		stop:       make(chan struct{}),
+	}
+}
+
+// Start starts the background flushing of throughput.
+func (a *Aggregator) Start() {
+	a.done.Add(1)
+	go func() {
+		defer a.done.Done()
+		ticker := time.NewTicker(a.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				a.flush()
+			case <-a.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Close stops the background flushing and writes out whatever has been aggregated so far.
+func (a *Aggregator) Close() error {
+	close(a.stop)
+	a.done.Wait()
+	a.flush()
+	return nil
+}
+
+// RecordSpan counts the span if it is the root span of a probabilistically sampled trace,
+// since only those reflect the sampling decision made by the client.
+func (a *Aggregator) RecordSpan(span *jModel.Span) {
+	if span.ParentSpanID != 0 || span.Process == nil {
+		return
+	}
+	samplerType, ok := span.Tags.FindByKey(samplerTypeKey)
+	if !ok || samplerType.AsString() != samplerTypeProbabilistic {
+		return
+	}
+	samplerParam, ok := span.Tags.FindByKey(samplerParamKey)
+	if !ok {
+		return
+	}
+	a.recordThroughput(span.Process.ServiceName, span.OperationName, samplerParam.AsString())
+}
+
+func (a *Aggregator) recordThroughput(service, operation, probability string) {
+	a.Lock()
+	defer a.Unlock()
+	operations, ok := a.throughput[service]
+	if !ok {
+		operations = make(map[string]*model.Throughput)
+		a.throughput[service] = operations
+	}
+	throughput, ok := operations[operation]
+	if !ok {
+		throughput = &model.Throughput{
+			Service:       service,
+			Operation:     operation,
+			Probabilities: make(map[string]struct{}),
+		}
+		operations[operation] = throughput
+	}
+	throughput.Count++
+	throughput.Probabilities[probability] = struct{}{}
+}
+
+func (a *Aggregator) flush() {
+	a.Lock()
+	current := a.throughput
+	a.throughput = make(map[string]map[string]*model.Throughput)
+	a.Unlock()
+
+	var throughput []*model.Throughput
+	for _, operations := range current {
+		for _, t := range operations {
+			throughput = append(throughput, t)
+		}
+	}
+	if len(throughput) == 0 {
+		return
+	}
+	if err := a.store.InsertThroughput(throughput); err != nil {
+		a.logger.Error("Failed to write throughput to sampling store", zap.Error(err))
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package adaptive
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/uber/jaeger/cmd/collector/app/sampling/model"
+	jModel "github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/pkg/testutils"
+	"github.com/uber/jaeger/storage/samplingstore/mocks"
+)
+
+func rootSpan(service, operation string, tags ...jModel.KeyValue) *jModel.Span {
+	return &jModel.Span{
+		OperationName: operation,
+		Process:       &jModel.Process{ServiceName: service},
+		Tags:          tags,
+	}
+}
+
+func probabilisticTags(probability float64) []jModel.KeyValue {
+	return []jModel.KeyValue{
+		jModel.String(samplerTypeKey, samplerTypeProbabilistic),
+		jModel.Float64(samplerParamKey, probability),
+	}
+}
+
+func TestAggregatorRecordSpan(t *testing.T) {
+	store := &mocks.Store{}
+	var written []*model.Throughput
+	store.On("InsertThroughput", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		written = args.Get(0).([]*model.Throughput)
+	})
+	a := NewAggregator(store, time.Hour, zap.NewNop())
+
+	a.RecordSpan(rootSpan("svc", "op", probabilisticTags(0.001)...))
+	a.RecordSpan(rootSpan("svc", "op", probabilisticTags(0.002)...))
+	// not probabilistic
+	a.RecordSpan(rootSpan("svc", "op", jModel.String(samplerTypeKey, "const"), jModel.Bool(samplerParamKey, true)))
+	// no sampler tags
+	a.RecordSpan(rootSpan("svc", "op"))
+	// not a root span
+	child := rootSpan("svc", "op", probabilisticTags(0.001)...)
+	child.ParentSpanID = 1
+	a.RecordSpan(child)
+
+	a.flush()
+	require.Len(t, written, 1)
+	assert.Equal(t, "svc", written[0].Service)
+	assert.Equal(t, "op", written[0].Operation)
+	assert.EqualValues(t, 2, written[0].Count)
+	assert.Len(t, written[0].Probabilities, 2)
+
+	// nothing recorded since the last flush, nothing written
+	written = nil
+	a.flush()
+	assert.Nil(t, written)
+	store.AssertNumberOfCalls(t, "InsertThroughput", 1)
+}
+
+func TestAggregatorFlushesPeriodically(t *testing.T) {
+	store := &mocks.Store{}
+	flushed := make(chan struct{}, 1)
+	store.On("InsertThroughput", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		select {
+		case flushed <- struct{}{}:
+		default:
+		}
+	})
+	a := NewAggregator(store, time.Millisecond, zap.NewNop())
+	a.Start()
+	defer a.Close()
+
+	a.RecordSpan(rootSpan("svc", "op", probabilisticTags(0.001)...))
+	select {
+	case <-flushed:
+	case <-time.After(time.Second):
+		t.Fatal("throughput was never flushed")
+	}
+}
+
+func TestAggregatorFlushesOnClose(t *testing.T) {
+	logger, buf := testutils.NewLogger()
+	store := &mocks.Store{}
+	store.On("InsertThroughput", mock.Anything).Return(errors.New("store down"))
+	a := NewAggregator(store, time.Hour, logger)
+	a.Start()
+
+	a.RecordSpan(rootSpan("svc", "op", probabilisticTags(0.001)...))
+	require.NoError(t, a.Close())
+	store.AssertNumberOfCalls(t, "InsertThroughput", 1)
+	assert.Equal(t, "Failed to write throughput to sampling store", buf.JSONLine(0)["msg"])
+}
@@ -0,0 +1,214 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package adaptive
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/uber/tchannel-go/thrift"
+	"go.uber.org/zap"
+
+	"github.com/uber/jaeger/cmd/collector/app/sampling/model"
+	"github.com/uber/jaeger/storage/samplingstore"
+	"github.com/uber/jaeger/thrift-gen/sampling"
+)
+
+const (
+	// DefaultTargetQPS is the default number of traces per second sampled for every operation
+	DefaultTargetQPS = 1.0
+	// DefaultAggregationInterval is the default interval at which throughput is flushed and probabilities recalculated
+	DefaultAggregationInterval = time.Minute
+
+	defaultSamplingProbability = 0.001
+	minSamplingProbability     = 0.00001
+	// lowerBoundTracesPerSecond guarantees that rarely called operations are still sampled once a minute
+	lowerBoundTracesPerSecond = 1.0 / 60
+)
+
+// Processor periodically computes per operation sampling probabilities from the throughput in the
+// sampling store so that every operation is sampled at roughly targetQPS, and serves the result
+// as sampling strategies.
+type Processor struct {
+	sync.RWMutex
+
+	store     samplingstore.Store
+	hostname  string
+	targetQPS float64
+	interval  time.Duration
+	logger    *zap.Logger
+
+	probabilities model.ServiceOperationProbabilities
+	strategies    map[string]*sampling.SamplingStrategyResponse
+
+	timeNow func() time.Time
+	stop    chan struct{}
+	done    sync.WaitGroup
+}
+
+// NewProcessor creates a Processor. hostname identifies this collector when the calculated
+// probabilities are written to the store.
+func NewProcessor(
+	store samplingstore.Store,
+	hostname string,
+	targetQPS float64,
+	interval time.Duration,
+	logger *zap.Logger,
+) *Processor {
+	return &Processor{
+		store:         store,
+		hostname:      hostname,
+		targetQPS:     targetQPS,
+		interval:      interval,
+		logger:        logger,
+		probabilities: make(model.ServiceOperationProbabilities),
+		strategies:    make(map[string]*sampling.SamplingStrategyResponse),
+		timeNow:       time.Now,
+		stop:          make(chan struct{}),
+	}
+}
+
+// Start loads the latest probabilities from the store and starts recalculating them every interval.
+func (p *Processor) Start() {
+	probabilities, err := p.store.GetLatestProbabilities()
+	if err != nil {
+		p.logger.Error("Failed to load latest sampling probabilities", zap.Error(err))
+	} else if probabilities != nil {
+		p.setProbabilities(probabilities)
+	}
+
+	p.done.Add(1)
+	go func() {
+		defer p.done.Done()
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				p.calculate()
+			case <-p.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Close stops recalculating the probabilities.
+func (p *Processor) Close() error {
+	close(p.stop)
+	p.done.Wait()
+	return nil
+}
+
+// GetSamplingStrategy implements sampling.TChanSamplingManager.
+func (p *Processor) GetSamplingStrategy(ctx thrift.Context, serviceName string) (*sampling.SamplingStrategyResponse, error) {
+	p.RLock()
+	defer p.RUnlock()
+	if strategy, ok := p.strategies[serviceName]; ok {
+		return strategy, nil
+	}
+	return newStrategy(nil), nil
+}
+
+func (p *Processor) calculate() {
+	end := p.timeNow()
+	throughput, err := p.store.GetThroughput(end.Add(-p.interval), end)
+	if err != nil {
+		p.logger.Error("Failed to read throughput from sampling store", zap.Error(err))
+		return
+	}
+
+	qps := make(model.ServiceOperationQPS)
+	for _, t := range throughput {
+		if _, ok := qps[t.Service]; !ok {
+			qps[t.Service] = make(map[string]float64)
+		}
+		qps[t.Service][t.Operation] += float64(t.Count) / p.interval.Seconds()
+	}
+
+	p.RLock()
+	probabilities := make(model.ServiceOperationProbabilities, len(p.probabilities))
+	for service, operations := range p.probabilities {
+		probabilities[service] = make(map[string]float64, len(operations))
+		for operation, probability := range operations {
+			probabilities[service][operation] = probability
+		}
+	}
+	p.RUnlock()
+
+	for service, operations := range qps {
+		if _, ok := probabilities[service]; !ok {
+			probabilities[service] = make(map[string]float64)
+		}
+		for operation, operationQPS := range operations {
+			probability, ok := probabilities[service][operation]
+			if !ok {
+				probability = defaultSamplingProbability
+			}
+			probabilities[service][operation] = p.calculateProbability(probability, operationQPS)
+		}
+	}
+
+	if err := p.store.InsertProbabilitiesAndQPS(p.hostname, probabilities, qps); err != nil {
+		p.logger.Error("Failed to write sampling probabilities to sampling store", zap.Error(err))
+	}
+	p.setProbabilities(probabilities)
+}
+
+// calculateProbability scales the current probability by how far the observed qps of sampled
+// traces is from the target. The observed qps was produced with the current probability, so
+// the same ratio applied to the probability brings the next interval close to the target.
+func (p *Processor) calculateProbability(probability, qps float64) float64 {
+	if qps == 0 {
+		return probability
+	}
+	newProbability := probability * p.targetQPS / qps
+	return math.Min(1.0, math.Max(minSamplingProbability, newProbability))
+}
+
+func (p *Processor) setProbabilities(probabilities model.ServiceOperationProbabilities) {
+	strategies := make(map[string]*sampling.SamplingStrategyResponse, len(probabilities))
+	for service, operations := range probabilities {
+		strategies[service] = newStrategy(operations)
+	}
+	p.Lock()
+	defer p.Unlock()
+	p.probabilities = probabilities
+	p.strategies = strategies
+}
+
+func newStrategy(operations map[string]float64) *sampling.SamplingStrategyResponse {
+	strategies := make([]*sampling.OperationSamplingStrategy, 0, len(operations))
+	for operation, probability := range operations {
+		strategies = append(strategies, &sampling.OperationSamplingStrategy{
+			Operation: operation,
+			ProbabilisticSampling: &sampling.ProbabilisticSamplingStrategy{
+				SamplingRate: probability,
+			},
+		})
+	}
+	return &sampling.SamplingStrategyResponse{
+		StrategyType: sampling.SamplingStrategyType_PROBABILISTIC,
+		ProbabilisticSampling: &sampling.ProbabilisticSamplingStrategy{
+			SamplingRate: defaultSamplingProbability,
+		},
+		OperationSampling: &sampling.PerOperationSamplingStrategies{
+			DefaultSamplingProbability:       defaultSamplingProbability,
+			DefaultLowerBoundTracesPerSecond: lowerBoundTracesPerSecond,
+			PerOperationStrategies:           strategies,
+		},
+	}
+}
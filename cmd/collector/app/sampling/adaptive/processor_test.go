@@ -0,0 +1,118 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package adaptive
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/uber/jaeger/cmd/collector/app/sampling/model"
+	"github.com/uber/jaeger/storage/samplingstore/mocks"
+	"github.com/uber/jaeger/thrift-gen/sampling"
+)
+
+func TestProcessorCalculate(t *testing.T) {
+	now := time.Unix(1000, 0)
+	interval := 10 * time.Second
+	store := &mocks.Store{}
+	store.On("GetLatestProbabilities").Return(model.ServiceOperationProbabilities{
+		"svc": {"op1": 0.1, "idle": 0.5},
+	}, nil)
+	store.On("GetThroughput", now.Add(-interval), now).Return([]*model.Throughput{
+		// op1 is sampled at 4 qps across two collectors with probability 0.1, so it should go down to 0.025
+		{Service: "svc", Operation: "op1", Count: 20},
+		{Service: "svc", Operation: "op1", Count: 20},
+		// op2 is new and sampled at 0.1 qps with the default probability, so it should go up tenfold
+		{Service: "svc", Operation: "op2", Count: 1},
+	}, nil)
+	var qps model.ServiceOperationQPS
+	store.On("InsertProbabilitiesAndQPS", "host", mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		qps = args.Get(2).(model.ServiceOperationQPS)
+	})
+
+	p := NewProcessor(store, "host", 1.0, interval, zap.NewNop())
+	p.timeNow = func() time.Time { return now }
+	p.Start()
+	defer p.Close()
+	p.calculate()
+
+	assert.Equal(t, model.ServiceOperationQPS{"svc": {"op1": 4, "op2": 0.1}}, qps)
+	assert.InDelta(t, 0.025, p.probabilities["svc"]["op1"], 1e-9)
+	assert.InDelta(t, defaultSamplingProbability*10, p.probabilities["svc"]["op2"], 1e-9)
+	assert.InDelta(t, 0.5, p.probabilities["svc"]["idle"], 1e-9, "operations without throughput keep their probability")
+
+	strategy, err := p.GetSamplingStrategy(nil, "svc")
+	require.NoError(t, err)
+	require.NotNil(t, strategy.OperationSampling)
+	assert.Len(t, strategy.OperationSampling.PerOperationStrategies, 3)
+	for _, s := range strategy.OperationSampling.PerOperationStrategies {
+		assert.InDelta(t, p.probabilities["svc"][s.Operation], s.ProbabilisticSampling.SamplingRate, 1e-9)
+	}
+}
+
+func TestProcessorCalculateProbability(t *testing.T) {
+	p := NewProcessor(&mocks.Store{}, "host", 1.0, time.Minute, zap.NewNop())
+	tests := []struct {
+		probability float64
+		qps         float64
+		expected    float64
+	}{
+		{probability: 0.5, qps: 0, expected: 0.5},
+		{probability: 0.5, qps: 0.1, expected: 1.0},
+		{probability: 0.5, qps: 2, expected: 0.25},
+		{probability: 0.001, qps: 1000000, expected: minSamplingProbability},
+	}
+	for _, test := range tests {
+		assert.InDelta(t, test.expected, p.calculateProbability(test.probability, test.qps), 1e-9)
+	}
+}
+
+func TestProcessorUnknownService(t *testing.T) {
+	store := &mocks.Store{}
+	store.On("GetLatestProbabilities").Return(model.ServiceOperationProbabilities(nil), errors.New("store down"))
+	p := NewProcessor(store, "host", 1.0, time.Hour, zap.NewNop())
+	p.Start()
+	defer p.Close()
+
+	strategy, err := p.GetSamplingStrategy(nil, "svc")
+	require.NoError(t, err)
+	assert.Equal(t, sampling.SamplingStrategyType_PROBABILISTIC, strategy.StrategyType)
+	assert.Equal(t, defaultSamplingProbability, strategy.ProbabilisticSampling.SamplingRate)
+	assert.Equal(t, defaultSamplingProbability, strategy.OperationSampling.DefaultSamplingProbability)
+	assert.Empty(t, strategy.OperationSampling.PerOperationStrategies)
+}
+
+func TestProcessorStoreErrors(t *testing.T) {
+	store := &mocks.Store{}
+	store.On("GetThroughput", mock.Anything, mock.Anything).Return(nil, errors.New("store down")).Once()
+	p := NewProcessor(store, "host", 1.0, time.Hour, zap.NewNop())
+	p.calculate()
+	store.AssertNotCalled(t, "InsertProbabilitiesAndQPS", mock.Anything, mock.Anything, mock.Anything)
+
+	store.On("GetThroughput", mock.Anything, mock.Anything).Return([]*model.Throughput{
+		{Service: "svc", Operation: "op", Count: 60},
+	}, nil)
+	store.On("InsertProbabilitiesAndQPS", "host", mock.Anything, mock.Anything).Return(errors.New("store down"))
+	p.calculate()
+	// 60 traces an hour is a qps of 1/60, far below the target of 1
+	assert.InDelta(t, defaultSamplingProbability*60, p.probabilities["svc"]["op"], 1e-9,
+		"probabilities are still served when they cannot be persisted")
+}
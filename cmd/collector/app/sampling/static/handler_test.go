@@ -0,0 +1,88 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package static
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/uber/jaeger/thrift-gen/sampling"
+)
+
+func getStrategies(t *testing.T, router *mux.Router) *Strategies {
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/sampling/strategies", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	var strategies Strategies
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &strategies))
+	return &strategies
+}
+
+func TestStrategiesHandler(t *testing.T) {
+	store, err := NewStore("fixtures/strategies.json", time.Hour, zap.NewNop())
+	require.NoError(t, err)
+	defer store.Close()
+	router := mux.NewRouter()
+	RegisterRoute(router, store)
+
+	strategies := getStrategies(t, router)
+	assert.Equal(t, store.Strategies(), strategies)
+	assert.Equal(t, 0.5, strategies.DefaultStrategy.ProbabilisticSampling.SamplingRate)
+	require.Len(t, strategies.ServiceStrategies, 2)
+	assert.EqualValues(t, 5, strategies.ServiceStrategies["bar"].RateLimitingSampling.MaxTracesPerSecond)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/sampling/strategies", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code, "the endpoint is read-only")
+}
+
+func TestStrategiesHandlerAfterReload(t *testing.T) {
+	path, cleanup := writeTempFile(t, `{"default_strategy": {"type": "probabilistic", "param": 0.5}}`)
+	defer cleanup()
+	store, err := NewStore(path, time.Millisecond, zap.NewNop())
+	require.NoError(t, err)
+	defer store.Close()
+	router := mux.NewRouter()
+	RegisterRoute(router, store)
+
+	strategies := getStrategies(t, router)
+	assert.Equal(t, 0.5, strategies.DefaultStrategy.ProbabilisticSampling.SamplingRate)
+	assert.Empty(t, strategies.ServiceStrategies)
+
+	updateFile(t, path, `{
+		"default_strategy": {"type": "probabilistic", "param": 0.1},
+		"service_strategies": [{"service": "foo", "type": "ratelimiting", "param": 3}]
+	}`, time.Now().Add(time.Minute))
+	for i := 0; i < 1000; i++ {
+		if strategies = getStrategies(t, router); len(strategies.ServiceStrategies) > 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, 0.1, strategies.DefaultStrategy.ProbabilisticSampling.SamplingRate)
+	require.Contains(t, strategies.ServiceStrategies, "foo")
+	foo := strategies.ServiceStrategies["foo"]
+	assert.Equal(t, sampling.SamplingStrategyType_RATE_LIMITING, foo.StrategyType)
+	assert.EqualValues(t, 3, foo.RateLimitingSampling.MaxTracesPerSecond)
+}
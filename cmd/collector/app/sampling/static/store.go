@@ -0,0 +1,266 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package static
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/uber/tchannel-go/thrift"
+	"go.uber.org/zap"
+
+	"github.com/uber/jaeger/thrift-gen/sampling"
+)
+
+// DefaultReloadInterval is the default interval at which the strategies file is checked for changes
+const DefaultReloadInterval = 10 * time.Second
+
+// envVarPattern matches the ${VAR} and ${VAR:-default} references to environment variables
+var envVarPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// Store serves sampling strategies read from a JSON file. The file is reloaded when it changes.
+// The ${VAR} and ${VAR:-default} references in the file are replaced with the values of the environment
+// variables, e.g. to set the sampling rates of each environment.
+type Store struct {
+	sync.RWMutex
+
+	path           string
+	reloadInterval time.Duration
+	logger         *zap.Logger
+
+	modTime           time.Time
+	defaultStrategy   *sampling.SamplingStrategyResponse
+	serviceStrategies map[string]*sampling.SamplingStrategyResponse
+
+	stop chan struct{}
+	done sync.WaitGroup
+}
+
+// NewStore creates a Store from the strategies file at path and checks the file for changes
+// every reloadInterval. It returns an error if the file cannot be loaded.
+func NewStore(path string, reloadInterval time.Duration, logger *zap.Logger) (*Store, error) {
+	s := &Store{
+		path:           path,
+		reloadInterval: reloadInterval,
+		logger:         logger,
+		stop:           make(chan struct{}),
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.load(info.ModTime()); err != nil {
+		return nil, err
+	}
+	s.done.Add(1)
+	go s.watch()
+	return s, nil
+}
+
+// GetSamplingStrategy implements sampling.TChanSamplingManager.
+func (s *Store) GetSamplingStrategy(ctx thrift.Context, serviceName string) (*sampling.SamplingStrategyResponse, error) {
+	s.RLock()
+	defer s.RUnlock()
+	if strategy, ok := s.serviceStrategies[serviceName]; ok {
+		return strategy, nil
+	}
+	return s.defaultStrategy, nil
+}
+
+// Strategies is a snapshot of the sampling strategies served by a Store
+type Strategies struct {
+	// DefaultStrategy is served to the services without a strategy of their own
+	DefaultStrategy *sampling.SamplingStrategyResponse `json:"defaultStrategy"`
+	// ServiceStrategies are the strategies of the services listed in the strategies file
+	ServiceStrategies map[string]*sampling.SamplingStrategyResponse `json:"serviceStrategies"`
+}
+
+// Strategies returns the strategies currently served, as of the last reload of the strategies file.
+func (s *Store) Strategies() *Strategies {
+	s.RLock()
+	defer s.RUnlock()
+	serviceStrategies := make(map[string]*sampling.SamplingStrategyResponse, len(s.serviceStrategies))
+	for service, strategy := range s.serviceStrategies {
+		serviceStrategies[service] = strategy
+	}
+	return &Strategies{
+		DefaultStrategy:   s.defaultStrategy,
+		ServiceStrategies: serviceStrategies,
+	}
+}
+
+// Close stops watching the strategies file.
+func (s *Store) Close() error {
+	close(s.stop)
+	s.done.Wait()
+	return nil
+}
+
+func (s *Store) watch() {
+	defer s.done.Done()
+	ticker := time.NewTicker(s.reloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.reloadIfChanged()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+func (s *Store) reloadIfChanged() {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		s.logger.Error("Failed to check sampling strategies file", zap.String("file", s.path), zap.Error(err))
+		return
+	}
+	s.RLock()
+	modTime := s.modTime
+	s.RUnlock()
+	if info.ModTime().Equal(modTime) {
+		return
+	}
+	if err := s.load(info.ModTime()); err != nil {
+		s.logger.Error("Failed to reload sampling strategies, keeping the previous ones",
+			zap.String("file", s.path), zap.Error(err))
+		return
+	}
+	s.logger.Info("Reloaded sampling strategies", zap.String("file", s.path))
+}
+
+func (s *Store) load(modTime time.Time) error {
+	data, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		return err
+	}
+	if data, err = expandEnv(data, os.LookupEnv); err != nil {
+		return fmt.Errorf("invalid sampling strategies file %s: %v", s.path, err)
+	}
+	strategies, err := parseStrategies(data)
+	if err != nil {
+		return fmt.Errorf("invalid sampling strategies file %s: %v", s.path, err)
+	}
+
+	defaultStrategy := defaultProbabilisticStrategy()
+	if strategies.DefaultStrategy != nil {
+		defaultStrategy = toThrift(strategies.DefaultStrategy.Type, strategies.DefaultStrategy.Param)
+	}
+	serviceStrategies := make(map[string]*sampling.SamplingStrategyResponse, len(strategies.ServiceStrategies))
+	for _, service := range strategies.ServiceStrategies {
+		serviceStrategies[service.Service] = serviceToThrift(service)
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	s.modTime = modTime
+	s.defaultStrategy = defaultStrategy
+	s.serviceStrategies = serviceStrategies
+	return nil
+}
+
+// expandEnv replaces the ${VAR} references in data with the values of the variables returned by lookupEnv,
+// and the ${VAR:-default} references with default if the variable is unset or empty. It returns an error
+// listing the variables referenced without a default that are not set.
+func expandEnv(data []byte, lookupEnv func(string) (string, bool)) ([]byte, error) {
+	undefined := make(map[string]struct{})
+	expanded := envVarPattern.ReplaceAllFunc(data, func(ref []byte) []byte {
+		match := envVarPattern.FindSubmatch(ref)
+		name, hasDefault := string(match[1]), match[2] != nil
+		if value, ok := lookupEnv(name); ok && (value != "" || !hasDefault) {
+			return []byte(value)
+		}
+		if hasDefault {
+			return match[3]
+		}
+		undefined[name] = struct{}{}
+		return ref
+	})
+	if len(undefined) > 0 {
+		names := make([]string, 0, len(undefined))
+		for name := range undefined {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("undefined environment variables %s", strings.Join(names, ", "))
+	}
+	return expanded, nil
+}
+
+func parseStrategies(data []byte) (*strategies, error) {
+	var s strategies
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	if err := s.validate(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func defaultProbabilisticStrategy() *sampling.SamplingStrategyResponse {
+	return toThrift(samplerTypeProbabilistic, defaultSamplingProbability)
+}
+
+func toThrift(samplerType string, param float64) *sampling.SamplingStrategyResponse {
+	if samplerType == samplerTypeRateLimiting {
+		return &sampling.SamplingStrategyResponse{
+			StrategyType: sampling.SamplingStrategyType_RATE_LIMITING,
+			RateLimitingSampling: &sampling.RateLimitingSamplingStrategy{
+				MaxTracesPerSecond: int16(param),
+			},
+		}
+	}
+	return &sampling.SamplingStrategyResponse{
+		StrategyType: sampling.SamplingStrategyType_PROBABILISTIC,
+		ProbabilisticSampling: &sampling.ProbabilisticSamplingStrategy{
+			SamplingRate: param,
+		},
+	}
+}
+
+func serviceToThrift(service *serviceStrategy) *sampling.SamplingStrategyResponse {
+	response := toThrift(service.Type, service.Param)
+	if len(service.OperationStrategies) == 0 {
+		return response
+	}
+	// operations without an override use the service probability, or the default one when the service is rate limited
+	defaultProbability := defaultSamplingProbability
+	if service.Type == samplerTypeProbabilistic {
+		defaultProbability = service.Param
+	}
+	operations := make([]*sampling.OperationSamplingStrategy, 0, len(service.OperationStrategies))
+	for _, operation := range service.OperationStrategies {
+		operations = append(operations, &sampling.OperationSamplingStrategy{
+			Operation: operation.Operation,
+			ProbabilisticSampling: &sampling.ProbabilisticSamplingStrategy{
+				SamplingRate: operation.Param,
+			},
+		})
+	}
+	response.OperationSampling = &sampling.PerOperationSamplingStrategies{
+		DefaultSamplingProbability: defaultProbability,
+		PerOperationStrategies:     operations,
+	}
+	return response
+}
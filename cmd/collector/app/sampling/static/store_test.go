@@ -0,0 +1,238 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package static
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/uber/jaeger/thrift-gen/sampling"
+)
+
+func TestStoreFromFixture(t *testing.T) {
+	store, err := NewStore("fixtures/strategies.json", time.Hour, zap.NewNop())
+	require.NoError(t, err)
+	defer store.Close()
+
+	s, err := store.GetSamplingStrategy(nil, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, sampling.SamplingStrategyType_PROBABILISTIC, s.StrategyType)
+	assert.Equal(t, 0.8, s.ProbabilisticSampling.SamplingRate)
+	require.NotNil(t, s.OperationSampling)
+	assert.Equal(t, 0.8, s.OperationSampling.DefaultSamplingProbability)
+	require.Len(t, s.OperationSampling.PerOperationStrategies, 2)
+	assert.Equal(t, "op1", s.OperationSampling.PerOperationStrategies[0].Operation)
+	assert.Equal(t, 0.2, s.OperationSampling.PerOperationStrategies[0].ProbabilisticSampling.SamplingRate)
+	assert.Equal(t, "op2", s.OperationSampling.PerOperationStrategies[1].Operation)
+	assert.Equal(t, 0.4, s.OperationSampling.PerOperationStrategies[1].ProbabilisticSampling.SamplingRate)
+
+	s, err = store.GetSamplingStrategy(nil, "bar")
+	require.NoError(t, err)
+	assert.Equal(t, sampling.SamplingStrategyType_RATE_LIMITING, s.StrategyType)
+	assert.EqualValues(t, 5, s.RateLimitingSampling.MaxTracesPerSecond)
+	assert.Nil(t, s.OperationSampling)
+
+	s, err = store.GetSamplingStrategy(nil, "unknown")
+	require.NoError(t, err)
+	assert.Equal(t, sampling.SamplingStrategyType_PROBABILISTIC, s.StrategyType)
+	assert.Equal(t, 0.5, s.ProbabilisticSampling.SamplingRate)
+}
+
+func TestStoreWithoutDefaultStrategy(t *testing.T) {
+	path, cleanup := writeTempFile(t, `{"service_strategies": []}`)
+	defer cleanup()
+	store, err := NewStore(path, time.Hour, zap.NewNop())
+	require.NoError(t, err)
+	defer store.Close()
+
+	s, err := store.GetSamplingStrategy(nil, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, defaultProbabilisticStrategy(), s)
+}
+
+func TestParseStrategiesErrors(t *testing.T) {
+	testCases := []struct {
+		json string
+		err  string
+	}{
+		{
+			json: `[]`,
+			err:  "json: cannot unmarshal array",
+		},
+		{
+			json: `{"default_strategy": {"type": "probabilistic", "param": 0.5}, "sevice_strategies": []}`,
+			err:  `unknown field "sevice_strategies"`,
+		},
+		{
+			json: `{"default_strategy": {"type": "probabilistic", "parm": 0.5}}`,
+			err:  `unknown field "parm"`,
+		},
+		{
+			json: `{"service_strategies": [{"service": "foo", "type": "probabilistic", "param": 0.5, "operations": []}]}`,
+			err:  `unknown field "operations"`,
+		},
+		{
+			json: `{"service_strategies": [{"service": "foo", "type": "probabilistic", "param": 0.5,
+				"operation_strategies": [{"operation": "op", "type": "probabilistic", "param": 0.5, "extra": 1}]}]}`,
+			err: `unknown field "extra"`,
+		},
+		{
+			json: `{"default_strategy": {"type": "const", "param": 1}}`,
+			err:  `default strategy: unknown sampling strategy type "const"`,
+		},
+		{
+			json: `{"default_strategy": {"type": "probabilistic", "param": 1.5}}`,
+			err:  "default strategy: probabilistic sampling param must be between 0 and 1, got 1.5",
+		},
+		{
+			json: `{"service_strategies": [{"service": "foo", "type": "ratelimiting", "param": -1}]}`,
+			err:  "service foo: rate limiting sampling param must be between 0 and 32767, got -1",
+		},
+		{
+			json: `{"service_strategies": [{"type": "ratelimiting", "param": 1}]}`,
+			err:  "service strategy is missing the service name",
+		},
+		{
+			json: `{"service_strategies": [{"service": "foo", "type": "ratelimiting", "param": 1},
+				{"service": "foo", "type": "ratelimiting", "param": 2}]}`,
+			err: "service foo has more than one strategy",
+		},
+		{
+			json: `{"service_strategies": [{"service": "foo", "type": "probabilistic", "param": 0.5,
+				"operation_strategies": [{"operation": "op", "type": "ratelimiting", "param": 5}]}]}`,
+			err: "service foo operation op: only probabilistic operation strategies are supported",
+		},
+		{
+			json: `{"service_strategies": [{"service": "foo", "type": "probabilistic", "param": 0.5,
+				"operation_strategies": [{"operation": "op", "type": "probabilistic", "param": 2}]}]}`,
+			err: "service foo operation op: probabilistic sampling param must be between 0 and 1, got 2",
+		},
+	}
+	for _, tc := range testCases {
+		_, err := parseStrategies([]byte(tc.json))
+		require.Error(t, err, tc.json)
+		assert.Contains(t, err.Error(), tc.err, tc.json)
+	}
+}
+
+func TestNewStoreErrors(t *testing.T) {
+	_, err := NewStore("fixtures/missing.json", time.Hour, zap.NewNop())
+	assert.Error(t, err)
+
+	path, cleanup := writeTempFile(t, `{"default_strategy": {"type": "const"}}`)
+	defer cleanup()
+	_, err = NewStore(path, time.Hour, zap.NewNop())
+	assert.EqualError(t, err, "invalid sampling strategies file "+path+`: default strategy: unknown sampling strategy type "const"`)
+}
+
+func TestExpandEnv(t *testing.T) {
+	env := map[string]string{"SAMPLING_QPS": "5", "EMPTY": ""}
+	lookupEnv := func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+	testCases := []struct {
+		data     string
+		expected string
+	}{
+		{data: `{"param": ${SAMPLING_QPS}}`, expected: `{"param": 5}`},
+		{data: `{"param": ${SAMPLING_QPS:-1}}`, expected: `{"param": 5}`},
+		{data: `{"param": ${MISSING:-0.25}}`, expected: `{"param": 0.25}`},
+		{data: `{"param": ${EMPTY:-0.25}}`, expected: `{"param": 0.25}`},
+		{data: `{"service": "a${EMPTY}b"}`, expected: `{"service": "ab"}`},
+		{data: `{"service": "${MISSING:-}"}`, expected: `{"service": ""}`},
+		{data: `{"service": "$SAMPLING_QPS $ {x}"}`, expected: `{"service": "$SAMPLING_QPS $ {x}"}`},
+	}
+	for _, tc := range testCases {
+		expanded, err := expandEnv([]byte(tc.data), lookupEnv)
+		require.NoError(t, err, tc.data)
+		assert.Equal(t, tc.expected, string(expanded), tc.data)
+	}
+
+	_, err := expandEnv([]byte(`{"param": ${SAMPLING_QPS}, "other": ${MISSING}, "again": ${MISSING}, "last": ${ALSO_MISSING}}`), lookupEnv)
+	assert.EqualError(t, err, "undefined environment variables ALSO_MISSING, MISSING")
+}
+
+func TestStoreExpandsEnv(t *testing.T) {
+	require.NoError(t, os.Setenv("JAEGER_TEST_SAMPLING_QPS", "7"))
+	defer os.Unsetenv("JAEGER_TEST_SAMPLING_QPS")
+	path, cleanup := writeTempFile(t, `{"default_strategy": {"type": "ratelimiting", "param": ${JAEGER_TEST_SAMPLING_QPS}},
+		"service_strategies": [{"service": "foo", "type": "probabilistic", "param": ${JAEGER_TEST_FOO_RATE:-0.25}}]}`)
+	defer cleanup()
+	store, err := NewStore(path, time.Hour, zap.NewNop())
+	require.NoError(t, err)
+	defer store.Close()
+
+	s, err := store.GetSamplingStrategy(nil, "bar")
+	require.NoError(t, err)
+	assert.EqualValues(t, 7, s.RateLimitingSampling.MaxTracesPerSecond)
+	s, err = store.GetSamplingStrategy(nil, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, 0.25, s.ProbabilisticSampling.SamplingRate)
+}
+
+func TestNewStoreUndefinedEnv(t *testing.T) {
+	path, cleanup := writeTempFile(t, `{"default_strategy": {"type": "ratelimiting", "param": ${JAEGER_TEST_UNDEFINED}}}`)
+	defer cleanup()
+	_, err := NewStore(path, time.Hour, zap.NewNop())
+	assert.EqualError(t, err, "invalid sampling strategies file "+path+": undefined environment variables JAEGER_TEST_UNDEFINED")
+}
+
+func TestStoreReload(t *testing.T) {
+	path, cleanup := writeTempFile(t, `{"default_strategy": {"type": "probabilistic", "param": 0.5}}`)
+	defer cleanup()
+	store, err := NewStore(path, time.Millisecond, zap.NewNop())
+	require.NoError(t, err)
+	defer store.Close()
+
+	// an invalid file is ignored
+	updateFile(t, path, `{"default_strategy": {"type": "probabilistic", "param": 5}}`, time.Now().Add(time.Minute))
+	time.Sleep(20 * time.Millisecond)
+	s, err := store.GetSamplingStrategy(nil, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, 0.5, s.ProbabilisticSampling.SamplingRate)
+
+	updateFile(t, path, `{"default_strategy": {"type": "ratelimiting", "param": 3}}`, time.Now().Add(2*time.Minute))
+	for i := 0; i < 1000; i++ {
+		if s, _ = store.GetSamplingStrategy(nil, "foo"); s.StrategyType == sampling.SamplingStrategyType_RATE_LIMITING {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, sampling.SamplingStrategyType_RATE_LIMITING, s.StrategyType)
+	assert.EqualValues(t, 3, s.RateLimitingSampling.MaxTracesPerSecond)
+}
+
+func writeTempFile(t *testing.T, content string) (string, func()) {
+	dir, err := ioutil.TempDir("", "strategies")
+	require.NoError(t, err)
+	path := filepath.Join(dir, "strategies.json")
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+	return path, func() { os.RemoveAll(dir) }
+}
+
+// updateFile writes content and sets an explicit modification time, since writes within
+// the file system's timestamp resolution would otherwise be indistinguishable.
+func updateFile(t *testing.T, path, content string, modTime time.Time) {
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
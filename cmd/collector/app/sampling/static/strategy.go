@@ -0,0 +1,144 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package static
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// strategy defines a sampling strategy. Param is the probability for a probabilistic
+// strategy and the number of traces per second for a rate limiting strategy.
+type strategy struct {
+	Type  string  `json:"type"`
+	Param float64 `json:"param"`
+}
+
+// operationStrategy defines the sampling strategy for an operation.
+type operationStrategy struct {
+	Operation string  `json:"operation"`
+	Type      string  `json:"type"`
+	Param     float64 `json:"param"`
+}
+
+// serviceStrategy defines the sampling strategy for a service and its operations.
+type serviceStrategy struct {
+	Service             string               `json:"service"`
+	Type                string               `json:"type"`
+	Param               float64              `json:"param"`
+	OperationStrategies []*operationStrategy `json:"operation_strategies"`
+}
+
+// strategies holds the contents of the strategies file.
+type strategies struct {
+	DefaultStrategy   *strategy          `json:"default_strategy"`
+	ServiceStrategies []*serviceStrategy `json:"service_strategies"`
+}
+
+// UnmarshalJSON rejects unknown fields so that typos in the file are reported.
+func (s *strategy) UnmarshalJSON(data []byte) error {
+	type plain strategy
+	return unmarshalStrict(data, (*plain)(s), "type", "param")
+}
+
+// UnmarshalJSON rejects unknown fields so that typos in the file are reported.
+func (s *operationStrategy) UnmarshalJSON(data []byte) error {
+	type plain operationStrategy
+	return unmarshalStrict(data, (*plain)(s), "operation", "type", "param")
+}
+
+// UnmarshalJSON rejects unknown fields so that typos in the file are reported.
+func (s *serviceStrategy) UnmarshalJSON(data []byte) error {
+	type plain serviceStrategy
+	return unmarshalStrict(data, (*plain)(s), "service", "type", "param", "operation_strategies")
+}
+
+// UnmarshalJSON rejects unknown fields so that typos in the file are reported.
+func (s *strategies) UnmarshalJSON(data []byte) error {
+	type plain strategies
+	return unmarshalStrict(data, (*plain)(s), "default_strategy", "service_strategies")
+}
+
+func (s *strategies) validate() error {
+	if s.DefaultStrategy != nil {
+		if err := validateStrategy(s.DefaultStrategy.Type, s.DefaultStrategy.Param); err != nil {
+			return fmt.Errorf("default strategy: %v", err)
+		}
+	}
+	seen := make(map[string]struct{}, len(s.ServiceStrategies))
+	for _, service := range s.ServiceStrategies {
+		if service.Service == "" {
+			return fmt.Errorf("service strategy is missing the service name")
+		}
+		if _, ok := seen[service.Service]; ok {
+			return fmt.Errorf("service %s has more than one strategy", service.Service)
+		}
+		seen[service.Service] = struct{}{}
+		if err := validateStrategy(service.Type, service.Param); err != nil {
+			return fmt.Errorf("service %s: %v", service.Service, err)
+		}
+		for _, operation := range service.OperationStrategies {
+			// the sampling API only supports probabilistic sampling per operation
+			if operation.Type != samplerTypeProbabilistic {
+				return fmt.Errorf("service %s operation %s: only probabilistic operation strategies are supported",
+					service.Service, operation.Operation)
+			}
+			if err := validateStrategy(operation.Type, operation.Param); err != nil {
+				return fmt.Errorf("service %s operation %s: %v", service.Service, operation.Operation, err)
+			}
+		}
+	}
+	return nil
+}
+
+func validateStrategy(samplerType string, param float64) error {
+	switch samplerType {
+	case samplerTypeProbabilistic:
+		if param < 0 || param > 1 {
+			return fmt.Errorf("probabilistic sampling param must be between 0 and 1, got %v", param)
+		}
+	case samplerTypeRateLimiting:
+		if param < 0 || param > math.MaxInt16 {
+			return fmt.Errorf("rate limiting sampling param must be between 0 and %d, got %v", math.MaxInt16, param)
+		}
+	default:
+		return fmt.Errorf("unknown sampling strategy type %q", samplerType)
+	}
+	return nil
+}
+
+// unmarshalStrict unmarshals data into v, returning an error if data has keys other than fields.
+func unmarshalStrict(data []byte, v interface{}, fields ...string) error {
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(data, &object); err != nil {
+		return err
+	}
+	for key := range object {
+		if !contains(fields, key) {
+			return fmt.Errorf("unknown field %q", key)
+		}
+	}
+	return json.Unmarshal(data, v)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
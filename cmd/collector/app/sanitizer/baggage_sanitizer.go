@@ -0,0 +1,72 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sanitizer
+
+import (
+	"github.com/uber/jaeger/model"
+)
+
+const (
+	// Jaeger clients record every baggage item set on a span as a log with these fields,
+	// e.g. {"event": "baggage", "key": "user.id", "value": "42"}
+	baggageEventValue = "baggage"
+	baggageKeyField   = "key"
+	baggageValueField = "value"
+	eventField        = "event"
+)
+
+// NewBaggageSanitizer creates a sanitizer that turns the baggage items logged on a span into tags,
+// so that they can be searched. Only the baggage keys listed in keys are promoted, and a baggage
+// item does not replace a tag the span already has.
+func NewBaggageSanitizer(keys []string) SanitizeSpan {
+	allowed := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		allowed[key] = struct{}{}
+	}
+	return func(span *model.Span) *model.Span {
+		for _, log := range span.Logs {
+			key, value, ok := baggageItem(log)
+			if !ok {
+				continue
+			}
+			if _, ok := allowed[key]; !ok {
+				continue
+			}
+			if _, ok := span.Tags.FindByKey(key); ok {
+				continue
+			}
+			span.Tags = append(span.Tags, model.String(key, value))
+		}
+		return span
+	}
+}
+
+// baggageItem returns the key and value of the baggage item recorded by log, if it is a baggage log
+func baggageItem(log model.Log) (key string, value string, ok bool) {
+	fields := model.KeyValues(log.Fields)
+	event, ok := fields.FindByKey(eventField)
+	if !ok || event.VType != model.StringType || event.VStr != baggageEventValue {
+		return "", "", false
+	}
+	keyField, ok := fields.FindByKey(baggageKeyField)
+	if !ok || keyField.VType != model.StringType {
+		return "", "", false
+	}
+	valueField, ok := fields.FindByKey(baggageValueField)
+	if !ok {
+		return "", "", false
+	}
+	return keyField.VStr, valueField.AsString(), true
+}
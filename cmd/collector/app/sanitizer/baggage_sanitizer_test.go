@@ -0,0 +1,74 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sanitizer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uber/jaeger/model"
+)
+
+func baggageLog(key, value string) model.Log {
+	return model.Log{Fields: model.KeyValues{
+		model.String("event", "baggage"),
+		model.String("key", key),
+		model.String("value", value),
+	}}
+}
+
+func TestBaggageSanitizer(t *testing.T) {
+	sanitize := NewBaggageSanitizer([]string{"tenant", "user.id"})
+
+	span := sanitize(&model.Span{
+		Tags: model.KeyValues{model.String("http.method", "GET")},
+		Logs: []model.Log{
+			baggageLog("tenant", "acme"),
+			baggageLog("session.id", "d0c5"),
+			{Fields: model.KeyValues{model.String("event", "retry"), model.String("key", "user.id")}},
+			baggageLog("user.id", "42"),
+		},
+	})
+	assert.Equal(t, model.KeyValues{
+		model.String("http.method", "GET"),
+		model.String("tenant", "acme"),
+		model.String("user.id", "42"),
+	}, span.Tags)
+	assert.Len(t, span.Logs, 4, "the baggage logs are kept")
+}
+
+func TestBaggageSanitizerKeepsExistingTags(t *testing.T) {
+	sanitize := NewBaggageSanitizer([]string{"tenant"})
+
+	span := sanitize(&model.Span{
+		Tags: model.KeyValues{model.String("tenant", "initech")},
+		Logs: []model.Log{baggageLog("tenant", "acme"), baggageLog("tenant", "globex")},
+	})
+	assert.Equal(t, model.KeyValues{model.String("tenant", "initech")}, span.Tags)
+
+	span = sanitize(&model.Span{Logs: []model.Log{baggageLog("tenant", "acme"), baggageLog("tenant", "globex")}})
+	assert.Equal(t, model.KeyValues{model.String("tenant", "acme")}, span.Tags)
+}
+
+func TestBaggageSanitizerIgnoresMalformedLogs(t *testing.T) {
+	sanitize := NewBaggageSanitizer([]string{"tenant"})
+
+	span := sanitize(&model.Span{Logs: []model.Log{
+		{Fields: model.KeyValues{model.String("event", "baggage"), model.String("key", "tenant")}},
+		{Fields: model.KeyValues{model.String("event", "baggage"), model.Int64("key", 1), model.String("value", "acme")}},
+		{Fields: model.KeyValues{model.Bool("event", true), model.String("key", "tenant"), model.String("value", "acme")}},
+	}})
+	assert.Empty(t, span.Tags)
+}
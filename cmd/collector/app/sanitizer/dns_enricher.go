@@ -0,0 +1,151 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sanitizer
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/pkg/cache"
+)
+
+const (
+	// PeerHostnameTagKey is the key of the tag that DNSEnricher records the hostname of the peer in
+	PeerHostnameTagKey = "peer.hostname"
+	peerIPv4TagKey     = "peer.ipv4"
+
+	// DefaultDNSCacheSize is the default number of IP addresses whose hostnames DNSEnricher remembers
+	DefaultDNSCacheSize = 10000
+	// DefaultDNSTimeout is the default time DNSEnricher waits for a reverse lookup
+	DefaultDNSTimeout = time.Second
+
+	// dnsCacheTTL is how long hostnames, and failures to look them up, are remembered
+	dnsCacheTTL = 10 * time.Minute
+	// maxPendingLookups bounds the number of reverse lookups in flight at once
+	maxPendingLookups = 16
+)
+
+// Resolver looks up the hostnames of IP addresses, it is implemented by net.Resolver
+type Resolver interface {
+	LookupAddr(ctx context.Context, addr string) ([]string, error)
+}
+
+// DNSEnricher tags spans that have a peer.ipv4 tag but no peer.hostname tag with the hostname of the
+// peer, found by reverse DNS. Lookups never hold up spans: a span whose peer was not looked up yet
+// is passed on untagged while the lookup runs in the background, and later spans are tagged from a cache.
+type DNSEnricher struct {
+	resolver  Resolver
+	timeout   time.Duration
+	hostnames cache.Cache
+	// pendingLookups limits how many lookups run at once, when it is full IP addresses are not looked up
+	pendingLookups chan struct{}
+
+	lock    sync.Mutex
+	pending map[string]struct{}
+}
+
+// NewDNSEnricher creates a DNSEnricher that remembers the hostnames of at most cacheSize IP addresses,
+// and gives up on reverse lookups that take longer than timeout.
+func NewDNSEnricher(resolver Resolver, cacheSize int, timeout time.Duration) *DNSEnricher {
+	return &DNSEnricher{
+		resolver:       resolver,
+		timeout:        timeout,
+		hostnames:      cache.NewLRUWithOptions(cacheSize, &cache.Options{TTL: dnsCacheTTL}),
+		pendingLookups: make(chan struct{}, maxPendingLookups),
+		pending:        make(map[string]struct{}),
+	}
+}
+
+// Sanitize adds the peer.hostname tag to the span if the hostname of its peer is known
+func (e *DNSEnricher) Sanitize(span *model.Span) *model.Span {
+	ip, ok := peerIP(span.Tags)
+	if !ok {
+		return span
+	}
+	if _, ok := span.Tags.FindByKey(PeerHostnameTagKey); ok {
+		return span
+	}
+	if hostname, ok := e.hostnames.Get(ip).(string); ok {
+		// an empty hostname is a lookup that failed
+		if hostname != "" {
+			span.Tags = append(span.Tags, model.String(PeerHostnameTagKey, hostname))
+		}
+		return span
+	}
+	e.lookup(ip)
+	return span
+}
+
+// lookup resolves the hostname of ip in the background, unless it is already being resolved or there
+// are too many lookups in flight
+func (e *DNSEnricher) lookup(ip string) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if _, ok := e.pending[ip]; ok {
+		return
+	}
+	select {
+	case e.pendingLookups <- struct{}{}:
+	default:
+		return
+	}
+	e.pending[ip] = struct{}{}
+	go func() {
+		hostname := e.resolve(ip)
+		e.hostnames.Put(ip, hostname)
+		e.lock.Lock()
+		delete(e.pending, ip)
+		e.lock.Unlock()
+		<-e.pendingLookups
+	}()
+}
+
+// resolve returns the hostname of ip, or an empty string if it cannot be found
+func (e *DNSEnricher) resolve(ip string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
+	defer cancel()
+	names, err := e.resolver.LookupAddr(ctx, ip)
+	if err != nil || len(names) == 0 {
+		return ""
+	}
+	return strings.TrimSuffix(names[0], ".")
+}
+
+// peerIP returns the peer.ipv4 tag as an IP address, clients record it either as a string or as a
+// number with the address packed into its lower 32 bits
+func peerIP(tags model.KeyValues) (string, bool) {
+	tag, ok := tags.FindByKey(peerIPv4TagKey)
+	if !ok {
+		return "", false
+	}
+	switch tag.VType {
+	case model.StringType:
+		ip := net.ParseIP(tag.VStr)
+		if ip == nil {
+			return "", false
+		}
+		return ip.String(), true
+	case model.Int64Type:
+		ip := make(net.IP, net.IPv4len)
+		binary.BigEndian.PutUint32(ip, uint32(tag.VNum))
+		return ip.String(), true
+	}
+	return "", false
+}
@@ -0,0 +1,161 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sanitizer
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/uber/jaeger/model"
+)
+
+type stubResolver struct {
+	sync.Mutex
+	hostnames map[string][]string
+	calls     map[string]int
+	// block makes lookups hang until they time out or release is closed
+	block   bool
+	release chan struct{}
+}
+
+func newStubResolver(hostnames map[string][]string) *stubResolver {
+	return &stubResolver{hostnames: hostnames, calls: make(map[string]int)}
+}
+
+func (r *stubResolver) LookupAddr(ctx context.Context, addr string) ([]string, error) {
+	r.Lock()
+	r.calls[addr]++
+	names, ok := r.hostnames[addr]
+	block := r.block
+	r.Unlock()
+	if block {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-r.release:
+			return nil, errors.New("released")
+		}
+	}
+	if !ok {
+		return nil, errors.New("no such host")
+	}
+	return names, nil
+}
+
+func (r *stubResolver) getCalls(addr string) int {
+	r.Lock()
+	defer r.Unlock()
+	return r.calls[addr]
+}
+
+func waitForLookup(e *DNSEnricher, ip string) {
+	for i := 0; i < 1000 && e.hostnames.Get(ip) == nil; i++ {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func peerSpan(ip model.KeyValue) *model.Span {
+	return &model.Span{Tags: model.KeyValues{ip}}
+}
+
+func TestDNSEnricher(t *testing.T) {
+	resolver := newStubResolver(map[string][]string{"10.0.0.1": {"host-1.example.com.", "alias.example.com."}})
+	e := NewDNSEnricher(resolver, DefaultDNSCacheSize, DefaultDNSTimeout)
+
+	// the first span is not held up by the lookup
+	span := e.Sanitize(peerSpan(model.String("peer.ipv4", "10.0.0.1")))
+	assert.Len(t, span.Tags, 1)
+	waitForLookup(e, "10.0.0.1")
+
+	span = e.Sanitize(peerSpan(model.String("peer.ipv4", "10.0.0.1")))
+	assert.Equal(t, model.KeyValues{
+		model.String("peer.ipv4", "10.0.0.1"),
+		model.String(PeerHostnameTagKey, "host-1.example.com"),
+	}, span.Tags)
+	// 167772161 is 10.0.0.1 packed into a number
+	span = e.Sanitize(peerSpan(model.Int64("peer.ipv4", 167772161)))
+	assert.Equal(t, model.KeyValues{
+		model.Int64("peer.ipv4", 167772161),
+		model.String(PeerHostnameTagKey, "host-1.example.com"),
+	}, span.Tags)
+	assert.Equal(t, 1, resolver.getCalls("10.0.0.1"))
+}
+
+func TestDNSEnricherSkipsSpans(t *testing.T) {
+	resolver := newStubResolver(map[string][]string{"10.0.0.1": {"host-1"}})
+	e := NewDNSEnricher(resolver, DefaultDNSCacheSize, DefaultDNSTimeout)
+
+	spans := []*model.Span{
+		{},
+		peerSpan(model.String("peer.ipv4", "not an ip")),
+		peerSpan(model.Bool("peer.ipv4", true)),
+		{Tags: model.KeyValues{model.String("peer.ipv4", "10.0.0.1"), model.String(PeerHostnameTagKey, "known")}},
+	}
+	for _, span := range spans {
+		numTags := len(span.Tags)
+		assert.Len(t, e.Sanitize(span).Tags, numTags)
+	}
+	assert.Equal(t, 0, resolver.getCalls("10.0.0.1"))
+}
+
+func TestDNSEnricherLookupFailure(t *testing.T) {
+	resolver := newStubResolver(nil)
+	e := NewDNSEnricher(resolver, DefaultDNSCacheSize, DefaultDNSTimeout)
+
+	e.Sanitize(peerSpan(model.String("peer.ipv4", "10.0.0.2")))
+	waitForLookup(e, "10.0.0.2")
+	// failures are remembered too, so that the resolver is not asked again for every span
+	span := e.Sanitize(peerSpan(model.String("peer.ipv4", "10.0.0.2")))
+	assert.Len(t, span.Tags, 1)
+	assert.Equal(t, 1, resolver.getCalls("10.0.0.2"))
+}
+
+func TestDNSEnricherTimeout(t *testing.T) {
+	resolver := newStubResolver(nil)
+	resolver.block = true
+	e := NewDNSEnricher(resolver, DefaultDNSCacheSize, time.Millisecond)
+
+	start := time.Now()
+	span := e.Sanitize(peerSpan(model.String("peer.ipv4", "10.0.0.3")))
+	assert.Len(t, span.Tags, 1)
+	assert.True(t, time.Since(start) < time.Second, "a hanging lookup does not block spans")
+	waitForLookup(e, "10.0.0.3")
+	assert.Equal(t, "", e.hostnames.Get("10.0.0.3"))
+}
+
+func TestDNSEnricherBoundsPendingLookups(t *testing.T) {
+	resolver := newStubResolver(nil)
+	resolver.block = true
+	resolver.release = make(chan struct{})
+	defer close(resolver.release)
+	e := NewDNSEnricher(resolver, DefaultDNSCacheSize, time.Hour)
+
+	ip := make(net.IP, net.IPv4len)
+	for i := 0; i < 2*maxPendingLookups; i++ {
+		ip[3] = byte(i)
+		// the same address is only looked up once while the lookup is pending
+		e.Sanitize(peerSpan(model.String("peer.ipv4", ip.String())))
+		e.Sanitize(peerSpan(model.String("peer.ipv4", ip.String())))
+	}
+	e.lock.Lock()
+	assert.Len(t, e.pending, maxPendingLookups)
+	e.lock.Unlock()
+}
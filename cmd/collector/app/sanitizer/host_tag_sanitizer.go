@@ -0,0 +1,31 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sanitizer
+
+import (
+	"github.com/uber/jaeger/model"
+)
+
+// CollectorHostTagKey is the key of the tag that records which collector instance ingested a span
+const CollectorHostTagKey = "jaeger.collector.host"
+
+// NewHostTagSanitizer creates a sanitizer that tags every span with the hostname of the collector.
+func NewHostTagSanitizer(hostname string) SanitizeSpan {
+	tag := model.String(CollectorHostTagKey, hostname)
+	return func(span *model.Span) *model.Span {
+		span.Tags = append(span.Tags, tag)
+		return span
+	}
+}
@@ -0,0 +1,35 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sanitizer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uber/jaeger/model"
+)
+
+func TestHostTagSanitizer(t *testing.T) {
+	sanitize := NewHostTagSanitizer("collector-1")
+
+	span := sanitize(&model.Span{Tags: model.KeyValues{model.String("user.id", "42")}})
+	assert.Equal(t, model.KeyValues{
+		model.String("user.id", "42"),
+		model.String(CollectorHostTagKey, "collector-1"),
+	}, span.Tags)
+
+	span = sanitize(&model.Span{})
+	assert.Equal(t, model.KeyValues{model.String(CollectorHostTagKey, "collector-1")}, span.Tags)
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sanitizer
+
+import (
+	"unicode/utf8"
+
+	"github.com/uber/jaeger/model"
+)
+
+const (
+	// LogsTruncatedTagKey is the key of the tag marking the spans whose logs were truncated
+	LogsTruncatedTagKey = "jaeger.logs-truncated"
+
+	// numericFieldBytes is the size counted for the fields of type bool, int64 and float64
+	numericFieldBytes = 8
+)
+
+// NewLogTruncationSanitizer creates a sanitizer that caps the total size of the keys and values of the log fields
+// of a span at maxBytes. The fields are kept in order until the limit is reached: the string or binary value of
+// the field that crosses it is cut to fit, and the fields and logs after it are dropped. Truncated spans are
+// tagged with LogsTruncatedTagKey.
+func NewLogTruncationSanitizer(maxBytes int) SanitizeSpan {
+	return func(span *model.Span) *model.Span {
+		budget := maxBytes
+		for i, log := range span.Logs {
+			for j, field := range log.Fields {
+				size := len(field.Key) + fieldValueBytes(field)
+				if size <= budget {
+					budget -= size
+					continue
+				}
+				fields := log.Fields[:j]
+				if cut, ok := truncateField(field, budget-len(field.Key)); ok {
+					fields = append(fields, cut)
+				}
+				logs := span.Logs[:i]
+				if len(fields) > 0 {
+					logs = append(logs, model.Log{Timestamp: log.Timestamp, Fields: fields})
+				}
+				span.Logs = logs
+				span.Tags = append(span.Tags, model.Bool(LogsTruncatedTagKey, true))
+				return span
+			}
+		}
+		return span
+	}
+}
+
+func fieldValueBytes(field model.KeyValue) int {
+	switch field.VType {
+	case model.StringType:
+		return len(field.VStr)
+	case model.BinaryType:
+		return len(field.VBlob)
+	default:
+		return numericFieldBytes
+	}
+}
+
+// truncateField cuts the string or binary value of field to n bytes, it returns false if nothing of the value fits
+func truncateField(field model.KeyValue, n int) (model.KeyValue, bool) {
+	if n <= 0 {
+		return field, false
+	}
+	switch field.VType {
+	case model.StringType:
+		// do not cut a multi-byte character in half
+		for n > 0 && !utf8.RuneStart(field.VStr[n]) {
+			n--
+		}
+		if n == 0 {
+			return field, false
+		}
+		return model.String(field.Key, field.VStr[:n]), true
+	case model.BinaryType:
+		return model.Binary(field.Key, field.VBlob[:n]), true
+	default:
+		return field, false
+	}
+}
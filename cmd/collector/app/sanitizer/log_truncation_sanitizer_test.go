@@ -0,0 +1,83 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sanitizer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uber/jaeger/model"
+)
+
+func TestLogTruncationSanitizer(t *testing.T) {
+	sanitize := NewLogTruncationSanitizer(60)
+	ts := time.Unix(1500000000, 0)
+
+	span := sanitize(&model.Span{
+		Tags: model.KeyValues{model.String("user.id", "42")},
+		Logs: []model.Log{
+			{Timestamp: ts, Fields: model.KeyValues{model.String("event", "cache miss"), model.Int64("retries", 2)}},
+			{Timestamp: ts, Fields: model.KeyValues{
+				model.String("event", "response"),
+				model.String("body", strings.Repeat("x", 1000)),
+				model.String("status", "ok"),
+			}},
+			{Timestamp: ts, Fields: model.KeyValues{model.String("event", "done")}},
+		},
+	})
+	assert.Equal(t, []model.Log{
+		{Timestamp: ts, Fields: model.KeyValues{model.String("event", "cache miss"), model.Int64("retries", 2)}},
+		{Timestamp: ts, Fields: model.KeyValues{
+			model.String("event", "response"),
+			model.String("body", strings.Repeat("x", 13)),
+		}},
+	}, span.Logs)
+	assert.Equal(t, model.KeyValues{
+		model.String("user.id", "42"),
+		model.Bool(LogsTruncatedTagKey, true),
+	}, span.Tags)
+}
+
+func TestLogTruncationSanitizerFieldLength(t *testing.T) {
+	sanitize := NewLogTruncationSanitizer(10)
+
+	span := sanitize(&model.Span{Logs: []model.Log{
+		{Fields: model.KeyValues{model.Binary("payload", []byte("0123456789"))}},
+	}})
+	assert.Equal(t, []model.Log{{Fields: model.KeyValues{model.Binary("payload", []byte("012"))}}}, span.Logs)
+
+	span = sanitize(&model.Span{Logs: []model.Log{
+		{Fields: model.KeyValues{model.String("msg", "héééé")}},
+	}})
+	assert.Equal(t, []model.Log{{Fields: model.KeyValues{model.String("msg", "hééé")}}}, span.Logs, "characters are not cut in half")
+
+	span = sanitize(&model.Span{Logs: []model.Log{
+		{Fields: model.KeyValues{model.String("a-very-long-key", "value")}},
+		{Fields: model.KeyValues{model.String("event", "done")}},
+	}})
+	assert.Empty(t, span.Logs, "logs left without fields are dropped")
+	assert.Equal(t, model.KeyValues{model.Bool(LogsTruncatedTagKey, true)}, span.Tags)
+}
+
+func TestLogTruncationSanitizerUnderLimit(t *testing.T) {
+	sanitize := NewLogTruncationSanitizer(1024)
+	logs := []model.Log{{Fields: model.KeyValues{model.String("event", "cache miss"), model.Float64("ratio", 0.5)}}}
+
+	span := sanitize(&model.Span{Logs: logs})
+	assert.Equal(t, logs, span.Logs)
+	assert.Empty(t, span.Tags)
+}
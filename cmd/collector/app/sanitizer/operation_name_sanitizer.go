@@ -0,0 +1,42 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sanitizer
+
+import (
+	"github.com/uber/jaeger/model"
+)
+
+// OriginalOperationNameTagKey is the tag that keeps the operation name of a span whose operation name
+// was replaced by the value of the operation name tag
+const OriginalOperationNameTagKey = "jaeger.original-operation-name"
+
+// NewOperationNameSanitizer creates a sanitizer that replaces the operation name of the spans with the
+// value of their tagKey tag, so that the spans are grouped by it. The original operation name is kept in
+// the OriginalOperationNameTagKey tag. The spans without the tag, or with an empty one, are left as is.
+func NewOperationNameSanitizer(tagKey string) SanitizeSpan {
+	return func(span *model.Span) *model.Span {
+		tag, ok := span.Tags.FindByKey(tagKey)
+		if !ok {
+			return span
+		}
+		operationName := tag.AsString()
+		if operationName == "" || operationName == span.OperationName {
+			return span
+		}
+		span.Tags = append(span.Tags, model.String(OriginalOperationNameTagKey, span.OperationName))
+		span.OperationName = operationName
+		return span
+	}
+}
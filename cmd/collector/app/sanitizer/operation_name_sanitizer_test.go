@@ -0,0 +1,58 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sanitizer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uber/jaeger/model"
+)
+
+func TestOperationNameSanitizer(t *testing.T) {
+	sanitize := NewOperationNameSanitizer("rpc.method")
+
+	span := sanitize(&model.Span{
+		OperationName: "POST",
+		Tags:          model.KeyValues{model.String("rpc.method", "GetDriver")},
+	})
+	assert.Equal(t, "GetDriver", span.OperationName)
+	assert.Equal(t, model.KeyValues{
+		model.String("rpc.method", "GetDriver"),
+		model.String(OriginalOperationNameTagKey, "POST"),
+	}, span.Tags)
+
+	span = sanitize(&model.Span{
+		OperationName: "POST",
+		Tags:          model.KeyValues{model.Int64("rpc.method", 7)},
+	})
+	assert.Equal(t, "7", span.OperationName, "the tags that are not strings are formatted")
+}
+
+func TestOperationNameSanitizerWithoutTag(t *testing.T) {
+	sanitize := NewOperationNameSanitizer("rpc.method")
+
+	testCases := []model.KeyValues{
+		nil,
+		{model.String("http.method", "GET")},
+		{model.String("rpc.method", "")},
+		{model.String("rpc.method", "POST")},
+	}
+	for _, tags := range testCases {
+		span := sanitize(&model.Span{OperationName: "POST", Tags: tags})
+		assert.Equal(t, "POST", span.OperationName)
+		assert.Equal(t, tags, span.Tags, "the original operation name is not recorded")
+	}
+}
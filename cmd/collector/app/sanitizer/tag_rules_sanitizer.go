@@ -0,0 +1,149 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sanitizer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/uber/jaeger/model"
+)
+
+const (
+	// TagActionDrop removes the tag from the span
+	TagActionDrop = "drop"
+	// TagActionTruncate shortens the string value of the tag to Length characters
+	TagActionTruncate = "truncate"
+	// TagActionRename changes the key of the tag to NewKey
+	TagActionRename = "rename"
+)
+
+// TagRule describes what to do with the span tags whose key matches Key. A Key ending with `*`
+// matches all keys with that prefix.
+type TagRule struct {
+	Key    string `json:"key"`
+	Action string `json:"action"`
+	Length int    `json:"length,omitempty"`
+	NewKey string `json:"new_key,omitempty"`
+}
+
+type tagRulesFile struct {
+	Rules []TagRule `json:"rules"`
+}
+
+// LoadTagRules reads the tag rules from a JSON file of the form {"rules": [...]}
+func LoadTagRules(path string) ([]TagRule, error) {
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file tagRulesFile
+	if err := json.Unmarshal(bytes, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse tag rules file %s: %v", path, err)
+	}
+	for i, rule := range file.Rules {
+		if err := rule.validate(); err != nil {
+			return nil, fmt.Errorf("invalid tag rule %d in %s: %v", i, path, err)
+		}
+	}
+	return file.Rules, nil
+}
+
+func (r TagRule) validate() error {
+	if r.Key == "" || r.Key == "*" {
+		return fmt.Errorf("rule must match a key")
+	}
+	switch r.Action {
+	case TagActionDrop:
+	case TagActionTruncate:
+		if r.Length <= 0 {
+			return fmt.Errorf("truncate length must be positive, got %d", r.Length)
+		}
+	case TagActionRename:
+		if r.NewKey == "" {
+			return fmt.Errorf("rename of %s requires a new key", r.Key)
+		}
+	default:
+		return fmt.Errorf("unknown action %q", r.Action)
+	}
+	return nil
+}
+
+func (r TagRule) matches(key string) bool {
+	if strings.HasSuffix(r.Key, "*") {
+		return strings.HasPrefix(key, strings.TrimSuffix(r.Key, "*"))
+	}
+	return key == r.Key
+}
+
+// tagRulesSanitizer applies tag rules to the span tags
+type tagRulesSanitizer struct {
+	rules []TagRule
+}
+
+// NewTagRulesSanitizer creates a sanitizer that applies the first of the rules matching each span tag.
+// Tags that no rule matches are kept as they are.
+func NewTagRulesSanitizer(rules []TagRule) SanitizeSpan {
+	tagRulesSanitizer := tagRulesSanitizer{rules: rules}
+	return tagRulesSanitizer.Sanitize
+}
+
+// Sanitize drops, truncates, or renames the span tags.
+func (s *tagRulesSanitizer) Sanitize(span *model.Span) *model.Span {
+	tags := span.Tags[:0]
+	for _, tag := range span.Tags {
+		rule, ok := s.findRule(tag.Key)
+		if !ok {
+			tags = append(tags, tag)
+			continue
+		}
+		switch rule.Action {
+		case TagActionDrop:
+			continue
+		case TagActionTruncate:
+			if tag.VType == model.StringType {
+				tag.VStr = truncate(tag.VStr, rule.Length)
+			}
+		case TagActionRename:
+			tag.Key = rule.NewKey
+		}
+		tags = append(tags, tag)
+	}
+	span.Tags = tags
+	return span
+}
+
+func (s *tagRulesSanitizer) findRule(key string) (TagRule, bool) {
+	for _, rule := range s.rules {
+		if rule.matches(key) {
+			return rule, true
+		}
+	}
+	return TagRule{}, false
+}
+
+// truncate shortens s to at most length characters without splitting a multi-byte character
+func truncate(s string, length int) string {
+	if len(s) <= length {
+		return s
+	}
+	runes := []rune(s)
+	if len(runes) <= length {
+		return s
+	}
+	return string(runes[:length])
+}
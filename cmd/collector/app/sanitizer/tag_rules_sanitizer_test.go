@@ -0,0 +1,135 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sanitizer
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/jaeger/model"
+)
+
+func TestTagRulesSanitizer(t *testing.T) {
+	tests := []struct {
+		caption  string
+		rules    []TagRule
+		input    model.KeyValues
+		expected model.KeyValues
+	}{
+		{
+			caption:  "no matching rule",
+			rules:    []TagRule{{Key: "user.id", Action: TagActionDrop}},
+			input:    model.KeyValues{model.String("http.method", "GET")},
+			expected: model.KeyValues{model.String("http.method", "GET")},
+		},
+		{
+			caption:  "drop",
+			rules:    []TagRule{{Key: "user.id", Action: TagActionDrop}},
+			input:    model.KeyValues{model.String("user.id", "42"), model.String("http.method", "GET")},
+			expected: model.KeyValues{model.String("http.method", "GET")},
+		},
+		{
+			caption:  "truncate",
+			rules:    []TagRule{{Key: "http.url", Action: TagActionTruncate, Length: 10}},
+			input:    model.KeyValues{model.String("http.url", "http://example.com/users/42")},
+			expected: model.KeyValues{model.String("http.url", "http://exa")},
+		},
+		{
+			caption:  "truncate multi-byte characters",
+			rules:    []TagRule{{Key: "name", Action: TagActionTruncate, Length: 2}},
+			input:    model.KeyValues{model.String("name", "żółw")},
+			expected: model.KeyValues{model.String("name", "żó")},
+		},
+		{
+			caption:  "truncate ignores non-string values",
+			rules:    []TagRule{{Key: "count", Action: TagActionTruncate, Length: 1}},
+			input:    model.KeyValues{model.Int64("count", 12345)},
+			expected: model.KeyValues{model.Int64("count", 12345)},
+		},
+		{
+			caption:  "rename",
+			rules:    []TagRule{{Key: "peer.hostname", Action: TagActionRename, NewKey: "peer.host"}},
+			input:    model.KeyValues{model.String("peer.hostname", "db1")},
+			expected: model.KeyValues{model.String("peer.host", "db1")},
+		},
+		{
+			caption:  "prefix",
+			rules:    []TagRule{{Key: "user.*", Action: TagActionDrop}},
+			input:    model.KeyValues{model.String("user.id", "42"), model.String("user.name", "x"), model.String("username", "x")},
+			expected: model.KeyValues{model.String("username", "x")},
+		},
+		{
+			caption: "first matching rule wins",
+			rules: []TagRule{
+				{Key: "user.id", Action: TagActionRename, NewKey: "user"},
+				{Key: "user.*", Action: TagActionDrop},
+			},
+			input:    model.KeyValues{model.String("user.id", "42"), model.String("user.name", "x")},
+			expected: model.KeyValues{model.String("user", "42")},
+		},
+	}
+	for _, test := range tests {
+		span := NewTagRulesSanitizer(test.rules)(&model.Span{Tags: test.input})
+		assert.Equal(t, test.expected, span.Tags, test.caption)
+	}
+}
+
+func writeTagRules(t *testing.T, content string) string {
+	file, err := ioutil.TempFile("", "tag-rules")
+	require.NoError(t, err)
+	defer file.Close()
+	_, err = file.WriteString(content)
+	require.NoError(t, err)
+	return file.Name()
+}
+
+func TestLoadTagRules(t *testing.T) {
+	rules, err := LoadTagRules("fixtures/tag_rules.json")
+	require.NoError(t, err)
+	assert.Equal(t, []TagRule{
+		{Key: "user.id", Action: TagActionDrop},
+		{Key: "http.url", Action: TagActionTruncate, Length: 64},
+		{Key: "peer.hostname", Action: TagActionRename, NewKey: "peer.host"},
+	}, rules)
+}
+
+func TestLoadTagRulesErrors(t *testing.T) {
+	tests := []struct {
+		content string
+		err     string
+	}{
+		{content: `{"rules": [`, err: "failed to parse tag rules file"},
+		{content: `{"rules": [{"action": "drop"}]}`, err: "invalid tag rule 0 in .*: rule must match a key"},
+		{content: `{"rules": [{"key": "*", "action": "drop"}]}`, err: "rule must match a key"},
+		{content: `{"rules": [{"key": "a", "action": "hash"}]}`, err: `unknown action "hash"`},
+		{content: `{"rules": [{"key": "a", "action": "truncate"}]}`, err: "truncate length must be positive, got 0"},
+		{content: `{"rules": [{"key": "a", "action": "drop"}, {"key": "b", "action": "rename"}]}`, err: "invalid tag rule 1 in .*: rename of b requires a new key"},
+	}
+	for _, test := range tests {
+		path := writeTagRules(t, test.content)
+		_, err := LoadTagRules(path)
+		if assert.Error(t, err, test.content) {
+			assert.Regexp(t, test.err, err.Error(), test.content)
+		}
+		os.Remove(path)
+	}
+
+	_, err := LoadTagRules("/does/not/exist.json")
+	assert.Error(t, err)
+}
@@ -53,6 +53,16 @@ func (cs ChainedSanitizer) Sanitize(span *zc.Span) *zc.Span {
 	return span
 }
 
+// NewStandardSanitizers returns the sanitizers applied to every Zipkin span received by the collector
+func NewStandardSanitizers() []Sanitizer {
+	return []Sanitizer{
+		NewSpanDurationSanitizer(),
+		NewSpanStartTimeSanitizer(),
+		NewParentIDSanitizer(),
+		NewErrorTagSanitizer(),
+	}
+}
+
 // NewSpanDurationSanitizer returns a sanitizer that deals with nil or 0 span duration.
 func NewSpanDurationSanitizer() Sanitizer {
 	return &spanDurationSanitizer{}
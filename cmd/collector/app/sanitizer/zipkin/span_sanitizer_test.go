@@ -35,6 +35,14 @@ func TestChainedSanitizer(t *testing.T) {
 	assert.Equal(t, positiveDuration, *actual.Duration)
 }
 
+func TestStandardSanitizers(t *testing.T) {
+	sanitizer := NewChainedSanitizer(NewStandardSanitizers()...)
+
+	span := &zipkincore.Span{Duration: &negativeDuration}
+	actual := sanitizer.Sanitize(span)
+	assert.Equal(t, positiveDuration, *actual.Duration)
+}
+
 func TestSpanDurationSanitizer(t *testing.T) {
 	sanitizer := NewSpanDurationSanitizer()
 
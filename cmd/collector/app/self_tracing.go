@@ -0,0 +1,122 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"context"
+	"io"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	otlog "github.com/opentracing/opentracing-go/log"
+
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/storage/spanstore"
+)
+
+// SelfTraceTagKey is the process tag of the spans the collector reports about its own work.
+// The collector does not trace the processing of these spans, so that reporting them to itself does not loop.
+const SelfTraceTagKey = "jaeger.self-trace"
+
+// SelfTracer traces the batches the collector processes and the spans it writes to storage
+type SelfTracer struct {
+	tracer opentracing.Tracer
+}
+
+// NewSelfTracer creates a SelfTracer that starts spans with tracer, whose process must be tagged
+// with SelfTraceTagKey set to true.
+func NewSelfTracer(tracer opentracing.Tracer) *SelfTracer {
+	return &SelfTracer{tracer: tracer}
+}
+
+// SpanProcessor returns a SpanProcessor that traces each call to processor, unless all the spans are self-traces.
+func (t *SelfTracer) SpanProcessor(processor SpanProcessor) SpanProcessor {
+	return &tracedSpanProcessor{tracer: t.tracer, SpanProcessor: processor}
+}
+
+// SpanWriter returns a spanstore.Writer that traces each span written to writer, unless it is a self-trace.
+func (t *SelfTracer) SpanWriter(writer spanstore.Writer) spanstore.Writer {
+	return &tracedSpanWriter{tracer: t.tracer, writer: writer}
+}
+
+// IsSelfTrace returns true if the span was reported by the collector about its own work
+func IsSelfTrace(span *model.Span) bool {
+	if span.Process == nil {
+		return false
+	}
+	tag, ok := model.KeyValues(span.Process.Tags).FindByKey(SelfTraceTagKey)
+	return ok && (tag.Bool() || tag.VStr == "true")
+}
+
+func finishSelfTraceSpan(span opentracing.Span, err error) {
+	if err != nil {
+		ext.Error.Set(span, true)
+		span.LogFields(otlog.Error(err))
+	}
+	span.Finish()
+}
+
+type tracedSpanProcessor struct {
+	tracer opentracing.Tracer
+	SpanProcessor
+}
+
+func (p *tracedSpanProcessor) ProcessSpans(mSpans []*model.Span, spanFormat string) ([]bool, error) {
+	selfTraces := 0
+	for _, mSpan := range mSpans {
+		if IsSelfTrace(mSpan) {
+			selfTraces++
+		}
+	}
+	if len(mSpans) == 0 || selfTraces == len(mSpans) {
+		return p.SpanProcessor.ProcessSpans(mSpans, spanFormat)
+	}
+	span := p.tracer.StartSpan("ProcessSpans")
+	span.SetTag("format", spanFormat)
+	span.SetTag("spans", len(mSpans))
+	oks, err := p.SpanProcessor.ProcessSpans(mSpans, spanFormat)
+	finishSelfTraceSpan(span, err)
+	return oks, err
+}
+
+type tracedSpanWriter struct {
+	tracer opentracing.Tracer
+	writer spanstore.Writer
+}
+
+func (w *tracedSpanWriter) WriteSpan(ctx context.Context, mSpan *model.Span) error {
+	if IsSelfTrace(mSpan) {
+		return w.writer.WriteSpan(ctx, mSpan)
+	}
+	var opts []opentracing.StartSpanOption
+	if parent := opentracing.SpanFromContext(ctx); parent != nil {
+		opts = append(opts, opentracing.ChildOf(parent.Context()))
+	}
+	span := w.tracer.StartSpan("WriteSpan", opts...)
+	if mSpan.Process != nil {
+		span.SetTag("service", mSpan.Process.ServiceName)
+	}
+	err := w.writer.WriteSpan(opentracing.ContextWithSpan(ctx, span), mSpan)
+	finishSelfTraceSpan(span, err)
+	return err
+}
+
+// Close closes the underlying Writer if it supports it.
+func (w *tracedSpanWriter) Close() error {
+	if closer, ok := w.writer.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/jaeger/model"
+)
+
+type failingSpanWriter struct {
+	err error
+}
+
+func (w *failingSpanWriter) WriteSpan(ctx context.Context, span *model.Span) error {
+	return w.err
+}
+
+func selfTraceSpan() *model.Span {
+	return &model.Span{
+		SpanID: 1,
+		Process: &model.Process{
+			ServiceName: "jaeger-collector",
+			Tags:        []model.KeyValue{model.Bool(SelfTraceTagKey, true)},
+		},
+	}
+}
+
+func TestIsSelfTrace(t *testing.T) {
+	assert.True(t, IsSelfTrace(selfTraceSpan()))
+	assert.True(t, IsSelfTrace(&model.Span{Process: &model.Process{Tags: []model.KeyValue{model.String(SelfTraceTagKey, "true")}}}))
+	assert.False(t, IsSelfTrace(&model.Span{Process: &model.Process{Tags: []model.KeyValue{model.Bool(SelfTraceTagKey, false)}}}))
+	assert.False(t, IsSelfTrace(&model.Span{Process: &model.Process{ServiceName: "service"}}))
+	assert.False(t, IsSelfTrace(&model.Span{}))
+}
+
+func TestSelfTracerSpanProcessor(t *testing.T) {
+	tracer := mocktracer.New()
+	selfTracer := NewSelfTracer(tracer)
+	processor := &recordingProcessor{}
+	traced := selfTracer.SpanProcessor(processor)
+
+	_, err := traced.ProcessSpans([]*model.Span{{SpanID: 1, Process: &model.Process{ServiceName: "service"}}, selfTraceSpan()}, JaegerFormatType)
+	require.NoError(t, err)
+	require.Len(t, tracer.FinishedSpans(), 1)
+	span := tracer.FinishedSpans()[0]
+	assert.Equal(t, "ProcessSpans", span.OperationName)
+	assert.Equal(t, JaegerFormatType, span.Tag("format"))
+	assert.Equal(t, 2, span.Tag("spans"))
+
+	processor.setErr(errors.New("queue is full"))
+	_, err = traced.ProcessSpans([]*model.Span{{SpanID: 2}}, ZipkinFormatType)
+	assert.EqualError(t, err, "queue is full")
+	require.Len(t, tracer.FinishedSpans(), 2)
+	assert.Equal(t, true, tracer.FinishedSpans()[1].Tag("error"))
+	assert.NoError(t, traced.Close())
+}
+
+func TestSelfTracerSpanProcessorSkipsSelfTraces(t *testing.T) {
+	tracer := mocktracer.New()
+	selfTracer := NewSelfTracer(tracer)
+	processor := &recordingProcessor{}
+	traced := selfTracer.SpanProcessor(processor)
+
+	_, err := traced.ProcessSpans([]*model.Span{selfTraceSpan(), selfTraceSpan()}, JaegerFormatType)
+	require.NoError(t, err)
+	spans, _ := processor.getSpans()
+	assert.Len(t, spans, 2)
+	assert.Empty(t, tracer.FinishedSpans())
+}
+
+func TestSelfTracerSpanWriter(t *testing.T) {
+	tracer := mocktracer.New()
+	selfTracer := NewSelfTracer(tracer)
+	writer := &countingSpanWriter{}
+	traced := selfTracer.SpanWriter(writer)
+
+	require.NoError(t, traced.WriteSpan(context.Background(), &model.Span{SpanID: 1, Process: &model.Process{ServiceName: "service"}}))
+	require.NoError(t, traced.WriteSpan(context.Background(), selfTraceSpan()))
+	assert.EqualValues(t, 2, writer.writes)
+	require.Len(t, tracer.FinishedSpans(), 1)
+	span := tracer.FinishedSpans()[0]
+	assert.Equal(t, "WriteSpan", span.OperationName)
+	assert.Equal(t, "service", span.Tag("service"))
+
+	parent := tracer.StartSpan("parent")
+	ctx := opentracing.ContextWithSpan(context.Background(), parent)
+	traced = selfTracer.SpanWriter(&failingSpanWriter{err: errors.New("storage is down")})
+	assert.EqualError(t, traced.WriteSpan(ctx, &model.Span{SpanID: 2}), "storage is down")
+	parent.Finish()
+	require.Len(t, tracer.FinishedSpans(), 3)
+	span = tracer.FinishedSpans()[1]
+	assert.Equal(t, true, span.Tag("error"))
+	assert.Equal(t, parent.Context().(mocktracer.MockSpanContext).SpanID, span.ParentID)
+	assert.NoError(t, traced.(io.Closer).Close())
+}
@@ -14,7 +14,15 @@
 
 package app
 
-import "strings"
+import (
+	"strings"
+
+	"github.com/uber/jaeger/model"
+)
+
+// OriginalServiceNameTagKey is the process tag that keeps the service name reported by a client when it
+// was changed by the ServiceNameNormalizer
+const OriginalServiceNameTagKey = "jaeger.original-service-name"
 
 // NormalizeServiceName converts service name to a lowercase string that is safe to use in metrics
 func NormalizeServiceName(serviceName string) string {
@@ -57,3 +65,31 @@ func newServiceNameReplacer() *strings.Replacer {
 
 	return strings.NewReplacer(oldnew...)
 }
+
+// ServiceNameNormalizer lowercases the service names of the spans and trims the whitespace around them,
+// so that the spans of a service whose clients spell its name differently, e.g. "MyService" and
+// " myservice ", are grouped together. Unlike NormalizeServiceName it keeps the other runes as they are.
+type ServiceNameNormalizer struct{}
+
+// NewServiceNameNormalizer creates a ServiceNameNormalizer
+func NewServiceNameNormalizer() *ServiceNameNormalizer {
+	return &ServiceNameNormalizer{}
+}
+
+// ProcessSpans normalizes the service names of the processes of the spans and keeps the original names in
+// the OriginalServiceNameTagKey process tag, it can be used as the PreProcessSpans option. The service
+// names that would be left empty are not changed.
+func (n *ServiceNameNormalizer) ProcessSpans(spans []*model.Span) {
+	for _, span := range spans {
+		if span.Process == nil {
+			continue
+		}
+		// the spans of a batch may share their process, which is then only changed the first time
+		serviceName := strings.ToLower(strings.TrimSpace(span.Process.ServiceName))
+		if serviceName == "" || serviceName == span.Process.ServiceName {
+			continue
+		}
+		span.Process.Tags = append(span.Process.Tags, model.String(OriginalServiceNameTagKey, span.Process.ServiceName))
+		span.Process.ServiceName = serviceName
+	}
+}
@@ -18,6 +18,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/uber/jaeger/model"
 )
 
 func TestServiceNameReplacer(t *testing.T) {
@@ -25,3 +27,30 @@ func TestServiceNameReplacer(t *testing.T) {
 	assert.Equal(t, "a_b_c__", NormalizeServiceName("a&b%c/:"), "disallowed runes to underscore")
 	assert.Equal(t, "a_z_0123456789.", NormalizeServiceName("A_Z_0123456789."), "allowed runes")
 }
+
+func TestServiceNameNormalizer(t *testing.T) {
+	shared := &model.Process{ServiceName: " MyService\t"}
+	spans := []*model.Span{
+		{Process: shared},
+		{Process: shared},
+		{Process: &model.Process{ServiceName: "myservice"}},
+		{Process: &model.Process{ServiceName: "My Service/v2", Tags: model.KeyValues{model.String("hostname", "h1")}}},
+		{Process: &model.Process{ServiceName: "  "}},
+		{},
+	}
+	NewServiceNameNormalizer().ProcessSpans(spans)
+
+	assert.Equal(t, "myservice", shared.ServiceName)
+	assert.Equal(t, model.KeyValues{model.String(OriginalServiceNameTagKey, " MyService\t")}, shared.Tags,
+		"the original name is kept once for the process shared by the spans")
+	assert.Equal(t, "myservice", spans[2].Process.ServiceName)
+	assert.Empty(t, spans[2].Process.Tags, "the names already normalized are not tagged")
+	assert.Equal(t, "my service/v2", spans[3].Process.ServiceName)
+	assert.Equal(t, model.KeyValues{
+		model.String("hostname", "h1"),
+		model.String(OriginalServiceNameTagKey, "My Service/v2"),
+	}, spans[3].Process.Tags)
+	assert.Equal(t, "  ", spans[4].Process.ServiceName, "the names that would be left empty are not changed")
+	assert.Empty(t, spans[4].Process.Tags)
+	assert.Nil(t, spans[5].Process)
+}
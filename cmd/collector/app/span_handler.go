@@ -15,6 +15,9 @@
 package app
 
 import (
+	"io"
+	"time"
+
 	"github.com/uber/tchannel-go/thrift"
 	"go.uber.org/zap"
 
@@ -31,6 +34,8 @@ const (
 	JaegerFormatType = "jaeger"
 	// ZipkinFormatType is for zipkin Spans
 	ZipkinFormatType = "zipkin"
+	// OTLPFormatType is for OpenTelemetry protocol (OTLP) spans
+	OTLPFormatType = "otlp"
 	// UnknownFormatType is for spans that do not have a widely defined/well-known format type
 	UnknownFormatType = "unknown"
 )
@@ -51,11 +56,13 @@ type JaegerBatchesHandler interface {
 type SpanProcessor interface {
 	// ProcessSpans processes model spans and return with either a list of true/false success or an error
 	ProcessSpans(mSpans []*model.Span, spanFormat string) ([]bool, error)
+	io.Closer
 }
 
 type jaegerBatchesHandler struct {
 	logger         *zap.Logger
 	modelProcessor SpanProcessor
+	benchmark      *Benchmark
 }
 
 // NewJaegerSpanHandler returns a JaegerBatchesHandler
@@ -69,11 +76,13 @@ func NewJaegerSpanHandler(logger *zap.Logger, modelProcessor SpanProcessor) Jaeg
 func (jbh *jaegerBatchesHandler) SubmitBatches(ctx thrift.Context, batches []*jaeger.Batch) ([]*jaeger.BatchSubmitResponse, error) {
 	responses := make([]*jaeger.BatchSubmitResponse, 0, len(batches))
 	for _, batch := range batches {
+		start := time.Now()
 		mSpans := make([]*model.Span, 0, len(batch.Spans))
 		for _, span := range batch.Spans {
 			mSpan := jConv.ToDomainSpan(span, batch.Process)
 			mSpans = append(mSpans, mSpan)
 		}
+		jbh.benchmark.recordDecode(start, len(mSpans))
 		oks, err := jbh.modelProcessor.ProcessSpans(mSpans, JaegerFormatType)
 		if err != nil {
 			return nil, err
@@ -97,6 +106,7 @@ type zipkinSpanHandler struct {
 	logger         *zap.Logger
 	sanitizer      zipkinS.Sanitizer
 	modelProcessor SpanProcessor
+	benchmark      *Benchmark
 }
 
 // NewZipkinSpanHandler returns a ZipkinSpansHandler
@@ -110,11 +120,13 @@ func NewZipkinSpanHandler(logger *zap.Logger, modelHandler SpanProcessor, saniti
 
 // SubmitZipkinBatch records a batch of spans already in Zipkin Thrift format.
 func (h *zipkinSpanHandler) SubmitZipkinBatch(ctx thrift.Context, spans []*zipkincore.Span) ([]*zipkincore.Response, error) {
+	start := time.Now()
 	mSpans := make([]*model.Span, 0, len(spans))
 	for _, span := range spans {
 		sanitized := h.sanitizer.Sanitize(span)
 		mSpans = append(mSpans, convertZipkinToModel(sanitized, h.logger)...)
 	}
+	h.benchmark.recordDecode(start, len(mSpans))
 	bools, err := h.modelProcessor.ProcessSpans(mSpans, ZipkinFormatType)
 	if err != nil {
 		return nil, err
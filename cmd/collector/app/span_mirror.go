@@ -0,0 +1,167 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/uber/jaeger/model"
+	jsonConv "github.com/uber/jaeger/model/converter/json"
+)
+
+const (
+	// DefaultMirrorQueueSize is the default number of mirrored spans waiting to be written to the mirror sink
+	DefaultMirrorQueueSize = 1000
+
+	// mirrorSalt makes the traces that are mirrored independent from the ones kept when downsampling
+	mirrorSalt        = "jaeger.mirror"
+	mirrorHTTPTimeout = 5 * time.Second
+)
+
+// MirrorSink receives the spans copied by a SpanMirror, each one encoded in the JSON format accepted by
+// the collector's /api/span endpoint
+type MirrorSink interface {
+	Write(span []byte) error
+	Close() error
+}
+
+// NewMirrorSink creates the MirrorSink for target: an http or https URL the spans are posted to one at
+// a time, or the path of a file the spans are appended to, one per line.
+func NewMirrorSink(target string) (MirrorSink, error) {
+	if strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://") {
+		return &httpMirrorSink{url: target, client: &http.Client{Timeout: mirrorHTTPTimeout}}, nil
+	}
+	file, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &fileMirrorSink{file: file}, nil
+}
+
+type fileMirrorSink struct {
+	file *os.File
+}
+
+func (s *fileMirrorSink) Write(span []byte) error {
+	_, err := s.file.Write(append(span, '\n'))
+	return err
+}
+
+func (s *fileMirrorSink) Close() error {
+	return s.file.Close()
+}
+
+type httpMirrorSink struct {
+	url    string
+	client *http.Client
+}
+
+func (s *httpMirrorSink) Write(span []byte) error {
+	resp, err := s.client.Post(s.url, "application/json", bytes.NewReader(span))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("mirror target responded with %v", resp.Status)
+	}
+	return nil
+}
+
+func (s *httpMirrorSink) Close() error {
+	return nil
+}
+
+// SpanMirror copies a fraction of the incoming spans, as received, to a MirrorSink for offline analysis.
+// The traces are chosen from a hash of their ID, so all the spans of a trace are mirrored together. The
+// copies are written in the background and dropped when the sink falls behind, so that mirroring never
+// holds up ingestion. The spans.mirrored counter counts the copies written, spans.mirror-dropped the ones
+// dropped since the sink was behind and spans.mirror-failed the ones the sink failed to write.
+type SpanMirror struct {
+	sampler  *ProbabilisticSampler
+	sink     MirrorSink
+	logger   *zap.Logger
+	queue    chan []byte
+	mirrored metrics.Counter
+	dropped  metrics.Counter
+	failed   metrics.Counter
+	done     sync.WaitGroup
+}
+
+// NewSpanMirror creates a SpanMirror that writes fraction of the traces, between 0 and 1, to sink. Up to
+// queueSize copies wait to be written, the ones past it are dropped.
+func NewSpanMirror(fraction float64, sink MirrorSink, queueSize int, metricsFactory metrics.Factory, logger *zap.Logger) *SpanMirror {
+	m := &SpanMirror{
+		sampler:  NewProbabilisticSampler(fraction, mirrorSalt),
+		sink:     sink,
+		logger:   logger,
+		queue:    make(chan []byte, queueSize),
+		mirrored: metricsFactory.Counter("spans.mirrored", nil),
+		dropped:  metricsFactory.Counter("spans.mirror-dropped", nil),
+		failed:   metricsFactory.Counter("spans.mirror-failed", nil),
+	}
+	m.done.Add(1)
+	go m.write()
+	return m
+}
+
+// ProcessSpans queues copies of the spans of the mirrored traces, it can be used as the PreProcessSpans
+// option. The spans are encoded right away, since they are changed further down the pipeline.
+func (m *SpanMirror) ProcessSpans(spans []*model.Span) {
+	for _, span := range spans {
+		if span.Process == nil || !m.sampler.ShouldSample(span) {
+			continue
+		}
+		data, err := json.Marshal(jsonConv.FromDomainEmbedProcess(span))
+		if err != nil {
+			m.failed.Inc(1)
+			continue
+		}
+		select {
+		case m.queue <- data:
+		default:
+			m.dropped.Inc(1)
+		}
+	}
+}
+
+// Close writes the queued copies and closes the sink. ProcessSpans must not be called after Close.
+func (m *SpanMirror) Close() error {
+	close(m.queue)
+	m.done.Wait()
+	return m.sink.Close()
+}
+
+func (m *SpanMirror) write() {
+	defer m.done.Done()
+	for data := range m.queue {
+		if err := m.sink.Write(data); err != nil {
+			m.failed.Inc(1)
+			m.logger.Debug("Failed to mirror span", zap.Error(err))
+			continue
+		}
+		m.mirrored.Inc(1)
+	}
+}
@@ -0,0 +1,192 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"bufio"
+	"errors"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/uber/jaeger/model"
+)
+
+type fakeMirrorSink struct {
+	sync.Mutex
+	spans   [][]byte
+	err     error
+	blocked chan struct{}
+	closed  bool
+}
+
+func (s *fakeMirrorSink) Write(span []byte) error {
+	if s.blocked != nil {
+		<-s.blocked
+	}
+	s.Lock()
+	defer s.Unlock()
+	s.spans = append(s.spans, span)
+	return s.err
+}
+
+func (s *fakeMirrorSink) Close() error {
+	s.closed = true
+	return nil
+}
+
+func mirroredTraceSpans(n int) []*model.Span {
+	spans := make([]*model.Span, n)
+	for i := range spans {
+		spans[i] = &model.Span{
+			TraceID: model.TraceID{High: rand.Uint64(), Low: rand.Uint64()},
+			SpanID:  model.SpanID(1),
+			Process: &model.Process{ServiceName: "service"},
+		}
+	}
+	return spans
+}
+
+func TestSpanMirrorFraction(t *testing.T) {
+	for _, fraction := range []float64{0, 0.1, 0.5, 1} {
+		sink := &fakeMirrorSink{}
+		mb := metrics.NewLocalFactory(0)
+		mirror := NewSpanMirror(fraction, sink, 10000, mb, zap.NewNop())
+		mirror.ProcessSpans(mirroredTraceSpans(10000))
+		require.NoError(t, mirror.Close())
+
+		assert.InDelta(t, fraction*10000, len(sink.spans), 300, "fraction %v", fraction)
+		assert.True(t, sink.closed)
+		counters, _ := mb.Snapshot()
+		assert.EqualValues(t, len(sink.spans), counters["spans.mirrored"], "fraction %v", fraction)
+		assert.EqualValues(t, 0, counters["spans.mirror-dropped"], "fraction %v", fraction)
+	}
+}
+
+func TestSpanMirrorMirrorsWholeTraces(t *testing.T) {
+	sink := &fakeMirrorSink{}
+	mirror := NewSpanMirror(0.5, sink, 10000, metrics.NullFactory, zap.NewNop())
+	traces := mirroredTraceSpans(100)
+	spans := append(traces, cloneSpans(traces)...)
+	mirror.ProcessSpans(spans)
+	require.NoError(t, mirror.Close())
+	assert.True(t, len(sink.spans)%2 == 0, "both spans of a trace are mirrored or neither is")
+}
+
+func cloneSpans(spans []*model.Span) []*model.Span {
+	clones := make([]*model.Span, len(spans))
+	for i, span := range spans {
+		clone := *span
+		clone.SpanID = model.SpanID(2)
+		clones[i] = &clone
+	}
+	return clones
+}
+
+func TestSpanMirrorNeverBlocks(t *testing.T) {
+	sink := &fakeMirrorSink{blocked: make(chan struct{})}
+	mb := metrics.NewLocalFactory(0)
+	mirror := NewSpanMirror(1, sink, 2, mb, zap.NewNop())
+	mirror.ProcessSpans(mirroredTraceSpans(10))
+	close(sink.blocked)
+	require.NoError(t, mirror.Close())
+
+	counters, _ := mb.Snapshot()
+	assert.EqualValues(t, 10, counters["spans.mirrored"]+counters["spans.mirror-dropped"])
+	assert.True(t, counters["spans.mirror-dropped"] >= 7, "the spans past the queue are dropped")
+}
+
+func TestSpanMirrorSinkFailure(t *testing.T) {
+	sink := &fakeMirrorSink{err: errors.New("disk full")}
+	mb := metrics.NewLocalFactory(0)
+	mirror := NewSpanMirror(1, sink, 10, mb, zap.NewNop())
+	mirror.ProcessSpans(append(mirroredTraceSpans(2), &model.Span{TraceID: model.TraceID{Low: 1}}))
+	require.NoError(t, mirror.Close())
+
+	counters, _ := mb.Snapshot()
+	assert.EqualValues(t, 0, counters["spans.mirrored"])
+	assert.EqualValues(t, 2, counters["spans.mirror-failed"], "the span without a process is not mirrored")
+}
+
+func TestFileMirrorSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mirror")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "spans.json")
+
+	sink, err := NewMirrorSink(path)
+	require.NoError(t, err)
+	mirror := NewSpanMirror(1, sink, 10, metrics.NullFactory, zap.NewNop())
+	mirror.ProcessSpans(mirroredTraceSpans(3))
+	require.NoError(t, mirror.Close())
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	decoder := jsonSpanDecoder{}
+	var lines int
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		spans, err := decoder.Decode(scanner.Bytes())
+		require.NoError(t, err, "the mirrored spans can be posted to /api/span")
+		assert.Equal(t, "service", spans[0].Process.ServiceName)
+		lines++
+	}
+	assert.Equal(t, 3, lines)
+
+	_, err = NewMirrorSink(filepath.Join(dir, "missing", "spans.json"))
+	assert.Error(t, err)
+}
+
+func TestHTTPMirrorSink(t *testing.T) {
+	var lock sync.Mutex
+	var received int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		_, err := jsonSpanDecoder{}.Decode(body)
+		assert.NoError(t, err)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		lock.Lock()
+		received++
+		lock.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	sink, err := NewMirrorSink(server.URL + JSONSpanPath)
+	require.NoError(t, err)
+	mirror := NewSpanMirror(1, sink, 10, metrics.NullFactory, zap.NewNop())
+	mirror.ProcessSpans(mirroredTraceSpans(3))
+	require.NoError(t, mirror.Close())
+	assert.Equal(t, 3, received)
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	sink, err = NewMirrorSink(failing.URL)
+	require.NoError(t, err)
+	assert.EqualError(t, sink.Write([]byte("{}")), "mirror target responded with 503 Service Unavailable")
+}
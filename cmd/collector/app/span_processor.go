@@ -15,6 +15,9 @@
 package app
 
 import (
+	"context"
+	"fmt"
+	"math"
 	"time"
 
 	"github.com/uber/tchannel-go"
@@ -24,25 +27,54 @@ import (
 	"github.com/uber/jaeger/storage/spanstore"
 
 	"github.com/uber/jaeger/cmd/collector/app/sanitizer"
+	"github.com/uber/jaeger/pkg/clock"
 	"github.com/uber/jaeger/pkg/queue"
 )
 
+const (
+	drainPollInterval = 10 * time.Millisecond
+	// drainReportInterval is how often the progress of draining the queue on shutdown is reported
+	drainReportInterval = time.Second
+)
+
 type spanProcessor struct {
 	queue           *queue.BoundedQueue
 	metrics         *SpanProcessorMetrics
 	preProcessSpans ProcessSpans
 	filterSpan      FilterSpan             // filter is called before the sanitizer but after preProcessSpans
-	sanitizer       sanitizer.SanitizeSpan // sanitizer is called before processSpan
-	processSpan     ProcessSpan
+	sanitizer       sanitizer.SanitizeSpan // sanitizer is called before preSave
+	preSave         ProcessSpan            // preSave is called before the span is written to spanWriter
 	logger          *zap.Logger
 	spanWriter      spanstore.Writer
+	writeAheadLog   WriteAheadLog
+	clock           clock.Clock
+	// ctx is passed to spanWriter, it is cancelled when the processor stops so that writes in progress are abandoned
+	ctx             context.Context
+	cancel          context.CancelFunc
 	reportBusy      bool
+	blockingSubmit  bool
 	numWorkers      int
+	shutdownTimeout time.Duration
+	// backpressureSize is the queue size from which new spans are rejected, 0 if they never are
+	backpressureSize int
 }
 
 type queueItem struct {
 	queuedTime time.Time
 	span       *model.Span
+	// walSegment is the write-ahead log segment the span was appended to, 0 if it was not
+	walSegment uint64
+}
+
+// WriteAheadLog records the spans queued by the span processor until they are saved, so that the
+// spans that were not saved before the collector stopped can be replayed when it restarts
+type WriteAheadLog interface {
+	// Append records span and returns the id of the segment it is acknowledged with
+	Append(span *model.Span) (uint64, error)
+	// Ack acknowledges that a span of the segment was saved
+	Ack(segmentID uint64)
+	// Replay calls fn with the spans that were appended and not acknowledged by the previous process
+	Replay(fn func(span *model.Span, segmentID uint64)) error
 }
 
 // NewSpanProcessor returns a SpanProcessor that preProcesses, filters, queues, sanitizes, and processes spans
@@ -57,8 +89,11 @@ func NewSpanProcessor(
 		sp.processItemFromQueue(value)
 	})
 
-	sp.queue.StartLengthReporting(1*time.Second, sp.metrics.QueueLength)
+	sp.queue.StartLengthReporting(1*time.Second, sp.queueGauges())
 
+	if sp.writeAheadLog != nil {
+		sp.replayWriteAheadLog()
+	}
 	return sp
 }
 
@@ -72,7 +107,9 @@ func newSpanProcessor(spanWriter spanstore.Writer, opts ...Option) *spanProcesso
 		handlerMetrics.SpansDropped.Inc(1)
 	}
 	boundedQueue := queue.NewBoundedQueue(options.queueSize, droppedItemHandler)
+	handlerMetrics.QueueCapacity.Update(int64(boundedQueue.Capacity()))
 
+	ctx, cancel := context.WithCancel(context.Background())
 	sp := spanProcessor{
 		queue:           boundedQueue,
 		metrics:         handlerMetrics,
@@ -81,37 +118,133 @@ func newSpanProcessor(spanWriter spanstore.Writer, opts ...Option) *spanProcesso
 		filterSpan:      options.spanFilter,
 		sanitizer:       options.sanitizer,
 		reportBusy:      options.reportBusy,
+		blockingSubmit:  options.blockingSubmit,
 		numWorkers:      options.numWorkers,
+		shutdownTimeout: options.shutdownTimeout,
 		spanWriter:      spanWriter,
+		writeAheadLog:   options.writeAheadLog,
+		preSave:         options.preSave,
+		clock:           options.clock,
+		ctx:             ctx,
+		cancel:          cancel,
+	}
+	if options.backpressure > 0 {
+		sp.backpressureSize = int(math.Ceil(options.backpressure * float64(boundedQueue.Capacity())))
 	}
-	sp.processSpan = ChainedProcessSpan(
-		options.preSave,
-		sp.saveSpan,
-	)
-
 	return &sp
 }
 
-// Stop halts the span processor and all its go-routines.
+// replayWriteAheadLog queues the spans of the write-ahead log that were not saved by the previous process,
+// it returns once they are all queued
+func (sp *spanProcessor) replayWriteAheadLog() {
+	replayed := 0
+	err := sp.writeAheadLog.Replay(func(span *model.Span, segmentID uint64) {
+		item := &queueItem{
+			queuedTime: sp.clock.Now(),
+			span:       span,
+			walSegment: segmentID,
+		}
+		if sp.queue.ProduceBlocking(item) {
+			replayed++
+		}
+	})
+	if err != nil {
+		sp.logger.Error("Failed to replay the write-ahead log", zap.Error(err))
+	}
+	if replayed > 0 {
+		sp.logger.Info("Replayed spans from the write-ahead log", zap.Int("spans", replayed))
+	}
+}
+
+// Stop halts the span processor and all its go-routines, cancelling the writes in progress.
 func (sp *spanProcessor) Stop() {
+	sp.cancel()
 	sp.queue.Stop()
 }
 
-func (sp *spanProcessor) saveSpan(span *model.Span) {
-	startTime := time.Now()
-	if err := sp.spanWriter.WriteSpan(span); err != nil {
+// Close waits up to shutdownTimeout for the queued spans to be saved and then halts the span processor,
+// cancelling the writes still in progress. Callers are expected to stop submitting new spans before calling Close.
+// While the queue drains, the number of spans left in it is logged and reported every drainReportInterval.
+func (sp *spanProcessor) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), sp.shutdownTimeout)
+	defer cancel()
+	return sp.drain(ctx, sp.shutdownTimeout)
+}
+
+// Shutdown is like Close, but waits for the queued spans until ctx is done rather than for shutdownTimeout,
+// so that the caller can spend a single deadline on stopping its listeners and draining the queue.
+func (sp *spanProcessor) Shutdown(ctx context.Context) error {
+	var timeout time.Duration
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline).Round(time.Millisecond)
+	}
+	return sp.drain(ctx, timeout)
+}
+
+// drain waits until ctx is done for the queued spans to be saved and then halts the span processor,
+// timeout is the time ctx had left when drain was called, it is only used in the error.
+func (sp *spanProcessor) drain(ctx context.Context, timeout time.Duration) error {
+	deadline, _ := ctx.Deadline()
+	var lastReport time.Time
+	remaining := sp.queue.Size()
+	for remaining > 0 && ctx.Err() == nil {
+		if time.Since(lastReport) >= drainReportInterval {
+			sp.reportDrainProgress(remaining, deadline)
+			lastReport = time.Now()
+		}
+		time.Sleep(drainPollInterval)
+		remaining = sp.queue.Size()
+	}
+	sp.metrics.DrainRemaining.Update(int64(remaining))
+	stopped := make(chan struct{})
+	go func() {
+		sp.queue.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		// the writes in progress are cancelled, rather than waited for, once the timeout is over
+		sp.cancel()
+		<-stopped
+	}
+	sp.cancel()
+	if remaining > 0 {
+		return fmt.Errorf("%d spans were still queued after shutdown timeout of %v", remaining, timeout)
+	}
+	return nil
+}
+
+func (sp *spanProcessor) reportDrainProgress(remaining int, deadline time.Time) {
+	sp.metrics.DrainRemaining.Update(int64(remaining))
+	sp.logger.Info("Draining span queue",
+		zap.Int("queue-remaining", remaining),
+		zap.Duration("time-left", time.Until(deadline)))
+}
+
+// saveSpan writes the span to spanWriter and returns true if it was saved
+func (sp *spanProcessor) saveSpan(span *model.Span) bool {
+	startTime := sp.clock.Now()
+	err := sp.spanWriter.WriteSpan(sp.ctx, span)
+	if err != nil {
 		sp.logger.Error("Failed to save span", zap.Error(err))
 	} else {
 		sp.metrics.SavedBySvc.ReportServiceNameForSpan(span)
 	}
-	sp.metrics.SaveLatency.Record(time.Now().Sub(startTime))
+	sp.metrics.SaveLatency.Record(sp.clock.Now().Sub(startTime))
+	return err == nil
 }
 
 func (sp *spanProcessor) ProcessSpans(mSpans []*model.Span, spanFormat string) ([]bool, error) {
 	sp.preProcessSpans(mSpans)
 	sp.metrics.GetCountsForFormat(spanFormat).Received.Inc(int64(len(mSpans)))
 	sp.metrics.BatchSize.Update(int64(len(mSpans)))
+	if sp.backpressureSize > 0 && sp.queue.Size() >= sp.backpressureSize {
+		sp.metrics.RejectedBusy.Inc(1)
+		return nil, tchannel.ErrServerBusy
+	}
 	retMe := make([]bool, len(mSpans))
+	defer sp.reportQueueLength()
 	for i, mSpan := range mSpans {
 		ok := sp.enqueueSpan(mSpan, spanFormat)
 		if !ok && sp.reportBusy {
@@ -122,9 +255,35 @@ func (sp *spanProcessor) ProcessSpans(mSpans []*model.Span, spanFormat string) (
 	return retMe, nil
 }
 
+// reportQueueLength updates the queue length and utilization gauges right away, rather than waiting for the
+// periodic reporter, so that autoscalers see the spans being queued and dequeued
+func (sp *spanProcessor) reportQueueLength() {
+	sp.queueGauges().Update(int64(sp.queue.Size()))
+}
+
+func (sp *spanProcessor) queueGauges() queueLengthGauge {
+	return queueLengthGauge{
+		length:      sp.metrics.QueueLength,
+		utilization: sp.metrics.QueueUtilization,
+		capacity:    sp.queue.Capacity(),
+	}
+}
+
 func (sp *spanProcessor) processItemFromQueue(item *queueItem) {
-	sp.processSpan(sp.sanitizer(item.span))
-	sp.metrics.InQueueLatency.Record(time.Now().Sub(item.queuedTime))
+	sp.reportQueueLength()
+	span := sp.sanitizer(item.span)
+	sp.preSave(span)
+	// a span that failed to be saved stays in the write-ahead log, to be replayed after a restart
+	if sp.saveSpan(span) {
+		sp.ackWriteAheadLog(item)
+	}
+	sp.metrics.InQueueLatency.Record(sp.clock.Now().Sub(item.queuedTime))
+}
+
+func (sp *spanProcessor) ackWriteAheadLog(item *queueItem) {
+	if item.walSegment != 0 {
+		sp.writeAheadLog.Ack(item.walSegment)
+	}
 }
 
 func (sp *spanProcessor) enqueueSpan(span *model.Span, originalFormat string) bool {
@@ -136,12 +295,25 @@ func (sp *spanProcessor) enqueueSpan(span *model.Span, originalFormat string) bo
 		return true // as in "not dropped", because it's actively rejected
 	}
 	item := &queueItem{
-		queuedTime: time.Now(),
+		queuedTime: sp.clock.Now(),
 		span:       span,
 	}
-	addedToQueue := sp.queue.Produce(item)
+	if sp.writeAheadLog != nil {
+		if segmentID, err := sp.writeAheadLog.Append(span); err != nil {
+			sp.logger.Error("Failed to append span to the write-ahead log", zap.Error(err))
+		} else {
+			item.walSegment = segmentID
+		}
+	}
+	var addedToQueue bool
+	if sp.blockingSubmit {
+		addedToQueue = sp.queue.ProduceBlocking(item)
+	} else {
+		addedToQueue = sp.queue.Produce(item)
+	}
 	if !addedToQueue {
 		sp.metrics.ErrorBusy.Inc(1)
+		sp.ackWriteAheadLog(item)
 	}
 	return addedToQueue
 }
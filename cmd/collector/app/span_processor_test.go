@@ -21,8 +21,10 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/uber/jaeger-lib/metrics"
 	metricsTest "github.com/uber/jaeger-lib/metrics/testutils"
+	"github.com/uber/tchannel-go"
 	"github.com/uber/tchannel-go/thrift"
 	"go.uber.org/zap"
 	"golang.org/x/net/context"
@@ -156,7 +158,7 @@ type fakeSpanWriter struct {
 	err error
 }
 
-func (n *fakeSpanWriter) WriteSpan(span *model.Span) error {
+func (n *fakeSpanWriter) WriteSpan(ctx context.Context, span *model.Span) error {
 	return n.err
 }
 
@@ -251,7 +253,7 @@ type blockingWriter struct {
 	sync.Mutex
 }
 
-func (w *blockingWriter) WriteSpan(span *model.Span) error {
+func (w *blockingWriter) WriteSpan(ctx context.Context, span *model.Span) error {
 	w.Lock()
 	defer w.Unlock()
 	return nil
@@ -292,3 +294,444 @@ func TestSpanProcessorBusy(t *testing.T) {
 	assert.Error(t, err, "expcting busy error")
 	assert.Nil(t, res)
 }
+
+func TestSpanProcessorCloseDrainsQueue(t *testing.T) {
+	w := &blockingWriter{}
+	p := NewSpanProcessor(w,
+		Options.NumWorkers(1),
+		Options.QueueSize(10),
+		Options.ShutdownTimeout(time.Second),
+	).(*spanProcessor)
+
+	w.Lock()
+	res, err := p.ProcessSpans([]*model.Span{
+		{Process: &model.Process{ServiceName: "x"}},
+		{Process: &model.Process{ServiceName: "x"}},
+	}, JaegerFormatType)
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{true, true}, res)
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		w.Unlock()
+	}()
+	assert.NoError(t, p.Close())
+	assert.Equal(t, 0, p.queue.Size())
+}
+
+func TestSpanProcessorCloseTimeout(t *testing.T) {
+	w := &blockingWriter{}
+	p := NewSpanProcessor(w,
+		Options.NumWorkers(1),
+		Options.QueueSize(10),
+		Options.ShutdownTimeout(10*time.Millisecond),
+	).(*spanProcessor)
+
+	w.Lock()
+	_, err := p.ProcessSpans([]*model.Span{
+		{Process: &model.Process{ServiceName: "x"}},
+		{Process: &model.Process{ServiceName: "x"}},
+	}, JaegerFormatType)
+	assert.NoError(t, err)
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		w.Unlock()
+	}()
+	assert.EqualError(t, p.Close(), "1 spans were still queued after shutdown timeout of 10ms")
+}
+
+func TestSpanProcessorCloseReportsDrainProgress(t *testing.T) {
+	logger, logBuf := testutils.NewLogger()
+	mb := metrics.NewLocalFactory(time.Hour)
+	w := &blockingWriter{}
+	p := NewSpanProcessor(w,
+		Options.Logger(logger),
+		Options.HostMetrics(mb),
+		Options.NumWorkers(1),
+		Options.QueueSize(10),
+		Options.ShutdownTimeout(50*time.Millisecond),
+	).(*spanProcessor)
+
+	w.Lock()
+	_, err := p.ProcessSpans([]*model.Span{
+		{Process: &model.Process{ServiceName: "x"}},
+		{Process: &model.Process{ServiceName: "x"}},
+		{Process: &model.Process{ServiceName: "x"}},
+	}, JaegerFormatType)
+	assert.NoError(t, err)
+	for i := 0; i < 100 && p.queue.Size() > 2; i++ {
+		time.Sleep(time.Millisecond)
+	}
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		w.Unlock()
+	}()
+	assert.EqualError(t, p.Close(), "2 spans were still queued after shutdown timeout of 50ms")
+
+	lines := logBuf.Lines()
+	require.NotEmpty(t, lines)
+	assert.Contains(t, lines[0], `"msg":"Draining span queue"`)
+	assert.Contains(t, lines[0], `"queue-remaining":2`)
+	_, gauges := mb.Snapshot()
+	assert.EqualValues(t, 2, gauges["shutdown.queue-remaining"])
+}
+
+func TestSpanProcessorShutdownUsesContextDeadline(t *testing.T) {
+	w := &blockingWriter{}
+	p := NewSpanProcessor(w,
+		Options.NumWorkers(1),
+		Options.QueueSize(10),
+		Options.ShutdownTimeout(time.Hour),
+	).(*spanProcessor)
+
+	w.Lock()
+	_, err := p.ProcessSpans([]*model.Span{
+		{Process: &model.Process{ServiceName: "x"}},
+		{Process: &model.Process{ServiceName: "x"}},
+	}, JaegerFormatType)
+	assert.NoError(t, err)
+	for i := 0; i < 100 && p.queue.Size() > 1; i++ {
+		time.Sleep(time.Millisecond)
+	}
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		w.Unlock()
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err = p.Shutdown(ctx)
+	assert.Regexp(t, "^1 spans were still queued after shutdown timeout of ", err)
+	assert.True(t, time.Since(start) < time.Minute, "the shutdown timeout of the processor is not waited for")
+}
+
+// hangingWriter blocks every write until its context is cancelled
+type hangingWriter struct {
+	cancelled chan error
+}
+
+func (w *hangingWriter) WriteSpan(ctx context.Context, span *model.Span) error {
+	<-ctx.Done()
+	w.cancelled <- ctx.Err()
+	return ctx.Err()
+}
+
+func TestSpanProcessorCloseCancelsWrites(t *testing.T) {
+	w := &hangingWriter{cancelled: make(chan error, 1)}
+	p := NewSpanProcessor(w,
+		Options.NumWorkers(1),
+		Options.QueueSize(10),
+		Options.ShutdownTimeout(10*time.Millisecond),
+	).(*spanProcessor)
+
+	_, err := p.ProcessSpans([]*model.Span{
+		{Process: &model.Process{ServiceName: "x"}},
+	}, JaegerFormatType)
+	assert.NoError(t, err)
+	for i := 0; i < 100 && p.queue.Size() > 0; i++ {
+		time.Sleep(time.Millisecond)
+	}
+
+	assert.NoError(t, p.Close())
+	select {
+	case err := <-w.cancelled:
+		assert.Equal(t, context.Canceled, err)
+	case <-time.After(time.Second):
+		t.Fatal("the write in progress was not cancelled")
+	}
+}
+
+func TestSpanProcessorQueueMetrics(t *testing.T) {
+	mb := metrics.NewLocalFactory(time.Hour)
+	w := &blockingWriter{}
+	p := NewSpanProcessor(w,
+		Options.HostMetrics(mb.Namespace("host", nil)),
+		Options.NumWorkers(1),
+		Options.QueueSize(2),
+	).(*spanProcessor)
+	defer p.Stop()
+
+	_, gauges := mb.Snapshot()
+	assert.EqualValues(t, 2, gauges["host.queue-capacity"])
+	assert.EqualValues(t, 0, gauges["host.queue-length"])
+
+	// block the writer so that the first span occupies the only worker, the next two
+	// fill up the queue and the last one is dropped.
+	w.Lock()
+	defer w.Unlock()
+
+	res, err := p.ProcessSpans([]*model.Span{
+		{Process: &model.Process{ServiceName: "x"}},
+	}, JaegerFormatType)
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{true}, res)
+	for i := 0; i < 100 && p.queue.Size() > 0; i++ {
+		time.Sleep(time.Millisecond)
+	}
+
+	res, err = p.ProcessSpans([]*model.Span{
+		{Process: &model.Process{ServiceName: "x"}},
+		{Process: &model.Process{ServiceName: "x"}},
+		{Process: &model.Process{ServiceName: "x"}},
+	}, JaegerFormatType)
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{true, true, false}, res)
+
+	counters, gauges := mb.Snapshot()
+	assert.EqualValues(t, 2, gauges["host.queue-length"])
+	assert.EqualValues(t, 1, counters["host.spans.dropped"])
+}
+
+// gatedWriter signals every write it starts on started and holds it until release is closed
+type gatedWriter struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (w *gatedWriter) WriteSpan(ctx context.Context, span *model.Span) error {
+	w.started <- struct{}{}
+	<-w.release
+	return nil
+}
+
+func TestSpanProcessorQueueUtilization(t *testing.T) {
+	mb := metrics.NewLocalFactory(time.Hour)
+	w := &gatedWriter{started: make(chan struct{}, 4), release: make(chan struct{})}
+	p := NewSpanProcessor(w,
+		Options.HostMetrics(mb.Namespace("host", nil)),
+		Options.NumWorkers(1),
+		Options.QueueSize(4),
+	).(*spanProcessor)
+	defer p.Stop()
+
+	_, gauges := mb.Snapshot()
+	assert.EqualValues(t, 0, gauges["host.queue-utilization"])
+
+	// the first span occupies the only worker, the next three fill three quarters of the queue
+	_, err := p.ProcessSpans([]*model.Span{{Process: &model.Process{ServiceName: "x"}}}, JaegerFormatType)
+	require.NoError(t, err)
+	<-w.started
+	_, err = p.ProcessSpans([]*model.Span{
+		{Process: &model.Process{ServiceName: "x"}},
+		{Process: &model.Process{ServiceName: "x"}},
+		{Process: &model.Process{ServiceName: "x"}},
+	}, JaegerFormatType)
+	require.NoError(t, err)
+
+	_, gauges = mb.Snapshot()
+	assert.EqualValues(t, 3, gauges["host.queue-length"])
+	assert.EqualValues(t, 75, gauges["host.queue-utilization"])
+
+	// the gauge follows the spans taken off the queue
+	close(w.release)
+	for i := 0; i < 3; i++ {
+		<-w.started
+	}
+	_, gauges = mb.Snapshot()
+	assert.EqualValues(t, 0, gauges["host.queue-utilization"])
+}
+
+func TestSpanProcessorQueueFullPolicy(t *testing.T) {
+	for _, blocking := range []bool{false, true} {
+		w := &blockingWriter{}
+		p := NewSpanProcessor(w,
+			Options.NumWorkers(1),
+			Options.QueueSize(1),
+			Options.BlockingSubmit(blocking),
+		).(*spanProcessor)
+
+		// the writer is slow: it holds the only worker until it is unlocked
+		w.Lock()
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			w.Unlock()
+		}()
+
+		res, err := p.ProcessSpans([]*model.Span{
+			{Process: &model.Process{ServiceName: "x"}},
+			{Process: &model.Process{ServiceName: "x"}},
+			{Process: &model.Process{ServiceName: "x"}},
+		}, JaegerFormatType)
+		assert.NoError(t, err)
+		if blocking {
+			assert.Equal(t, []bool{true, true, true}, res, "block policy must not drop spans")
+		} else {
+			assert.Contains(t, res, false, "drop policy must drop spans that do not fit in the queue")
+		}
+		p.Stop()
+	}
+}
+
+func TestSpanProcessorBackpressure(t *testing.T) {
+	mb := metrics.NewLocalFactory(time.Hour)
+	// the consumers are not started, so that the queue only fills up
+	p := newSpanProcessor(&fakeSpanWriter{},
+		Options.HostMetrics(mb.Namespace("host", nil)),
+		Options.QueueSize(10),
+		Options.BackpressureThreshold(0.5),
+	)
+	defer p.Stop()
+	assert.Equal(t, 5, p.backpressureSize)
+
+	spans := []*model.Span{
+		{Process: &model.Process{ServiceName: "x"}},
+		{Process: &model.Process{ServiceName: "x"}},
+		{Process: &model.Process{ServiceName: "x"}},
+	}
+	res, err := p.ProcessSpans(spans, JaegerFormatType)
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{true, true, true}, res)
+
+	// the queue is below the threshold when the batch arrives, so all of it is accepted
+	res, err = p.ProcessSpans(spans, JaegerFormatType)
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{true, true, true}, res)
+
+	res, err = p.ProcessSpans(spans, JaegerFormatType)
+	assert.Equal(t, tchannel.ErrServerBusy, err)
+	assert.Nil(t, res)
+	assert.Equal(t, 6, p.queue.Size())
+
+	counters, _ := mb.Snapshot()
+	assert.EqualValues(t, 1, counters["host.batches.rejected|reason=busy"])
+}
+
+type fakeWriteAheadLog struct {
+	sync.Mutex
+	appendErr error
+	appended  []*model.Span
+	acked     []uint64
+	replay    []*model.Span
+}
+
+func (l *fakeWriteAheadLog) Append(span *model.Span) (uint64, error) {
+	l.Lock()
+	defer l.Unlock()
+	if l.appendErr != nil {
+		return 0, l.appendErr
+	}
+	l.appended = append(l.appended, span)
+	return uint64(len(l.appended)), nil
+}
+
+func (l *fakeWriteAheadLog) Ack(segmentID uint64) {
+	l.Lock()
+	defer l.Unlock()
+	l.acked = append(l.acked, segmentID)
+}
+
+func (l *fakeWriteAheadLog) Replay(fn func(span *model.Span, segmentID uint64)) error {
+	for i, span := range l.replay {
+		fn(span, uint64(100+i))
+	}
+	return nil
+}
+
+func (l *fakeWriteAheadLog) getAcked() []uint64 {
+	l.Lock()
+	defer l.Unlock()
+	return l.acked
+}
+
+// serviceFailingWriter fails to write the spans of one service
+type serviceFailingWriter struct {
+	service string
+}
+
+func (w *serviceFailingWriter) WriteSpan(ctx context.Context, span *model.Span) error {
+	if span.Process.ServiceName == w.service {
+		return fmt.Errorf("cannot save span of %s", w.service)
+	}
+	return nil
+}
+
+func TestSpanProcessorWriteAheadLog(t *testing.T) {
+	wal := &fakeWriteAheadLog{}
+	p := NewSpanProcessor(&serviceFailingWriter{service: "y"},
+		Options.NumWorkers(1),
+		Options.QueueSize(10),
+		Options.SpanFilter(isSpanAllowed),
+		Options.WriteAheadLog(wal),
+	).(*spanProcessor)
+
+	res, err := p.ProcessSpans([]*model.Span{
+		{Process: &model.Process{ServiceName: "x"}},
+		{Process: &model.Process{ServiceName: "y"}},
+		{Process: &model.Process{ServiceName: blackListedService}},
+	}, JaegerFormatType)
+	require.NoError(t, err)
+	assert.Equal(t, []bool{true, true, true}, res)
+	require.NoError(t, p.Close())
+
+	require.Len(t, wal.appended, 2, "filtered spans are not appended")
+	assert.Equal(t, "x", wal.appended[0].Process.ServiceName)
+	assert.Equal(t, "y", wal.appended[1].Process.ServiceName)
+	assert.Equal(t, []uint64{1}, wal.getAcked(), "the span that failed to be saved is not acknowledged")
+}
+
+func TestSpanProcessorWriteAheadLogQueueFull(t *testing.T) {
+	w := &blockingWriter{}
+	wal := &fakeWriteAheadLog{}
+	p := NewSpanProcessor(w,
+		Options.NumWorkers(1),
+		Options.QueueSize(1),
+		Options.WriteAheadLog(wal),
+	).(*spanProcessor)
+	defer p.Stop()
+
+	// block the writer so that the first span is read from the queue and blocks the processor,
+	// and eiher the second or the third span is rejected since the queue capacity is just 1.
+	w.Lock()
+	defer w.Unlock()
+
+	res, err := p.ProcessSpans([]*model.Span{
+		{Process: &model.Process{ServiceName: "x"}},
+		{Process: &model.Process{ServiceName: "x"}},
+		{Process: &model.Process{ServiceName: "x"}},
+	}, JaegerFormatType)
+	require.NoError(t, err)
+	assert.Contains(t, res, false, "one of the spans was dropped")
+	assert.Len(t, wal.appended, 3)
+	assert.Len(t, wal.getAcked(), 1, "the dropped span is acknowledged so that it is not replayed")
+}
+
+func TestSpanProcessorReplaysWriteAheadLog(t *testing.T) {
+	w := &countingSpanWriter{}
+	wal := &fakeWriteAheadLog{
+		replay: []*model.Span{
+			{Process: &model.Process{ServiceName: "x"}},
+			{Process: &model.Process{ServiceName: "x"}},
+		},
+	}
+	logger, logBuf := testutils.NewLogger()
+	p := NewSpanProcessor(w, Options.Logger(logger), Options.WriteAheadLog(wal))
+	require.NoError(t, p.Close())
+
+	assert.EqualValues(t, 2, w.writes)
+	assert.Len(t, wal.getAcked(), 2)
+	assert.Contains(t, wal.getAcked(), uint64(100))
+	assert.Contains(t, wal.getAcked(), uint64(101))
+	assert.Contains(t, logBuf.String(), "Replayed spans from the write-ahead log")
+}
+
+func TestSpanProcessorWriteAheadLogAppendError(t *testing.T) {
+	w := &countingSpanWriter{}
+	wal := &fakeWriteAheadLog{appendErr: fmt.Errorf("disk full")}
+	logger, logBuf := testutils.NewLogger()
+	p := NewSpanProcessor(w, Options.Logger(logger), Options.QueueSize(10), Options.WriteAheadLog(wal))
+
+	_, err := p.ProcessSpans([]*model.Span{{Process: &model.Process{ServiceName: "x"}}}, JaegerFormatType)
+	require.NoError(t, err)
+	require.NoError(t, p.Close())
+
+	assert.EqualValues(t, 1, w.writes, "the span is saved even though it could not be appended")
+	assert.Empty(t, wal.getAcked())
+	assert.Equal(t, map[string]string{
+		"level": "error",
+		"msg":   "Failed to append span to the write-ahead log",
+		"error": "disk full",
+	}, logBuf.JSONLine(0))
+}
@@ -0,0 +1,102 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"time"
+
+	"github.com/uber/jaeger-lib/metrics"
+
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/pkg/clock"
+)
+
+const (
+	rejectReasonZeroTraceID      = "zero-trace-id"
+	rejectReasonZeroSpanID       = "zero-span-id"
+	rejectReasonNegativeDuration = "negative-duration"
+	rejectReasonFutureStartTime  = "future-start-time"
+	rejectReasonInvalidReference = "invalid-reference"
+)
+
+// SpanValidator checks that spans are well formed before they are queued for storage
+type SpanValidator struct {
+	maxClockSkew time.Duration
+	clock        clock.Clock
+	rejected     map[string]metrics.Counter
+}
+
+// NewSpanValidator creates a SpanValidator. Spans starting more than maxClockSkew in the future
+// of clock are rejected, unless maxClockSkew is 0 which disables the check. Rejected spans are counted
+// in the spans.rejected counter tagged by reason.
+func NewSpanValidator(maxClockSkew time.Duration, clock clock.Clock, metricsFactory metrics.Factory) *SpanValidator {
+	rejected := make(map[string]metrics.Counter)
+	for _, reason := range []string{
+		rejectReasonZeroTraceID,
+		rejectReasonZeroSpanID,
+		rejectReasonNegativeDuration,
+		rejectReasonFutureStartTime,
+		rejectReasonInvalidReference,
+	} {
+		rejected[reason] = metricsFactory.Counter("spans.rejected", map[string]string{"reason": reason})
+	}
+	return &SpanValidator{
+		maxClockSkew: maxClockSkew,
+		clock:        clock,
+		rejected:     rejected,
+	}
+}
+
+// Validate returns true if the span is valid, otherwise it counts the rejection and returns false.
+// It can be used as a FilterSpan.
+func (v *SpanValidator) Validate(span *model.Span) bool {
+	if reason := v.rejectReason(span); reason != "" {
+		v.rejected[reason].Inc(1)
+		return false
+	}
+	return true
+}
+
+func (v *SpanValidator) rejectReason(span *model.Span) string {
+	if span.TraceID.Low == 0 && span.TraceID.High == 0 {
+		return rejectReasonZeroTraceID
+	}
+	if span.SpanID == 0 {
+		return rejectReasonZeroSpanID
+	}
+	if span.Duration < 0 {
+		return rejectReasonNegativeDuration
+	}
+	if v.maxClockSkew > 0 && span.StartTime.After(v.clock.Now().Add(v.maxClockSkew)) {
+		return rejectReasonFutureStartTime
+	}
+	for _, ref := range span.References {
+		if !validReference(span, ref) {
+			return rejectReasonInvalidReference
+		}
+	}
+	return ""
+}
+
+// validReference returns false if the reference has a zero ID or points to the span itself
+func validReference(span *model.Span, ref model.SpanRef) bool {
+	if ref.TraceID.Low == 0 && ref.TraceID.High == 0 {
+		return false
+	}
+	if ref.SpanID == 0 {
+		return false
+	}
+	return ref.TraceID != span.TraceID || ref.SpanID != span.SpanID
+}
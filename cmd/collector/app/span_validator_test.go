@@ -0,0 +1,120 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics"
+
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/pkg/clock"
+	"github.com/uber/jaeger/storage/spanstore/memory"
+)
+
+func TestSpanValidator(t *testing.T) {
+	now := time.Unix(1000, 0)
+	validSpan := func() *model.Span {
+		return &model.Span{
+			TraceID:   model.TraceID{Low: 1},
+			SpanID:    model.SpanID(1),
+			StartTime: now,
+			Duration:  time.Second,
+			Process:   &model.Process{ServiceName: "x"},
+		}
+	}
+	testCases := []struct {
+		caption string
+		mutate  func(span *model.Span)
+		reason  string
+	}{
+		{caption: "valid", mutate: func(span *model.Span) {}},
+		{caption: "high trace id only", mutate: func(span *model.Span) { span.TraceID = model.TraceID{High: 1} }},
+		{caption: "zero trace id", mutate: func(span *model.Span) { span.TraceID = model.TraceID{} }, reason: rejectReasonZeroTraceID},
+		{caption: "zero span id", mutate: func(span *model.Span) { span.SpanID = 0 }, reason: rejectReasonZeroSpanID},
+		{caption: "negative duration", mutate: func(span *model.Span) { span.Duration = -time.Second }, reason: rejectReasonNegativeDuration},
+		{caption: "within skew", mutate: func(span *model.Span) { span.StartTime = now.Add(time.Minute) }},
+		{caption: "beyond skew", mutate: func(span *model.Span) { span.StartTime = now.Add(time.Hour) }, reason: rejectReasonFutureStartTime},
+		{caption: "child of", mutate: func(span *model.Span) { span.References = []model.SpanRef{childOf(1, 2)} }},
+		{caption: "follows from another trace", mutate: func(span *model.Span) {
+			span.References = []model.SpanRef{{RefType: model.FollowsFrom, TraceID: model.TraceID{Low: 2}, SpanID: 1}}
+		}},
+		{caption: "reference to zero trace id", mutate: func(span *model.Span) { span.References = []model.SpanRef{childOf(0, 2)} }, reason: rejectReasonInvalidReference},
+		{caption: "reference to zero span id", mutate: func(span *model.Span) { span.References = []model.SpanRef{childOf(1, 0)} }, reason: rejectReasonInvalidReference},
+		{caption: "reference to itself", mutate: func(span *model.Span) { span.References = []model.SpanRef{childOf(1, 2), childOf(1, 1)} }, reason: rejectReasonInvalidReference},
+	}
+	for _, tc := range testCases {
+		mb := metrics.NewLocalFactory(time.Hour)
+		v := NewSpanValidator(5*time.Minute, &fakeClock{now: now}, mb)
+
+		span := validSpan()
+		tc.mutate(span)
+		if tc.reason == "" {
+			assert.True(t, v.Validate(span), tc.caption)
+			counters, _ := mb.Snapshot()
+			for _, c := range counters {
+				assert.EqualValues(t, 0, c, tc.caption)
+			}
+		} else {
+			assert.False(t, v.Validate(span), tc.caption)
+			counters, _ := mb.Snapshot()
+			assert.EqualValues(t, 1, counters["spans.rejected|reason="+tc.reason], tc.caption)
+		}
+	}
+}
+
+func childOf(traceID uint64, spanID uint64) model.SpanRef {
+	return model.SpanRef{RefType: model.ChildOf, TraceID: model.TraceID{Low: traceID}, SpanID: model.SpanID(spanID)}
+}
+
+func TestSpanValidatorNoClockSkewCheck(t *testing.T) {
+	v := NewSpanValidator(0, clock.System, metrics.NullFactory)
+	assert.True(t, v.Validate(&model.Span{
+		TraceID:   model.TraceID{Low: 1},
+		SpanID:    model.SpanID(1),
+		StartTime: time.Now().Add(24 * time.Hour),
+	}))
+}
+
+func TestSpanValidatorInBatch(t *testing.T) {
+	mb := metrics.NewLocalFactory(time.Hour)
+	v := NewSpanValidator(0, clock.System, mb.Namespace("host", nil))
+	store := memory.NewStore()
+	p := NewSpanProcessor(store,
+		Options.ServiceMetrics(mb.Namespace("service", nil)),
+		Options.SpanFilter(v.Validate),
+	)
+
+	res, err := p.ProcessSpans([]*model.Span{
+		{TraceID: model.TraceID{Low: 1}, SpanID: 1, Process: &model.Process{ServiceName: "x"}},
+		{TraceID: model.TraceID{Low: 1}, SpanID: 0, Process: &model.Process{ServiceName: "x"}},
+		{TraceID: model.TraceID{Low: 1}, SpanID: 2, Process: &model.Process{ServiceName: "x"}},
+	}, JaegerFormatType)
+	assert.NoError(t, err)
+	// rejected spans are reported as "not dropped", while the valid ones proceed to storage
+	assert.Equal(t, []bool{true, true, true}, res)
+	require.NoError(t, p.Close())
+
+	trace, err := store.GetTrace(model.TraceID{Low: 1})
+	require.NoError(t, err)
+	assert.Len(t, trace.Spans, 2)
+
+	counters, _ := mb.Snapshot()
+	assert.EqualValues(t, 1, counters["service.jaeger.spans.rejected"])
+	assert.EqualValues(t, 1, counters["host.spans.rejected|reason="+rejectReasonZeroSpanID])
+}
@@ -0,0 +1,147 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"sync"
+	"time"
+
+	"github.com/uber/jaeger-lib/metrics"
+
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/pkg/hyperloglog"
+)
+
+const (
+	// DefaultTagCardinalityMaxKeys is the default number of tag keys whose cardinality is measured
+	DefaultTagCardinalityMaxKeys = 100
+
+	// DefaultTagCardinalityWindow is the default window over which the distinct values of a tag key are counted
+	DefaultTagCardinalityWindow = 10 * time.Minute
+
+	// tagCardinalityPrecision estimates the cardinalities with a standard error of 1.6% in 4KiB per sketch
+	tagCardinalityPrecision = 12
+)
+
+// TagCardinality estimates how many distinct values every span tag key had over a sliding window, to
+// catch the tags that would blow up the indexes of the span storage, e.g. one holding a request ID.
+// The estimates are reported in the tag-cardinality gauge tagged with the key every half window.
+// Only the first maxKeys keys are measured, the occurrences of the other keys are counted in the
+// tag-cardinality.untracked-tags counter. The keys not seen for a whole window stop being measured.
+type TagCardinality struct {
+	maxKeys        int
+	interval       time.Duration
+	metricsFactory metrics.Factory
+	untracked      metrics.Counter
+
+	lock  sync.Mutex
+	keys  map[string]*tagKeyCardinality
+	union *hyperloglog.Sketch
+
+	stop chan struct{}
+	done sync.WaitGroup
+}
+
+// tagKeyCardinality holds the values of a tag key seen in the current and in the previous half window
+type tagKeyCardinality struct {
+	current  *hyperloglog.Sketch
+	previous *hyperloglog.Sketch
+	gauge    metrics.Gauge
+}
+
+// NewTagCardinality creates a TagCardinality that measures at most maxKeys tag keys over window
+func NewTagCardinality(window time.Duration, maxKeys int, metricsFactory metrics.Factory) *TagCardinality {
+	c := &TagCardinality{
+		maxKeys:        maxKeys,
+		interval:       window / 2,
+		metricsFactory: metricsFactory,
+		untracked:      metricsFactory.Counter("tag-cardinality.untracked-tags", nil),
+		keys:           make(map[string]*tagKeyCardinality),
+		union:          hyperloglog.New(tagCardinalityPrecision),
+		stop:           make(chan struct{}),
+	}
+	c.done.Add(1)
+	go c.report()
+	return c
+}
+
+// ProcessSpans adds the tag values of the spans to the estimates, it can be used as the PreProcessSpans option
+func (c *TagCardinality) ProcessSpans(spans []*model.Span) {
+	var untracked int64
+	c.lock.Lock()
+	for _, span := range spans {
+		for i := range span.Tags {
+			tag := &span.Tags[i]
+			key, ok := c.keys[tag.Key]
+			if !ok {
+				if len(c.keys) >= c.maxKeys {
+					untracked++
+					continue
+				}
+				key = &tagKeyCardinality{
+					current:  hyperloglog.New(tagCardinalityPrecision),
+					previous: hyperloglog.New(tagCardinalityPrecision),
+					gauge:    c.metricsFactory.Gauge("tag-cardinality", map[string]string{"key": tag.Key}),
+				}
+				c.keys[tag.Key] = key
+			}
+			key.current.Add(tag.AsString())
+		}
+	}
+	c.lock.Unlock()
+	if untracked > 0 {
+		c.untracked.Inc(untracked)
+	}
+}
+
+// Close stops reporting the estimates
+func (c *TagCardinality) Close() error {
+	close(c.stop)
+	c.done.Wait()
+	return nil
+}
+
+func (c *TagCardinality) report() {
+	defer c.done.Done()
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.rotate()
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+// rotate reports the estimates of the window that just ended and starts a new half window
+func (c *TagCardinality) rotate() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for name, key := range c.keys {
+		c.union.Reset()
+		c.union.Merge(key.previous)
+		c.union.Merge(key.current)
+		estimate := c.union.Estimate()
+		key.gauge.Update(int64(estimate))
+		if estimate == 0 {
+			delete(c.keys, name)
+			continue
+		}
+		key.previous, key.current = key.current, key.previous
+		key.current.Reset()
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics"
+
+	"github.com/uber/jaeger/model"
+)
+
+func TestTagCardinality(t *testing.T) {
+	metricsFactory := metrics.NewLocalFactory(0)
+	c := NewTagCardinality(time.Hour, 10, metricsFactory)
+	defer c.Close()
+
+	var spans []*model.Span
+	for i := 0; i < 5000; i++ {
+		spans = append(spans, &model.Span{Tags: model.KeyValues{
+			model.String("http.method", []string{"GET", "POST"}[i%2]),
+			model.String("request.id", "req-"+strconv.Itoa(i)),
+			model.Int64("user.id", int64(i%300)),
+		}})
+	}
+	c.ProcessSpans(spans[:2500])
+	c.rotate()
+	_, gauges := metricsFactory.Snapshot()
+	assert.EqualValues(t, 2, gauges["tag-cardinality|key=http.method"])
+	assert.InDelta(t, 2500, gauges["tag-cardinality|key=request.id"], 125)
+	assert.InDelta(t, 300, gauges["tag-cardinality|key=user.id"], 15)
+
+	// the estimates cover the previous half window too
+	c.ProcessSpans(spans[2500:])
+	c.rotate()
+	_, gauges = metricsFactory.Snapshot()
+	assert.EqualValues(t, 2, gauges["tag-cardinality|key=http.method"])
+	assert.InDelta(t, 5000, gauges["tag-cardinality|key=request.id"], 250)
+	assert.InDelta(t, 300, gauges["tag-cardinality|key=user.id"], 15)
+
+	// the values of the first half window slide out
+	c.rotate()
+	_, gauges = metricsFactory.Snapshot()
+	assert.InDelta(t, 2500, gauges["tag-cardinality|key=request.id"], 125)
+}
+
+func TestTagCardinalityExpiresKeys(t *testing.T) {
+	metricsFactory := metrics.NewLocalFactory(0)
+	c := NewTagCardinality(time.Hour, 10, metricsFactory)
+	defer c.Close()
+
+	c.ProcessSpans([]*model.Span{{Tags: model.KeyValues{model.String("a", "1")}}})
+	c.rotate()
+	c.rotate()
+	require.Len(t, c.keys, 1)
+	_, gauges := metricsFactory.Snapshot()
+	assert.EqualValues(t, 1, gauges["tag-cardinality|key=a"])
+
+	c.rotate()
+	assert.Empty(t, c.keys, "a key not seen for a whole window stops being measured")
+	_, gauges = metricsFactory.Snapshot()
+	assert.EqualValues(t, 0, gauges["tag-cardinality|key=a"])
+}
+
+func TestTagCardinalityMaxKeys(t *testing.T) {
+	metricsFactory := metrics.NewLocalFactory(0)
+	c := NewTagCardinality(time.Hour, 2, metricsFactory)
+	defer c.Close()
+
+	span := &model.Span{Tags: model.KeyValues{
+		model.String("a", "1"),
+		model.String("b", "1"),
+		model.String("c", "1"),
+		model.String("d", "1"),
+	}}
+	c.ProcessSpans([]*model.Span{span, span})
+	c.rotate()
+
+	counters, gauges := metricsFactory.Snapshot()
+	assert.EqualValues(t, 4, counters["tag-cardinality.untracked-tags"])
+	assert.EqualValues(t, 1, gauges["tag-cardinality|key=a"])
+	assert.EqualValues(t, 1, gauges["tag-cardinality|key=b"])
+	_, ok := gauges["tag-cardinality|key=c"]
+	assert.False(t, ok)
+}
+
+func TestTagCardinalityReports(t *testing.T) {
+	metricsFactory := metrics.NewLocalFactory(0)
+	c := NewTagCardinality(2*time.Millisecond, 10, metricsFactory)
+	c.ProcessSpans([]*model.Span{{Tags: model.KeyValues{model.String("a", "1")}}})
+	reported := false
+	for i := 0; i < 1000 && !reported; i++ {
+		time.Sleep(time.Millisecond)
+		_, gauges := metricsFactory.Snapshot()
+		_, reported = gauges["tag-cardinality|key=a"]
+	}
+	require.NoError(t, c.Close())
+	assert.True(t, reported, "the estimates are reported every half window")
+}
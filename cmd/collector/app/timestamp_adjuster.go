@@ -0,0 +1,74 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"time"
+
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/pkg/clock"
+)
+
+// ClockSkewAdjustedTagKey is the tag added to the spans whose start time was clamped because it was
+// too far in the future
+const ClockSkewAdjustedTagKey = "jaeger.clock-skew-adjusted"
+
+// TimestampAdjuster replaces the start time of the spans reported by clients with bad clocks. The
+// timestamps of the logs of a span are shifted by as much as its start time, so that they stay within it.
+type TimestampAdjuster struct {
+	// clamp denotes whether only the start times too far in the future are replaced, rather than all of them
+	clamp        bool
+	maxClockSkew time.Duration
+	clock        clock.Clock
+}
+
+// NewReceiveTimeAdjuster creates a TimestampAdjuster that starts every span at the time of clock when
+// the collector received it
+func NewReceiveTimeAdjuster(clock clock.Clock) *TimestampAdjuster {
+	return &TimestampAdjuster{clock: clock}
+}
+
+// NewClockSkewClamp creates a TimestampAdjuster that moves the spans starting more than maxClockSkew in
+// the future of clock back to maxClockSkew from the time the collector received them, and tags them with
+// ClockSkewAdjustedTagKey
+func NewClockSkewClamp(maxClockSkew time.Duration, clock clock.Clock) *TimestampAdjuster {
+	return &TimestampAdjuster{
+		clamp:        true,
+		maxClockSkew: maxClockSkew,
+		clock:        clock,
+	}
+}
+
+// ProcessSpans adjusts the start times of the spans, it can be used as the PreProcessSpans option
+func (a *TimestampAdjuster) ProcessSpans(spans []*model.Span) {
+	now := a.clock.Now()
+	for _, span := range spans {
+		if !a.clamp {
+			shiftSpan(span, now.Sub(span.StartTime))
+			continue
+		}
+		if latest := now.Add(a.maxClockSkew); span.StartTime.After(latest) {
+			shiftSpan(span, latest.Sub(span.StartTime))
+			span.Tags = append(span.Tags, model.Bool(ClockSkewAdjustedTagKey, true))
+		}
+	}
+}
+
+func shiftSpan(span *model.Span, delta time.Duration) {
+	span.StartTime = span.StartTime.Add(delta)
+	for i := range span.Logs {
+		span.Logs[i].Timestamp = span.Logs[i].Timestamp.Add(delta)
+	}
+}
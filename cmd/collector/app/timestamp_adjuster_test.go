@@ -0,0 +1,74 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/uber/jaeger/model"
+)
+
+func skewedSpan(startTime time.Time) *model.Span {
+	return &model.Span{
+		StartTime: startTime,
+		Duration:  time.Second,
+		Logs:      []model.Log{{Timestamp: startTime.Add(100 * time.Millisecond)}},
+	}
+}
+
+func TestReceiveTimeAdjuster(t *testing.T) {
+	now := time.Unix(1000, 0)
+	a := NewReceiveTimeAdjuster(&fakeClock{now: now})
+
+	spans := []*model.Span{skewedSpan(now.Add(time.Hour)), skewedSpan(now.Add(-time.Hour)), skewedSpan(now)}
+	a.ProcessSpans(spans)
+	for _, span := range spans {
+		assert.Equal(t, now, span.StartTime)
+		assert.Equal(t, time.Second, span.Duration)
+		assert.Equal(t, now.Add(100*time.Millisecond), span.Logs[0].Timestamp, "the logs are shifted with the span")
+		assert.Empty(t, span.Tags)
+	}
+}
+
+func TestClockSkewClamp(t *testing.T) {
+	now := time.Unix(1000, 0)
+	a := NewClockSkewClamp(5*time.Minute, &fakeClock{now: now})
+
+	testCases := []struct {
+		caption   string
+		startTime time.Time
+		expected  time.Time
+		adjusted  bool
+	}{
+		{caption: "in the past", startTime: now.Add(-time.Hour), expected: now.Add(-time.Hour)},
+		{caption: "within skew", startTime: now.Add(time.Minute), expected: now.Add(time.Minute)},
+		{caption: "at the skew", startTime: now.Add(5 * time.Minute), expected: now.Add(5 * time.Minute)},
+		{caption: "beyond skew", startTime: now.Add(time.Hour), expected: now.Add(5 * time.Minute), adjusted: true},
+	}
+	for _, tc := range testCases {
+		span := skewedSpan(tc.startTime)
+		a.ProcessSpans([]*model.Span{span})
+		assert.Equal(t, tc.expected, span.StartTime, tc.caption)
+		assert.Equal(t, tc.expected.Add(100*time.Millisecond), span.Logs[0].Timestamp, tc.caption)
+		if tc.adjusted {
+			assert.Equal(t, model.KeyValues{model.Bool(ClockSkewAdjustedTagKey, true)}, span.Tags, tc.caption)
+		} else {
+			assert.Empty(t, span.Tags, tc.caption)
+		}
+	}
+}
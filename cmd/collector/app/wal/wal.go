@@ -0,0 +1,395 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wal
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"go.uber.org/zap"
+
+	"github.com/uber/jaeger/model"
+	jConv "github.com/uber/jaeger/model/converter/thrift/jaeger"
+	"github.com/uber/jaeger/thrift-gen/jaeger"
+)
+
+const (
+	// DefaultSyncInterval is how often appended spans are synced to disk by default
+	DefaultSyncInterval = time.Second
+	// DefaultMaxSegmentBytes is the size from which a new segment file is started
+	DefaultMaxSegmentBytes = 16 * 1024 * 1024
+
+	segmentSuffix = ".wal"
+	// recordHeaderSize is the size of the length and the CRC32 checksum in front of each record
+	recordHeaderSize = 8
+)
+
+var (
+	errClosed         = errors.New("write-ahead log is closed")
+	errRecordTooLarge = errors.New("span is larger than a write-ahead log segment")
+)
+
+// Log is a write-ahead log of spans, which survive a crash of the collector until they are saved
+// to storage. Spans are appended to segment files, a segment file is deleted once all its spans
+// have been acknowledged, and the spans of the segments left by a previous process are replayed.
+//
+// Each record is the length and CRC32 checksum of the span, followed by the span encoded as
+// a single span jaeger.thrift Batch. A record is at most maxSegmentBytes long, and a torn record
+// at the end of a segment is ignored on replay.
+type Log struct {
+	dir             string
+	maxSegmentBytes int64
+	logger          *zap.Logger
+
+	mux     sync.Mutex
+	current *segment
+	// pending is the number of spans of each segment that have not been acknowledged
+	pending map[uint64]int
+	// replayable are the ids of the segments left by a previous process, in order
+	replayable []uint64
+	dirty      bool
+	closed     bool
+
+	stopSync chan struct{}
+	syncWG   sync.WaitGroup
+}
+
+type segment struct {
+	id   uint64
+	file *os.File
+	size int64
+	// torn is set when the bytes of a failed write could not be removed, no record is appended after them
+	torn bool
+}
+
+// Open opens the write-ahead log in dir, creating the directory if needed. Appended spans are
+// synced to disk every syncInterval, or after each span if syncInterval is 0.
+func Open(dir string, syncInterval time.Duration, maxSegmentBytes int64, logger *zap.Logger) (*Log, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	ids, err := listSegments(dir)
+	if err != nil {
+		return nil, err
+	}
+	var nextID uint64 = 1
+	if len(ids) > 0 {
+		nextID = ids[len(ids)-1] + 1
+	}
+	l := &Log{
+		dir:             dir,
+		maxSegmentBytes: maxSegmentBytes,
+		logger:          logger,
+		pending:         make(map[uint64]int),
+		replayable:      ids,
+	}
+	if l.current, err = l.createSegment(nextID); err != nil {
+		return nil, err
+	}
+	if syncInterval > 0 {
+		l.stopSync = make(chan struct{})
+		l.syncWG.Add(1)
+		go l.syncEvery(syncInterval)
+	}
+	return l, nil
+}
+
+func listSegments(dir string) ([]uint64, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var ids []uint64
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, segmentSuffix) {
+			continue
+		}
+		id, err := strconv.ParseUint(strings.TrimSuffix(name, segmentSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids, nil
+}
+
+func (l *Log) segmentPath(id uint64) string {
+	return filepath.Join(l.dir, fmt.Sprintf("%020d%s", id, segmentSuffix))
+}
+
+func (l *Log) createSegment(id uint64) (*segment, error) {
+	file, err := os.OpenFile(l.segmentPath(id), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &segment{id: id, file: file}, nil
+}
+
+// Replay calls fn with each span of the segments left by a previous process, with the id of its segment
+// to acknowledge it with. Replay must be called at most once, before the segments can be deleted.
+func (l *Log) Replay(fn func(span *model.Span, segmentID uint64)) error {
+	l.mux.Lock()
+	ids := l.replayable
+	l.replayable = nil
+	l.mux.Unlock()
+
+	for _, id := range ids {
+		spans, err := l.readSegment(id)
+		if err != nil {
+			return err
+		}
+		l.mux.Lock()
+		if len(spans) > 0 {
+			l.pending[id] = len(spans)
+		}
+		l.mux.Unlock()
+		if len(spans) == 0 {
+			l.removeSegment(id)
+			continue
+		}
+		for _, span := range spans {
+			fn(span, id)
+		}
+	}
+	return nil
+}
+
+func (l *Log) readSegment(id uint64) ([]*model.Span, error) {
+	file, err := os.Open(l.segmentPath(id))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	reader := bufio.NewReader(file)
+	var spans []*model.Span
+	header := make([]byte, recordHeaderSize)
+	for {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			if err != io.EOF {
+				l.logger.Warn("Ignoring torn record at the end of write-ahead log segment", zap.Uint64("segment", id), zap.Error(err))
+			}
+			return spans, nil
+		}
+		length := binary.BigEndian.Uint32(header[:4])
+		if int64(length) > l.maxSegmentBytes {
+			l.logger.Warn("Ignoring corrupt record in write-ahead log segment", zap.Uint64("segment", id), zap.Uint32("length", length))
+			return spans, nil
+		}
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(reader, payload); err != nil {
+			l.logger.Warn("Ignoring torn record at the end of write-ahead log segment", zap.Uint64("segment", id), zap.Error(err))
+			return spans, nil
+		}
+		if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:]) {
+			l.logger.Warn("Ignoring corrupt record in write-ahead log segment", zap.Uint64("segment", id))
+			return spans, nil
+		}
+		span, err := decodeSpan(payload)
+		if err != nil {
+			l.logger.Warn("Ignoring undecodable record in write-ahead log segment", zap.Uint64("segment", id), zap.Error(err))
+			return spans, nil
+		}
+		spans = append(spans, span)
+	}
+}
+
+func encodeSpan(span *model.Span) ([]byte, error) {
+	batch := &jaeger.Batch{
+		Process: jConv.FromDomainProcess(span.Process),
+		Spans:   []*jaeger.Span{jConv.FromDomainSpan(span)},
+	}
+	payload, err := thrift.NewTSerializer().Write(batch)
+	if err != nil {
+		return nil, err
+	}
+	record := make([]byte, recordHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(record[:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(record[4:recordHeaderSize], crc32.ChecksumIEEE(payload))
+	copy(record[recordHeaderSize:], payload)
+	return record, nil
+}
+
+func decodeSpan(payload []byte) (*model.Span, error) {
+	batch := &jaeger.Batch{}
+	if err := thrift.NewTDeserializer().Read(batch, payload); err != nil {
+		return nil, err
+	}
+	if len(batch.Spans) != 1 {
+		return nil, fmt.Errorf("expected 1 span, found %d", len(batch.Spans))
+	}
+	return jConv.ToDomainSpan(batch.Spans[0], batch.Process), nil
+}
+
+// Append writes span to the current segment and returns the id of the segment, which the span
+// is acknowledged with once it is saved.
+func (l *Log) Append(span *model.Span) (uint64, error) {
+	record, err := encodeSpan(span)
+	if err != nil {
+		return 0, err
+	}
+	if int64(len(record)) > l.maxSegmentBytes {
+		return 0, errRecordTooLarge
+	}
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	if l.closed {
+		return 0, errClosed
+	}
+	if l.current.torn || (l.current.size > 0 && l.current.size+int64(len(record)) > l.maxSegmentBytes) {
+		if err := l.roll(); err != nil {
+			return 0, err
+		}
+	}
+	if _, err := l.current.file.Write(record); err != nil {
+		l.rewind()
+		return 0, err
+	}
+	l.current.size += int64(len(record))
+	if l.stopSync == nil {
+		if err := l.current.file.Sync(); err != nil {
+			return 0, err
+		}
+	} else {
+		l.dirty = true
+	}
+	l.pending[l.current.id]++
+	return l.current.id, nil
+}
+
+// rewind removes the bytes of a failed write from the current segment, or starts the next segment if
+// it cannot, so that the next record does not follow a torn one. The caller must hold mux.
+func (l *Log) rewind() {
+	err := l.current.file.Truncate(l.current.size)
+	if err == nil {
+		_, err = l.current.file.Seek(l.current.size, io.SeekStart)
+	}
+	if err == nil {
+		return
+	}
+	l.logger.Error("Failed to truncate write-ahead log segment", zap.Uint64("segment", l.current.id), zap.Error(err))
+	l.current.torn = true
+	if err := l.roll(); err != nil {
+		l.logger.Error("Failed to start write-ahead log segment", zap.Uint64("segment", l.current.id+1), zap.Error(err))
+	}
+}
+
+// roll closes the current segment and starts the next one, the caller must hold mux
+func (l *Log) roll() error {
+	previous := l.current
+	next, err := l.createSegment(previous.id + 1)
+	if err != nil {
+		return err
+	}
+	l.current = next
+	l.dirty = false
+	if err := previous.file.Sync(); err != nil {
+		l.logger.Error("Failed to sync write-ahead log segment", zap.Uint64("segment", previous.id), zap.Error(err))
+	}
+	previous.file.Close()
+	if l.pending[previous.id] == 0 {
+		delete(l.pending, previous.id)
+		l.removeSegment(previous.id)
+	}
+	return nil
+}
+
+// Ack acknowledges that a span of the segment was saved, the segment is deleted once all its spans are.
+func (l *Log) Ack(segmentID uint64) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	l.pending[segmentID]--
+	if l.pending[segmentID] > 0 {
+		return
+	}
+	delete(l.pending, segmentID)
+	if segmentID != l.current.id {
+		l.removeSegment(segmentID)
+	}
+}
+
+func (l *Log) removeSegment(id uint64) {
+	if err := os.Remove(l.segmentPath(id)); err != nil {
+		l.logger.Error("Failed to delete write-ahead log segment", zap.Uint64("segment", id), zap.Error(err))
+	}
+}
+
+func (l *Log) syncEvery(interval time.Duration) {
+	defer l.syncWG.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			l.sync()
+		case <-l.stopSync:
+			return
+		}
+	}
+}
+
+func (l *Log) sync() {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	if !l.dirty || l.closed {
+		return
+	}
+	if err := l.current.file.Sync(); err != nil {
+		l.logger.Error("Failed to sync write-ahead log segment", zap.Uint64("segment", l.current.id), zap.Error(err))
+		return
+	}
+	l.dirty = false
+}
+
+// Close syncs and closes the current segment, which is deleted if all its spans were acknowledged.
+// The segments with spans that were not acknowledged are kept to be replayed by the next process.
+func (l *Log) Close() error {
+	l.mux.Lock()
+	if l.closed {
+		l.mux.Unlock()
+		return nil
+	}
+	l.closed = true
+	l.mux.Unlock()
+	if l.stopSync != nil {
+		close(l.stopSync)
+		l.syncWG.Wait()
+	}
+
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	err := l.current.file.Sync()
+	if closeErr := l.current.file.Close(); err == nil {
+		err = closeErr
+	}
+	if l.pending[l.current.id] == 0 {
+		l.removeSegment(l.current.id)
+	}
+	return err
+}
@@ -0,0 +1,298 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wal
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/uber/jaeger/model"
+)
+
+func newTestSpan(spanID uint64) *model.Span {
+	return &model.Span{
+		TraceID:       model.TraceID{Low: 42},
+		SpanID:        model.SpanID(spanID),
+		OperationName: "operation",
+		Tags:          []model.KeyValue{model.String("key", "value")},
+		Process:       &model.Process{ServiceName: "service"},
+	}
+}
+
+// recordSize is the size of the record of a test span, the segments of that size hold a single span
+func recordSize(t *testing.T) int64 {
+	record, err := encodeSpan(newTestSpan(1))
+	require.NoError(t, err)
+	return int64(len(record))
+}
+
+func openTestLog(t *testing.T, dir string, maxSegmentBytes int64) *Log {
+	l, err := Open(dir, 0, maxSegmentBytes, zap.NewNop())
+	require.NoError(t, err)
+	return l
+}
+
+// crash closes the file of the current segment without the bookkeeping of Close
+func crash(l *Log) {
+	l.current.file.Close()
+}
+
+func segmentFiles(t *testing.T, dir string) []string {
+	files, err := filepath.Glob(filepath.Join(dir, "*"+segmentSuffix))
+	require.NoError(t, err)
+	return files
+}
+
+func replayAll(t *testing.T, l *Log) ([]*model.Span, []uint64) {
+	var spans []*model.Span
+	var segmentIDs []uint64
+	require.NoError(t, l.Replay(func(span *model.Span, segmentID uint64) {
+		spans = append(spans, span)
+		segmentIDs = append(segmentIDs, segmentID)
+	}))
+	return spans, segmentIDs
+}
+
+func TestLogAckDeletesSegments(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wal")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// every span goes to a segment of its own
+	l := openTestLog(t, dir, recordSize(t))
+	var segmentIDs []uint64
+	for spanID := uint64(1); spanID <= 3; spanID++ {
+		segmentID, err := l.Append(newTestSpan(spanID))
+		require.NoError(t, err)
+		segmentIDs = append(segmentIDs, segmentID)
+	}
+	assert.Equal(t, []uint64{1, 2, 3}, segmentIDs)
+	assert.Len(t, segmentFiles(t, dir), 3)
+
+	l.Ack(1)
+	assert.Len(t, segmentFiles(t, dir), 2)
+	l.Ack(3)
+	assert.Len(t, segmentFiles(t, dir), 2, "the current segment is kept until it is closed")
+
+	require.NoError(t, l.Close())
+	assert.Equal(t, []string{l.segmentPath(2)}, segmentFiles(t, dir), "the segment with a span that was not saved is kept")
+	_, err = l.Append(newTestSpan(4))
+	assert.Equal(t, errClosed, err)
+	assert.NoError(t, l.Close())
+}
+
+func TestLogReplayAfterCrash(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wal")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	l := openTestLog(t, dir, recordSize(t))
+	for spanID := uint64(1); spanID <= 3; spanID++ {
+		_, err := l.Append(newTestSpan(spanID))
+		require.NoError(t, err)
+	}
+	l.Ack(1)
+	crash(l)
+
+	l = openTestLog(t, dir, DefaultMaxSegmentBytes)
+	defer l.Close()
+	spans, segmentIDs := replayAll(t, l)
+	require.Len(t, spans, 2)
+	assert.Equal(t, []uint64{2, 3}, segmentIDs)
+	for i, span := range spans {
+		assert.Equal(t, model.TraceID{Low: 42}, span.TraceID)
+		assert.Equal(t, model.SpanID(i+2), span.SpanID)
+		assert.Equal(t, "operation", span.OperationName)
+		assert.Equal(t, "service", span.Process.ServiceName)
+		assert.Equal(t, "value", span.Tags[0].AsString())
+	}
+
+	segmentID, err := l.Append(newTestSpan(4))
+	require.NoError(t, err)
+	assert.EqualValues(t, 4, segmentID, "new segments follow the replayed ones")
+	for _, segmentID := range segmentIDs {
+		l.Ack(segmentID)
+	}
+	assert.Equal(t, []string{l.segmentPath(4)}, segmentFiles(t, dir))
+
+	spans, _ = replayAll(t, l)
+	assert.Empty(t, spans, "the segments are only replayed once")
+}
+
+func TestLogReplayTornRecord(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wal")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	l := openTestLog(t, dir, DefaultMaxSegmentBytes)
+	for spanID := uint64(1); spanID <= 2; spanID++ {
+		_, err := l.Append(newTestSpan(spanID))
+		require.NoError(t, err)
+	}
+	crash(l)
+	info, err := os.Stat(l.segmentPath(1))
+	require.NoError(t, err)
+	require.NoError(t, os.Truncate(l.segmentPath(1), info.Size()-3))
+
+	l = openTestLog(t, dir, DefaultMaxSegmentBytes)
+	defer l.Close()
+	spans, _ := replayAll(t, l)
+	require.Len(t, spans, 1)
+	assert.Equal(t, model.SpanID(1), spans[0].SpanID)
+}
+
+func TestLogReplayCorruptRecord(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wal")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	l := openTestLog(t, dir, DefaultMaxSegmentBytes)
+	_, err = l.Append(newTestSpan(1))
+	require.NoError(t, err)
+	crash(l)
+	data, err := ioutil.ReadFile(l.segmentPath(1))
+	require.NoError(t, err)
+	data[len(data)-1]++
+	require.NoError(t, ioutil.WriteFile(l.segmentPath(1), data, 0644))
+
+	l = openTestLog(t, dir, DefaultMaxSegmentBytes)
+	defer l.Close()
+	spans, _ := replayAll(t, l)
+	assert.Empty(t, spans)
+	assert.Equal(t, []string{l.segmentPath(2)}, segmentFiles(t, dir), "the segment without spans is deleted")
+}
+
+func TestLogReplayOversizedRecord(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wal")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	l := openTestLog(t, dir, DefaultMaxSegmentBytes)
+	_, err = l.Append(newTestSpan(1))
+	require.NoError(t, err)
+	crash(l)
+	data, err := ioutil.ReadFile(l.segmentPath(1))
+	require.NoError(t, err)
+	data[0], data[1], data[2], data[3] = 0xff, 0xff, 0xff, 0xff
+	require.NoError(t, ioutil.WriteFile(l.segmentPath(1), data, 0644))
+
+	l = openTestLog(t, dir, DefaultMaxSegmentBytes)
+	defer l.Close()
+	spans, _ := replayAll(t, l)
+	assert.Empty(t, spans, "the length of the record is not trusted")
+}
+
+func TestLogAppendTooLarge(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wal")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	l := openTestLog(t, dir, recordSize(t)-1)
+	defer l.Close()
+	_, err = l.Append(newTestSpan(1))
+	assert.Equal(t, errRecordTooLarge, err)
+}
+
+func TestLogAppendRewindsFailedWrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wal")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	l := openTestLog(t, dir, DefaultMaxSegmentBytes)
+	_, err = l.Append(newTestSpan(1))
+	require.NoError(t, err)
+	// the bytes of a write that failed midway
+	_, err = l.current.file.Write([]byte{0, 0, 1})
+	require.NoError(t, err)
+	l.rewind()
+	segmentID, err := l.Append(newTestSpan(2))
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, segmentID)
+	crash(l)
+
+	l = openTestLog(t, dir, DefaultMaxSegmentBytes)
+	defer l.Close()
+	spans, _ := replayAll(t, l)
+	require.Len(t, spans, 2, "the span appended after the failed write is replayed")
+	assert.Equal(t, model.SpanID(2), spans[1].SpanID)
+}
+
+func TestLogAppendRollsAfterFailedWrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wal")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	l := openTestLog(t, dir, DefaultMaxSegmentBytes)
+	_, err = l.Append(newTestSpan(1))
+	require.NoError(t, err)
+	// neither writes nor truncates succeed on a read-only file
+	l.current.file.Close()
+	l.current.file, err = os.Open(l.segmentPath(1))
+	require.NoError(t, err)
+	_, err = l.Append(newTestSpan(2))
+	assert.Error(t, err)
+	segmentID, err := l.Append(newTestSpan(3))
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, segmentID, "the span after the failed write goes to the next segment")
+	crash(l)
+
+	l = openTestLog(t, dir, DefaultMaxSegmentBytes)
+	defer l.Close()
+	spans, _ := replayAll(t, l)
+	require.Len(t, spans, 2)
+	assert.Equal(t, model.SpanID(1), spans[0].SpanID)
+	assert.Equal(t, model.SpanID(3), spans[1].SpanID)
+}
+
+func TestLogSyncInterval(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wal")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	l, err := Open(dir, time.Millisecond, DefaultMaxSegmentBytes, zap.NewNop())
+	require.NoError(t, err)
+	_, err = l.Append(newTestSpan(1))
+	require.NoError(t, err)
+	for i := 0; i < 1000; i++ {
+		l.mux.Lock()
+		dirty := l.dirty
+		l.mux.Unlock()
+		if !dirty {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	l.mux.Lock()
+	assert.False(t, l.dirty, "the appended span is synced")
+	l.mux.Unlock()
+	require.NoError(t, l.Close())
+}
+
+func TestOpenFailure(t *testing.T) {
+	file, err := ioutil.TempFile("", "wal")
+	require.NoError(t, err)
+	file.Close()
+	defer os.Remove(file.Name())
+
+	_, err = Open(file.Name(), 0, DefaultMaxSegmentBytes, zap.NewNop())
+	assert.Error(t, err)
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zipkin
+
+import (
+	"net/http"
+	"strings"
+)
+
+// DefaultCORSAllowedHeaders are the request headers that browsers are allowed to send by default,
+// Content-Type must be allowed for them to post JSON
+var DefaultCORSAllowedHeaders = []string{"Content-Type"}
+
+// corsPolicy answers CORS requests from browsers, so that tracers running in web pages can post spans
+type corsPolicy struct {
+	allowAnyOrigin bool
+	allowedOrigins map[string]struct{}
+	allowedHeaders string
+}
+
+func newCORSPolicy(allowedOrigins []string, allowedHeaders []string) *corsPolicy {
+	if len(allowedOrigins) == 0 {
+		return nil
+	}
+	policy := &corsPolicy{
+		allowedOrigins: make(map[string]struct{}, len(allowedOrigins)),
+		allowedHeaders: strings.Join(allowedHeaders, ", "),
+	}
+	for _, origin := range allowedOrigins {
+		if origin == "*" {
+			policy.allowAnyOrigin = true
+		}
+		policy.allowedOrigins[origin] = struct{}{}
+	}
+	return policy
+}
+
+func (p *corsPolicy) isAllowed(origin string) bool {
+	if p.allowAnyOrigin {
+		return true
+	}
+	_, ok := p.allowedOrigins[origin]
+	return ok
+}
+
+// setAllowOrigin lets the browser hand the response to the page if the request's origin is allowed
+func (p *corsPolicy) setAllowOrigin(w http.ResponseWriter, r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	w.Header().Add("Vary", "Origin")
+	if origin == "" || !p.isAllowed(origin) {
+		return false
+	}
+	w.Header().Set("Access-Control-Allow-Origin", origin)
+	return true
+}
+
+// preflight answers the OPTIONS request that browsers send before posting spans from another origin
+func (p *corsPolicy) preflight(w http.ResponseWriter, r *http.Request) {
+	if !p.setAllowOrigin(w, r) {
+		http.Error(w, "Origin not allowed", http.StatusForbidden)
+		return
+	}
+	w.Header().Set("Access-Control-Allow-Methods", http.MethodPost)
+	if p.allowedHeaders != "" {
+		w.Header().Set("Access-Control-Allow-Headers", p.allowedHeaders)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// allow returns a handler function that adds the CORS headers to the responses to allowed origins.
+// A nil corsPolicy does not add anything.
+func (p *corsPolicy) allow(handler http.HandlerFunc) http.HandlerFunc {
+	if p == nil {
+		return handler
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		p.setAllowOrigin(w, r)
+		handler(w, r)
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zipkin
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/jaeger/thrift-gen/zipkincore"
+)
+
+func initializeCORSTestServer(allowedOrigins []string, allowedHeaders []string) (*httptest.Server, *mockZipkinHandler) {
+	zipkinHandler := &mockZipkinHandler{}
+	r := mux.NewRouter()
+	NewAPIHandler(zipkinHandler, HandlerOptions.CORS(allowedOrigins, allowedHeaders)).RegisterRoutes(r)
+	return httptest.NewServer(r), zipkinHandler
+}
+
+func preflight(t *testing.T, urlStr string, origin string) *http.Response {
+	req, err := http.NewRequest(http.MethodOptions, urlStr, nil)
+	require.NoError(t, err)
+	req.Header.Set("Origin", origin)
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	req.Header.Set("Access-Control-Request-Headers", "Content-Type")
+	res, err := httpClient.Do(req)
+	require.NoError(t, err)
+	res.Body.Close()
+	return res
+}
+
+func TestCORSPreflight(t *testing.T) {
+	server, _ := initializeCORSTestServer([]string{"http://example.com"}, []string{"Content-Type", "X-Requested-With"})
+	defer server.Close()
+
+	for _, endpoint := range []string{"/api/v1/spans", "/api/v2/spans"} {
+		res := preflight(t, server.URL+endpoint, "http://example.com")
+		assert.EqualValues(t, http.StatusNoContent, res.StatusCode, endpoint)
+		assert.Equal(t, "http://example.com", res.Header.Get("Access-Control-Allow-Origin"), endpoint)
+		assert.Equal(t, http.MethodPost, res.Header.Get("Access-Control-Allow-Methods"), endpoint)
+		assert.Equal(t, "Content-Type, X-Requested-With", res.Header.Get("Access-Control-Allow-Headers"), endpoint)
+		assert.Equal(t, "Origin", res.Header.Get("Vary"), endpoint)
+
+		res = preflight(t, server.URL+endpoint, "http://evil.com")
+		assert.EqualValues(t, http.StatusForbidden, res.StatusCode, endpoint)
+		assert.Empty(t, res.Header.Get("Access-Control-Allow-Origin"), endpoint)
+		assert.Empty(t, res.Header.Get("Access-Control-Allow-Methods"), endpoint)
+	}
+}
+
+func TestCORSAnyOrigin(t *testing.T) {
+	server, _ := initializeCORSTestServer([]string{"*"}, DefaultCORSAllowedHeaders)
+	defer server.Close()
+
+	res := preflight(t, server.URL+"/api/v2/spans", "http://example.com")
+	assert.EqualValues(t, http.StatusNoContent, res.StatusCode)
+	assert.Equal(t, "http://example.com", res.Header.Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "Content-Type", res.Header.Get("Access-Control-Allow-Headers"))
+}
+
+func TestCORSPost(t *testing.T) {
+	server, zipkinHandler := initializeCORSTestServer([]string{"http://example.com"}, nil)
+	defer server.Close()
+
+	bodyBytes := zipkinSerialize([]*zipkincore.Span{{ID: 12345}})
+	for _, test := range []struct {
+		origin      string
+		allowOrigin string
+	}{
+		{origin: "http://example.com", allowOrigin: "http://example.com"},
+		{origin: "http://evil.com", allowOrigin: ""},
+		{origin: "", allowOrigin: ""},
+	} {
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/api/v1/spans", bytes.NewReader(bodyBytes))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/x-thrift")
+		if test.origin != "" {
+			req.Header.Set("Origin", test.origin)
+		}
+		res, err := httpClient.Do(req)
+		require.NoError(t, err)
+		res.Body.Close()
+		assert.EqualValues(t, http.StatusAccepted, res.StatusCode, test.origin)
+		assert.Equal(t, test.allowOrigin, res.Header.Get("Access-Control-Allow-Origin"), test.origin)
+		assert.Equal(t, "Origin", res.Header.Get("Vary"), test.origin)
+	}
+	waitForSpans(t, zipkinHandler, 3)
+}
+
+func TestCORSDisabled(t *testing.T) {
+	server, _ := initializeCORSTestServer(nil, DefaultCORSAllowedHeaders)
+	defer server.Close()
+
+	res := preflight(t, server.URL+"/api/v2/spans", "http://example.com")
+	assert.NotEqual(t, http.StatusNoContent, res.StatusCode)
+	assert.Empty(t, res.Header.Get("Access-Control-Allow-Origin"))
+}
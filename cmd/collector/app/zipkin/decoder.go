@@ -0,0 +1,70 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zipkin
+
+import (
+	"github.com/uber/jaeger/cmd/collector/app"
+	zipkinS "github.com/uber/jaeger/cmd/collector/app/sanitizer/zipkin"
+	"github.com/uber/jaeger/model"
+	zConv "github.com/uber/jaeger/model/converter/thrift/zipkin"
+	"github.com/uber/jaeger/thrift-gen/zipkincore"
+)
+
+const (
+	// ThriftContentType is the Content-Type of a list of Zipkin Thrift spans
+	ThriftContentType = "application/x-thrift"
+	// JSONContentType is the Content-Type of a list of Zipkin JSON v1 spans
+	JSONContentType = "application/json"
+	// MsgpackContentType is the Content-Type of a list of Zipkin spans encoded with msgpack, with the same fields as JSON spans
+	MsgpackContentType = "application/x-msgpack"
+)
+
+func init() {
+	app.RegisterDecoder(ThriftContentType, newSpanDecoder(deserializeThrift))
+	app.RegisterDecoder(JSONContentType, newSpanDecoder(DeserializeJSON))
+	app.RegisterDecoder(MsgpackContentType, newSpanDecoder(DeserializeMsgpack))
+}
+
+// spanDecoder converts Zipkin spans to the model after applying the same sanitizers as the
+// Zipkin span handler
+type spanDecoder struct {
+	deserialize func(body []byte) ([]*zipkincore.Span, error)
+	sanitizer   zipkinS.Sanitizer
+}
+
+func newSpanDecoder(deserialize func(body []byte) ([]*zipkincore.Span, error)) spanDecoder {
+	return spanDecoder{
+		deserialize: deserialize,
+		sanitizer:   zipkinS.NewChainedSanitizer(zipkinS.NewStandardSanitizers()...),
+	}
+}
+
+func (d spanDecoder) Decode(body []byte) ([]*model.Span, error) {
+	zSpans, err := d.deserialize(body)
+	if err != nil {
+		return nil, err
+	}
+	var spans []*model.Span
+	for _, zSpan := range zSpans {
+		// the spans are valid even when there is an error, it only describes issues in the data
+		mSpans, _ := zConv.ToDomainSpan(d.sanitizer.Sanitize(zSpan))
+		spans = append(spans, mSpans...)
+	}
+	return spans, nil
+}
+
+func (d spanDecoder) SpanFormat() string {
+	return app.ZipkinFormatType
+}
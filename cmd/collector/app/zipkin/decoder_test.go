@@ -0,0 +1,63 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zipkin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/jaeger/cmd/collector/app"
+	"github.com/uber/jaeger/thrift-gen/zipkincore"
+)
+
+func TestRegisteredDecoders(t *testing.T) {
+	for _, contentType := range []string{ThriftContentType, JSONContentType, MsgpackContentType} {
+		decoder, ok := app.LookupDecoder(contentType)
+		require.True(t, ok, contentType)
+		assert.Equal(t, app.ZipkinFormatType, decoder.SpanFormat())
+	}
+}
+
+func TestDecodeThrift(t *testing.T) {
+	decoder, ok := app.LookupDecoder(ThriftContentType)
+	require.True(t, ok)
+
+	negativeDuration := int64(-1)
+	body := zipkinSerialize([]*zipkincore.Span{{ID: 12345, Name: "foo", Duration: &negativeDuration}})
+	spans, err := decoder.Decode(body)
+	require.NoError(t, err)
+	require.Len(t, spans, 1)
+	assert.Equal(t, "foo", spans[0].OperationName)
+	assert.Equal(t, time.Microsecond, spans[0].Duration, "the span is sanitized")
+
+	_, err = decoder.Decode([]byte("not thrift"))
+	assert.Error(t, err)
+}
+
+func TestDecodeJSON(t *testing.T) {
+	decoder, ok := app.LookupDecoder(JSONContentType)
+	require.True(t, ok)
+
+	spans, err := decoder.Decode([]byte(createSpan("bar", "1", "2", "3", 156, 15145, false, "", "")))
+	require.NoError(t, err)
+	require.Len(t, spans, 1)
+	assert.Equal(t, "bar", spans[0].OperationName)
+
+	_, err = decoder.Decode([]byte(""))
+	assert.Error(t, err)
+}
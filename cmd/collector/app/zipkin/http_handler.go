@@ -15,15 +15,15 @@
 package zipkin
 
 import (
-	"compress/gzip"
 	"fmt"
 	"io/ioutil"
+	"mime"
 	"net/http"
-	"strings"
 	"time"
 
 	"github.com/apache/thrift/lib/go/thrift"
 	"github.com/gorilla/mux"
+	"github.com/uber/jaeger-lib/metrics"
 	tchanThrift "github.com/uber/tchannel-go/thrift"
 
 	"github.com/uber/jaeger/cmd/collector/app"
@@ -33,61 +33,176 @@ import (
 // APIHandler handles all HTTP calls to the collector
 type APIHandler struct {
 	zipkinSpansHandler app.ZipkinSpansHandler
+	bodyLimiter        *app.RequestBodyLimiter
+	cors               *corsPolicy
+	idValidator        *idValidator
+}
+
+// HandlerOption is a function that sets some option on the APIHandler
+type HandlerOption func(handler *APIHandler)
+
+// HandlerOptions is a factory for all available HandlerOptions
+var HandlerOptions handlerOptions
+
+type handlerOptions struct{}
+
+// RequestBodyLimiter creates a HandlerOption that limits the size of the request bodies accepted by the APIHandler
+func (handlerOptions) RequestBodyLimiter(bodyLimiter *app.RequestBodyLimiter) HandlerOption {
+	return func(apiHandler *APIHandler) {
+		apiHandler.bodyLimiter = bodyLimiter
+	}
+}
+
+// CORS creates a HandlerOption that lets web pages from the allowed origins post spans to the APIHandler,
+// sending the allowed request headers. An origin of * allows any origin, and no origins disable CORS.
+func (handlerOptions) CORS(allowedOrigins []string, allowedHeaders []string) HandlerOption {
+	return func(apiHandler *APIHandler) {
+		apiHandler.cors = newCORSPolicy(allowedOrigins, allowedHeaders)
+	}
+}
+
+// StrictIDs creates a HandlerOption that rejects the JSON spans with trace or span IDs that are not 16 or
+// 32 hex characters long, counting the rejected spans in metricsFactory. Thrift spans carry their IDs as
+// numbers and are not affected.
+func (handlerOptions) StrictIDs(metricsFactory metrics.Factory) HandlerOption {
+	return func(apiHandler *APIHandler) {
+		apiHandler.idValidator = newIDValidator(metricsFactory)
+	}
 }
 
 // NewAPIHandler returns a new APIHandler
 func NewAPIHandler(
 	zipkinSpansHandler app.ZipkinSpansHandler,
+	options ...HandlerOption,
 ) *APIHandler {
-	return &APIHandler{
+	aH := &APIHandler{
 		zipkinSpansHandler: zipkinSpansHandler,
 	}
+	for _, option := range options {
+		option(aH)
+	}
+	return aH
 }
 
 // RegisterRoutes registers Zipkin routes
 func (aH *APIHandler) RegisterRoutes(router *mux.Router) {
-	router.HandleFunc("/api/v1/spans", aH.saveSpans).Methods(http.MethodPost)
+	router.HandleFunc("/api/v1/spans", aH.cors.allow(aH.bodyLimiter.Limit(aH.saveSpans))).Methods(http.MethodPost)
+	router.HandleFunc("/api/v2/spans", aH.cors.allow(aH.bodyLimiter.Limit(aH.saveSpansV2))).Methods(http.MethodPost)
+	if aH.cors != nil {
+		router.HandleFunc("/api/v1/spans", aH.cors.preflight).Methods(http.MethodOptions)
+		router.HandleFunc("/api/v2/spans", aH.cors.preflight).Methods(http.MethodOptions)
+	}
 }
 
 func (aH *APIHandler) saveSpans(w http.ResponseWriter, r *http.Request) {
-	bRead := r.Body
-	defer r.Body.Close()
+	bodyBytes, ok := readBody(w, r)
+	if !ok {
+		return
+	}
 
-	if strings.Contains(r.Header.Get("Content-Encoding"), "gzip") {
-		gz, err := gzip.NewReader(r.Body)
-		if err != nil {
-			http.Error(w, fmt.Sprintf(app.UnableToReadBodyErrFormat, err), http.StatusBadRequest)
-			return
+	contentType := mediaType(r)
+	var tSpans []*zipkincore.Span
+	var err error
+	if contentType == ThriftContentType {
+		tSpans, err = deserializeThrift(bodyBytes)
+	} else if contentType == JSONContentType {
+		tSpans, err = aH.deserializeJSON(bodyBytes)
+	} else if contentType == MsgpackContentType {
+		if bodyBytes, err = msgpackToJSON(bodyBytes); err == nil {
+			tSpans, err = aH.deserializeJSON(bodyBytes)
 		}
-		defer gz.Close()
-		bRead = gz
+	} else {
+		http.Error(w, "Unsupported Content-Type", http.StatusBadRequest)
+		return
 	}
-
-	bodyBytes, err := ioutil.ReadAll(bRead)
 	if err != nil {
-		http.Error(w, fmt.Sprintf(app.UnableToReadBodyErrFormat, err), http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf(app.UnableToReadBodyErrFormat, err), http.StatusBadRequest)
 		return
 	}
 
-	contentType := r.Header.Get("Content-Type")
-	var tSpans []*zipkincore.Span
-	if contentType == "application/x-thrift" {
-		tSpans, err = deserializeThrift(bodyBytes)
-	} else if contentType == "application/json" {
-		tSpans, err = DeserializeJSON(bodyBytes)
-	} else {
+	aH.submitSpans(w, tSpans)
+}
+
+func (aH *APIHandler) saveSpansV2(w http.ResponseWriter, r *http.Request) {
+	bodyBytes, ok := readBody(w, r)
+	if !ok {
+		return
+	}
+
+	contentType := mediaType(r)
+	if contentType == MsgpackContentType {
+		var err error
+		if bodyBytes, err = msgpackToJSON(bodyBytes); err != nil {
+			http.Error(w, fmt.Sprintf(app.UnableToReadBodyErrFormat, err), http.StatusBadRequest)
+			return
+		}
+	} else if contentType != JSONContentType {
 		http.Error(w, "Unsupported Content-Type", http.StatusBadRequest)
 		return
 	}
+	tSpans, err := aH.deserializeJSONV2(bodyBytes)
 	if err != nil {
 		http.Error(w, fmt.Sprintf(app.UnableToReadBodyErrFormat, err), http.StatusBadRequest)
 		return
 	}
 
+	aH.submitSpans(w, tSpans)
+}
+
+func (aH *APIHandler) deserializeJSON(body []byte) ([]*zipkincore.Span, error) {
+	spans, err := decode(body)
+	if err != nil {
+		return nil, err
+	}
+	if err := aH.idValidator.validateSpans(spans); err != nil {
+		return nil, err
+	}
+	return spansToThrift(spans)
+}
+
+func (aH *APIHandler) deserializeJSONV2(body []byte) ([]*zipkincore.Span, error) {
+	spans, err := decodeV2(body)
+	if err != nil {
+		return nil, err
+	}
+	if err := aH.idValidator.validateSpansV2(spans); err != nil {
+		return nil, err
+	}
+	return spansV2ToThrift(spans)
+}
+
+// mediaType returns the media type of the request's Content-Type, without parameters such as the charset
+func mediaType(r *http.Request) string {
+	contentType := r.Header.Get("Content-Type")
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return contentType
+	}
+	return mediaType
+}
+
+// readBody reads the request body, which the gzipfilter has already decompressed. If it fails it writes
+// the error response and returns false.
+func readBody(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	defer r.Body.Close()
+
+	bodyBytes, err := ioutil.ReadAll(r.Body)
+	if err == app.ErrRequestBodyTooLarge {
+		http.Error(w, fmt.Sprintf(app.UnableToReadBodyErrFormat, err), http.StatusRequestEntityTooLarge)
+		return nil, false
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf(app.UnableToReadBodyErrFormat, err), http.StatusInternalServerError)
+		return nil, false
+	}
+	return bodyBytes, true
+}
+
+func (aH *APIHandler) submitSpans(w http.ResponseWriter, tSpans []*zipkincore.Span) {
 	if len(tSpans) > 0 {
 		ctx, _ := tchanThrift.NewContext(time.Minute)
-		if _, err = aH.zipkinSpansHandler.SubmitZipkinBatch(ctx, tSpans); err != nil {
-			http.Error(w, fmt.Sprintf("Cannot submit Zipkin batch: %v", err), http.StatusInternalServerError)
+		if _, err := aH.zipkinSpansHandler.SubmitZipkinBatch(ctx, tSpans); err != nil {
+			app.WriteSubmitError(w, "Cannot submit Zipkin batch: %v", err)
 			return
 		}
 	}
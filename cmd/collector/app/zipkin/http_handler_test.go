@@ -16,7 +16,6 @@ package zipkin
 
 import (
 	"bytes"
-	"compress/gzip"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -31,8 +30,11 @@ import (
 	"github.com/stretchr/testify/require"
 	jaegerClient "github.com/uber/jaeger-client-go"
 	zipkinTransport "github.com/uber/jaeger-client-go/transport/zipkin"
+	"github.com/uber/jaeger-lib/metrics"
+	"github.com/uber/tchannel-go"
 	tchanThrift "github.com/uber/tchannel-go/thrift"
 
+	"github.com/uber/jaeger/cmd/collector/app"
 	"github.com/uber/jaeger/thrift-gen/zipkincore"
 )
 
@@ -119,6 +121,40 @@ func TestThriftFormat(t *testing.T) {
 	assert.EqualValues(t, "Cannot submit Zipkin batch: Bad times ahead\n", resBodyStr)
 }
 
+func TestThriftSpanList(t *testing.T) {
+	server, handler := initializeTestServer(nil)
+	defer server.Close()
+
+	parentID := int64(1)
+	spans := []*zipkincore.Span{
+		{TraceID: 1, ID: 1, Name: "get"},
+		{TraceID: 1, ID: 2, Name: "query", ParentID: &parentID},
+	}
+	bodyBytes := zipkinSerialize(spans)
+	statusCode, resBodyStr, err := postBytes(server.URL+`/api/v1/spans`, bodyBytes, createHeader("application/x-thrift; charset=utf-8"))
+	assert.NoError(t, err)
+	assert.EqualValues(t, http.StatusAccepted, statusCode)
+	assert.EqualValues(t, "", resBodyStr)
+
+	received := handler.zipkinSpansHandler.(*mockZipkinHandler).getSpans()
+	require.Len(t, received, 2)
+	assert.Equal(t, "get", received[0].Name)
+	assert.Equal(t, "query", received[1].Name)
+	assert.Equal(t, &parentID, received[1].ParentID)
+}
+
+func TestThriftTruncatedSpanList(t *testing.T) {
+	server, handler := initializeTestServer(nil)
+	defer server.Close()
+
+	bodyBytes := zipkinSerialize([]*zipkincore.Span{{TraceID: 1, ID: 1, Name: "get"}, {TraceID: 1, ID: 2, Name: "query"}})
+	statusCode, resBodyStr, err := postBytes(server.URL+`/api/v1/spans`, bodyBytes[:len(bodyBytes)-5], createHeader("application/x-thrift"))
+	assert.NoError(t, err)
+	assert.EqualValues(t, http.StatusBadRequest, statusCode)
+	assert.Contains(t, resBodyStr, "Unable to process request body")
+	assert.Empty(t, handler.zipkinSpansHandler.(*mockZipkinHandler).getSpans())
+}
+
 func TestJsonFormat(t *testing.T) {
 	server, handler := initializeTestServer(nil)
 	defer server.Close()
@@ -177,27 +213,46 @@ func TestJsonFormat(t *testing.T) {
 	}
 }
 
-func TestGzipEncoding(t *testing.T) {
-	server, _ := initializeTestServer(nil)
+func TestJsonV2Format(t *testing.T) {
+	server, handler := initializeTestServer(nil)
 	defer server.Close()
-	bodyBytes := zipkinSerialize([]*zipkincore.Span{{}})
-	header := createHeader("application/x-thrift")
-	header.Add("Content-Encoding", "gzip")
-	statusCode, resBodyStr, err := postBytes(server.URL+`/api/v1/spans`, gzipEncode(bodyBytes), header)
+
+	spanJSON := `[{"traceId": "1234567891234568", "id": "1234567891234565", "kind": "SERVER", "name": "get",
+		"timestamp": 156, "duration": 15145, "localEndpoint": {"serviceName": "foo", "ipv4": "127.0.0.1"}}]`
+	statusCode, resBodyStr, err := postBytes(server.URL+`/api/v2/spans`, []byte(spanJSON), createHeader("application/json"))
 	assert.NoError(t, err)
 	assert.EqualValues(t, http.StatusAccepted, statusCode)
 	assert.EqualValues(t, "", resBodyStr)
-}
+	waitForSpans(t, handler.zipkinSpansHandler.(*mockZipkinHandler), 1)
+	recdSpan := handler.zipkinSpansHandler.(*mockZipkinHandler).getSpans()[0]
+	require.Len(t, recdSpan.Annotations, 2)
+	assert.Equal(t, zipkincore.SERVER_RECV, recdSpan.Annotations[0].Value)
 
-func TestGzipBadBody(t *testing.T) {
-	server, _ := initializeTestServer(nil)
-	defer server.Close()
-	header := createHeader("")
-	header.Add("Content-Encoding", "gzip")
-	statusCode, resBodyStr, err := postBytes(server.URL+`/api/v1/spans`, []byte("not good"), header)
-	assert.NoError(t, err)
-	assert.EqualValues(t, http.StatusBadRequest, statusCode)
-	assert.EqualValues(t, "Unable to process request body: unexpected EOF\n", resBodyStr)
+	tests := []struct {
+		payload     string
+		contentType string
+		expected    string
+		statusCode  int
+	}{
+		{
+			payload:     spanJSON,
+			contentType: "application/x-thrift",
+			expected:    "Unsupported Content-Type\n",
+			statusCode:  http.StatusBadRequest,
+		},
+		{
+			payload:     `[{"traceId": "1", "id": "ZTA"}]`,
+			contentType: "application/json",
+			expected:    "Unable to process request body: strconv.ParseUint: parsing \"ZTA\": invalid syntax\n",
+			statusCode:  http.StatusBadRequest,
+		},
+	}
+	for _, test := range tests {
+		statusCode, resBodyStr, err = postBytes(server.URL+`/api/v2/spans`, []byte(test.payload), createHeader(test.contentType))
+		require.NoError(t, err)
+		assert.EqualValues(t, test.statusCode, statusCode)
+		assert.EqualValues(t, test.expected, resBodyStr)
+	}
 }
 
 func TestUnsupportedContentType(t *testing.T) {
@@ -223,6 +278,57 @@ func TestDeserializeWithBadListStart(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestRequestBodyTooLarge(t *testing.T) {
+	mb := metrics.NewLocalFactory(time.Hour)
+	zipkinHandler := &mockZipkinHandler{}
+	r := mux.NewRouter()
+	NewAPIHandler(zipkinHandler, HandlerOptions.RequestBodyLimiter(app.NewRequestBodyLimiter(1024, mb))).RegisterRoutes(r)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	spans := make([]*zipkincore.Span, 100)
+	for i := range spans {
+		spans[i] = &zipkincore.Span{ID: int64(i + 1), Name: "operation"}
+	}
+	bodyBytes := zipkinSerialize(spans)
+	require.True(t, len(bodyBytes) > 1024)
+
+	for _, endpoint := range []string{"/api/v1/spans", "/api/v2/spans"} {
+		statusCode, resBodyStr, err := postBytes(server.URL+endpoint, bodyBytes, createHeader("application/json"))
+		assert.NoError(t, err)
+		assert.EqualValues(t, http.StatusRequestEntityTooLarge, statusCode, endpoint)
+		assert.EqualValues(t, "Unable to process request body: request body too large\n", resBodyStr, endpoint)
+	}
+	assert.Empty(t, zipkinHandler.getSpans())
+
+	counters, _ := mb.Snapshot()
+	assert.EqualValues(t, 2, counters["batches.rejected|reason=too-many-bytes"])
+}
+
+func TestBatchTooLarge(t *testing.T) {
+	server, _ := initializeTestServer(app.ErrBatchTooLarge)
+	defer server.Close()
+	bodyBytes := zipkinSerialize([]*zipkincore.Span{{ID: 12345}})
+	statusCode, resBodyStr, err := postBytes(server.URL+`/api/v1/spans`, bodyBytes, createHeader("application/x-thrift"))
+	assert.NoError(t, err)
+	assert.EqualValues(t, http.StatusRequestEntityTooLarge, statusCode)
+	assert.EqualValues(t, "Cannot submit Zipkin batch: batch has too many spans\n", resBodyStr)
+}
+
+func TestServerBusy(t *testing.T) {
+	server, _ := initializeTestServer(tchannel.ErrServerBusy)
+	defer server.Close()
+	bodyBytes := zipkinSerialize([]*zipkincore.Span{{ID: 12345}})
+	req, err := http.NewRequest(http.MethodPost, server.URL+`/api/v1/spans`, bytes.NewReader(bodyBytes))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-thrift")
+	res, err := httpClient.Do(req)
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.EqualValues(t, http.StatusTooManyRequests, res.StatusCode)
+	assert.NotEmpty(t, res.Header.Get("Retry-After"))
+}
+
 func TestCannotReadBodyFromRequest(t *testing.T) {
 	handler := NewAPIHandler(&mockZipkinHandler{})
 	req, err := http.NewRequest(http.MethodPost, "whatever", &errReader{})
@@ -276,14 +382,6 @@ func zipkinSerialize(spans []*zipkincore.Span) []byte {
 	return t.Buffer.Bytes()
 }
 
-func gzipEncode(b []byte) []byte {
-	buffer := &bytes.Buffer{}
-	z := gzip.NewWriter(buffer)
-	z.Write(b)
-	z.Close()
-	return buffer.Bytes()
-}
-
 func postBytes(urlStr string, bytesBody []byte, header *http.Header) (int, string, error) {
 	req, err := http.NewRequest(http.MethodPost, urlStr, bytes.NewBuffer(bytesBody))
 	if err != nil {
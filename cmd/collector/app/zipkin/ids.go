@@ -0,0 +1,91 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zipkin
+
+import (
+	"fmt"
+
+	"github.com/uber/jaeger-lib/metrics"
+)
+
+const (
+	// idLength is the number of hex characters in a 64 bit ID, 128 bit IDs have twice as many
+	idLength = 16
+
+	rejectReasonTruncatedID = "truncated-id"
+)
+
+// idValidator rejects the JSON batches with IDs that are not full length: trace and span IDs must have
+// 16 or 32 hex characters, of which the low 64 bits of a span ID are kept. An ID that was truncated, or
+// printed without its leading zeros, would otherwise be parsed into a different ID and break the trace
+// apart. The spans of the rejected batches are counted in the spans.rejected counter tagged with
+// reason=truncated-id.
+type idValidator struct {
+	rejected metrics.Counter
+}
+
+func newIDValidator(metricsFactory metrics.Factory) *idValidator {
+	return &idValidator{
+		rejected: metricsFactory.Counter("spans.rejected", map[string]string{"reason": rejectReasonTruncatedID}),
+	}
+}
+
+// validateSpans returns an error if an ID of any of the spans is not full length. A nil idValidator accepts all IDs.
+func (v *idValidator) validateSpans(spans []zipkinSpan) error {
+	if v == nil {
+		return nil
+	}
+	for _, s := range spans {
+		if err := checkIDs(s.TraceID, s.ID, s.ParentID); err != nil {
+			v.rejected.Inc(int64(len(spans)))
+			return err
+		}
+	}
+	return nil
+}
+
+// validateSpansV2 is validateSpans for Zipkin v2 spans.
+func (v *idValidator) validateSpansV2(spans []zipkinSpanV2) error {
+	if v == nil {
+		return nil
+	}
+	for _, s := range spans {
+		if err := checkIDs(s.TraceID, s.ID, s.ParentID); err != nil {
+			v.rejected.Inc(int64(len(spans)))
+			return err
+		}
+	}
+	return nil
+}
+
+func checkIDs(traceID, id, parentID string) error {
+	if err := checkID("trace", traceID); err != nil {
+		return err
+	}
+	if err := checkID("span", id); err != nil {
+		return err
+	}
+	if parentID != "" {
+		return checkID("parent span", parentID)
+	}
+	return nil
+}
+
+func checkID(kind, id string) error {
+	if len(id) != idLength && len(id) != 2*idLength {
+		return fmt.Errorf("%s ID %q is truncated, it must have %d or %d hex characters", kind, id, idLength, 2*idLength)
+	}
+	return nil
+}
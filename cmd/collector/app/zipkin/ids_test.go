@@ -0,0 +1,139 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zipkin
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics"
+
+	"github.com/uber/jaeger/model"
+	zConv "github.com/uber/jaeger/model/converter/thrift/zipkin"
+)
+
+func TestCheckIDs(t *testing.T) {
+	tests := []struct {
+		traceID, id, parentID string
+		err                   string
+	}{
+		{traceID: "1234567891234568", id: "1234567891234565"},
+		{traceID: "00000000000000011234567891234568", id: "1234567891234565", parentID: "0000000000000001"},
+		{traceID: "1234567891234568", id: "00000000000000011234567891234565"},
+		{traceID: "4567891234568", id: "1234567891234565", err: `trace ID "4567891234568" is truncated, it must have 16 or 32 hex characters`},
+		{traceID: "11234567891234568", id: "1234567891234565", err: `trace ID "11234567891234568" is truncated, it must have 16 or 32 hex characters`},
+		{traceID: "1234567891234568", id: "1", err: `span ID "1" is truncated, it must have 16 or 32 hex characters`},
+		{traceID: "1234567891234568", id: "1234567891234565", parentID: "1", err: `parent span ID "1" is truncated, it must have 16 or 32 hex characters`},
+	}
+	for _, test := range tests {
+		err := checkIDs(test.traceID, test.id, test.parentID)
+		if test.err == "" {
+			assert.NoError(t, err, test.traceID)
+		} else {
+			assert.EqualError(t, err, test.err)
+		}
+	}
+}
+
+func TestNilIDValidator(t *testing.T) {
+	var v *idValidator
+	assert.NoError(t, v.validateSpans([]zipkinSpan{{TraceID: "1", ID: "1"}}))
+	assert.NoError(t, v.validateSpansV2([]zipkinSpanV2{{TraceID: "1", ID: "1"}}))
+}
+
+func TestTraceIDsRoundTrip(t *testing.T) {
+	tests := []struct {
+		traceID string
+		high    uint64
+		low     uint64
+	}{
+		{traceID: "1234567891234568", low: 0x1234567891234568},
+		{traceID: "b6dbe5b3f5d9e8651234567891234568", high: 0xb6dbe5b3f5d9e865, low: 0x1234567891234568},
+		{traceID: "00000000000000011234567891234568", high: 1, low: 0x1234567891234568},
+	}
+	for _, test := range tests {
+		v1 := fmt.Sprintf(`[{"traceId": %q, "id": "1234567891234565", "parentId": "0000000000000001", "name": "get",
+			"annotations": [{"value": "sr", "timestamp": 156, "endpoint": {"serviceName": "foo"}}]}]`, test.traceID)
+		v2 := fmt.Sprintf(`[{"traceId": %q, "id": "1234567891234565", "parentId": "0000000000000001", "name": "get",
+			"kind": "SERVER", "timestamp": 156, "localEndpoint": {"serviceName": "foo"}}]`, test.traceID)
+		for version, body := range map[string]string{"v1": v1, "v2": v2} {
+			deserialize := DeserializeJSON
+			if version == "v2" {
+				deserialize = DeserializeJSONV2
+			}
+			tSpans, err := deserialize([]byte(body))
+			require.NoError(t, err, version)
+			require.Len(t, tSpans, 1)
+			if test.high == 0 {
+				assert.Nil(t, tSpans[0].TraceIDHigh, version)
+			} else {
+				require.NotNil(t, tSpans[0].TraceIDHigh, version)
+				assert.Equal(t, test.high, uint64(*tSpans[0].TraceIDHigh), version)
+			}
+
+			spans, err := zConv.ToDomainSpan(tSpans[0])
+			require.NoError(t, err, version)
+			require.NotEmpty(t, spans)
+			for _, span := range spans {
+				assert.Equal(t, model.TraceID{High: test.high, Low: test.low}, span.TraceID, version)
+				assert.Equal(t, model.SpanID(0x1234567891234565), span.SpanID, version)
+				assert.Equal(t, model.SpanID(1), span.ParentSpanID, version)
+			}
+			traceID := model.TraceID{High: test.high, Low: test.low}
+			parsed, err := model.TraceIDFromString(traceID.String())
+			require.NoError(t, err, version)
+			assert.Equal(t, traceID, parsed, version)
+		}
+	}
+}
+
+func TestStrictIDs(t *testing.T) {
+	mb := metrics.NewLocalFactory(time.Hour)
+	zipkinHandler := &mockZipkinHandler{}
+	r := mux.NewRouter()
+	NewAPIHandler(zipkinHandler, HandlerOptions.StrictIDs(mb)).RegisterRoutes(r)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	for _, endpoint := range []string{"/api/v1/spans", "/api/v2/spans"} {
+		body := `[{"traceId": "00000000000000011234567891234568", "id": "1234567891234565", "name": "get"},
+			{"traceId": "1234567891234568", "id": "1234567891234566", "parentId": "1234567891234565", "name": "get"}]`
+		statusCode, resBodyStr, err := postBytes(server.URL+endpoint, []byte(body), createHeader("application/json"))
+		require.NoError(t, err)
+		assert.EqualValues(t, http.StatusAccepted, statusCode, endpoint)
+		assert.EqualValues(t, "", resBodyStr, endpoint)
+
+		body = `[{"traceId": "1234567891234568", "id": "1234567891234565", "name": "get"},
+			{"traceId": "1234567891234568", "id": "1234567891234566", "parentId": "4567891234565", "name": "get"}]`
+		statusCode, resBodyStr, err = postBytes(server.URL+endpoint, []byte(body), createHeader("application/json"))
+		require.NoError(t, err)
+		assert.EqualValues(t, http.StatusBadRequest, statusCode, endpoint)
+		assert.EqualValues(t, "Unable to process request body: parent span ID \"4567891234565\" is truncated, it must have 16 or 32 hex characters\n", resBodyStr, endpoint)
+	}
+	spans := zipkinHandler.getSpans()
+	require.Len(t, spans, 4)
+	require.NotNil(t, spans[0].TraceIDHigh)
+	assert.EqualValues(t, 1, *spans[0].TraceIDHigh)
+	assert.Nil(t, spans[1].TraceIDHigh)
+
+	counters, _ := mb.Snapshot()
+	assert.EqualValues(t, 4, counters["spans.rejected|reason=truncated-id"])
+}
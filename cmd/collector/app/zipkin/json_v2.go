@@ -0,0 +1,217 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zipkin
+
+import (
+	"encoding/json"
+
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/thrift-gen/zipkincore"
+)
+
+const (
+	kindClient   = "CLIENT"
+	kindServer   = "SERVER"
+	kindProducer = "PRODUCER"
+	kindConsumer = "CONSUMER"
+
+	// messaging annotations are not defined in zipkincore.thrift
+	messageSend = "ms"
+	messageRecv = "mr"
+	messageAddr = "ma"
+)
+
+type annotationV2 struct {
+	Value     string `json:"value"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+type zipkinSpanV2 struct {
+	ID             string            `json:"id"`
+	ParentID       string            `json:"parentId,omitempty"`
+	TraceID        string            `json:"traceId"`
+	Name           string            `json:"name"`
+	Kind           string            `json:"kind"`
+	Timestamp      *int64            `json:"timestamp"`
+	Duration       *int64            `json:"duration"`
+	Debug          bool              `json:"debug"`
+	Shared         bool              `json:"shared"`
+	LocalEndpoint  *endpoint         `json:"localEndpoint"`
+	RemoteEndpoint *endpoint         `json:"remoteEndpoint"`
+	Annotations    []annotationV2    `json:"annotations"`
+	Tags           map[string]string `json:"tags"`
+}
+
+// DeserializeJSONV2 deserializes zipkin v2 json spans into zipkin thrift
+func DeserializeJSONV2(body []byte) ([]*zipkincore.Span, error) {
+	spans, err := decodeV2(body)
+	if err != nil {
+		return nil, err
+	}
+
+	return spansV2ToThrift(spans)
+}
+
+func decodeV2(body []byte) ([]zipkinSpanV2, error) {
+	var spans []zipkinSpanV2
+	if err := json.Unmarshal(body, &spans); err != nil {
+		return nil, err
+	}
+	return spans, nil
+}
+
+func spansV2ToThrift(spans []zipkinSpanV2) ([]*zipkincore.Span, error) {
+	var tSpans []*zipkincore.Span
+	for _, span := range spans {
+		tSpan, err := spanV2ToThrift(span)
+		if err != nil {
+			return nil, err
+		}
+		tSpans = append(tSpans, tSpan)
+	}
+	return tSpans, nil
+}
+
+// spanV2ToThrift converts a v2 span into the v1 thrift model, following the same rules
+// as Zipkin's own v2 to v1 converter: the span kind becomes a pair of core annotations,
+// the remote endpoint becomes an address binary annotation and tags become binary annotations.
+func spanV2ToThrift(s zipkinSpanV2) (*zipkincore.Span, error) {
+	id, err := model.SpanIDFromString(cutLongID(s.ID))
+	if err != nil {
+		return nil, err
+	}
+	traceID, err := model.TraceIDFromString(s.TraceID)
+	if err != nil {
+		return nil, err
+	}
+
+	tSpan := &zipkincore.Span{
+		ID:      int64(id),
+		TraceID: int64(traceID.Low),
+		Name:    s.Name,
+		Debug:   s.Debug,
+	}
+	if traceID.High != 0 {
+		help := int64(traceID.High)
+		tSpan.TraceIDHigh = &help
+	}
+	if len(s.ParentID) > 0 {
+		parentID, err := model.SpanIDFromString(cutLongID(s.ParentID))
+		if err != nil {
+			return nil, err
+		}
+		signed := int64(parentID)
+		tSpan.ParentID = &signed
+	}
+	// a shared server span does not own the timestamp and duration, the client side does
+	if !(s.Shared && s.Kind == kindServer) {
+		tSpan.Timestamp = s.Timestamp
+		tSpan.Duration = s.Duration
+	}
+
+	var localEndpoint *zipkincore.Endpoint
+	if s.LocalEndpoint != nil {
+		if localEndpoint, err = endpointToThrift(*s.LocalEndpoint); err != nil {
+			return nil, err
+		}
+	}
+
+	tSpan.Annotations = kindToThrift(s, localEndpoint)
+	for _, a := range s.Annotations {
+		tSpan.Annotations = append(tSpan.Annotations, &zipkincore.Annotation{
+			Timestamp: a.Timestamp,
+			Value:     a.Value,
+			Host:      localEndpoint,
+		})
+	}
+
+	if s.RemoteEndpoint != nil {
+		remoteEndpoint, err := endpointToThrift(*s.RemoteEndpoint)
+		if err != nil {
+			return nil, err
+		}
+		if key := remoteEndpointKey(s.Kind); key != "" {
+			tSpan.BinaryAnnotations = append(tSpan.BinaryAnnotations, &zipkincore.BinaryAnnotation{
+				Key:            key,
+				Value:          []byte{1},
+				Host:           remoteEndpoint,
+				AnnotationType: zipkincore.AnnotationType_BOOL,
+			})
+		}
+	}
+	if len(tSpan.Annotations) == 0 && localEndpoint != nil {
+		// without core annotations the local component annotation carries the service name
+		tSpan.BinaryAnnotations = append(tSpan.BinaryAnnotations, &zipkincore.BinaryAnnotation{
+			Key:            zipkincore.LOCAL_COMPONENT,
+			Value:          []byte{},
+			Host:           localEndpoint,
+			AnnotationType: zipkincore.AnnotationType_STRING,
+		})
+	}
+	for key, value := range s.Tags {
+		tSpan.BinaryAnnotations = append(tSpan.BinaryAnnotations, &zipkincore.BinaryAnnotation{
+			Key:            key,
+			Value:          []byte(value),
+			Host:           localEndpoint,
+			AnnotationType: zipkincore.AnnotationType_STRING,
+		})
+	}
+	return tSpan, nil
+}
+
+func kindToThrift(s zipkinSpanV2, localEndpoint *zipkincore.Endpoint) []*zipkincore.Annotation {
+	var start, end string
+	switch s.Kind {
+	case kindClient:
+		start, end = zipkincore.CLIENT_SEND, zipkincore.CLIENT_RECV
+	case kindServer:
+		start, end = zipkincore.SERVER_RECV, zipkincore.SERVER_SEND
+	case kindProducer:
+		start = messageSend
+	case kindConsumer:
+		start = messageRecv
+	default:
+		return nil
+	}
+	if s.Timestamp == nil {
+		return nil
+	}
+	annotations := []*zipkincore.Annotation{{
+		Timestamp: *s.Timestamp,
+		Value:     start,
+		Host:      localEndpoint,
+	}}
+	if end != "" && s.Duration != nil {
+		annotations = append(annotations, &zipkincore.Annotation{
+			Timestamp: *s.Timestamp + *s.Duration,
+			Value:     end,
+			Host:      localEndpoint,
+		})
+	}
+	return annotations
+}
+
+func remoteEndpointKey(kind string) string {
+	switch kind {
+	case kindClient:
+		return zipkincore.SERVER_ADDR
+	case kindServer:
+		return zipkincore.CLIENT_ADDR
+	case kindProducer, kindConsumer:
+		return messageAddr
+	default:
+		return ""
+	}
+}
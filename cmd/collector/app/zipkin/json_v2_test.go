@@ -0,0 +1,152 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zipkin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/jaeger/thrift-gen/zipkincore"
+)
+
+var spanV2JSON = `[{
+	"traceId": "00000000000000011234567891234568",
+	"parentId": "1234567891234567",
+	"id": "1234567891234565",
+	"kind": "CLIENT",
+	"name": "get",
+	"timestamp": 1000,
+	"duration": 500,
+	"debug": true,
+	"localEndpoint": {"serviceName": "foo", "ipv4": "127.0.0.1", "port": 80},
+	"remoteEndpoint": {"serviceName": "bar", "ipv4": "127.0.0.2", "port": 8080},
+	"annotations": [{"timestamp": 1200, "value": "retry"}],
+	"tags": {"http.status_code": "200"}
+}]`
+
+func TestDeserializeJSONV2(t *testing.T) {
+	spans, err := DeserializeJSONV2([]byte(spanV2JSON))
+	require.NoError(t, err)
+	require.Len(t, spans, 1)
+	span := spans[0]
+
+	assert.EqualValues(t, 0x1234567891234565, span.ID)
+	assert.EqualValues(t, 0x1234567891234568, span.TraceID)
+	require.NotNil(t, span.TraceIDHigh)
+	assert.EqualValues(t, 1, *span.TraceIDHigh)
+	require.NotNil(t, span.ParentID)
+	assert.EqualValues(t, 0x1234567891234567, *span.ParentID)
+	assert.Equal(t, "get", span.Name)
+	assert.True(t, span.Debug)
+	assert.EqualValues(t, 1000, *span.Timestamp)
+	assert.EqualValues(t, 500, *span.Duration)
+
+	require.Len(t, span.Annotations, 3)
+	assert.Equal(t, zipkincore.CLIENT_SEND, span.Annotations[0].Value)
+	assert.EqualValues(t, 1000, span.Annotations[0].Timestamp)
+	assert.Equal(t, zipkincore.CLIENT_RECV, span.Annotations[1].Value)
+	assert.EqualValues(t, 1500, span.Annotations[1].Timestamp)
+	assert.Equal(t, "retry", span.Annotations[2].Value)
+	for _, a := range span.Annotations {
+		require.NotNil(t, a.Host)
+		assert.Equal(t, "foo", a.Host.ServiceName)
+	}
+
+	require.Len(t, span.BinaryAnnotations, 2)
+	assert.Equal(t, zipkincore.SERVER_ADDR, span.BinaryAnnotations[0].Key)
+	assert.Equal(t, "bar", span.BinaryAnnotations[0].Host.ServiceName)
+	assert.Equal(t, zipkincore.AnnotationType_BOOL, span.BinaryAnnotations[0].AnnotationType)
+	assert.Equal(t, "http.status_code", span.BinaryAnnotations[1].Key)
+	assert.Equal(t, []byte("200"), span.BinaryAnnotations[1].Value)
+	assert.Equal(t, zipkincore.AnnotationType_STRING, span.BinaryAnnotations[1].AnnotationType)
+}
+
+func TestDeserializeJSONV2SharedServerSpan(t *testing.T) {
+	json := `[{"traceId": "1", "id": "2", "kind": "SERVER", "shared": true, "timestamp": 10, "duration": 5,
+		"localEndpoint": {"serviceName": "foo"}, "remoteEndpoint": {"serviceName": "bar"}}]`
+	spans, err := DeserializeJSONV2([]byte(json))
+	require.NoError(t, err)
+	require.Len(t, spans, 1)
+	assert.Nil(t, spans[0].Timestamp)
+	assert.Nil(t, spans[0].Duration)
+	require.Len(t, spans[0].Annotations, 2)
+	assert.Equal(t, zipkincore.SERVER_RECV, spans[0].Annotations[0].Value)
+	assert.Equal(t, zipkincore.SERVER_SEND, spans[0].Annotations[1].Value)
+	require.Len(t, spans[0].BinaryAnnotations, 1)
+	assert.Equal(t, zipkincore.CLIENT_ADDR, spans[0].BinaryAnnotations[0].Key)
+}
+
+func TestDeserializeJSONV2Messaging(t *testing.T) {
+	json := `[{"traceId": "1", "id": "2", "kind": "PRODUCER", "timestamp": 10, "duration": 5,
+		"localEndpoint": {"serviceName": "foo"}, "remoteEndpoint": {"serviceName": "kafka"}}]`
+	spans, err := DeserializeJSONV2([]byte(json))
+	require.NoError(t, err)
+	require.Len(t, spans, 1)
+	require.Len(t, spans[0].Annotations, 1)
+	assert.Equal(t, messageSend, spans[0].Annotations[0].Value)
+	require.Len(t, spans[0].BinaryAnnotations, 1)
+	assert.Equal(t, messageAddr, spans[0].BinaryAnnotations[0].Key)
+}
+
+func TestDeserializeJSONV2LocalSpan(t *testing.T) {
+	json := `[{"traceId": "1", "id": "2", "name": "compute", "timestamp": 10, "duration": 5,
+		"localEndpoint": {"serviceName": "foo"}}]`
+	spans, err := DeserializeJSONV2([]byte(json))
+	require.NoError(t, err)
+	require.Len(t, spans, 1)
+	assert.Empty(t, spans[0].Annotations)
+	require.Len(t, spans[0].BinaryAnnotations, 1)
+	assert.Equal(t, zipkincore.LOCAL_COMPONENT, spans[0].BinaryAnnotations[0].Key)
+	assert.Equal(t, "foo", spans[0].BinaryAnnotations[0].Host.ServiceName)
+}
+
+func TestDeserializeJSONV2Errors(t *testing.T) {
+	tests := []struct {
+		payload  string
+		expected string
+	}{
+		{
+			payload:  `{"traceId": "1"}`,
+			expected: "json: cannot unmarshal object into Go value of type []zipkin.zipkinSpanV2",
+		},
+		{
+			payload:  `[{"traceId": "1", "id": "ZTA"}]`,
+			expected: `strconv.ParseUint: parsing "ZTA": invalid syntax`,
+		},
+		{
+			payload:  `[{"traceId": "ZTA", "id": "1"}]`,
+			expected: `strconv.ParseUint: parsing "ZTA": invalid syntax`,
+		},
+		{
+			payload:  `[{"traceId": "1", "id": "1", "parentId": "ZTA"}]`,
+			expected: `strconv.ParseUint: parsing "ZTA": invalid syntax`,
+		},
+		{
+			payload:  `[{"traceId": "1", "id": "1", "localEndpoint": {"ipv4": "127.0.0.A"}}]`,
+			expected: "wrong ipv4",
+		},
+		{
+			payload:  `[{"traceId": "1", "id": "1", "kind": "CLIENT", "remoteEndpoint": {"ipv4": "127.0.0.A"}}]`,
+			expected: "wrong ipv4",
+		},
+	}
+	for _, test := range tests {
+		_, err := DeserializeJSONV2([]byte(test.payload))
+		require.Error(t, err, test.payload)
+		assert.EqualError(t, err, test.expected, test.payload)
+	}
+}
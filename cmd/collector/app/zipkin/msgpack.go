@@ -0,0 +1,41 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zipkin
+
+import (
+	"encoding/json"
+
+	"github.com/uber/jaeger/pkg/msgpack"
+	"github.com/uber/jaeger/thrift-gen/zipkincore"
+)
+
+// DeserializeMsgpack deserializes zipkin v1 msgpack spans into zipkin thrift
+func DeserializeMsgpack(body []byte) ([]*zipkincore.Span, error) {
+	jsonBody, err := msgpackToJSON(body)
+	if err != nil {
+		return nil, err
+	}
+	return DeserializeJSON(jsonBody)
+}
+
+// msgpackToJSON converts a msgpack document into the equivalent JSON document. Msgpack spans are
+// encoded with the same fields and values as JSON spans, so that they can be decoded as JSON.
+func msgpackToJSON(body []byte) ([]byte, error) {
+	v, err := msgpack.Unmarshal(body)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
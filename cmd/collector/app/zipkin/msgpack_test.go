@@ -0,0 +1,177 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zipkin
+
+import (
+	"encoding/binary"
+	"net/http"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/jaeger/cmd/collector/app"
+	"github.com/uber/jaeger/thrift-gen/zipkincore"
+)
+
+// msgpackEncode encodes the maps, arrays, strings, integers and booleans in v the way msgpack tracers do
+func msgpackEncode(v interface{}) []byte {
+	switch v := v.(type) {
+	case nil:
+		return []byte{0xc0}
+	case bool:
+		if v {
+			return []byte{0xc3}
+		}
+		return []byte{0xc2}
+	case int:
+		b := make([]byte, 9)
+		b[0] = 0xd3
+		binary.BigEndian.PutUint64(b[1:], uint64(v))
+		return b
+	case string:
+		b := []byte{0xd9, byte(len(v))}
+		return append(b, v...)
+	case []interface{}:
+		b := []byte{0xdc, 0, 0}
+		binary.BigEndian.PutUint16(b[1:], uint16(len(v)))
+		for _, e := range v {
+			b = append(b, msgpackEncode(e)...)
+		}
+		return b
+	case obj:
+		b := []byte{0xde, 0, 0}
+		binary.BigEndian.PutUint16(b[1:], uint16(len(v)))
+		var keys []string
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			b = append(b, msgpackEncode(k)...)
+			b = append(b, msgpackEncode(v[k])...)
+		}
+		return b
+	}
+	panic("unsupported type")
+}
+
+type obj map[string]interface{}
+
+func msgpackSpanV1() []byte {
+	return msgpackEncode([]interface{}{obj{
+		"traceId":   "00000000000000011234567891234568",
+		"id":        "1234567891234565",
+		"parentId":  "1234567891234564",
+		"name":      "get",
+		"timestamp": 156,
+		"duration":  15145,
+		"debug":     true,
+		"annotations": []interface{}{
+			obj{"value": "sr", "timestamp": 156, "endpoint": obj{"serviceName": "foo", "ipv4": "127.0.0.1", "port": 80}},
+			obj{"value": "ss", "timestamp": 15301, "endpoint": obj{"serviceName": "foo", "ipv4": "127.0.0.1", "port": 80}},
+		},
+		"binaryAnnotations": []interface{}{
+			obj{"key": "http.path", "value": "/api", "type": "STRING", "endpoint": obj{"serviceName": "foo"}},
+			obj{"key": "retried", "value": true, "type": "BOOL", "endpoint": obj{"serviceName": "foo"}},
+		},
+	}})
+}
+
+func TestDeserializeMsgpack(t *testing.T) {
+	tSpans, err := DeserializeMsgpack(msgpackSpanV1())
+	require.NoError(t, err)
+	require.Len(t, tSpans, 1)
+	span := tSpans[0]
+	assert.Equal(t, "get", span.Name)
+	assert.EqualValues(t, 0x1234567891234568, span.TraceID)
+	require.NotNil(t, span.TraceIDHigh)
+	assert.EqualValues(t, 1, *span.TraceIDHigh)
+	assert.EqualValues(t, 0x1234567891234565, span.ID)
+	require.NotNil(t, span.ParentID)
+	assert.EqualValues(t, 0x1234567891234564, *span.ParentID)
+	assert.EqualValues(t, 156, *span.Timestamp)
+	assert.EqualValues(t, 15145, *span.Duration)
+	assert.True(t, span.Debug)
+	require.Len(t, span.Annotations, 2)
+	assert.Equal(t, zipkincore.SERVER_RECV, span.Annotations[0].Value)
+	assert.Equal(t, "foo", span.Annotations[0].Host.ServiceName)
+	assert.EqualValues(t, 127<<24|1, span.Annotations[0].Host.Ipv4)
+	require.Len(t, span.BinaryAnnotations, 2)
+	assert.Equal(t, "http.path", span.BinaryAnnotations[0].Key)
+	assert.Equal(t, []byte("/api"), span.BinaryAnnotations[0].Value)
+	assert.Equal(t, zipkincore.AnnotationType_BOOL, span.BinaryAnnotations[1].AnnotationType)
+
+	for _, body := range [][]byte{{0xc1}, {0x91}, msgpackEncode("spans"), msgpackEncode([]interface{}{obj{"traceId": "1", "id": "ZTA"}})} {
+		_, err := DeserializeMsgpack(body)
+		assert.Error(t, err, "%x", body)
+	}
+}
+
+func TestDecodeMsgpack(t *testing.T) {
+	decoder, ok := app.LookupDecoder(MsgpackContentType)
+	require.True(t, ok)
+
+	spans, err := decoder.Decode(msgpackSpanV1())
+	require.NoError(t, err)
+	require.Len(t, spans, 1)
+	assert.Equal(t, "get", spans[0].OperationName)
+	assert.Equal(t, "foo", spans[0].Process.ServiceName)
+
+	_, err = decoder.Decode([]byte{0xc1})
+	assert.Error(t, err)
+}
+
+func TestMsgpackFormat(t *testing.T) {
+	server, handler := initializeTestServer(nil)
+	defer server.Close()
+
+	statusCode, resBodyStr, err := postBytes(server.URL+`/api/v1/spans`, msgpackSpanV1(), createHeader(MsgpackContentType))
+	require.NoError(t, err)
+	assert.EqualValues(t, http.StatusAccepted, statusCode)
+	assert.EqualValues(t, "", resBodyStr)
+	waitForSpans(t, handler.zipkinSpansHandler.(*mockZipkinHandler), 1)
+	recdSpan := handler.zipkinSpansHandler.(*mockZipkinHandler).getSpans()[0]
+	assert.Equal(t, "get", recdSpan.Name)
+	require.Len(t, recdSpan.Annotations, 2)
+
+	spanV2 := msgpackEncode([]interface{}{obj{
+		"traceId":       "1234567891234568",
+		"id":            "1234567891234566",
+		"kind":          "CLIENT",
+		"name":          "post",
+		"timestamp":     200,
+		"duration":      100,
+		"localEndpoint": obj{"serviceName": "bar"},
+		"tags":          obj{"http.method": "POST"},
+	}})
+	statusCode, resBodyStr, err = postBytes(server.URL+`/api/v2/spans`, spanV2, createHeader(MsgpackContentType))
+	require.NoError(t, err)
+	assert.EqualValues(t, http.StatusAccepted, statusCode)
+	assert.EqualValues(t, "", resBodyStr)
+	waitForSpans(t, handler.zipkinSpansHandler.(*mockZipkinHandler), 2)
+	recdSpan = handler.zipkinSpansHandler.(*mockZipkinHandler).getSpans()[1]
+	assert.Equal(t, "post", recdSpan.Name)
+	require.Len(t, recdSpan.Annotations, 2)
+	assert.Equal(t, zipkincore.CLIENT_SEND, recdSpan.Annotations[0].Value)
+
+	for _, endpoint := range []string{"/api/v1/spans", "/api/v2/spans"} {
+		statusCode, resBodyStr, err = postBytes(server.URL+endpoint, []byte{0x91}, createHeader(MsgpackContentType))
+		require.NoError(t, err)
+		assert.EqualValues(t, http.StatusBadRequest, statusCode, endpoint)
+		assert.EqualValues(t, "Unable to process request body: msgpack: unexpected end of data\n", resBodyStr, endpoint)
+	}
+}
@@ -0,0 +1,46 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"net"
+	"strconv"
+
+	"github.com/uber/jaeger/cmd/collector/app/builder"
+)
+
+// listenerOptions configures the sockets of the collector's TCP listeners
+type listenerOptions struct {
+	// backlog is the maximum length of the queue of pending connections, 0 uses the system default
+	backlog int
+	// reusePort sets SO_REUSEPORT so that several processes can listen on the same port
+	reusePort bool
+}
+
+func newListenerOptions(builderOpts *builder.CollectorOptions) listenerOptions {
+	return listenerOptions{
+		backlog:   builderOpts.ListenBacklog,
+		reusePort: builderOpts.ReusePort,
+	}
+}
+
+// listenTCP listens on port on all interfaces. The listener is created with net.Listen unless a backlog
+// or SO_REUSEPORT is requested, which are only supported on Linux, macOS and FreeBSD.
+func listenTCP(port int, opts listenerOptions) (net.Listener, error) {
+	if opts.backlog == 0 && !opts.reusePort {
+		return net.Listen("tcp", ":"+strconv.Itoa(port))
+	}
+	return listenTCPWithOptions(port, opts)
+}
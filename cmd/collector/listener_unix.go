@@ -0,0 +1,77 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build darwin freebsd linux
+
+package main
+
+import (
+	"net"
+	"os"
+	"strconv"
+
+	"golang.org/x/sys/unix"
+)
+
+// listenTCPWithOptions creates the socket itself since net.Listen neither sets SO_REUSEPORT nor lets
+// the backlog be chosen. It listens on both IPv4 and IPv6 like net.Listen, unless IPv6 is not available.
+func listenTCPWithOptions(port int, opts listenerOptions) (net.Listener, error) {
+	family := unix.AF_INET6
+	fd, err := unix.Socket(family, unix.SOCK_STREAM, unix.IPPROTO_TCP)
+	if err == unix.EAFNOSUPPORT {
+		family = unix.AF_INET
+		fd, err = unix.Socket(family, unix.SOCK_STREAM, unix.IPPROTO_TCP)
+	}
+	if err != nil {
+		return nil, os.NewSyscallError("socket", err)
+	}
+	unix.CloseOnExec(fd)
+	if err := setupListenSocket(fd, family, port, opts); err != nil {
+		unix.Close(fd)
+		return nil, err
+	}
+	file := os.NewFile(uintptr(fd), "tcp:"+strconv.Itoa(port))
+	// FileListener duplicates the socket, so the file is always closed
+	defer file.Close()
+	return net.FileListener(file)
+}
+
+func setupListenSocket(fd int, family int, port int, opts listenerOptions) error {
+	if err := unix.SetsockoptInt(fd, unix.SOL_SOCKET, unix.SO_REUSEADDR, 1); err != nil {
+		return os.NewSyscallError("setsockopt", err)
+	}
+	if opts.reusePort {
+		if err := unix.SetsockoptInt(fd, unix.SOL_SOCKET, unix.SO_REUSEPORT, 1); err != nil {
+			return os.NewSyscallError("setsockopt", err)
+		}
+	}
+	var addr unix.Sockaddr = &unix.SockaddrInet4{Port: port}
+	if family == unix.AF_INET6 {
+		if err := unix.SetsockoptInt(fd, unix.IPPROTO_IPV6, unix.IPV6_V6ONLY, 0); err != nil {
+			return os.NewSyscallError("setsockopt", err)
+		}
+		addr = &unix.SockaddrInet6{Port: port}
+	}
+	if err := unix.Bind(fd, addr); err != nil {
+		return os.NewSyscallError("bind", err)
+	}
+	backlog := opts.backlog
+	if backlog == 0 {
+		backlog = unix.SOMAXCONN
+	}
+	if err := unix.Listen(fd, backlog); err != nil {
+		return os.NewSyscallError("listen", err)
+	}
+	return nil
+}
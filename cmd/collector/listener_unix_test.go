@@ -0,0 +1,73 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build darwin freebsd linux
+
+package main
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+func getSockoptInt(t *testing.T, listener net.Listener, level, opt int) int {
+	file, err := listener.(*net.TCPListener).File()
+	require.NoError(t, err)
+	defer file.Close()
+	value, err := unix.GetsockoptInt(int(file.Fd()), level, opt)
+	require.NoError(t, err)
+	return value
+}
+
+func TestListenTCPReusePort(t *testing.T) {
+	port := freePort(t)
+	opts := listenerOptions{backlog: 16, reusePort: true}
+	first, err := listenTCP(port, opts)
+	require.NoError(t, err)
+	defer first.Close()
+	assert.NotEqual(t, 0, getSockoptInt(t, first, unix.SOL_SOCKET, unix.SO_REUSEPORT))
+
+	// a second process, or listener, can listen on the same port
+	second, err := listenTCP(port, opts)
+	require.NoError(t, err)
+	defer second.Close()
+
+	conn, err := net.Dial("tcp", first.Addr().String())
+	require.NoError(t, err)
+	conn.Close()
+}
+
+func TestListenTCPBacklog(t *testing.T) {
+	port := freePort(t)
+	listener, err := listenTCP(port, listenerOptions{backlog: 16})
+	require.NoError(t, err)
+	defer listener.Close()
+	assert.Equal(t, 0, getSockoptInt(t, listener, unix.SOL_SOCKET, unix.SO_REUSEPORT))
+
+	_, err = listenTCP(port, listenerOptions{backlog: 16})
+	assert.Error(t, err, "the port is already in use without SO_REUSEPORT")
+
+	go func() {
+		if conn, err := listener.Accept(); err == nil {
+			conn.Close()
+		}
+	}()
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	conn.Close()
+}
@@ -15,34 +15,54 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"strconv"
 	"syscall"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/opentracing/opentracing-go"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
-	"github.com/uber/jaeger-lib/metrics/go-kit"
-	"github.com/uber/jaeger-lib/metrics/go-kit/expvar"
+	jaegerClient "github.com/uber/jaeger-client-go"
+	"github.com/uber/jaeger-client-go/transport"
 	"github.com/uber/tchannel-go"
+	"github.com/uber/tchannel-go/hyperbahn"
 	"github.com/uber/tchannel-go/thrift"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
 
 	basicB "github.com/uber/jaeger/cmd/builder"
 	"github.com/uber/jaeger/cmd/collector/app"
 	"github.com/uber/jaeger/cmd/collector/app/builder"
+	collectorGRPC "github.com/uber/jaeger/cmd/collector/app/grpc"
+	"github.com/uber/jaeger/cmd/collector/app/sampling/static"
 	"github.com/uber/jaeger/cmd/collector/app/zipkin"
 	"github.com/uber/jaeger/cmd/flags"
 	casFlags "github.com/uber/jaeger/cmd/flags/cassandra"
 	esFlags "github.com/uber/jaeger/cmd/flags/es"
+	kafkaFlags "github.com/uber/jaeger/cmd/flags/kafka"
+	memoryFlags "github.com/uber/jaeger/cmd/flags/memory"
+	"github.com/uber/jaeger/pkg/accesslog"
+	cascfg "github.com/uber/jaeger/pkg/cassandra/config"
 	"github.com/uber/jaeger/pkg/config"
+	"github.com/uber/jaeger/pkg/gzipfilter"
 	"github.com/uber/jaeger/pkg/healthcheck"
+	"github.com/uber/jaeger/pkg/loglevel"
+	pMetrics "github.com/uber/jaeger/pkg/metrics"
 	"github.com/uber/jaeger/pkg/recoveryhandler"
+	"github.com/uber/jaeger/pkg/tlscfg"
 	"github.com/uber/jaeger/pkg/version"
+	"github.com/uber/jaeger/storage/spanstore/memory"
 	jc "github.com/uber/jaeger/thrift-gen/jaeger"
+	"github.com/uber/jaeger/thrift-gen/sampling"
 	zc "github.com/uber/jaeger/thrift-gen/zipkincore"
 )
 
@@ -50,10 +70,12 @@ func main() {
 	var signalsChannel = make(chan os.Signal, 0)
 	signal.Notify(signalsChannel, os.Interrupt, syscall.SIGTERM)
 
+	// logger is replaced by one built from the logging flags once they are parsed
 	logger, _ := zap.NewProduction()
-	serviceName := "jaeger-collector"
 	casOptions := casFlags.NewOptions("cassandra")
 	esOptions := esFlags.NewOptions("es")
+	kafkaOptions := kafkaFlags.NewOptions("kafka")
+	memoryOptions := memoryFlags.NewOptions("memory")
 
 	v := viper.New()
 	command := &cobra.Command{
@@ -63,69 +85,253 @@ func main() {
 				a processing pipeline.`,
 		Run: func(cmd *cobra.Command, args []string) {
 			flags.TryLoadConfigFile(v, logger)
+			flags.WarnDeprecatedEnvVars(v, logger)
 
 			sFlags := new(flags.SharedFlags).InitFromViper(v)
+			// the level of the logger can be changed at runtime through logConfig.Level
+			logConfig, err := sFlags.Logging.NewConfig()
+			if err != nil {
+				logger.Fatal("Invalid logging configuration", zap.Error(err))
+			}
+			flagsLogger, err := logConfig.Build()
+			if err != nil {
+				logger.Fatal("Cannot create logger", zap.Error(err))
+			}
+			logger = flagsLogger
 			casOptions.InitFromViper(v)
 			esOptions.InitFromViper(v)
-
-			baseMetrics := xkit.Wrap(serviceName, expvar.NewFactory(10))
+			kafkaOptions.InitFromViper(v)
+			memoryOptions.InitFromViper(v)
 
 			builderOpts := new(builder.CollectorOptions).InitFromViper(v)
 
-			hc, err := healthcheck.Serve(http.StatusServiceUnavailable, builderOpts.CollectorHealthCheckHTTPPort, logger)
+			mBldr := new(pMetrics.Builder)
+			mBldr.InitFromViper(v)
+			baseMetrics, err := mBldr.CreateMetricsFactory(builderOpts.ServiceName)
+			if err != nil {
+				logger.Fatal("Cannot create metrics factory.", zap.Error(err))
+			}
+			builderOpts.TLS.Logger = logger
+			builderOpts.TLS.MetricsFactory = baseMetrics
+
+			// a readiness file left over by a collector that crashed would claim this one is ready
+			readiness := readinessFile(builderOpts.ReadinessFile)
+			if err := readiness.remove(); err != nil {
+				logger.Warn("Could not remove the readiness file", zap.String("readiness-file", builderOpts.ReadinessFile), zap.Error(err))
+			}
+
+			hc, err := healthcheck.ServeWithBasePath(builderOpts.HTTPBasePath, http.StatusServiceUnavailable, builderOpts.CollectorHealthCheckHTTPPort, logger)
 			if err != nil {
 				logger.Fatal("Could not start the health check server.", zap.Error(err))
 			}
 
-			handlerBuilder, err := builder.NewSpanHandlerBuilder(
-				builderOpts,
-				sFlags,
+			storageOpts := []basicB.Option{
 				basicB.Options.CassandraSessionOption(casOptions.GetPrimary()),
 				basicB.Options.ElasticClientOption(esOptions.GetPrimary()),
+				basicB.Options.KafkaProducerOption(kafkaOptions.GetPrimary()),
 				basicB.Options.LoggerOption(logger),
 				basicB.Options.MetricsFactoryOption(baseMetrics),
-			)
+			}
+			if sFlags.SpanStorage.Has(flags.CassandraStorageType) {
+				storageOpts = append(storageOpts, basicB.Options.CassandraSpanTTLOption(casOptions.GetSpanTTL(), casOptions.GetServiceTTLFile()))
+				tenants, err := casOptions.GetTenants()
+				if err != nil {
+					logger.Fatal("Invalid Cassandra tenants", zap.Error(err))
+				}
+				if len(tenants) > 0 {
+					sessionBuilders := make(map[string]cascfg.SessionBuilder, len(tenants))
+					for tenant, cfg := range tenants {
+						sessionBuilders[tenant] = cfg
+					}
+					storageOpts = append(storageOpts, basicB.Options.CassandraTenantsOption(casOptions.GetTenantTag(), sessionBuilders))
+				}
+			}
+			if sFlags.SpanStorage.Has(flags.MemoryStorageType) {
+				memStore, err := memory.NewStoreWithMaxTraces(memoryOptions.MaxTraces)
+				if err != nil {
+					logger.Fatal("Invalid memory storage configuration", zap.Error(err))
+				}
+				storageOpts = append(storageOpts, basicB.Options.MemoryStoreOption(memStore))
+			}
+			if builderOpts.SelfTracing {
+				tracer, closer, err := newSelfTracer(builderOpts)
+				if err != nil {
+					logger.Fatal("Failed to initialize self-tracing", zap.Error(err))
+				}
+				defer closer.Close()
+				storageOpts = append(storageOpts, basicB.Options.TracerOption(tracer))
+			}
+			handlerBuilder, err := builder.NewSpanHandlerBuilder(builderOpts, sFlags, storageOpts...)
 			if err != nil {
 				logger.Fatal("Unable to set up builder", zap.Error(err))
 			}
+			if builderOpts.BenchmarkMode {
+				logger.Warn("Benchmark mode, spans are decoded and validated but discarded instead of being saved to the span storage")
+			} else if builderOpts.SpanStore == builder.SpanStoreNoop {
+				logger.Warn("Spans are discarded instead of being saved to the span storage",
+					zap.Float64("noop-log-fraction", builderOpts.NoopLogFraction))
+			} else if builderOpts.SpanStore == builder.SpanStoreStdout {
+				logger.Warn("Spans are printed to stdout instead of being saved to the span storage")
+			}
+			logger.Info("Configured span processing queue",
+				zap.Int("queue-size", builderOpts.QueueSize),
+				zap.Int("num-workers", builderOpts.NumWorkers),
+				zap.String("queue-full-policy", builderOpts.QueueFullPolicy))
 
-			ch, err := tchannel.NewChannel(serviceName, &tchannel.ChannelOptions{})
-			if err != nil {
+			startupRetry := newStartupRetry(logger, builderOpts)
+			var ch *tchannel.Channel
+			if err := startupRetry.do("create TChannel", func() (err error) {
+				ch, err = newTChannel(builderOpts)
+				return err
+			}); err != nil {
 				logger.Fatal("Unable to create new TChannel", zap.Error(err))
 			}
 			server := thrift.NewServer(ch)
 			zipkinSpansHandler, jaegerBatchesHandler := handlerBuilder.BuildHandlers()
 			server.Register(jc.NewTChanCollectorServer(jaegerBatchesHandler))
 			server.Register(zc.NewTChanZipkinCollectorServer(zipkinSpansHandler))
+			if samplingManager := handlerBuilder.SamplingManager(); samplingManager != nil {
+				logger.Info("Serving sampling strategies",
+					zap.String("sampling-strategy", builderOpts.SamplingStrategy),
+					zap.String("strategies-file", builderOpts.SamplingStrategiesFile))
+				server.Register(sampling.NewTChanSamplingManagerServer(samplingManager))
+			}
 
-			portStr := ":" + strconv.Itoa(builderOpts.CollectorPort)
-			listener, err := net.Listen("tcp", portStr)
-			if err != nil {
+			if err := startupRetry.do("listen on TChannel port", func() error {
+				return serveTChannel(ch, builderOpts.CollectorPort, newListenerOptions(builderOpts))
+			}); err != nil {
 				logger.Fatal("Unable to start listening on channel", zap.Error(err))
 			}
-			ch.Serve(listener)
+			if len(builderOpts.HyperbahnNodes) > 0 {
+				var hyperbahnClient *hyperbahn.Client
+				if err := startupRetry.do("advertise on Hyperbahn", func() (err error) {
+					hyperbahnClient, err = advertiseOnHyperbahn(ch, builderOpts.HyperbahnNodes)
+					return err
+				}); err != nil {
+					logger.Fatal("Unable to advertise on Hyperbahn", zap.Error(err))
+				}
+				defer hyperbahnClient.Close()
+				logger.Info("Advertised on Hyperbahn",
+					zap.String("tchannel-service-name", ch.ServiceName()),
+					zap.Strings("hyperbahn-nodes", builderOpts.HyperbahnNodes))
+			}
+
+			// Failing to start a secondary listener is not fatal, the collector keeps accepting spans on
+			// the other listeners and reports itself unhealthy.
+			var secondaryListenerFailed bool
+			grpcServer, err := startGRPCServer(logger, builderOpts.CollectorGRPCPort, jaegerBatchesHandler, hc)
+			if err != nil {
+				logger.Error("Could not start gRPC server", zap.Error(err))
+				secondaryListenerFailed = true
+			}
 
-			r := mux.NewRouter()
-			apiHandler := app.NewAPIHandler(jaegerBatchesHandler)
+			var bodyLimiter *app.RequestBodyLimiter
+			if builderOpts.MaxBatchBytes > 0 {
+				bodyLimiter = app.NewRequestBodyLimiter(builderOpts.MaxBatchBytes, baseMetrics)
+			}
+
+			authenticator, err := newHTTPAuthenticator(builderOpts)
+			if err != nil {
+				logger.Fatal("Unable to configure the HTTP API authentication", zap.Error(err))
+			}
+			root, r := newAPIRouter(builderOpts.HTTPBasePath)
+			apiHandler := app.NewAPIHandler(
+				jaegerBatchesHandler,
+				app.HandlerOptions.RequestBodyLimiter(bodyLimiter),
+				app.HandlerOptions.SpanProcessor(handlerBuilder.SpanProcessor()),
+				app.HandlerOptions.MetricsFactory(baseMetrics),
+			)
 			apiHandler.RegisterRoutes(r)
-			httpPortStr := ":" + strconv.Itoa(builderOpts.CollectorHTTPPort)
-			recoveryHandler := recoveryhandler.NewRecoveryHandler(logger, true)
+			mBldr.RegisterRoute(r)
+			version.RegisterRoute(r, logger)
+			if staticStrategies := handlerBuilder.StaticStrategies(); staticStrategies != nil {
+				logger.Info("Serving the sampling strategies at /sampling/strategies")
+				static.RegisterRoute(r, staticStrategies)
+			}
+			if builderOpts.LogLevelEndpoint {
+				logger.Info("Serving the log level at /log-level")
+				loglevel.RegisterRoute(r, logConfig.Level)
+			}
+			if builderOpts.ExposeConfig {
+				logger.Info("Serving the resolved configuration at /config")
+				config.RegisterRoute(r, v)
+			}
+			if builderOpts.FlushEndpoint {
+				logger.Info("Serving the flush of the buffered spans at /flush")
+				app.RegisterFlushRoute(r, handlerBuilder.Flush)
+			}
+			recoveryHandler := recoveryhandler.NewRecoveryHandler(logger, true, recoveryhandler.Options.MetricsFactory(baseMetrics))
+			if builderOpts.HTTPAccessLog {
+				recoveryHandler = withAccessLog(logger, recoveryHandler)
+			}
 
-			go startZipkinHTTPAPI(logger, builderOpts.CollectorZipkinHTTPPort, zipkinSpansHandler, recoveryHandler)
+			if builderOpts.TLS.Enabled() {
+				if _, err := builderOpts.TLS.Config(); err != nil {
+					logger.Fatal("Invalid TLS configuration", zap.Error(err))
+				}
+			}
 
-			logger.Info("Starting Jaeger Collector HTTP server", zap.Int("http-port", builderOpts.CollectorHTTPPort))
+			if builderOpts.RateLimitQPS > 0 {
+				rateLimiter := app.NewRequestRateLimiter(builderOpts.RateLimitQPS, builderOpts.RateLimitBurst, builderOpts.RateLimitKeyHeader, app.DefaultRateLimitMaxClients, baseMetrics)
+				recoveryHandler = withRateLimit(rateLimiter, recoveryHandler)
+			}
 
-			go func() {
-				if err := http.ListenAndServe(httpPortStr, recoveryHandler(r)); err != nil {
-					logger.Fatal("Could not launch service", zap.Error(err))
+			zipkinOpts := []zipkin.HandlerOption{
+				zipkin.HandlerOptions.RequestBodyLimiter(bodyLimiter),
+				zipkin.HandlerOptions.CORS(builderOpts.CollectorZipkinAllowedOrigins, builderOpts.CollectorZipkinAllowedHeaders),
+			}
+			if builderOpts.CollectorZipkinStrictIDs {
+				zipkinOpts = append(zipkinOpts, zipkin.HandlerOptions.StrictIDs(baseMetrics))
+			}
+			zipkinServer, err := startZipkinHTTPAPI(logger, builderOpts.CollectorZipkinHTTPPort, zipkinSpansHandler, zipkinOpts, authenticator, recoveryHandler, newHTTPServerOptions(builderOpts), hc)
+			if err != nil {
+				if builderOpts.CollectorZipkinRequired {
+					logger.Fatal("Could not start Zipkin HTTP server", zap.Error(err))
 				}
+				logger.Error("Could not start Zipkin HTTP server", zap.Error(err))
+				secondaryListenerFailed = true
+			}
+
+			// the credentials are checked before the request body is decompressed
+			httpHandler := recoveryHandler(app.Authenticate(authenticator, gzipfilter.NewGzipFilter(root, builderOpts.MaxBatchBytes)))
+			onHTTPServeError := func(err error) {
 				hc.Set(http.StatusInternalServerError)
-			}()
+				logger.Fatal("Could not launch service", zap.Error(err))
+			}
+
+			httpServer, socketServer, err := startJaegerHTTPAPI(logger, builderOpts, httpHandler, onHTTPServeError)
+			if err != nil {
+				logger.Fatal("Could not launch service", zap.Error(err))
+			}
+			logger.Info("Accepting spans",
+				zap.Int("tchannel-port", builderOpts.CollectorPort),
+				zap.Int("grpc-port", builderOpts.CollectorGRPCPort),
+				zap.Bool("http-enabled", httpServer != nil || socketServer != nil),
+				zap.Int("zipkin.http-port", builderOpts.CollectorZipkinHTTPPort))
 
 			hc.Ready()
+			if err := readiness.ready(); err != nil {
+				logger.Warn("Could not create the readiness file", zap.String("readiness-file", builderOpts.ReadinessFile), zap.Error(err))
+			}
+			if secondaryListenerFailed {
+				hc.Set(http.StatusInternalServerError)
+			}
+			if probe := handlerBuilder.StorageProbe(); probe != nil {
+				hc.StartProbes(builderOpts.HealthCheckProbeInterval, probe)
+			}
 			select {
 			case <-signalsChannel:
-				logger.Info("Jaeger Collector is finishing")
+				logger.Info("Jaeger Collector is finishing", zap.Duration("shutdown-timeout", builderOpts.ShutdownTimeout))
+				hc.Close()
+				hc.Set(http.StatusServiceUnavailable)
+				if err := readiness.remove(); err != nil {
+					logger.Warn("Could not remove the readiness file", zap.String("readiness-file", builderOpts.ReadinessFile), zap.Error(err))
+				}
+				if !waitForShutdown(logger, signalsChannel, func() {
+					shutdown(logger, builderOpts.ShutdownTimeout, ch, grpcServer, handlerBuilder, httpServer, socketServer, zipkinServer)
+				}) {
+					os.Exit(1)
+				}
 			}
 		},
 	}
@@ -137,9 +343,12 @@ func main() {
 		command,
 		flags.AddConfigFileFlag,
 		flags.AddFlags,
+		pMetrics.AddFlags,
 		builder.AddFlags,
 		casOptions.AddFlags,
 		esOptions.AddFlags,
+		kafkaOptions.AddFlags,
+		memoryOptions.AddFlags,
 	)
 
 	if error := command.Execute(); error != nil {
@@ -147,20 +356,359 @@ func main() {
 	}
 }
 
+const channelClosePollInterval = 10 * time.Millisecond
+
+var (
+	errSelfTracingEndpoint       = errors.New("self-tracing requires an endpoint when the collector's HTTP API is disabled")
+	errSelfTracingSecureEndpoint = errors.New("self-tracing requires an endpoint when the collector's HTTP API uses TLS or authentication")
+)
+
+// newSelfTracer creates the tracer of the collector's own work, which reports to the self-tracing endpoint
+// or to the collector's own HTTP API. The process of its spans is tagged so that they are not traced in turn.
+// The tracer has no client certificate nor credentials, so the endpoint must be set when the collector's own
+// HTTP API requires them.
+func newSelfTracer(builderOpts *builder.CollectorOptions) (opentracing.Tracer, io.Closer, error) {
+	endpoint := builderOpts.SelfTracingEndpoint
+	if endpoint == "" {
+		if !builderOpts.CollectorHTTPEnabled || builderOpts.CollectorHTTPPort == 0 {
+			return nil, nil, errSelfTracingEndpoint
+		}
+		if builderOpts.TLS.Enabled() || (builderOpts.HTTPAuth != "" && builderOpts.HTTPAuth != builder.HTTPAuthNone) {
+			return nil, nil, errSelfTracingSecureEndpoint
+		}
+		endpoint = fmt.Sprintf("http://localhost:%d%s/api/traces?format=jaeger.thrift", builderOpts.CollectorHTTPPort, builderOpts.HTTPBasePath)
+	}
+	sampler, err := jaegerClient.NewProbabilisticSampler(builderOpts.SelfTracingSamplingRate)
+	if err != nil {
+		return nil, nil, err
+	}
+	tracer, closer := jaegerClient.NewTracer(
+		builderOpts.ServiceName,
+		sampler,
+		jaegerClient.NewRemoteReporter(transport.NewHTTPTransport(endpoint)),
+		jaegerClient.TracerOptions.Tag(app.SelfTraceTagKey, true),
+	)
+	return tracer, closer, nil
+}
+
+// newTChannel creates the collector's TChannel, which advertises the TChannel service name when one is
+// configured and the service name of the collector otherwise
+func newTChannel(builderOpts *builder.CollectorOptions) (*tchannel.Channel, error) {
+	serviceName := builderOpts.TChannelServiceName
+	if serviceName == "" {
+		serviceName = builderOpts.ServiceName
+	}
+	return tchannel.NewChannel(serviceName, &tchannel.ChannelOptions{})
+}
+
+// advertiseOnHyperbahn advertises the service of the channel to the Hyperbahn nodes, so that clients can
+// discover the collector through Hyperbahn. The channel must already be listening.
+func advertiseOnHyperbahn(ch *tchannel.Channel, nodes []string) (*hyperbahn.Client, error) {
+	client, err := hyperbahn.NewClient(ch, hyperbahn.Configuration{InitialNodes: nodes}, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := client.Advertise(); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+var errMissingBasicAuthCredentials = errors.New("basic authentication requires a username and a password")
+
+// newHTTPAuthenticator creates the authenticator of the collector's HTTP API, nil when no authentication is required
+func newHTTPAuthenticator(builderOpts *builder.CollectorOptions) (app.Authenticator, error) {
+	switch builderOpts.HTTPAuth {
+	case "", builder.HTTPAuthNone:
+		return nil, nil
+	case builder.HTTPAuthBasic:
+		if builderOpts.HTTPAuthUsername == "" || builderOpts.HTTPAuthPassword == "" {
+			return nil, errMissingBasicAuthCredentials
+		}
+		return app.NewBasicAuthenticator(builderOpts.HTTPAuthUsername, builderOpts.HTTPAuthPassword), nil
+	case builder.HTTPAuthBearer:
+		tokens := []string{builderOpts.HTTPAuthToken}
+		if builderOpts.HTTPAuthTokenFile != "" {
+			fileTokens, err := app.LoadBearerTokens(builderOpts.HTTPAuthTokenFile)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, fileTokens...)
+		}
+		return app.NewBearerAuthenticator(tokens)
+	}
+	return nil, fmt.Errorf("unsupported HTTP API authentication %q", builderOpts.HTTPAuth)
+}
+
 func startZipkinHTTPAPI(
 	logger *zap.Logger,
 	zipkinPort int,
 	zipkinSpansHandler app.ZipkinSpansHandler,
+	zipkinOpts []zipkin.HandlerOption,
+	authenticator app.Authenticator,
 	recoveryHandler func(http.Handler) http.Handler,
+	serverOpts httpServerOptions,
+	hc *healthcheck.State,
+) (*http.Server, error) {
+	if zipkinPort == 0 {
+		return nil, nil
+	}
+	r := mux.NewRouter()
+	zipkin.NewAPIHandler(zipkinSpansHandler, zipkinOpts...).RegisterRoutes(r)
+	logger.Info("Listening for Zipkin HTTP traffic", zap.Int("zipkin.http-port", zipkinPort))
+
+	return startHTTPServer(zipkinPort, recoveryHandler(app.Authenticate(authenticator, gzipfilter.NewGzipFilter(r, serverOpts.maxBodyBytes))), serverOpts, func(err error) {
+		logger.Error("Zipkin HTTP server failed", zap.Error(err))
+		hc.Set(http.StatusInternalServerError)
+	})
+}
+
+// startJaegerHTTPAPI starts the servers of the collector's HTTP API on the http port and socket, either
+// server is nil when it is not enabled. The http port may only be disabled when the API is served on a
+// socket instead.
+func startJaegerHTTPAPI(
+	logger *zap.Logger,
+	builderOpts *builder.CollectorOptions,
+	handler http.Handler,
+	onServeError func(error),
+) (httpServer *http.Server, socketServer *http.Server, err error) {
+	if !builderOpts.CollectorHTTPEnabled {
+		logger.Info("Jaeger Collector HTTP API is disabled")
+		return nil, nil, nil
+	}
+	serverOpts := newHTTPServerOptions(builderOpts)
+	if builderOpts.CollectorHTTPPort != 0 || builderOpts.CollectorHTTPSocket == "" {
+		logger.Info("Starting Jaeger Collector HTTP server",
+			zap.Int("http-port", builderOpts.CollectorHTTPPort),
+			zap.String("http-base-path", builderOpts.HTTPBasePath),
+			zap.Bool("tls", builderOpts.TLS.Enabled()))
+		if httpServer, err = startHTTPServer(builderOpts.CollectorHTTPPort, handler, serverOpts, onServeError); err != nil {
+			return nil, nil, err
+		}
+	}
+	if builderOpts.CollectorHTTPSocket != "" {
+		logger.Info("Starting Jaeger Collector HTTP server on a Unix domain socket",
+			zap.String("http-socket", builderOpts.CollectorHTTPSocket),
+			zap.Bool("tls", builderOpts.TLS.Enabled()))
+		if socketServer, err = startHTTPSocketServer(builderOpts.CollectorHTTPSocket, handler, serverOpts, onServeError); err != nil {
+			if httpServer != nil {
+				httpServer.Close()
+			}
+			return nil, nil, err
+		}
+	}
+	return httpServer, socketServer, nil
+}
+
+// newAPIRouter returns the root router to serve and the router to register the collector's http API on,
+// which is mounted under basePath so that the requests to other paths get a 404
+func newAPIRouter(basePath string) (*mux.Router, *mux.Router) {
+	root := mux.NewRouter()
+	if basePath == "" {
+		return root, root
+	}
+	return root, root.PathPrefix(basePath).Subrouter()
+}
+
+// startHTTPServer binds the port before returning, so that the caller can decide whether failing to
+// bind it is fatal, and then serves in the background. onServeError is called if serving fails.
+func startHTTPServer(port int, handler http.Handler, serverOpts httpServerOptions, onServeError func(error)) (*http.Server, error) {
+	listener, err := listenTCP(port, serverOpts.listener)
+	if err != nil {
+		return nil, err
+	}
+	return serveHTTP(listener, handler, serverOpts, onServeError), nil
+}
+
+// startHTTPSocketServer is like startHTTPServer but listens on a Unix domain socket. A socket file left
+// behind by a collector that did not shut down cleanly is replaced, and the socket file is removed when
+// the server is shut down.
+func startHTTPSocketServer(path string, handler http.Handler, serverOpts httpServerOptions, onServeError func(error)) (*http.Server, error) {
+	if info, err := os.Stat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	// Closing the listener, which Shutdown does, unlinks the socket file
+	listener.(*net.UnixListener).SetUnlinkOnClose(true)
+	return serveHTTP(listener, handler, serverOpts, onServeError), nil
+}
+
+// httpServerOptions holds the TLS, timeout, body size, and listener configuration shared by the collector's HTTP servers
+type httpServerOptions struct {
+	listener     listenerOptions
+	tls          tlscfg.Options
+	readTimeout  time.Duration
+	writeTimeout time.Duration
+	idleTimeout  time.Duration
+	// maxBodyBytes is the largest request body, once decompressed, that the server accepts, 0 for no limit
+	maxBodyBytes int64
+}
+
+func newHTTPServerOptions(builderOpts *builder.CollectorOptions) httpServerOptions {
+	return httpServerOptions{
+		listener:     newListenerOptions(builderOpts),
+		tls:          builderOpts.TLS,
+		readTimeout:  builderOpts.HTTPReadTimeout,
+		writeTimeout: builderOpts.HTTPWriteTimeout,
+		idleTimeout:  builderOpts.HTTPIdleTimeout,
+		maxBodyBytes: builderOpts.MaxBatchBytes,
+	}
+}
+
+func serveHTTP(listener net.Listener, handler http.Handler, serverOpts httpServerOptions, onServeError func(error)) *http.Server {
+	server := &http.Server{
+		Addr:         listener.Addr().String(),
+		Handler:      handler,
+		ReadTimeout:  serverOpts.readTimeout,
+		WriteTimeout: serverOpts.writeTimeout,
+		IdleTimeout:  serverOpts.idleTimeout,
+	}
+	go func() {
+		if err := serverOpts.tls.Serve(server, listener); err != http.ErrServerClosed {
+			onServeError(err)
+		}
+	}()
+	return server
+}
+
+// withAccessLog logs every request after it has gone through the recovery handler, so that
+// requests that panicked are logged with their 500 status.
+func withAccessLog(logger *zap.Logger, recoveryHandler func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	accessLogHandler := accesslog.NewAccessLogHandler(logger)
+	return func(h http.Handler) http.Handler {
+		return accessLogHandler(recoveryHandler(h))
+	}
+}
+
+// withRateLimit rejects requests over the rate limit after they have gone through the recovery
+// handler and the access log, so that rejected requests are logged.
+func withRateLimit(rateLimiter *app.RequestRateLimiter, recoveryHandler func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return recoveryHandler(rateLimiter.Limit(h))
+	}
+}
+
+func startGRPCServer(
+	logger *zap.Logger,
+	port int,
+	jaegerBatchesHandler app.JaegerBatchesHandler,
+	hc *healthcheck.State,
+) (*grpc.Server, error) {
+	listener, err := net.Listen("tcp", ":"+strconv.Itoa(port))
+	if err != nil {
+		return nil, err
+	}
+	server := collectorGRPC.NewServer(collectorGRPC.NewHandler(jaegerBatchesHandler))
+	logger.Info("Starting Jaeger Collector gRPC server", zap.Int("grpc-port", port))
+	go func() {
+		if err := server.Serve(listener); err != nil {
+			logger.Error("Could not launch gRPC service", zap.Error(err))
+			hc.Set(http.StatusInternalServerError)
+		}
+	}()
+	return server, nil
+}
+
+// waitForShutdown runs shutdown and waits for it to finish, unless another signal is received in the meantime,
+// e.g. a second SIGTERM from an operator who does not want to wait for the queue to drain. It returns false
+// if shutdown was cut short.
+func waitForShutdown(logger *zap.Logger, signals <-chan os.Signal, shutdown func()) bool {
+	done := make(chan struct{})
+	go func() {
+		shutdown()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case sig := <-signals:
+		logger.Warn("Exiting without waiting for the span queue to drain", zap.Stringer("signal", sig))
+		return false
+	}
+}
+
+// shutdown stops accepting new spans on all listeners, then waits for the queued spans to be written to storage.
+// Both share a single deadline of timeout.
+func shutdown(
+	logger *zap.Logger,
+	timeout time.Duration,
+	ch *tchannel.Channel,
+	grpcServer *grpc.Server,
+	handlerBuilder *builder.SpanHandlerBuilder,
+	httpServers ...*http.Server,
 ) {
-	if zipkinPort != 0 {
-		r := mux.NewRouter()
-		zipkin.NewAPIHandler(zipkinSpansHandler).RegisterRoutes(r)
-		httpPortStr := ":" + strconv.Itoa(zipkinPort)
-		logger.Info("Listening for Zipkin HTTP traffic", zap.Int("zipkin.http-port", zipkinPort))
-
-		if err := http.ListenAndServe(httpPortStr, recoveryHandler(r)); err != nil {
-			logger.Fatal("Could not launch service", zap.Error(err))
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	ch.Close()
+	if grpcServer != nil {
+		if err := stopGRPCServer(ctx, grpcServer); err != nil {
+			logger.Error("gRPC calls were still in progress after the shutdown timeout", zap.Error(err))
+		}
+	}
+	for _, server := range httpServers {
+		if server == nil {
+			continue
+		}
+		if err := server.Shutdown(ctx); err != nil {
+			logger.Error("Failed to shut down HTTP server", zap.String("addr", server.Addr), zap.Error(err))
+		}
+	}
+	if err := waitForChannelClosed(ctx, ch); err != nil {
+		logger.Error("TChannel calls were still in progress after the shutdown timeout", zap.Error(err))
+	}
+	if err := handlerBuilder.Shutdown(ctx); err != nil {
+		logger.Error("Failed to drain span processing queue", zap.Error(err))
+	}
+	logger.Info("Jaeger Collector has shut down")
+}
+
+// grpcStopper is the part of grpc.Server that stopGRPCServer uses
+type grpcStopper interface {
+	GracefulStop()
+	Stop()
+}
+
+// stopGRPCServer waits for the calls in progress to finish, and stops the server right away, closing
+// their connections, once ctx is done.
+func stopGRPCServer(ctx context.Context, server grpcStopper) error {
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		server.Stop()
+		<-stopped
+		return ctx.Err()
+	}
+}
+
+// channelStater is the part of tchannel.Channel that waitForChannelClosed polls
+type channelStater interface {
+	State() tchannel.ChannelState
+}
+
+// waitForChannelClosed waits until the calls in progress on a channel being closed are done, since
+// tchannel's Close only starts closing the channel. It gives up when ctx is done.
+func waitForChannelClosed(ctx context.Context, ch channelStater) error {
+	ticker := time.NewTicker(channelClosePollInterval)
+	defer ticker.Stop()
+	for ch.State() != tchannel.ChannelClosed {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
 		}
 	}
+	return nil
 }
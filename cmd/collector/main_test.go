@@ -0,0 +1,517 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/golang/snappy"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics"
+	"github.com/uber/tchannel-go"
+	tchanThrift "github.com/uber/tchannel-go/thrift"
+	"go.uber.org/zap"
+
+	"github.com/uber/jaeger/cmd/collector/app"
+	"github.com/uber/jaeger/cmd/collector/app/builder"
+	"github.com/uber/jaeger/pkg/healthcheck"
+	"github.com/uber/jaeger/pkg/recoveryhandler"
+	"github.com/uber/jaeger/pkg/testutils"
+	"github.com/uber/jaeger/pkg/tlscfg"
+	"github.com/uber/jaeger/thrift-gen/jaeger"
+	"github.com/uber/jaeger/thrift-gen/zipkincore"
+)
+
+type mockZipkinHandler struct{}
+
+func (mockZipkinHandler) SubmitZipkinBatch(ctx tchanThrift.Context, spans []*zipkincore.Span) ([]*zipkincore.Response, error) {
+	return nil, nil
+}
+
+type mockJaegerHandler struct{}
+
+func (mockJaegerHandler) SubmitBatches(ctx tchanThrift.Context, batches []*jaeger.Batch) ([]*jaeger.BatchSubmitResponse, error) {
+	return nil, nil
+}
+
+func TestStartZipkinHTTPAPIDisabled(t *testing.T) {
+	hc, _ := healthcheck.NewState(http.StatusNoContent, zap.NewNop())
+	server, err := startZipkinHTTPAPI(zap.NewNop(), 0, mockZipkinHandler{}, nil, nil, recoveryhandler.NewRecoveryHandler(zap.NewNop(), true), httpServerOptions{}, hc)
+	assert.NoError(t, err)
+	assert.Nil(t, server)
+}
+
+func TestStartZipkinHTTPAPIPortInUse(t *testing.T) {
+	logger := zap.NewNop()
+	hc, _ := healthcheck.NewState(http.StatusNoContent, logger)
+	recoveryHandler := recoveryhandler.NewRecoveryHandler(logger, true)
+
+	listener, err := net.Listen("tcp", ":0")
+	require.NoError(t, err)
+	defer listener.Close()
+	zipkinPort := listener.Addr().(*net.TCPAddr).Port
+
+	zipkinServer, err := startZipkinHTTPAPI(logger, zipkinPort, mockZipkinHandler{}, nil, nil, recoveryHandler, httpServerOptions{}, hc)
+	assert.Error(t, err)
+	assert.Nil(t, zipkinServer)
+
+	r := mux.NewRouter()
+	app.NewAPIHandler(mockJaegerHandler{}).RegisterRoutes(r)
+	httpServer, err := startHTTPServer(0, recoveryHandler(r), httpServerOptions{}, func(err error) {
+		t.Errorf("Jaeger HTTP server failed: %v", err)
+	})
+	require.NoError(t, err)
+	defer httpServer.Close()
+
+	batch, err := thrift.NewTSerializer().Write(&jaeger.Batch{Process: &jaeger.Process{ServiceName: "service"}})
+	require.NoError(t, err)
+	_, port, err := net.SplitHostPort(httpServer.Addr)
+	require.NoError(t, err)
+	res, err := http.Post("http://127.0.0.1:"+port+"/api/traces?format=jaeger.thrift", "application/x-thrift", bytes.NewReader(batch))
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusAccepted, res.StatusCode)
+}
+
+func TestStartZipkinHTTPAPIContentEncodings(t *testing.T) {
+	logger := zap.NewNop()
+	hc, _ := healthcheck.NewState(http.StatusNoContent, logger)
+	port := freePort(t)
+	server, err := startZipkinHTTPAPI(logger, port, mockZipkinHandler{}, nil, nil, recoveryhandler.NewRecoveryHandler(logger, true), httpServerOptions{}, hc)
+	require.NoError(t, err)
+	defer server.Close()
+
+	var gzipped bytes.Buffer
+	gz := gzip.NewWriter(&gzipped)
+	_, err = gz.Write([]byte("[]"))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+
+	tests := []struct {
+		encoding   string
+		body       []byte
+		statusCode int
+	}{
+		{encoding: "", body: []byte("[]"), statusCode: http.StatusAccepted},
+		{encoding: "gzip", body: gzipped.Bytes(), statusCode: http.StatusAccepted},
+		{encoding: "snappy", body: snappy.Encode(nil, []byte("[]")), statusCode: http.StatusAccepted},
+		{encoding: "zstd", body: []byte("[]"), statusCode: http.StatusUnsupportedMediaType},
+	}
+	for _, test := range tests {
+		req, err := http.NewRequest(http.MethodPost, "http://127.0.0.1:"+strconv.Itoa(port)+"/api/v1/spans", bytes.NewReader(test.body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		if test.encoding != "" {
+			req.Header.Set("Content-Encoding", test.encoding)
+		}
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err, test.encoding)
+		res.Body.Close()
+		assert.Equal(t, test.statusCode, res.StatusCode, test.encoding)
+	}
+}
+
+func TestStartZipkinHTTPAPIAuthentication(t *testing.T) {
+	logger := zap.NewNop()
+	hc, _ := healthcheck.NewState(http.StatusNoContent, logger)
+	port := freePort(t)
+	authenticator := app.NewBasicAuthenticator("jaeger", "secret")
+	server, err := startZipkinHTTPAPI(logger, port, mockZipkinHandler{}, nil, authenticator, recoveryhandler.NewRecoveryHandler(logger, true), httpServerOptions{}, hc)
+	require.NoError(t, err)
+	defer server.Close()
+
+	for _, path := range []string{"/api/v1/spans", "/api/v2/spans"} {
+		for _, password := range []string{"", "secret"} {
+			req, err := http.NewRequest(http.MethodPost, "http://127.0.0.1:"+strconv.Itoa(port)+path, bytes.NewReader([]byte("[]")))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			if password != "" {
+				req.SetBasicAuth("jaeger", password)
+			}
+			res, err := http.DefaultClient.Do(req)
+			require.NoError(t, err, path)
+			res.Body.Close()
+			if password == "" {
+				assert.Equal(t, http.StatusUnauthorized, res.StatusCode, path)
+			} else {
+				assert.Equal(t, http.StatusAccepted, res.StatusCode, path)
+			}
+		}
+	}
+}
+
+func TestNewAPIRouterBasePath(t *testing.T) {
+	root, r := newAPIRouter("/jaeger")
+	app.NewAPIHandler(mockJaegerHandler{}).RegisterRoutes(r)
+	server := httptest.NewServer(root)
+	defer server.Close()
+
+	batch, err := thrift.NewTSerializer().Write(&jaeger.Batch{Process: &jaeger.Process{ServiceName: "service"}})
+	require.NoError(t, err)
+	post := func(path string) int {
+		res, err := http.Post(server.URL+path+"?format=jaeger.thrift", "application/x-thrift", bytes.NewReader(batch))
+		require.NoError(t, err)
+		res.Body.Close()
+		return res.StatusCode
+	}
+	assert.Equal(t, http.StatusAccepted, post("/jaeger/api/traces"))
+	assert.Equal(t, http.StatusNotFound, post("/api/traces"))
+}
+
+func TestNewAPIRouterWithoutBasePath(t *testing.T) {
+	root, r := newAPIRouter("")
+	assert.True(t, root == r)
+}
+
+func TestWithRateLimit(t *testing.T) {
+	rateLimiter := app.NewRequestRateLimiter(1, 1, "", app.DefaultRateLimitMaxClients, metrics.NullFactory)
+	handler := withRateLimit(rateLimiter, recoveryhandler.NewRecoveryHandler(zap.NewNop(), true))(http.NotFoundHandler())
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/traces", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/traces", nil))
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+}
+
+func TestWaitForShutdown(t *testing.T) {
+	signals := make(chan os.Signal, 1)
+	var shutDown bool
+	assert.True(t, waitForShutdown(zap.NewNop(), signals, func() { shutDown = true }))
+	assert.True(t, shutDown)
+}
+
+func TestWaitForShutdownForced(t *testing.T) {
+	logger, logBuf := testutils.NewLogger()
+	signals := make(chan os.Signal, 1)
+	draining := make(chan struct{})
+	defer close(draining)
+	signals <- syscall.SIGTERM
+	assert.False(t, waitForShutdown(logger, signals, func() { <-draining }), "a second signal cuts the drain short")
+	assert.Equal(t, map[string]string{
+		"level":  "warn",
+		"msg":    "Exiting without waiting for the span queue to drain",
+		"signal": "terminated",
+	}, logBuf.JSONLine(0))
+}
+
+// stuckGRPCServer is a gRPC server whose GracefulStop only returns once Stop is called
+type stuckGRPCServer struct {
+	stop    chan struct{}
+	stopped bool
+}
+
+func (s *stuckGRPCServer) GracefulStop() { <-s.stop }
+
+func (s *stuckGRPCServer) Stop() {
+	s.stopped = true
+	close(s.stop)
+}
+
+func TestStopGRPCServer(t *testing.T) {
+	server := &stuckGRPCServer{stop: make(chan struct{})}
+	close(server.stop)
+	assert.NoError(t, stopGRPCServer(context.Background(), server))
+	assert.False(t, server.stopped)
+
+	server = &stuckGRPCServer{stop: make(chan struct{})}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, stopGRPCServer(ctx, server))
+	assert.True(t, server.stopped, "the server is stopped once the shutdown timeout is over")
+}
+
+// closingChannel reports ChannelClosing until it has been polled closingPolls times
+type closingChannel struct {
+	closingPolls int
+}
+
+func (ch *closingChannel) State() tchannel.ChannelState {
+	if ch.closingPolls == 0 {
+		return tchannel.ChannelClosed
+	}
+	ch.closingPolls--
+	return tchannel.ChannelClosing
+}
+
+func TestWaitForChannelClosed(t *testing.T) {
+	ch := &closingChannel{closingPolls: 3}
+	assert.NoError(t, waitForChannelClosed(context.Background(), ch))
+	assert.Equal(t, 0, ch.closingPolls)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, waitForChannelClosed(ctx, &closingChannel{closingPolls: 1000000}))
+}
+
+func TestStartHTTPServer(t *testing.T) {
+	server, err := startHTTPServer(0, http.NotFoundHandler(), httpServerOptions{}, func(err error) {
+		t.Errorf("HTTP server failed: %v", err)
+	})
+	require.NoError(t, err)
+	defer server.Close()
+
+	_, port, err := net.SplitHostPort(server.Addr)
+	require.NoError(t, err)
+	portNum, err := strconv.Atoi(port)
+	require.NoError(t, err)
+	assert.NotZero(t, portNum)
+
+	_, err = startHTTPServer(portNum, http.NotFoundHandler(), httpServerOptions{}, nil)
+	assert.Error(t, err, "the port is already in use")
+}
+
+func TestStartHTTPServerReadTimeout(t *testing.T) {
+	server, err := startHTTPServer(0, http.NotFoundHandler(), httpServerOptions{readTimeout: 100 * time.Millisecond}, func(err error) {
+		t.Errorf("HTTP server failed: %v", err)
+	})
+	require.NoError(t, err)
+	defer server.Close()
+	_, port, err := net.SplitHostPort(server.Addr)
+	require.NoError(t, err)
+
+	conn, err := net.Dial("tcp", "127.0.0.1:"+port)
+	require.NoError(t, err)
+	defer conn.Close()
+	// the headers are never finished, so the request cannot be read before the timeout
+	_, err = conn.Write([]byte("POST /api/traces HTTP/1.1\r\nHost: collector\r\n"))
+	require.NoError(t, err)
+
+	start := time.Now()
+	require.NoError(t, conn.SetReadDeadline(start.Add(5*time.Second)))
+	_, err = ioutil.ReadAll(conn)
+	assert.NoError(t, err, "the server closes the connection")
+	assert.True(t, time.Since(start) < 5*time.Second, "the connection is closed when the read times out")
+}
+
+func TestStartHTTPSocketServer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jaeger-collector")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	socketPath := filepath.Join(dir, "collector.sock")
+
+	// a socket file left behind by a previous collector is replaced
+	stale, err := net.ListenUnix("unix", &net.UnixAddr{Name: socketPath, Net: "unix"})
+	require.NoError(t, err)
+	stale.SetUnlinkOnClose(false)
+	stale.Close()
+
+	r := mux.NewRouter()
+	app.NewAPIHandler(mockJaegerHandler{}).RegisterRoutes(r)
+	server, err := startHTTPSocketServer(socketPath, recoveryhandler.NewRecoveryHandler(zap.NewNop(), true)(r), httpServerOptions{}, func(err error) {
+		t.Errorf("Jaeger HTTP socket server failed: %v", err)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, socketPath, server.Addr)
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socketPath)
+		},
+	}}
+	batch, err := thrift.NewTSerializer().Write(&jaeger.Batch{Process: &jaeger.Process{ServiceName: "service"}})
+	require.NoError(t, err)
+	res, err := client.Post("http://collector/api/traces?format=jaeger.thrift", "application/x-thrift", bytes.NewReader(batch))
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusAccepted, res.StatusCode)
+
+	require.NoError(t, server.Shutdown(context.Background()))
+	_, err = os.Stat(socketPath)
+	assert.True(t, os.IsNotExist(err), "the socket file is removed on shutdown")
+}
+
+func TestStartHTTPSocketServerBadPath(t *testing.T) {
+	_, err := startHTTPSocketServer(filepath.Join("does", "not", "exist", "collector.sock"), http.NotFoundHandler(), httpServerOptions{}, nil)
+	assert.Error(t, err)
+}
+
+func freePort(t *testing.T) int {
+	listener, err := net.Listen("tcp", ":0")
+	require.NoError(t, err)
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func TestStartJaegerHTTPAPI(t *testing.T) {
+	port := freePort(t)
+	httpServer, socketServer, err := startJaegerHTTPAPI(zap.NewNop(), &builder.CollectorOptions{
+		CollectorHTTPEnabled: true,
+		CollectorHTTPPort:    port,
+	}, http.NotFoundHandler(), func(err error) {
+		t.Errorf("HTTP server failed: %v", err)
+	})
+	require.NoError(t, err)
+	require.NotNil(t, httpServer)
+	defer httpServer.Close()
+	assert.Nil(t, socketServer)
+
+	_, err = net.Listen("tcp", ":"+strconv.Itoa(port))
+	assert.Error(t, err, "the http port is bound")
+}
+
+func TestStartJaegerHTTPAPIDisabled(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jaeger-collector")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	socketPath := filepath.Join(dir, "collector.sock")
+
+	port := freePort(t)
+	httpServer, socketServer, err := startJaegerHTTPAPI(zap.NewNop(), &builder.CollectorOptions{
+		CollectorHTTPEnabled: false,
+		CollectorHTTPPort:    port,
+		CollectorHTTPSocket:  socketPath,
+	}, http.NotFoundHandler(), nil)
+	require.NoError(t, err)
+	assert.Nil(t, httpServer)
+	assert.Nil(t, socketServer)
+
+	listener, err := net.Listen("tcp", ":"+strconv.Itoa(port))
+	require.NoError(t, err, "the http port is not bound")
+	listener.Close()
+	_, err = os.Stat(socketPath)
+	assert.True(t, os.IsNotExist(err), "the socket is not created")
+}
+
+func TestNewSelfTracer(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case received <- r:
+		default:
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	tracer, closer, err := newSelfTracer(&builder.CollectorOptions{
+		ServiceName:             builder.DefaultServiceName,
+		SelfTracingEndpoint:     server.URL + "/api/traces?format=jaeger.thrift",
+		SelfTracingSamplingRate: 1,
+	})
+	require.NoError(t, err)
+	tracer.StartSpan("ProcessSpans").Finish()
+	require.NoError(t, closer.Close())
+
+	select {
+	case r := <-received:
+		assert.Equal(t, "/api/traces", r.URL.Path)
+		assert.Equal(t, "jaeger.thrift", r.URL.Query().Get("format"))
+	case <-time.After(5 * time.Second):
+		t.Fatal("the self-trace was not reported")
+	}
+}
+
+func TestNewSelfTracerErrors(t *testing.T) {
+	_, _, err := newSelfTracer(&builder.CollectorOptions{SelfTracingSamplingRate: 1})
+	assert.Equal(t, errSelfTracingEndpoint, err)
+
+	_, _, err = newSelfTracer(&builder.CollectorOptions{
+		CollectorHTTPEnabled:    true,
+		CollectorHTTPPort:       14268,
+		TLS:                     tlscfg.Options{CertPath: "cert.pem", KeyPath: "key.pem"},
+		SelfTracingSamplingRate: 1,
+	})
+	assert.Equal(t, errSelfTracingSecureEndpoint, err)
+
+	_, _, err = newSelfTracer(&builder.CollectorOptions{
+		CollectorHTTPEnabled:    true,
+		CollectorHTTPPort:       14268,
+		HTTPAuth:                builder.HTTPAuthBearer,
+		SelfTracingSamplingRate: 1,
+	})
+	assert.Equal(t, errSelfTracingSecureEndpoint, err)
+
+	_, _, err = newSelfTracer(&builder.CollectorOptions{
+		CollectorHTTPEnabled:    true,
+		CollectorHTTPPort:       14268,
+		SelfTracingSamplingRate: 2,
+	})
+	assert.Error(t, err)
+}
+
+func TestNewTChannel(t *testing.T) {
+	ch, err := newTChannel(&builder.CollectorOptions{
+		ServiceName:         builder.DefaultServiceName,
+		TChannelServiceName: "tracing-collector",
+	})
+	require.NoError(t, err)
+	defer ch.Close()
+	assert.Equal(t, "tracing-collector", ch.ServiceName())
+
+	ch, err = newTChannel(&builder.CollectorOptions{ServiceName: builder.DefaultServiceName})
+	require.NoError(t, err)
+	defer ch.Close()
+	assert.Equal(t, builder.DefaultServiceName, ch.ServiceName(), "the channel defaults to the service name")
+}
+
+func TestNewHTTPAuthenticator(t *testing.T) {
+	authenticator, err := newHTTPAuthenticator(&builder.CollectorOptions{HTTPAuth: builder.HTTPAuthNone})
+	require.NoError(t, err)
+	assert.Nil(t, authenticator)
+
+	authenticator, err = newHTTPAuthenticator(&builder.CollectorOptions{
+		HTTPAuth:         builder.HTTPAuthBasic,
+		HTTPAuthUsername: "jaeger",
+		HTTPAuthPassword: "secret",
+	})
+	require.NoError(t, err)
+	assert.IsType(t, &app.BasicAuthenticator{}, authenticator)
+
+	_, err = newHTTPAuthenticator(&builder.CollectorOptions{HTTPAuth: builder.HTTPAuthBasic, HTTPAuthUsername: "jaeger"})
+	assert.Equal(t, errMissingBasicAuthCredentials, err)
+
+	dir, err := ioutil.TempDir("", "jaeger-collector")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	tokenFile := filepath.Join(dir, "tokens")
+	require.NoError(t, ioutil.WriteFile(tokenFile, []byte("# agents\ntoken-1\n\ntoken-2\n"), 0600))
+	authenticator, err = newHTTPAuthenticator(&builder.CollectorOptions{
+		HTTPAuth:          builder.HTTPAuthBearer,
+		HTTPAuthTokenFile: tokenFile,
+	})
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, "/api/traces", nil)
+	req.Header.Set("Authorization", "Bearer token-2")
+	assert.True(t, authenticator.Authenticate(req))
+
+	_, err = newHTTPAuthenticator(&builder.CollectorOptions{HTTPAuth: builder.HTTPAuthBearer})
+	assert.Equal(t, app.ErrNoBearerTokens, err)
+
+	_, err = newHTTPAuthenticator(&builder.CollectorOptions{
+		HTTPAuth:          builder.HTTPAuthBearer,
+		HTTPAuthTokenFile: filepath.Join(dir, "missing"),
+	})
+	assert.Error(t, err)
+
+	_, err = newHTTPAuthenticator(&builder.CollectorOptions{HTTPAuth: "digest"})
+	assert.EqualError(t, err, `unsupported HTTP API authentication "digest"`)
+}
@@ -0,0 +1,43 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+)
+
+// readinessFile tells the processes that share the collector's filesystem, e.g. a sidecar, that the
+// collector is ready to accept spans by existing. The zero value, without a path, does nothing.
+type readinessFile string
+
+// ready creates the file, or updates its modification time when it is left over from an earlier run
+func (f readinessFile) ready() error {
+	if f == "" {
+		return nil
+	}
+	return ioutil.WriteFile(string(f), nil, 0644)
+}
+
+// remove deletes the file, it is not an error for the file not to exist
+func (f readinessFile) remove() error {
+	if f == "" {
+		return nil
+	}
+	if err := os.Remove(string(f)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadinessFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "readiness")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "ready")
+	f := readinessFile(path)
+	require.NoError(t, f.remove(), "a missing file is not an error")
+
+	require.NoError(t, f.ready())
+	_, err = os.Stat(path)
+	assert.NoError(t, err, "the file appears when the collector is ready")
+	require.NoError(t, f.ready(), "a file left over from an earlier run is reused")
+
+	require.NoError(t, f.remove())
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err), "the file disappears on shutdown")
+}
+
+func TestReadinessFileErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "readiness")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	f := readinessFile(filepath.Join(dir, "missing", "ready"))
+	assert.Error(t, f.ready())
+
+	// a directory cannot be removed as long as it is not empty
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "file"), nil, 0644))
+	assert.Error(t, readinessFile(dir).remove())
+}
+
+func TestReadinessFileDisabled(t *testing.T) {
+	var f readinessFile
+	assert.NoError(t, f.ready())
+	assert.NoError(t, f.remove())
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"time"
+
+	"github.com/uber/tchannel-go"
+	"go.uber.org/zap"
+
+	"github.com/uber/jaeger/cmd/collector/app/builder"
+)
+
+// maxStartupRetryBackoff caps the backoff between two attempts, which doubles after every failure
+const maxStartupRetryBackoff = time.Minute
+
+// startupRetry retries the steps of the collector's startup that can fail transiently, e.g. binding a
+// port that is still held by the collector being replaced
+type startupRetry struct {
+	logger *zap.Logger
+	// retries is how many times a failed step is retried, 0 fails on the first error
+	retries int
+	// backoff is how long to wait before the first retry
+	backoff time.Duration
+	sleep   func(time.Duration)
+}
+
+func newStartupRetry(logger *zap.Logger, builderOpts *builder.CollectorOptions) startupRetry {
+	return startupRetry{
+		logger:  logger,
+		retries: builderOpts.StartupRetries,
+		backoff: builderOpts.StartupRetryBackoff,
+		sleep:   time.Sleep,
+	}
+}
+
+// do calls fn until it succeeds or the retries are exhausted, and returns the error of the last attempt
+func (r startupRetry) do(step string, fn func() error) error {
+	backoff := r.backoff
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt > r.retries {
+			return err
+		}
+		r.logger.Warn("Collector startup step failed, retrying",
+			zap.String("step", step),
+			zap.Int("attempt", attempt),
+			zap.Int("retries", r.retries),
+			zap.Duration("backoff", backoff),
+			zap.Error(err))
+		r.sleep(backoff)
+		if backoff *= 2; backoff > maxStartupRetryBackoff {
+			backoff = maxStartupRetryBackoff
+		}
+	}
+}
+
+// serveTChannel listens on the TChannel port and serves the channel on it
+func serveTChannel(ch *tchannel.Channel, port int, opts listenerOptions) error {
+	listener, err := listenTCP(port, opts)
+	if err != nil {
+		return err
+	}
+	if err := ch.Serve(listener); err != nil {
+		listener.Close()
+		return err
+	}
+	return nil
+}
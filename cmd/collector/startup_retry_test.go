@@ -0,0 +1,111 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/tchannel-go"
+	"go.uber.org/zap"
+)
+
+func TestStartupRetry(t *testing.T) {
+	var backoffs []time.Duration
+	retry := startupRetry{
+		logger:  zap.NewNop(),
+		retries: 3,
+		backoff: 40 * time.Second,
+		sleep:   func(d time.Duration) { backoffs = append(backoffs, d) },
+	}
+
+	attempts := 0
+	err := retry.do("test", func() error {
+		attempts++
+		if attempts < 3 {
+			return errors.New("transient")
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+	assert.Equal(t, []time.Duration{40 * time.Second, maxStartupRetryBackoff}, backoffs)
+
+	attempts = 0
+	err = retry.do("test", func() error {
+		attempts++
+		return errors.New("permanent")
+	})
+	assert.EqualError(t, err, "permanent")
+	assert.Equal(t, 4, attempts, "the first attempt and three retries")
+}
+
+func TestStartupRetryNoRetries(t *testing.T) {
+	retry := startupRetry{
+		logger: zap.NewNop(),
+		sleep:  func(time.Duration) { t.Fatal("a step is not retried without retries") },
+	}
+	attempts := 0
+	err := retry.do("test", func() error {
+		attempts++
+		return errors.New("failed")
+	})
+	assert.EqualError(t, err, "failed")
+	assert.Equal(t, 1, attempts)
+}
+
+func TestServeTChannelRetriesBindFailure(t *testing.T) {
+	blocker, err := net.Listen("tcp", ":0")
+	require.NoError(t, err)
+	port := blocker.Addr().(*net.TCPAddr).Port
+
+	ch, err := tchannel.NewChannel("jaeger-collector", &tchannel.ChannelOptions{})
+	require.NoError(t, err)
+	defer ch.Close()
+
+	// the port is released while the collector backs off, like when the previous collector finally exits
+	retry := startupRetry{
+		logger:  zap.NewNop(),
+		retries: 2,
+		backoff: time.Millisecond,
+		sleep:   func(time.Duration) { blocker.Close() },
+	}
+	attempts := 0
+	err = retry.do("listen on TChannel port", func() error {
+		attempts++
+		return serveTChannel(ch, port, listenerOptions{})
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, attempts)
+	assert.Equal(t, tchannel.ChannelListening, ch.State())
+}
+
+func TestServeTChannelBindFailure(t *testing.T) {
+	blocker, err := net.Listen("tcp", ":0")
+	require.NoError(t, err)
+	defer blocker.Close()
+
+	ch, err := tchannel.NewChannel("jaeger-collector", &tchannel.ChannelOptions{})
+	require.NoError(t, err)
+	defer ch.Close()
+
+	err = serveTChannel(ch, blocker.Addr().(*net.TCPAddr).Port, listenerOptions{})
+	assert.Error(t, err)
+	assert.Equal(t, tchannel.ChannelClient, ch.State())
+}
@@ -16,7 +16,9 @@ package cassandra
 
 import (
 	"flag"
+	"fmt"
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 
@@ -27,13 +29,27 @@ const (
 	suffixConnPerHost      = ".connections-per-host"
 	suffixMaxRetryAttempts = ".max-retry-attempts"
 	suffixTimeout          = ".timeout"
+	suffixReconnectInt     = ".reconnect-interval"
 	suffixServers          = ".servers"
 	suffixPort             = ".port"
 	suffixKeyspace         = ".keyspace"
 	suffixProtoVer         = ".proto-version"
 	suffixSocketKeepAlive  = ".socket-keep-alive"
+	suffixConsistency      = ".consistency"
+	suffixLocalDC          = ".local-dc"
 	suffixUsername         = ".username"
 	suffixPassword         = ".password"
+	suffixTLS              = ".tls.enabled"
+	suffixTLSCA            = ".tls.ca"
+	suffixTLSCert          = ".tls.cert"
+	suffixTLSKey           = ".tls.key"
+	suffixTenants          = ".tenants"
+	suffixTenantTag        = ".tenant-tag"
+	suffixSpanTTL          = ".span-ttl"
+	suffixServiceTTLFile   = ".service-ttl-file"
+	suffixSchemaCheck      = ".schema-check"
+
+	defaultTenantTag = "tenant"
 )
 
 // TODO this should be moved next to config.Configuration struct (maybe ./flags package)
@@ -45,6 +61,14 @@ type Options struct {
 	primary *namespaceConfig
 
 	others map[string]*namespaceConfig
+
+	// tenants is a comma-separated list of tenant=keyspace pairs, which applies to the primary namespace only
+	tenants   string
+	tenantTag string
+
+	// spanTTL and serviceTTLFile apply to the spans written to the primary namespace only
+	spanTTL        time.Duration
+	serviceTTLFile string
 }
 
 // the Servers field in config.Configuration is a list, which we cannot represent with flags.
@@ -66,6 +90,9 @@ func NewOptions(primaryNamespace string, otherNamespaces ...string) *Options {
 				Keyspace:           "jaeger_v1_local",
 				ProtoVersion:       4,
 				ConnectionsPerHost: 2,
+				ReconnectInterval:  60 * time.Second,
+				Consistency:        "LOCAL_ONE",
+				SchemaCheck:        config.SchemaCheckWarn,
 			},
 			servers:   "127.0.0.1",
 			namespace: primaryNamespace,
@@ -83,6 +110,26 @@ func NewOptions(primaryNamespace string, otherNamespaces ...string) *Options {
 // AddFlags adds flags for Options
 func (opt *Options) AddFlags(flagSet *flag.FlagSet) {
 	addFlags(flagSet, opt.primary)
+	flagSet.String(
+		opt.primary.namespace+suffixTenants,
+		"",
+		"The comma-separated list of tenant=keyspace pairs, spans of a tenant are written to its keyspace")
+	flagSet.String(
+		opt.primary.namespace+suffixTenantTag,
+		defaultTenantTag,
+		"The span tag that holds the tenant of a span, spans without it are written to the primary keyspace")
+	flagSet.Duration(
+		opt.primary.namespace+suffixSpanTTL,
+		0,
+		"The TTL of the spans of services that are not listed in the service TTL file (0 uses the default TTL of the tables)")
+	flagSet.String(
+		opt.primary.namespace+suffixServiceTTLFile,
+		"",
+		`The path of a JSON file with the TTL of the spans of each service, of the form {"services": {"service-name": "168h"}}`)
+	flagSet.String(
+		opt.primary.namespace+suffixSchemaCheck,
+		opt.primary.SchemaCheck,
+		"What to do at startup when the keyspace is missing tables: warn, fail, or create-defaults to create them with the default schema")
 	for _, cfg := range opt.others {
 		addFlags(flagSet, cfg)
 	}
@@ -92,7 +139,7 @@ func addFlags(flagSet *flag.FlagSet, nsConfig *namespaceConfig) {
 	flagSet.Int(
 		nsConfig.namespace+suffixConnPerHost,
 		nsConfig.ConnectionsPerHost,
-		"The number of Cassandra connections from a single backend instance")
+		"The number of Cassandra connections from a single backend instance to each Cassandra server")
 	flagSet.Int(
 		nsConfig.namespace+suffixMaxRetryAttempts,
 		nsConfig.MaxRetryAttempts,
@@ -101,6 +148,10 @@ func addFlags(flagSet *flag.FlagSet, nsConfig *namespaceConfig) {
 		nsConfig.namespace+suffixTimeout,
 		nsConfig.Timeout,
 		"Timeout used for queries")
+	flagSet.Duration(
+		nsConfig.namespace+suffixReconnectInt,
+		nsConfig.ReconnectInterval,
+		"How often to try to reconnect to the Cassandra servers that are down (0 disables reconnecting)")
 	flagSet.String(
 		nsConfig.namespace+suffixServers,
 		nsConfig.servers,
@@ -121,6 +172,14 @@ func addFlags(flagSet *flag.FlagSet, nsConfig *namespaceConfig) {
 		nsConfig.namespace+suffixSocketKeepAlive,
 		nsConfig.SocketKeepAlive,
 		"Cassandra's keepalive period to use, enabled if > 0")
+	flagSet.String(
+		nsConfig.namespace+suffixConsistency,
+		nsConfig.Consistency,
+		"The Cassandra consistency level of the queries, e.g. LOCAL_QUORUM")
+	flagSet.String(
+		nsConfig.namespace+suffixLocalDC,
+		nsConfig.LocalDC,
+		"The Cassandra data center whose servers are queried, instead of the servers of all data centers")
 	flagSet.String(
 		nsConfig.namespace+suffixUsername,
 		nsConfig.Authenticator.Basic.Username,
@@ -129,11 +188,32 @@ func addFlags(flagSet *flag.FlagSet, nsConfig *namespaceConfig) {
 		nsConfig.namespace+suffixPassword,
 		nsConfig.Authenticator.Basic.Password,
 		"Password for password authentication for Cassandra")
+	flagSet.Bool(
+		nsConfig.namespace+suffixTLS,
+		nsConfig.TLS.Enabled,
+		"Connect to Cassandra over TLS, verifying the certificates and host names of the servers")
+	flagSet.String(
+		nsConfig.namespace+suffixTLSCA,
+		nsConfig.TLS.CaPath,
+		"Path to a TLS CA file used to verify the certificates of the Cassandra servers (default is the system's CAs)")
+	flagSet.String(
+		nsConfig.namespace+suffixTLSCert,
+		nsConfig.TLS.CertPath,
+		"Path to a TLS client certificate file presented to the Cassandra servers")
+	flagSet.String(
+		nsConfig.namespace+suffixTLSKey,
+		nsConfig.TLS.KeyPath,
+		"Path to the TLS private key file of the client certificate")
 }
 
 // InitFromViper initializes Options with properties from viper
 func (opt *Options) InitFromViper(v *viper.Viper) {
 	initFromViper(opt.primary, v)
+	opt.tenants = v.GetString(opt.primary.namespace + suffixTenants)
+	opt.tenantTag = v.GetString(opt.primary.namespace + suffixTenantTag)
+	opt.spanTTL = v.GetDuration(opt.primary.namespace + suffixSpanTTL)
+	opt.serviceTTLFile = v.GetString(opt.primary.namespace + suffixServiceTTLFile)
+	opt.primary.SchemaCheck = v.GetString(opt.primary.namespace + suffixSchemaCheck)
 	for _, cfg := range opt.others {
 		initFromViper(cfg, v)
 	}
@@ -143,13 +223,20 @@ func initFromViper(cfg *namespaceConfig, v *viper.Viper) {
 	cfg.ConnectionsPerHost = v.GetInt(cfg.namespace + suffixConnPerHost)
 	cfg.MaxRetryAttempts = v.GetInt(cfg.namespace + suffixMaxRetryAttempts)
 	cfg.Timeout = v.GetDuration(cfg.namespace + suffixTimeout)
+	cfg.ReconnectInterval = v.GetDuration(cfg.namespace + suffixReconnectInt)
 	cfg.servers = v.GetString(cfg.namespace + suffixServers)
 	cfg.Port = v.GetInt(cfg.namespace + suffixPort)
 	cfg.Keyspace = v.GetString(cfg.namespace + suffixKeyspace)
 	cfg.ProtoVersion = v.GetInt(cfg.namespace + suffixProtoVer)
 	cfg.SocketKeepAlive = v.GetDuration(cfg.namespace + suffixSocketKeepAlive)
+	cfg.Consistency = v.GetString(cfg.namespace + suffixConsistency)
+	cfg.LocalDC = v.GetString(cfg.namespace + suffixLocalDC)
 	cfg.Authenticator.Basic.Username = v.GetString(cfg.namespace + suffixUsername)
 	cfg.Authenticator.Basic.Password = v.GetString(cfg.namespace + suffixPassword)
+	cfg.TLS.Enabled = v.GetBool(cfg.namespace + suffixTLS)
+	cfg.TLS.CaPath = v.GetString(cfg.namespace + suffixTLSCA)
+	cfg.TLS.CertPath = v.GetString(cfg.namespace + suffixTLSCert)
+	cfg.TLS.KeyPath = v.GetString(cfg.namespace + suffixTLSKey)
 }
 
 // GetPrimary returns primary configuration.
@@ -172,3 +259,44 @@ func (opt *Options) Get(namespace string) *config.Configuration {
 	nsCfg.Servers = strings.Split(nsCfg.servers, ",")
 	return &nsCfg.Configuration
 }
+
+// GetTenantTag returns the span tag that holds the tenant of a span.
+func (opt *Options) GetTenantTag() string {
+	if opt.tenantTag == "" {
+		return defaultTenantTag
+	}
+	return opt.tenantTag
+}
+
+// GetSpanTTL returns the TTL of the spans of services that are not listed in the service TTL file.
+func (opt *Options) GetSpanTTL() time.Duration {
+	return opt.spanTTL
+}
+
+// GetServiceTTLFile returns the path of the file with the TTL of the spans of each service.
+func (opt *Options) GetServiceTTLFile() string {
+	return opt.serviceTTLFile
+}
+
+// GetTenants returns the configuration for each tenant, which is the primary configuration
+// with the tenant's keyspace.
+func (opt *Options) GetTenants() (map[string]*config.Configuration, error) {
+	tenants := make(map[string]*config.Configuration)
+	if opt.tenants == "" {
+		return tenants, nil
+	}
+	primary := opt.GetPrimary()
+	for _, pair := range strings.Split(opt.tenants, ",") {
+		parts := strings.Split(strings.TrimSpace(pair), "=")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid tenant %q, expected tenant=keyspace", pair)
+		}
+		if _, ok := tenants[parts[0]]; ok {
+			return nil, fmt.Errorf("tenant %s is listed more than once", parts[0])
+		}
+		tenantCfg := *primary
+		tenantCfg.Keyspace = parts[1]
+		tenants[parts[0]] = &tenantCfg
+	}
+	return tenants, nil
+}
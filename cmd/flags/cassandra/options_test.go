@@ -18,7 +18,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/gocql/gocql"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/uber/jaeger/pkg/config"
 )
@@ -29,6 +31,7 @@ func TestOptions(t *testing.T) {
 	assert.NotEmpty(t, primary.Keyspace)
 	assert.NotEmpty(t, primary.Servers)
 	assert.Equal(t, 2, primary.ConnectionsPerHost)
+	assert.Equal(t, "warn", primary.SchemaCheck)
 
 	aux := opts.Get("archive")
 	assert.Equal(t, primary.Keyspace, aux.Keyspace)
@@ -45,9 +48,11 @@ func TestOptionsWithFlags(t *testing.T) {
 		"--cas.connections-per-host=42",
 		"--cas.max-retry-attempts=42",
 		"--cas.timeout=42s",
+		"--cas.reconnect-interval=42s",
 		"--cas.port=4242",
 		"--cas.proto-version=3",
 		"--cas.socket-keep-alive=42s",
+		"--cas.schema-check=fail",
 		// a couple overrides
 		"--cas.aux.keyspace=jaeger-archive",
 		"--cas.aux.servers=3.3.3.3,4.4.4.4",
@@ -57,6 +62,7 @@ func TestOptionsWithFlags(t *testing.T) {
 	primary := opts.GetPrimary()
 	assert.Equal(t, "jaeger", primary.Keyspace)
 	assert.Equal(t, []string{"1.1.1.1", "2.2.2.2"}, primary.Servers)
+	assert.Equal(t, "fail", primary.SchemaCheck)
 
 	aux := opts.Get("cas.aux")
 	assert.Equal(t, "jaeger-archive", aux.Keyspace)
@@ -64,7 +70,194 @@ func TestOptionsWithFlags(t *testing.T) {
 	assert.Equal(t, 42, aux.ConnectionsPerHost)
 	assert.Equal(t, 42, aux.MaxRetryAttempts)
 	assert.Equal(t, 42*time.Second, aux.Timeout)
+	assert.Equal(t, 42*time.Second, aux.ReconnectInterval)
 	assert.Equal(t, 4242, aux.Port)
 	assert.Equal(t, 3, aux.ProtoVersion)
 	assert.Equal(t, 42*time.Second, aux.SocketKeepAlive)
 }
+
+func TestOptionsAuthAndTLS(t *testing.T) {
+	opts := NewOptions("cas", "cas.aux")
+	v, command := config.Viperize(opts.AddFlags)
+	command.ParseFlags([]string{
+		"--cas.username=jaeger",
+		"--cas.password=secret",
+		"--cas.tls.enabled=true",
+		"--cas.tls.ca=/etc/cassandra/ca.pem",
+		"--cas.tls.cert=/etc/cassandra/client.pem",
+		"--cas.tls.key=/etc/cassandra/client-key.pem",
+	})
+	opts.InitFromViper(v)
+
+	cluster, err := opts.GetPrimary().NewCluster()
+	require.NoError(t, err)
+	assert.Equal(t, gocql.PasswordAuthenticator{Username: "jaeger", Password: "secret"}, cluster.Authenticator)
+	require.NotNil(t, cluster.SslOpts)
+	assert.Equal(t, "/etc/cassandra/ca.pem", cluster.SslOpts.CaPath)
+	assert.Equal(t, "/etc/cassandra/client.pem", cluster.SslOpts.CertPath)
+	assert.Equal(t, "/etc/cassandra/client-key.pem", cluster.SslOpts.KeyPath)
+	assert.True(t, cluster.SslOpts.EnableHostVerification)
+
+	cluster, err = opts.Get("cas.aux").NewCluster()
+	require.NoError(t, err)
+	assert.Nil(t, cluster.Authenticator)
+	assert.Nil(t, cluster.SslOpts)
+}
+
+func TestOptionsConsistencyAndLocalDC(t *testing.T) {
+	opts := NewOptions("cas", "cas.aux")
+	v, command := config.Viperize(opts.AddFlags)
+	command.ParseFlags([]string{
+		"--cas.consistency=local_quorum",
+		"--cas.local-dc=dc1",
+		"--cas.aux.consistency=ONE",
+	})
+	opts.InitFromViper(v)
+
+	primary := opts.GetPrimary()
+	assert.Equal(t, "local_quorum", primary.Consistency)
+	assert.Equal(t, "dc1", primary.LocalDC)
+	cluster, err := primary.NewCluster()
+	require.NoError(t, err)
+	assert.Equal(t, gocql.LocalQuorum, cluster.Consistency)
+
+	aux := opts.Get("cas.aux")
+	assert.Equal(t, "ONE", aux.Consistency)
+	assert.Equal(t, "dc1", aux.LocalDC)
+}
+
+func TestOptionsDefaultConsistency(t *testing.T) {
+	opts := NewOptions("cas")
+	v, command := config.Viperize(opts.AddFlags)
+	command.ParseFlags([]string{})
+	opts.InitFromViper(v)
+
+	cluster, err := opts.GetPrimary().NewCluster()
+	require.NoError(t, err)
+	assert.Equal(t, gocql.LocalOne, cluster.Consistency)
+}
+
+func TestOptionsPoolAndTimeouts(t *testing.T) {
+	opts := NewOptions("cas", "cas.aux")
+	v, command := config.Viperize(opts.AddFlags)
+	command.ParseFlags([]string{})
+	opts.InitFromViper(v)
+
+	cluster, err := opts.GetPrimary().NewCluster()
+	require.NoError(t, err)
+	assert.Equal(t, 2, cluster.NumConns)
+	assert.Equal(t, 60*time.Second, cluster.ReconnectInterval)
+	assert.Equal(t, &gocql.SimpleRetryPolicy{NumRetries: 2}, cluster.RetryPolicy)
+
+	v, command = config.Viperize(opts.AddFlags)
+	command.ParseFlags([]string{
+		"--cas.connections-per-host=16",
+		"--cas.timeout=3s",
+		"--cas.reconnect-interval=15s",
+		"--cas.max-retry-attempts=5",
+		"--cas.aux.connections-per-host=4",
+	})
+	opts.InitFromViper(v)
+
+	cluster, err = opts.GetPrimary().NewCluster()
+	require.NoError(t, err)
+	assert.Equal(t, 16, cluster.NumConns)
+	assert.Equal(t, 3*time.Second, cluster.Timeout)
+	assert.Equal(t, 15*time.Second, cluster.ReconnectInterval)
+	assert.Equal(t, &gocql.SimpleRetryPolicy{NumRetries: 4}, cluster.RetryPolicy)
+
+	cluster, err = opts.Get("cas.aux").NewCluster()
+	require.NoError(t, err)
+	assert.Equal(t, 4, cluster.NumConns)
+	assert.Equal(t, 3*time.Second, cluster.Timeout)
+	assert.Equal(t, 15*time.Second, cluster.ReconnectInterval)
+}
+
+func TestOptionsInvalidPool(t *testing.T) {
+	opts := NewOptions("cas")
+	v, command := config.Viperize(opts.AddFlags)
+	command.ParseFlags([]string{"--cas.connections-per-host=0"})
+	opts.InitFromViper(v)
+
+	_, err := opts.GetPrimary().NewSession()
+	assert.EqualError(t, err, "invalid Cassandra connections per host 0, must be at least 1")
+}
+
+func TestOptionsInvalidConsistency(t *testing.T) {
+	opts := NewOptions("cas")
+	v, command := config.Viperize(opts.AddFlags)
+	command.ParseFlags([]string{"--cas.consistency=LOCAL_MOST"})
+	opts.InitFromViper(v)
+
+	_, err := opts.GetPrimary().NewCluster()
+	assert.EqualError(t, err, `invalid Cassandra consistency "LOCAL_MOST"`)
+	_, err = opts.GetPrimary().NewSession()
+	assert.EqualError(t, err, `invalid Cassandra consistency "LOCAL_MOST"`)
+}
+
+func TestOptionsTenants(t *testing.T) {
+	opts := NewOptions("cas")
+	v, command := config.Viperize(opts.AddFlags)
+	command.ParseFlags([]string{
+		"--cas.keyspace=jaeger",
+		"--cas.servers=1.1.1.1",
+		"--cas.tenants=acme=jaeger_acme, initech=jaeger_initech",
+		"--cas.tenant-tag=customer",
+	})
+	opts.InitFromViper(v)
+
+	tenants, err := opts.GetTenants()
+	require.NoError(t, err)
+	require.Len(t, tenants, 2)
+	assert.Equal(t, "jaeger_acme", tenants["acme"].Keyspace)
+	assert.Equal(t, "jaeger_initech", tenants["initech"].Keyspace)
+	assert.Equal(t, []string{"1.1.1.1"}, tenants["acme"].Servers)
+	assert.Equal(t, "jaeger", opts.GetPrimary().Keyspace, "the primary keyspace is not modified")
+	assert.Equal(t, "customer", opts.GetTenantTag())
+}
+
+func TestOptionsSpanTTL(t *testing.T) {
+	opts := NewOptions("cas")
+	v, command := config.Viperize(opts.AddFlags)
+	command.ParseFlags([]string{
+		"--cas.span-ttl=48h",
+		"--cas.service-ttl-file=service_ttls.json",
+	})
+	opts.InitFromViper(v)
+
+	assert.Equal(t, 48*time.Hour, opts.GetSpanTTL())
+	assert.Equal(t, "service_ttls.json", opts.GetServiceTTLFile())
+}
+
+func TestOptionsNoTenants(t *testing.T) {
+	opts := NewOptions("cas")
+	v, command := config.Viperize(opts.AddFlags)
+	command.ParseFlags([]string{})
+	opts.InitFromViper(v)
+
+	tenants, err := opts.GetTenants()
+	require.NoError(t, err)
+	assert.Empty(t, tenants)
+	assert.Equal(t, "tenant", opts.GetTenantTag())
+}
+
+func TestOptionsInvalidTenants(t *testing.T) {
+	testCases := []struct {
+		tenants string
+		err     string
+	}{
+		{tenants: "acme", err: `invalid tenant "acme", expected tenant=keyspace`},
+		{tenants: "acme=", err: `invalid tenant "acme=", expected tenant=keyspace`},
+		{tenants: "acme=a=b", err: `invalid tenant "acme=a=b", expected tenant=keyspace`},
+		{tenants: "acme=a,acme=b", err: "tenant acme is listed more than once"},
+	}
+	for _, tc := range testCases {
+		opts := NewOptions("cas")
+		v, command := config.Viperize(opts.AddFlags)
+		command.ParseFlags([]string{"--cas.tenants=" + tc.tenants})
+		opts.InitFromViper(v)
+
+		_, err := opts.GetTenants()
+		assert.EqualError(t, err, tc.err)
+	}
+}
@@ -16,6 +16,7 @@ package es
 
 import (
 	"flag"
+	"fmt"
 	"strings"
 	"time"
 
@@ -32,6 +33,19 @@ const (
 	suffixMaxSpanAge  = ".max-span-age"
 	suffixNumShards   = ".num-shards"
 	suffixNumReplicas = ".num-replicas"
+	suffixIndexPrefix = ".index-prefix"
+	suffixRotation    = ".index-rotation"
+	suffixTemplates   = ".create-index-templates"
+	suffixBulkWorkers = ".bulk-workers"
+	suffixBulkActions = ".bulk-actions"
+	suffixBulkSize    = ".bulk-size"
+	suffixBulkFlush   = ".bulk-flush-interval"
+	suffixRouting     = ".use-traceid-routing"
+	suffixTLS         = ".tls.enabled"
+	suffixTLSCA       = ".tls.ca"
+	suffixTLSCert     = ".tls.cert"
+	suffixTLSKey      = ".tls.key"
+	suffixTLSSkipHost = ".tls.skip-host-verify"
 )
 
 // TODO this should be moved next to config.Configuration struct (maybe ./flags package)
@@ -60,12 +74,17 @@ func NewOptions(primaryNamespace string, otherNamespaces ...string) *Options {
 	options := &Options{
 		primary: &namespaceConfig{
 			Configuration: config.Configuration{
-				Username:    "",
-				Password:    "",
-				Sniffer:     false,
-				MaxSpanAge:  72 * time.Hour,
-				NumShards:   5,
-				NumReplicas: 1,
+				Username:          "",
+				Password:          "",
+				Sniffer:           false,
+				MaxSpanAge:        72 * time.Hour,
+				NumShards:         5,
+				NumReplicas:       1,
+				IndexRotation:     config.IndexRotationDaily,
+				BulkWorkers:       0,
+				BulkActions:       1000,
+				BulkSize:          5 * 1000 * 1000,
+				BulkFlushInterval: 200 * time.Millisecond,
 			},
 			servers:   "http://127.0.0.1:9200",
 			namespace: primaryNamespace,
@@ -100,7 +119,7 @@ func addFlags(flagSet *flag.FlagSet, nsConfig *namespaceConfig) {
 	flagSet.Bool(
 		nsConfig.namespace+suffixSniffer,
 		nsConfig.Sniffer,
-		"The sniffer config for ElasticSearch; client uses sniffing process to find all nodes automatically, disable if not required")
+		"The sniffer config for ElasticSearch; client uses sniffing process to find all nodes automatically, disable if not required, e.g. when the server URL is a load balancer")
 	flagSet.String(
 		nsConfig.namespace+suffixServerURLs,
 		nsConfig.servers,
@@ -117,6 +136,59 @@ func addFlags(flagSet *flag.FlagSet, nsConfig *namespaceConfig) {
 		nsConfig.namespace+suffixNumReplicas,
 		nsConfig.NumReplicas,
 		"The number of replicas per index in ElasticSearch")
+	flagSet.String(
+		nsConfig.namespace+suffixIndexPrefix,
+		nsConfig.IndexPrefix,
+		"Optional prefix of the index names, e.g. production makes the span indices production-jaeger-span-yyyy-mm-dd")
+	flagSet.String(
+		nsConfig.namespace+suffixRotation,
+		nsConfig.IndexRotation,
+		fmt.Sprintf("How often new indices are created in ElasticSearch, options are [%v,%v]", config.IndexRotationDaily, config.IndexRotationMonthly))
+	flagSet.Bool(
+		nsConfig.namespace+suffixTemplates,
+		nsConfig.CreateIndexTemplates,
+		"Install index templates in ElasticSearch on startup and let ElasticSearch create the indices from them, instead of creating every index")
+	flagSet.Int(
+		nsConfig.namespace+suffixBulkWorkers,
+		nsConfig.BulkWorkers,
+		"The number of workers sending bulk requests to ElasticSearch; 0 writes one span at a time and reports whether it was stored, "+
+			"bulk requests report the spans as written once they are queued")
+	flagSet.Int(
+		nsConfig.namespace+suffixBulkActions,
+		nsConfig.BulkActions,
+		"The number of spans that triggers a bulk request to ElasticSearch, only used with "+nsConfig.namespace+suffixBulkWorkers)
+	flagSet.Int(
+		nsConfig.namespace+suffixBulkSize,
+		nsConfig.BulkSize,
+		"The size in bytes of the spans that triggers a bulk request to ElasticSearch, only used with "+nsConfig.namespace+suffixBulkWorkers)
+	flagSet.Duration(
+		nsConfig.namespace+suffixBulkFlush,
+		nsConfig.BulkFlushInterval,
+		"The time after which a bulk request is sent to ElasticSearch regardless of the number or size of its spans (0 disables it), only used with "+nsConfig.namespace+suffixBulkWorkers)
+	flagSet.Bool(
+		nsConfig.namespace+suffixRouting,
+		nsConfig.UseTraceIDRouting,
+		"Route the spans to the ElasticSearch shards by trace ID, so that the spans of a trace are stored on the same shard")
+	flagSet.Bool(
+		nsConfig.namespace+suffixTLS,
+		nsConfig.TLS.Enabled,
+		"Connect to ElasticSearch over TLS, verifying the certificates and host names of the servers")
+	flagSet.String(
+		nsConfig.namespace+suffixTLSCA,
+		nsConfig.TLS.CaPath,
+		"Path to a TLS CA file used to verify the certificates of the ElasticSearch servers (default is the system's CAs)")
+	flagSet.String(
+		nsConfig.namespace+suffixTLSCert,
+		nsConfig.TLS.CertPath,
+		"Path to a TLS client certificate file presented to the ElasticSearch servers")
+	flagSet.String(
+		nsConfig.namespace+suffixTLSKey,
+		nsConfig.TLS.KeyPath,
+		"Path to the TLS private key file of the client certificate")
+	flagSet.Bool(
+		nsConfig.namespace+suffixTLSSkipHost,
+		nsConfig.TLS.SkipHostVerify,
+		"Do not verify the certificates and host names of the ElasticSearch servers, e.g. for self-signed certificates in testing")
 }
 
 // InitFromViper initializes Options with properties from viper
@@ -135,6 +207,24 @@ func initFromViper(cfg *namespaceConfig, v *viper.Viper) {
 	cfg.MaxSpanAge = v.GetDuration(cfg.namespace + suffixMaxSpanAge)
 	cfg.NumShards = v.GetInt64(cfg.namespace + suffixNumShards)
 	cfg.NumReplicas = v.GetInt64(cfg.namespace + suffixNumReplicas)
+	cfg.IndexPrefix = v.GetString(cfg.namespace + suffixIndexPrefix)
+	cfg.IndexRotation = v.GetString(cfg.namespace + suffixRotation)
+	cfg.CreateIndexTemplates = v.GetBool(cfg.namespace + suffixTemplates)
+	cfg.BulkWorkers = v.GetInt(cfg.namespace + suffixBulkWorkers)
+	if cfg.BulkWorkers > 0 {
+		cfg.BulkActions = v.GetInt(cfg.namespace + suffixBulkActions)
+		cfg.BulkSize = v.GetInt(cfg.namespace + suffixBulkSize)
+		cfg.BulkFlushInterval = v.GetDuration(cfg.namespace + suffixBulkFlush)
+	} else {
+		// the bulk settings only apply to bulk requests, which are opt-in
+		cfg.BulkActions, cfg.BulkSize, cfg.BulkFlushInterval = 0, 0, 0
+	}
+	cfg.UseTraceIDRouting = v.GetBool(cfg.namespace + suffixRouting)
+	cfg.TLS.Enabled = v.GetBool(cfg.namespace + suffixTLS)
+	cfg.TLS.CaPath = v.GetString(cfg.namespace + suffixTLSCA)
+	cfg.TLS.CertPath = v.GetString(cfg.namespace + suffixTLSCert)
+	cfg.TLS.KeyPath = v.GetString(cfg.namespace + suffixTLSKey)
+	cfg.TLS.SkipHostVerify = v.GetBool(cfg.namespace + suffixTLSSkipHost)
 }
 
 // GetPrimary returns primary configuration.
@@ -19,8 +19,10 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/uber/jaeger/pkg/config"
+	esConfig "github.com/uber/jaeger/pkg/es/config"
 )
 
 func TestOptions(t *testing.T) {
@@ -33,6 +35,14 @@ func TestOptions(t *testing.T) {
 	assert.Equal(t, int64(1), primary.NumReplicas)
 	assert.Equal(t, 72*time.Hour, primary.MaxSpanAge)
 	assert.False(t, primary.Sniffer)
+	assert.Empty(t, primary.IndexPrefix)
+	assert.Equal(t, "daily", primary.IndexRotation)
+	assert.False(t, primary.CreateIndexTemplates)
+	assert.Equal(t, 0, primary.BulkWorkers)
+	assert.Equal(t, 1000, primary.BulkActions)
+	assert.Equal(t, 5000000, primary.BulkSize)
+	assert.Equal(t, 200*time.Millisecond, primary.BulkFlushInterval)
+	assert.False(t, primary.UseTraceIDRouting)
 
 	aux := opts.Get("archive")
 	assert.Equal(t, primary.Username, aux.Username)
@@ -51,6 +61,14 @@ func TestOptionsWithFlags(t *testing.T) {
 		"--es.max-span-age=48h",
 		"--es.num-shards=20",
 		"--es.num-replicas=10",
+		"--es.index-prefix=production",
+		"--es.index-rotation=monthly",
+		"--es.create-index-templates=true",
+		"--es.bulk-workers=4",
+		"--es.bulk-actions=500",
+		"--es.bulk-size=1000000",
+		"--es.bulk-flush-interval=1s",
+		"--es.use-traceid-routing=true",
 		// a couple overrides
 		"--es.aux.server-urls=3.3.3.3,4.4.4.4",
 		"--es.aux.max-span-age=24h",
@@ -63,6 +81,14 @@ func TestOptionsWithFlags(t *testing.T) {
 	assert.Equal(t, []string{"1.1.1.1", "2.2.2.2"}, primary.Servers)
 	assert.Equal(t, 48*time.Hour, primary.MaxSpanAge)
 	assert.True(t, primary.Sniffer)
+	assert.Equal(t, "production", primary.IndexPrefix)
+	assert.Equal(t, "monthly", primary.IndexRotation)
+	assert.True(t, primary.CreateIndexTemplates)
+	assert.Equal(t, 4, primary.BulkWorkers)
+	assert.Equal(t, 500, primary.BulkActions)
+	assert.Equal(t, 1000000, primary.BulkSize)
+	assert.Equal(t, time.Second, primary.BulkFlushInterval)
+	assert.True(t, primary.UseTraceIDRouting)
 
 	aux := opts.Get("es.aux")
 	assert.Equal(t, []string{"3.3.3.3", "4.4.4.4"}, aux.Servers)
@@ -72,5 +98,82 @@ func TestOptionsWithFlags(t *testing.T) {
 	assert.Equal(t, int64(10), aux.NumReplicas)
 	assert.Equal(t, 24*time.Hour, aux.MaxSpanAge)
 	assert.True(t, aux.Sniffer)
+	assert.Equal(t, "production", aux.IndexPrefix)
+	assert.Equal(t, "monthly", aux.IndexRotation)
+	assert.True(t, aux.CreateIndexTemplates)
+	assert.Equal(t, 4, aux.BulkWorkers)
+	assert.True(t, aux.UseTraceIDRouting)
 
 }
+
+func TestOptionsBulkDefaults(t *testing.T) {
+	opts := NewOptions("es")
+	v, command := config.Viperize(opts.AddFlags)
+	command.ParseFlags([]string{})
+	opts.InitFromViper(v)
+
+	primary := opts.GetPrimary()
+	assert.Equal(t, 0, primary.BulkWorkers, "bulk requests are opt-in")
+	assert.Equal(t, 0, primary.BulkActions)
+	assert.Equal(t, 0, primary.BulkSize)
+	assert.Equal(t, time.Duration(0), primary.BulkFlushInterval)
+
+	opts = NewOptions("es")
+	v, command = config.Viperize(opts.AddFlags)
+	command.ParseFlags([]string{"--es.bulk-workers=2"})
+	opts.InitFromViper(v)
+
+	primary = opts.GetPrimary()
+	assert.Equal(t, 2, primary.BulkWorkers)
+	assert.Equal(t, 1000, primary.BulkActions)
+	assert.Equal(t, 5000000, primary.BulkSize)
+	assert.Equal(t, 200*time.Millisecond, primary.BulkFlushInterval)
+}
+
+func TestOptionsAuthAndTLS(t *testing.T) {
+	opts := NewOptions("es", "es.aux")
+	v, command := config.Viperize(opts.AddFlags)
+	command.ParseFlags([]string{
+		"--es.server-urls=https://es.example.com:9200",
+		"--es.username=jaeger",
+		"--es.password=secret",
+		"--es.tls.enabled=true",
+		"--es.tls.ca=/etc/es/ca.pem",
+		"--es.tls.cert=/etc/es/client.pem",
+		"--es.tls.key=/etc/es/client-key.pem",
+	})
+	opts.InitFromViper(v)
+
+	primary := opts.GetPrimary()
+	assert.Equal(t, []string{"https://es.example.com:9200"}, primary.Servers)
+	assert.Equal(t, "jaeger", primary.Username)
+	assert.Equal(t, "secret", primary.Password)
+	assert.False(t, primary.Sniffer, "sniffing is off unless enabled, a single URL is often a load balancer")
+	assert.Equal(t, esConfig.TLS{
+		Enabled:  true,
+		CaPath:   "/etc/es/ca.pem",
+		CertPath: "/etc/es/client.pem",
+		KeyPath:  "/etc/es/client-key.pem",
+	}, primary.TLS)
+	assert.Len(t, primary.GetConfigs(), 4, "sniffed nodes are connected to over https")
+
+	aux := opts.Get("es.aux")
+	assert.Equal(t, primary.TLS, aux.TLS)
+}
+
+func TestOptionsTLSSkipHostVerify(t *testing.T) {
+	opts := NewOptions("es")
+	v, command := config.Viperize(opts.AddFlags)
+	command.ParseFlags([]string{
+		"--es.tls.enabled=true",
+		"--es.tls.skip-host-verify=true",
+	})
+	opts.InitFromViper(v)
+
+	primary := opts.GetPrimary()
+	tlsConfig, err := primary.TLS.Config()
+	require.NoError(t, err)
+	assert.True(t, tlsConfig.InsecureSkipVerify)
+	assert.Nil(t, tlsConfig.RootCAs, "the system's CAs are used")
+	assert.Empty(t, tlsConfig.Certificates)
+}
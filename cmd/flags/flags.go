@@ -18,11 +18,17 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/uber/jaeger/pkg/config"
 )
 
 const (
@@ -31,27 +37,102 @@ const (
 	// MemoryStorageType is the storage type flag denoting an in-memory store
 	MemoryStorageType = "memory"
 	// ESStorageType is the storage type flag denoting an ElasticSearch backing store
-	ESStorageType                  = "elasticsearch"
+	ESStorageType = "elasticsearch"
+	// KafkaStorageType is the storage type flag denoting a Kafka topic that spans are produced to
+	KafkaStorageType = "kafka"
+	// LogEncodingJSON is the log encoding flag denoting one JSON object per log entry
+	LogEncodingJSON = "json"
+	// LogEncodingConsole is the log encoding flag denoting human-readable log lines
+	LogEncodingConsole             = "console"
 	spanStorageType                = "span-storage.type"
 	logLevel                       = "log-level"
+	logEncoding                    = "log-encoding"
 	dependencyStorageDataFrequency = "dependency-storage.data-frequency"
 	configFile                     = "config-file"
 )
 
 // AddConfigFileFlag adds flags for ExternalConfFlags
 func AddConfigFileFlag(flagSet *flag.FlagSet) {
-	flagSet.String(configFile, "", "Configuration file in JSON, TOML, YAML, HCL, or Java properties formats (default none). See spf13/viper for precedence.")
+	flagSet.String(configFile, "", "Comma-separated list of configuration files or directories of them in JSON, TOML, YAML, HCL, or Java properties formats detected from the file extension (default none). Later files override values from earlier ones and environment variables override values from all of them.")
 }
 
-// TryLoadConfigFile initializes viper with config file specified as flag
+// TryLoadConfigFile initializes viper with config files specified as flag. The flag holds a comma-separated
+// list of files or directories, whose files are loaded in lexical order, and the format of every file is
+// detected from its extension. Values from later files override earlier ones. Values from the files take
+// precedence over defaults, but not over environment variables or command line flags.
 func TryLoadConfigFile(v *viper.Viper, logger *zap.Logger) {
-	if file := v.GetString(configFile); file != "" {
-		v.SetConfigFile(file)
-		err := v.ReadInConfig()
+	if list := v.GetString(configFile); list != "" {
+		files, err := loadConfigFiles(v, list)
+		if err != nil {
+			logger.Fatal("Error loading config file", zap.Error(err), zap.String(configFile, list))
+		}
+		logger.Info("Loaded config files", zap.Strings("files", files))
+	}
+}
+
+// WarnDeprecatedEnvVars logs the environmental variables without the JAEGER_ prefix which still override flags.
+func WarnDeprecatedEnvVars(v *viper.Viper, logger *zap.Logger) {
+	for _, envVar := range config.DeprecatedEnvVars(v) {
+		logger.Warn(
+			"Environmental variable without the "+config.EnvPrefix+"_ prefix is deprecated and will be ignored in the next release",
+			zap.String("env", envVar),
+			zap.String("replacement", config.EnvPrefix+"_"+envVar),
+		)
+	}
+}
+
+func loadConfigFiles(v *viper.Viper, list string) ([]string, error) {
+	var files []string
+	for _, path := range strings.Split(list, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		dirFiles, err := configFilesIn(path)
 		if err != nil {
-			logger.Fatal("Error loading config file", zap.Error(err), zap.String(configFile, file))
+			return nil, err
+		}
+		files = append(files, dirFiles...)
+	}
+	for _, file := range files {
+		v.SetConfigFile(file)
+		if err := v.MergeInConfig(); err != nil {
+			return nil, fmt.Errorf("cannot load config file %s: %v", file, err)
+		}
+	}
+	return files, nil
+}
+
+// configFilesIn returns path if it is a file, or the files in it with a supported extension if it is a directory.
+func configFilesIn(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+	entries, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() && isSupportedConfigFile(entry.Name()) {
+			files = append(files, filepath.Join(path, entry.Name()))
+		}
+	}
+	return files, nil
+}
+
+func isSupportedConfigFile(name string) bool {
+	ext := strings.TrimPrefix(filepath.Ext(name), ".")
+	for _, supported := range viper.SupportedExts {
+		if ext == supported {
+			return true
 		}
 	}
+	return false
 }
 
 // SharedFlags holds flags configuration
@@ -60,19 +141,24 @@ type SharedFlags struct {
 	SpanStorage spanStorage
 	// DependencyStorage defines common settings for Dependency Storage.
 	DependencyStorage dependencyStorage
+	// Logging defines the level and encoding of the logs.
+	Logging logging
 }
 
 // InitFromViper initializes SharedFlags with properties from viper
 func (flags *SharedFlags) InitFromViper(v *viper.Viper) *SharedFlags {
 	flags.SpanStorage.Type = v.GetString(spanStorageType)
 	flags.DependencyStorage.DataFrequency = v.GetDuration(dependencyStorageDataFrequency)
+	flags.Logging.Level = v.GetString(logLevel)
+	flags.Logging.Encoding = v.GetString(logEncoding)
 	return flags
 }
 
 // AddFlags adds flags for SharedFlags
 func AddFlags(flagSet *flag.FlagSet) {
-	flagSet.String(spanStorageType, CassandraStorageType, fmt.Sprintf("The type of span storage backend to use, options are currently [%v,%v,%v]", CassandraStorageType, ESStorageType, MemoryStorageType))
-	flagSet.String(logLevel, "info", "Minimal allowed log level")
+	flagSet.String(spanStorageType, CassandraStorageType, fmt.Sprintf("The type of span storage backend to use, options are currently [%v,%v,%v,%v]. The collector also accepts a comma-separated list of types to write spans to all of them, the first one being the primary storage", CassandraStorageType, ESStorageType, MemoryStorageType, KafkaStorageType))
+	flagSet.String(logLevel, "info", "Minimal allowed log level, options are [debug,info,warn,error,dpanic,panic,fatal]")
+	flagSet.String(logEncoding, LogEncodingJSON, fmt.Sprintf("The encoding of the logs, options are [%v,%v]", LogEncodingJSON, LogEncodingConsole))
 	flagSet.Duration(dependencyStorageDataFrequency, time.Hour*24, "Frequency of service dependency calculations")
 }
 
@@ -80,13 +166,64 @@ func AddFlags(flagSet *flag.FlagSet) {
 var ErrUnsupportedStorageType = errors.New("Storage Type is not supported")
 
 type logging struct {
-	Level string
+	Level    string
+	Encoding string
+}
+
+// NewConfig returns the production zap config with the level and encoding of the logging flags.
+// The console encoding also uses the human-readable time and level format of the development config.
+func (l logging) NewConfig() (zap.Config, error) {
+	conf := zap.NewProductionConfig()
+	var level zapcore.Level
+	if err := level.UnmarshalText([]byte(l.Level)); err != nil {
+		return conf, fmt.Errorf("invalid log level %q: %v", l.Level, err)
+	}
+	conf.Level.SetLevel(level)
+	switch l.Encoding {
+	case LogEncodingJSON:
+	case LogEncodingConsole:
+		conf.Encoding = LogEncodingConsole
+		conf.EncoderConfig = zap.NewDevelopmentEncoderConfig()
+	default:
+		return conf, fmt.Errorf("unsupported log encoding %q, options are [%v,%v]", l.Encoding, LogEncodingJSON, LogEncodingConsole)
+	}
+	return conf, nil
 }
 
 type spanStorage struct {
+	// Type is a span storage type, or a comma-separated list of them to write spans to several storages
 	Type string
 }
 
+// Types returns the span storage types listed in Type, the first one is the primary storage
+func (s spanStorage) Types() []string {
+	var types []string
+	for _, storageType := range strings.Split(s.Type, ",") {
+		if storageType = strings.TrimSpace(storageType); storageType != "" {
+			types = append(types, storageType)
+		}
+	}
+	return types
+}
+
+// Primary returns the primary span storage type, which spans are read from
+func (s spanStorage) Primary() string {
+	if types := s.Types(); len(types) > 0 {
+		return types[0]
+	}
+	return ""
+}
+
+// Has returns whether storageType is one of the span storage types
+func (s spanStorage) Has(storageType string) bool {
+	for _, t := range s.Types() {
+		if t == storageType {
+			return true
+		}
+	}
+	return false
+}
+
 type dependencyStorage struct {
 	Type          string
 	DataFrequency time.Duration
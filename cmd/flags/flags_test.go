@@ -0,0 +1,196 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flags
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/uber/jaeger/pkg/config"
+	"github.com/uber/jaeger/pkg/testutils"
+)
+
+func writeConfigFile(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestTryLoadConfigFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jaeger-config")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	testCases := []struct {
+		caption string
+		file    string
+	}{
+		{caption: "yaml", file: writeConfigFile(t, dir, "config.yaml", "span-storage:\n  type: memory\nlog-level: debug\n")},
+		{caption: "json", file: writeConfigFile(t, dir, "config.json", `{"span-storage": {"type": "memory"}, "log-level": "debug"}`)},
+	}
+	for _, tc := range testCases {
+		v, command := config.Viperize(AddConfigFileFlag, AddFlags)
+		require.NoError(t, command.ParseFlags([]string{"--config-file=" + tc.file}))
+		TryLoadConfigFile(v, zap.NewNop())
+
+		assert.Equal(t, MemoryStorageType, v.GetString(spanStorageType), tc.caption)
+		assert.Equal(t, "debug", v.GetString(logLevel), tc.caption)
+		assert.Equal(t, "24h0m0s", v.GetDuration(dependencyStorageDataFrequency).String(), tc.caption)
+	}
+}
+
+func TestConfigFilePrecedence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jaeger-config")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	file := writeConfigFile(t, dir, "config.yaml", "span-storage:\n  type: memory\nlog-level: debug\n")
+
+	envVar := config.EnvPrefix + "_SPAN_STORAGE_TYPE"
+	defer os.Setenv(envVar, os.Getenv(envVar))
+	os.Setenv(envVar, ESStorageType)
+
+	v, command := config.Viperize(AddConfigFileFlag, AddFlags)
+	require.NoError(t, command.ParseFlags([]string{"--config-file=" + file}))
+	TryLoadConfigFile(v, zap.NewNop())
+	assert.Equal(t, ESStorageType, v.GetString(spanStorageType), "environment overrides the file")
+	assert.Equal(t, "debug", v.GetString(logLevel), "file overrides the default")
+
+	v, command = config.Viperize(AddConfigFileFlag, AddFlags)
+	require.NoError(t, command.ParseFlags([]string{"--config-file=" + file, "--span-storage.type=" + KafkaStorageType}))
+	TryLoadConfigFile(v, zap.NewNop())
+	assert.Equal(t, KafkaStorageType, v.GetString(spanStorageType), "flags override the environment")
+}
+
+func TestTryLoadConfigFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jaeger-config")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	confDir := filepath.Join(dir, "conf.d")
+	require.NoError(t, os.Mkdir(confDir, 0755))
+	require.NoError(t, os.Mkdir(filepath.Join(confDir, "nested.yaml"), 0755))
+	base := writeConfigFile(t, dir, "base.yaml", "span-storage:\n  type: memory\nlog-level: debug\ndependency-storage:\n  data-frequency: 1h\n")
+	storage := writeConfigFile(t, confDir, "10-storage.yaml", "span-storage:\n  type: kafka\n")
+	logging := writeConfigFile(t, confDir, "20-logging.json", `{"log-level": "warn"}`)
+	writeConfigFile(t, confDir, "README.md", "not a config file")
+
+	v, command := config.Viperize(AddConfigFileFlag, AddFlags)
+	require.NoError(t, command.ParseFlags([]string{"--config-file=" + base + ", " + confDir}))
+	logger, logBuffer := testutils.NewLogger()
+	TryLoadConfigFile(v, logger)
+
+	assert.Equal(t, KafkaStorageType, v.GetString(spanStorageType), "directory overrides the earlier file")
+	assert.Equal(t, "warn", v.GetString(logLevel), "later file in the directory overrides the earlier ones")
+	assert.Equal(t, "1h0m0s", v.GetDuration(dependencyStorageDataFrequency).String(), "values only in the earlier file are kept")
+	for _, file := range []string{base, storage, logging} {
+		assert.Contains(t, logBuffer.String(), file)
+	}
+
+	v, command = config.Viperize(AddConfigFileFlag, AddFlags)
+	require.NoError(t, command.ParseFlags([]string{"--config-file=" + confDir + "," + base}))
+	TryLoadConfigFile(v, zap.NewNop())
+	assert.Equal(t, MemoryStorageType, v.GetString(spanStorageType), "file overrides the earlier directory")
+	assert.Equal(t, "debug", v.GetString(logLevel), "file overrides the earlier directory")
+}
+
+func TestWarnDeprecatedEnvVars(t *testing.T) {
+	envVar := "SPAN_STORAGE_TYPE"
+	defer os.Setenv(envVar, os.Getenv(envVar))
+	os.Setenv(envVar, KafkaStorageType)
+
+	v, _ := config.Viperize(AddFlags)
+	logger, logBuffer := testutils.NewLogger()
+	WarnDeprecatedEnvVars(v, logger)
+	assert.Equal(t, KafkaStorageType, v.GetString(spanStorageType))
+	assert.Equal(t, envVar, logBuffer.JSONLine(0)["env"])
+	assert.Equal(t, config.EnvPrefix+"_"+envVar, logBuffer.JSONLine(0)["replacement"])
+}
+
+func TestLoadConfigFilesErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jaeger-config")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	missing := filepath.Join(dir, "missing.d")
+	invalid := writeConfigFile(t, dir, "invalid.json", "{")
+
+	_, err = loadConfigFiles(viper.New(), missing)
+	assert.EqualError(t, err, "stat "+missing+": no such file or directory")
+
+	_, err = loadConfigFiles(viper.New(), invalid)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot load config file "+invalid)
+}
+
+func TestSpanStorageTypes(t *testing.T) {
+	testCases := []struct {
+		storageType string
+		types       []string
+		primary     string
+	}{
+		{storageType: "", types: nil, primary: ""},
+		{storageType: "cassandra", types: []string{"cassandra"}, primary: "cassandra"},
+		{storageType: "cassandra, elasticsearch", types: []string{"cassandra", "elasticsearch"}, primary: "cassandra"},
+		{storageType: "elasticsearch,,cassandra", types: []string{"elasticsearch", "cassandra"}, primary: "elasticsearch"},
+	}
+	for _, testCase := range testCases {
+		storage := spanStorage{Type: testCase.storageType}
+		assert.Equal(t, testCase.types, storage.Types(), testCase.storageType)
+		assert.Equal(t, testCase.primary, storage.Primary(), testCase.storageType)
+	}
+
+	storage := spanStorage{Type: "cassandra,elasticsearch"}
+	assert.True(t, storage.Has(CassandraStorageType))
+	assert.True(t, storage.Has(ESStorageType))
+	assert.False(t, storage.Has(MemoryStorageType))
+}
+
+func TestLoggingNewConfig(t *testing.T) {
+	testCases := []struct {
+		flags    []string
+		encoding string
+		level    zapcore.Level
+	}{
+		{flags: []string{"test"}, encoding: "json", level: zapcore.InfoLevel},
+		{flags: []string{"test", "--log-encoding=console", "--log-level=debug"}, encoding: "console", level: zapcore.DebugLevel},
+		{flags: []string{"test", "--log-encoding=json", "--log-level=error"}, encoding: "json", level: zapcore.ErrorLevel},
+	}
+	for _, testCase := range testCases {
+		v, command := config.Viperize(AddFlags)
+		require.NoError(t, command.ParseFlags(testCase.flags))
+		sFlags := new(SharedFlags).InitFromViper(v)
+
+		conf, err := sFlags.Logging.NewConfig()
+		require.NoError(t, err, testCase.flags)
+		assert.Equal(t, testCase.encoding, conf.Encoding, testCase.flags)
+		assert.Equal(t, testCase.level, conf.Level.Level(), testCase.flags)
+		logger, err := conf.Build()
+		require.NoError(t, err, testCase.flags)
+		assert.NotNil(t, logger)
+	}
+}
+
+func TestLoggingNewConfigErrors(t *testing.T) {
+	_, err := logging{Level: "loud", Encoding: LogEncodingJSON}.NewConfig()
+	assert.Error(t, err)
+	_, err = logging{Level: "info", Encoding: "xml"}.NewConfig()
+	assert.EqualError(t, err, `unsupported log encoding "xml", options are [json,console]`)
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafka
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/viper"
+
+	"github.com/uber/jaeger/pkg/kafka/config"
+)
+
+const (
+	suffixBrokers   = ".brokers"
+	suffixTopic     = ".topic"
+	suffixEncoding  = ".encoding"
+	suffixTopicTag  = ".topic-tag"
+	suffixMaxTopics = ".max-topics"
+
+	defaultBroker    = "127.0.0.1:9092"
+	defaultTopic     = "jaeger-spans"
+	defaultEncoding  = config.EncodingJSON
+	defaultMaxTopics = 100
+)
+
+// Options stores the configuration options for Kafka
+type Options struct {
+	config.Configuration
+	brokers   string
+	namespace string
+}
+
+// NewOptions creates a new Options struct.
+func NewOptions(namespace string) *Options {
+	return &Options{namespace: namespace}
+}
+
+// AddFlags adds flags for Options
+func (opt *Options) AddFlags(flagSet *flag.FlagSet) {
+	flagSet.String(
+		opt.namespace+suffixBrokers,
+		defaultBroker,
+		"The comma-separated list of Kafka brokers, i.e. '127.0.0.1:9092,127.0.0.2:9092'")
+	flagSet.String(
+		opt.namespace+suffixTopic,
+		defaultTopic,
+		"The name of the Kafka topic spans are produced to")
+	flagSet.String(
+		opt.namespace+suffixEncoding,
+		defaultEncoding,
+		fmt.Sprintf("The encoding of spans produced to Kafka, options are [%v,%v,%v]", config.EncodingJSON, config.EncodingProtobuf, config.EncodingThrift))
+	flagSet.String(
+		opt.namespace+suffixTopicTag,
+		"",
+		"The span tag whose value routes a span to the topic <topic>-<value>, spans without it are produced to the topic")
+	flagSet.Int(
+		opt.namespace+suffixMaxTopics,
+		defaultMaxTopics,
+		"The maximum number of topics spans are routed to by the topic tag, spans with other values are produced to the topic")
+}
+
+// InitFromViper initializes Options with properties from viper
+func (opt *Options) InitFromViper(v *viper.Viper) {
+	opt.brokers = v.GetString(opt.namespace + suffixBrokers)
+	opt.Topic = v.GetString(opt.namespace + suffixTopic)
+	opt.Encoding = v.GetString(opt.namespace + suffixEncoding)
+	opt.TopicTag = v.GetString(opt.namespace + suffixTopicTag)
+	opt.MaxTopics = v.GetInt(opt.namespace + suffixMaxTopics)
+}
+
+// GetPrimary returns the Kafka configuration.
+func (opt *Options) GetPrimary() *config.Configuration {
+	opt.Brokers = strings.Split(opt.brokers, ",")
+	return &opt.Configuration
+}
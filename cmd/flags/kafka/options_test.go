@@ -0,0 +1,56 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafka
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/uber/jaeger/pkg/config"
+)
+
+func TestOptionsWithFlags(t *testing.T) {
+	opts := NewOptions("kafka")
+	v, command := config.Viperize(opts.AddFlags)
+	command.ParseFlags([]string{
+		"--kafka.brokers=127.0.0.1:9092,0.0.0:1234",
+		"--kafka.topic=topic1",
+		"--kafka.encoding=thrift",
+		"--kafka.topic-tag=tenant",
+		"--kafka.max-topics=10",
+	})
+	opts.InitFromViper(v)
+
+	primary := opts.GetPrimary()
+	assert.Equal(t, []string{"127.0.0.1:9092", "0.0.0:1234"}, primary.Brokers)
+	assert.Equal(t, "topic1", primary.GetTopic())
+	assert.Equal(t, "thrift", primary.GetEncoding())
+	assert.Equal(t, "tenant", primary.GetTopicTag())
+	assert.Equal(t, 10, primary.GetMaxTopics())
+}
+
+func TestDefaultOptions(t *testing.T) {
+	opts := NewOptions("kafka")
+	v, _ := config.Viperize(opts.AddFlags)
+	opts.InitFromViper(v)
+
+	primary := opts.GetPrimary()
+	assert.Equal(t, []string{defaultBroker}, primary.Brokers)
+	assert.Equal(t, defaultTopic, primary.Topic)
+	assert.Equal(t, defaultEncoding, primary.Encoding)
+	assert.Empty(t, primary.TopicTag)
+	assert.Equal(t, defaultMaxTopics, primary.MaxTopics)
+}
@@ -0,0 +1,52 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"flag"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	suffixMaxTraces = ".max-traces"
+
+	defaultMaxTraces = 100000
+)
+
+// Options stores the configuration options for the in-memory storage
+type Options struct {
+	// MaxTraces is the maximum number of traces kept in memory, 0 means unbounded
+	MaxTraces int
+	namespace string
+}
+
+// NewOptions creates a new Options struct.
+func NewOptions(namespace string) *Options {
+	return &Options{MaxTraces: defaultMaxTraces, namespace: namespace}
+}
+
+// AddFlags adds flags for Options
+func (opt *Options) AddFlags(flagSet *flag.FlagSet) {
+	flagSet.Int(
+		opt.namespace+suffixMaxTraces,
+		opt.MaxTraces,
+		"The maximum amount of traces to store in memory, the oldest traces are evicted first (0 means unbounded)")
+}
+
+// InitFromViper initializes Options with properties from viper
+func (opt *Options) InitFromViper(v *viper.Viper) {
+	opt.MaxTraces = v.GetInt(opt.namespace + suffixMaxTraces)
+}
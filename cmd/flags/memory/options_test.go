@@ -0,0 +1,40 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/uber/jaeger/pkg/config"
+)
+
+func TestOptionsWithFlags(t *testing.T) {
+	opts := NewOptions("memory")
+	v, command := config.Viperize(opts.AddFlags)
+	command.ParseFlags([]string{"--memory.max-traces=100"})
+	opts.InitFromViper(v)
+
+	assert.Equal(t, 100, opts.MaxTraces)
+}
+
+func TestDefaultOptions(t *testing.T) {
+	opts := NewOptions("memory")
+	v, _ := config.Viperize(opts.AddFlags)
+	opts.InitFromViper(v)
+
+	assert.Equal(t, defaultMaxTraces, opts.MaxTraces)
+}
@@ -410,7 +410,7 @@ func (aH *APIHandler) archiveTrace(w http.ResponseWriter, r *http.Request) {
 	aH.withTraceFromReader(w, r, aH.spanReader, nil, func(trace *model.Trace) {
 		var writeErrors []error
 		for _, span := range trace.Spans {
-			err := aH.archiveSpanWriter.WriteSpan(span)
+			err := aH.archiveSpanWriter.WriteSpan(r.Context(), span)
 			if err != nil {
 				writeErrors = append(writeErrors, err)
 			}
@@ -75,7 +75,7 @@ func TestArchiveTrace_NoStorage(t *testing.T) {
 
 func TestArchiveTrace_Success(t *testing.T) {
 	mockWriter := &spanstoremocks.Writer{}
-	mockWriter.On("WriteSpan", mock.AnythingOfType("*model.Span")).
+	mockWriter.On("WriteSpan", mock.Anything, mock.AnythingOfType("*model.Span")).
 		Return(nil).Times(2)
 	withTestServer(t, func(ts *testServer) {
 		ts.spanReader.On("GetTrace", mock.AnythingOfType("model.TraceID")).
@@ -88,7 +88,7 @@ func TestArchiveTrace_Success(t *testing.T) {
 
 func TestArchiveTrace_WriteErrors(t *testing.T) {
 	mockWriter := &spanstoremocks.Writer{}
-	mockWriter.On("WriteSpan", mock.AnythingOfType("*model.Span")).
+	mockWriter.On("WriteSpan", mock.Anything, mock.AnythingOfType("*model.Span")).
 		Return(errors.New("cannot save")).Times(2)
 	withTestServer(t, func(ts *testServer) {
 		ts.spanReader.On("GetTrace", mock.AnythingOfType("model.TraceID")).
@@ -56,6 +56,7 @@ func main() {
 		Long:  `Jaeger query is a service to access tracing data and host UI.`,
 		Run: func(cmd *cobra.Command, args []string) {
 			flags.TryLoadConfigFile(v, logger)
+			flags.WarnDeprecatedEnvVars(v, logger)
 
 			sFlags := new(flags.SharedFlags).InitFromViper(v)
 			casOptions.InitFromViper(v)
@@ -82,7 +83,7 @@ func main() {
 			defer closer.Close()
 
 			storageBuild, err := builder.NewStorageBuilder(
-				sFlags.SpanStorage.Type,
+				sFlags.SpanStorage.Primary(),
 				sFlags.DependencyStorage.DataFrequency,
 				basicB.Options.LoggerOption(logger),
 				basicB.Options.MetricsFactoryOption(metricsFactory),
@@ -38,9 +38,11 @@ import (
 	collector "github.com/uber/jaeger/cmd/collector/app/builder"
 	"github.com/uber/jaeger/cmd/collector/app/zipkin"
 	"github.com/uber/jaeger/cmd/flags"
+	memoryFlags "github.com/uber/jaeger/cmd/flags/memory"
 	queryApp "github.com/uber/jaeger/cmd/query/app"
 	query "github.com/uber/jaeger/cmd/query/app/builder"
 	"github.com/uber/jaeger/pkg/config"
+	"github.com/uber/jaeger/pkg/gzipfilter"
 	pMetrics "github.com/uber/jaeger/pkg/metrics"
 	"github.com/uber/jaeger/pkg/recoveryhandler"
 	"github.com/uber/jaeger/pkg/version"
@@ -53,6 +55,7 @@ import (
 func main() {
 	logger, _ := zap.NewProduction()
 	v := viper.New()
+	memoryOptions := memoryFlags.NewOptions("memory")
 
 	command := &cobra.Command{
 		Use:   "jaeger-standalone",
@@ -61,14 +64,19 @@ func main() {
 		 uses only in-memory database.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			flags.TryLoadConfigFile(v, logger)
+			flags.WarnDeprecatedEnvVars(v, logger)
 
 			runtime.GOMAXPROCS(runtime.NumCPU())
 			sFlags := new(flags.SharedFlags).InitFromViper(v)
 			cOpts := new(collector.CollectorOptions).InitFromViper(v)
 			qOpts := new(query.QueryOptions).InitFromViper(v)
+			memoryOptions.InitFromViper(v)
 
 			metricsFactory := xkit.Wrap("jaeger-standalone", expvar.NewFactory(10))
-			memStore := memory.NewStore()
+			memStore, err := memory.NewStoreWithMaxTraces(memoryOptions.MaxTraces)
+			if err != nil {
+				return err
+			}
 
 			builder := &agentApp.Builder{}
 			builder.InitFromViper(v)
@@ -90,6 +98,7 @@ func main() {
 		query.AddFlags,
 		agentApp.AddFlags,
 		pMetrics.AddFlags,
+		memoryOptions.AddFlags,
 	)
 
 	if err := command.Execute(); err != nil {
@@ -157,14 +166,13 @@ func startCollector(
 	r := mux.NewRouter()
 	apiHandler := collectorApp.NewAPIHandler(jaegerBatchesHandler)
 	apiHandler.RegisterRoutes(r)
-	httpPortStr := ":" + strconv.Itoa(cOpts.CollectorHTTPPort)
 	recoveryHandler := recoveryhandler.NewRecoveryHandler(logger, true)
 
-	go startZipkinHTTPAPI(logger, cOpts.CollectorZipkinHTTPPort, zipkinSpansHandler, recoveryHandler)
+	go startZipkinHTTPAPI(logger, cOpts, zipkinSpansHandler, recoveryHandler)
 
 	logger.Info("Starting jaeger-collector HTTP server", zap.Int("http-port", cOpts.CollectorHTTPPort))
 	go func() {
-		if err := http.ListenAndServe(httpPortStr, recoveryHandler(r)); err != nil {
+		if err := newCollectorHTTPServer(cOpts.CollectorHTTPPort, recoveryHandler(r), cOpts).ListenAndServe(); err != nil {
 			logger.Fatal("Could not launch jaeger-collector HTTP server", zap.Error(err))
 		}
 	}()
@@ -172,22 +180,33 @@ func startCollector(
 
 func startZipkinHTTPAPI(
 	logger *zap.Logger,
-	zipkinPort int,
+	cOpts *collector.CollectorOptions,
 	zipkinSpansHandler collectorApp.ZipkinSpansHandler,
 	recoveryHandler func(http.Handler) http.Handler,
 ) {
-	if zipkinPort != 0 {
+	if zipkinPort := cOpts.CollectorZipkinHTTPPort; zipkinPort != 0 {
 		r := mux.NewRouter()
 		zipkin.NewAPIHandler(zipkinSpansHandler).RegisterRoutes(r)
-		httpPortStr := ":" + strconv.Itoa(zipkinPort)
 		logger.Info("Listening for Zipkin HTTP traffic", zap.Int("zipkin.http-port", zipkinPort))
 
-		if err := http.ListenAndServe(httpPortStr, recoveryHandler(r)); err != nil {
+		handler := recoveryHandler(gzipfilter.NewGzipFilter(r, cOpts.MaxBatchBytes))
+		if err := newCollectorHTTPServer(zipkinPort, handler, cOpts).ListenAndServe(); err != nil {
 			logger.Fatal("Could not launch service", zap.Error(err))
 		}
 	}
 }
 
+// newCollectorHTTPServer returns a server on port with the timeouts of the collector's HTTP servers
+func newCollectorHTTPServer(port int, handler http.Handler, cOpts *collector.CollectorOptions) *http.Server {
+	return &http.Server{
+		Addr:         ":" + strconv.Itoa(port),
+		Handler:      handler,
+		ReadTimeout:  cOpts.HTTPReadTimeout,
+		WriteTimeout: cOpts.HTTPWriteTimeout,
+		IdleTimeout:  cOpts.HTTPIdleTimeout,
+	}
+}
+
 func startQuery(
 	qOpts *query.QueryOptions,
 	sFlags *flags.SharedFlags,
@@ -198,7 +217,7 @@ func startQuery(
 	metricsFactory := baseFactory.Namespace("jaeger-query", nil)
 
 	storageBuild, err := query.NewStorageBuilder(
-		sFlags.SpanStorage.Type,
+		sFlags.SpanStorage.Primary(),
 		sFlags.DependencyStorage.DataFrequency,
 		basic.Options.LoggerOption(logger),
 		basic.Options.MetricsFactoryOption(metricsFactory),
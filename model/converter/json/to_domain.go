@@ -17,6 +17,7 @@ package json
 import (
 	"encoding/hex"
 	"fmt"
+	"math"
 	"strconv"
 
 	"github.com/pkg/errors"
@@ -131,7 +132,10 @@ func (td toDomain) convertKeyValue(tag *json.KeyValue) (model.KeyValue, error) {
 }
 
 func (td toDomain) convertKeyValueOfType(tag *json.KeyValue, vType model.ValueType) (model.KeyValue, error) {
-	tagValue := tag.Value.(string)
+	tagValue, ok := tag.Value.(string)
+	if !ok {
+		return td.convertTypedKeyValue(tag, vType)
+	}
 	switch vType {
 	case model.StringType:
 		return model.String(tag.Key, tagValue), nil
@@ -163,6 +167,25 @@ func (td toDomain) convertKeyValueOfType(tag *json.KeyValue, vType model.ValueTy
 	return model.KeyValue{}, fmt.Errorf("not a valid ValueType string %s", vType.String())
 }
 
+// convertTypedKeyValue converts the bool and number values that were decoded from JSON with their own type,
+// rather than as strings.
+func (td toDomain) convertTypedKeyValue(tag *json.KeyValue, vType model.ValueType) (model.KeyValue, error) {
+	switch value := tag.Value.(type) {
+	case bool:
+		if vType == model.BoolType {
+			return model.Bool(tag.Key, value), nil
+		}
+	case float64:
+		if vType == model.Float64Type {
+			return model.Float64(tag.Key, value), nil
+		}
+		if vType == model.Int64Type && value == math.Trunc(value) {
+			return model.Int64(tag.Key, int64(value)), nil
+		}
+	}
+	return model.KeyValue{}, fmt.Errorf("invalid %s value %v of tag %s", vType.String(), tag.Value, tag.Key)
+}
+
 func (td toDomain) convertLogs(logs []json.Log) ([]model.Log, error) {
 	retMe := make([]model.Log, len(logs))
 	for i, l := range logs {
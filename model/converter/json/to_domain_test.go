@@ -139,6 +139,38 @@ func TestFailureBadBinaryTags(t *testing.T) {
 	failingSpanTransformAnyMsg(t, &badTagESSpan)
 }
 
+func TestTypedTags(t *testing.T) {
+	span, err := createGoodSpan(1)
+	require.NoError(t, err)
+
+	span.Tags = []jModel.KeyValue{
+		{Key: "error", Value: true, Type: "bool"},
+		{Key: "http.status_code", Value: float64(200), Type: "int64"},
+		{Key: "ratio", Value: 0.5, Type: "float64"},
+	}
+	mSpan, err := SpanToDomain(&span)
+	require.NoError(t, err)
+	assert.Equal(t, model.KeyValues{
+		model.Bool("error", true),
+		model.Int64("http.status_code", 200),
+		model.Float64("ratio", 0.5),
+	}, mSpan.Tags)
+}
+
+func TestFailureBadTypedTags(t *testing.T) {
+	for _, tag := range []jModel.KeyValue{
+		{Key: "meh", Value: 1.5, Type: "int64"},
+		{Key: "meh", Value: true, Type: "int64"},
+		{Key: "meh", Value: float64(1), Type: "bool"},
+		{Key: "meh", Value: []interface{}{"a"}, Type: "string"},
+	} {
+		badTagESSpan, err := createGoodSpan(1)
+		require.NoError(t, err)
+		badTagESSpan.Tags = []jModel.KeyValue{tag}
+		failingSpanTransform(t, &badTagESSpan, fmt.Sprintf("invalid %s value %v of tag meh", tag.Type, tag.Value))
+	}
+}
+
 func TestFailureBadLogs(t *testing.T) {
 	badLogsESSpan, err := createGoodSpan(1)
 	require.NoError(t, err)
@@ -0,0 +1,119 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jaeger
+
+import (
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/proto-gen/jaeger"
+)
+
+// FromDomain transforms a slice of model.Span into a batch in the Protobuf Jaeger model.
+// Every span in the batch carries its own process.
+func FromDomain(spans []*model.Span) *jaeger.Batch {
+	fd := fromDomain{}
+	pSpans := make([]*jaeger.Span, len(spans))
+	for i, span := range spans {
+		pSpans[i] = fd.transformSpan(span)
+	}
+	return &jaeger.Batch{Spans: pSpans}
+}
+
+// fromDomain is a private struct that namespaces some conversion functions
+type fromDomain struct{}
+
+func (fd fromDomain) transformSpan(span *model.Span) *jaeger.Span {
+	return &jaeger.Span{
+		TraceIdLow:    span.TraceID.Low,
+		TraceIdHigh:   span.TraceID.High,
+		SpanId:        uint64(span.SpanID),
+		ParentSpanId:  uint64(span.ParentSpanID),
+		OperationName: span.OperationName,
+		References:    fd.transformReferences(span.References),
+		Flags:         uint32(span.Flags),
+		StartTime:     int64(model.TimeAsEpochMicroseconds(span.StartTime)),
+		Duration:      int64(model.DurationAsMicroseconds(span.Duration)),
+		Tags:          fd.transformTags(span.Tags),
+		Logs:          fd.transformLogs(span.Logs),
+		Process:       fd.transformProcess(span.Process),
+	}
+}
+
+func (fd fromDomain) transformReferences(refs []model.SpanRef) []*jaeger.SpanRef {
+	if len(refs) == 0 {
+		return nil
+	}
+	pRefs := make([]*jaeger.SpanRef, len(refs))
+	for i, ref := range refs {
+		pRefs[i] = &jaeger.SpanRef{
+			RefType:     jaeger.SpanRefType(ref.RefType),
+			TraceIdLow:  ref.TraceID.Low,
+			TraceIdHigh: ref.TraceID.High,
+			SpanId:      uint64(ref.SpanID),
+		}
+	}
+	return pRefs
+}
+
+func (fd fromDomain) transformProcess(process *model.Process) *jaeger.Process {
+	if process == nil {
+		return nil
+	}
+	return &jaeger.Process{
+		ServiceName: process.ServiceName,
+		Tags:        fd.transformTags(process.Tags),
+	}
+}
+
+func (fd fromDomain) transformTags(tags model.KeyValues) []*jaeger.KeyValue {
+	if len(tags) == 0 {
+		return nil
+	}
+	pTags := make([]*jaeger.KeyValue, len(tags))
+	for i := range tags {
+		pTags[i] = fd.transformTag(&tags[i])
+	}
+	return pTags
+}
+
+func (fd fromDomain) transformTag(kv *model.KeyValue) *jaeger.KeyValue {
+	pTag := &jaeger.KeyValue{Key: kv.Key}
+	switch kv.VType {
+	case model.StringType:
+		pTag.VType, pTag.VStr = jaeger.ValueType_STRING, kv.VStr
+	case model.BoolType:
+		pTag.VType, pTag.VBool = jaeger.ValueType_BOOL, kv.Bool()
+	case model.Int64Type:
+		pTag.VType, pTag.VInt64 = jaeger.ValueType_INT64, kv.Int64()
+	case model.Float64Type:
+		pTag.VType, pTag.VFloat64 = jaeger.ValueType_FLOAT64, kv.Float64()
+	case model.BinaryType:
+		pTag.VType, pTag.VBinary = jaeger.ValueType_BINARY, kv.Binary()
+	}
+	return pTag
+}
+
+func (fd fromDomain) transformLogs(logs []model.Log) []*jaeger.Log {
+	if len(logs) == 0 {
+		return nil
+	}
+	pLogs := make([]*jaeger.Log, len(logs))
+	for i, log := range logs {
+		pLogs[i] = &jaeger.Log{
+			Timestamp: int64(model.TimeAsEpochMicroseconds(log.Timestamp)),
+			Fields:    fd.transformTags(log.Fields),
+		}
+	}
+	return pLogs
+}
@@ -0,0 +1,76 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jaeger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/proto-gen/jaeger"
+)
+
+func TestFromDomainRoundTrip(t *testing.T) {
+	start := model.EpochMicrosecondsAsTime(1485467191639875)
+	spans := []*model.Span{
+		{
+			TraceID:       model.TraceID{Low: 1, High: 2},
+			SpanID:        3,
+			ParentSpanID:  4,
+			OperationName: "GET /",
+			References:    []model.SpanRef{{RefType: model.ChildOf, TraceID: model.TraceID{Low: 1, High: 2}, SpanID: 4}},
+			Flags:         1,
+			StartTime:     start,
+			Duration:      5 * time.Microsecond,
+			Tags: model.KeyValues{
+				model.String("string", "value"),
+				model.Bool("bool", true),
+				model.Int64("int64", 42),
+				model.Float64("float64", 0.5),
+				model.Binary("binary", []byte("bin")),
+			},
+			Logs: []model.Log{{
+				Timestamp: start.Add(time.Microsecond),
+				Fields:    model.KeyValues{model.String("event", "retry")},
+			}},
+			Process: &model.Process{
+				ServiceName: "frontend",
+				Tags:        model.KeyValues{model.String("hostname", "host1")},
+			},
+		},
+		{
+			TraceID:   model.TraceID{Low: 1, High: 2},
+			SpanID:    4,
+			StartTime: start,
+			Process:   &model.Process{ServiceName: "backend"},
+		},
+	}
+
+	data, err := proto.Marshal(FromDomain(spans))
+	require.NoError(t, err)
+	batch := &jaeger.Batch{}
+	require.NoError(t, proto.Unmarshal(data, batch))
+	assert.Equal(t, spans, ToDomain(batch))
+}
+
+func TestFromDomainWithoutProcess(t *testing.T) {
+	batch := FromDomain([]*model.Span{{TraceID: model.TraceID{Low: 1}, SpanID: 1}})
+	require.Len(t, batch.Spans, 1)
+	assert.Nil(t, batch.Spans[0].Process)
+}
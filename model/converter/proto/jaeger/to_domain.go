@@ -0,0 +1,131 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jaeger
+
+import (
+	"fmt"
+
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/proto-gen/jaeger"
+)
+
+// ToDomain transforms a batch in the Protobuf Jaeger model into a slice of model.Span.
+// Spans without a process of their own are given the process of the batch.
+// A valid []*model.Span is always returned, unknown value types are presented as string tags.
+func ToDomain(batch *jaeger.Batch) []*model.Span {
+	return toDomain{}.ToDomain(batch)
+}
+
+// toDomain is a private struct that namespaces some conversion functions
+type toDomain struct{}
+
+func (td toDomain) ToDomain(batch *jaeger.Batch) []*model.Span {
+	spans := make([]*model.Span, len(batch.Spans))
+	mProcess := td.getProcess(batch.Process)
+	for i, pSpan := range batch.Spans {
+		process := mProcess
+		if pSpan.Process != nil {
+			process = td.getProcess(pSpan.Process)
+		}
+		spans[i] = td.transformSpan(pSpan, process)
+	}
+	return spans
+}
+
+func (td toDomain) transformSpan(pSpan *jaeger.Span, mProcess *model.Process) *model.Span {
+	return &model.Span{
+		TraceID: model.TraceID{
+			High: pSpan.TraceIdHigh,
+			Low:  pSpan.TraceIdLow,
+		},
+		SpanID:        model.SpanID(pSpan.SpanId),
+		ParentSpanID:  model.SpanID(pSpan.ParentSpanId),
+		OperationName: pSpan.OperationName,
+		References:    td.getReferences(pSpan.References),
+		Flags:         model.Flags(pSpan.Flags),
+		StartTime:     model.EpochMicrosecondsAsTime(uint64(pSpan.StartTime)),
+		Duration:      model.MicrosecondsAsDuration(uint64(pSpan.Duration)),
+		Tags:          td.getTags(pSpan.Tags),
+		Logs:          td.getLogs(pSpan.Logs),
+		Process:       mProcess,
+	}
+}
+
+func (td toDomain) getReferences(pRefs []*jaeger.SpanRef) []model.SpanRef {
+	if len(pRefs) == 0 {
+		return nil
+	}
+	mRefs := make([]model.SpanRef, len(pRefs))
+	for i, pRef := range pRefs {
+		mRefs[i] = model.SpanRef{
+			RefType: model.SpanRefType(pRef.RefType),
+			TraceID: model.TraceID{High: pRef.TraceIdHigh, Low: pRef.TraceIdLow},
+			SpanID:  model.SpanID(pRef.SpanId),
+		}
+	}
+	return mRefs
+}
+
+func (td toDomain) getProcess(pProcess *jaeger.Process) *model.Process {
+	if pProcess == nil {
+		return &model.Process{}
+	}
+	return &model.Process{
+		ServiceName: pProcess.ServiceName,
+		Tags:        td.getTags(pProcess.Tags),
+	}
+}
+
+func (td toDomain) getTags(pTags []*jaeger.KeyValue) model.KeyValues {
+	if len(pTags) == 0 {
+		return nil
+	}
+	tags := make(model.KeyValues, len(pTags))
+	for i, pTag := range pTags {
+		tags[i] = td.getTag(pTag)
+	}
+	return tags
+}
+
+func (td toDomain) getTag(pTag *jaeger.KeyValue) model.KeyValue {
+	switch pTag.VType {
+	case jaeger.ValueType_STRING:
+		return model.String(pTag.Key, pTag.VStr)
+	case jaeger.ValueType_BOOL:
+		return model.Bool(pTag.Key, pTag.VBool)
+	case jaeger.ValueType_INT64:
+		return model.Int64(pTag.Key, pTag.VInt64)
+	case jaeger.ValueType_FLOAT64:
+		return model.Float64(pTag.Key, pTag.VFloat64)
+	case jaeger.ValueType_BINARY:
+		return model.Binary(pTag.Key, pTag.VBinary)
+	default:
+		return model.String(pTag.Key, fmt.Sprintf("Unknown VType: %+v", pTag))
+	}
+}
+
+func (td toDomain) getLogs(pLogs []*jaeger.Log) []model.Log {
+	if len(pLogs) == 0 {
+		return nil
+	}
+	logs := make([]model.Log, len(pLogs))
+	for i, pLog := range pLogs {
+		logs[i] = model.Log{
+			Timestamp: model.EpochMicrosecondsAsTime(uint64(pLog.Timestamp)),
+			Fields:    td.getTags(pLog.Fields),
+		}
+	}
+	return logs
+}
@@ -0,0 +1,103 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jaeger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/proto-gen/jaeger"
+)
+
+func TestToDomain(t *testing.T) {
+	batch := &jaeger.Batch{
+		Process: &jaeger.Process{
+			ServiceName: "frontend",
+			Tags:        []*jaeger.KeyValue{{Key: "hostname", VType: jaeger.ValueType_STRING, VStr: "host1"}},
+		},
+		Spans: []*jaeger.Span{
+			{
+				TraceIdLow:    1,
+				TraceIdHigh:   2,
+				SpanId:        3,
+				ParentSpanId:  4,
+				OperationName: "GET /",
+				References:    []*jaeger.SpanRef{{RefType: jaeger.SpanRefType_FOLLOWS_FROM, TraceIdLow: 1, TraceIdHigh: 2, SpanId: 5}},
+				Flags:         1,
+				StartTime:     1485467191639875,
+				Duration:      5,
+				Tags: []*jaeger.KeyValue{
+					{Key: "bool", VType: jaeger.ValueType_BOOL, VBool: true},
+					{Key: "int64", VType: jaeger.ValueType_INT64, VInt64: 42},
+					{Key: "float64", VType: jaeger.ValueType_FLOAT64, VFloat64: 0.5},
+					{Key: "binary", VType: jaeger.ValueType_BINARY, VBinary: []byte("bin")},
+				},
+				Logs: []*jaeger.Log{{
+					Timestamp: 1485467191639876,
+					Fields:    []*jaeger.KeyValue{{Key: "event", VStr: "retry"}},
+				}},
+			},
+			{
+				TraceIdLow: 1,
+				SpanId:     6,
+				Process:    &jaeger.Process{ServiceName: "backend"},
+			},
+		},
+	}
+
+	spans := ToDomain(batch)
+	require.Len(t, spans, 2)
+	assert.Equal(t, &model.Span{
+		TraceID:       model.TraceID{Low: 1, High: 2},
+		SpanID:        3,
+		ParentSpanID:  4,
+		OperationName: "GET /",
+		References:    []model.SpanRef{{RefType: model.FollowsFrom, TraceID: model.TraceID{Low: 1, High: 2}, SpanID: 5}},
+		Flags:         1,
+		StartTime:     model.EpochMicrosecondsAsTime(1485467191639875),
+		Duration:      5 * time.Microsecond,
+		Tags: model.KeyValues{
+			model.Bool("bool", true),
+			model.Int64("int64", 42),
+			model.Float64("float64", 0.5),
+			model.Binary("binary", []byte("bin")),
+		},
+		Logs: []model.Log{{
+			Timestamp: model.EpochMicrosecondsAsTime(1485467191639876),
+			Fields:    model.KeyValues{model.String("event", "retry")},
+		}},
+		Process: &model.Process{
+			ServiceName: "frontend",
+			Tags:        model.KeyValues{model.String("hostname", "host1")},
+		},
+	}, spans[0])
+	assert.Equal(t, &model.Process{ServiceName: "backend"}, spans[1].Process, "the span's own process overrides the batch's")
+}
+
+func TestToDomainWithoutProcess(t *testing.T) {
+	spans := ToDomain(&jaeger.Batch{Spans: []*jaeger.Span{{TraceIdLow: 1, SpanId: 1}}})
+	require.Len(t, spans, 1)
+	assert.Equal(t, &model.Process{}, spans[0].Process)
+}
+
+func TestToDomainUnknownValueType(t *testing.T) {
+	tag := toDomain{}.getTag(&jaeger.KeyValue{Key: "key", VType: jaeger.ValueType(-1)})
+	assert.Equal(t, model.StringType, tag.VType)
+	assert.Contains(t, tag.VStr, "Unknown VType")
+}
@@ -0,0 +1,288 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otlp
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/opentracing/opentracing-go/ext"
+
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/proto-gen/otlp"
+)
+
+const (
+	// ServiceNameAttribute is the resource attribute holding the name of the service emitting the spans
+	ServiceNameAttribute = "service.name"
+	// UnknownServiceName is the service name given to the spans of a resource without ServiceNameAttribute
+	UnknownServiceName = "unknown-service-name"
+
+	// DefaultLogFieldKey is the log field key holding the name of an OTLP event
+	DefaultLogFieldKey = "event"
+
+	// Tags that carry the OTLP fields without a counterpart in model.Span
+	statusCodeTagKey        = "otel.status_code"
+	statusDescriptionTagKey = "otel.status_description"
+	libraryNameTagKey       = "otel.library.name"
+	libraryVersionTagKey    = "otel.library.version"
+	traceStateTagKey        = "w3c.tracestate"
+)
+
+var spanKinds = map[otlp.Span_SpanKind]string{
+	otlp.Span_SPAN_KIND_INTERNAL: "internal",
+	otlp.Span_SPAN_KIND_SERVER:   string(ext.SpanKindRPCServerEnum),
+	otlp.Span_SPAN_KIND_CLIENT:   string(ext.SpanKindRPCClientEnum),
+	otlp.Span_SPAN_KIND_PRODUCER: string(ext.SpanKindProducerEnum),
+	otlp.Span_SPAN_KIND_CONSUMER: string(ext.SpanKindConsumerEnum),
+}
+
+// ToDomain transforms the OTLP spans of each resource into a slice of model.Span.
+// The resource attributes become the tags of the process, except for service.name which is its service name.
+// An error is returned if a span has a malformed trace or span ID.
+func ToDomain(resourceSpans []*otlp.ResourceSpans) ([]*model.Span, error) {
+	return toDomain{}.ToDomain(resourceSpans)
+}
+
+// toDomain is a private struct that namespaces some conversion functions
+type toDomain struct{}
+
+func (td toDomain) ToDomain(resourceSpans []*otlp.ResourceSpans) ([]*model.Span, error) {
+	var spans []*model.Span
+	for _, rSpans := range resourceSpans {
+		process := td.getProcess(rSpans.Resource)
+		for _, sSpans := range rSpans.ScopeSpans {
+			for _, oSpan := range sSpans.Spans {
+				span, err := td.transformSpan(oSpan, sSpans.Scope, process)
+				if err != nil {
+					return nil, err
+				}
+				spans = append(spans, span)
+			}
+		}
+	}
+	return spans, nil
+}
+
+func (td toDomain) transformSpan(oSpan *otlp.Span, scope *otlp.InstrumentationScope, process *model.Process) (*model.Span, error) {
+	traceID, err := td.getTraceID(oSpan.TraceId)
+	if err != nil {
+		return nil, err
+	}
+	spanID, err := td.getSpanID(oSpan.SpanId)
+	if err != nil {
+		return nil, err
+	}
+	span := &model.Span{
+		TraceID:       traceID,
+		SpanID:        spanID,
+		OperationName: oSpan.Name,
+		StartTime:     td.getTime(oSpan.StartTimeUnixNano),
+		Duration:      td.getTime(oSpan.EndTimeUnixNano).Sub(td.getTime(oSpan.StartTimeUnixNano)),
+		Tags:          td.getSpanTags(oSpan, scope),
+		Logs:          td.getLogs(oSpan.Events),
+		Process:       process,
+	}
+	// OTLP exporters only send the spans that were recorded
+	span.Flags.SetSampled()
+	if len(oSpan.ParentSpanId) > 0 {
+		if span.ParentSpanID, err = td.getSpanID(oSpan.ParentSpanId); err != nil {
+			return nil, err
+		}
+		span.References = append(span.References, model.SpanRef{
+			RefType: model.ChildOf,
+			TraceID: traceID,
+			SpanID:  span.ParentSpanID,
+		})
+	}
+	for _, link := range oSpan.Links {
+		ref, err := td.getReference(link)
+		if err != nil {
+			return nil, err
+		}
+		span.References = append(span.References, ref)
+	}
+	return span, nil
+}
+
+func (td toDomain) getTraceID(id []byte) (model.TraceID, error) {
+	if len(id) != 16 {
+		return model.TraceID{}, fmt.Errorf("invalid OTLP trace ID %x, it must be 16 bytes long", id)
+	}
+	return model.TraceID{
+		High: binary.BigEndian.Uint64(id[:8]),
+		Low:  binary.BigEndian.Uint64(id[8:]),
+	}, nil
+}
+
+func (td toDomain) getSpanID(id []byte) (model.SpanID, error) {
+	if len(id) != 8 {
+		return 0, fmt.Errorf("invalid OTLP span ID %x, it must be 8 bytes long", id)
+	}
+	return model.SpanID(binary.BigEndian.Uint64(id)), nil
+}
+
+// getReference turns a link into a FollowsFrom reference
+func (td toDomain) getReference(link *otlp.Span_Link) (model.SpanRef, error) {
+	traceID, err := td.getTraceID(link.TraceId)
+	if err != nil {
+		return model.SpanRef{}, err
+	}
+	spanID, err := td.getSpanID(link.SpanId)
+	if err != nil {
+		return model.SpanRef{}, err
+	}
+	return model.SpanRef{
+		RefType: model.FollowsFrom,
+		TraceID: traceID,
+		SpanID:  spanID,
+	}, nil
+}
+
+func (td toDomain) getTime(unixNano uint64) time.Time {
+	return model.EpochMicrosecondsAsTime(unixNano / 1000)
+}
+
+func (td toDomain) getProcess(resource *otlp.Resource) *model.Process {
+	process := &model.Process{ServiceName: UnknownServiceName}
+	if resource == nil {
+		return process
+	}
+	for _, attr := range resource.Attributes {
+		if attr.Key == ServiceNameAttribute && attr.Value.GetStringValue() != "" {
+			process.ServiceName = attr.Value.GetStringValue()
+			continue
+		}
+		process.Tags = append(process.Tags, td.getTag(attr))
+	}
+	return process
+}
+
+// getSpanTags returns the attributes of the span followed by the tags describing its kind, status,
+// trace state and instrumentation scope
+func (td toDomain) getSpanTags(oSpan *otlp.Span, scope *otlp.InstrumentationScope) model.KeyValues {
+	tags := td.getTags(oSpan.Attributes)
+	if kind, ok := spanKinds[oSpan.Kind]; ok {
+		tags = append(tags, model.String(string(ext.SpanKind), kind))
+	}
+	if status := oSpan.Status; status != nil {
+		switch status.Code {
+		case otlp.Status_STATUS_CODE_OK:
+			tags = append(tags, model.String(statusCodeTagKey, "OK"))
+		case otlp.Status_STATUS_CODE_ERROR:
+			tags = append(tags, model.String(statusCodeTagKey, "ERROR"), model.Bool(string(ext.Error), true))
+		}
+		if status.Message != "" {
+			tags = append(tags, model.String(statusDescriptionTagKey, status.Message))
+		}
+	}
+	if oSpan.TraceState != "" {
+		tags = append(tags, model.String(traceStateTagKey, oSpan.TraceState))
+	}
+	if scope != nil {
+		if scope.Name != "" {
+			tags = append(tags, model.String(libraryNameTagKey, scope.Name))
+		}
+		if scope.Version != "" {
+			tags = append(tags, model.String(libraryVersionTagKey, scope.Version))
+		}
+	}
+	return tags
+}
+
+func (td toDomain) getTags(attrs []*otlp.KeyValue) model.KeyValues {
+	if len(attrs) == 0 {
+		return nil
+	}
+	tags := make(model.KeyValues, len(attrs))
+	for i, attr := range attrs {
+		tags[i] = td.getTag(attr)
+	}
+	return tags
+}
+
+// getTag converts an attribute into a tag, arrays and key-value lists are presented as JSON strings
+func (td toDomain) getTag(attr *otlp.KeyValue) model.KeyValue {
+	switch v := attr.Value.GetValue().(type) {
+	case *otlp.AnyValue_StringValue:
+		return model.String(attr.Key, v.StringValue)
+	case *otlp.AnyValue_BoolValue:
+		return model.Bool(attr.Key, v.BoolValue)
+	case *otlp.AnyValue_IntValue:
+		return model.Int64(attr.Key, v.IntValue)
+	case *otlp.AnyValue_DoubleValue:
+		return model.Float64(attr.Key, v.DoubleValue)
+	case *otlp.AnyValue_BytesValue:
+		return model.Binary(attr.Key, v.BytesValue)
+	case nil:
+		return model.String(attr.Key, "")
+	default:
+		value, err := json.Marshal(td.getJSONValue(attr.Value))
+		if err != nil {
+			return model.String(attr.Key, fmt.Sprintf("Cannot encode OTLP value: %v", err))
+		}
+		return model.String(attr.Key, string(value))
+	}
+}
+
+func (td toDomain) getJSONValue(value *otlp.AnyValue) interface{} {
+	switch v := value.GetValue().(type) {
+	case *otlp.AnyValue_StringValue:
+		return v.StringValue
+	case *otlp.AnyValue_BoolValue:
+		return v.BoolValue
+	case *otlp.AnyValue_IntValue:
+		return v.IntValue
+	case *otlp.AnyValue_DoubleValue:
+		return v.DoubleValue
+	case *otlp.AnyValue_BytesValue:
+		return v.BytesValue
+	case *otlp.AnyValue_ArrayValue:
+		values := make([]interface{}, len(v.ArrayValue.GetValues()))
+		for i, item := range v.ArrayValue.GetValues() {
+			values[i] = td.getJSONValue(item)
+		}
+		return values
+	case *otlp.AnyValue_KvlistValue:
+		values := make(map[string]interface{}, len(v.KvlistValue.GetValues()))
+		for _, kv := range v.KvlistValue.GetValues() {
+			values[kv.Key] = td.getJSONValue(kv.Value)
+		}
+		return values
+	default:
+		return nil
+	}
+}
+
+// getLogs turns the events into logs, the name of an event is the DefaultLogFieldKey field of its log
+func (td toDomain) getLogs(events []*otlp.Span_Event) []model.Log {
+	if len(events) == 0 {
+		return nil
+	}
+	logs := make([]model.Log, len(events))
+	for i, event := range events {
+		fields := make(model.KeyValues, 0, len(event.Attributes)+1)
+		if event.Name != "" {
+			fields = append(fields, model.String(DefaultLogFieldKey, event.Name))
+		}
+		logs[i] = model.Log{
+			Timestamp: td.getTime(event.TimeUnixNano),
+			Fields:    append(fields, td.getTags(event.Attributes)...),
+		}
+	}
+	return logs
+}
@@ -0,0 +1,180 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otlp
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/proto-gen/otlp"
+)
+
+var (
+	testTraceID = []byte{0, 0, 0, 0, 0, 0, 0, 2, 0, 0, 0, 0, 0, 0, 0, 1}
+	testSpanID  = []byte{0, 0, 0, 0, 0, 0, 0, 3}
+)
+
+func stringAttr(key, value string) *otlp.KeyValue {
+	return &otlp.KeyValue{Key: key, Value: &otlp.AnyValue{Value: &otlp.AnyValue_StringValue{StringValue: value}}}
+}
+
+func intAttr(key string, value int64) *otlp.KeyValue {
+	return &otlp.KeyValue{Key: key, Value: &otlp.AnyValue{Value: &otlp.AnyValue_IntValue{IntValue: value}}}
+}
+
+// otlp_01.pb was encoded by the OpenTelemetry Go protobuf bindings (go.opentelemetry.io/proto/otlp)
+func TestToDomainFixture(t *testing.T) {
+	body, err := ioutil.ReadFile("fixtures/otlp_01.pb")
+	require.NoError(t, err)
+	req := &otlp.ExportTraceServiceRequest{}
+	require.NoError(t, proto.Unmarshal(body, req))
+
+	spans, err := ToDomain(req.ResourceSpans)
+	require.NoError(t, err)
+	require.Len(t, spans, 1)
+	expected := &model.Span{
+		TraceID:       model.TraceID{High: 2, Low: 1},
+		SpanID:        3,
+		ParentSpanID:  4,
+		OperationName: "GET /",
+		References:    []model.SpanRef{{RefType: model.ChildOf, TraceID: model.TraceID{High: 2, Low: 1}, SpanID: 4}},
+		Flags:         1,
+		StartTime:     model.EpochMicrosecondsAsTime(1485467191639875),
+		Duration:      5 * time.Microsecond,
+		Tags: model.KeyValues{
+			model.String("http.method", "GET"),
+			model.Int64("http.status_code", 500),
+			model.String("span.kind", "server"),
+			model.String("otel.status_code", "ERROR"),
+			model.Bool("error", true),
+			model.String("otel.status_description", "internal error"),
+			model.String("otel.library.name", "net/http"),
+			model.String("otel.library.version", "1.0.0"),
+		},
+		Logs: []model.Log{{
+			Timestamp: model.EpochMicrosecondsAsTime(1485467191639876),
+			Fields:    model.KeyValues{model.String("event", "retry"), model.Int64("attempt", 2)},
+		}},
+		Process: &model.Process{
+			ServiceName: "frontend",
+			Tags:        model.KeyValues{model.String("host.name", "host1")},
+		},
+	}
+	assert.Equal(t, expected, spans[0])
+}
+
+func TestToDomainAttributes(t *testing.T) {
+	resourceSpans := []*otlp.ResourceSpans{{
+		ScopeSpans: []*otlp.ScopeSpans{{
+			Spans: []*otlp.Span{{
+				TraceId: testTraceID,
+				SpanId:  testSpanID,
+				Kind:    otlp.Span_SPAN_KIND_CLIENT,
+				Attributes: []*otlp.KeyValue{
+					{Key: "bool", Value: &otlp.AnyValue{Value: &otlp.AnyValue_BoolValue{BoolValue: true}}},
+					{Key: "float64", Value: &otlp.AnyValue{Value: &otlp.AnyValue_DoubleValue{DoubleValue: 0.5}}},
+					{Key: "binary", Value: &otlp.AnyValue{Value: &otlp.AnyValue_BytesValue{BytesValue: []byte("bin")}}},
+					{Key: "array", Value: &otlp.AnyValue{Value: &otlp.AnyValue_ArrayValue{ArrayValue: &otlp.ArrayValue{
+						Values: []*otlp.AnyValue{{Value: &otlp.AnyValue_StringValue{StringValue: "a"}}, {Value: &otlp.AnyValue_IntValue{IntValue: 1}}},
+					}}}},
+					{Key: "kvlist", Value: &otlp.AnyValue{Value: &otlp.AnyValue_KvlistValue{KvlistValue: &otlp.KeyValueList{
+						Values: []*otlp.KeyValue{stringAttr("k", "v")},
+					}}}},
+					{Key: "empty"},
+				},
+				Status:     &otlp.Status{Code: otlp.Status_STATUS_CODE_OK},
+				TraceState: "vendor=value",
+				Links:      []*otlp.Span_Link{{TraceId: testTraceID, SpanId: []byte{0, 0, 0, 0, 0, 0, 0, 5}}},
+			}},
+		}},
+	}}
+
+	spans, err := ToDomain(resourceSpans)
+	require.NoError(t, err)
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, &model.Process{ServiceName: UnknownServiceName}, span.Process)
+	assert.Equal(t, model.SpanID(0), span.ParentSpanID)
+	assert.Equal(t, []model.SpanRef{{RefType: model.FollowsFrom, TraceID: model.TraceID{High: 2, Low: 1}, SpanID: 5}}, span.References)
+	assert.Equal(t, model.KeyValues{
+		model.Bool("bool", true),
+		model.Float64("float64", 0.5),
+		model.Binary("binary", []byte("bin")),
+		model.String("array", `["a",1]`),
+		model.String("kvlist", `{"k":"v"}`),
+		model.String("empty", ""),
+		model.String("span.kind", "client"),
+		model.String("otel.status_code", "OK"),
+		model.String("w3c.tracestate", "vendor=value"),
+	}, span.Tags)
+}
+
+func TestToDomainMultipleResources(t *testing.T) {
+	resourceSpans := []*otlp.ResourceSpans{
+		{
+			Resource:   &otlp.Resource{Attributes: []*otlp.KeyValue{stringAttr(ServiceNameAttribute, "frontend")}},
+			ScopeSpans: []*otlp.ScopeSpans{{Spans: []*otlp.Span{{TraceId: testTraceID, SpanId: testSpanID}}}},
+		},
+		{
+			Resource: &otlp.Resource{Attributes: []*otlp.KeyValue{stringAttr(ServiceNameAttribute, "backend"), intAttr("pid", 7)}},
+			ScopeSpans: []*otlp.ScopeSpans{
+				{Spans: []*otlp.Span{{TraceId: testTraceID, SpanId: testSpanID}}},
+				{Spans: []*otlp.Span{{TraceId: testTraceID, SpanId: testSpanID}}},
+			},
+		},
+	}
+
+	spans, err := ToDomain(resourceSpans)
+	require.NoError(t, err)
+	require.Len(t, spans, 3)
+	assert.Equal(t, "frontend", spans[0].Process.ServiceName)
+	assert.Equal(t, &model.Process{ServiceName: "backend", Tags: model.KeyValues{model.Int64("pid", 7)}}, spans[1].Process)
+	assert.Equal(t, spans[1].Process, spans[2].Process)
+}
+
+func TestToDomainInvalidIDs(t *testing.T) {
+	testCases := []struct {
+		span   *otlp.Span
+		errMsg string
+	}{
+		{
+			span:   &otlp.Span{TraceId: []byte{1}, SpanId: testSpanID},
+			errMsg: "invalid OTLP trace ID 01, it must be 16 bytes long",
+		},
+		{
+			span:   &otlp.Span{TraceId: testTraceID},
+			errMsg: "invalid OTLP span ID , it must be 8 bytes long",
+		},
+		{
+			span:   &otlp.Span{TraceId: testTraceID, SpanId: testSpanID, ParentSpanId: []byte{1, 2}},
+			errMsg: "invalid OTLP span ID 0102, it must be 8 bytes long",
+		},
+		{
+			span:   &otlp.Span{TraceId: testTraceID, SpanId: testSpanID, Links: []*otlp.Span_Link{{TraceId: testTraceID}}},
+			errMsg: "invalid OTLP span ID , it must be 8 bytes long",
+		},
+	}
+	for _, testCase := range testCases {
+		resourceSpans := []*otlp.ResourceSpans{{ScopeSpans: []*otlp.ScopeSpans{{Spans: []*otlp.Span{testCase.span}}}}}
+		_, err := ToDomain(resourceSpans)
+		assert.EqualError(t, err, testCase.errMsg)
+	}
+}
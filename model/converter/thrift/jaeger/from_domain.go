@@ -41,6 +41,12 @@ func FromDomainSpan(span *model.Span) *jaeger.Span {
 	return dToJ.transformSpan(span)
 }
 
+// FromDomainProcess takes a model.Process and converts it into a jaeger.Process.
+func FromDomainProcess(process *model.Process) *jaeger.Process {
+	dToJ := &domainToJaegerTransformer{}
+	return dToJ.transformProcess(process)
+}
+
 type domainToJaegerTransformer struct{}
 
 func (d domainToJaegerTransformer) keyValueToTag(kv *model.KeyValue) *jaeger.Tag {
@@ -154,3 +160,10 @@ func (d domainToJaegerTransformer) transformSpan(span *model.Span) *jaeger.Span
 	}
 	return jaegerSpan
 }
+
+func (d domainToJaegerTransformer) transformProcess(process *model.Process) *jaeger.Process {
+	return &jaeger.Process{
+		ServiceName: process.ServiceName,
+		Tags:        d.convertKeyValuesToTags(process.Tags),
+	}
+}
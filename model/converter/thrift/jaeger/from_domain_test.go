@@ -56,6 +56,16 @@ func TestFromDomainSpan(t *testing.T) {
 	assert.Equal(t, modelSpan, newModelSpan)
 }
 
+func TestFromDomainProcess(t *testing.T) {
+	batchFile := "fixtures/thrift_batch_01.json"
+	jaegerBatch := loadBatch(t, batchFile)
+
+	modelSpan := ToDomainSpan(jaegerBatch.Spans[0], jaegerBatch.Process)
+	jaegerProcess := FromDomainProcess(modelSpan.Process)
+	newModelSpan := ToDomainSpan(jaegerBatch.Spans[0], jaegerProcess)
+	assert.Equal(t, modelSpan.Process, newModelSpan.Process)
+}
+
 func TestFromDomain(t *testing.T) {
 	file := "fixtures/model_03.json"
 	modelSpans := loadSpans(t, file)
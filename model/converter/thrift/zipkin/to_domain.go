@@ -132,12 +132,14 @@ func (td toDomain) transformSpan(zSpan *zipkincore.Span) []*model.Span {
 		parentID = *zSpan.ParentID
 	}
 
+	traceID := model.TraceID{High: uint64(traceIDHigh), Low: uint64(zSpan.TraceID)}
 	flags := td.getFlags(zSpan)
 	result := []*model.Span{{
-		TraceID:       model.TraceID{High: uint64(traceIDHigh), Low: uint64(zSpan.TraceID)},
+		TraceID:       traceID,
 		SpanID:        model.SpanID(zSpan.ID),
 		OperationName: zSpan.Name,
 		ParentSpanID:  model.SpanID(parentID),
+		References:    td.getReferences(traceID, parentID),
 		Flags:         flags,
 		StartTime:     model.EpochMicrosecondsAsTime(uint64(zSpan.GetTimestamp())),
 		Duration:      model.MicrosecondsAsDuration(uint64(zSpan.GetDuration())),
@@ -150,10 +152,11 @@ func (td toDomain) transformSpan(zSpan *zipkincore.Span) []*model.Span {
 	if cs != nil && sr != nil {
 		// if the span is client and server we split it into two separate spans
 		s := &model.Span{
-			TraceID:       model.TraceID{High: uint64(traceIDHigh), Low: uint64(zSpan.TraceID)},
+			TraceID:       traceID,
 			SpanID:        model.SpanID(zSpan.ID),
 			OperationName: zSpan.Name,
 			ParentSpanID:  model.SpanID(parentID),
+			References:    td.getReferences(traceID, parentID),
 			Flags:         flags,
 		}
 		// if the first span is a client span we create server span and vice-versa.
@@ -175,6 +178,15 @@ func (td toDomain) transformSpan(zSpan *zipkincore.Span) []*model.Span {
 	return result
 }
 
+// getReferences returns the references of a span with the given parent. Zipkin only knows about
+// the parent of a span, so unlike Jaeger spans, Zipkin spans never have FollowsFrom references.
+func (td toDomain) getReferences(traceID model.TraceID, parentID int64) []model.SpanRef {
+	if parentID == 0 {
+		return nil
+	}
+	return []model.SpanRef{{RefType: model.ChildOf, TraceID: traceID, SpanID: model.SpanID(parentID)}}
+}
+
 // getFlags takes a Zipkin Span and deduces the proper flags settings
 func (td toDomain) getFlags(zSpan *zipkincore.Span) model.Flags {
 	f := model.Flags(0)
@@ -129,6 +129,22 @@ func TestToDomainMultipleSpanKinds(t *testing.T) {
 	}
 }
 
+func TestToDomainSharedSpanReferences(t *testing.T) {
+	trace, err := ToDomain(getZipkinSpans(t, `[{ "trace_id": 1, "id": 31, "parent_id": 30, "annotations": [
+		{"value": "cs", "host": {"service_name": "foo", "ipv4": 23456}},
+		{"value": "sr", "timestamp": 1, "host": {"service_name": "bar", "ipv4": 23456}}
+		]}]`))
+	require.NoError(t, err)
+	require.Len(t, trace.Spans, 2, "the shared span is split into a client and a server span")
+
+	expectedRefs := []model.SpanRef{{RefType: model.ChildOf, TraceID: model.TraceID{Low: 1}, SpanID: model.SpanID(30)}}
+	for _, span := range trace.Spans {
+		assert.Equal(t, model.SpanID(31), span.SpanID)
+		assert.Equal(t, model.SpanID(30), span.ParentSpanID)
+		assert.Equal(t, expectedRefs, span.References)
+	}
+}
+
 func TestInvalidAnnotationTypeError(t *testing.T) {
 	_, err := toDomain{}.transformBinaryAnnotation(&z.BinaryAnnotation{
 		AnnotationType: -1,
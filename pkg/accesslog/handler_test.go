@@ -0,0 +1,78 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package accesslog
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/jaeger/pkg/testutils"
+)
+
+func TestAccessLogHandler(t *testing.T) {
+	testCases := []struct {
+		handler http.HandlerFunc
+		status  float64
+		bytes   float64
+	}{
+		{
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusAccepted)
+			},
+			status: http.StatusAccepted,
+		},
+		{
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("hello"))
+			},
+			status: http.StatusOK,
+			bytes:  5,
+		},
+		{
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "bad", http.StatusBadRequest)
+			},
+			status: http.StatusBadRequest,
+			bytes:  4,
+		},
+		{
+			handler: func(w http.ResponseWriter, r *http.Request) {},
+			status:  http.StatusOK,
+		},
+	}
+	for _, tc := range testCases {
+		logger, log := testutils.NewLogger()
+		req, err := http.NewRequest(http.MethodPost, "/api/traces?format=jaeger.thrift", nil)
+		require.NoError(t, err)
+		res := httptest.NewRecorder()
+		NewAccessLogHandler(logger)(tc.handler).ServeHTTP(res, req)
+
+		require.Len(t, log.Lines(), 1)
+		var fields map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(log.Lines()[0]), &fields))
+		assert.Equal(t, "HTTP request", fields["msg"])
+		assert.Equal(t, http.MethodPost, fields["method"])
+		assert.Equal(t, "/api/traces", fields["path"])
+		assert.Equal(t, tc.status, fields["status"])
+		assert.Equal(t, tc.bytes, fields["bytes"])
+		assert.Contains(t, fields, "latency")
+		assert.EqualValues(t, tc.status, res.Code)
+	}
+}
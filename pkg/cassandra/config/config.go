@@ -16,6 +16,7 @@ package config
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/gocql/gocql"
@@ -31,11 +32,15 @@ type Configuration struct {
 	ConnectionsPerHost int           `validate:"min=1" yaml:"connections_per_host"`
 	Timeout            time.Duration `validate:"min=500"`
 	SocketKeepAlive    time.Duration `validate:"min=0" yaml:"socket_keep_alive"`
+	ReconnectInterval  time.Duration `validate:"min=0" yaml:"reconnect_interval"`
 	MaxRetryAttempts   int           `validate:"min=0" yaml:"max_retry_attempt"`
 	ProtoVersion       int           `yaml:"proto_version"`
 	Consistency        string        `yaml:"consistency"`
+	LocalDC            string        `yaml:"local_dc"`
 	Port               int           `yaml:"port"`
 	Authenticator      Authenticator `yaml:"authenticator"`
+	TLS                TLS           `yaml:"tls"`
+	SchemaCheck        string        `yaml:"schema_check"`
 }
 
 // Authenticator holds the authentication properties needed to connect to a Cassandra cluster
@@ -50,6 +55,14 @@ type BasicAuthenticator struct {
 	Password string `yaml:"password"`
 }
 
+// TLS holds the certificates used to connect to a Cassandra cluster over TLS
+type TLS struct {
+	Enabled  bool   `yaml:"enabled"`
+	CaPath   string `yaml:"ca_path"`
+	CertPath string `yaml:"cert_path"`
+	KeyPath  string `yaml:"key_path"`
+}
+
 // ApplyDefaults copies settings from source unless its own value is non-zero.
 func (c *Configuration) ApplyDefaults(source *Configuration) {
 	if c.ConnectionsPerHost == 0 {
@@ -73,6 +86,15 @@ func (c *Configuration) ApplyDefaults(source *Configuration) {
 	if c.SocketKeepAlive == 0 {
 		c.SocketKeepAlive = source.SocketKeepAlive
 	}
+	if c.ReconnectInterval == 0 {
+		c.ReconnectInterval = source.ReconnectInterval
+	}
+	if c.Consistency == "" {
+		c.Consistency = source.Consistency
+	}
+	if c.LocalDC == "" {
+		c.LocalDC = source.LocalDC
+	}
 }
 
 // SessionBuilder creates new cassandra.Session
@@ -82,7 +104,10 @@ type SessionBuilder interface {
 
 // NewSession creates a new Cassandra session
 func (c *Configuration) NewSession() (cassandra.Session, error) {
-	cluster := c.NewCluster()
+	cluster, err := c.NewCluster()
+	if err != nil {
+		return nil, err
+	}
 	session, err := cluster.CreateSession()
 	if err != nil {
 		return nil, err
@@ -90,13 +115,39 @@ func (c *Configuration) NewSession() (cassandra.Session, error) {
 	return gocqlw.WrapCQLSession(session), nil
 }
 
-// NewCluster creates a new gocql cluster from the configuration
-func (c *Configuration) NewCluster() *gocql.ClusterConfig {
+// Validate checks that the connection pool settings and timeouts are in range
+func (c *Configuration) Validate() error {
+	if c.ConnectionsPerHost < 1 {
+		return fmt.Errorf("invalid Cassandra connections per host %d, must be at least 1", c.ConnectionsPerHost)
+	}
+	if c.Timeout < 0 {
+		return fmt.Errorf("invalid Cassandra timeout %v, must not be negative", c.Timeout)
+	}
+	if c.ReconnectInterval < 0 {
+		return fmt.Errorf("invalid Cassandra reconnect interval %v, must not be negative", c.ReconnectInterval)
+	}
+	if c.MaxRetryAttempts < 0 {
+		return fmt.Errorf("invalid Cassandra max retry attempts %d, must not be negative", c.MaxRetryAttempts)
+	}
+	return nil
+}
+
+// NewCluster creates a new gocql cluster from the configuration, it fails if the configuration
+// is out of range or the consistency is unknown
+func (c *Configuration) NewCluster() (*gocql.ClusterConfig, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	consistency, err := ParseConsistency(c.Consistency)
+	if err != nil {
+		return nil, err
+	}
 	cluster := gocql.NewCluster(c.Servers...)
 	cluster.Keyspace = c.Keyspace
 	cluster.NumConns = c.ConnectionsPerHost
 	cluster.Timeout = c.Timeout
 	cluster.SocketKeepalive = c.SocketKeepAlive
+	cluster.ReconnectInterval = c.ReconnectInterval
 	if c.ProtoVersion > 0 {
 		cluster.ProtoVersion = c.ProtoVersion
 	}
@@ -107,19 +158,40 @@ func (c *Configuration) NewCluster() *gocql.ClusterConfig {
 		cluster.Port = c.Port
 	}
 	cluster.Compressor = gocql.SnappyCompressor{}
-	if c.Consistency == "" {
-		cluster.Consistency = gocql.LocalOne
+	cluster.Consistency = consistency
+	if c.LocalDC != "" {
+		cluster.PoolConfig.HostSelectionPolicy = gocql.TokenAwareHostPolicy(gocql.DCAwareRoundRobinPolicy(c.LocalDC))
 	} else {
-		cluster.Consistency = gocql.ParseConsistency(c.Consistency)
+		cluster.PoolConfig.HostSelectionPolicy = gocql.TokenAwareHostPolicy(gocql.RoundRobinHostPolicy())
 	}
-	cluster.PoolConfig.HostSelectionPolicy = gocql.TokenAwareHostPolicy(gocql.RoundRobinHostPolicy())
 	if c.Authenticator.Basic.Username != "" && c.Authenticator.Basic.Password != "" {
 		cluster.Authenticator = gocql.PasswordAuthenticator{
 			Username: c.Authenticator.Basic.Username,
 			Password: c.Authenticator.Basic.Password,
 		}
 	}
-	return cluster
+	if c.TLS.Enabled {
+		cluster.SslOpts = &gocql.SslOptions{
+			CaPath:                 c.TLS.CaPath,
+			CertPath:               c.TLS.CertPath,
+			KeyPath:                c.TLS.KeyPath,
+			EnableHostVerification: true,
+		}
+	}
+	return cluster, nil
+}
+
+// ParseConsistency returns the gocql consistency level named by consistency, e.g. LOCAL_QUORUM,
+// ignoring its case. The empty string is LOCAL_ONE.
+func ParseConsistency(consistency string) (gocql.Consistency, error) {
+	if consistency == "" {
+		return gocql.LocalOne, nil
+	}
+	var level gocql.Consistency
+	if err := level.UnmarshalText([]byte(strings.ToUpper(consistency))); err != nil {
+		return 0, fmt.Errorf("invalid Cassandra consistency %q", consistency)
+	}
+	return level, nil
 }
 
 func (c *Configuration) String() string {
@@ -0,0 +1,112 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseConsistency(t *testing.T) {
+	testCases := []struct {
+		consistency string
+		expected    gocql.Consistency
+	}{
+		{consistency: "", expected: gocql.LocalOne},
+		{consistency: "ANY", expected: gocql.Any},
+		{consistency: "ONE", expected: gocql.One},
+		{consistency: "TWO", expected: gocql.Two},
+		{consistency: "THREE", expected: gocql.Three},
+		{consistency: "QUORUM", expected: gocql.Quorum},
+		{consistency: "ALL", expected: gocql.All},
+		{consistency: "LOCAL_QUORUM", expected: gocql.LocalQuorum},
+		{consistency: "EACH_QUORUM", expected: gocql.EachQuorum},
+		{consistency: "LOCAL_ONE", expected: gocql.LocalOne},
+		{consistency: "local_quorum", expected: gocql.LocalQuorum},
+	}
+	for _, testCase := range testCases {
+		consistency, err := ParseConsistency(testCase.consistency)
+		require.NoError(t, err, testCase.consistency)
+		assert.Equal(t, testCase.expected, consistency, testCase.consistency)
+	}
+
+	_, err := ParseConsistency("LOCAL_MOST")
+	assert.EqualError(t, err, `invalid Cassandra consistency "LOCAL_MOST"`)
+}
+
+func TestNewClusterConsistency(t *testing.T) {
+	cfg := &Configuration{Servers: []string{"127.0.0.1"}, ConnectionsPerHost: 2, Consistency: "QUORUM", LocalDC: "dc1"}
+	cluster, err := cfg.NewCluster()
+	require.NoError(t, err)
+	assert.Equal(t, gocql.Quorum, cluster.Consistency)
+
+	cfg.Consistency = "LOCAL_MOST"
+	_, err = cfg.NewCluster()
+	assert.Error(t, err)
+}
+
+func TestNewClusterPool(t *testing.T) {
+	cfg := &Configuration{
+		Servers:            []string{"127.0.0.1"},
+		ConnectionsPerHost: 8,
+		Timeout:            2 * time.Second,
+		ReconnectInterval:  30 * time.Second,
+		MaxRetryAttempts:   4,
+	}
+	cluster, err := cfg.NewCluster()
+	require.NoError(t, err)
+	assert.Equal(t, 8, cluster.NumConns)
+	assert.Equal(t, 2*time.Second, cluster.Timeout)
+	assert.Equal(t, 30*time.Second, cluster.ReconnectInterval)
+	assert.Equal(t, &gocql.SimpleRetryPolicy{NumRetries: 3}, cluster.RetryPolicy)
+}
+
+func TestValidate(t *testing.T) {
+	valid := Configuration{ConnectionsPerHost: 1}
+	assert.NoError(t, valid.Validate())
+
+	testCases := []struct {
+		update func(cfg *Configuration)
+		err    string
+	}{
+		{
+			update: func(cfg *Configuration) { cfg.ConnectionsPerHost = 0 },
+			err:    "invalid Cassandra connections per host 0, must be at least 1",
+		},
+		{
+			update: func(cfg *Configuration) { cfg.Timeout = -time.Second },
+			err:    "invalid Cassandra timeout -1s, must not be negative",
+		},
+		{
+			update: func(cfg *Configuration) { cfg.ReconnectInterval = -time.Second },
+			err:    "invalid Cassandra reconnect interval -1s, must not be negative",
+		},
+		{
+			update: func(cfg *Configuration) { cfg.MaxRetryAttempts = -1 },
+			err:    "invalid Cassandra max retry attempts -1, must not be negative",
+		},
+	}
+	for _, testCase := range testCases {
+		cfg := valid
+		testCase.update(&cfg)
+		assert.EqualError(t, cfg.Validate(), testCase.err)
+		_, err := cfg.NewCluster()
+		assert.EqualError(t, err, testCase.err)
+	}
+}
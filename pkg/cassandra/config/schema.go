@@ -0,0 +1,306 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/uber/jaeger/pkg/cassandra"
+)
+
+// The modes of the schema check of a session, which decide what happens when tables are missing.
+const (
+	SchemaCheckWarn           = "warn"
+	SchemaCheckFail           = "fail"
+	SchemaCheckCreateDefaults = "create-defaults"
+)
+
+const (
+	tablesQuery = "SELECT table_name FROM system_schema.tables WHERE keyspace_name = ?"
+
+	defaultTraceTTL        = "172800"
+	defaultDependenciesTTL = "0"
+)
+
+// requiredTables are the tables of plugin/storage/cassandra/schema/v001.cql.tmpl.
+var requiredTables = []string{
+	"traces",
+	"service_names",
+	"operation_names",
+	"service_operation_index",
+	"service_name_index",
+	"duration_index",
+	"tag_index",
+	"dependencies",
+}
+
+// defaultSchema is plugin/storage/cassandra/schema/v001.cql.tmpl without the keyspace, which must
+// already exist to open a session, and with the default TTLs of create.sh.
+const defaultSchema = `
+CREATE TYPE IF NOT EXISTS ${keyspace}.keyvalue (
+    key             text,
+    value_type      text,
+    value_string    text,
+    value_bool      boolean,
+    value_long      bigint,
+    value_double    double,
+    value_binary    blob,
+);
+
+CREATE TYPE IF NOT EXISTS ${keyspace}.log (
+    ts      bigint,
+    fields  list<frozen<keyvalue>>,
+);
+
+CREATE TYPE IF NOT EXISTS ${keyspace}.span_ref (
+    ref_type        text,
+    trace_id        blob,
+    span_id         bigint,
+);
+
+CREATE TYPE IF NOT EXISTS ${keyspace}.process (
+    service_name    text,
+    tags            list<frozen<keyvalue>>,
+);
+
+CREATE TABLE IF NOT EXISTS ${keyspace}.traces (
+    trace_id        blob,
+    span_id         bigint,
+    span_hash       bigint,
+    parent_id       bigint,
+    operation_name  text,
+    flags           int,
+    start_time      bigint,
+    duration        bigint,
+    tags            list<frozen<keyvalue>>,
+    logs            list<frozen<log>>,
+    refs            list<frozen<span_ref>>,
+    process         frozen<process>,
+    PRIMARY KEY (trace_id, span_id, span_hash)
+)
+    WITH compaction = {
+        'compaction_window_size': '1',
+        'compaction_window_unit': 'HOURS',
+        'class': 'org.apache.cassandra.db.compaction.TimeWindowCompactionStrategy'
+    }
+    AND dclocal_read_repair_chance = 0.0
+    AND default_time_to_live = ${trace_ttl}
+    AND speculative_retry = 'NONE'
+    AND gc_grace_seconds = 10800;
+
+CREATE TABLE IF NOT EXISTS ${keyspace}.service_names (
+    service_name text,
+    PRIMARY KEY (service_name)
+)
+    WITH compaction = {
+        'min_threshold': '4',
+        'max_threshold': '32',
+        'class': 'org.apache.cassandra.db.compaction.SizeTieredCompactionStrategy'
+    }
+    AND dclocal_read_repair_chance = 0.0
+    AND default_time_to_live = ${trace_ttl}
+    AND speculative_retry = 'NONE'
+    AND gc_grace_seconds = 10800;
+
+CREATE TABLE IF NOT EXISTS ${keyspace}.operation_names (
+    service_name        text,
+    operation_name      text,
+    PRIMARY KEY ((service_name), operation_name)
+)
+    WITH compaction = {
+        'min_threshold': '4',
+        'max_threshold': '32',
+        'class': 'org.apache.cassandra.db.compaction.SizeTieredCompactionStrategy'
+    }
+    AND dclocal_read_repair_chance = 0.0
+    AND default_time_to_live = ${trace_ttl}
+    AND speculative_retry = 'NONE'
+    AND gc_grace_seconds = 10800;
+
+CREATE TABLE IF NOT EXISTS ${keyspace}.service_operation_index (
+    service_name        text,
+    operation_name      text,
+    start_time          bigint,
+    trace_id            blob,
+    PRIMARY KEY ((service_name, operation_name), start_time)
+) WITH CLUSTERING ORDER BY (start_time DESC)
+    AND compaction = {
+        'compaction_window_size': '1',
+        'compaction_window_unit': 'HOURS',
+        'class': 'org.apache.cassandra.db.compaction.TimeWindowCompactionStrategy'
+    }
+    AND dclocal_read_repair_chance = 0.0
+    AND default_time_to_live = ${trace_ttl}
+    AND speculative_retry = 'NONE'
+    AND gc_grace_seconds = 10800;
+
+CREATE TABLE IF NOT EXISTS ${keyspace}.service_name_index (
+    service_name      text,
+    bucket            int,
+    start_time        bigint,
+    trace_id          blob,
+    PRIMARY KEY ((service_name, bucket), start_time)
+) WITH CLUSTERING ORDER BY (start_time DESC)
+    AND compaction = {
+        'compaction_window_size': '1',
+        'compaction_window_unit': 'HOURS',
+        'class': 'org.apache.cassandra.db.compaction.TimeWindowCompactionStrategy'
+    }
+    AND dclocal_read_repair_chance = 0.0
+    AND default_time_to_live = ${trace_ttl}
+    AND speculative_retry = 'NONE'
+    AND gc_grace_seconds = 10800;
+
+CREATE TABLE IF NOT EXISTS ${keyspace}.duration_index (
+    service_name    text,
+    operation_name  text,
+    bucket          timestamp,
+    duration        bigint,
+    start_time      bigint,
+    trace_id        blob,
+    PRIMARY KEY ((service_name, operation_name, bucket), duration, start_time, trace_id)
+) WITH CLUSTERING ORDER BY (duration DESC, start_time DESC)
+    AND compaction = {
+        'compaction_window_size': '1',
+        'compaction_window_unit': 'HOURS',
+        'class': 'org.apache.cassandra.db.compaction.TimeWindowCompactionStrategy'
+    }
+    AND dclocal_read_repair_chance = 0.0
+    AND default_time_to_live = ${trace_ttl}
+    AND speculative_retry = 'NONE'
+    AND gc_grace_seconds = 10800;
+
+CREATE TABLE IF NOT EXISTS ${keyspace}.tag_index (
+    service_name    text,
+    tag_key         text,
+    tag_value       text,
+    start_time      bigint,
+    trace_id        blob,
+    span_id         bigint,
+    PRIMARY KEY ((service_name, tag_key, tag_value), start_time, trace_id, span_id)
+)
+    WITH CLUSTERING ORDER BY (start_time DESC)
+    AND compaction = {
+        'compaction_window_size': '1',
+        'compaction_window_unit': 'HOURS',
+        'class': 'org.apache.cassandra.db.compaction.TimeWindowCompactionStrategy'
+    }
+    AND dclocal_read_repair_chance = 0.0
+    AND default_time_to_live = ${trace_ttl}
+    AND speculative_retry = 'NONE'
+    AND gc_grace_seconds = 10800;
+
+CREATE TYPE IF NOT EXISTS ${keyspace}.dependency (
+    parent          text,
+    child           text,
+    call_count      bigint,
+);
+
+CREATE TABLE IF NOT EXISTS ${keyspace}.dependencies (
+    ts          timestamp,
+    ts_index    timestamp,
+    dependencies list<frozen<dependency>>,
+    PRIMARY KEY (ts)
+)
+    WITH compaction = {
+        'min_threshold': '4',
+        'max_threshold': '32',
+        'class': 'org.apache.cassandra.db.compaction.SizeTieredCompactionStrategy'
+    }
+    AND default_time_to_live = ${dependencies_ttl};
+
+CREATE CUSTOM INDEX IF NOT EXISTS dependencies_ts_index_idx ON ${keyspace}.dependencies (ts_index)
+    USING 'org.apache.cassandra.index.sasi.SASIIndex'
+    WITH OPTIONS = {'mode': 'SPARSE'};
+`
+
+// SchemaChecker is implemented by the SessionBuilders that can check the schema of the sessions they create
+type SchemaChecker interface {
+	CheckSchema(session cassandra.Session, logger *zap.Logger) error
+}
+
+// CheckSchema checks that the keyspace of session has the tables of the Jaeger schema. Depending on
+// SchemaCheck, missing tables are logged, fail the check or are created with the default schema.
+// An empty SchemaCheck skips the check.
+func (c *Configuration) CheckSchema(session cassandra.Session, logger *zap.Logger) error {
+	switch c.SchemaCheck {
+	case "":
+		return nil
+	case SchemaCheckWarn, SchemaCheckFail, SchemaCheckCreateDefaults:
+	default:
+		return fmt.Errorf("invalid Cassandra schema check %q, expected %s, %s or %s",
+			c.SchemaCheck, SchemaCheckWarn, SchemaCheckFail, SchemaCheckCreateDefaults)
+	}
+	missing, err := MissingTables(session, c.Keyspace)
+	if err != nil {
+		return err
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	switch c.SchemaCheck {
+	case SchemaCheckWarn:
+		logger.Warn("Cassandra keyspace is missing tables, spans written to them will fail",
+			zap.String("keyspace", c.Keyspace), zap.Strings("tables", missing))
+		return nil
+	case SchemaCheckFail:
+		return fmt.Errorf("Cassandra keyspace %s is missing tables %s", c.Keyspace, strings.Join(missing, ", "))
+	}
+	logger.Info("Creating the missing tables of the Cassandra keyspace with the default schema",
+		zap.String("keyspace", c.Keyspace), zap.Strings("tables", missing))
+	return CreateDefaultSchema(session, c.Keyspace)
+}
+
+// MissingTables returns the tables of the Jaeger schema that the keyspace does not have.
+func MissingTables(session cassandra.Session, keyspace string) ([]string, error) {
+	iter := session.Query(tablesQuery, keyspace).Iter()
+	tables := make(map[string]struct{})
+	var table string
+	for iter.Scan(&table) {
+		tables[table] = struct{}{}
+	}
+	if err := iter.Close(); err != nil {
+		return nil, fmt.Errorf("cannot list the tables of Cassandra keyspace %s: %v", keyspace, err)
+	}
+	var missing []string
+	for _, table := range requiredTables {
+		if _, ok := tables[table]; !ok {
+			missing = append(missing, table)
+		}
+	}
+	return missing, nil
+}
+
+// CreateDefaultSchema creates the types, tables and indices of the Jaeger schema that the keyspace
+// does not have, with the default TTLs.
+func CreateDefaultSchema(session cassandra.Session, keyspace string) error {
+	schema := strings.NewReplacer(
+		"${keyspace}", keyspace,
+		"${trace_ttl}", defaultTraceTTL,
+		"${dependencies_ttl}", defaultDependenciesTTL,
+	).Replace(defaultSchema)
+	for _, stmt := range strings.Split(schema, ";") {
+		if stmt = strings.TrimSpace(stmt); stmt == "" {
+			continue
+		}
+		if err := session.Query(stmt).Exec(); err != nil {
+			return fmt.Errorf("cannot create the default schema of Cassandra keyspace %s: %v", keyspace, err)
+		}
+	}
+	return nil
+}
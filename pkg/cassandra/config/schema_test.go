@@ -0,0 +1,129 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"errors"
+	"io/ioutil"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/uber/jaeger/pkg/cassandra/mocks"
+	"github.com/uber/jaeger/pkg/testutils"
+)
+
+const schemaTemplate = "../../../plugin/storage/cassandra/schema/v001.cql.tmpl"
+
+// withTables returns a session whose keyspace has the given tables
+func withTables(tables []string, closeErr error) *mocks.Session {
+	scanTable := func(args []interface{}) bool {
+		if len(tables) == 0 {
+			return false
+		}
+		*args[0].(*string) = tables[0]
+		tables = tables[1:]
+		return true
+	}
+	iter := &mocks.Iterator{}
+	iter.On("Scan", mock.MatchedBy(scanTable)).Return(true)
+	iter.On("Scan", mock.Anything).Return(false)
+	iter.On("Close").Return(closeErr)
+
+	query := &mocks.Query{}
+	query.On("Iter").Return(iter)
+
+	session := &mocks.Session{}
+	session.On("Query", tablesQuery, []interface{}{"jaeger"}).Return(query)
+	return session
+}
+
+func TestCheckSchemaWarn(t *testing.T) {
+	cfg := &Configuration{Keyspace: "jaeger", SchemaCheck: SchemaCheckWarn}
+
+	logger, logBuffer := testutils.NewLogger()
+	require.NoError(t, cfg.CheckSchema(withTables(requiredTables, nil), logger))
+	assert.Empty(t, logBuffer.String())
+
+	require.NoError(t, cfg.CheckSchema(withTables([]string{"traces", "service_names"}, nil), logger))
+	assert.Contains(t, logBuffer.String(), `"msg":"Cassandra keyspace is missing tables, spans written to them will fail"`)
+	assert.Contains(t, logBuffer.String(), `"tables":["operation_names","service_operation_index","service_name_index","duration_index","tag_index","dependencies"]`)
+}
+
+func TestCheckSchemaFail(t *testing.T) {
+	cfg := &Configuration{Keyspace: "jaeger", SchemaCheck: SchemaCheckFail}
+
+	assert.NoError(t, cfg.CheckSchema(withTables(requiredTables, nil), zap.NewNop()))
+
+	err := cfg.CheckSchema(withTables([]string{"traces", "service_names", "operation_names", "tag_index"}, nil), zap.NewNop())
+	assert.EqualError(t, err, "Cassandra keyspace jaeger is missing tables service_operation_index, service_name_index, duration_index, dependencies")
+
+	err = cfg.CheckSchema(withTables(nil, errors.New("unauthorized")), zap.NewNop())
+	assert.EqualError(t, err, "cannot list the tables of Cassandra keyspace jaeger: unauthorized")
+}
+
+func TestCheckSchemaCreateDefaults(t *testing.T) {
+	cfg := &Configuration{Keyspace: "jaeger", SchemaCheck: SchemaCheckCreateDefaults}
+
+	session := withTables([]string{"traces"}, nil)
+	var stmts []string
+	exec := &mocks.Query{}
+	exec.On("Exec").Return(nil)
+	session.On("Query", mock.AnythingOfType("string"), mock.Anything).Return(exec).Run(func(args mock.Arguments) {
+		stmts = append(stmts, args.String(0))
+	})
+
+	require.NoError(t, cfg.CheckSchema(session, zap.NewNop()))
+	require.Len(t, stmts, 14)
+	assert.Regexp(t, `^CREATE TYPE IF NOT EXISTS jaeger\.keyvalue \(`, stmts[0])
+	assert.Contains(t, stmts[4], "default_time_to_live = 172800")
+	assert.Contains(t, stmts[12], "default_time_to_live = 0")
+	assert.Regexp(t, `^CREATE CUSTOM INDEX IF NOT EXISTS dependencies_ts_index_idx ON jaeger\.dependencies`, stmts[13])
+
+	session = withTables(nil, nil)
+	failed := &mocks.Query{}
+	failed.On("Exec").Return(errors.New("unauthorized"))
+	session.On("Query", mock.AnythingOfType("string"), mock.Anything).Return(failed)
+	err := cfg.CheckSchema(session, zap.NewNop())
+	assert.EqualError(t, err, "cannot create the default schema of Cassandra keyspace jaeger: unauthorized")
+}
+
+func TestCheckSchemaSkippedOrInvalid(t *testing.T) {
+	session := &mocks.Session{}
+	assert.NoError(t, (&Configuration{Keyspace: "jaeger"}).CheckSchema(session, zap.NewNop()))
+	session.AssertNotCalled(t, "Query", mock.Anything, mock.Anything)
+
+	err := (&Configuration{Keyspace: "jaeger", SchemaCheck: "ignore"}).CheckSchema(session, zap.NewNop())
+	assert.EqualError(t, err, `invalid Cassandra schema check "ignore", expected warn, fail or create-defaults`)
+}
+
+func TestDefaultSchemaMatchesTemplate(t *testing.T) {
+	template, err := ioutil.ReadFile(schemaTemplate)
+	require.NoError(t, err)
+	tablePattern := regexp.MustCompile(`CREATE TABLE IF NOT EXISTS \$\{keyspace\}\.(\w+)`)
+	var templateTables, defaultTables []string
+	for _, match := range tablePattern.FindAllStringSubmatch(string(template), -1) {
+		templateTables = append(templateTables, match[1])
+	}
+	for _, match := range tablePattern.FindAllStringSubmatch(defaultSchema, -1) {
+		defaultTables = append(defaultTables, match[1])
+	}
+	assert.Equal(t, templateTables, requiredTables)
+	assert.Equal(t, templateTables, defaultTables)
+}
@@ -15,6 +15,8 @@
 package gocql
 
 import (
+	"context"
+
 	"github.com/gocql/gocql"
 
 	"github.com/uber/jaeger/pkg/cassandra"
@@ -87,6 +89,11 @@ func (q CQLQuery) PageSize(n int) cassandra.Query {
 	return WrapCQLQuery(q.query.PageSize(n))
 }
 
+// WithContext delegates to gocql.Query#WithContext and wraps the result as Query.
+func (q CQLQuery) WithContext(ctx context.Context) cassandra.Query {
+	return WrapCQLQuery(q.query.WithContext(ctx))
+}
+
 // ---
 
 // CQLIterator is a wrapper around gocql.Iter.
@@ -15,6 +15,7 @@
 package mocks
 
 import cassandra "github.com/uber/jaeger/pkg/cassandra"
+import context "context"
 import mock "github.com/stretchr/testify/mock"
 
 // Query is an autogenerated mock type for the Query type
@@ -70,6 +71,22 @@ func (_m *Query) PageSize(n int) cassandra.Query {
 	return r0
 }
 
+// WithContext provides a mock function with given fields: _a0
+func (_m *Query) WithContext(_a0 context.Context) cassandra.Query {
+	ret := _m.Called(_a0)
+
+	var r0 cassandra.Query
+	if rf, ok := ret.Get(0).(func(context.Context) cassandra.Query); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(cassandra.Query)
+		}
+	}
+
+	return r0
+}
+
 // Exec provides a mock function with given fields:
 func (_m *Query) Exec() error {
 	ret := _m.Called()
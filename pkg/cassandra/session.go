@@ -14,6 +14,10 @@
 
 package cassandra
 
+import (
+	"context"
+)
+
 // Consistency is Cassandra's consistency level for queries.
 type Consistency uint16
 
@@ -63,6 +67,7 @@ type Query interface {
 	Bind(v ...interface{}) Query
 	Consistency(level Consistency) Query
 	PageSize(int) Query
+	WithContext(context.Context) Query
 }
 
 // Iterator is an abstraction of gocql.Iter
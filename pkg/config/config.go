@@ -16,15 +16,22 @@ package config
 
 import (
 	"flag"
+	"os"
+	"sort"
 	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
+// EnvPrefix is the prefix of the environmental variables that override flags, e.g. JAEGER_SPAN_STORAGE_TYPE
+const EnvPrefix = "JAEGER"
+
+var envKeyReplacer = strings.NewReplacer("-", "_", ".", "_")
+
 // Viperize creates new Viper and command add passes flags to command
 // Viper is initialized with flags from command and configured to accept flags as environmental variables.
-// Characters `.-` in environmental variables are changed to `_`
+// Environmental variables are prefixed with EnvPrefix and characters `.-` in them are changed to `_`
 func Viperize(inits ...func(*flag.FlagSet)) (*viper.Viper, *cobra.Command) {
 	return AddFlags(viper.New(), &cobra.Command{}, inits...)
 }
@@ -39,10 +46,44 @@ func AddFlags(v *viper.Viper, command *cobra.Command, inits ...func(*flag.FlagSe
 
 	configureViper(v)
 	v.BindPFlags(command.Flags())
+	bindDeprecatedEnv(v)
 	return v, command
 }
 
+// DeprecatedEnvVars returns the environmental variables without EnvPrefix which override flags of viper
+// because the variable with the prefix is not set, e.g. SPAN_STORAGE_TYPE. They will stop being read
+// in the next release.
+func DeprecatedEnvVars(v *viper.Viper) []string {
+	var envVars []string
+	for _, envVar := range deprecatedEnvVars(v) {
+		envVars = append(envVars, envVar)
+	}
+	sort.Strings(envVars)
+	return envVars
+}
+
+// deprecatedEnvVars maps the keys of viper to their set environmental variables without EnvPrefix.
+func deprecatedEnvVars(v *viper.Viper) map[string]string {
+	envVars := make(map[string]string)
+	for _, key := range v.AllKeys() {
+		envVar := strings.ToUpper(envKeyReplacer.Replace(key))
+		if os.Getenv(EnvPrefix+"_"+envVar) == "" && os.Getenv(envVar) != "" {
+			envVars[key] = envVar
+		}
+	}
+	return envVars
+}
+
 func configureViper(v *viper.Viper) {
+	v.SetEnvPrefix(EnvPrefix)
 	v.AutomaticEnv()
-	v.SetEnvKeyReplacer(strings.NewReplacer("-", "_", ".", "_"))
+	v.SetEnvKeyReplacer(envKeyReplacer)
+}
+
+// bindDeprecatedEnv binds the keys of viper to their environmental variables without EnvPrefix
+// when only those are set.
+func bindDeprecatedEnv(v *viper.Viper) {
+	for key, envVar := range deprecatedEnvVars(v) {
+		v.BindEnv(key, envVar)
+	}
 }
@@ -52,7 +52,7 @@ func TestViperize(t *testing.T) {
 }
 
 func TestEnv(t *testing.T) {
-	envFlag := "jaeger.test-flag"
+	envFlag := "test-flag"
 	actualEnvFlag := "JAEGER_TEST_FLAG"
 
 	tempEnv := os.Getenv(actualEnvFlag)
@@ -67,3 +67,26 @@ func TestEnv(t *testing.T) {
 	v, _ := Viperize(addFlags)
 	assert.Equal(t, expectedString, v.GetString(envFlag))
 }
+
+func TestDeprecatedEnv(t *testing.T) {
+	envFlag := "test.deprecated-flag"
+	deprecatedEnvFlag := "TEST_DEPRECATED_FLAG"
+	actualEnvFlag := "JAEGER_TEST_DEPRECATED_FLAG"
+
+	addFlags := func(flagSet *flag.FlagSet) {
+		flagSet.String(envFlag, "", "")
+	}
+	defer os.Unsetenv(deprecatedEnvFlag)
+	os.Setenv(deprecatedEnvFlag, "deprecated")
+
+	v, _ := Viperize(addFlags)
+	assert.Equal(t, "deprecated", v.GetString(envFlag))
+	assert.Equal(t, []string{deprecatedEnvFlag}, DeprecatedEnvVars(v))
+
+	defer os.Unsetenv(actualEnvFlag)
+	os.Setenv(actualEnvFlag, "actual")
+
+	v, _ = Viperize(addFlags)
+	assert.Equal(t, "actual", v.GetString(envFlag), "the prefixed variable takes precedence")
+	assert.Empty(t, DeprecatedEnvVars(v))
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/spf13/viper"
+)
+
+// RedactedValue replaces the values of secret settings, such as passwords, in the output of /config
+const RedactedValue = "<redacted>"
+
+// secretKeyParts are the words that make a setting secret when they appear in the last segment of its key,
+// e.g. cassandra.password
+var secretKeyParts = []string{"password", "secret", "token"}
+
+// RegisterRoute registers a handler to /config on the given router. It returns the settings resolved by v from
+// flags, environment variables and config files as a JSON object keyed by flag name, with the secrets redacted.
+func RegisterRoute(router *mux.Router, v *viper.Viper) {
+	router.HandleFunc("/config", func(w http.ResponseWriter, _ *http.Request) {
+		body, err := json.Marshal(Settings(v))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	}).Methods(http.MethodGet)
+}
+
+// Settings returns the settings resolved by v, with the values of the secret settings, and the passwords in the URLs
+// of the other settings, replaced by RedactedValue
+func Settings(v *viper.Viper) map[string]interface{} {
+	settings := make(map[string]interface{})
+	for _, key := range v.AllKeys() {
+		if isSecret(key) {
+			if v.GetString(key) != "" {
+				settings[key] = RedactedValue
+			} else {
+				settings[key] = ""
+			}
+			continue
+		}
+		value := v.Get(key)
+		if str, ok := value.(string); ok {
+			value = redactURLPasswords(str)
+		}
+		settings[key] = value
+	}
+	return settings
+}
+
+// redactURLPasswords replaces the passwords in the userinfo of the URLs in a comma-separated value,
+// e.g. https://user:pass@es:9200 becomes https://user:<redacted>@es:9200
+func redactURLPasswords(value string) string {
+	if !strings.Contains(value, "@") {
+		return value
+	}
+	parts := strings.Split(value, ",")
+	for i, part := range parts {
+		u, err := url.Parse(strings.TrimSpace(part))
+		if err != nil || u.User == nil {
+			continue
+		}
+		if _, ok := u.User.Password(); !ok {
+			continue
+		}
+		scheme := strings.Index(part, "://") + len("://")
+		at := scheme + strings.Index(part[scheme:], "@")
+		colon := scheme + strings.Index(part[scheme:at], ":")
+		parts[i] = part[:colon+1] + RedactedValue + part[at:]
+	}
+	return strings.Join(parts, ",")
+}
+
+func isSecret(key string) bool {
+	name := strings.ToLower(key[strings.LastIndex(key, ".")+1:])
+	for _, part := range secretKeyParts {
+		if strings.Contains(name, part) {
+			return true
+		}
+	}
+	return false
+}
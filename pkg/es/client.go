@@ -24,9 +24,11 @@ import (
 type Client interface {
 	IndexExists(index string) IndicesExistsService
 	CreateIndex(index string) IndicesCreateService
+	CreateTemplate(id string) TemplateCreateService
 	Index() IndexService
 	Search(indices ...string) SearchService
 	MultiSearch() MultiSearchService
+	Bulk() BulkService
 }
 
 // IndicesExistsService is an abstraction for elastic.IndicesExistsService
@@ -40,11 +42,18 @@ type IndicesCreateService interface {
 	Do(ctx context.Context) (*elastic.IndicesCreateResult, error)
 }
 
+// TemplateCreateService is an abstraction for elastic.IndicesPutTemplateService
+type TemplateCreateService interface {
+	Body(mapping string) TemplateCreateService
+	Do(ctx context.Context) (*elastic.IndicesPutTemplateResponse, error)
+}
+
 // IndexService is an abstraction for elastic.IndexService
 type IndexService interface {
 	Index(index string) IndexService
 	Type(typ string) IndexService
 	Id(id string) IndexService
+	Routing(routing string) IndexService
 	BodyJson(body interface{}) IndexService
 	Do(ctx context.Context) (*elastic.IndexResponse, error)
 }
@@ -65,3 +74,9 @@ type MultiSearchService interface {
 	Index(indices ...string) MultiSearchService
 	Do(ctx context.Context) (*elastic.MultiSearchResult, error)
 }
+
+// BulkService is an abstraction for elastic.BulkService
+type BulkService interface {
+	Add(requests ...elastic.BulkableRequest) BulkService
+	Do(ctx context.Context) (*elastic.BulkResponse, error)
+}
@@ -15,6 +15,11 @@
 package config
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
 	"time"
 
 	"github.com/pkg/errors"
@@ -23,15 +28,40 @@ import (
 	"github.com/uber/jaeger/pkg/es"
 )
 
+const (
+	// IndexRotationDaily creates a new span index and service index every day
+	IndexRotationDaily = "daily"
+	// IndexRotationMonthly creates a new span index and service index every month
+	IndexRotationMonthly = "monthly"
+)
+
 // Configuration describes the configuration properties needed to connect to an ElasticSearch cluster
 type Configuration struct {
-	Servers     []string
-	Username    string
-	Password    string
-	Sniffer     bool          // https://github.com/olivere/elastic/wiki/Sniffing
-	MaxSpanAge  time.Duration `yaml:"max_span_age"` // configures the maximum lookback on span reads
-	NumShards   int64         `yaml:"shards"`
-	NumReplicas int64         `yaml:"replicas"`
+	Servers              []string
+	Username             string
+	Password             string
+	Sniffer              bool          // https://github.com/olivere/elastic/wiki/Sniffing
+	MaxSpanAge           time.Duration `yaml:"max_span_age"` // configures the maximum lookback on span reads
+	NumShards            int64         `yaml:"shards"`
+	NumReplicas          int64         `yaml:"replicas"`
+	IndexPrefix          string        `yaml:"index_prefix"`           // prepended to index names, e.g. prefix-jaeger-span-2017-01-02
+	IndexRotation        string        `yaml:"index_rotation"`         // how often new indices are created, daily or monthly
+	CreateIndexTemplates bool          `yaml:"create_index_templates"` // install index templates instead of creating every index
+	BulkWorkers          int           `yaml:"bulk_workers"`           // number of workers sending bulk requests, 0 writes one span at a time
+	BulkActions          int           `yaml:"bulk_actions"`           // number of spans that triggers a bulk request
+	BulkSize             int           `yaml:"bulk_size"`              // size in bytes of the spans that triggers a bulk request
+	BulkFlushInterval    time.Duration `yaml:"bulk_flush_interval"`    // time after which a bulk request is sent regardless of its size
+	UseTraceIDRouting    bool          `yaml:"use_traceid_routing"`    // route spans to shards by trace ID, keeping the spans of a trace on one shard
+	TLS                  TLS           `yaml:"tls"`
+}
+
+// TLS holds the certificates used to connect to an ElasticSearch cluster over TLS
+type TLS struct {
+	Enabled        bool   `yaml:"enabled"`
+	CaPath         string `yaml:"ca_path"`
+	CertPath       string `yaml:"cert_path"`
+	KeyPath        string `yaml:"key_path"`
+	SkipHostVerify bool   `yaml:"skip_host_verify"`
 }
 
 // ClientBuilder creates new es.Client
@@ -40,6 +70,14 @@ type ClientBuilder interface {
 	GetNumShards() int64
 	GetNumReplicas() int64
 	GetMaxSpanAge() time.Duration
+	GetIndexPrefix() string
+	GetIndexRotation() string
+	GetCreateIndexTemplates() bool
+	GetBulkWorkers() int
+	GetBulkActions() int
+	GetBulkSize() int
+	GetBulkFlushInterval() time.Duration
+	GetUseTraceIDRouting() bool
 }
 
 // NewClient creates a new ElasticSearch client
@@ -47,7 +85,17 @@ func (c *Configuration) NewClient() (es.Client, error) {
 	if len(c.Servers) < 1 {
 		return nil, errors.New("No servers specified")
 	}
-	rawClient, err := elastic.NewClient(c.GetConfigs()...)
+	options := c.GetConfigs()
+	if c.TLS.Enabled {
+		tlsConfig, err := c.TLS.Config()
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, elastic.SetHttpClient(&http.Client{
+			Transport: &http.Transport{Proxy: http.ProxyFromEnvironment, TLSClientConfig: tlsConfig},
+		}))
+	}
+	rawClient, err := elastic.NewClient(options...)
 	if err != nil {
 		return nil, err
 	}
@@ -74,6 +122,33 @@ func (c *Configuration) ApplyDefaults(source *Configuration) {
 	if c.NumReplicas == 0 {
 		c.NumReplicas = source.NumReplicas
 	}
+	if c.IndexPrefix == "" {
+		c.IndexPrefix = source.IndexPrefix
+	}
+	if c.IndexRotation == "" {
+		c.IndexRotation = source.IndexRotation
+	}
+	if c.CreateIndexTemplates == false {
+		c.CreateIndexTemplates = source.CreateIndexTemplates
+	}
+	if c.BulkWorkers == 0 {
+		c.BulkWorkers = source.BulkWorkers
+	}
+	if c.BulkActions == 0 {
+		c.BulkActions = source.BulkActions
+	}
+	if c.BulkSize == 0 {
+		c.BulkSize = source.BulkSize
+	}
+	if c.BulkFlushInterval == 0 {
+		c.BulkFlushInterval = source.BulkFlushInterval
+	}
+	if c.UseTraceIDRouting == false {
+		c.UseTraceIDRouting = source.UseTraceIDRouting
+	}
+	if !c.TLS.Enabled {
+		c.TLS = source.TLS
+	}
 }
 
 // GetNumShards returns number of shards from Configuration
@@ -91,11 +166,85 @@ func (c *Configuration) GetMaxSpanAge() time.Duration {
 	return c.MaxSpanAge
 }
 
+// GetIndexPrefix returns the index prefix from Configuration
+func (c *Configuration) GetIndexPrefix() string {
+	return c.IndexPrefix
+}
+
+// GetIndexRotation returns the index rotation from Configuration
+func (c *Configuration) GetIndexRotation() string {
+	return c.IndexRotation
+}
+
+// GetCreateIndexTemplates returns whether to create index templates from Configuration
+func (c *Configuration) GetCreateIndexTemplates() bool {
+	return c.CreateIndexTemplates
+}
+
+// GetBulkWorkers returns the number of bulk workers from Configuration
+func (c *Configuration) GetBulkWorkers() int {
+	return c.BulkWorkers
+}
+
+// GetBulkActions returns the number of spans per bulk request from Configuration
+func (c *Configuration) GetBulkActions() int {
+	return c.BulkActions
+}
+
+// GetBulkSize returns the size in bytes of a bulk request from Configuration
+func (c *Configuration) GetBulkSize() int {
+	return c.BulkSize
+}
+
+// GetBulkFlushInterval returns the bulk flush interval from Configuration
+func (c *Configuration) GetBulkFlushInterval() time.Duration {
+	return c.BulkFlushInterval
+}
+
+// GetUseTraceIDRouting returns whether to route spans to shards by trace ID from Configuration
+func (c *Configuration) GetUseTraceIDRouting() bool {
+	return c.UseTraceIDRouting
+}
+
 // GetConfigs wraps the configs to feed to the ElasticSearch client init
 func (c *Configuration) GetConfigs() []elastic.ClientOptionFunc {
-	options := make([]elastic.ClientOptionFunc, 3)
-	options[0] = elastic.SetURL(c.Servers...)
-	options[1] = elastic.SetBasicAuth(c.Username, c.Password)
-	options[2] = elastic.SetSniff(c.Sniffer)
+	options := []elastic.ClientOptionFunc{
+		elastic.SetURL(c.Servers...),
+		elastic.SetBasicAuth(c.Username, c.Password),
+		elastic.SetSniff(c.Sniffer),
+	}
+	if c.TLS.Enabled {
+		// the nodes found by sniffing are connected to with this scheme
+		options = append(options, elastic.SetScheme("https"))
+	}
 	return options
 }
+
+// Config creates the tls.Config used to connect to the ElasticSearch servers, it verifies their
+// certificates with the CA at CaPath, or the system's CAs if it is not set, and presents the client
+// certificate at CertPath if it is set.
+func (t TLS) Config() (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: t.SkipHostVerify,
+	}
+	if t.CaPath != "" {
+		caPEM, err := ioutil.ReadFile(t.CaPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load ElasticSearch TLS CA: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("no certificates found in ElasticSearch TLS CA %s", t.CaPath)
+		}
+		config.RootCAs = pool
+	}
+	if t.CertPath != "" || t.KeyPath != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertPath, t.KeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load ElasticSearch TLS client certificate: %v", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
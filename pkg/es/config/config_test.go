@@ -0,0 +1,88 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTLSConfig(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "es-tls")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	caPath := filepath.Join(dir, "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	require.NoError(t, ioutil.WriteFile(caPath, caPEM, 0600))
+
+	get := func(cfg TLS) error {
+		tlsConfig, err := cfg.Config()
+		require.NoError(t, err)
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+		res, err := client.Get(server.URL)
+		if err == nil {
+			res.Body.Close()
+		}
+		return err
+	}
+	assert.NoError(t, get(TLS{Enabled: true, CaPath: caPath}))
+	assert.Error(t, get(TLS{Enabled: true}), "the server certificate is not signed by a system CA")
+	assert.NoError(t, get(TLS{Enabled: true, SkipHostVerify: true}))
+}
+
+func TestTLSConfigErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "es-tls")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	notPEM := filepath.Join(dir, "not.pem")
+	require.NoError(t, ioutil.WriteFile(notPEM, []byte("not a certificate"), 0600))
+
+	testCases := []struct {
+		cfg TLS
+		err string
+	}{
+		{cfg: TLS{CaPath: filepath.Join(dir, "missing.pem")}, err: "failed to load ElasticSearch TLS CA"},
+		{cfg: TLS{CaPath: notPEM}, err: "no certificates found in ElasticSearch TLS CA"},
+		{cfg: TLS{CertPath: notPEM}, err: "failed to load ElasticSearch TLS client certificate"},
+	}
+	for _, testCase := range testCases {
+		_, err := testCase.cfg.Config()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), testCase.err)
+	}
+}
+
+func TestApplyDefaultsTLS(t *testing.T) {
+	source := &Configuration{TLS: TLS{Enabled: true, CaPath: "/etc/es/ca.pem"}}
+
+	cfg := &Configuration{}
+	cfg.ApplyDefaults(source)
+	assert.Equal(t, source.TLS, cfg.TLS)
+
+	cfg = &Configuration{TLS: TLS{Enabled: true, SkipHostVerify: true}}
+	cfg.ApplyDefaults(source)
+	assert.Equal(t, TLS{Enabled: true, SkipHostVerify: true}, cfg.TLS)
+}
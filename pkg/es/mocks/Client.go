@@ -24,6 +24,22 @@ type Client struct {
 	mock.Mock
 }
 
+// Bulk provides a mock function with given fields:
+func (_m *Client) Bulk() es.BulkService {
+	ret := _m.Called()
+
+	var r0 es.BulkService
+	if rf, ok := ret.Get(0).(func() es.BulkService); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(es.BulkService)
+		}
+	}
+
+	return r0
+}
+
 // CreateIndex provides a mock function with given fields: index
 func (_m *Client) CreateIndex(index string) es.IndicesCreateService {
 	ret := _m.Called(index)
@@ -40,6 +56,22 @@ func (_m *Client) CreateIndex(index string) es.IndicesCreateService {
 	return r0
 }
 
+// CreateTemplate provides a mock function with given fields: id
+func (_m *Client) CreateTemplate(id string) es.TemplateCreateService {
+	ret := _m.Called(id)
+
+	var r0 es.TemplateCreateService
+	if rf, ok := ret.Get(0).(func(string) es.TemplateCreateService); ok {
+		r0 = rf(id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(es.TemplateCreateService)
+		}
+	}
+
+	return r0
+}
+
 // Index provides a mock function with given fields:
 func (_m *Client) Index() es.IndexService {
 	ret := _m.Called()
@@ -42,6 +42,11 @@ func (c ESClient) CreateIndex(index string) IndicesCreateService {
 	return WrapESIndicesCreateService(c.client.CreateIndex(index))
 }
 
+// CreateTemplate calls this function to internal client.
+func (c ESClient) CreateTemplate(id string) TemplateCreateService {
+	return WrapESTemplateCreateService(c.client.IndexPutTemplate(id))
+}
+
 // Index calls this function to internal client.
 func (c ESClient) Index() IndexService {
 	return WrapESIndexService(c.client.Index())
@@ -57,6 +62,11 @@ func (c ESClient) MultiSearch() MultiSearchService {
 	return WrapESMultiSearchService(c.client.MultiSearch())
 }
 
+// Bulk calls this function to internal client.
+func (c ESClient) Bulk() BulkService {
+	return WrapESBulkService(c.client.Bulk())
+}
+
 // ---
 
 // ESIndicesExistsService is a wrapper around elastic.IndicesExistsService
@@ -98,6 +108,28 @@ func (c ESIndicesCreateService) Do(ctx context.Context) (*elastic.IndicesCreateR
 
 // ---
 
+// ESTemplateCreateService is a wrapper around elastic.IndicesPutTemplateService
+type ESTemplateCreateService struct {
+	templateCreateService *elastic.IndicesPutTemplateService
+}
+
+// WrapESTemplateCreateService creates an ESTemplateCreateService out of *elastic.IndicesPutTemplateService.
+func WrapESTemplateCreateService(templateCreateService *elastic.IndicesPutTemplateService) ESTemplateCreateService {
+	return ESTemplateCreateService{templateCreateService: templateCreateService}
+}
+
+// Body calls this function to internal service.
+func (c ESTemplateCreateService) Body(mapping string) TemplateCreateService {
+	return WrapESTemplateCreateService(c.templateCreateService.BodyString(mapping))
+}
+
+// Do calls this function to internal service.
+func (c ESTemplateCreateService) Do(ctx context.Context) (*elastic.IndicesPutTemplateResponse, error) {
+	return c.templateCreateService.Do(ctx)
+}
+
+// ---
+
 // ESIndexService is a wrapper around elastic.ESIndexService
 type ESIndexService struct {
 	indexService *elastic.IndexService
@@ -123,6 +155,11 @@ func (i ESIndexService) Id(id string) IndexService {
 	return WrapESIndexService(i.indexService.Id(id))
 }
 
+// Routing calls this function to internal service.
+func (i ESIndexService) Routing(routing string) IndexService {
+	return WrapESIndexService(i.indexService.Routing(routing))
+}
+
 // BodyJson calls this function to internal service.
 func (i ESIndexService) BodyJson(body interface{}) IndexService {
 	return WrapESIndexService(i.indexService.BodyJson(body))
@@ -199,3 +236,25 @@ func (s ESMultiSearchService) Index(indices ...string) MultiSearchService {
 func (s ESMultiSearchService) Do(ctx context.Context) (*elastic.MultiSearchResult, error) {
 	return s.multiSearchService.Do(ctx)
 }
+
+// ---
+
+// ESBulkService is a wrapper around elastic.BulkService
+type ESBulkService struct {
+	bulkService *elastic.BulkService
+}
+
+// WrapESBulkService creates an ESBulkService out of *elastic.BulkService.
+func WrapESBulkService(bulkService *elastic.BulkService) ESBulkService {
+	return ESBulkService{bulkService: bulkService}
+}
+
+// Add calls this function to internal service.
+func (s ESBulkService) Add(requests ...elastic.BulkableRequest) BulkService {
+	return WrapESBulkService(s.bulkService.Add(requests...))
+}
+
+// Do calls this function to internal service.
+func (s ESBulkService) Do(ctx context.Context) (*elastic.BulkResponse, error) {
+	return s.bulkService.Do(ctx)
+}
@@ -0,0 +1,61 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gzipfilter
+
+import (
+	"bytes"
+	"compress/gzip"
+
+	"github.com/golang/snappy"
+)
+
+// snappyStreamHeader starts the bodies encoded in the snappy framing format, rather than as a single block
+var snappyStreamHeader = []byte("\xff\x06\x00\x00sNaPpY")
+
+// decoder decodes a request body encoded with a Content-Encoding, failing with errBodyTooLarge when the
+// decoded body is larger than a positive maxBytes
+type decoder func(body []byte, maxBytes int64) ([]byte, error)
+
+// decoders are the decoders of the supported Content-Encodings
+var decoders = map[string]decoder{
+	"gzip":   gunzip,
+	"x-gzip": gunzip,
+	"snappy": unsnappy,
+}
+
+func gunzip(body []byte, maxBytes int64) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	return readAtMost(gz, maxBytes)
+}
+
+// unsnappy decodes a single snappy block, or a stream in the snappy framing format
+func unsnappy(body []byte, maxBytes int64) ([]byte, error) {
+	if bytes.HasPrefix(body, snappyStreamHeader) {
+		return readAtMost(snappy.NewReader(bytes.NewReader(body)), maxBytes)
+	}
+	// the decoded length of a block is in its header, so it is checked before allocating it
+	n, err := snappy.DecodedLen(body)
+	if err != nil {
+		return nil, err
+	}
+	if maxBytes > 0 && int64(n) > maxBytes {
+		return nil, errBodyTooLarge
+	}
+	return snappy.Decode(nil, body)
+}
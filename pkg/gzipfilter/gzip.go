@@ -0,0 +1,112 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gzipfilter
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+const contentEncoding = "Content-Encoding"
+
+// errBodyTooLarge is returned when a request body, or its decoded content, is larger than the filter accepts
+var errBodyTooLarge = errors.New("request body too large")
+
+// NewGzipFilter returns an http.Handler that decompresses the request bodies encoded with one of the
+// supported Content-Encodings, gzip and snappy, before passing them on to h. Requests without a
+// Content-Encoding are passed through unchanged, and requests with an unsupported one are rejected
+// with 415 Unsupported Media Type.
+//
+// When maxBytes is positive, requests whose body or decoded body is larger than maxBytes are rejected
+// with 413 Request Entity Too Large without decoding more than maxBytes of it, so that a small
+// compressed body can not be inflated without bound before the handler limits its size.
+func NewGzipFilter(h http.Handler, maxBytes int64) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encodings := requestEncodings(r)
+		if len(encodings) == 0 {
+			r.Header.Del(contentEncoding)
+			h.ServeHTTP(w, r)
+			return
+		}
+		for _, encoding := range encodings {
+			if _, ok := decoders[encoding]; !ok {
+				http.Error(w, fmt.Sprintf("Unsupported Content-Encoding %q", encoding), http.StatusUnsupportedMediaType)
+				return
+			}
+		}
+		body, err := decompress(r, encodings, maxBytes)
+		if err == errBodyTooLarge {
+			http.Error(w, fmt.Sprintf("Unable to decompress request body: %v", err), http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Unable to decompress request body: %v", err), http.StatusBadRequest)
+			return
+		}
+		r.Header.Del(contentEncoding)
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		r.ContentLength = int64(len(body))
+		h.ServeHTTP(w, r)
+	})
+}
+
+// requestEncodings returns the encodings of the request body in the order they were applied, without identity
+func requestEncodings(r *http.Request) []string {
+	var encodings []string
+	for _, encoding := range strings.Split(r.Header.Get(contentEncoding), ",") {
+		encoding = strings.ToLower(strings.TrimSpace(encoding))
+		if encoding != "" && encoding != "identity" {
+			encodings = append(encodings, encoding)
+		}
+	}
+	return encodings
+}
+
+// decompress reads the whole body so that corrupt streams are rejected before reaching the handler.
+// The encodings are undone in the reverse order of the one they were applied in.
+func decompress(r *http.Request, encodings []string, maxBytes int64) ([]byte, error) {
+	defer r.Body.Close()
+	body, err := readAtMost(r.Body, maxBytes)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(encodings) - 1; i >= 0; i-- {
+		if body, err = decoders[encodings[i]](body, maxBytes); err != nil {
+			return nil, err
+		}
+	}
+	return body, nil
+}
+
+// readAtMost reads r until EOF, failing with errBodyTooLarge once more than maxBytes have been read.
+// It does not limit anything when maxBytes is not positive.
+func readAtMost(r io.Reader, maxBytes int64) ([]byte, error) {
+	if maxBytes <= 0 {
+		return ioutil.ReadAll(r)
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(r, maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > maxBytes {
+		return nil, errBodyTooLarge
+	}
+	return body, nil
+}
@@ -0,0 +1,172 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gzipfilter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func echoHandler(t *testing.T) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get(contentEncoding))
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		w.Write(body)
+	})
+}
+
+func gzipEncode(t *testing.T, b []byte) []byte {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, err := zw.Write(b)
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func snappyStreamEncode(t *testing.T, b []byte) []byte {
+	var buf bytes.Buffer
+	sw := snappy.NewBufferedWriter(&buf)
+	_, err := sw.Write(b)
+	require.NoError(t, err)
+	require.NoError(t, sw.Close())
+	return buf.Bytes()
+}
+
+func TestGzipFilter(t *testing.T) {
+	tests := []struct {
+		body       []byte
+		encoding   string
+		statusCode int
+		expected   string
+	}{
+		{
+			body:       []byte("plain"),
+			statusCode: http.StatusOK,
+			expected:   "plain",
+		},
+		{
+			body:       gzipEncode(t, []byte("compressed")),
+			encoding:   "gzip",
+			statusCode: http.StatusOK,
+			expected:   "compressed",
+		},
+		{
+			body:       []byte("not good"),
+			encoding:   "gzip",
+			statusCode: http.StatusBadRequest,
+			expected:   "Unable to decompress request body: unexpected EOF\n",
+		},
+		{
+			body:       gzipEncode(t, []byte("compressed"))[:15],
+			encoding:   "gzip",
+			statusCode: http.StatusBadRequest,
+			expected:   "Unable to decompress request body: unexpected EOF\n",
+		},
+		{
+			body:       gzipEncode(t, []byte("compressed")),
+			encoding:   "x-gzip",
+			statusCode: http.StatusOK,
+			expected:   "compressed",
+		},
+		{
+			body:       []byte("plain"),
+			encoding:   "identity",
+			statusCode: http.StatusOK,
+			expected:   "plain",
+		},
+		{
+			body:       snappy.Encode(nil, []byte("compressed")),
+			encoding:   "snappy",
+			statusCode: http.StatusOK,
+			expected:   "compressed",
+		},
+		{
+			body:       snappyStreamEncode(t, []byte("compressed")),
+			encoding:   "snappy",
+			statusCode: http.StatusOK,
+			expected:   "compressed",
+		},
+		{
+			body:       snappy.Encode(nil, gzipEncode(t, []byte("compressed"))),
+			encoding:   "gzip, Snappy",
+			statusCode: http.StatusOK,
+			expected:   "compressed",
+		},
+		{
+			body:       []byte("not good"),
+			encoding:   "snappy",
+			statusCode: http.StatusBadRequest,
+			expected:   "Unable to decompress request body: snappy: corrupt input\n",
+		},
+		{
+			body:       []byte("compressed"),
+			encoding:   "zstd",
+			statusCode: http.StatusUnsupportedMediaType,
+			expected:   "Unsupported Content-Encoding \"zstd\"\n",
+		},
+		{
+			body:       gzipEncode(t, []byte("compressed")),
+			encoding:   "gzip, br",
+			statusCode: http.StatusUnsupportedMediaType,
+			expected:   "Unsupported Content-Encoding \"br\"\n",
+		},
+	}
+	for _, test := range tests {
+		req, err := http.NewRequest(http.MethodPost, "/api/traces", bytes.NewReader(test.body))
+		require.NoError(t, err)
+		if test.encoding != "" {
+			req.Header.Set(contentEncoding, test.encoding)
+		}
+		res := httptest.NewRecorder()
+		NewGzipFilter(echoHandler(t), 0).ServeHTTP(res, req)
+		assert.Equal(t, test.statusCode, res.Code)
+		assert.Equal(t, test.expected, res.Body.String())
+	}
+}
+
+func TestGzipFilterMaxBytes(t *testing.T) {
+	bomb := bytes.Repeat([]byte{0}, 10<<20)
+	tests := []struct {
+		body       []byte
+		encoding   string
+		statusCode int
+	}{
+		{body: gzipEncode(t, []byte("compressed")), encoding: "gzip", statusCode: http.StatusOK},
+		{body: gzipEncode(t, bomb), encoding: "gzip", statusCode: http.StatusRequestEntityTooLarge},
+		{body: snappy.Encode(nil, bomb), encoding: "snappy", statusCode: http.StatusRequestEntityTooLarge},
+		{body: snappyStreamEncode(t, bomb), encoding: "snappy", statusCode: http.StatusRequestEntityTooLarge},
+		{body: snappy.Encode(nil, gzipEncode(t, bomb)), encoding: "gzip, snappy", statusCode: http.StatusRequestEntityTooLarge},
+		{body: bytes.Repeat([]byte("x"), 2048), encoding: "gzip", statusCode: http.StatusRequestEntityTooLarge},
+	}
+	for _, test := range tests {
+		require.True(t, len(test.body) < 1<<20, "the compressed body is small")
+		req, err := http.NewRequest(http.MethodPost, "/api/traces", bytes.NewReader(test.body))
+		require.NoError(t, err)
+		req.Header.Set(contentEncoding, test.encoding)
+		res := httptest.NewRecorder()
+		NewGzipFilter(echoHandler(t), 1024).ServeHTTP(res, req)
+		assert.Equal(t, test.statusCode, res.Code, test.encoding)
+	}
+}
@@ -18,25 +18,39 @@ import (
 	"net"
 	"net/http"
 	"strconv"
+	"sync"
+	"time"
 
 	"go.uber.org/zap"
 
 	"github.com/uber/jaeger/pkg/version"
 )
 
+// Probe checks whether a dependency of the service is available, returning an error if it is not
+type Probe func() error
+
 // State represents the current health check state
 type State struct {
+	sync.RWMutex
 	state   int
 	logger  *zap.Logger
 	onClose chan struct{}
+	// probesFailing is true when the probes made the state unavailable
+	probesFailing bool
 }
 
 // Serve requests on the specified port. The initial state is what's specified with the state parameter
 func Serve(state int, port int, logger *zap.Logger) (*State, error) {
+	return ServeWithBasePath("", state, port, logger)
+}
+
+// ServeWithBasePath serves requests on the specified port like Serve, with the health check and the
+// version under basePath
+func ServeWithBasePath(basePath string, state int, port int, logger *zap.Logger) (*State, error) {
 	hs, err := NewState(state, logger)
 	handler, err := NewHandler(hs)
 
-	s := &http.Server{Handler: handler}
+	s := &http.Server{Handler: WithBasePath(basePath, handler)}
 	portStr := ":" + strconv.Itoa(port)
 	l, err := net.Listen("tcp", portStr)
 	if err != nil {
@@ -52,7 +66,7 @@ func Serve(state int, port int, logger *zap.Logger) (*State, error) {
 
 // NewState creates a new state instance. The initial state is what's specified with the state parameter
 func NewState(state int, logger *zap.Logger) (*State, error) {
-	s := &State{state: state, logger: logger}
+	s := &State{state: state, logger: logger, onClose: make(chan struct{})}
 	return s, nil
 }
 
@@ -60,7 +74,7 @@ func NewState(state int, logger *zap.Logger) (*State, error) {
 func NewHandler(s *State) (http.Handler, error) {
 	mu := http.NewServeMux()
 	mu.HandleFunc("/", func(w http.ResponseWriter, _ *http.Request) {
-		w.WriteHeader(s.state)
+		w.WriteHeader(s.Get())
 		// this is written only for response with an entity, so, it won't be used for a 204 - No content
 		w.Write([]byte("Server not available"))
 	})
@@ -69,6 +83,15 @@ func NewHandler(s *State) (http.Handler, error) {
 	return mu, nil
 }
 
+// WithBasePath serves handler with the paths it handles under basePath, requests to other paths get a 404.
+// An empty basePath returns handler as is.
+func WithBasePath(basePath string, handler http.Handler) http.Handler {
+	if basePath == "" {
+		return handler
+	}
+	return http.StripPrefix(basePath, handler)
+}
+
 // ServeWithListener starts a new HTTP server on the given port and with the provided handler
 func ServeWithListener(l net.Listener, s *http.Server, logger *zap.Logger) (*http.Server, error) {
 	go func() {
@@ -87,11 +110,61 @@ func (s *State) Ready() {
 
 // Set a new HTTP status for the health check
 func (s *State) Set(state int) {
+	s.Lock()
+	defer s.Unlock()
+	s.set(state)
+}
+
+func (s *State) set(state int) {
 	s.state = state
+	s.probesFailing = false
 	s.logger.Info("Health Check state change", zap.Int("http-status", s.state))
 }
 
 // Get the current status code for this health check
 func (s *State) Get() int {
+	s.RLock()
+	defer s.RUnlock()
 	return s.state
 }
+
+// StartProbes evaluates the probes every interval until Close is called. While the state is ready
+// and any probe fails, the state becomes unavailable. It becomes ready again once all probes pass.
+func (s *State) StartProbes(interval time.Duration, probes ...Probe) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.evaluate(probes)
+			case <-s.onClose:
+				return
+			}
+		}
+	}()
+}
+
+// Close stops evaluating the probes
+func (s *State) Close() {
+	close(s.onClose)
+}
+
+func (s *State) evaluate(probes []Probe) {
+	var err error
+	for _, probe := range probes {
+		if err = probe(); err != nil {
+			break
+		}
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	if err != nil && s.state >= 200 && s.state < 300 {
+		s.logger.Error("Health Check probe failed", zap.Error(err))
+		s.set(http.StatusServiceUnavailable)
+		s.probesFailing = true
+	} else if err == nil && s.probesFailing {
+		s.set(http.StatusNoContent)
+	}
+}
@@ -15,10 +15,13 @@
 package healthcheck_test
 
 import (
+	"errors"
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -97,6 +100,33 @@ func TestHttpCall(t *testing.T) {
 	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
 }
 
+func TestHttpCallWithBasePath(t *testing.T) {
+	state, err := healthcheck.NewState(http.StatusServiceUnavailable, zap.NewNop())
+	require.NoError(t, err)
+	handler, err := healthcheck.NewHandler(state)
+	require.NoError(t, err)
+
+	server := httptest.NewServer(healthcheck.WithBasePath("/jaeger", handler))
+	defer server.Close()
+
+	state.Ready()
+
+	resp, err := http.Get(server.URL + "/jaeger/")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	resp, err = http.Get(server.URL + "/jaeger/version")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = http.Get(server.URL + "/")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
 func TestListenerClose(t *testing.T) {
 	logger, _ := zap.NewDevelopment()
 	state, err := healthcheck.NewState(http.StatusServiceUnavailable, logger)
@@ -114,3 +144,56 @@ func TestListenerClose(t *testing.T) {
 func TestServeHandler(t *testing.T) {
 	healthcheck.Serve(http.StatusServiceUnavailable, 0, zap.NewNop())
 }
+
+func waitForState(t *testing.T, s *healthcheck.State, expected int) {
+	for i := 0; i < 1000 && s.Get() != expected; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, expected, s.Get())
+}
+
+func TestProbes(t *testing.T) {
+	state, err := healthcheck.NewState(http.StatusServiceUnavailable, zap.NewNop())
+	require.NoError(t, err)
+	var healthy atomic.Value
+	healthy.Store(false)
+	probe := func() error {
+		if healthy.Load().(bool) {
+			return nil
+		}
+		return errors.New("storage is down")
+	}
+	okProbe := func() error { return nil }
+	state.StartProbes(time.Millisecond, okProbe, probe)
+	defer state.Close()
+
+	// failing probes do not make a service that is still starting ready
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, http.StatusServiceUnavailable, state.Get())
+	healthy.Store(true)
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, http.StatusServiceUnavailable, state.Get())
+
+	state.Ready()
+	healthy.Store(false)
+	waitForState(t, state, http.StatusServiceUnavailable)
+	healthy.Store(true)
+	waitForState(t, state, http.StatusNoContent)
+}
+
+func TestProbesDoNotOverrideState(t *testing.T) {
+	state, err := healthcheck.NewState(http.StatusServiceUnavailable, zap.NewNop())
+	require.NoError(t, err)
+	var calls int32
+	state.StartProbes(time.Millisecond, func() error {
+		atomic.AddInt32(&calls, 1)
+		return nil
+	})
+	defer state.Close()
+
+	state.Set(http.StatusInternalServerError)
+	for i := 0; i < 1000 && atomic.LoadInt32(&calls) < 2; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, http.StatusInternalServerError, state.Get())
+}
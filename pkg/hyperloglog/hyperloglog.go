@@ -0,0 +1,126 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package hyperloglog estimates the number of distinct values in a stream in a fixed amount of memory.
+package hyperloglog
+
+import (
+	"hash/fnv"
+	"math"
+	"math/bits"
+)
+
+const (
+	// MinPrecision is the smallest precision of a Sketch, with a standard error of 26%
+	MinPrecision = 4
+	// MaxPrecision is the largest precision of a Sketch, with a standard error of 0.4%
+	MaxPrecision = 16
+)
+
+// Sketch is a HyperLogLog sketch. It uses 2^precision bytes and estimates the number of distinct values
+// added to it with a standard error of 1.04/sqrt(2^precision), e.g. 1.6% with a precision of 12.
+// A Sketch is not safe for concurrent use.
+type Sketch struct {
+	precision uint8
+	registers []uint8
+}
+
+// New creates an empty Sketch. A precision outside of [MinPrecision, MaxPrecision] is clamped to it.
+func New(precision uint8) *Sketch {
+	if precision < MinPrecision {
+		precision = MinPrecision
+	} else if precision > MaxPrecision {
+		precision = MaxPrecision
+	}
+	return &Sketch{
+		precision: precision,
+		registers: make([]uint8, 1<<precision),
+	}
+}
+
+// Add adds a value to the sketch
+func (s *Sketch) Add(value string) {
+	h := hash(value)
+	index := h >> (64 - s.precision)
+	// the bit set below the remaining bits bounds the rank when they are all zeros
+	rank := uint8(bits.LeadingZeros64(h<<s.precision|1<<(s.precision-1))) + 1
+	if rank > s.registers[index] {
+		s.registers[index] = rank
+	}
+}
+
+// Merge adds the values of other to the sketch, so that it estimates the number of distinct values
+// added to either. Both sketches must have the same precision, Merge does nothing otherwise.
+func (s *Sketch) Merge(other *Sketch) {
+	if other.precision != s.precision {
+		return
+	}
+	for i, rank := range other.registers {
+		if rank > s.registers[i] {
+			s.registers[i] = rank
+		}
+	}
+}
+
+// Reset empties the sketch
+func (s *Sketch) Reset() {
+	for i := range s.registers {
+		s.registers[i] = 0
+	}
+}
+
+// Estimate returns the estimated number of distinct values added to the sketch
+func (s *Sketch) Estimate() uint64 {
+	m := float64(len(s.registers))
+	var sum float64
+	var zeros int
+	for _, rank := range s.registers {
+		sum += 1 / float64(uint64(1)<<rank)
+		if rank == 0 {
+			zeros++
+		}
+	}
+	estimate := alpha(len(s.registers)) * m * m / sum
+	// linear counting is more accurate for small cardinalities
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return uint64(estimate + 0.5)
+}
+
+func alpha(m int) float64 {
+	switch m {
+	case 16:
+		return 0.673
+	case 32:
+		return 0.697
+	case 64:
+		return 0.709
+	}
+	return 0.7213 / (1 + 1.079/float64(m))
+}
+
+// hash returns the 64-bit FNV-1a hash of value with its bits mixed by the finalizer of SplitMix64,
+// since the high bits of FNV-1a are poorly distributed for short values
+func hash(value string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(value))
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
@@ -0,0 +1,66 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hyperloglog
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSketchEstimate(t *testing.T) {
+	for _, n := range []int{0, 1, 10, 100, 1000, 10000, 100000} {
+		s := New(12)
+		for i := 0; i < n; i++ {
+			s.Add("value-" + strconv.Itoa(i))
+			// duplicates do not count
+			s.Add("value-" + strconv.Itoa(i/2))
+		}
+		assert.InDelta(t, n, s.Estimate(), 0.05*float64(n)+1, "%d distinct values", n)
+	}
+}
+
+func TestSketchMerge(t *testing.T) {
+	a, b := New(12), New(12)
+	for i := 0; i < 6000; i++ {
+		a.Add(strconv.Itoa(i))
+	}
+	for i := 4000; i < 10000; i++ {
+		b.Add(strconv.Itoa(i))
+	}
+	a.Merge(b)
+	assert.InDelta(t, 10000, a.Estimate(), 500)
+
+	// sketches of different precisions do not merge
+	c := New(10)
+	c.Merge(a)
+	assert.EqualValues(t, 0, c.Estimate())
+}
+
+func TestSketchReset(t *testing.T) {
+	s := New(12)
+	s.Add("a")
+	s.Add("b")
+	assert.EqualValues(t, 2, s.Estimate())
+	s.Reset()
+	assert.EqualValues(t, 0, s.Estimate())
+}
+
+func TestNewClampsPrecision(t *testing.T) {
+	assert.Len(t, New(0).registers, 1<<MinPrecision)
+	assert.Len(t, New(30).registers, 1<<MaxPrecision)
+	assert.Len(t, New(12).registers, 4096)
+}
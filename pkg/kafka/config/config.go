@@ -0,0 +1,77 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"github.com/Shopify/sarama"
+	"github.com/pkg/errors"
+)
+
+const (
+	// EncodingJSON is used for spans encoded as JSON.
+	EncodingJSON = "json"
+	// EncodingThrift is used for spans encoded as a jaeger.thrift Batch.
+	EncodingThrift = "thrift"
+	// EncodingProtobuf is used for spans encoded as a Batch of the Protobuf Jaeger model.
+	EncodingProtobuf = "protobuf"
+)
+
+// Configuration describes the configuration properties needed to produce spans to a Kafka cluster
+type Configuration struct {
+	Brokers   []string
+	Topic     string
+	Encoding  string
+	TopicTag  string
+	MaxTopics int
+}
+
+// ProducerBuilder creates new sarama.AsyncProducer
+type ProducerBuilder interface {
+	NewProducer() (sarama.AsyncProducer, error)
+	GetTopic() string
+	GetEncoding() string
+	GetTopicTag() string
+	GetMaxTopics() int
+}
+
+// NewProducer creates a new asynchronous Kafka producer
+func (c *Configuration) NewProducer() (sarama.AsyncProducer, error) {
+	if len(c.Brokers) < 1 {
+		return nil, errors.New("No brokers specified")
+	}
+	saramaConfig := sarama.NewConfig()
+	saramaConfig.Producer.Return.Successes = true
+	return sarama.NewAsyncProducer(c.Brokers, saramaConfig)
+}
+
+// GetTopic returns the topic spans are produced to
+func (c *Configuration) GetTopic() string {
+	return c.Topic
+}
+
+// GetEncoding returns the encoding used for spans written to Kafka
+func (c *Configuration) GetEncoding() string {
+	return c.Encoding
+}
+
+// GetTopicTag returns the span tag whose value picks the topic of a span, if any
+func (c *Configuration) GetTopicTag() string {
+	return c.TopicTag
+}
+
+// GetMaxTopics returns the maximum number of topics that spans are routed to by their topic tag
+func (c *Configuration) GetMaxTopics() int {
+	return c.MaxTopics
+}
@@ -0,0 +1,76 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loglevel
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest"
+)
+
+func request(t *testing.T, method, url, body string) (int, string) {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, strings.TrimSpace(string(respBody))
+}
+
+func TestRegisterRoute(t *testing.T) {
+	level := zap.NewAtomicLevelAt(zapcore.InfoLevel)
+	buf := &zaptest.Buffer{}
+	logger := zap.New(zapcore.NewCore(zapcore.NewJSONEncoder(zapcore.EncoderConfig{MessageKey: "msg"}), buf, level))
+
+	r := mux.NewRouter()
+	RegisterRoute(r, level)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	status, body := request(t, http.MethodGet, server.URL+"/log-level", "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, `{"level":"info"}`, body)
+	logger.Debug("hidden")
+
+	status, body = request(t, http.MethodPut, server.URL+"/log-level", `{"level":"debug"}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, `{"level":"debug"}`, body)
+	logger.Debug("shown")
+
+	status, _ = request(t, http.MethodPut, server.URL+"/log-level", `{"level":"error"}`)
+	assert.Equal(t, http.StatusOK, status)
+	logger.Info("hidden")
+	logger.Error("shown too")
+
+	assert.Equal(t, []string{`{"msg":"shown"}`, `{"msg":"shown too"}`}, buf.Lines())
+
+	status, _ = request(t, http.MethodPut, server.URL+"/log-level", `{"level":"loud"}`)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, zapcore.ErrorLevel, level.Level())
+
+	status, _ = request(t, http.MethodPost, server.URL+"/log-level", `{"level":"debug"}`)
+	assert.Equal(t, http.StatusMethodNotAllowed, status)
+}
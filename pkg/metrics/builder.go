@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/gorilla/mux"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/spf13/viper"
 	xkit "github.com/uber/jaeger-lib/metrics/go-kit"
@@ -33,17 +34,21 @@ import (
 const (
 	metricsBackend        = "metrics-backend"
 	metricsHTTPRoute      = "metrics-http-route"
+	metricsExpvarBuckets  = "metrics-expvar-buckets"
 	defaultMetricsBackend = "expvar"
-	defaultMetricsRoute   = "/debug/vars"
+	defaultExpvarRoute    = "/debug/vars"
+	defaultPromRoute      = "/metrics"
+	defaultExpvarBuckets  = 10
 )
 
 var errUnknownBackend = errors.New("unknown metrics backend specified")
 
 // Builder provides command line options to configure metrics backend used by Jaeger executables.
 type Builder struct {
-	Backend   string
-	HTTPRoute string // endpoint name to expose metrics, e.g. for scraping
-	handler   http.Handler
+	Backend       string
+	HTTPRoute     string // endpoint name to expose metrics, e.g. for scraping, the backend's default route when empty
+	ExpvarBuckets int    // number of histogram bins the expvar backend approximates timer quantiles with
+	handler       http.Handler
 }
 
 // AddFlags adds flags for Builder.
@@ -55,28 +60,45 @@ func AddFlags(flags *flag.FlagSet) {
 			defaultMetricsBackend))
 	flags.String(
 		metricsHTTPRoute,
-		defaultMetricsRoute,
-		"Defines the route of HTTP endpoint for metrics backends that support scraping")
+		"",
+		fmt.Sprintf("Defines the route of HTTP endpoint for metrics backends that support scraping (default %s for expvar and %s for prometheus)",
+			defaultExpvarRoute, defaultPromRoute))
+	flags.Int(
+		metricsExpvarBuckets,
+		defaultExpvarBuckets,
+		"Defines the number of histogram bins that the expvar metrics backend approximates the p50, p90, p95 and p99 quantiles of timers with, more bins are more precise")
 }
 
 // InitFromViper initializes Builder with properties retrieved from Viper.
 func (b *Builder) InitFromViper(v *viper.Viper) {
 	b.Backend = v.GetString(metricsBackend)
 	b.HTTPRoute = v.GetString(metricsHTTPRoute)
+	b.ExpvarBuckets = v.GetInt(metricsExpvarBuckets)
 }
 
 // CreateMetricsFactory creates a metrics factory based on the configured type of the backend.
 // If the metrics backend supports HTTP endpoint for scraping, it is stored in the builder and
-// can be later added by RegisterHandler function.
+// can be later added by RegisterHandler function. Unless HTTPRoute is set, the endpoint is served at
+// /metrics for prometheus and at /debug/vars for expvar.
 func (b *Builder) CreateMetricsFactory(namespace string) (metrics.Factory, error) {
 	if b.Backend == "prometheus" {
 		metricsFactory := xkit.Wrap(namespace, kitprom.NewFactory("", "", nil))
 		b.handler = promhttp.Handler()
+		if b.HTTPRoute == "" {
+			b.HTTPRoute = defaultPromRoute
+		}
 		return metricsFactory, nil
 	}
 	if b.Backend == "expvar" {
-		metricsFactory := xkit.Wrap(namespace, kitexpvar.NewFactory(10))
+		buckets := b.ExpvarBuckets
+		if buckets <= 0 {
+			buckets = defaultExpvarBuckets
+		}
+		metricsFactory := xkit.Wrap(namespace, kitexpvar.NewFactory(buckets))
 		b.handler = expvar.Handler()
+		if b.HTTPRoute == "" {
+			b.HTTPRoute = defaultExpvarRoute
+		}
 		return metricsFactory, nil
 	}
 	if b.Backend == "none" || b.Backend == "" {
@@ -91,3 +113,10 @@ func (b *Builder) RegisterHandler(mux *http.ServeMux) {
 		mux.Handle(b.HTTPRoute, b.handler)
 	}
 }
+
+// RegisterRoute adds an endpoint to the router if the metrics backend supports it.
+func (b *Builder) RegisterRoute(router *mux.Router) {
+	if b.handler != nil && b.HTTPRoute != "" {
+		router.Handle(b.HTTPRoute, b.handler).Methods(http.MethodGet)
+	}
+}
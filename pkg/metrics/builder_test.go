@@ -15,10 +15,16 @@
 package metrics
 
 import (
+	"expvar"
 	"flag"
+	"fmt"
+	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/gorilla/mux"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
@@ -36,6 +42,7 @@ func TestAddFlags(t *testing.T) {
 	command.ParseFlags([]string{
 		"--metrics-backend=foo",
 		"--metrics-http-route=bar",
+		"--metrics-expvar-buckets=20",
 	})
 
 	b := &Builder{}
@@ -43,6 +50,41 @@ func TestAddFlags(t *testing.T) {
 
 	assert.Equal(t, "foo", b.Backend)
 	assert.Equal(t, "bar", b.HTTPRoute)
+	assert.Equal(t, 20, b.ExpvarBuckets)
+}
+
+func TestExpvarBuckets(t *testing.T) {
+	// a timer is published as its p50, p90, p95 and p99 quantiles, approximated by a histogram
+	// with as many bins as there are buckets
+	testCases := []struct {
+		buckets  string
+		p50, p99 float64
+	}{
+		{buckets: "10", p50: 1, p99: 3},
+		// both observations are merged into one bin, so all quantiles are their average
+		{buckets: "1", p50: 2, p99: 2},
+	}
+	for i, testCase := range testCases {
+		v := viper.New()
+		command := cobra.Command{}
+		flags := &flag.FlagSet{}
+		AddFlags(flags)
+		command.PersistentFlags().AddGoFlagSet(flags)
+		v.BindPFlags(command.PersistentFlags())
+		command.ParseFlags([]string{"--metrics-expvar-buckets=" + testCase.buckets})
+
+		b := &Builder{}
+		b.InitFromViper(v)
+		namespace := fmt.Sprintf("expvar_buckets_test_%d", i)
+		mf, err := b.CreateMetricsFactory(namespace)
+		require.NoError(t, err)
+		timer := mf.Timer("latency", nil)
+		timer.Record(time.Second)
+		timer.Record(3 * time.Second)
+
+		assert.Equal(t, testCase.p50, expvar.Get(namespace+".latency.p50").(*expvar.Float).Value(), testCase.buckets)
+		assert.Equal(t, testCase.p99, expvar.Get(namespace+".latency.p99").(*expvar.Float).Value(), testCase.buckets)
+	}
 }
 
 func TestBuilder(t *testing.T) {
@@ -95,3 +137,42 @@ func TestBuilder(t *testing.T) {
 		}
 	}
 }
+
+func TestDefaultHTTPRoute(t *testing.T) {
+	for backend, route := range map[string]string{"expvar": "/debug/vars", "prometheus": "/metrics"} {
+		v := viper.New()
+		command := cobra.Command{}
+		flags := &flag.FlagSet{}
+		AddFlags(flags)
+		command.PersistentFlags().AddGoFlagSet(flags)
+		v.BindPFlags(command.PersistentFlags())
+		command.ParseFlags([]string{"--metrics-backend=" + backend})
+
+		b := &Builder{}
+		b.InitFromViper(v)
+		_, err := b.CreateMetricsFactory("default_route_" + backend)
+		require.NoError(t, err)
+		assert.Equal(t, route, b.HTTPRoute, backend)
+	}
+}
+
+func TestRegisterRoute(t *testing.T) {
+	// the prometheus backend is scraped at /metrics unless another route is set
+	b := &Builder{Backend: "prometheus"}
+	mf, err := b.CreateMetricsFactory("foo")
+	require.NoError(t, err)
+	mf.Counter("scrape_test_counter", nil).Inc(1)
+
+	router := mux.NewRouter()
+	b.RegisterRoute(router)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	res, err := http.Get(server.URL + "/metrics")
+	require.NoError(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	body, err := ioutil.ReadAll(res.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "scrape_test_counter")
+}
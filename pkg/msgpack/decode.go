@@ -0,0 +1,224 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package msgpack decodes MessagePack documents into the same generic values that encoding/json
+// decodes JSON documents into.
+package msgpack
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// maxDepth is how deeply arrays and maps may be nested, so that a malicious document cannot exhaust the stack
+const maxDepth = 100
+
+var (
+	errUnexpectedEnd = errors.New("msgpack: unexpected end of data")
+	errTooDeep       = errors.New("msgpack: document is nested too deeply")
+	errTrailingData  = errors.New("msgpack: trailing data after the document")
+)
+
+// Unmarshal decodes the MessagePack document in data, which must not be followed by more data. Maps are
+// decoded into map[string]interface{} and must have string keys, arrays into []interface{}, strings and
+// binary data into string, integers into int64, or uint64 when they do not fit, and floats into float64.
+// Extension types are not supported.
+func Unmarshal(data []byte) (interface{}, error) {
+	d := &decoder{data: data}
+	v, err := d.value(0)
+	if err != nil {
+		return nil, err
+	}
+	if d.pos != len(d.data) {
+		return nil, errTrailingData
+	}
+	return v, nil
+}
+
+type decoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *decoder) value(depth int) (interface{}, error) {
+	if depth > maxDepth {
+		return nil, errTooDeep
+	}
+	b, err := d.next(1)
+	if err != nil {
+		return nil, err
+	}
+	c := b[0]
+	switch {
+	case c <= 0x7f:
+		return int64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c >= 0x80 && c <= 0x8f:
+		return d.mapValue(int(c&0x0f), depth)
+	case c >= 0x90 && c <= 0x9f:
+		return d.arrayValue(int(c&0x0f), depth)
+	case c >= 0xa0 && c <= 0xbf:
+		return d.str(int(c & 0x1f))
+	}
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xd9:
+		return d.strWithLength(1)
+	case 0xc5, 0xda:
+		return d.strWithLength(2)
+	case 0xc6, 0xdb:
+		return d.strWithLength(4)
+	case 0xca:
+		n, err := d.uint(4)
+		return float64(math.Float32frombits(uint32(n))), err
+	case 0xcb:
+		n, err := d.uint(8)
+		return math.Float64frombits(n), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		n, err := d.uint(1 << (c - 0xcc))
+		if n > math.MaxInt64 {
+			return n, err
+		}
+		return int64(n), err
+	case 0xd0:
+		n, err := d.uint(1)
+		return int64(int8(n)), err
+	case 0xd1:
+		n, err := d.uint(2)
+		return int64(int16(n)), err
+	case 0xd2:
+		n, err := d.uint(4)
+		return int64(int32(n)), err
+	case 0xd3:
+		n, err := d.uint(8)
+		return int64(n), err
+	case 0xdc:
+		n, err := d.uint(2)
+		if err != nil {
+			return nil, err
+		}
+		return d.arrayValue(int(n), depth)
+	case 0xdd:
+		n, err := d.uint(4)
+		if err != nil {
+			return nil, err
+		}
+		return d.arrayValue(int(n), depth)
+	case 0xde:
+		n, err := d.uint(2)
+		if err != nil {
+			return nil, err
+		}
+		return d.mapValue(int(n), depth)
+	case 0xdf:
+		n, err := d.uint(4)
+		if err != nil {
+			return nil, err
+		}
+		return d.mapValue(int(n), depth)
+	}
+	return nil, fmt.Errorf("msgpack: unsupported type 0x%02x", c)
+}
+
+// next returns the next n bytes and advances past them
+func (d *decoder) next(n int) ([]byte, error) {
+	if n < 0 || n > len(d.data)-d.pos {
+		return nil, errUnexpectedEnd
+	}
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+// uint reads a big endian unsigned integer of size bytes
+func (d *decoder) uint(size int) (uint64, error) {
+	b, err := d.next(size)
+	if err != nil {
+		return 0, err
+	}
+	switch size {
+	case 1:
+		return uint64(b[0]), nil
+	case 2:
+		return uint64(binary.BigEndian.Uint16(b)), nil
+	case 4:
+		return uint64(binary.BigEndian.Uint32(b)), nil
+	}
+	return binary.BigEndian.Uint64(b), nil
+}
+
+func (d *decoder) str(n int) (string, error) {
+	b, err := d.next(n)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// strWithLength reads a string or binary data preceded by its length in lengthSize bytes
+func (d *decoder) strWithLength(lengthSize int) (interface{}, error) {
+	n, err := d.uint(lengthSize)
+	if err != nil {
+		return nil, err
+	}
+	return d.str(int(n))
+}
+
+func (d *decoder) arrayValue(n int, depth int) (interface{}, error) {
+	// every element takes at least one byte, which bounds the allocation by the size of the data
+	if n > len(d.data)-d.pos {
+		return nil, errUnexpectedEnd
+	}
+	array := make([]interface{}, n)
+	for i := range array {
+		v, err := d.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		array[i] = v
+	}
+	return array, nil
+}
+
+func (d *decoder) mapValue(n int, depth int) (interface{}, error) {
+	// every key and value takes at least one byte each
+	if n > (len(d.data)-d.pos)/2 {
+		return nil, errUnexpectedEnd
+	}
+	m := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		k, err := d.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		key, ok := k.(string)
+		if !ok {
+			return nil, fmt.Errorf("msgpack: map key %v is not a string", k)
+		}
+		v, err := d.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		m[key] = v
+	}
+	return m, nil
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package msgpack
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnmarshal(t *testing.T) {
+	tests := []struct {
+		data     []byte
+		expected interface{}
+	}{
+		{data: []byte{0xc0}, expected: nil},
+		{data: []byte{0xc2}, expected: false},
+		{data: []byte{0xc3}, expected: true},
+		{data: []byte{0x7f}, expected: int64(127)},
+		{data: []byte{0xff}, expected: int64(-1)},
+		{data: []byte{0xcc, 0xff}, expected: int64(255)},
+		{data: []byte{0xcd, 0x01, 0x00}, expected: int64(256)},
+		{data: []byte{0xce, 0x00, 0x01, 0x00, 0x00}, expected: int64(65536)},
+		{data: []byte{0xcf, 0x7f, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, expected: int64(math.MaxInt64)},
+		{data: []byte{0xcf, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, expected: uint64(math.MaxUint64)},
+		{data: []byte{0xd0, 0x80}, expected: int64(-128)},
+		{data: []byte{0xd1, 0xff, 0x00}, expected: int64(-256)},
+		{data: []byte{0xd2, 0xff, 0xff, 0x00, 0x00}, expected: int64(-65536)},
+		{data: []byte{0xd3, 0x80, 0, 0, 0, 0, 0, 0, 0}, expected: int64(math.MinInt64)},
+		{data: []byte{0xca, 0x3f, 0xc0, 0x00, 0x00}, expected: 1.5},
+		{data: []byte{0xcb, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0}, expected: 1.5},
+		{data: []byte{0xa3, 'f', 'o', 'o'}, expected: "foo"},
+		{data: []byte{0xd9, 0x03, 'f', 'o', 'o'}, expected: "foo"},
+		{data: []byte{0xda, 0x00, 0x03, 'f', 'o', 'o'}, expected: "foo"},
+		{data: []byte{0xdb, 0x00, 0x00, 0x00, 0x03, 'f', 'o', 'o'}, expected: "foo"},
+		{data: []byte{0xc4, 0x02, 0x01, 0x02}, expected: "\x01\x02"},
+		{data: []byte{0x92, 0x01, 0xa1, 'a'}, expected: []interface{}{int64(1), "a"}},
+		{data: []byte{0xdc, 0x00, 0x01, 0xc3}, expected: []interface{}{true}},
+		{data: []byte{0xdd, 0x00, 0x00, 0x00, 0x00}, expected: []interface{}{}},
+		{data: []byte{0x81, 0xa1, 'a', 0x90}, expected: map[string]interface{}{"a": []interface{}{}}},
+		{data: []byte{0xde, 0x00, 0x01, 0xa1, 'a', 0xc0}, expected: map[string]interface{}{"a": nil}},
+		{data: []byte{0xdf, 0x00, 0x00, 0x00, 0x00}, expected: map[string]interface{}{}},
+	}
+	for _, test := range tests {
+		v, err := Unmarshal(test.data)
+		require.NoError(t, err, "%x", test.data)
+		assert.Equal(t, test.expected, v, "%x", test.data)
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	tests := []struct {
+		data []byte
+		err  string
+	}{
+		{data: []byte{}, err: errUnexpectedEnd.Error()},
+		{data: []byte{0xa3, 'f'}, err: errUnexpectedEnd.Error()},
+		{data: []byte{0xcd, 0x01}, err: errUnexpectedEnd.Error()},
+		{data: []byte{0xdd, 0xff, 0xff, 0xff, 0xff}, err: errUnexpectedEnd.Error()},
+		{data: []byte{0xdf, 0xff, 0xff, 0xff, 0xff}, err: errUnexpectedEnd.Error()},
+		{data: []byte{0x92, 0x01}, err: errUnexpectedEnd.Error()},
+		{data: []byte{0xc0, 0xc0}, err: errTrailingData.Error()},
+		{data: []byte{0x81, 0x01, 0x01}, err: "msgpack: map key 1 is not a string"},
+		{data: []byte{0xc1}, err: "msgpack: unsupported type 0xc1"},
+		{data: []byte{0xd4, 0x01, 0x01}, err: "msgpack: unsupported type 0xd4"},
+		{data: []byte(strings.Repeat("\x91", maxDepth+1) + "\xc0"), err: errTooDeep.Error()},
+	}
+	for _, test := range tests {
+		_, err := Unmarshal(test.data)
+		assert.EqualError(t, err, test.err, "%x", test.data)
+	}
+}
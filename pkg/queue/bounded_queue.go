@@ -90,6 +90,25 @@ func (q *BoundedQueue) Produce(item interface{}) bool {
 	}
 }
 
+// ProduceBlocking is like Produce, but when the queue is full it waits for a consumer to free up space
+// instead of dropping the item. Returns false only if the queue is stopped.
+func (q *BoundedQueue) ProduceBlocking(item interface{}) bool {
+	if atomic.LoadInt32(&q.stopped) != 0 {
+		q.onDroppedItem(item)
+		return false
+	}
+	select {
+	case q.items <- item:
+		atomic.AddInt32(&q.size, 1)
+		return true
+	case <-q.stopCh:
+		if q.onDroppedItem != nil {
+			q.onDroppedItem(item)
+		}
+		return false
+	}
+}
+
 // Stop stops all consumers, as well as the length reporter if started,
 // and releases the items channel. It blocks until all consumers have stopped.
 func (q *BoundedQueue) Stop() {
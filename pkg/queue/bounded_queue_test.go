@@ -154,3 +154,47 @@ func (s *consumerState) assertConsumed(expected map[string]bool) {
 	}
 	assert.Equal(s.t, expected, s.snapshot())
 }
+
+func TestBoundedQueueProduceBlocking(t *testing.T) {
+	var dropped int32
+	q := NewBoundedQueue(1, func(item interface{}) {
+		atomic.AddInt32(&dropped, 1)
+	})
+
+	var startLock sync.Mutex
+	startLock.Lock() // block consumers
+	consumerState := newConsumerState(t)
+	q.StartConsumers(1, func(item interface{}) {
+		consumerState.record(item.(string))
+		startLock.Lock()
+		startLock.Unlock()
+	})
+
+	assert.True(t, q.ProduceBlocking("a"))
+	consumerState.waitToConsumeOnce()
+	assert.True(t, q.ProduceBlocking("b"))
+
+	// the queue is full, so the next item must wait until the consumer is unblocked
+	produced := make(chan bool)
+	go func() {
+		produced <- q.ProduceBlocking("c")
+	}()
+	select {
+	case <-produced:
+		t.Fatal("expected ProduceBlocking to wait for the full queue")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	startLock.Unlock()
+	assert.True(t, <-produced)
+	consumerState.assertConsumed(map[string]bool{
+		"a": true,
+		"b": true,
+		"c": true,
+	})
+	assert.EqualValues(t, 0, atomic.LoadInt32(&dropped))
+
+	q.Stop()
+	assert.False(t, q.ProduceBlocking("x"), "cannot push to closed queue")
+	assert.EqualValues(t, 1, atomic.LoadInt32(&dropped))
+}
@@ -19,22 +19,63 @@ import (
 	"net/http"
 
 	"github.com/gorilla/handlers"
+	"github.com/uber/jaeger-lib/metrics"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 // zapRecoveryWrapper wraps a zap logger into a gorilla RecoveryLogger
 type zapRecoveryWrapper struct {
-	logger *zap.Logger
+	logger     *zap.Logger
+	panics     metrics.Counter
+	logStack   bool
+	stackLevel zapcore.Level
+}
+
+// Option is a function that sets some option on the handler returned by NewRecoveryHandler
+type Option func(z *zapRecoveryWrapper)
+
+// Options is a factory for all available Options
+var Options options
+
+type options struct{}
+
+// MetricsFactory creates an Option that counts the recovered panics as http.panics in metricsFactory
+func (options) MetricsFactory(metricsFactory metrics.Factory) Option {
+	return func(z *zapRecoveryWrapper) {
+		z.panics = metricsFactory.Counter("http.panics", nil)
+	}
+}
+
+// LogStack creates an Option that logs the recovered panics at level together with the stack
+// trace of the panicking goroutine, instead of at error level without it
+func (options) LogStack(level zapcore.Level) Option {
+	return func(z *zapRecoveryWrapper) {
+		z.logStack = true
+		z.stackLevel = level
+	}
 }
 
 // Println logs an error message with the given fields
 func (z zapRecoveryWrapper) Println(fields ...interface{}) {
+	z.panics.Inc(1)
 	// if you think i'm going to check the type of each of the fields and then logger with fields, you're crazy.
-	z.logger.Error(fmt.Sprintln(fields))
+	msg := fmt.Sprintln(fields)
+	if !z.logStack {
+		z.logger.Error(msg)
+		return
+	}
+	// Println is called while the panic is being recovered, so the stack still holds the panicking frames
+	if ce := z.logger.Check(z.stackLevel, msg); ce != nil {
+		ce.Write(zap.Stack("stack"))
+	}
 }
 
-// NewRecoveryHandler returns an http.Handler that recovers on panics
-func NewRecoveryHandler(logger *zap.Logger, printStack bool) func(h http.Handler) http.Handler {
-	zWrapper := zapRecoveryWrapper{logger}
+// NewRecoveryHandler returns an http.Handler that recovers on panics and responds with 500
+func NewRecoveryHandler(logger *zap.Logger, printStack bool, opts ...Option) func(h http.Handler) http.Handler {
+	zWrapper := zapRecoveryWrapper{logger: logger, panics: metrics.NullCounter}
+	for _, opt := range opts {
+		opt(&zWrapper)
+	}
 	return handlers.RecoveryHandler(handlers.RecoveryLogger(zWrapper), handlers.PrintRecoveryStack(printStack))
 }
@@ -20,6 +20,9 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 
 	"github.com/uber/jaeger/pkg/testutils"
 )
@@ -43,3 +46,43 @@ func TestNewRecoveryHandler(t *testing.T) {
 		"msg":   "[Unexpected error!]\n",
 	}, log.JSONLine(0))
 }
+
+func TestNewRecoveryHandlerWithOptions(t *testing.T) {
+	logger, log := testutils.NewLogger()
+	metricsFactory := metrics.NewLocalFactory(0)
+
+	handlerFunc := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		panic("Unexpected error!")
+	})
+
+	recovery := NewRecoveryHandler(logger, false, Options.MetricsFactory(metricsFactory), Options.LogStack(zapcore.WarnLevel))(handlerFunc)
+	req, err := http.NewRequest("GET", "/subdir/asdf", nil)
+	assert.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		res := httptest.NewRecorder()
+		recovery.ServeHTTP(res, req)
+		assert.Equal(t, http.StatusInternalServerError, res.Code)
+	}
+	counters, _ := metricsFactory.Snapshot()
+	assert.EqualValues(t, 2, counters["http.panics"])
+
+	line := log.JSONLine(0)
+	assert.Equal(t, "warn", line["level"])
+	assert.Equal(t, "[Unexpected error!]\n", line["msg"])
+	assert.Contains(t, line["stack"], "TestNewRecoveryHandlerWithOptions")
+}
+
+func TestNewRecoveryHandlerStackLevelDisabled(t *testing.T) {
+	handlerFunc := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		panic("Unexpected error!")
+	})
+
+	recovery := NewRecoveryHandler(zap.NewNop(), false, Options.LogStack(zapcore.DebugLevel))(handlerFunc)
+	req, err := http.NewRequest("GET", "/subdir/asdf", nil)
+	assert.NoError(t, err)
+
+	res := httptest.NewRecorder()
+	recovery.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusInternalServerError, res.Code)
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tlscfg
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/uber/jaeger/pkg/clock"
+)
+
+// DefaultReloadInterval is how often the certificate files are checked for changes by default
+const DefaultReloadInterval = 10 * time.Second
+
+// Options describes the certificates used by a TLS server
+type Options struct {
+	// CertPath is the path to the PEM encoded server certificate
+	CertPath string
+	// KeyPath is the path to the PEM encoded server private key
+	KeyPath string
+	// ClientCAPath is the path to the PEM encoded CA used to verify client certificates.
+	// When set, clients must present a certificate signed by this CA.
+	ClientCAPath string
+	// ReloadInterval is how often the certificate files are checked for changes, DefaultReloadInterval if not positive
+	ReloadInterval time.Duration
+	// Logger logs the certificates that fail to be reloaded, nothing is logged when it is nil
+	Logger *zap.Logger
+	// MetricsFactory counts the certificates that fail to be reloaded in tls.reload-failures, nothing is
+	// counted when it is nil
+	MetricsFactory metrics.Factory
+}
+
+// Enabled returns true if a server certificate is configured
+func (o Options) Enabled() bool {
+	return o.CertPath != "" || o.KeyPath != ""
+}
+
+// Config creates a tls.Config from the options. The certificate is reloaded when its files change,
+// the client CA is only loaded once.
+func (o Options) Config() (*tls.Config, error) {
+	if o.CertPath == "" || o.KeyPath == "" {
+		return nil, errors.New("both TLS certificate and key must be provided")
+	}
+	reloadInterval := o.ReloadInterval
+	if reloadInterval <= 0 {
+		reloadInterval = DefaultReloadInterval
+	}
+	logger := o.Logger
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	metricsFactory := o.MetricsFactory
+	if metricsFactory == nil {
+		metricsFactory = metrics.NullFactory
+	}
+	reloader, err := newCertReloader(o.CertPath, o.KeyPath, reloadInterval, clock.System, logger, metricsFactory)
+	if err != nil {
+		return nil, err
+	}
+	config := &tls.Config{
+		GetCertificate: reloader.GetCertificate,
+		MinVersion:     tls.VersionTLS12,
+	}
+	if o.ClientCAPath != "" {
+		caPEM, err := ioutil.ReadFile(o.ClientCAPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS client CA: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("no certificates found in TLS client CA %s", o.ClientCAPath)
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
+
+// Serve serves on listener with TLS if the options are enabled, and in plaintext otherwise
+func (o Options) Serve(server *http.Server, listener net.Listener) error {
+	if !o.Enabled() {
+		return server.Serve(listener)
+	}
+	config, err := o.Config()
+	if err != nil {
+		return err
+	}
+	server.TLSConfig = config
+	return server.ServeTLS(listener, "", "")
+}
@@ -0,0 +1,326 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tlscfg
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics"
+
+	"github.com/uber/jaeger/pkg/testutils"
+)
+
+type certFiles struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+// newCert creates a certificate valid for 127.0.0.1, self-signed if parent is nil
+func newCert(t *testing.T, parent *certFiles, isCA bool) *certFiles {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: "jaeger"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+	}
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return &certFiles{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+func writeFile(t *testing.T, dir, name string, data []byte) string {
+	path := filepath.Join(dir, name)
+	require.NoError(t, ioutil.WriteFile(path, data, 0600))
+	return path
+}
+
+// startServer serves with opts on a local port and returns the server and its URL
+func startServer(t *testing.T, opts Options) (*http.Server, string) {
+	_, err := opts.Config()
+	require.NoError(t, err)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})}
+	go opts.Serve(server, listener)
+	return server, "https://" + listener.Addr().String()
+}
+
+func TestTLSHandshake(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tlscfg")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	serverCert := newCert(t, nil, true)
+	opts := Options{
+		CertPath: writeFile(t, dir, "server.crt", serverCert.certPEM),
+		KeyPath:  writeFile(t, dir, "server.key", serverCert.keyPEM),
+	}
+	assert.True(t, opts.Enabled())
+	server, url := startServer(t, opts)
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(serverCert.cert)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+	res, err := client.Get(url)
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusAccepted, res.StatusCode)
+}
+
+func TestTLSClientCA(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tlscfg")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	serverCert := newCert(t, nil, true)
+	clientCA := newCert(t, nil, true)
+	clientCert := newCert(t, clientCA, false)
+	opts := Options{
+		CertPath:     writeFile(t, dir, "server.crt", serverCert.certPEM),
+		KeyPath:      writeFile(t, dir, "server.key", serverCert.keyPEM),
+		ClientCAPath: writeFile(t, dir, "ca.crt", clientCA.certPEM),
+	}
+	server, url := startServer(t, opts)
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(serverCert.cert)
+
+	noCertClient := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+	_, err = noCertClient.Get(url)
+	assert.Error(t, err, "clients without a certificate must be rejected")
+
+	keyPair, err := tls.X509KeyPair(clientCert.certPEM, clientCert.keyPEM)
+	require.NoError(t, err)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		RootCAs:      roots,
+		Certificates: []tls.Certificate{keyPair},
+	}}}
+	res, err := client.Get(url)
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusAccepted, res.StatusCode)
+}
+
+func TestTLSConfigErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tlscfg")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	serverCert := newCert(t, nil, true)
+	certPath := writeFile(t, dir, "server.crt", serverCert.certPEM)
+	keyPath := writeFile(t, dir, "server.key", serverCert.keyPEM)
+
+	assert.False(t, Options{}.Enabled())
+	testCases := []Options{
+		{CertPath: certPath},
+		{CertPath: certPath, KeyPath: filepath.Join(dir, "missing.key")},
+		{CertPath: certPath, KeyPath: keyPath, ClientCAPath: filepath.Join(dir, "missing.crt")},
+		{CertPath: certPath, KeyPath: keyPath, ClientCAPath: keyPath},
+	}
+	for _, opts := range testCases {
+		_, err := opts.Config()
+		assert.Error(t, err, "%+v", opts)
+	}
+}
+
+func TestServe(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tlscfg")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	serverCert := newCert(t, nil, true)
+	roots := x509.NewCertPool()
+	roots.AddCert(serverCert.cert)
+	testCases := []struct {
+		caption string
+		opts    Options
+		scheme  string
+	}{
+		{caption: "plaintext", scheme: "http"},
+		{
+			caption: "tls",
+			opts: Options{
+				CertPath: writeFile(t, dir, "server.crt", serverCert.certPEM),
+				KeyPath:  writeFile(t, dir, "server.key", serverCert.keyPEM),
+			},
+			scheme: "https",
+		},
+	}
+	for _, tc := range testCases {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusAccepted)
+		})}
+		go tc.opts.Serve(server, listener)
+
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+		res, err := client.Get(tc.scheme + "://" + listener.Addr().String())
+		require.NoError(t, err, tc.caption)
+		res.Body.Close()
+		assert.Equal(t, http.StatusAccepted, res.StatusCode, tc.caption)
+		server.Close()
+	}
+}
+
+func TestServeInvalidConfig(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	err = Options{CertPath: "cert.pem"}.Serve(&http.Server{}, listener)
+	assert.EqualError(t, err, "both TLS certificate and key must be provided")
+}
+
+func TestCertificateReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tlscfg")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	oldCert := newCert(t, nil, true)
+	opts := Options{
+		CertPath:       writeFile(t, dir, "server.crt", oldCert.certPEM),
+		KeyPath:        writeFile(t, dir, "server.key", oldCert.keyPEM),
+		ReloadInterval: time.Nanosecond,
+	}
+	server, url := startServer(t, opts)
+	defer server.Close()
+
+	newServerCert := newCert(t, nil, true)
+	roots := x509.NewCertPool()
+	roots.AddCert(oldCert.cert)
+	roots.AddCert(newServerCert.cert)
+	servedCert := func() *x509.Certificate {
+		// a new transport opens a new connection, so every request makes a handshake
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+		res, err := client.Get(url)
+		require.NoError(t, err)
+		res.Body.Close()
+		return res.TLS.PeerCertificates[0]
+	}
+	assert.Equal(t, oldCert.cert.SerialNumber, servedCert().SerialNumber)
+
+	// the modification times are moved forward, as the files may be rewritten within the resolution of the clock
+	modTime := time.Now().Add(time.Minute)
+	writeFile(t, dir, "server.crt", newServerCert.certPEM)
+	require.NoError(t, os.Chtimes(opts.CertPath, modTime, modTime))
+	assert.Equal(t, oldCert.cert.SerialNumber, servedCert().SerialNumber, "the old certificate is served until the key is replaced")
+
+	writeFile(t, dir, "server.key", newServerCert.keyPEM)
+	require.NoError(t, os.Chtimes(opts.KeyPath, modTime, modTime))
+	assert.Equal(t, newServerCert.cert.SerialNumber, servedCert().SerialNumber)
+
+	require.NoError(t, os.Remove(opts.CertPath))
+	assert.Equal(t, newServerCert.cert.SerialNumber, servedCert().SerialNumber, "the last certificate is served while the files are missing")
+}
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func TestCertReloaderCheckInterval(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tlscfg")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	oldCert := newCert(t, nil, true)
+	certPath := writeFile(t, dir, "server.crt", oldCert.certPEM)
+	keyPath := writeFile(t, dir, "server.key", oldCert.keyPEM)
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	logger, logBuf := testutils.NewLogger()
+	metricsFactory := metrics.NewLocalFactory(0)
+	reloader, err := newCertReloader(certPath, keyPath, time.Minute, clock, logger, metricsFactory)
+	require.NoError(t, err)
+	servedCert := func() []byte {
+		cert, err := reloader.GetCertificate(nil)
+		require.NoError(t, err)
+		return cert.Certificate[0]
+	}
+	failures := func() int64 {
+		counters, _ := metricsFactory.Snapshot()
+		return counters["tls.reload-failures"]
+	}
+
+	modTime := time.Now().Add(time.Minute)
+	writeFile(t, dir, "server.crt", []byte("not a certificate"))
+	require.NoError(t, os.Chtimes(certPath, modTime, modTime))
+	clock.now = clock.now.Add(30 * time.Second)
+	assert.Equal(t, oldCert.cert.Raw, servedCert(), "the files are not checked before the check interval is over")
+	assert.Equal(t, int64(0), failures())
+
+	clock.now = clock.now.Add(30 * time.Second)
+	assert.Equal(t, oldCert.cert.Raw, servedCert())
+	assert.Equal(t, int64(1), failures())
+	assert.Contains(t, logBuf.String(), "Failed to reload the TLS certificate")
+
+	clock.now = clock.now.Add(time.Minute)
+	assert.Equal(t, oldCert.cert.Raw, servedCert())
+	assert.Equal(t, int64(1), failures(), "the files are not loaded again until they are modified")
+
+	newServerCert := newCert(t, nil, true)
+	modTime = modTime.Add(time.Minute)
+	writeFile(t, dir, "server.crt", newServerCert.certPEM)
+	writeFile(t, dir, "server.key", newServerCert.keyPEM)
+	require.NoError(t, os.Chtimes(certPath, modTime, modTime))
+	require.NoError(t, os.Chtimes(keyPath, modTime, modTime))
+	clock.now = clock.now.Add(time.Minute)
+	assert.Equal(t, newServerCert.cert.Raw, servedCert())
+	assert.Equal(t, int64(1), failures())
+}
@@ -0,0 +1,135 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tlscfg
+
+import (
+	"crypto/tls"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/uber/jaeger/pkg/clock"
+)
+
+// certReloader serves the certificate of a pair of files and reloads it when the files change,
+// so that rotated certificates are picked up without restarting the server
+type certReloader struct {
+	// nextCheck is the time in unix nanoseconds after which the next handshake checks the files. It is
+	// the first field so that it is 64-bit aligned for the atomic operations on 386 and 32-bit ARM.
+	nextCheck int64
+
+	certPath      string
+	keyPath       string
+	checkInterval time.Duration
+	clock         clock.Clock
+	logger        *zap.Logger
+	failures      metrics.Counter
+
+	// cert holds the *tls.Certificate served to the handshakes, which read it without locking
+	cert atomic.Value
+
+	// lock is held while the files are checked, and guards the modification times
+	lock        sync.Mutex
+	certModTime time.Time
+	keyModTime  time.Time
+}
+
+func newCertReloader(
+	certPath, keyPath string,
+	checkInterval time.Duration,
+	clock clock.Clock,
+	logger *zap.Logger,
+	metricsFactory metrics.Factory,
+) (*certReloader, error) {
+	r := &certReloader{
+		certPath:      certPath,
+		keyPath:       keyPath,
+		checkInterval: checkInterval,
+		clock:         clock,
+		logger:        logger,
+		failures:      metricsFactory.Counter("tls.reload-failures", nil),
+	}
+	certInfo, keyInfo, err := r.stat()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.load(certInfo.ModTime(), keyInfo.ModTime()); err != nil {
+		return nil, err
+	}
+	r.nextCheck = clock.Now().Add(checkInterval).UnixNano()
+	return r, nil
+}
+
+// GetCertificate returns the certificate. At most once per check interval, the handshake checks whether
+// either file was modified since it was last loaded, and reloads the certificate first if so. While the files
+// cannot be loaded, e.g. if the certificate was replaced but not the key yet, the failure is logged and counted,
+// the previous certificate is returned and the files are loaded again once either of them is modified again.
+// It can be used as tls.Config.GetCertificate.
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	now := r.clock.Now().UnixNano()
+	nextCheck := atomic.LoadInt64(&r.nextCheck)
+	// only the handshake that moves the next check forward checks the files, the others do not wait for it
+	if now >= nextCheck && atomic.CompareAndSwapInt64(&r.nextCheck, nextCheck, now+int64(r.checkInterval)) {
+		r.check()
+	}
+	return r.cert.Load().(*tls.Certificate), nil
+}
+
+func (r *certReloader) check() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	certInfo, keyInfo, err := r.stat()
+	if err == nil && certInfo.ModTime().Equal(r.certModTime) && keyInfo.ModTime().Equal(r.keyModTime) {
+		return
+	}
+	if err == nil {
+		err = r.load(certInfo.ModTime(), keyInfo.ModTime())
+	}
+	if err != nil {
+		r.logger.Error("Failed to reload the TLS certificate, the previous certificate is still served",
+			zap.String("cert", r.certPath), zap.String("key", r.keyPath), zap.Error(err))
+		r.failures.Inc(1)
+	}
+}
+
+func (r *certReloader) stat() (os.FileInfo, os.FileInfo, error) {
+	certInfo, err := os.Stat(r.certPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load TLS certificate: %v", err)
+	}
+	keyInfo, err := os.Stat(r.keyPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load TLS certificate: %v", err)
+	}
+	return certInfo, keyInfo, nil
+}
+
+// load loads the certificate. The modification times are recorded even if it fails, so that a pair that
+// cannot be loaded is not loaded again until either file is modified.
+func (r *certReloader) load(certModTime, keyModTime time.Time) error {
+	r.certModTime = certModTime
+	r.keyModTime = keyModTime
+	cert, err := tls.LoadX509KeyPair(r.certPath, r.keyPath)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %v", err)
+	}
+	r.cert.Store(&cert)
+	return nil
+}
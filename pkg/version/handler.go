@@ -18,18 +18,29 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/gorilla/mux"
 	"go.uber.org/zap"
 )
 
 // RegisterHandler registers version handler to /version
 func RegisterHandler(mu *http.ServeMux, logger *zap.Logger) {
+	mu.HandleFunc("/version", newHandler(logger))
+}
+
+// RegisterRoute registers version handler to /version on the given router
+func RegisterRoute(router *mux.Router, logger *zap.Logger) {
+	router.HandleFunc("/version", newHandler(logger)).Methods(http.MethodGet)
+}
+
+func newHandler(logger *zap.Logger) http.HandlerFunc {
 	info := Get()
 	json, err := json.Marshal(info)
 	if err != nil {
 		logger.Fatal("Could not get Jaeger version", zap.Error(err))
 	}
-	mu.HandleFunc("/version", func(w http.ResponseWriter, _ *http.Request) {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(200)
 		w.Write(json)
-	})
+	}
 }
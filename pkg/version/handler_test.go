@@ -0,0 +1,64 @@
+// Copyright (c) 2017 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package version
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestRegisterRoute(t *testing.T) {
+	commitSHA, latestVersion, date = "deadbeef", "v1.2.3", "2017-11-01T10:00:00Z"
+	defer func() { commitSHA, latestVersion, date = "", "", "" }()
+
+	r := mux.NewRouter()
+	RegisterRoute(r, zap.NewNop())
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/version")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+	var info map[string]string
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&info))
+	assert.Equal(t, map[string]string{
+		"gitCommit":  "deadbeef",
+		"GitVersion": "v1.2.3",
+		"BuildDate":  "2017-11-01T10:00:00Z",
+	}, info)
+}
+
+func TestRegisterHandler(t *testing.T) {
+	mu := http.NewServeMux()
+	RegisterHandler(mu, zap.NewNop())
+
+	w := httptest.NewRecorder()
+	mu.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/version", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var info Info
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &info))
+	assert.Equal(t, Get(), info)
+}
@@ -15,6 +15,7 @@
 package main
 
 import (
+	"context"
 	"time"
 
 	"github.com/uber/jaeger-lib/metrics"
@@ -44,7 +45,7 @@ func main() {
 	}
 	spanStore := cSpanStore.NewSpanWriter(cqlSession, time.Hour*12, noScope, logger)
 	spanReader := cSpanStore.NewSpanReader(cqlSession, noScope, logger)
-	if err = spanStore.WriteSpan(getSomeSpan()); err != nil {
+	if err = spanStore.WriteSpan(context.Background(), getSomeSpan()); err != nil {
 		logger.Fatal("Failed to save", zap.Error(err))
 	} else {
 		logger.Info("Saved span", zap.String("spanID", getSomeSpan().SpanID.String()))
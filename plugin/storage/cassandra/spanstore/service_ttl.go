@@ -0,0 +1,51 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"time"
+)
+
+type serviceTTLsFile struct {
+	Services map[string]string `json:"services"`
+}
+
+// LoadServiceTTLs reads the TTL of the spans of each service from a JSON file of the form
+// {"services": {"service-name": "168h", ...}}, where TTLs are durations of at least a second.
+func LoadServiceTTLs(path string) (map[string]time.Duration, error) {
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file serviceTTLsFile
+	if err := json.Unmarshal(bytes, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse service TTL file %s: %v", path, err)
+	}
+	ttls := make(map[string]time.Duration, len(file.Services))
+	for service, value := range file.Services {
+		ttl, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TTL of service %s in %s: %v", service, path, err)
+		}
+		if ttl < time.Second {
+			return nil, fmt.Errorf("invalid TTL of service %s in %s: must be at least 1s, got %v", service, path, ttl)
+		}
+		ttls[service] = ttl
+	}
+	return ttls, nil
+}
@@ -0,0 +1,61 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadServiceTTLs(t *testing.T) {
+	ttls, err := LoadServiceTTLs("fixtures/service_ttls.json")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]time.Duration{
+		"payments": 720 * time.Hour,
+		"frontend": 24 * time.Hour,
+	}, ttls)
+}
+
+func TestLoadServiceTTLsErrors(t *testing.T) {
+	testCases := []struct {
+		caption string
+		content string
+		err     string
+	}{
+		{caption: "not json", content: "{", err: "failed to parse service TTL file"},
+		{caption: "not a duration", content: `{"services": {"payments": "a month"}}`, err: "invalid TTL of service payments"},
+		{caption: "too short", content: `{"services": {"payments": "500ms"}}`, err: "must be at least 1s, got 500ms"},
+	}
+	for _, tc := range testCases {
+		file, err := ioutil.TempFile("", "service_ttls")
+		require.NoError(t, err)
+		defer os.Remove(file.Name())
+		_, err = file.WriteString(tc.content)
+		require.NoError(t, err)
+		require.NoError(t, file.Close())
+
+		_, err = LoadServiceTTLs(file.Name())
+		require.Error(t, err, tc.caption)
+		assert.Contains(t, err.Error(), tc.err, tc.caption)
+	}
+
+	_, err := LoadServiceTTLs("fixtures/missing.json")
+	assert.Error(t, err)
+}
@@ -15,6 +15,7 @@
 package spanstore
 
 import (
+	"context"
 	"encoding/json"
 	"strings"
 	"sync/atomic"
@@ -87,6 +88,8 @@ type SpanWriter struct {
 	tagIndexSkipped      metrics.Counter
 	bucketCounter        uint32
 	tagFilter            dbmodel.TagFilter
+	defaultTTL           time.Duration
+	serviceTTLs          map[string]time.Duration
 }
 
 // NewSpanWriter returns a SpanWriter
@@ -115,14 +118,16 @@ func NewSpanWriter(
 		logger:          logger,
 		tagIndexSkipped: tagIndexSkipped,
 		tagFilter:       opts.tagFilter,
+		defaultTTL:      opts.defaultTTL,
+		serviceTTLs:     opts.serviceTTLs,
 	}
 }
 
-// WriteSpan saves the span into Cassandra
-func (s *SpanWriter) WriteSpan(span *model.Span) error {
+// WriteSpan saves the span into Cassandra, the queries of the span and its indexes are abandoned when ctx is cancelled
+func (s *SpanWriter) WriteSpan(ctx context.Context, span *model.Span) error {
 	ds := dbmodel.FromDomain(span)
-	mainQuery := s.session.Query(
-		insertSpan,
+	ttl := s.spanTTL(ds.ServiceName)
+	mainQuery := s.query(ctx, withTTL(insertSpan, ttl), withTTLValue(ttl,
 		ds.TraceID,
 		ds.SpanID,
 		ds.SpanHash,
@@ -135,7 +140,7 @@ func (s *SpanWriter) WriteSpan(span *model.Span) error {
 		ds.Logs,
 		ds.Refs,
 		ds.Process,
-	)
+	)...)
 
 	if err := s.writerMetrics.traces.Exec(mainQuery, s.logger); err != nil {
 		return s.logError(ds, err, "Failed to insert span", s.logger)
@@ -145,30 +150,31 @@ func (s *SpanWriter) WriteSpan(span *model.Span) error {
 		return s.logError(ds, err, "Failed to insert service name and operation name", s.logger)
 	}
 
-	if err := s.indexByTags(span, ds); err != nil {
+	if err := s.indexByTags(ctx, span, ds, ttl); err != nil {
 		return s.logError(ds, err, "Failed to index tags", s.logger)
 	}
 
-	if err := s.indexBySerice(span.TraceID, ds); err != nil {
+	if err := s.indexBySerice(ctx, span.TraceID, ds, ttl); err != nil {
 		return s.logError(ds, err, "Failed to index service name", s.logger)
 	}
 
-	if err := s.indexByOperation(span.TraceID, ds); err != nil {
+	if err := s.indexByOperation(ctx, span.TraceID, ds, ttl); err != nil {
 		return s.logError(ds, err, "Failed to index operation name", s.logger)
 	}
 
-	if err := s.indexByDuration(ds, span.StartTime); err != nil {
+	if err := s.indexByDuration(ctx, ds, span.StartTime, ttl); err != nil {
 		return s.logError(ds, err, "Failed to index duration", s.logger)
 	}
 	return nil
 }
 
-func (s *SpanWriter) indexByTags(span *model.Span, ds *dbmodel.Span) error {
+func (s *SpanWriter) indexByTags(ctx context.Context, span *model.Span, ds *dbmodel.Span, ttl time.Duration) error {
 	for _, v := range dbmodel.GetAllUniqueTags(span, s.tagFilter) {
 		// we should introduce retries or just ignore failures imo, retrying each individual tag insertion might be better
 		// we should consider bucketing.
 		if s.shouldIndexTag(v) {
-			insertTagQuery := s.session.Query(insertTag, ds.TraceID, ds.SpanID, v.ServiceName, ds.StartTime, v.TagKey, v.TagValue)
+			insertTagQuery := s.query(ctx, withTTL(insertTag, ttl),
+				withTTLValue(ttl, ds.TraceID, ds.SpanID, v.ServiceName, ds.StartTime, v.TagKey, v.TagValue)...)
 			if err := s.writerMetrics.tagIndex.Exec(insertTagQuery, s.logger); err != nil {
 				withTagInfo := s.logger.
 					With(zap.String("tag_key", v.TagKey)).
@@ -183,12 +189,12 @@ func (s *SpanWriter) indexByTags(span *model.Span, ds *dbmodel.Span) error {
 	return nil
 }
 
-func (s *SpanWriter) indexByDuration(span *dbmodel.Span, startTime time.Time) error {
-	query := s.session.Query(durationIndex)
+func (s *SpanWriter) indexByDuration(ctx context.Context, span *dbmodel.Span, startTime time.Time, ttl time.Duration) error {
+	query := s.query(ctx, withTTL(durationIndex, ttl))
 	timeBucket := startTime.Round(durationBucketSize)
 	var err error
 	indexByOperationName := func(operationName string) {
-		q1 := query.Bind(span.Process.ServiceName, operationName, timeBucket, span.Duration, span.StartTime, span.TraceID)
+		q1 := query.Bind(withTTLValue(ttl, span.Process.ServiceName, operationName, timeBucket, span.Duration, span.StartTime, span.TraceID)...)
 		if err2 := s.writerMetrics.durationIndex.Exec(q1, s.logger); err2 != nil {
 			s.logError(span, err2, "Cannot index duration", s.logger)
 			err = err2
@@ -199,19 +205,49 @@ func (s *SpanWriter) indexByDuration(span *dbmodel.Span, startTime time.Time) er
 	return err
 }
 
-func (s *SpanWriter) indexBySerice(traceID model.TraceID, span *dbmodel.Span) error {
+func (s *SpanWriter) indexBySerice(ctx context.Context, traceID model.TraceID, span *dbmodel.Span, ttl time.Duration) error {
 	bucketNo := atomic.AddUint32(&s.bucketCounter, 1) % defaultNumBuckets
-	query := s.session.Query(serviceNameIndex)
-	q := query.Bind(span.Process.ServiceName, bucketNo, span.StartTime, span.TraceID)
+	query := s.query(ctx, withTTL(serviceNameIndex, ttl))
+	q := query.Bind(withTTLValue(ttl, span.Process.ServiceName, bucketNo, span.StartTime, span.TraceID)...)
 	return s.writerMetrics.serviceNameIndex.Exec(q, s.logger)
 }
 
-func (s *SpanWriter) indexByOperation(traceID model.TraceID, span *dbmodel.Span) error {
-	query := s.session.Query(serviceOperationIndex)
-	q := query.Bind(span.Process.ServiceName, span.OperationName, span.StartTime, span.TraceID)
+func (s *SpanWriter) indexByOperation(ctx context.Context, traceID model.TraceID, span *dbmodel.Span, ttl time.Duration) error {
+	query := s.query(ctx, withTTL(serviceOperationIndex, ttl))
+	q := query.Bind(withTTLValue(ttl, span.Process.ServiceName, span.OperationName, span.StartTime, span.TraceID)...)
 	return s.writerMetrics.serviceOperationIndex.Exec(q, s.logger)
 }
 
+// query creates a query that is abandoned when ctx is cancelled
+func (s *SpanWriter) query(ctx context.Context, stmt string, values ...interface{}) cassandra.Query {
+	return s.session.Query(stmt, values...).WithContext(ctx)
+}
+
+// spanTTL returns the TTL of the spans of the service, 0 if the default TTL of the tables applies.
+// The indexes of a span are written with the same TTL as the span, so that they expire together.
+func (s *SpanWriter) spanTTL(serviceName string) time.Duration {
+	if ttl, ok := s.serviceTTLs[serviceName]; ok {
+		return ttl
+	}
+	return s.defaultTTL
+}
+
+// withTTL adds a TTL to an insert statement, unless ttl is 0
+func withTTL(stmt string, ttl time.Duration) string {
+	if ttl <= 0 {
+		return stmt
+	}
+	return stmt + " USING TTL ?"
+}
+
+// withTTLValue appends the TTL in seconds to the values of a statement prepared by withTTL
+func withTTLValue(ttl time.Duration, values ...interface{}) []interface{} {
+	if ttl <= 0 {
+		return values
+	}
+	return append(values, int(ttl.Seconds()))
+}
+
 // shouldIndexTag checks to see if the tag is json or not, if it's UTF8 valid and it's not too large
 func (s *SpanWriter) shouldIndexTag(tag dbmodel.TagInsertion) bool {
 	isJSON := func(s string) bool {
@@ -15,6 +15,8 @@
 package spanstore
 
 import (
+	"time"
+
 	"github.com/uber/jaeger/plugin/storage/cassandra/spanstore/dbmodel"
 )
 
@@ -23,7 +25,9 @@ type Option func(c *Options)
 
 // Options control behavior of the writer.
 type Options struct {
-	tagFilter dbmodel.TagFilter
+	tagFilter   dbmodel.TagFilter
+	defaultTTL  time.Duration
+	serviceTTLs map[string]time.Duration
 }
 
 // TagFilter can be provided to filter any tags that should not be indexed.
@@ -33,6 +37,15 @@ func TagFilter(tagFilter dbmodel.TagFilter) Option {
 	}
 }
 
+// ServiceTTLs sets the TTL of the spans of each service in serviceTTLs, and defaultTTL for the spans of
+// other services. A TTL of 0 leaves the spans to the default TTL of the tables.
+func ServiceTTLs(defaultTTL time.Duration, serviceTTLs map[string]time.Duration) Option {
+	return func(o *Options) {
+		o.defaultTTL = defaultTTL
+		o.serviceTTLs = serviceTTLs
+	}
+}
+
 func applyOptions(opts ...Option) Options {
 	o := Options{}
 	for _, opt := range opts {
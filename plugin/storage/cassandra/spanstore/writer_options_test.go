@@ -16,6 +16,7 @@ package spanstore
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -25,4 +26,9 @@ import (
 func TestWriterOpetions(t *testing.T) {
 	opts := applyOptions(TagFilter(dbmodel.DefaultTagFilter))
 	assert.Equal(t, dbmodel.DefaultTagFilter, opts.tagFilter)
+
+	ttls := map[string]time.Duration{"payments": time.Hour}
+	opts = applyOptions(ServiceTTLs(time.Minute, ttls))
+	assert.Equal(t, time.Minute, opts.defaultTTL)
+	assert.Equal(t, ttls, opts.serviceTTLs)
 }
@@ -15,12 +15,15 @@
 package spanstore
 
 import (
+	"context"
 	"errors"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"github.com/uber/jaeger-lib/metrics"
 	"go.uber.org/zap"
 
@@ -150,25 +153,30 @@ func TestSpanWriter(t *testing.T) {
 				}
 
 				spanQuery := &mocks.Query{}
+				spanQuery.On("WithContext", mock.Anything).Return(spanQuery)
 				spanQuery.On("Bind", matchEverything()).Return(spanQuery)
 				spanQuery.On("Exec").Return(testCase.mainQueryError)
 				spanQuery.On("String").Return("select from traces")
 
 				tagsQuery := &mocks.Query{}
+				tagsQuery.On("WithContext", mock.Anything).Return(tagsQuery)
 				tagsQuery.On("Exec").Return(testCase.tagsQueryError)
 				tagsQuery.On("String").Return("select from tags")
 
 				serviceNameQuery := &mocks.Query{}
+				serviceNameQuery.On("WithContext", mock.Anything).Return(serviceNameQuery)
 				serviceNameQuery.On("Bind", matchEverything()).Return(serviceNameQuery)
 				serviceNameQuery.On("Exec").Return(testCase.serviceNameQueryError)
 				serviceNameQuery.On("String").Return("select from service_name_index")
 
 				serviceOperationNameQuery := &mocks.Query{}
+				serviceOperationNameQuery.On("WithContext", mock.Anything).Return(serviceOperationNameQuery)
 				serviceOperationNameQuery.On("Bind", matchEverything()).Return(serviceOperationNameQuery)
 				serviceOperationNameQuery.On("Exec").Return(testCase.serviceOperationNameQueryError)
 				serviceOperationNameQuery.On("String").Return("select from service_operation_index")
 
 				durationNoOperationQuery := &mocks.Query{}
+				durationNoOperationQuery.On("WithContext", mock.Anything).Return(durationNoOperationQuery)
 				durationNoOperationQuery.On("Bind", matchEverything()).Return(durationNoOperationQuery)
 				durationNoOperationQuery.On("Exec").Return(testCase.durationNoOperationQueryError)
 				durationNoOperationQuery.On("String").Return("select from duration_index")
@@ -184,7 +192,7 @@ func TestSpanWriter(t *testing.T) {
 
 				w.writer.serviceNamesWriter = func(serviceName string) error { return testCase.serviceNameError }
 				w.writer.operationNamesWriter = func(serviceName, operationName string) error { return testCase.serviceNameError }
-				err := w.writer.WriteSpan(span)
+				err := w.writer.WriteSpan(context.Background(), span)
 
 				if testCase.expectedError == "" {
 					assert.NoError(t, err)
@@ -202,6 +210,94 @@ func TestSpanWriter(t *testing.T) {
 	}
 }
 
+func TestSpanWriterContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var contexts []context.Context
+	query := &mocks.Query{}
+	query.On("WithContext", mock.Anything).Run(func(args mock.Arguments) {
+		contexts = append(contexts, args.Get(0).(context.Context))
+	}).Return(query)
+	query.On("Bind", matchEverything()).Return(query)
+	query.On("Exec").Return(nil)
+	session := &mocks.Session{}
+	session.On("Query", mock.Anything, matchEverything()).Return(query)
+
+	writer := NewSpanWriter(session, 0, metrics.NullFactory, zap.NewNop())
+	writer.serviceNamesWriter = func(serviceName string) error { return nil }
+	writer.operationNamesWriter = func(serviceName, operationName string) error { return nil }
+	err := writer.WriteSpan(ctx, &model.Span{
+		TraceID:       model.TraceID{Low: 1},
+		OperationName: "operation",
+		Tags:          model.KeyValues{model.String("x", "y")},
+		Process:       &model.Process{ServiceName: "service"},
+	})
+	require.NoError(t, err)
+
+	// the span and its tag, service, operation, and duration indexes
+	require.Len(t, contexts, 5)
+	for _, c := range contexts {
+		assert.Equal(t, ctx, c)
+	}
+}
+
+func TestSpanWriterServiceTTL(t *testing.T) {
+	serviceTTLs := ServiceTTLs(48*time.Hour, map[string]time.Duration{"payments": 720 * time.Hour})
+	testCases := []struct {
+		caption string
+		service string
+		options []Option
+		ttl     int
+	}{
+		{caption: "no TTL", service: "payments"},
+		{caption: "service TTL", service: "payments", options: []Option{serviceTTLs}, ttl: 720 * 3600},
+		{caption: "default TTL", service: "frontend", options: []Option{serviceTTLs}, ttl: 48 * 3600},
+	}
+	for _, tc := range testCases {
+		testCase := tc // capture loop var
+		t.Run(testCase.caption, func(t *testing.T) {
+			var statements []string
+			var values [][]interface{}
+			query := &mocks.Query{}
+			query.On("WithContext", mock.Anything).Return(query)
+			query.On("Bind", matchEverything()).Run(func(args mock.Arguments) {
+				values = append(values, args.Get(0).([]interface{}))
+			}).Return(query)
+			query.On("Exec").Return(nil)
+			session := &mocks.Session{}
+			session.On("Query", mock.Anything, matchEverything()).Run(func(args mock.Arguments) {
+				statements = append(statements, args.String(0))
+				if v := args.Get(1).([]interface{}); len(v) > 0 {
+					values = append(values, v)
+				}
+			}).Return(query)
+
+			writer := NewSpanWriter(session, 0, metrics.NullFactory, zap.NewNop(), testCase.options...)
+			writer.serviceNamesWriter = func(serviceName string) error { return nil }
+			writer.operationNamesWriter = func(serviceName, operationName string) error { return nil }
+			err := writer.WriteSpan(context.Background(), &model.Span{
+				TraceID:       model.TraceID{Low: 1},
+				OperationName: "operation",
+				Tags:          model.KeyValues{model.String("x", "y")},
+				Process:       &model.Process{ServiceName: testCase.service},
+			})
+			require.NoError(t, err)
+
+			// the span and its tag, service, operation, and duration indexes
+			assert.Len(t, statements, 5)
+			assert.Len(t, values, 6)
+			for _, stmt := range statements {
+				assert.Equal(t, testCase.ttl > 0, strings.HasSuffix(stmt, " USING TTL ?"), stmt)
+			}
+			if testCase.ttl > 0 {
+				for _, v := range values {
+					assert.Equal(t, testCase.ttl, v[len(v)-1])
+				}
+			}
+		})
+	}
+}
+
 func TestSpanWriterSaveServiceNameAndOperationName(t *testing.T) {
 	expectedErr := errors.New("some error")
 	testCases := []struct {
@@ -0,0 +1,195 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+	"gopkg.in/olivere/elastic.v5"
+
+	"github.com/uber/jaeger/pkg/es"
+	storageMetrics "github.com/uber/jaeger/storage/spanstore/metrics"
+)
+
+// bulkProcessor batches index requests and sends them to ElasticSearch in bulk requests. A batch is
+// sent when it holds bulkActions requests or bulkSize bytes, or flushInterval after the previous one,
+// whichever comes first. A zero bulkActions, bulkSize, or flushInterval disables that condition.
+type bulkProcessor struct {
+	client        es.Client
+	logger        *zap.Logger
+	bulkMetrics   *storageMetrics.WriteMetrics
+	failedItems   metrics.Counter
+	bulkActions   int
+	bulkSize      int
+	flushInterval time.Duration
+
+	sync.Mutex
+	requests []elastic.BulkableRequest
+	size     int
+
+	batches chan bulkBatch
+	stopCh  chan struct{}
+	wg      sync.WaitGroup
+}
+
+// bulkBatch holds the requests sent in one bulk request, done is closed once the request is done unless it is nil
+type bulkBatch struct {
+	requests []elastic.BulkableRequest
+	done     chan struct{}
+}
+
+func newBulkProcessor(
+	client es.Client,
+	logger *zap.Logger,
+	metricsFactory metrics.Factory,
+	workers int,
+	bulkActions int,
+	bulkSize int,
+	flushInterval time.Duration,
+) *bulkProcessor {
+	p := &bulkProcessor{
+		client:        client,
+		logger:        logger,
+		bulkMetrics:   storageMetrics.NewWriteMetrics(metricsFactory, "BulkIndex"),
+		failedItems:   metricsFactory.Counter("bulkIndexFailedItems", nil),
+		bulkActions:   bulkActions,
+		bulkSize:      bulkSize,
+		flushInterval: flushInterval,
+		batches:       make(chan bulkBatch),
+		stopCh:        make(chan struct{}),
+	}
+	for i := 0; i < workers; i++ {
+		p.wg.Add(1)
+		go p.worker()
+	}
+	if flushInterval > 0 {
+		p.wg.Add(1)
+		go p.flushPeriodically()
+	}
+	return p
+}
+
+// Add queues the request for the next bulk request. It blocks while all workers are busy sending
+// the previous bulk requests.
+func (p *bulkProcessor) Add(request elastic.BulkableRequest) {
+	p.Lock()
+	defer p.Unlock()
+	p.requests = append(p.requests, request)
+	p.size += requestSize(request)
+	if (p.bulkActions > 0 && len(p.requests) >= p.bulkActions) || (p.bulkSize > 0 && p.size >= p.bulkSize) {
+		p.flush(nil)
+	}
+}
+
+// Flush sends the queued requests to ElasticSearch.
+func (p *bulkProcessor) Flush() {
+	p.Lock()
+	defer p.Unlock()
+	p.flush(nil)
+}
+
+// FlushAndWait sends the queued requests to ElasticSearch and waits until the bulk request is done,
+// it returns the number of requests sent. The bulk requests sent earlier may still be in progress.
+func (p *bulkProcessor) FlushAndWait() int {
+	done := make(chan struct{})
+	p.Lock()
+	flushed := len(p.requests)
+	p.flush(done)
+	p.Unlock()
+	if flushed > 0 {
+		<-done
+	}
+	return flushed
+}
+
+// Close sends the queued requests and waits until all bulk requests are done. Add must not be called
+// after Close.
+func (p *bulkProcessor) Close() {
+	close(p.stopCh)
+	p.Flush()
+	close(p.batches)
+	p.wg.Wait()
+}
+
+// flush must be called with the lock held, done is closed once the bulk request is done unless it is nil
+func (p *bulkProcessor) flush(done chan struct{}) {
+	if len(p.requests) == 0 {
+		return
+	}
+	p.batches <- bulkBatch{requests: p.requests, done: done}
+	p.requests = nil
+	p.size = 0
+}
+
+func (p *bulkProcessor) flushPeriodically() {
+	defer p.wg.Done()
+	ticker := time.NewTicker(p.flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			p.Flush()
+		case <-p.stopCh:
+			return
+		}
+	}
+}
+
+func (p *bulkProcessor) worker() {
+	defer p.wg.Done()
+	for batch := range p.batches {
+		p.commit(batch.requests)
+		if batch.done != nil {
+			close(batch.done)
+		}
+	}
+}
+
+func (p *bulkProcessor) commit(batch []elastic.BulkableRequest) {
+	start := time.Now()
+	res, err := p.client.Bulk().Add(batch...).Do(context.Background())
+	p.bulkMetrics.Emit(err, time.Since(start))
+	if err != nil {
+		p.failedItems.Inc(int64(len(batch)))
+		p.logger.Error("Failed to send bulk request", zap.Int("items", len(batch)), zap.Error(err))
+		return
+	}
+	if failed := res.Failed(); len(failed) > 0 {
+		p.failedItems.Inc(int64(len(failed)))
+		for _, item := range failed {
+			p.logger.Error("Failed to index span in bulk request",
+				zap.String("index", item.Index),
+				zap.Int("status", item.Status),
+				zap.Any("error", item.Error))
+		}
+	}
+}
+
+// requestSize estimates the size of the request in the body of a bulk request
+func requestSize(request elastic.BulkableRequest) int {
+	lines, err := request.Source()
+	if err != nil {
+		return 0
+	}
+	size := 0
+	for _, line := range lines {
+		size += len(line) + 1 // the lines are separated by new lines in the bulk request
+	}
+	return size
+}
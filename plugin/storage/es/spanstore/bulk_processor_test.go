@@ -0,0 +1,192 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+	"gopkg.in/olivere/elastic.v5"
+
+	"github.com/uber/jaeger/pkg/es/mocks"
+	"github.com/uber/jaeger/pkg/testutils"
+)
+
+type bulkProcessorTest struct {
+	client      *mocks.Client
+	bulkService *mocks.BulkService
+	// committed receives the number of requests in every bulk request
+	committed chan int
+}
+
+func withBulkService(response *elastic.BulkResponse, err error, fn func(b *bulkProcessorTest)) {
+	b := &bulkProcessorTest{
+		client:      &mocks.Client{},
+		bulkService: &mocks.BulkService{},
+		committed:   make(chan int, 10),
+	}
+	var added int
+	for i := 1; i <= 3; i++ {
+		args := make([]interface{}, i)
+		for j := range args {
+			args[j] = mock.Anything
+		}
+		numRequests := i
+		b.bulkService.On("Add", args...).Return(b.bulkService).Run(func(mock.Arguments) {
+			added = numRequests
+		})
+	}
+	b.bulkService.On("Do", mock.Anything).Return(response, err).Run(func(mock.Arguments) {
+		b.committed <- added
+	})
+	b.client.On("Bulk").Return(b.bulkService)
+	fn(b)
+}
+
+func (b *bulkProcessorTest) waitForCommit(t *testing.T) int {
+	select {
+	case numRequests := <-b.committed:
+		return numRequests
+	case <-time.After(time.Second):
+		t.Fatal("bulk request was not sent")
+		return 0
+	}
+}
+
+func newIndexRequest() elastic.BulkableRequest {
+	return elastic.NewBulkIndexRequest().Index("jaeger-span-1995-04-21").Type(spanType).Doc(&Span{})
+}
+
+func TestBulkProcessorFlushOnActions(t *testing.T) {
+	withBulkService(&elastic.BulkResponse{}, nil, func(b *bulkProcessorTest) {
+		p := newBulkProcessor(b.client, zap.NewNop(), metrics.NullFactory, 1, 2, 0, 0)
+		defer p.Close()
+
+		p.Add(newIndexRequest())
+		assert.Len(t, b.committed, 0)
+		p.Add(newIndexRequest())
+		assert.Equal(t, 2, b.waitForCommit(t))
+	})
+}
+
+func TestBulkProcessorFlushOnSize(t *testing.T) {
+	withBulkService(&elastic.BulkResponse{}, nil, func(b *bulkProcessorTest) {
+		size := requestSize(newIndexRequest())
+		assert.True(t, size > 0)
+		p := newBulkProcessor(b.client, zap.NewNop(), metrics.NullFactory, 1, 100, size*3/2, 0)
+		defer p.Close()
+
+		p.Add(newIndexRequest())
+		assert.Len(t, b.committed, 0)
+		p.Add(newIndexRequest())
+		assert.Equal(t, 2, b.waitForCommit(t))
+	})
+}
+
+func TestBulkProcessorFlushOnInterval(t *testing.T) {
+	withBulkService(&elastic.BulkResponse{}, nil, func(b *bulkProcessorTest) {
+		p := newBulkProcessor(b.client, zap.NewNop(), metrics.NullFactory, 1, 100, 0, 10*time.Millisecond)
+		defer p.Close()
+
+		p.Add(newIndexRequest())
+		assert.Equal(t, 1, b.waitForCommit(t))
+	})
+}
+
+func TestBulkProcessorFlushOnClose(t *testing.T) {
+	withBulkService(&elastic.BulkResponse{}, nil, func(b *bulkProcessorTest) {
+		p := newBulkProcessor(b.client, zap.NewNop(), metrics.NullFactory, 2, 100, 0, time.Hour)
+
+		p.Add(newIndexRequest())
+		p.Add(newIndexRequest())
+		p.Add(newIndexRequest())
+		assert.Len(t, b.committed, 0)
+		p.Close()
+		assert.Equal(t, 3, b.waitForCommit(t))
+		b.bulkService.AssertNumberOfCalls(t, "Do", 1)
+	})
+}
+
+func TestBulkProcessorFlushAndWait(t *testing.T) {
+	withBulkService(&elastic.BulkResponse{}, nil, func(b *bulkProcessorTest) {
+		p := newBulkProcessor(b.client, zap.NewNop(), metrics.NullFactory, 2, 100, 0, time.Hour)
+		defer p.Close()
+
+		assert.Equal(t, 0, p.FlushAndWait(), "nothing is sent without requests")
+		p.Add(newIndexRequest())
+		p.Add(newIndexRequest())
+		assert.Equal(t, 2, p.FlushAndWait())
+		// the bulk request is done when FlushAndWait returns
+		assert.Len(t, b.committed, 1)
+		assert.Equal(t, 2, <-b.committed)
+	})
+}
+
+func TestBulkProcessorFailures(t *testing.T) {
+	failedResponse := &elastic.BulkResponse{
+		Errors: true,
+		Items: []map[string]*elastic.BulkResponseItem{
+			{"index": {Index: "jaeger-span-1995-04-21", Status: 201}},
+			{"index": {Index: "jaeger-span-1995-04-21", Status: 400}},
+		},
+	}
+	testCases := []struct {
+		caption        string
+		response       *elastic.BulkResponse
+		err            error
+		expectedErrors int64
+		expectedFailed int64
+		expectedLog    string
+	}{
+		{
+			caption:        "request error",
+			err:            errors.New("bulk error"),
+			expectedErrors: 1,
+			expectedFailed: 2,
+			expectedLog:    `"msg":"Failed to send bulk request"`,
+		},
+		{
+			caption:        "item error",
+			response:       failedResponse,
+			expectedFailed: 1,
+			expectedLog:    `"msg":"Failed to index span in bulk request"`,
+		},
+	}
+	for _, tc := range testCases {
+		testCase := tc // capture loop var
+		t.Run(testCase.caption, func(t *testing.T) {
+			withBulkService(testCase.response, testCase.err, func(b *bulkProcessorTest) {
+				logger, logBuffer := testutils.NewLogger()
+				metricsFactory := metrics.NewLocalFactory(0)
+				p := newBulkProcessor(b.client, logger, metricsFactory, 1, 2, 0, 0)
+
+				p.Add(newIndexRequest())
+				p.Add(newIndexRequest())
+				p.Close()
+
+				counters, _ := metricsFactory.Snapshot()
+				assert.Equal(t, int64(1), counters["BulkIndex.attempts"])
+				assert.Equal(t, testCase.expectedErrors, counters["BulkIndex.errors"])
+				assert.Equal(t, testCase.expectedFailed, counters["bulkIndexFailedItems"])
+				assert.Contains(t, logBuffer.String(), testCase.expectedLog)
+			})
+		})
+	}
+}
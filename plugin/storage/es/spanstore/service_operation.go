@@ -69,7 +69,7 @@ func NewServiceOperationStorage(
 }
 
 // Write saves a service to operation pair.
-func (s *ServiceOperationStorage) Write(indexName string, jsonSpan *jModel.Span) error {
+func (s *ServiceOperationStorage) Write(ctx context.Context, indexName string, jsonSpan *jModel.Span) error {
 	// Insert serviceName:operationName document
 	service := Service{
 		ServiceName:   jsonSpan.Process.ServiceName,
@@ -79,7 +79,7 @@ func (s *ServiceOperationStorage) Write(indexName string, jsonSpan *jModel.Span)
 	cacheKey := fmt.Sprintf("%s:%s", indexName, serviceID)
 	if !keyInCache(cacheKey, s.serviceCache) {
 		start := time.Now()
-		_, err := s.client.Index().Index(indexName).Type(serviceType).Id(serviceID).BodyJson(service).Do(s.ctx)
+		_, err := s.client.Index().Index(indexName).Type(serviceType).Id(serviceID).BodyJson(service).Do(ctx)
 		s.metrics.Emit(err, time.Since(start))
 		if err != nil {
 			return s.logError(jsonSpan, err, "Failed to insert service:operation", s.logger)
@@ -15,6 +15,7 @@
 package spanstore
 
 import (
+	"context"
 	"errors"
 	"strings"
 	"testing"
@@ -50,14 +51,14 @@ func TestWriteService(t *testing.T) {
 			},
 		}
 
-		err := w.writer.writeService(indexName, jsonSpan)
+		err := w.writer.writeService(context.Background(), indexName, jsonSpan)
 		require.NoError(t, err)
 
 		indexService.AssertNumberOfCalls(t, "Do", 1)
 		assert.Equal(t, "", w.logBuffer.String())
 
 		// test that cache works, will call the index service only once.
-		err = w.writer.writeService(indexName, jsonSpan)
+		err = w.writer.writeService(context.Background(), indexName, jsonSpan)
 		require.NoError(t, err)
 		indexService.AssertNumberOfCalls(t, "Do", 1)
 	})
@@ -85,7 +86,7 @@ func TestWriteServiceError(t *testing.T) {
 			},
 		}
 
-		err := w.writer.writeService(indexName, jsonSpan)
+		err := w.writer.writeService(context.Background(), indexName, jsonSpan)
 		assert.EqualError(t, err, "Failed to insert service:operation: service insertion error")
 
 		indexService.AssertNumberOfCalls(t, "Do", 1)
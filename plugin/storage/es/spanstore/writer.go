@@ -23,12 +23,14 @@ import (
 	"github.com/pkg/errors"
 	"github.com/uber/jaeger-lib/metrics"
 	"go.uber.org/zap"
+	"gopkg.in/olivere/elastic.v5"
 
 	"github.com/uber/jaeger/model"
 	"github.com/uber/jaeger/model/converter/json"
 	jModel "github.com/uber/jaeger/model/json"
 	"github.com/uber/jaeger/pkg/cache"
 	"github.com/uber/jaeger/pkg/es"
+	"github.com/uber/jaeger/pkg/es/config"
 	storageMetrics "github.com/uber/jaeger/storage/spanstore/metrics"
 )
 
@@ -38,6 +40,9 @@ const (
 
 	defaultNumShards   = 5
 	defaultNumReplicas = 1
+
+	dailyDateLayout   = "2006-01-02"
+	monthlyDateLayout = "2006-01"
 )
 
 type spanWriterMetrics struct {
@@ -45,7 +50,7 @@ type spanWriterMetrics struct {
 	spans       *storageMetrics.WriteMetrics
 }
 
-type serviceWriter func(string, *jModel.Span) error
+type serviceWriter func(context.Context, string, *jModel.Span) error
 
 // SpanWriter is a wrapper around elastic.Client
 type SpanWriter struct {
@@ -57,6 +62,13 @@ type SpanWriter struct {
 	serviceWriter serviceWriter
 	numShards     int64
 	numReplicas   int64
+	// spanIndexPrefix and serviceIndexPrefix are the index names without the date
+	spanIndexPrefix    string
+	serviceIndexPrefix string
+	indexDateLayout    string
+	createTemplates    bool
+	traceIDRouting     bool
+	bulkProcessor      *bulkProcessor
 }
 
 // Service is the JSON struct for service:operation documents in ElasticSearch
@@ -81,8 +93,10 @@ func NewSpanWriter(
 	metricsFactory metrics.Factory,
 	numShards int64,
 	numReplicas int64,
+	options ...Option,
 ) *SpanWriter {
 	ctx := context.Background()
+	opts := applyOptions(options...)
 	if numShards == 0 {
 		numShards = defaultNumShards
 	}
@@ -91,6 +105,10 @@ func NewSpanWriter(
 	}
 	// TODO: Configurable TTL
 	serviceOperationStorage := NewServiceOperationStorage(ctx, client, metricsFactory, logger, time.Hour*12)
+	var bulk *bulkProcessor
+	if opts.bulkWorkers > 0 {
+		bulk = newBulkProcessor(client, logger, metricsFactory, opts.bulkWorkers, opts.bulkActions, opts.bulkSize, opts.bulkFlushInterval)
+	}
 	return &SpanWriter{
 		ctx:    ctx,
 		client: client,
@@ -106,43 +124,94 @@ func NewSpanWriter(
 				TTL: 48 * time.Hour,
 			},
 		),
-		numShards:   numShards,
-		numReplicas: numReplicas,
+		numShards:          numShards,
+		numReplicas:        numReplicas,
+		spanIndexPrefix:    prefixIndexName(opts.indexPrefix, spanIndexPrefix),
+		serviceIndexPrefix: prefixIndexName(opts.indexPrefix, serviceIndexPrefix),
+		indexDateLayout:    indexDateLayout(opts.indexRotation),
+		createTemplates:    opts.createTemplates,
+		traceIDRouting:     opts.traceIDRouting,
+		bulkProcessor:      bulk,
+	}
+}
+
+func prefixIndexName(prefix string, indexName string) string {
+	if prefix == "" {
+		return indexName
+	}
+	return prefix + "-" + indexName
+}
+
+func indexDateLayout(indexRotation string) string {
+	if indexRotation == config.IndexRotationMonthly {
+		return monthlyDateLayout
 	}
+	return dailyDateLayout
 }
 
-// WriteSpan writes a span and its corresponding service:operation in ElasticSearch
-func (s *SpanWriter) WriteSpan(span *model.Span) error {
-	spanIndexName, serviceIndexName := indexNames(span)
+// WriteSpan writes a span and its corresponding service:operation in ElasticSearch, the requests are
+// abandoned when ctx is cancelled. Spans added to the bulk processor are sent regardless of ctx.
+func (s *SpanWriter) WriteSpan(ctx context.Context, span *model.Span) error {
+	spanIndexName, serviceIndexName := s.indexNames(span)
 	// Convert model.Span into json.Span
 	jsonSpan := json.FromDomainEmbedProcess(span)
 
-	if err := s.createIndex(serviceIndexName, serviceMapping, jsonSpan); err != nil {
+	if err := s.createIndex(ctx, serviceIndexName, serviceMapping, jsonSpan); err != nil {
 		return err
 	}
-	if err := s.writeService(serviceIndexName, jsonSpan); err != nil {
+	if err := s.writeService(ctx, serviceIndexName, jsonSpan); err != nil {
 		return err
 	}
-	if err := s.createIndex(spanIndexName, spanMapping, jsonSpan); err != nil {
+	if err := s.createIndex(ctx, spanIndexName, spanMapping, jsonSpan); err != nil {
+		return err
+	}
+	return s.writeSpan(ctx, spanIndexName, jsonSpan)
+}
+
+// Flush sends the spans waiting for a bulk request to ElasticSearch and waits until the request is done,
+// it returns the number of spans sent. It does nothing without bulk processing.
+func (s *SpanWriter) Flush() int {
+	if s.bulkProcessor == nil {
+		return 0
+	}
+	return s.bulkProcessor.FlushAndWait()
+}
+
+// Close sends the spans waiting for a bulk request to ElasticSearch. WriteSpan must not be called after Close.
+func (s *SpanWriter) Close() error {
+	if s.bulkProcessor != nil {
+		s.bulkProcessor.Close()
+	}
+	return nil
+}
+
+// CreateTemplates installs the span and service index templates, so that ElasticSearch creates
+// every new index with the right mapping when the first document is written to it.
+func (s *SpanWriter) CreateTemplates() error {
+	if err := s.createTemplate(s.serviceIndexPrefix, serviceMapping); err != nil {
 		return err
 	}
-	return s.writeSpan(spanIndexName, jsonSpan)
+	return s.createTemplate(s.spanIndexPrefix, spanMapping)
 }
 
-func indexNames(span *model.Span) (string, string) {
-	spanDate := span.StartTime.Format("2006-01-02")
-	return spanIndexPrefix + spanDate, serviceIndexPrefix + spanDate
+func (s *SpanWriter) indexNames(span *model.Span) (string, string) {
+	spanDate := span.StartTime.Format(s.indexDateLayout)
+	return s.spanIndexPrefix + spanDate, s.serviceIndexPrefix + spanDate
 }
 
-func (s *SpanWriter) createIndex(indexName string, mapping string, jsonSpan *jModel.Span) error {
+func (s *SpanWriter) createIndex(ctx context.Context, indexName string, mapping string, jsonSpan *jModel.Span) error {
+	if s.createTemplates {
+		// the index is created by ElasticSearch from the template
+		return nil
+	}
 	if !keyInCache(indexName, s.indexCache) {
 		start := time.Now()
-		exists, _ := s.client.IndexExists(indexName).Do(s.ctx) // don't need to check the error because the exists variable will be false anyway if there is an error
+		exists, _ := s.client.IndexExists(indexName).Do(ctx) // don't need to check the error because the exists variable will be false anyway if there is an error
 		if !exists {
 			// if there are multiple collectors writing to the same elasticsearch host, if the collectors pass
 			// the exists check above and try to create the same index all at once, this might fail and
 			// drop a couple spans (~1 per collector). Creating indices ahead of time alleviates this issue.
-			_, err := s.client.CreateIndex(indexName).Body(s.fixMapping(mapping)).Do(s.ctx)
+			_, err := s.client.CreateIndex(indexName).Body(s.fixMapping(mapping)).Do(ctx)
 			s.writerMetrics.indexCreate.Emit(err, time.Since(start))
 			if err != nil {
 				return s.logError(jsonSpan, err, "Failed to create index", s.logger)
@@ -153,6 +222,21 @@ func (s *SpanWriter) createIndex(indexName string, mapping string, jsonSpan *jMo
 	return nil
 }
 
+func (s *SpanWriter) createTemplate(indexPrefix string, mapping string) error {
+	templateName := strings.TrimSuffix(indexPrefix, "-")
+	_, err := s.client.CreateTemplate(templateName).Body(templateBody(indexPrefix+"*", s.fixMapping(mapping))).Do(s.ctx)
+	if err != nil {
+		s.logger.Error("Failed to create index template", zap.String("template", templateName), zap.Error(err))
+		return errors.Wrap(err, "Failed to create index template")
+	}
+	return nil
+}
+
+// templateBody turns an index mapping into an index template applied to the indices matching pattern
+func templateBody(pattern string, mapping string) string {
+	return `{"template":"` + pattern + `",` + strings.TrimPrefix(strings.TrimSpace(mapping), "{")
+}
+
 func keyInCache(key string, c cache.Cache) bool {
 	return c.Get(key) != nil
 }
@@ -167,14 +251,27 @@ func (s *SpanWriter) fixMapping(mapping string) string {
 	return mapping
 }
 
-func (s *SpanWriter) writeService(indexName string, jsonSpan *jModel.Span) error {
-	return s.serviceWriter(indexName, jsonSpan)
+func (s *SpanWriter) writeService(ctx context.Context, indexName string, jsonSpan *jModel.Span) error {
+	return s.serviceWriter(ctx, indexName, jsonSpan)
 }
 
-func (s *SpanWriter) writeSpan(indexName string, jsonSpan *jModel.Span) error {
+func (s *SpanWriter) writeSpan(ctx context.Context, indexName string, jsonSpan *jModel.Span) error {
 	start := time.Now()
 	elasticSpan := Span{Span: jsonSpan, StartTimeMillis: jsonSpan.StartTime / 1000} // Microseconds to milliseconds
-	_, err := s.client.Index().Index(indexName).Type(spanType).BodyJson(&elasticSpan).Do(s.ctx)
+	if s.bulkProcessor != nil {
+		request := elastic.NewBulkIndexRequest().Index(indexName).Type(spanType).Doc(&elasticSpan)
+		if s.traceIDRouting {
+			request.Routing(routingKey(jsonSpan))
+		}
+		// failures are reported by the bulk processor once the bulk request is done
+		s.bulkProcessor.Add(request)
+		return nil
+	}
+	index := s.client.Index().Index(indexName).Type(spanType)
+	if s.traceIDRouting {
+		index = index.Routing(routingKey(jsonSpan))
+	}
+	_, err := index.BodyJson(&elasticSpan).Do(ctx)
 	s.writerMetrics.spans.Emit(err, time.Since(start))
 	if err != nil {
 		return s.logError(jsonSpan, err, "Failed to insert span", s.logger)
@@ -182,6 +279,13 @@ func (s *SpanWriter) writeSpan(indexName string, jsonSpan *jModel.Span) error {
 	return nil
 }
 
+// routingKey returns the routing key of the span, its trace ID. ElasticSearch stores the span on the shard
+// selected by a hash of the routing key, so all the spans of a trace end up on the same shard while the
+// traces are spread evenly across the shards.
+func routingKey(jsonSpan *jModel.Span) string {
+	return string(jsonSpan.TraceID)
+}
+
 func (s *SpanWriter) logError(span *jModel.Span, err error, msg string, logger *zap.Logger) error {
 	logger.
 		With(zap.String("trace_id", string(span.TraceID))).
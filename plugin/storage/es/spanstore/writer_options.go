@@ -0,0 +1,91 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"time"
+
+	"github.com/uber/jaeger/pkg/es/config"
+)
+
+// Option is a function that sets some option on the writer.
+type Option func(c *Options)
+
+// Options control behavior of the writer.
+type Options struct {
+	indexPrefix     string
+	indexRotation   string
+	createTemplates bool
+
+	bulkWorkers       int
+	bulkActions       int
+	bulkSize          int
+	bulkFlushInterval time.Duration
+
+	traceIDRouting bool
+}
+
+// IndexPrefix is prepended to the names of the span and service indices.
+func IndexPrefix(indexPrefix string) Option {
+	return func(o *Options) {
+		o.indexPrefix = indexPrefix
+	}
+}
+
+// IndexRotation sets how often new indices are created, config.IndexRotationDaily or config.IndexRotationMonthly.
+func IndexRotation(indexRotation string) Option {
+	return func(o *Options) {
+		o.indexRotation = indexRotation
+	}
+}
+
+// CreateTemplates makes the writer rely on the index templates installed by SpanWriter.CreateTemplates
+// instead of creating every index itself.
+func CreateTemplates(createTemplates bool) Option {
+	return func(o *Options) {
+		o.createTemplates = createTemplates
+	}
+}
+
+// BulkProcessing makes the writer send spans to ElasticSearch in bulk requests from the given number
+// of workers. A bulk request is sent when it holds bulkActions spans or bulkSize bytes, or flushInterval
+// after the previous one, whichever comes first.
+func BulkProcessing(workers int, bulkActions int, bulkSize int, flushInterval time.Duration) Option {
+	return func(o *Options) {
+		o.bulkWorkers = workers
+		o.bulkActions = bulkActions
+		o.bulkSize = bulkSize
+		o.bulkFlushInterval = flushInterval
+	}
+}
+
+// TraceIDRouting makes the writer route every span to a shard by its trace ID, so that the spans
+// of a trace are stored on the same shard of the span index.
+func TraceIDRouting(traceIDRouting bool) Option {
+	return func(o *Options) {
+		o.traceIDRouting = traceIDRouting
+	}
+}
+
+func applyOptions(opts ...Option) Options {
+	o := Options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.indexRotation == "" {
+		o.indexRotation = config.IndexRotationDaily
+	}
+	return o
+}
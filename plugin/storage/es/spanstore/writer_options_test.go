@@ -0,0 +1,46 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/uber/jaeger/pkg/es/config"
+)
+
+func TestWriterOptions(t *testing.T) {
+	opts := applyOptions()
+	assert.Empty(t, opts.indexPrefix)
+	assert.Equal(t, config.IndexRotationDaily, opts.indexRotation)
+	assert.False(t, opts.createTemplates)
+	assert.Equal(t, 0, opts.bulkWorkers)
+
+	opts = applyOptions(
+		IndexPrefix("production"),
+		IndexRotation(config.IndexRotationMonthly),
+		CreateTemplates(true),
+		BulkProcessing(2, 100, 1000, time.Second),
+	)
+	assert.Equal(t, "production", opts.indexPrefix)
+	assert.Equal(t, config.IndexRotationMonthly, opts.indexRotation)
+	assert.True(t, opts.createTemplates)
+	assert.Equal(t, 2, opts.bulkWorkers)
+	assert.Equal(t, 100, opts.bulkActions)
+	assert.Equal(t, 1000, opts.bulkSize)
+	assert.Equal(t, time.Second, opts.bulkFlushInterval)
+}
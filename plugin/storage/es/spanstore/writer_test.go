@@ -15,6 +15,7 @@
 package spanstore
 
 import (
+	"context"
 	"errors"
 	"strings"
 	"testing"
@@ -29,6 +30,7 @@ import (
 
 	"github.com/uber/jaeger/model"
 	"github.com/uber/jaeger/model/json"
+	"github.com/uber/jaeger/pkg/es/config"
 	"github.com/uber/jaeger/pkg/es/mocks"
 	"github.com/uber/jaeger/pkg/testutils"
 	"github.com/uber/jaeger/storage/spanstore"
@@ -203,7 +205,7 @@ func TestSpanWriter_WriteSpan(t *testing.T) {
 				w.client.On("CreateIndex", stringMatcher(serviceIndexName)).Return(serviceCreateService)
 				w.client.On("Index").Return(indexService)
 
-				err = w.writer.WriteSpan(span)
+				err = w.writer.WriteSpan(context.Background(), span)
 
 				if testCase.expectedError == "" {
 					assert.NoError(t, err)
@@ -230,9 +232,100 @@ func TestSpanIndexName(t *testing.T) {
 	span := &model.Span{
 		StartTime: date,
 	}
-	spanIndexName, serviceIndexName := indexNames(span)
-	assert.Equal(t, "jaeger-span-1995-04-21", spanIndexName)
-	assert.Equal(t, "jaeger-service-1995-04-21", serviceIndexName)
+	testCases := []struct {
+		options          []Option
+		spanIndexName    string
+		serviceIndexName string
+	}{
+		{
+			spanIndexName:    "jaeger-span-1995-04-21",
+			serviceIndexName: "jaeger-service-1995-04-21",
+		},
+		{
+			options:          []Option{IndexRotation(config.IndexRotationDaily)},
+			spanIndexName:    "jaeger-span-1995-04-21",
+			serviceIndexName: "jaeger-service-1995-04-21",
+		},
+		{
+			options:          []Option{IndexRotation(config.IndexRotationMonthly)},
+			spanIndexName:    "jaeger-span-1995-04",
+			serviceIndexName: "jaeger-service-1995-04",
+		},
+		{
+			options:          []Option{IndexPrefix("prefix")},
+			spanIndexName:    "prefix-jaeger-span-1995-04-21",
+			serviceIndexName: "prefix-jaeger-service-1995-04-21",
+		},
+		{
+			options:          []Option{IndexPrefix("prefix"), IndexRotation(config.IndexRotationMonthly)},
+			spanIndexName:    "prefix-jaeger-span-1995-04",
+			serviceIndexName: "prefix-jaeger-service-1995-04",
+		},
+	}
+	for _, testCase := range testCases {
+		writer := NewSpanWriter(&mocks.Client{}, zap.NewNop(), metrics.NullFactory, 0, 0, testCase.options...)
+		spanIndexName, serviceIndexName := writer.indexNames(span)
+		assert.Equal(t, testCase.spanIndexName, spanIndexName)
+		assert.Equal(t, testCase.serviceIndexName, serviceIndexName)
+	}
+}
+
+func TestCreateTemplates(t *testing.T) {
+	testCases := []struct {
+		createError   error
+		expectedError string
+		expectedLogs  []string
+	}{
+		{},
+		{
+			createError:   errors.New("template creation error"),
+			expectedError: "Failed to create index template: template creation error",
+			expectedLogs: []string{
+				`"msg":"Failed to create index template"`,
+				`"template":"prefix-jaeger-service"`,
+				`"error":"template creation error"`,
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		client := &mocks.Client{}
+		logger, logBuffer := testutils.NewLogger()
+		writer := NewSpanWriter(client, logger, metrics.NullFactory, 0, 0, IndexPrefix("prefix"), CreateTemplates(true))
+
+		serviceTemplate := &mocks.TemplateCreateService{}
+		serviceTemplate.On("Body", templateBody("prefix-jaeger-service-*", writer.fixMapping(serviceMapping))).Return(serviceTemplate)
+		serviceTemplate.On("Do", mock.AnythingOfType("*context.emptyCtx")).Return(&elastic.IndicesPutTemplateResponse{}, testCase.createError)
+		spanTemplate := &mocks.TemplateCreateService{}
+		spanTemplate.On("Body", templateBody("prefix-jaeger-span-*", writer.fixMapping(spanMapping))).Return(spanTemplate)
+		spanTemplate.On("Do", mock.AnythingOfType("*context.emptyCtx")).Return(&elastic.IndicesPutTemplateResponse{}, nil)
+		client.On("CreateTemplate", "prefix-jaeger-service").Return(serviceTemplate)
+		client.On("CreateTemplate", "prefix-jaeger-span").Return(spanTemplate)
+
+		err := writer.CreateTemplates()
+		if testCase.expectedError == "" {
+			assert.NoError(t, err)
+			spanTemplate.AssertNumberOfCalls(t, "Do", 1)
+		} else {
+			assert.EqualError(t, err, testCase.expectedError)
+			spanTemplate.AssertNotCalled(t, "Do", mock.Anything)
+		}
+		for _, expectedLog := range testCase.expectedLogs {
+			assert.True(t, strings.Contains(logBuffer.String(), expectedLog), "Log must contain %s, but was %s", expectedLog, logBuffer.String())
+		}
+	}
+}
+
+func TestTemplateBody(t *testing.T) {
+	assert.Equal(t, `{"template":"jaeger-span-*", "settings":{}}`, templateBody("jaeger-span-*", `{ "settings":{}}`))
+}
+
+func TestCreateIndexWithTemplates(t *testing.T) {
+	client := &mocks.Client{}
+	writer := NewSpanWriter(client, zap.NewNop(), metrics.NullFactory, 0, 0, CreateTemplates(true))
+	err := writer.createIndex(context.Background(), "jaeger-span-1995-04-21", spanMapping, &json.Span{})
+	assert.NoError(t, err)
+	client.AssertNotCalled(t, "IndexExists", mock.Anything)
+	client.AssertNotCalled(t, "CreateIndex", mock.Anything)
 }
 
 func TestCheckAndCreateIndex(t *testing.T) {
@@ -278,13 +371,13 @@ func TestCheckAndCreateIndex(t *testing.T) {
 				SpanID:  json.SpanID("0"),
 			}
 
-			err := w.writer.createIndex(indexName, spanMapping, jsonSpan)
+			err := w.writer.createIndex(context.Background(), indexName, spanMapping, jsonSpan)
 			createService.AssertNumberOfCalls(t, "Do", 1)
 
 			if testCase.expectedError == "" {
 				assert.NoError(t, err)
 				// makes sure that the cache works
-				_ = w.writer.createIndex(indexName, spanMapping, jsonSpan)
+				_ = w.writer.createIndex(context.Background(), indexName, spanMapping, jsonSpan)
 				createService.AssertNumberOfCalls(t, "Do", 1)
 			} else {
 				assert.EqualError(t, err, testCase.expectedError)
@@ -353,7 +446,7 @@ func TestWriteSpanInternal(t *testing.T) {
 
 		jsonSpan := &json.Span{}
 
-		err := w.writer.writeSpan(indexName, jsonSpan)
+		err := w.writer.writeSpan(context.Background(), indexName, jsonSpan)
 		require.NoError(t, err)
 
 		indexService.AssertNumberOfCalls(t, "Do", 1)
@@ -361,6 +454,86 @@ func TestWriteSpanInternal(t *testing.T) {
 	})
 }
 
+func TestWriteSpanInternalBulk(t *testing.T) {
+	client := &mocks.Client{}
+	bulkService := &mocks.BulkService{}
+	bulkService.On("Add", mock.AnythingOfType("*elastic.BulkIndexRequest")).Return(bulkService)
+	bulkService.On("Do", mock.Anything).Return(&elastic.BulkResponse{}, nil)
+	client.On("Bulk").Return(bulkService)
+	writer := NewSpanWriter(client, zap.NewNop(), metrics.NullFactory, 0, 0, BulkProcessing(1, 10, 0, 0))
+
+	err := writer.writeSpan(context.Background(), "jaeger-1995-04-21", &json.Span{})
+	require.NoError(t, err)
+	bulkService.AssertNotCalled(t, "Do", mock.Anything)
+
+	require.NoError(t, writer.Close())
+	bulkService.AssertNumberOfCalls(t, "Do", 1)
+	client.AssertNotCalled(t, "Index")
+}
+
+func TestSpanWriterFlush(t *testing.T) {
+	client := &mocks.Client{}
+	bulkService := &mocks.BulkService{}
+	bulkService.On("Add", mock.AnythingOfType("*elastic.BulkIndexRequest")).Return(bulkService)
+	bulkService.On("Do", mock.Anything).Return(&elastic.BulkResponse{}, nil)
+	client.On("Bulk").Return(bulkService)
+	writer := NewSpanWriter(client, zap.NewNop(), metrics.NullFactory, 0, 0, BulkProcessing(1, 10, 0, 0))
+	defer writer.Close()
+
+	err := writer.writeSpan(context.Background(), "jaeger-1995-04-21", &json.Span{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, writer.Flush())
+	bulkService.AssertNumberOfCalls(t, "Do", 1)
+
+	assert.Equal(t, 0, NewSpanWriter(client, zap.NewNop(), metrics.NullFactory, 0, 0).Flush(), "spans are not held without bulk processing")
+}
+
+func TestWriteSpanInternalTraceIDRouting(t *testing.T) {
+	client := &mocks.Client{}
+	indexService := &mocks.IndexService{}
+	indexName := "jaeger-1995-04-21"
+	indexService.On("Index", stringMatcher(indexName)).Return(indexService)
+	indexService.On("Type", stringMatcher(spanType)).Return(indexService)
+	indexService.On("Routing", stringMatcher("1234567891234568")).Return(indexService)
+	indexService.On("BodyJson", mock.AnythingOfType("*spanstore.Span")).Return(indexService)
+	indexService.On("Do", mock.AnythingOfType("*context.emptyCtx")).Return(&elastic.IndexResponse{}, nil)
+	client.On("Index").Return(indexService)
+	writer := NewSpanWriter(client, zap.NewNop(), metrics.NullFactory, 0, 0, TraceIDRouting(true))
+
+	err := writer.writeSpan(context.Background(), indexName, &json.Span{TraceID: json.TraceID("1234567891234568")})
+	require.NoError(t, err)
+	indexService.AssertCalled(t, "Routing", "1234567891234568")
+	indexService.AssertNumberOfCalls(t, "Do", 1)
+}
+
+func TestWriteSpanInternalBulkTraceIDRouting(t *testing.T) {
+	client := &mocks.Client{}
+	bulkService := &mocks.BulkService{}
+	var requests []elastic.BulkableRequest
+	bulkService.On("Add", mock.AnythingOfType("*elastic.BulkIndexRequest")).Run(func(args mock.Arguments) {
+		requests = append(requests, args.Get(0).(elastic.BulkableRequest))
+	}).Return(bulkService)
+	bulkService.On("Do", mock.Anything).Return(&elastic.BulkResponse{}, nil)
+	client.On("Bulk").Return(bulkService)
+	writer := NewSpanWriter(client, zap.NewNop(), metrics.NullFactory, 0, 0, BulkProcessing(1, 10, 0, 0), TraceIDRouting(true))
+
+	for _, traceID := range []string{"1234567891234568", "b6dbe5b3f5d9e8651234567891234568"} {
+		err := writer.writeSpan(context.Background(), "jaeger-1995-04-21", &json.Span{TraceID: json.TraceID(traceID)})
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.Close())
+	require.Len(t, requests, 2)
+	assert.Contains(t, requests[0].String(), `"_routing":"1234567891234568"`)
+	assert.Contains(t, requests[1].String(), `"_routing":"b6dbe5b3f5d9e8651234567891234568"`)
+}
+
+func TestRoutingKey(t *testing.T) {
+	span := &json.Span{TraceID: json.TraceID("10000000000000002"), SpanID: json.SpanID("3")}
+	other := &json.Span{TraceID: json.TraceID("10000000000000002"), SpanID: json.SpanID("4")}
+	assert.Equal(t, "10000000000000002", routingKey(span))
+	assert.Equal(t, routingKey(span), routingKey(other), "the spans of a trace have the same routing key")
+}
+
 func TestWriteSpanInternalError(t *testing.T) {
 	withSpanWriter(func(w *spanWriterTest) {
 		indexService := &mocks.IndexService{}
@@ -378,7 +551,7 @@ func TestWriteSpanInternalError(t *testing.T) {
 			SpanID:  json.SpanID("0"),
 		}
 
-		err := w.writer.writeSpan(indexName, jsonSpan)
+		err := w.writer.writeSpan(context.Background(), indexName, jsonSpan)
 		assert.EqualError(t, err, "Failed to insert span: span insertion error")
 
 		indexService.AssertNumberOfCalls(t, "Do", 1)
@@ -15,6 +15,7 @@
 package integration
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -185,7 +186,7 @@ func (s *StorageIntegration) writeTraces(traces []*model.Trace) error {
 
 func (s *StorageIntegration) writeTrace(trace *model.Trace) error {
 	for _, span := range trace.Spans {
-		if err := s.spanWriter.WriteSpan(span); err != nil {
+		if err := s.spanWriter.WriteSpan(context.Background(), span); err != nil {
 			return err
 		}
 	}
@@ -0,0 +1,128 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafka
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"sync"
+	"sync/atomic"
+
+	"github.com/uber/jaeger-lib/metrics"
+
+	"github.com/uber/jaeger/model"
+)
+
+const (
+	// CompressionNone writes the marshalled spans as they are
+	CompressionNone = "none"
+	// CompressionGzip compresses each marshalled span with gzip
+	CompressionGzip = "gzip"
+)
+
+// Compressions lists the supported compressions of the marshalled spans
+var Compressions = []string{CompressionNone, CompressionGzip}
+
+// Decompress reverses the compression of a message written with NewCompressingMarshaller, so that
+// the consumers of the topic can unmarshal the span
+func Decompress(compression string, data []byte) ([]byte, error) {
+	switch compression {
+	case "", CompressionNone:
+		return data, nil
+	case CompressionGzip:
+		reader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		return ioutil.ReadAll(reader)
+	}
+	return nil, unsupportedCompressionError(compression)
+}
+
+func unsupportedCompressionError(compression string) error {
+	return fmt.Errorf("unsupported compression %q, options are %v", compression, Compressions)
+}
+
+type compressionMetrics struct {
+	// MarshalledBytes is the size of the spans before compression
+	MarshalledBytes metrics.Counter
+	// CompressedBytes is the size of the spans after compression
+	CompressedBytes metrics.Counter
+	// CompressionRatio is the size of all the spans before compression divided by their size after it, in percent
+	CompressionRatio metrics.Gauge
+}
+
+type gzipMarshaller struct {
+	marshaller Marshaller
+	writers    sync.Pool
+	metrics    compressionMetrics
+
+	marshalledBytes int64
+	compressedBytes int64
+}
+
+// NewCompressingMarshaller wraps marshaller so that the spans are compressed after they are marshalled.
+// The consumers of the topic must call Decompress with the same compression before unmarshalling them.
+func NewCompressingMarshaller(marshaller Marshaller, compression string, factory metrics.Factory) (Marshaller, error) {
+	switch compression {
+	case "", CompressionNone:
+		return marshaller, nil
+	case CompressionGzip:
+		return &gzipMarshaller{
+			marshaller: marshaller,
+			metrics: compressionMetrics{
+				MarshalledBytes:  factory.Counter("kafka.spans.bytes", map[string]string{"stage": "marshalled"}),
+				CompressedBytes:  factory.Counter("kafka.spans.bytes", map[string]string{"stage": "compressed"}),
+				CompressionRatio: factory.Gauge("kafka.spans.compression-ratio", nil),
+			},
+		}, nil
+	}
+	return nil, unsupportedCompressionError(compression)
+}
+
+// Marshal encodes a span with the wrapped marshaller and compresses it with gzip
+func (m *gzipMarshaller) Marshal(span *model.Span) ([]byte, error) {
+	spanBytes, err := m.marshaller.Marshal(span)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	writer, ok := m.writers.Get().(*gzip.Writer)
+	if ok {
+		writer.Reset(&buf)
+	} else {
+		writer = gzip.NewWriter(&buf)
+	}
+	defer m.writers.Put(writer)
+	if _, err := writer.Write(spanBytes); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	m.record(len(spanBytes), buf.Len())
+	return buf.Bytes(), nil
+}
+
+func (m *gzipMarshaller) record(marshalled, compressed int) {
+	m.metrics.MarshalledBytes.Inc(int64(marshalled))
+	m.metrics.CompressedBytes.Inc(int64(compressed))
+	marshalledTotal := atomic.AddInt64(&m.marshalledBytes, int64(marshalled))
+	compressedTotal := atomic.AddInt64(&m.compressedBytes, int64(compressed))
+	m.metrics.CompressionRatio.Update(100 * marshalledTotal / compressedTotal)
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafka
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics"
+
+	"github.com/uber/jaeger/model"
+)
+
+type failingMarshaller struct{}
+
+func (failingMarshaller) Marshal(*model.Span) ([]byte, error) {
+	return nil, errors.New("marshalling failed")
+}
+
+func TestCompressingMarshallerRoundTrip(t *testing.T) {
+	for _, compression := range Compressions {
+		for _, marshaller := range []Marshaller{NewJSONMarshaller(), NewThriftMarshaller()} {
+			expected, err := marshaller.Marshal(testSpan)
+			require.NoError(t, err)
+
+			compressing, err := NewCompressingMarshaller(marshaller, compression, metrics.NullFactory)
+			require.NoError(t, err)
+			// marshal twice to check that the compressors are reused correctly
+			for i := 0; i < 2; i++ {
+				compressed, err := compressing.Marshal(testSpan)
+				require.NoError(t, err, compression)
+				decompressed, err := Decompress(compression, compressed)
+				require.NoError(t, err, compression)
+				assert.Equal(t, expected, decompressed, compression)
+			}
+		}
+	}
+}
+
+func TestCompressingMarshallerMetrics(t *testing.T) {
+	mf := metrics.NewLocalFactory(time.Hour)
+	marshaller, err := NewCompressingMarshaller(NewJSONMarshaller(), CompressionGzip, mf)
+	require.NoError(t, err)
+	uncompressed, err := NewJSONMarshaller().Marshal(testSpan)
+	require.NoError(t, err)
+	compressed, err := marshaller.Marshal(testSpan)
+	require.NoError(t, err)
+
+	counters, gauges := mf.Snapshot()
+	assert.EqualValues(t, len(uncompressed), counters["kafka.spans.bytes|stage=marshalled"])
+	assert.EqualValues(t, len(compressed), counters["kafka.spans.bytes|stage=compressed"])
+	assert.EqualValues(t, 100*len(uncompressed)/len(compressed), gauges["kafka.spans.compression-ratio"])
+}
+
+func TestCompressingMarshallerErrors(t *testing.T) {
+	_, err := NewCompressingMarshaller(NewJSONMarshaller(), "zstd", metrics.NullFactory)
+	assert.EqualError(t, err, `unsupported compression "zstd", options are [none gzip]`)
+
+	marshaller, err := NewCompressingMarshaller(failingMarshaller{}, CompressionGzip, metrics.NullFactory)
+	require.NoError(t, err)
+	_, err = marshaller.Marshal(testSpan)
+	assert.EqualError(t, err, "marshalling failed")
+
+	_, err = Decompress(CompressionGzip, []byte("not gzip"))
+	assert.Error(t, err)
+	_, err = Decompress("zstd", nil)
+	assert.Error(t, err)
+}
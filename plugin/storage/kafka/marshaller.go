@@ -0,0 +1,73 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafka
+
+import (
+	"encoding/json"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/golang/protobuf/proto"
+
+	"github.com/uber/jaeger/model"
+	jConverter "github.com/uber/jaeger/model/converter/json"
+	pConverter "github.com/uber/jaeger/model/converter/proto/jaeger"
+	tConverter "github.com/uber/jaeger/model/converter/thrift/jaeger"
+	"github.com/uber/jaeger/thrift-gen/jaeger"
+)
+
+// Marshaller encodes a span into a byte array to be sent to Kafka
+type Marshaller interface {
+	Marshal(*model.Span) ([]byte, error)
+}
+
+type jsonMarshaller struct{}
+
+// NewJSONMarshaller constructs a Marshaller that encodes spans as JSON with the process embedded
+func NewJSONMarshaller() Marshaller {
+	return &jsonMarshaller{}
+}
+
+// Marshal encodes a span as JSON
+func (h *jsonMarshaller) Marshal(span *model.Span) ([]byte, error) {
+	return json.Marshal(jConverter.FromDomainEmbedProcess(span))
+}
+
+type thriftMarshaller struct{}
+
+// NewThriftMarshaller constructs a Marshaller that encodes spans as a single span jaeger.thrift Batch
+func NewThriftMarshaller() Marshaller {
+	return &thriftMarshaller{}
+}
+
+// Marshal encodes a span as a jaeger.thrift Batch
+func (h *thriftMarshaller) Marshal(span *model.Span) ([]byte, error) {
+	batch := &jaeger.Batch{
+		Process: tConverter.FromDomainProcess(span.Process),
+		Spans:   []*jaeger.Span{tConverter.FromDomainSpan(span)},
+	}
+	return thrift.NewTSerializer().Write(batch)
+}
+
+type protobufMarshaller struct{}
+
+// NewProtobufMarshaller constructs a Marshaller that encodes spans as a single span Batch of the Protobuf Jaeger model
+func NewProtobufMarshaller() Marshaller {
+	return &protobufMarshaller{}
+}
+
+// Marshal encodes a span as a Protobuf Batch
+func (h *protobufMarshaller) Marshal(span *model.Span) ([]byte, error) {
+	return proto.Marshal(pConverter.FromDomain([]*model.Span{span}))
+}
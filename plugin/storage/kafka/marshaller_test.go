@@ -0,0 +1,71 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafka
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	jConverter "github.com/uber/jaeger/model/converter/json"
+	pConverter "github.com/uber/jaeger/model/converter/proto/jaeger"
+	tConverter "github.com/uber/jaeger/model/converter/thrift/jaeger"
+	jModel "github.com/uber/jaeger/model/json"
+	pJaeger "github.com/uber/jaeger/proto-gen/jaeger"
+	"github.com/uber/jaeger/thrift-gen/jaeger"
+)
+
+func TestJSONMarshaller(t *testing.T) {
+	bytes, err := NewJSONMarshaller().Marshal(testSpan)
+	require.NoError(t, err)
+
+	var span jModel.Span
+	require.NoError(t, json.Unmarshal(bytes, &span))
+	newSpan, err := jConverter.SpanToDomain(&span)
+	require.NoError(t, err)
+	assert.Equal(t, testSpan.TraceID, newSpan.TraceID)
+	assert.Equal(t, testSpan.OperationName, newSpan.OperationName)
+	assert.Equal(t, testSpan.Process, newSpan.Process)
+}
+
+func TestProtobufMarshaller(t *testing.T) {
+	bytes, err := NewProtobufMarshaller().Marshal(testSpan)
+	require.NoError(t, err)
+
+	batch := &pJaeger.Batch{}
+	require.NoError(t, proto.Unmarshal(bytes, batch))
+	spans := pConverter.ToDomain(batch)
+	require.Len(t, spans, 1)
+	assert.Equal(t, testSpan.TraceID, spans[0].TraceID)
+	assert.Equal(t, testSpan.OperationName, spans[0].OperationName)
+	assert.Equal(t, testSpan.Process, spans[0].Process)
+}
+
+func TestThriftMarshaller(t *testing.T) {
+	bytes, err := NewThriftMarshaller().Marshal(testSpan)
+	require.NoError(t, err)
+
+	batch := &jaeger.Batch{}
+	require.NoError(t, thrift.NewTDeserializer().Read(batch, bytes))
+	require.Len(t, batch.Spans, 1)
+	newSpan := tConverter.ToDomainSpan(batch.Spans[0], batch.Process)
+	assert.Equal(t, testSpan.TraceID, newSpan.TraceID)
+	assert.Equal(t, testSpan.OperationName, newSpan.OperationName)
+	assert.Equal(t, testSpan.Process, newSpan.Process)
+}
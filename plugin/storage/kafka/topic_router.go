@@ -0,0 +1,113 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafka
+
+import (
+	"regexp"
+	"sync"
+
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/uber/jaeger/model"
+)
+
+const maxTopicNameLength = 249
+
+var topicNamePattern = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
+
+type topicRouterMetrics struct {
+	Topics            metrics.Gauge
+	InvalidFallbacks  metrics.Counter
+	OverflowFallbacks metrics.Counter
+}
+
+// topicRouter picks the topic of each span from the value of its tag, capping the number of
+// topics so that a tag with unbounded values does not create unbounded topics.
+type topicRouter struct {
+	tag          string
+	defaultTopic string
+	maxTopics    int
+	metrics      topicRouterMetrics
+	logger       *zap.Logger
+
+	sync.RWMutex
+	topics map[string]struct{}
+}
+
+func newTopicRouter(tag, defaultTopic string, maxTopics int, factory metrics.Factory, logger *zap.Logger) *topicRouter {
+	return &topicRouter{
+		tag:          tag,
+		defaultTopic: defaultTopic,
+		maxTopics:    maxTopics,
+		metrics: topicRouterMetrics{
+			Topics:            factory.Gauge("kafka.topics", nil),
+			InvalidFallbacks:  factory.Counter("kafka.topics.fallback", map[string]string{"reason": "invalid"}),
+			OverflowFallbacks: factory.Counter("kafka.topics.fallback", map[string]string{"reason": "overflow"}),
+		},
+		logger: logger,
+		topics: make(map[string]struct{}),
+	}
+}
+
+// topic returns the topic of the span, which is the default topic for spans without the tag, with a value
+// that is not valid in a topic name, or with a new value once the maximum number of topics is in use.
+func (r *topicRouter) topic(span *model.Span) string {
+	value := r.tagValue(span)
+	if value == "" {
+		return r.defaultTopic
+	}
+	topic := r.defaultTopic + "-" + value
+	if len(topic) > maxTopicNameLength || !topicNamePattern.MatchString(topic) {
+		r.metrics.InvalidFallbacks.Inc(1)
+		return r.defaultTopic
+	}
+
+	r.RLock()
+	_, ok := r.topics[topic]
+	r.RUnlock()
+	if ok {
+		return topic
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	if _, ok := r.topics[topic]; ok {
+		return topic
+	}
+	if len(r.topics) >= r.maxTopics {
+		r.metrics.OverflowFallbacks.Inc(1)
+		return r.defaultTopic
+	}
+	r.topics[topic] = struct{}{}
+	r.metrics.Topics.Update(int64(len(r.topics)))
+	if len(r.topics) == r.maxTopics {
+		r.logger.Warn("Reached the maximum number of Kafka topics, spans with new tag values are produced to the default topic",
+			zap.Int("max-topics", r.maxTopics), zap.String("topic", r.defaultTopic))
+	}
+	return topic
+}
+
+func (r *topicRouter) tagValue(span *model.Span) string {
+	if kv, ok := span.Tags.FindByKey(r.tag); ok {
+		return kv.AsString()
+	}
+	if span.Process != nil {
+		if kv, ok := span.Process.Tags.FindByKey(r.tag); ok {
+			return kv.AsString()
+		}
+	}
+	return ""
+}
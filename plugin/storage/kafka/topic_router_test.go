@@ -0,0 +1,73 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafka
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/pkg/testutils"
+)
+
+func spanWithTenant(tenant string) *model.Span {
+	return &model.Span{
+		Tags:    []model.KeyValue{model.String("tenant", tenant)},
+		Process: &model.Process{ServiceName: "someServiceName"},
+	}
+}
+
+func TestTopicRouter(t *testing.T) {
+	metricsFactory := metrics.NewLocalFactory(0)
+	router := newTopicRouter("tenant", "jaeger-spans", 2, metricsFactory, zap.NewNop())
+
+	assert.Equal(t, "jaeger-spans-acme", router.topic(spanWithTenant("acme")))
+	assert.Equal(t, "jaeger-spans-acme", router.topic(spanWithTenant("acme")))
+	assert.Equal(t, "jaeger-spans-initech", router.topic(spanWithTenant("initech")))
+
+	processTagged := &model.Span{Process: &model.Process{Tags: []model.KeyValue{model.String("tenant", "initech")}}}
+	assert.Equal(t, "jaeger-spans-initech", router.topic(processTagged))
+	assert.Equal(t, "jaeger-spans", router.topic(testSpan))
+	assert.Equal(t, "jaeger-spans", router.topic(&model.Span{}))
+
+	counters, gauges := metricsFactory.Snapshot()
+	assert.EqualValues(t, 2, gauges["kafka.topics"])
+	assert.EqualValues(t, 0, counters["kafka.topics.fallback|reason=overflow"])
+}
+
+func TestTopicRouterFallback(t *testing.T) {
+	metricsFactory := metrics.NewLocalFactory(0)
+	logger, logBuffer := testutils.NewLogger()
+	router := newTopicRouter("tenant", "jaeger-spans", 1, metricsFactory, logger)
+
+	assert.Equal(t, "jaeger-spans-acme", router.topic(spanWithTenant("acme")))
+	assert.Contains(t, logBuffer.String(), "Reached the maximum number of Kafka topics")
+	assert.Equal(t, "jaeger-spans", router.topic(spanWithTenant("initech")))
+	assert.Equal(t, "jaeger-spans", router.topic(spanWithTenant("initech")))
+	assert.Equal(t, "jaeger-spans-acme", router.topic(spanWithTenant("acme")))
+
+	assert.Equal(t, "jaeger-spans", router.topic(spanWithTenant("acme corp")))
+	assert.Equal(t, "jaeger-spans", router.topic(spanWithTenant("acme/corp")))
+	assert.Equal(t, "jaeger-spans", router.topic(spanWithTenant(strings.Repeat("a", 250))))
+
+	counters, gauges := metricsFactory.Snapshot()
+	assert.EqualValues(t, 1, gauges["kafka.topics"])
+	assert.EqualValues(t, 2, counters["kafka.topics.fallback|reason=overflow"])
+	assert.EqualValues(t, 3, counters["kafka.topics.fallback|reason=invalid"])
+}
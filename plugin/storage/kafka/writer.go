@@ -0,0 +1,114 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafka
+
+import (
+	"context"
+
+	"github.com/Shopify/sarama"
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/uber/jaeger/model"
+)
+
+type spanWriterMetrics struct {
+	SpansWrittenSuccess metrics.Counter
+	SpansWrittenFailure metrics.Counter
+}
+
+// SpanWriter writes spans to a Kafka topic. Writes are asynchronous, so delivery failures
+// are not returned from WriteSpan but are logged and counted instead.
+type SpanWriter struct {
+	metrics    spanWriterMetrics
+	producer   sarama.AsyncProducer
+	marshaller Marshaller
+	topic      string
+	router     *topicRouter
+}
+
+// NewSpanWriter initiates and returns a new kafka SpanWriter
+func NewSpanWriter(
+	producer sarama.AsyncProducer,
+	marshaller Marshaller,
+	topic string,
+	factory metrics.Factory,
+	logger *zap.Logger,
+	options ...Option,
+) *SpanWriter {
+	opts := applyOptions(options...)
+	writeMetrics := spanWriterMetrics{
+		SpansWrittenSuccess: factory.Counter("kafka.spans.written", map[string]string{"status": "success"}),
+		SpansWrittenFailure: factory.Counter("kafka.spans.written", map[string]string{"status": "failure"}),
+	}
+
+	go func() {
+		for range producer.Successes() {
+			writeMetrics.SpansWrittenSuccess.Inc(1)
+		}
+	}()
+	go func() {
+		for e := range producer.Errors() {
+			if e != nil && e.Err != nil {
+				logger.Error(e.Err.Error())
+			}
+			writeMetrics.SpansWrittenFailure.Inc(1)
+		}
+	}()
+
+	writer := &SpanWriter{
+		producer:   producer,
+		marshaller: marshaller,
+		topic:      topic,
+		metrics:    writeMetrics,
+	}
+	if opts.topicTag != "" {
+		writer.router = newTopicRouter(opts.topicTag, topic, opts.maxTopics, factory, logger)
+	}
+	return writer
+}
+
+// WriteSpan writes the span to kafka. It gives up when ctx is cancelled while the producer's input is full.
+func (w *SpanWriter) WriteSpan(ctx context.Context, span *model.Span) error {
+	spanBytes, err := w.marshaller.Marshal(span)
+	if err != nil {
+		w.metrics.SpansWrittenFailure.Inc(1)
+		return err
+	}
+
+	// The AsyncProducer accepts messages on a channel and produces them asynchronously
+	// in the background as efficiently as possible
+	topic := w.topic
+	if w.router != nil {
+		topic = w.router.topic(span)
+	}
+	message := &sarama.ProducerMessage{
+		Topic: topic,
+		Key:   sarama.StringEncoder(span.TraceID.String()),
+		Value: sarama.ByteEncoder(spanBytes),
+	}
+	select {
+	case w.producer.Input() <- message:
+		return nil
+	case <-ctx.Done():
+		w.metrics.SpansWrittenFailure.Inc(1)
+		return ctx.Err()
+	}
+}
+
+// Close flushes the buffered messages and closes the producer
+func (w *SpanWriter) Close() error {
+	return w.producer.Close()
+}
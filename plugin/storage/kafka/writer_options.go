@@ -0,0 +1,42 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafka
+
+// Option is a function that sets some option on the writer.
+type Option func(o *Options)
+
+// Options control behavior of the writer.
+type Options struct {
+	topicTag  string
+	maxTopics int
+}
+
+// TopicTag routes the spans with tag to the topic named after the writer's topic and the tag's value,
+// e.g. jaeger-spans-acme. Once maxTopics topics are in use, spans with new values are produced to the
+// writer's topic, as are the spans without the tag.
+func TopicTag(tag string, maxTopics int) Option {
+	return func(o *Options) {
+		o.topicTag = tag
+		o.maxTopics = maxTopics
+	}
+}
+
+func applyOptions(opts ...Option) Options {
+	o := Options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
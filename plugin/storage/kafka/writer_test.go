@@ -0,0 +1,181 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafka
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	saramaMocks "github.com/Shopify/sarama/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/uber/jaeger/model"
+)
+
+var (
+	sTags = []model.KeyValue{
+		model.String("someStringTagKey", "someStringTagValue"),
+		model.Int64("someInt64TagKey", 123),
+	}
+
+	testSpan = &model.Span{
+		TraceID:       model.TraceID{Low: 1, High: 2},
+		SpanID:        model.SpanID(3),
+		OperationName: "someOperationName",
+		Flags:         model.Flags(4),
+		StartTime:     time.Unix(0, 5000),
+		Duration:      6 * time.Microsecond,
+		Tags:          sTags,
+		Process: &model.Process{
+			ServiceName: "someServiceName",
+			Tags:        sTags,
+		},
+	}
+)
+
+type spanWriterTest struct {
+	producer       *saramaMocks.AsyncProducer
+	metricsFactory *metrics.LocalFactory
+	writer         *SpanWriter
+}
+
+func withSpanWriter(t *testing.T, marshaller Marshaller, fn func(w *spanWriterTest)) {
+	saramaConfig := saramaMocks.NewTestConfig()
+	saramaConfig.Producer.Return.Successes = true
+	producer := saramaMocks.NewAsyncProducer(t, saramaConfig)
+	metricsFactory := metrics.NewLocalFactory(0)
+	w := &spanWriterTest{
+		producer:       producer,
+		metricsFactory: metricsFactory,
+		writer:         NewSpanWriter(producer, marshaller, "someTopic", metricsFactory, zap.NewNop()),
+	}
+	fn(w)
+}
+
+func waitForCounter(t *testing.T, factory *metrics.LocalFactory, name string, expected int64) {
+	for i := 0; i < 1000; i++ {
+		counters, _ := factory.Snapshot()
+		if counters[name] == expected {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	counters, _ := factory.Snapshot()
+	assert.Equal(t, expected, counters[name], name)
+}
+
+func TestKafkaWriter(t *testing.T) {
+	withSpanWriter(t, NewJSONMarshaller(), func(w *spanWriterTest) {
+		w.producer.ExpectInputAndSucceed()
+
+		err := w.writer.WriteSpan(context.Background(), testSpan)
+		require.NoError(t, err)
+
+		waitForCounter(t, w.metricsFactory, "kafka.spans.written|status=success", 1)
+		require.NoError(t, w.writer.Close())
+	})
+}
+
+func TestKafkaWriterErr(t *testing.T) {
+	withSpanWriter(t, NewThriftMarshaller(), func(w *spanWriterTest) {
+		w.producer.ExpectInputAndFail(sarama.ErrRequestTimedOut)
+
+		err := w.writer.WriteSpan(context.Background(), testSpan)
+		require.NoError(t, err, "write errors are reported asynchronously")
+
+		waitForCounter(t, w.metricsFactory, "kafka.spans.written|status=failure", 1)
+		require.NoError(t, w.writer.Close())
+	})
+}
+
+type failingMarshaller struct{}
+
+func (failingMarshaller) Marshal(*model.Span) ([]byte, error) {
+	return nil, errors.New("oops")
+}
+
+func TestKafkaWriterMarshallerErr(t *testing.T) {
+	withSpanWriter(t, failingMarshaller{}, func(w *spanWriterTest) {
+		err := w.writer.WriteSpan(context.Background(), testSpan)
+		assert.EqualError(t, err, "oops")
+
+		counters, _ := w.metricsFactory.Snapshot()
+		assert.EqualValues(t, 1, counters["kafka.spans.written|status=failure"])
+		require.NoError(t, w.writer.Close())
+	})
+}
+
+// blockedProducer is an AsyncProducer whose input is never consumed
+type blockedProducer struct {
+	sarama.AsyncProducer
+	input chan *sarama.ProducerMessage
+}
+
+func (p *blockedProducer) Input() chan<- *sarama.ProducerMessage { return p.input }
+
+func (p *blockedProducer) Successes() <-chan *sarama.ProducerMessage {
+	ch := make(chan *sarama.ProducerMessage)
+	close(ch)
+	return ch
+}
+
+func (p *blockedProducer) Errors() <-chan *sarama.ProducerError {
+	ch := make(chan *sarama.ProducerError)
+	close(ch)
+	return ch
+}
+
+func TestKafkaWriterContextCancelled(t *testing.T) {
+	metricsFactory := metrics.NewLocalFactory(0)
+	producer := &blockedProducer{input: make(chan *sarama.ProducerMessage)}
+	writer := NewSpanWriter(producer, NewJSONMarshaller(), "someTopic", metricsFactory, zap.NewNop())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := writer.WriteSpan(ctx, testSpan)
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	counters, _ := metricsFactory.Snapshot()
+	assert.EqualValues(t, 1, counters["kafka.spans.written|status=failure"])
+}
+
+func TestKafkaWriterTopicTag(t *testing.T) {
+	producer := &blockedProducer{input: make(chan *sarama.ProducerMessage, 3)}
+	writer := NewSpanWriter(
+		producer,
+		NewJSONMarshaller(),
+		"someTopic",
+		metrics.NullFactory,
+		zap.NewNop(),
+		TopicTag("tenant", 1),
+	)
+
+	for _, tenant := range []string{"acme", "initech"} {
+		span := *testSpan
+		span.Tags = []model.KeyValue{model.String("tenant", tenant)}
+		require.NoError(t, writer.WriteSpan(context.Background(), &span))
+	}
+	require.NoError(t, writer.WriteSpan(context.Background(), testSpan))
+
+	assert.Equal(t, "someTopic-acme", (<-producer.input).Topic)
+	assert.Equal(t, "someTopic", (<-producer.input).Topic, "spans over the maximum number of topics fall back to the topic")
+	assert.Equal(t, "someTopic", (<-producer.input).Topic, "spans without the tag are produced to the topic")
+}
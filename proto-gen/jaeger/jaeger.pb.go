@@ -0,0 +1,435 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: jaeger.proto
+
+/*
+Package jaeger is a generated protocol buffer package.
+
+It is generated from these files:
+
+	jaeger.proto
+
+It has these top-level messages:
+
+	Batch
+	Process
+	Span
+	SpanRef
+	Log
+	KeyValue
+*/
+package jaeger
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
+
+type SpanRefType int32
+
+const (
+	SpanRefType_CHILD_OF     SpanRefType = 0
+	SpanRefType_FOLLOWS_FROM SpanRefType = 1
+)
+
+var SpanRefType_name = map[int32]string{
+	0: "CHILD_OF",
+	1: "FOLLOWS_FROM",
+}
+var SpanRefType_value = map[string]int32{
+	"CHILD_OF":     0,
+	"FOLLOWS_FROM": 1,
+}
+
+func (x SpanRefType) String() string {
+	return proto.EnumName(SpanRefType_name, int32(x))
+}
+func (SpanRefType) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+
+type ValueType int32
+
+const (
+	ValueType_STRING  ValueType = 0
+	ValueType_BOOL    ValueType = 1
+	ValueType_INT64   ValueType = 2
+	ValueType_FLOAT64 ValueType = 3
+	ValueType_BINARY  ValueType = 4
+)
+
+var ValueType_name = map[int32]string{
+	0: "STRING",
+	1: "BOOL",
+	2: "INT64",
+	3: "FLOAT64",
+	4: "BINARY",
+}
+var ValueType_value = map[string]int32{
+	"STRING":  0,
+	"BOOL":    1,
+	"INT64":   2,
+	"FLOAT64": 3,
+	"BINARY":  4,
+}
+
+func (x ValueType) String() string {
+	return proto.EnumName(ValueType_name, int32(x))
+}
+func (ValueType) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
+// Batch is a collection of spans reported by a single process, the Protobuf
+// counterpart of the Batch struct in jaeger.thrift.
+type Batch struct {
+	Process *Process `protobuf:"bytes,1,opt,name=process" json:"process,omitempty"`
+	Spans   []*Span  `protobuf:"bytes,2,rep,name=spans" json:"spans,omitempty"`
+}
+
+func (m *Batch) Reset()                    { *m = Batch{} }
+func (m *Batch) String() string            { return proto.CompactTextString(m) }
+func (*Batch) ProtoMessage()               {}
+func (*Batch) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+
+func (m *Batch) GetProcess() *Process {
+	if m != nil {
+		return m.Process
+	}
+	return nil
+}
+
+func (m *Batch) GetSpans() []*Span {
+	if m != nil {
+		return m.Spans
+	}
+	return nil
+}
+
+// Process describes the traced process that emitted the spans.
+type Process struct {
+	ServiceName string      `protobuf:"bytes,1,opt,name=service_name,json=serviceName" json:"service_name,omitempty"`
+	Tags        []*KeyValue `protobuf:"bytes,2,rep,name=tags" json:"tags,omitempty"`
+}
+
+func (m *Process) Reset()                    { *m = Process{} }
+func (m *Process) String() string            { return proto.CompactTextString(m) }
+func (*Process) ProtoMessage()               {}
+func (*Process) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
+func (m *Process) GetServiceName() string {
+	if m != nil {
+		return m.ServiceName
+	}
+	return ""
+}
+
+func (m *Process) GetTags() []*KeyValue {
+	if m != nil {
+		return m.Tags
+	}
+	return nil
+}
+
+// Span represents a named unit of work performed by a service.
+type Span struct {
+	TraceIdLow    uint64     `protobuf:"varint,1,opt,name=trace_id_low,json=traceIdLow" json:"trace_id_low,omitempty"`
+	TraceIdHigh   uint64     `protobuf:"varint,2,opt,name=trace_id_high,json=traceIdHigh" json:"trace_id_high,omitempty"`
+	SpanId        uint64     `protobuf:"varint,3,opt,name=span_id,json=spanId" json:"span_id,omitempty"`
+	ParentSpanId  uint64     `protobuf:"varint,4,opt,name=parent_span_id,json=parentSpanId" json:"parent_span_id,omitempty"`
+	OperationName string     `protobuf:"bytes,5,opt,name=operation_name,json=operationName" json:"operation_name,omitempty"`
+	References    []*SpanRef `protobuf:"bytes,6,rep,name=references" json:"references,omitempty"`
+	Flags         uint32     `protobuf:"varint,7,opt,name=flags" json:"flags,omitempty"`
+	// start_time is the number of microseconds since the Unix epoch
+	StartTime int64 `protobuf:"varint,8,opt,name=start_time,json=startTime" json:"start_time,omitempty"`
+	// duration is in microseconds
+	Duration int64       `protobuf:"varint,9,opt,name=duration" json:"duration,omitempty"`
+	Tags     []*KeyValue `protobuf:"bytes,10,rep,name=tags" json:"tags,omitempty"`
+	Logs     []*Log      `protobuf:"bytes,11,rep,name=logs" json:"logs,omitempty"`
+	// process overrides the process of the batch for this span when set
+	Process *Process `protobuf:"bytes,12,opt,name=process" json:"process,omitempty"`
+}
+
+func (m *Span) Reset()                    { *m = Span{} }
+func (m *Span) String() string            { return proto.CompactTextString(m) }
+func (*Span) ProtoMessage()               {}
+func (*Span) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+
+func (m *Span) GetTraceIdLow() uint64 {
+	if m != nil {
+		return m.TraceIdLow
+	}
+	return 0
+}
+
+func (m *Span) GetTraceIdHigh() uint64 {
+	if m != nil {
+		return m.TraceIdHigh
+	}
+	return 0
+}
+
+func (m *Span) GetSpanId() uint64 {
+	if m != nil {
+		return m.SpanId
+	}
+	return 0
+}
+
+func (m *Span) GetParentSpanId() uint64 {
+	if m != nil {
+		return m.ParentSpanId
+	}
+	return 0
+}
+
+func (m *Span) GetOperationName() string {
+	if m != nil {
+		return m.OperationName
+	}
+	return ""
+}
+
+func (m *Span) GetReferences() []*SpanRef {
+	if m != nil {
+		return m.References
+	}
+	return nil
+}
+
+func (m *Span) GetFlags() uint32 {
+	if m != nil {
+		return m.Flags
+	}
+	return 0
+}
+
+func (m *Span) GetStartTime() int64 {
+	if m != nil {
+		return m.StartTime
+	}
+	return 0
+}
+
+func (m *Span) GetDuration() int64 {
+	if m != nil {
+		return m.Duration
+	}
+	return 0
+}
+
+func (m *Span) GetTags() []*KeyValue {
+	if m != nil {
+		return m.Tags
+	}
+	return nil
+}
+
+func (m *Span) GetLogs() []*Log {
+	if m != nil {
+		return m.Logs
+	}
+	return nil
+}
+
+func (m *Span) GetProcess() *Process {
+	if m != nil {
+		return m.Process
+	}
+	return nil
+}
+
+// SpanRef describes a causal relationship of the span with another span.
+type SpanRef struct {
+	RefType     SpanRefType `protobuf:"varint,1,opt,name=ref_type,json=refType,enum=jaeger.SpanRefType" json:"ref_type,omitempty"`
+	TraceIdLow  uint64      `protobuf:"varint,2,opt,name=trace_id_low,json=traceIdLow" json:"trace_id_low,omitempty"`
+	TraceIdHigh uint64      `protobuf:"varint,3,opt,name=trace_id_high,json=traceIdHigh" json:"trace_id_high,omitempty"`
+	SpanId      uint64      `protobuf:"varint,4,opt,name=span_id,json=spanId" json:"span_id,omitempty"`
+}
+
+func (m *SpanRef) Reset()                    { *m = SpanRef{} }
+func (m *SpanRef) String() string            { return proto.CompactTextString(m) }
+func (*SpanRef) ProtoMessage()               {}
+func (*SpanRef) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+
+func (m *SpanRef) GetRefType() SpanRefType {
+	if m != nil {
+		return m.RefType
+	}
+	return SpanRefType_CHILD_OF
+}
+
+func (m *SpanRef) GetTraceIdLow() uint64 {
+	if m != nil {
+		return m.TraceIdLow
+	}
+	return 0
+}
+
+func (m *SpanRef) GetTraceIdHigh() uint64 {
+	if m != nil {
+		return m.TraceIdHigh
+	}
+	return 0
+}
+
+func (m *SpanRef) GetSpanId() uint64 {
+	if m != nil {
+		return m.SpanId
+	}
+	return 0
+}
+
+// Log is a set of fields recorded at a point in time during the span.
+type Log struct {
+	// timestamp is the number of microseconds since the Unix epoch
+	Timestamp int64       `protobuf:"varint,1,opt,name=timestamp" json:"timestamp,omitempty"`
+	Fields    []*KeyValue `protobuf:"bytes,2,rep,name=fields" json:"fields,omitempty"`
+}
+
+func (m *Log) Reset()                    { *m = Log{} }
+func (m *Log) String() string            { return proto.CompactTextString(m) }
+func (*Log) ProtoMessage()               {}
+func (*Log) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+
+func (m *Log) GetTimestamp() int64 {
+	if m != nil {
+		return m.Timestamp
+	}
+	return 0
+}
+
+func (m *Log) GetFields() []*KeyValue {
+	if m != nil {
+		return m.Fields
+	}
+	return nil
+}
+
+// KeyValue is a typed tag or log field, only the value matching v_type is set.
+type KeyValue struct {
+	Key      string    `protobuf:"bytes,1,opt,name=key" json:"key,omitempty"`
+	VType    ValueType `protobuf:"varint,2,opt,name=v_type,json=vType,enum=jaeger.ValueType" json:"v_type,omitempty"`
+	VStr     string    `protobuf:"bytes,3,opt,name=v_str,json=vStr" json:"v_str,omitempty"`
+	VBool    bool      `protobuf:"varint,4,opt,name=v_bool,json=vBool" json:"v_bool,omitempty"`
+	VInt64   int64     `protobuf:"varint,5,opt,name=v_int64,json=vInt64" json:"v_int64,omitempty"`
+	VFloat64 float64   `protobuf:"fixed64,6,opt,name=v_float64,json=vFloat64" json:"v_float64,omitempty"`
+	VBinary  []byte    `protobuf:"bytes,7,opt,name=v_binary,json=vBinary,proto3" json:"v_binary,omitempty"`
+}
+
+func (m *KeyValue) Reset()                    { *m = KeyValue{} }
+func (m *KeyValue) String() string            { return proto.CompactTextString(m) }
+func (*KeyValue) ProtoMessage()               {}
+func (*KeyValue) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+
+func (m *KeyValue) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *KeyValue) GetVType() ValueType {
+	if m != nil {
+		return m.VType
+	}
+	return ValueType_STRING
+}
+
+func (m *KeyValue) GetVStr() string {
+	if m != nil {
+		return m.VStr
+	}
+	return ""
+}
+
+func (m *KeyValue) GetVBool() bool {
+	if m != nil {
+		return m.VBool
+	}
+	return false
+}
+
+func (m *KeyValue) GetVInt64() int64 {
+	if m != nil {
+		return m.VInt64
+	}
+	return 0
+}
+
+func (m *KeyValue) GetVFloat64() float64 {
+	if m != nil {
+		return m.VFloat64
+	}
+	return 0
+}
+
+func (m *KeyValue) GetVBinary() []byte {
+	if m != nil {
+		return m.VBinary
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*Batch)(nil), "jaeger.Batch")
+	proto.RegisterType((*Process)(nil), "jaeger.Process")
+	proto.RegisterType((*Span)(nil), "jaeger.Span")
+	proto.RegisterType((*SpanRef)(nil), "jaeger.SpanRef")
+	proto.RegisterType((*Log)(nil), "jaeger.Log")
+	proto.RegisterType((*KeyValue)(nil), "jaeger.KeyValue")
+	proto.RegisterEnum("jaeger.SpanRefType", SpanRefType_name, SpanRefType_value)
+	proto.RegisterEnum("jaeger.ValueType", ValueType_name, ValueType_value)
+}
+
+func init() { proto.RegisterFile("jaeger.proto", fileDescriptor0) }
+
+var fileDescriptor0 = []byte{
+	// 645 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x84, 0x54, 0xdb, 0x6e, 0xd3, 0x4c,
+	0x10, 0xae, 0xe3, 0xf3, 0xd8, 0xed, 0xef, 0x7f, 0x0b, 0xc2, 0x9c, 0x44, 0xb0, 0x8a, 0x14, 0x2a,
+	0x51, 0xa4, 0x52, 0xf5, 0xbe, 0x01, 0x42, 0x23, 0xdc, 0x04, 0x6d, 0xa2, 0x22, 0xb8, 0xb1, 0xb6,
+	0xc9, 0xc6, 0x31, 0x38, 0x5e, 0x6b, 0xed, 0xba, 0xca, 0x9b, 0xf0, 0x04, 0xbc, 0x08, 0x2f, 0x86,
+	0x76, 0x6d, 0x87, 0x08, 0x44, 0xb9, 0xb2, 0xbf, 0xc3, 0x8e, 0xf7, 0x9b, 0x19, 0x19, 0xdc, 0x2f,
+	0x84, 0xc6, 0x94, 0x1f, 0xe5, 0x9c, 0x95, 0x0c, 0x19, 0x35, 0x0a, 0x2e, 0x41, 0xef, 0x93, 0x72,
+	0xb6, 0x44, 0xcf, 0xc1, 0xcc, 0x39, 0x9b, 0xd1, 0xa2, 0xf0, 0x95, 0xae, 0xd2, 0x73, 0x8e, 0xff,
+	0x3b, 0x6a, 0x0e, 0x7c, 0xa8, 0x69, 0xdc, 0xea, 0x28, 0x00, 0xbd, 0xc8, 0x49, 0x56, 0xf8, 0x9d,
+	0xae, 0xda, 0x73, 0x8e, 0xdd, 0xd6, 0x38, 0xc9, 0x49, 0x86, 0x6b, 0x29, 0xc0, 0x60, 0x36, 0xe7,
+	0xd0, 0x53, 0x70, 0x0b, 0xca, 0xab, 0x64, 0x46, 0xa3, 0x8c, 0xac, 0xa8, 0x2c, 0x6f, 0x63, 0xa7,
+	0xe1, 0x46, 0x64, 0x45, 0xd1, 0x01, 0x68, 0x25, 0x89, 0xdb, 0x82, 0x5e, 0x5b, 0xf0, 0x3d, 0x5d,
+	0x5f, 0x92, 0xf4, 0x9a, 0x62, 0xa9, 0x06, 0xdf, 0x55, 0xd0, 0xc4, 0x37, 0x50, 0x17, 0xdc, 0x92,
+	0x93, 0x19, 0x8d, 0x92, 0x79, 0x94, 0xb2, 0x1b, 0x59, 0x51, 0xc3, 0x20, 0xb9, 0xe1, 0x3c, 0x64,
+	0x37, 0x28, 0x80, 0xdd, 0x8d, 0x63, 0x99, 0xc4, 0x4b, 0xbf, 0x23, 0x2d, 0x4e, 0x63, 0x39, 0x4f,
+	0xe2, 0x25, 0xba, 0x07, 0xa6, 0xb8, 0x6b, 0x94, 0xcc, 0x7d, 0x55, 0xaa, 0x86, 0x80, 0xc3, 0x39,
+	0x3a, 0x80, 0xbd, 0x9c, 0x70, 0x9a, 0x95, 0x51, 0xab, 0x6b, 0x52, 0x77, 0x6b, 0x76, 0x52, 0xbb,
+	0x9e, 0xc1, 0x1e, 0xcb, 0x29, 0x27, 0x65, 0xc2, 0xb2, 0x3a, 0x98, 0x2e, 0x83, 0xed, 0x6e, 0x58,
+	0x19, 0xed, 0x25, 0x00, 0xa7, 0x0b, 0xca, 0x69, 0x36, 0xa3, 0x85, 0x6f, 0x74, 0xd5, 0xed, 0xd6,
+	0xca, 0x8e, 0xd1, 0x05, 0xde, 0xb2, 0xa0, 0x3b, 0xa0, 0x2f, 0x52, 0xd1, 0x0c, 0xb3, 0xab, 0xf4,
+	0x76, 0x71, 0x0d, 0xd0, 0x63, 0x80, 0xa2, 0x24, 0xbc, 0x8c, 0xca, 0x64, 0x45, 0x7d, 0xab, 0xab,
+	0xf4, 0x54, 0x6c, 0x4b, 0x66, 0x9a, 0xac, 0x28, 0x7a, 0x00, 0xd6, 0xfc, 0xba, 0xfe, 0xaa, 0x6f,
+	0x4b, 0x71, 0x83, 0x37, 0xcd, 0x85, 0xdb, 0x9a, 0x8b, 0x9e, 0x80, 0x96, 0xb2, 0xb8, 0xf0, 0x1d,
+	0xe9, 0x72, 0x5a, 0x57, 0xc8, 0x62, 0x2c, 0x85, 0xed, 0x05, 0x71, 0x6f, 0x5f, 0x90, 0xe0, 0x9b,
+	0x02, 0x66, 0x13, 0x0d, 0x1d, 0x81, 0xc5, 0xe9, 0x22, 0x2a, 0xd7, 0x79, 0x3d, 0xf9, 0xbd, 0xe3,
+	0xfd, 0xdf, 0xd2, 0x4f, 0xd7, 0x39, 0xc5, 0x26, 0xaf, 0x5f, 0xfe, 0x98, 0x6d, 0xe7, 0xdf, 0xb3,
+	0x55, 0x6f, 0x9d, 0xad, 0xb6, 0x3d, 0xdb, 0xe0, 0x02, 0xd4, 0x90, 0xc5, 0xe8, 0x11, 0xd8, 0xa2,
+	0x91, 0x45, 0x49, 0x56, 0xb9, 0xbc, 0x96, 0x8a, 0x7f, 0x11, 0xa8, 0x07, 0xc6, 0x22, 0xa1, 0xe9,
+	0xfc, 0xef, 0x0b, 0xd9, 0xe8, 0xc1, 0x0f, 0x05, 0xac, 0x96, 0x44, 0x1e, 0xa8, 0x5f, 0xe9, 0xba,
+	0xd9, 0x6f, 0xf1, 0x2a, 0x0a, 0x55, 0x75, 0xf4, 0x8e, 0x8c, 0xfe, 0x7f, 0x5b, 0x48, 0x1e, 0x90,
+	0xc1, 0xf5, 0x4a, 0x3c, 0xd0, 0x3e, 0xe8, 0x55, 0x54, 0x94, 0x5c, 0x86, 0xb1, 0xb1, 0x56, 0x4d,
+	0x4a, 0x8e, 0xee, 0x8a, 0xe3, 0x57, 0x8c, 0xa5, 0x32, 0x84, 0x85, 0xf5, 0xaa, 0xcf, 0x58, 0x2a,
+	0xc2, 0x55, 0x51, 0x92, 0x95, 0xa7, 0x27, 0x72, 0xe5, 0x54, 0x6c, 0x54, 0x43, 0x81, 0xd0, 0x43,
+	0xb0, 0xab, 0x68, 0x91, 0x32, 0x22, 0x24, 0xa3, 0xab, 0xf4, 0x14, 0x6c, 0x55, 0x83, 0x1a, 0xa3,
+	0xfb, 0x60, 0x55, 0xd1, 0x55, 0x92, 0x11, 0xbe, 0x96, 0xab, 0xe5, 0x62, 0xb3, 0xea, 0x4b, 0x78,
+	0xf8, 0x02, 0x9c, 0xad, 0x59, 0x20, 0x17, 0xac, 0xd7, 0xe7, 0xc3, 0xf0, 0x4d, 0x34, 0x1e, 0x78,
+	0x3b, 0xc8, 0x03, 0x77, 0x30, 0x0e, 0xc3, 0xf1, 0xc7, 0x49, 0x34, 0xc0, 0xe3, 0x0b, 0x4f, 0x39,
+	0x7c, 0x0b, 0xf6, 0xe6, 0xfe, 0x08, 0xc0, 0x98, 0x4c, 0xf1, 0x70, 0xf4, 0xce, 0xdb, 0x41, 0x16,
+	0x68, 0xfd, 0xf1, 0x38, 0xf4, 0x14, 0x64, 0x83, 0x3e, 0x1c, 0x4d, 0x4f, 0x4f, 0xbc, 0x0e, 0x72,
+	0xc0, 0x1c, 0x84, 0xe3, 0x33, 0x01, 0x54, 0xe1, 0xee, 0x0f, 0x47, 0x67, 0xf8, 0x93, 0xa7, 0xf5,
+	0xad, 0xcf, 0xcd, 0x4f, 0xe8, 0xca, 0x90, 0xff, 0xa4, 0x57, 0x3f, 0x07, 0x00, 0x85, 0x88, 0x85,
+	0x95, 0xa3, 0x04, 0x00, 0x00,
+}
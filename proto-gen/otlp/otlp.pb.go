@@ -0,0 +1,925 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: otlp.proto
+
+/*
+Package otlp is a generated protocol buffer package.
+
+It is generated from these files:
+
+	otlp.proto
+
+It has these top-level messages:
+
+	ExportTraceServiceRequest
+	ExportTraceServiceResponse
+	ResourceSpans
+	Resource
+	ScopeSpans
+	InstrumentationScope
+	Span
+	Status
+	KeyValue
+	AnyValue
+	ArrayValue
+	KeyValueList
+*/
+package otlp
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
+
+type Span_SpanKind int32
+
+const (
+	Span_SPAN_KIND_UNSPECIFIED Span_SpanKind = 0
+	Span_SPAN_KIND_INTERNAL    Span_SpanKind = 1
+	Span_SPAN_KIND_SERVER      Span_SpanKind = 2
+	Span_SPAN_KIND_CLIENT      Span_SpanKind = 3
+	Span_SPAN_KIND_PRODUCER    Span_SpanKind = 4
+	Span_SPAN_KIND_CONSUMER    Span_SpanKind = 5
+)
+
+var Span_SpanKind_name = map[int32]string{
+	0: "SPAN_KIND_UNSPECIFIED",
+	1: "SPAN_KIND_INTERNAL",
+	2: "SPAN_KIND_SERVER",
+	3: "SPAN_KIND_CLIENT",
+	4: "SPAN_KIND_PRODUCER",
+	5: "SPAN_KIND_CONSUMER",
+}
+var Span_SpanKind_value = map[string]int32{
+	"SPAN_KIND_UNSPECIFIED": 0,
+	"SPAN_KIND_INTERNAL":    1,
+	"SPAN_KIND_SERVER":      2,
+	"SPAN_KIND_CLIENT":      3,
+	"SPAN_KIND_PRODUCER":    4,
+	"SPAN_KIND_CONSUMER":    5,
+}
+
+func (x Span_SpanKind) String() string {
+	return proto.EnumName(Span_SpanKind_name, int32(x))
+}
+func (Span_SpanKind) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{6, 0} }
+
+type Status_StatusCode int32
+
+const (
+	Status_STATUS_CODE_UNSET Status_StatusCode = 0
+	Status_STATUS_CODE_OK    Status_StatusCode = 1
+	Status_STATUS_CODE_ERROR Status_StatusCode = 2
+)
+
+var Status_StatusCode_name = map[int32]string{
+	0: "STATUS_CODE_UNSET",
+	1: "STATUS_CODE_OK",
+	2: "STATUS_CODE_ERROR",
+}
+var Status_StatusCode_value = map[string]int32{
+	"STATUS_CODE_UNSET": 0,
+	"STATUS_CODE_OK":    1,
+	"STATUS_CODE_ERROR": 2,
+}
+
+func (x Status_StatusCode) String() string {
+	return proto.EnumName(Status_StatusCode_name, int32(x))
+}
+func (Status_StatusCode) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{7, 0} }
+
+// ExportTraceServiceRequest is the body of an OTLP trace export request.
+type ExportTraceServiceRequest struct {
+	ResourceSpans []*ResourceSpans `protobuf:"bytes,1,rep,name=resource_spans,json=resourceSpans" json:"resource_spans,omitempty"`
+}
+
+func (m *ExportTraceServiceRequest) Reset()                    { *m = ExportTraceServiceRequest{} }
+func (m *ExportTraceServiceRequest) String() string            { return proto.CompactTextString(m) }
+func (*ExportTraceServiceRequest) ProtoMessage()               {}
+func (*ExportTraceServiceRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+
+func (m *ExportTraceServiceRequest) GetResourceSpans() []*ResourceSpans {
+	if m != nil {
+		return m.ResourceSpans
+	}
+	return nil
+}
+
+// ExportTraceServiceResponse is the body of the response to an OTLP trace
+// export request.
+type ExportTraceServiceResponse struct {
+}
+
+func (m *ExportTraceServiceResponse) Reset()                    { *m = ExportTraceServiceResponse{} }
+func (m *ExportTraceServiceResponse) String() string            { return proto.CompactTextString(m) }
+func (*ExportTraceServiceResponse) ProtoMessage()               {}
+func (*ExportTraceServiceResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
+// ResourceSpans is a collection of spans emitted by a single resource.
+type ResourceSpans struct {
+	Resource   *Resource     `protobuf:"bytes,1,opt,name=resource" json:"resource,omitempty"`
+	ScopeSpans []*ScopeSpans `protobuf:"bytes,2,rep,name=scope_spans,json=scopeSpans" json:"scope_spans,omitempty"`
+	SchemaUrl  string        `protobuf:"bytes,3,opt,name=schema_url,json=schemaUrl" json:"schema_url,omitempty"`
+}
+
+func (m *ResourceSpans) Reset()                    { *m = ResourceSpans{} }
+func (m *ResourceSpans) String() string            { return proto.CompactTextString(m) }
+func (*ResourceSpans) ProtoMessage()               {}
+func (*ResourceSpans) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+
+func (m *ResourceSpans) GetResource() *Resource {
+	if m != nil {
+		return m.Resource
+	}
+	return nil
+}
+
+func (m *ResourceSpans) GetScopeSpans() []*ScopeSpans {
+	if m != nil {
+		return m.ScopeSpans
+	}
+	return nil
+}
+
+func (m *ResourceSpans) GetSchemaUrl() string {
+	if m != nil {
+		return m.SchemaUrl
+	}
+	return ""
+}
+
+// Resource describes the entity, typically a service, emitting the spans.
+type Resource struct {
+	Attributes             []*KeyValue `protobuf:"bytes,1,rep,name=attributes" json:"attributes,omitempty"`
+	DroppedAttributesCount uint32      `protobuf:"varint,2,opt,name=dropped_attributes_count,json=droppedAttributesCount" json:"dropped_attributes_count,omitempty"`
+}
+
+func (m *Resource) Reset()                    { *m = Resource{} }
+func (m *Resource) String() string            { return proto.CompactTextString(m) }
+func (*Resource) ProtoMessage()               {}
+func (*Resource) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+
+func (m *Resource) GetAttributes() []*KeyValue {
+	if m != nil {
+		return m.Attributes
+	}
+	return nil
+}
+
+func (m *Resource) GetDroppedAttributesCount() uint32 {
+	if m != nil {
+		return m.DroppedAttributesCount
+	}
+	return 0
+}
+
+// ScopeSpans is a collection of spans produced by a single instrumentation
+// scope.
+type ScopeSpans struct {
+	Scope     *InstrumentationScope `protobuf:"bytes,1,opt,name=scope" json:"scope,omitempty"`
+	Spans     []*Span               `protobuf:"bytes,2,rep,name=spans" json:"spans,omitempty"`
+	SchemaUrl string                `protobuf:"bytes,3,opt,name=schema_url,json=schemaUrl" json:"schema_url,omitempty"`
+}
+
+func (m *ScopeSpans) Reset()                    { *m = ScopeSpans{} }
+func (m *ScopeSpans) String() string            { return proto.CompactTextString(m) }
+func (*ScopeSpans) ProtoMessage()               {}
+func (*ScopeSpans) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+
+func (m *ScopeSpans) GetScope() *InstrumentationScope {
+	if m != nil {
+		return m.Scope
+	}
+	return nil
+}
+
+func (m *ScopeSpans) GetSpans() []*Span {
+	if m != nil {
+		return m.Spans
+	}
+	return nil
+}
+
+func (m *ScopeSpans) GetSchemaUrl() string {
+	if m != nil {
+		return m.SchemaUrl
+	}
+	return ""
+}
+
+// InstrumentationScope is the instrumentation library that produced the spans.
+type InstrumentationScope struct {
+	Name                   string      `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	Version                string      `protobuf:"bytes,2,opt,name=version" json:"version,omitempty"`
+	Attributes             []*KeyValue `protobuf:"bytes,3,rep,name=attributes" json:"attributes,omitempty"`
+	DroppedAttributesCount uint32      `protobuf:"varint,4,opt,name=dropped_attributes_count,json=droppedAttributesCount" json:"dropped_attributes_count,omitempty"`
+}
+
+func (m *InstrumentationScope) Reset()                    { *m = InstrumentationScope{} }
+func (m *InstrumentationScope) String() string            { return proto.CompactTextString(m) }
+func (*InstrumentationScope) ProtoMessage()               {}
+func (*InstrumentationScope) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+
+func (m *InstrumentationScope) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *InstrumentationScope) GetVersion() string {
+	if m != nil {
+		return m.Version
+	}
+	return ""
+}
+
+func (m *InstrumentationScope) GetAttributes() []*KeyValue {
+	if m != nil {
+		return m.Attributes
+	}
+	return nil
+}
+
+func (m *InstrumentationScope) GetDroppedAttributesCount() uint32 {
+	if m != nil {
+		return m.DroppedAttributesCount
+	}
+	return 0
+}
+
+// Span represents a single operation within a trace. Its timestamps are in
+// nanoseconds since the epoch.
+type Span struct {
+	TraceId                []byte        `protobuf:"bytes,1,opt,name=trace_id,json=traceId,proto3" json:"trace_id,omitempty"`
+	SpanId                 []byte        `protobuf:"bytes,2,opt,name=span_id,json=spanId,proto3" json:"span_id,omitempty"`
+	TraceState             string        `protobuf:"bytes,3,opt,name=trace_state,json=traceState" json:"trace_state,omitempty"`
+	ParentSpanId           []byte        `protobuf:"bytes,4,opt,name=parent_span_id,json=parentSpanId,proto3" json:"parent_span_id,omitempty"`
+	Flags                  uint32        `protobuf:"fixed32,16,opt,name=flags" json:"flags,omitempty"`
+	Name                   string        `protobuf:"bytes,5,opt,name=name" json:"name,omitempty"`
+	Kind                   Span_SpanKind `protobuf:"varint,6,opt,name=kind,enum=otlp.Span_SpanKind" json:"kind,omitempty"`
+	StartTimeUnixNano      uint64        `protobuf:"fixed64,7,opt,name=start_time_unix_nano,json=startTimeUnixNano" json:"start_time_unix_nano,omitempty"`
+	EndTimeUnixNano        uint64        `protobuf:"fixed64,8,opt,name=end_time_unix_nano,json=endTimeUnixNano" json:"end_time_unix_nano,omitempty"`
+	Attributes             []*KeyValue   `protobuf:"bytes,9,rep,name=attributes" json:"attributes,omitempty"`
+	DroppedAttributesCount uint32        `protobuf:"varint,10,opt,name=dropped_attributes_count,json=droppedAttributesCount" json:"dropped_attributes_count,omitempty"`
+	Events                 []*Span_Event `protobuf:"bytes,11,rep,name=events" json:"events,omitempty"`
+	DroppedEventsCount     uint32        `protobuf:"varint,12,opt,name=dropped_events_count,json=droppedEventsCount" json:"dropped_events_count,omitempty"`
+	Links                  []*Span_Link  `protobuf:"bytes,13,rep,name=links" json:"links,omitempty"`
+	DroppedLinksCount      uint32        `protobuf:"varint,14,opt,name=dropped_links_count,json=droppedLinksCount" json:"dropped_links_count,omitempty"`
+	Status                 *Status       `protobuf:"bytes,15,opt,name=status" json:"status,omitempty"`
+}
+
+func (m *Span) Reset()                    { *m = Span{} }
+func (m *Span) String() string            { return proto.CompactTextString(m) }
+func (*Span) ProtoMessage()               {}
+func (*Span) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
+
+func (m *Span) GetTraceId() []byte {
+	if m != nil {
+		return m.TraceId
+	}
+	return nil
+}
+
+func (m *Span) GetSpanId() []byte {
+	if m != nil {
+		return m.SpanId
+	}
+	return nil
+}
+
+func (m *Span) GetTraceState() string {
+	if m != nil {
+		return m.TraceState
+	}
+	return ""
+}
+
+func (m *Span) GetParentSpanId() []byte {
+	if m != nil {
+		return m.ParentSpanId
+	}
+	return nil
+}
+
+func (m *Span) GetFlags() uint32 {
+	if m != nil {
+		return m.Flags
+	}
+	return 0
+}
+
+func (m *Span) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Span) GetKind() Span_SpanKind {
+	if m != nil {
+		return m.Kind
+	}
+	return Span_SPAN_KIND_UNSPECIFIED
+}
+
+func (m *Span) GetStartTimeUnixNano() uint64 {
+	if m != nil {
+		return m.StartTimeUnixNano
+	}
+	return 0
+}
+
+func (m *Span) GetEndTimeUnixNano() uint64 {
+	if m != nil {
+		return m.EndTimeUnixNano
+	}
+	return 0
+}
+
+func (m *Span) GetAttributes() []*KeyValue {
+	if m != nil {
+		return m.Attributes
+	}
+	return nil
+}
+
+func (m *Span) GetDroppedAttributesCount() uint32 {
+	if m != nil {
+		return m.DroppedAttributesCount
+	}
+	return 0
+}
+
+func (m *Span) GetEvents() []*Span_Event {
+	if m != nil {
+		return m.Events
+	}
+	return nil
+}
+
+func (m *Span) GetDroppedEventsCount() uint32 {
+	if m != nil {
+		return m.DroppedEventsCount
+	}
+	return 0
+}
+
+func (m *Span) GetLinks() []*Span_Link {
+	if m != nil {
+		return m.Links
+	}
+	return nil
+}
+
+func (m *Span) GetDroppedLinksCount() uint32 {
+	if m != nil {
+		return m.DroppedLinksCount
+	}
+	return 0
+}
+
+func (m *Span) GetStatus() *Status {
+	if m != nil {
+		return m.Status
+	}
+	return nil
+}
+
+// Event is a time-stamped annotation of the span.
+type Span_Event struct {
+	TimeUnixNano           uint64      `protobuf:"fixed64,1,opt,name=time_unix_nano,json=timeUnixNano" json:"time_unix_nano,omitempty"`
+	Name                   string      `protobuf:"bytes,2,opt,name=name" json:"name,omitempty"`
+	Attributes             []*KeyValue `protobuf:"bytes,3,rep,name=attributes" json:"attributes,omitempty"`
+	DroppedAttributesCount uint32      `protobuf:"varint,4,opt,name=dropped_attributes_count,json=droppedAttributesCount" json:"dropped_attributes_count,omitempty"`
+}
+
+func (m *Span_Event) Reset()                    { *m = Span_Event{} }
+func (m *Span_Event) String() string            { return proto.CompactTextString(m) }
+func (*Span_Event) ProtoMessage()               {}
+func (*Span_Event) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6, 0} }
+
+func (m *Span_Event) GetTimeUnixNano() uint64 {
+	if m != nil {
+		return m.TimeUnixNano
+	}
+	return 0
+}
+
+func (m *Span_Event) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Span_Event) GetAttributes() []*KeyValue {
+	if m != nil {
+		return m.Attributes
+	}
+	return nil
+}
+
+func (m *Span_Event) GetDroppedAttributesCount() uint32 {
+	if m != nil {
+		return m.DroppedAttributesCount
+	}
+	return 0
+}
+
+// Link is a pointer from the span to a span in the same or another trace.
+type Span_Link struct {
+	TraceId                []byte      `protobuf:"bytes,1,opt,name=trace_id,json=traceId,proto3" json:"trace_id,omitempty"`
+	SpanId                 []byte      `protobuf:"bytes,2,opt,name=span_id,json=spanId,proto3" json:"span_id,omitempty"`
+	TraceState             string      `protobuf:"bytes,3,opt,name=trace_state,json=traceState" json:"trace_state,omitempty"`
+	Attributes             []*KeyValue `protobuf:"bytes,4,rep,name=attributes" json:"attributes,omitempty"`
+	DroppedAttributesCount uint32      `protobuf:"varint,5,opt,name=dropped_attributes_count,json=droppedAttributesCount" json:"dropped_attributes_count,omitempty"`
+	Flags                  uint32      `protobuf:"fixed32,6,opt,name=flags" json:"flags,omitempty"`
+}
+
+func (m *Span_Link) Reset()                    { *m = Span_Link{} }
+func (m *Span_Link) String() string            { return proto.CompactTextString(m) }
+func (*Span_Link) ProtoMessage()               {}
+func (*Span_Link) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6, 1} }
+
+func (m *Span_Link) GetTraceId() []byte {
+	if m != nil {
+		return m.TraceId
+	}
+	return nil
+}
+
+func (m *Span_Link) GetSpanId() []byte {
+	if m != nil {
+		return m.SpanId
+	}
+	return nil
+}
+
+func (m *Span_Link) GetTraceState() string {
+	if m != nil {
+		return m.TraceState
+	}
+	return ""
+}
+
+func (m *Span_Link) GetAttributes() []*KeyValue {
+	if m != nil {
+		return m.Attributes
+	}
+	return nil
+}
+
+func (m *Span_Link) GetDroppedAttributesCount() uint32 {
+	if m != nil {
+		return m.DroppedAttributesCount
+	}
+	return 0
+}
+
+func (m *Span_Link) GetFlags() uint32 {
+	if m != nil {
+		return m.Flags
+	}
+	return 0
+}
+
+// Status is the result of the operation represented by a span.
+type Status struct {
+	Message string            `protobuf:"bytes,2,opt,name=message" json:"message,omitempty"`
+	Code    Status_StatusCode `protobuf:"varint,3,opt,name=code,enum=otlp.Status_StatusCode" json:"code,omitempty"`
+}
+
+func (m *Status) Reset()                    { *m = Status{} }
+func (m *Status) String() string            { return proto.CompactTextString(m) }
+func (*Status) ProtoMessage()               {}
+func (*Status) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
+
+func (m *Status) GetMessage() string {
+	if m != nil {
+		return m.Message
+	}
+	return ""
+}
+
+func (m *Status) GetCode() Status_StatusCode {
+	if m != nil {
+		return m.Code
+	}
+	return Status_STATUS_CODE_UNSET
+}
+
+// KeyValue is an attribute of a resource, span or event.
+type KeyValue struct {
+	Key   string    `protobuf:"bytes,1,opt,name=key" json:"key,omitempty"`
+	Value *AnyValue `protobuf:"bytes,2,opt,name=value" json:"value,omitempty"`
+}
+
+func (m *KeyValue) Reset()                    { *m = KeyValue{} }
+func (m *KeyValue) String() string            { return proto.CompactTextString(m) }
+func (*KeyValue) ProtoMessage()               {}
+func (*KeyValue) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{8} }
+
+func (m *KeyValue) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *KeyValue) GetValue() *AnyValue {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+// AnyValue is the value of an attribute.
+type AnyValue struct {
+	// Types that are valid to be assigned to Value:
+	//	*AnyValue_StringValue
+	//	*AnyValue_BoolValue
+	//	*AnyValue_IntValue
+	//	*AnyValue_DoubleValue
+	//	*AnyValue_ArrayValue
+	//	*AnyValue_KvlistValue
+	//	*AnyValue_BytesValue
+	Value isAnyValue_Value `protobuf_oneof:"value"`
+}
+
+func (m *AnyValue) Reset()                    { *m = AnyValue{} }
+func (m *AnyValue) String() string            { return proto.CompactTextString(m) }
+func (*AnyValue) ProtoMessage()               {}
+func (*AnyValue) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{9} }
+
+type isAnyValue_Value interface{ isAnyValue_Value() }
+
+type AnyValue_StringValue struct {
+	StringValue string `protobuf:"bytes,1,opt,name=string_value,json=stringValue,oneof"`
+}
+type AnyValue_BoolValue struct {
+	BoolValue bool `protobuf:"varint,2,opt,name=bool_value,json=boolValue,oneof"`
+}
+type AnyValue_IntValue struct {
+	IntValue int64 `protobuf:"varint,3,opt,name=int_value,json=intValue,oneof"`
+}
+type AnyValue_DoubleValue struct {
+	DoubleValue float64 `protobuf:"fixed64,4,opt,name=double_value,json=doubleValue,oneof"`
+}
+type AnyValue_ArrayValue struct {
+	ArrayValue *ArrayValue `protobuf:"bytes,5,opt,name=array_value,json=arrayValue,oneof"`
+}
+type AnyValue_KvlistValue struct {
+	KvlistValue *KeyValueList `protobuf:"bytes,6,opt,name=kvlist_value,json=kvlistValue,oneof"`
+}
+type AnyValue_BytesValue struct {
+	BytesValue []byte `protobuf:"bytes,7,opt,name=bytes_value,json=bytesValue,proto3,oneof"`
+}
+
+func (*AnyValue_StringValue) isAnyValue_Value() {}
+func (*AnyValue_BoolValue) isAnyValue_Value()   {}
+func (*AnyValue_IntValue) isAnyValue_Value()    {}
+func (*AnyValue_DoubleValue) isAnyValue_Value() {}
+func (*AnyValue_ArrayValue) isAnyValue_Value()  {}
+func (*AnyValue_KvlistValue) isAnyValue_Value() {}
+func (*AnyValue_BytesValue) isAnyValue_Value()  {}
+
+func (m *AnyValue) GetValue() isAnyValue_Value {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+func (m *AnyValue) GetStringValue() string {
+	if x, ok := m.GetValue().(*AnyValue_StringValue); ok {
+		return x.StringValue
+	}
+	return ""
+}
+
+func (m *AnyValue) GetBoolValue() bool {
+	if x, ok := m.GetValue().(*AnyValue_BoolValue); ok {
+		return x.BoolValue
+	}
+	return false
+}
+
+func (m *AnyValue) GetIntValue() int64 {
+	if x, ok := m.GetValue().(*AnyValue_IntValue); ok {
+		return x.IntValue
+	}
+	return 0
+}
+
+func (m *AnyValue) GetDoubleValue() float64 {
+	if x, ok := m.GetValue().(*AnyValue_DoubleValue); ok {
+		return x.DoubleValue
+	}
+	return 0
+}
+
+func (m *AnyValue) GetArrayValue() *ArrayValue {
+	if x, ok := m.GetValue().(*AnyValue_ArrayValue); ok {
+		return x.ArrayValue
+	}
+	return nil
+}
+
+func (m *AnyValue) GetKvlistValue() *KeyValueList {
+	if x, ok := m.GetValue().(*AnyValue_KvlistValue); ok {
+		return x.KvlistValue
+	}
+	return nil
+}
+
+func (m *AnyValue) GetBytesValue() []byte {
+	if x, ok := m.GetValue().(*AnyValue_BytesValue); ok {
+		return x.BytesValue
+	}
+	return nil
+}
+
+// XXX_OneofFuncs is for the internal use of the proto package.
+func (*AnyValue) XXX_OneofFuncs() (func(msg proto.Message, b *proto.Buffer) error, func(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error), func(msg proto.Message) (n int), []interface{}) {
+	return _AnyValue_OneofMarshaler, _AnyValue_OneofUnmarshaler, _AnyValue_OneofSizer, []interface{}{
+		(*AnyValue_StringValue)(nil),
+		(*AnyValue_BoolValue)(nil),
+		(*AnyValue_IntValue)(nil),
+		(*AnyValue_DoubleValue)(nil),
+		(*AnyValue_ArrayValue)(nil),
+		(*AnyValue_KvlistValue)(nil),
+		(*AnyValue_BytesValue)(nil),
+	}
+}
+
+func _AnyValue_OneofMarshaler(msg proto.Message, b *proto.Buffer) error {
+	m := msg.(*AnyValue)
+	// value
+	switch x := m.Value.(type) {
+	case *AnyValue_StringValue:
+		b.EncodeVarint(1<<3 | proto.WireBytes)
+		b.EncodeStringBytes(x.StringValue)
+	case *AnyValue_BoolValue:
+		t := uint64(0)
+		if x.BoolValue {
+			t = 1
+		}
+		b.EncodeVarint(2<<3 | proto.WireVarint)
+		b.EncodeVarint(t)
+	case *AnyValue_IntValue:
+		b.EncodeVarint(3<<3 | proto.WireVarint)
+		b.EncodeVarint(uint64(x.IntValue))
+	case *AnyValue_DoubleValue:
+		b.EncodeVarint(4<<3 | proto.WireFixed64)
+		b.EncodeFixed64(math.Float64bits(x.DoubleValue))
+	case *AnyValue_ArrayValue:
+		b.EncodeVarint(5<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.ArrayValue); err != nil {
+			return err
+		}
+	case *AnyValue_KvlistValue:
+		b.EncodeVarint(6<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.KvlistValue); err != nil {
+			return err
+		}
+	case *AnyValue_BytesValue:
+		b.EncodeVarint(7<<3 | proto.WireBytes)
+		b.EncodeRawBytes(x.BytesValue)
+	case nil:
+	default:
+		return fmt.Errorf("AnyValue.Value has unexpected type %T", x)
+	}
+	return nil
+}
+
+func _AnyValue_OneofUnmarshaler(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error) {
+	m := msg.(*AnyValue)
+	switch tag {
+	case 1: // value.string_value
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		x, err := b.DecodeStringBytes()
+		m.Value = &AnyValue_StringValue{x}
+		return true, err
+	case 2: // value.bool_value
+		if wire != proto.WireVarint {
+			return true, proto.ErrInternalBadWireType
+		}
+		x, err := b.DecodeVarint()
+		m.Value = &AnyValue_BoolValue{x != 0}
+		return true, err
+	case 3: // value.int_value
+		if wire != proto.WireVarint {
+			return true, proto.ErrInternalBadWireType
+		}
+		x, err := b.DecodeVarint()
+		m.Value = &AnyValue_IntValue{int64(x)}
+		return true, err
+	case 4: // value.double_value
+		if wire != proto.WireFixed64 {
+			return true, proto.ErrInternalBadWireType
+		}
+		x, err := b.DecodeFixed64()
+		m.Value = &AnyValue_DoubleValue{math.Float64frombits(x)}
+		return true, err
+	case 5: // value.array_value
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(ArrayValue)
+		err := b.DecodeMessage(msg)
+		m.Value = &AnyValue_ArrayValue{msg}
+		return true, err
+	case 6: // value.kvlist_value
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(KeyValueList)
+		err := b.DecodeMessage(msg)
+		m.Value = &AnyValue_KvlistValue{msg}
+		return true, err
+	case 7: // value.bytes_value
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		x, err := b.DecodeRawBytes(true)
+		m.Value = &AnyValue_BytesValue{x}
+		return true, err
+	default:
+		return false, nil
+	}
+}
+
+func _AnyValue_OneofSizer(msg proto.Message) (n int) {
+	m := msg.(*AnyValue)
+	// value
+	switch x := m.Value.(type) {
+	case *AnyValue_StringValue:
+		n += proto.SizeVarint(1<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(len(x.StringValue)))
+		n += len(x.StringValue)
+	case *AnyValue_BoolValue:
+		n += proto.SizeVarint(2<<3 | proto.WireVarint)
+		n += 1
+	case *AnyValue_IntValue:
+		n += proto.SizeVarint(3<<3 | proto.WireVarint)
+		n += proto.SizeVarint(uint64(x.IntValue))
+	case *AnyValue_DoubleValue:
+		n += proto.SizeVarint(4<<3 | proto.WireFixed64)
+		n += 8
+	case *AnyValue_ArrayValue:
+		s := proto.Size(x.ArrayValue)
+		n += proto.SizeVarint(5<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *AnyValue_KvlistValue:
+		s := proto.Size(x.KvlistValue)
+		n += proto.SizeVarint(6<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *AnyValue_BytesValue:
+		n += proto.SizeVarint(7<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(len(x.BytesValue)))
+		n += len(x.BytesValue)
+	case nil:
+	default:
+		panic(fmt.Sprintf("proto: unexpected type %T in oneof", x))
+	}
+	return n
+}
+
+// ArrayValue is a list of attribute values.
+type ArrayValue struct {
+	Values []*AnyValue `protobuf:"bytes,1,rep,name=values" json:"values,omitempty"`
+}
+
+func (m *ArrayValue) Reset()                    { *m = ArrayValue{} }
+func (m *ArrayValue) String() string            { return proto.CompactTextString(m) }
+func (*ArrayValue) ProtoMessage()               {}
+func (*ArrayValue) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{10} }
+
+func (m *ArrayValue) GetValues() []*AnyValue {
+	if m != nil {
+		return m.Values
+	}
+	return nil
+}
+
+// KeyValueList is a list of attributes used as an attribute value.
+type KeyValueList struct {
+	Values []*KeyValue `protobuf:"bytes,1,rep,name=values" json:"values,omitempty"`
+}
+
+func (m *KeyValueList) Reset()                    { *m = KeyValueList{} }
+func (m *KeyValueList) String() string            { return proto.CompactTextString(m) }
+func (*KeyValueList) ProtoMessage()               {}
+func (*KeyValueList) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{11} }
+
+func (m *KeyValueList) GetValues() []*KeyValue {
+	if m != nil {
+		return m.Values
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*ExportTraceServiceRequest)(nil), "otlp.ExportTraceServiceRequest")
+	proto.RegisterType((*ExportTraceServiceResponse)(nil), "otlp.ExportTraceServiceResponse")
+	proto.RegisterType((*ResourceSpans)(nil), "otlp.ResourceSpans")
+	proto.RegisterType((*Resource)(nil), "otlp.Resource")
+	proto.RegisterType((*ScopeSpans)(nil), "otlp.ScopeSpans")
+	proto.RegisterType((*InstrumentationScope)(nil), "otlp.InstrumentationScope")
+	proto.RegisterType((*Span)(nil), "otlp.Span")
+	proto.RegisterType((*Span_Event)(nil), "otlp.Span.Event")
+	proto.RegisterType((*Span_Link)(nil), "otlp.Span.Link")
+	proto.RegisterType((*Status)(nil), "otlp.Status")
+	proto.RegisterType((*KeyValue)(nil), "otlp.KeyValue")
+	proto.RegisterType((*AnyValue)(nil), "otlp.AnyValue")
+	proto.RegisterType((*ArrayValue)(nil), "otlp.ArrayValue")
+	proto.RegisterType((*KeyValueList)(nil), "otlp.KeyValueList")
+	proto.RegisterEnum("otlp.Span_SpanKind", Span_SpanKind_name, Span_SpanKind_value)
+	proto.RegisterEnum("otlp.Status_StatusCode", Status_StatusCode_name, Status_StatusCode_value)
+}
+
+func init() { proto.RegisterFile("otlp.proto", fileDescriptor0) }
+
+var fileDescriptor0 = []byte{
+	// 1042 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xbc, 0x56, 0xdd, 0x6e, 0xe3, 0x44,
+	0x14, 0x8e, 0x13, 0xdb, 0x49, 0x4e, 0xd2, 0xac, 0x3b, 0xdb, 0xdd, 0x75, 0x2b, 0x56, 0x1b, 0x4c,
+	0x81, 0x88, 0x95, 0xca, 0xd2, 0x45, 0x80, 0xb8, 0x4b, 0x53, 0xa3, 0x86, 0x96, 0xb4, 0x1a, 0x27,
+	0x8b, 0xc4, 0x8d, 0xe5, 0xc6, 0x43, 0xb1, 0x9a, 0x8c, 0x83, 0x67, 0x52, 0xb5, 0x57, 0x3c, 0x01,
+	0x0f, 0xc0, 0x05, 0x97, 0x5c, 0x21, 0xed, 0x0b, 0xf1, 0x32, 0x68, 0x7e, 0x9c, 0xd8, 0xd1, 0x52,
+	0x21, 0x55, 0x70, 0x93, 0x78, 0xbe, 0xef, 0x9b, 0x6f, 0xce, 0x39, 0x73, 0x8e, 0x65, 0x80, 0x94,
+	0xcf, 0x16, 0x07, 0x8b, 0x2c, 0xe5, 0x29, 0x32, 0xc5, 0xb3, 0xf7, 0x3d, 0xec, 0xfa, 0xb7, 0x8b,
+	0x34, 0xe3, 0xe3, 0x2c, 0x9a, 0x92, 0x80, 0x64, 0x37, 0xc9, 0x94, 0x60, 0xf2, 0xf3, 0x92, 0x30,
+	0x8e, 0xbe, 0x86, 0x4e, 0x46, 0x58, 0xba, 0xcc, 0xa6, 0x24, 0x64, 0x8b, 0x88, 0x32, 0xd7, 0xe8,
+	0xd6, 0x7a, 0xad, 0xc3, 0xc7, 0x07, 0xd2, 0x07, 0x6b, 0x2e, 0x10, 0x14, 0xde, 0xca, 0x8a, 0x4b,
+	0xef, 0x3d, 0xd8, 0x7b, 0x97, 0x31, 0x5b, 0xa4, 0x94, 0x11, 0xef, 0x57, 0x03, 0xb6, 0x4a, 0xdb,
+	0xd1, 0x27, 0xd0, 0xc8, 0x0d, 0x5c, 0xa3, 0x6b, 0xf4, 0x5a, 0x87, 0x9d, 0xf2, 0x29, 0x78, 0xc5,
+	0xa3, 0xcf, 0xa0, 0xc5, 0xa6, 0xe9, 0x22, 0x0f, 0xaa, 0x2a, 0x83, 0x72, 0x94, 0x3c, 0x10, 0x84,
+	0x8a, 0x08, 0xd8, 0xea, 0x19, 0x3d, 0x07, 0x60, 0xd3, 0x9f, 0xc8, 0x3c, 0x0a, 0x97, 0xd9, 0xcc,
+	0xad, 0x75, 0x8d, 0x5e, 0x13, 0x37, 0x15, 0x32, 0xc9, 0x66, 0x1e, 0x87, 0x46, 0x7e, 0x0e, 0x3a,
+	0x00, 0x88, 0x38, 0xcf, 0x92, 0xcb, 0x25, 0x27, 0x79, 0xc6, 0x3a, 0x96, 0x53, 0x72, 0xf7, 0x26,
+	0x9a, 0x2d, 0x09, 0x2e, 0x28, 0xd0, 0x57, 0xe0, 0xc6, 0x59, 0xba, 0x58, 0x90, 0x38, 0x5c, 0xa3,
+	0xe1, 0x34, 0x5d, 0x52, 0xee, 0x56, 0xbb, 0x46, 0x6f, 0x0b, 0x3f, 0xd5, 0x7c, 0x7f, 0x45, 0x0f,
+	0x04, 0xeb, 0xfd, 0x02, 0xb0, 0x0e, 0x17, 0xbd, 0x02, 0x4b, 0x06, 0xac, 0xd3, 0xdf, 0x53, 0x47,
+	0x0e, 0x29, 0xe3, 0xd9, 0x72, 0x4e, 0x28, 0x8f, 0x78, 0x92, 0x52, 0xa9, 0xc7, 0x4a, 0x88, 0xba,
+	0x60, 0x15, 0x2b, 0x00, 0xba, 0x02, 0x8b, 0x88, 0x62, 0x8b, 0xfd, 0x9b, 0xb4, 0xdf, 0x1a, 0xb0,
+	0xf3, 0xae, 0x03, 0x10, 0x02, 0x93, 0x46, 0x73, 0x15, 0x4a, 0x13, 0xcb, 0x67, 0xe4, 0x42, 0xfd,
+	0x86, 0x64, 0x2c, 0x49, 0xa9, 0x4c, 0xab, 0x89, 0xf3, 0xe5, 0x46, 0xc5, 0x6a, 0x0f, 0xaa, 0x98,
+	0x79, 0x6f, 0xc5, 0x7e, 0x6f, 0x82, 0x29, 0xf2, 0x43, 0xbb, 0xd0, 0xe0, 0xa2, 0xb1, 0xc2, 0x24,
+	0x96, 0x41, 0xb6, 0x71, 0x5d, 0xae, 0x87, 0x31, 0x7a, 0x06, 0x75, 0x91, 0xbc, 0x60, 0xaa, 0x92,
+	0xb1, 0xc5, 0x72, 0x18, 0xa3, 0x17, 0xd0, 0x52, 0x7b, 0x18, 0x8f, 0x38, 0xd1, 0xd5, 0x00, 0x09,
+	0x05, 0x02, 0x41, 0xfb, 0xd0, 0x59, 0x44, 0x19, 0xa1, 0x3c, 0xcc, 0x0d, 0x4c, 0x69, 0xd0, 0x56,
+	0x68, 0xa0, 0x6c, 0x76, 0xc0, 0xfa, 0x71, 0x16, 0x5d, 0x31, 0xd7, 0xe9, 0x1a, 0xbd, 0x3a, 0x56,
+	0x8b, 0x55, 0xc5, 0xac, 0x42, 0xc5, 0x3e, 0x06, 0xf3, 0x3a, 0xa1, 0xb1, 0x6b, 0x77, 0x8d, 0x5e,
+	0x27, 0x9f, 0x1a, 0xe1, 0x22, 0x7f, 0x4e, 0x13, 0x1a, 0x63, 0x29, 0x40, 0x9f, 0xc2, 0x0e, 0xe3,
+	0x51, 0xc6, 0x43, 0x9e, 0xcc, 0x49, 0xb8, 0xa4, 0xc9, 0x6d, 0x48, 0x23, 0x9a, 0xba, 0xf5, 0xae,
+	0xd1, 0xb3, 0xf1, 0xb6, 0xe4, 0xc6, 0xc9, 0x9c, 0x4c, 0x68, 0x72, 0x3b, 0x8a, 0x68, 0x8a, 0x5e,
+	0x02, 0x22, 0x34, 0xde, 0x94, 0x37, 0xa4, 0xfc, 0x11, 0xa1, 0x71, 0x49, 0x5c, 0xbe, 0x9e, 0xe6,
+	0x83, 0xae, 0x07, 0xee, 0xbb, 0x1e, 0xd4, 0x03, 0x9b, 0xdc, 0x10, 0xca, 0x99, 0xdb, 0x2a, 0xcd,
+	0xa4, 0x48, 0xd9, 0x17, 0x04, 0xd6, 0x3c, 0x7a, 0x05, 0x3b, 0xf9, 0x19, 0x0a, 0xd1, 0xfe, 0x6d,
+	0xe9, 0x8f, 0x34, 0x27, 0xf7, 0x68, 0xef, 0x0f, 0xc1, 0x9a, 0x25, 0xf4, 0x9a, 0xb9, 0x5b, 0xd2,
+	0xfa, 0x51, 0xc1, 0xfa, 0x2c, 0xa1, 0xd7, 0x58, 0xb1, 0xe8, 0x00, 0x1e, 0xe7, 0xc6, 0x12, 0xd0,
+	0xbe, 0x1d, 0xe9, 0xbb, 0xad, 0x29, 0xb1, 0x41, 0xdb, 0xee, 0x83, 0x2d, 0xda, 0x61, 0xc9, 0xdc,
+	0x47, 0x72, 0xec, 0xda, 0xda, 0x57, 0x62, 0x58, 0x73, 0x7b, 0x7f, 0x1a, 0x60, 0xc9, 0x60, 0x44,
+	0x8f, 0x6c, 0x54, 0xdd, 0x90, 0x55, 0x6f, 0xf3, 0x62, 0xc9, 0xf3, 0x6e, 0xa8, 0x16, 0xba, 0xe1,
+	0x7f, 0x9b, 0x92, 0xbd, 0xbf, 0x0c, 0x30, 0x45, 0x8a, 0xff, 0xcd, 0x94, 0x94, 0xf3, 0x30, 0x1f,
+	0x94, 0x87, 0x75, 0x6f, 0x3b, 0xad, 0x26, 0xcd, 0x2e, 0x4c, 0x9a, 0xf7, 0x9b, 0x01, 0x8d, 0x7c,
+	0x7e, 0xd0, 0x2e, 0x3c, 0x09, 0x2e, 0xfa, 0xa3, 0xf0, 0x74, 0x38, 0x3a, 0x0e, 0x27, 0xa3, 0xe0,
+	0xc2, 0x1f, 0x0c, 0xbf, 0x19, 0xfa, 0xc7, 0x4e, 0x05, 0x3d, 0x05, 0xb4, 0xa6, 0x86, 0xa3, 0xb1,
+	0x8f, 0x47, 0xfd, 0x33, 0xc7, 0x40, 0x3b, 0xe0, 0xac, 0xf1, 0xc0, 0xc7, 0x6f, 0x7c, 0xec, 0x54,
+	0xcb, 0xe8, 0xe0, 0x6c, 0xe8, 0x8f, 0xc6, 0x4e, 0xad, 0xec, 0x71, 0x81, 0xcf, 0x8f, 0x27, 0x03,
+	0x1f, 0x3b, 0x66, 0x19, 0x1f, 0x9c, 0x8f, 0x82, 0xc9, 0x77, 0x3e, 0x76, 0x2c, 0xef, 0x0f, 0x03,
+	0x6c, 0xd5, 0x3a, 0xe2, 0x75, 0x39, 0x27, 0x8c, 0x45, 0x57, 0x79, 0x17, 0xe4, 0x4b, 0xf4, 0x12,
+	0xcc, 0x69, 0x1a, 0xab, 0xd2, 0x76, 0x0e, 0x9f, 0x15, 0x1b, 0x4e, 0xff, 0x0d, 0xd2, 0x98, 0x60,
+	0x29, 0xf2, 0x46, 0x00, 0x6b, 0x0c, 0x3d, 0x81, 0xed, 0x60, 0xdc, 0x1f, 0x4f, 0x82, 0x70, 0x70,
+	0x7e, 0xec, 0x8b, 0x84, 0xfd, 0xb1, 0x53, 0x41, 0x08, 0x3a, 0x45, 0xf8, 0xfc, 0xd4, 0x31, 0x36,
+	0xa5, 0x3e, 0xc6, 0xe7, 0xd8, 0xa9, 0x7e, 0x6b, 0x36, 0x0c, 0xa7, 0xea, 0x1d, 0x41, 0x23, 0xbf,
+	0x2b, 0xe4, 0x40, 0xed, 0x9a, 0xdc, 0xe9, 0x57, 0xbd, 0x78, 0x44, 0xfb, 0x60, 0xdd, 0x08, 0x4a,
+	0x06, 0xbe, 0xba, 0xdc, 0x3e, 0xd5, 0x97, 0xab, 0x48, 0xef, 0x6d, 0x15, 0x1a, 0x39, 0x86, 0x3e,
+	0x80, 0x36, 0xe3, 0x59, 0x42, 0xaf, 0x42, 0xb5, 0x53, 0xba, 0x9d, 0x54, 0x70, 0x4b, 0xa1, 0x4a,
+	0xf4, 0x02, 0xe0, 0x32, 0x4d, 0x67, 0xe1, 0xda, 0xbc, 0x71, 0x52, 0xc1, 0x4d, 0x81, 0x29, 0xc1,
+	0x73, 0x68, 0x26, 0x94, 0x6b, 0x5e, 0x94, 0xa7, 0x76, 0x52, 0xc1, 0x8d, 0x84, 0xf2, 0xd5, 0x21,
+	0x71, 0xba, 0xbc, 0x9c, 0x11, 0xad, 0x10, 0x53, 0x60, 0x88, 0x43, 0x14, 0xaa, 0x44, 0xaf, 0xa1,
+	0x15, 0x65, 0x59, 0x74, 0xa7, 0x35, 0x56, 0xd7, 0x58, 0xbf, 0x88, 0xfa, 0x82, 0x90, 0xb2, 0x93,
+	0x0a, 0x86, 0x68, 0xb5, 0x42, 0x5f, 0x42, 0xfb, 0xfa, 0x66, 0x96, 0xb0, 0xfc, 0x6c, 0x5b, 0xee,
+	0x42, 0xe5, 0xae, 0x3e, 0x4b, 0x18, 0x17, 0xa7, 0x29, 0xa5, 0xda, 0xf8, 0x3e, 0xb4, 0x2e, 0xef,
+	0x44, 0x3f, 0xab, 0x7d, 0xe2, 0x85, 0xdd, 0x16, 0xde, 0x12, 0x94, 0x92, 0xa3, 0xba, 0xae, 0xa6,
+	0xf7, 0x39, 0xc0, 0x3a, 0x00, 0xf4, 0x11, 0xd8, 0x12, 0xde, 0xf8, 0xc4, 0x58, 0x55, 0x59, 0xb3,
+	0xde, 0x17, 0xd0, 0x2e, 0x06, 0xf0, 0x4f, 0xfb, 0x56, 0xa3, 0xa7, 0xd9, 0x23, 0xfb, 0x07, 0xf9,
+	0x85, 0x77, 0x69, 0xcb, 0xcf, 0xbd, 0xd7, 0x7f, 0x0f, 0x00, 0xbe, 0xa7, 0x5e, 0x46, 0xfc, 0x09,
+	0x00, 0x00,
+}
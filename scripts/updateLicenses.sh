@@ -2,4 +2,4 @@
 
 set -e
 
-python scripts/updateLicense.py $(git ls-files "*\.go" | grep -v -e thrift-gen)
+python scripts/updateLicense.py $(git ls-files "*\.go" | grep -v -e thrift-gen -e proto-gen)
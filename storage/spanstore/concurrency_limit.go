@@ -0,0 +1,69 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"io"
+	"sync/atomic"
+
+	"github.com/uber/jaeger-lib/metrics"
+
+	"github.com/uber/jaeger/model"
+)
+
+// ConcurrencyLimitWriter is a span Writer that limits the number of writes in flight to the underlying
+// writer, so that a saturated storage makes the callers of WriteSpan wait instead of receiving ever more
+// concurrent requests
+type ConcurrencyLimitWriter struct {
+	writer    Writer
+	semaphore chan struct{}
+	inFlight  int64
+	gauge     metrics.Gauge
+}
+
+// NewConcurrencyLimitWriter creates a ConcurrencyLimitWriter that allows at most maxWrites concurrent
+// writes to writer. The number of writes in flight is reported in the storage.in-flight-writes gauge.
+func NewConcurrencyLimitWriter(writer Writer, maxWrites int, metricsFactory metrics.Factory) *ConcurrencyLimitWriter {
+	return &ConcurrencyLimitWriter{
+		writer:    writer,
+		semaphore: make(chan struct{}, maxWrites),
+		gauge:     metricsFactory.Gauge("storage.in-flight-writes", nil),
+	}
+}
+
+// WriteSpan waits until fewer than maxWrites writes are in flight and then writes the span. It gives up
+// waiting and returns the error of ctx when ctx is cancelled.
+func (w *ConcurrencyLimitWriter) WriteSpan(ctx context.Context, span *model.Span) error {
+	select {
+	case w.semaphore <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	w.gauge.Update(atomic.AddInt64(&w.inFlight, 1))
+	defer func() {
+		w.gauge.Update(atomic.AddInt64(&w.inFlight, -1))
+		<-w.semaphore
+	}()
+	return w.writer.WriteSpan(ctx, span)
+}
+
+// Close closes the underlying writer if it supports it.
+func (w *ConcurrencyLimitWriter) Close() error {
+	if closer, ok := w.writer.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics"
+
+	"github.com/uber/jaeger/model"
+)
+
+// slowWriter takes delay to write a span and records the highest number of concurrent writes
+type slowWriter struct {
+	delay    time.Duration
+	inFlight int64
+	max      int64
+	writes   int64
+	closed   bool
+}
+
+func (w *slowWriter) WriteSpan(ctx context.Context, span *model.Span) error {
+	n := atomic.AddInt64(&w.inFlight, 1)
+	defer atomic.AddInt64(&w.inFlight, -1)
+	for {
+		max := atomic.LoadInt64(&w.max)
+		if n <= max || atomic.CompareAndSwapInt64(&w.max, max, n) {
+			break
+		}
+	}
+	time.Sleep(w.delay)
+	atomic.AddInt64(&w.writes, 1)
+	return nil
+}
+
+func (w *slowWriter) Close() error {
+	w.closed = true
+	return nil
+}
+
+func TestConcurrencyLimitWriter(t *testing.T) {
+	mb := metrics.NewLocalFactory(time.Hour)
+	writer := &slowWriter{delay: time.Millisecond}
+	w := NewConcurrencyLimitWriter(writer, 3, mb)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				assert.NoError(t, w.WriteSpan(context.Background(), &model.Span{}))
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.EqualValues(t, 500, atomic.LoadInt64(&writer.writes))
+	assert.EqualValues(t, 3, atomic.LoadInt64(&writer.max))
+	_, gauges := mb.Snapshot()
+	assert.EqualValues(t, 0, gauges["storage.in-flight-writes"])
+
+	require.NoError(t, w.Close())
+	assert.True(t, writer.closed)
+}
+
+func TestConcurrencyLimitWriterReportsInFlight(t *testing.T) {
+	mb := metrics.NewLocalFactory(time.Hour)
+	blocked := &blockingWriter{release: make(chan struct{}), started: make(chan struct{}, 2)}
+	w := NewConcurrencyLimitWriter(blocked, 2, mb)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, w.WriteSpan(context.Background(), &model.Span{}))
+		}()
+	}
+	<-blocked.started
+	<-blocked.started
+	_, gauges := mb.Snapshot()
+	assert.EqualValues(t, 2, gauges["storage.in-flight-writes"])
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, w.WriteSpan(ctx, &model.Span{}))
+
+	close(blocked.release)
+	wg.Wait()
+	_, gauges = mb.Snapshot()
+	assert.EqualValues(t, 0, gauges["storage.in-flight-writes"])
+}
+
+// blockingWriter signals started when a write begins and blocks it until release is closed
+type blockingWriter struct {
+	release chan struct{}
+	started chan struct{}
+}
+
+func (w *blockingWriter) WriteSpan(ctx context.Context, span *model.Span) error {
+	w.started <- struct{}{}
+	<-w.release
+	return nil
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"io"
+
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/pkg/multierror"
+)
+
+// FanoutWriter is a span Writer that saves every span into a primary Writer and into secondary Writers,
+// e.g. while migrating to another storage. Unlike MultiplexWriter, only the primary Writer can fail a write.
+type FanoutWriter struct {
+	primary     Writer
+	secondaries []secondaryWriter
+	logger      *zap.Logger
+}
+
+type secondaryWriter struct {
+	name   string
+	writer Writer
+	failed metrics.Counter
+}
+
+// NewFanoutWriter creates a FanoutWriter. The failures of the secondary Writers, which are keyed by the name
+// of their storage, are logged and counted in the spans.secondary-write-failed counter tagged by storage.
+func NewFanoutWriter(primary Writer, secondaries map[string]Writer, metricsFactory metrics.Factory, logger *zap.Logger) *FanoutWriter {
+	w := &FanoutWriter{
+		primary: primary,
+		logger:  logger,
+	}
+	for name, writer := range secondaries {
+		w.secondaries = append(w.secondaries, secondaryWriter{
+			name:   name,
+			writer: writer,
+			failed: metricsFactory.Counter("spans.secondary-write-failed", map[string]string{"storage": name}),
+		})
+	}
+	return w
+}
+
+// WriteSpan writes the span to the primary Writer and then to every secondary Writer. It returns the error
+// of the primary Writer, the span is written to the secondary Writers even if the primary Writer fails.
+func (w *FanoutWriter) WriteSpan(ctx context.Context, span *model.Span) error {
+	err := w.primary.WriteSpan(ctx, span)
+	for _, secondary := range w.secondaries {
+		if err := secondary.writer.WriteSpan(ctx, span); err != nil {
+			secondary.failed.Inc(1)
+			w.logger.Error("Failed to save span to secondary storage", zap.String("storage", secondary.name), zap.Error(err))
+		}
+	}
+	return err
+}
+
+// Close closes the primary and secondary Writers that support it.
+func (w *FanoutWriter) Close() error {
+	var errors []error
+	writers := []Writer{w.primary}
+	for _, secondary := range w.secondaries {
+		writers = append(writers, secondary.writer)
+	}
+	for _, writer := range writers {
+		if closer, ok := writer.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				errors = append(errors, err)
+			}
+		}
+	}
+	return multierror.Wrap(errors)
+}
@@ -0,0 +1,66 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/uber/jaeger/model"
+)
+
+func TestFanoutWriterSecondaryFailure(t *testing.T) {
+	primary := &flakyWriter{}
+	failing := &flakyWriter{failures: 2}
+	healthy := &flakyWriter{}
+	metricsFactory := metrics.NewLocalFactory(0)
+	w := NewFanoutWriter(primary, map[string]Writer{"elasticsearch": failing, "kafka": healthy}, metricsFactory, zap.NewNop())
+
+	span := &model.Span{SpanID: 1}
+	assert.NoError(t, w.WriteSpan(context.Background(), span), "secondary failures do not fail the write")
+	assert.NoError(t, w.WriteSpan(context.Background(), span))
+	assert.Equal(t, []*model.Span{span, span}, primary.getSaved())
+	assert.Equal(t, []*model.Span{span, span}, healthy.getSaved())
+	assert.Empty(t, failing.getSaved())
+
+	counters, _ := metricsFactory.Snapshot()
+	assert.EqualValues(t, 2, counters["spans.secondary-write-failed|storage=elasticsearch"])
+	assert.EqualValues(t, 0, counters["spans.secondary-write-failed|storage=kafka"])
+}
+
+func TestFanoutWriterPrimaryFailure(t *testing.T) {
+	primary := &flakyWriter{failures: 1}
+	secondary := &flakyWriter{}
+	w := NewFanoutWriter(primary, map[string]Writer{"elasticsearch": secondary}, metrics.NullFactory, zap.NewNop())
+
+	span := &model.Span{SpanID: 1}
+	assert.Equal(t, errWriteFailed, w.WriteSpan(context.Background(), span))
+	assert.Equal(t, []*model.Span{span}, secondary.getSaved(), "the span is still written to the secondary storage")
+	assert.NoError(t, w.WriteSpan(context.Background(), span))
+}
+
+func TestFanoutWriterClose(t *testing.T) {
+	primary := &flakyWriter{}
+	secondary := &flakyWriter{}
+	w := NewFanoutWriter(primary, map[string]Writer{"elasticsearch": secondary, "noop": NewNoopWriter(zap.NewNop(), 0)}, metrics.NullFactory, zap.NewNop())
+
+	assert.NoError(t, w.Close())
+	assert.True(t, primary.closed)
+	assert.True(t, secondary.closed)
+}
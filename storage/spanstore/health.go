@@ -0,0 +1,204 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/uber/jaeger/model"
+)
+
+// ErrStorageDown is returned by HealthAwareWriter when the storage is down and its buffer is full
+var ErrStorageDown = errors.New("span storage is down")
+
+// HealthAwareWriter is a span Writer that pings the storage, and while the pings fail holds the
+// spans in a bounded buffer instead of writing them, so that a short outage does not fail every
+// write until the storage recovers. The buffered spans are written once a ping succeeds again.
+type HealthAwareWriter struct {
+	writer     Writer
+	ping       func() error
+	bufferSize int
+	logger     *zap.Logger
+	// ctx is the context of the writes of buffered spans, it is cancelled by Close
+	ctx      context.Context
+	cancel   context.CancelFunc
+	done     chan struct{}
+	upGauge  metrics.Gauge
+	buffered metrics.Counter
+	dropped  metrics.Counter
+
+	mux    sync.Mutex
+	up     bool
+	buffer []*model.Span
+}
+
+// NewHealthAwareWriter creates a HealthAwareWriter that calls ping every interval. While ping fails
+// up to bufferSize spans are buffered, further spans are rejected with ErrStorageDown. The state of the
+// storage is reported in the storage.up gauge as 1 or 0, the buffered spans are counted in spans.buffered
+// and the rejected ones, or the ones still buffered on Close, in spans.buffer-dropped.
+func NewHealthAwareWriter(
+	writer Writer,
+	ping func() error,
+	interval time.Duration,
+	bufferSize int,
+	metricsFactory metrics.Factory,
+	logger *zap.Logger,
+) *HealthAwareWriter {
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &HealthAwareWriter{
+		writer:     writer,
+		ping:       ping,
+		bufferSize: bufferSize,
+		logger:     logger,
+		ctx:        ctx,
+		cancel:     cancel,
+		done:       make(chan struct{}),
+		upGauge:    metricsFactory.Gauge("storage.up", nil),
+		buffered:   metricsFactory.Counter("spans.buffered", nil),
+		dropped:    metricsFactory.Counter("spans.buffer-dropped", nil),
+		up:         true,
+	}
+	w.upGauge.Update(1)
+	go w.pingEvery(interval)
+	return w
+}
+
+func (w *HealthAwareWriter) pingEvery(interval time.Duration) {
+	defer close(w.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			w.check()
+		case <-w.ctx.Done():
+			return
+		}
+	}
+}
+
+// check pings the storage, and writes the buffered spans if it is reachable again
+func (w *HealthAwareWriter) check() {
+	err := w.ping()
+	w.mux.Lock()
+	wasUp := w.up
+	if err != nil {
+		w.up = false
+		w.upGauge.Update(0)
+	}
+	w.mux.Unlock()
+	if err != nil {
+		if wasUp {
+			w.logger.Error("Span storage is down, buffering spans", zap.Int("buffer-size", w.bufferSize), zap.Error(err))
+		}
+		return
+	}
+	if !wasUp {
+		w.flush()
+	}
+}
+
+// flush writes the buffered spans, including the ones buffered while it runs, and marks the storage up
+// once the buffer is empty. If a write fails the unwritten spans are buffered again and the storage stays down.
+func (w *HealthAwareWriter) flush() {
+	flushed := 0
+	for {
+		w.mux.Lock()
+		spans := w.buffer
+		w.buffer = nil
+		if len(spans) == 0 {
+			w.up = true
+			w.upGauge.Update(1)
+			w.mux.Unlock()
+			w.logger.Info("Span storage is up, buffered spans are written", zap.Int("spans", flushed))
+			return
+		}
+		w.mux.Unlock()
+		for i, span := range spans {
+			if err := w.writer.WriteSpan(w.ctx, span); err != nil {
+				w.logger.Error("Failed to write buffered spans, the span storage is still down", zap.Error(err))
+				w.rebuffer(spans[i:])
+				return
+			}
+			flushed++
+		}
+	}
+}
+
+// rebuffer puts back spans that failed to be written in front of the spans buffered since,
+// the most recent spans are dropped if they do not all fit anymore
+func (w *HealthAwareWriter) rebuffer(spans []*model.Span) {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	w.buffer = append(spans, w.buffer...)
+	if excess := len(w.buffer) - w.bufferSize; excess > 0 {
+		w.dropped.Inc(int64(excess))
+		w.buffer = w.buffer[:w.bufferSize]
+	}
+}
+
+// WriteSpan writes the span while the storage is up. While it is down the span is buffered and no
+// error is returned, unless the buffer is full.
+func (w *HealthAwareWriter) WriteSpan(ctx context.Context, span *model.Span) error {
+	w.mux.Lock()
+	if !w.up {
+		defer w.mux.Unlock()
+		if len(w.buffer) >= w.bufferSize {
+			w.dropped.Inc(1)
+			return ErrStorageDown
+		}
+		w.buffer = append(w.buffer, span)
+		w.buffered.Inc(1)
+		return nil
+	}
+	w.mux.Unlock()
+	return w.writer.WriteSpan(ctx, span)
+}
+
+// Probe returns ErrStorageDown while the last ping failed or the buffered spans are being written.
+// It can be used as a readiness probe.
+func (w *HealthAwareWriter) Probe() error {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	if !w.up {
+		return ErrStorageDown
+	}
+	return nil
+}
+
+// Close stops pinging the storage, drops the spans still buffered and closes the underlying writer
+// if it supports it.
+func (w *HealthAwareWriter) Close() error {
+	w.cancel()
+	<-w.done
+	w.mux.Lock()
+	if buffered := len(w.buffer); buffered > 0 {
+		w.dropped.Inc(int64(buffered))
+		w.logger.Error("Dropping the spans buffered while the span storage is down", zap.Int("spans", buffered))
+		w.buffer = nil
+	}
+	w.mux.Unlock()
+	if closer, ok := w.writer.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/uber/jaeger/model"
+)
+
+// toggledBackend is a storage that can be made unreachable, its ping and writes fail while it is down
+type toggledBackend struct {
+	sync.Mutex
+	down  bool
+	saved []*model.Span
+}
+
+func (b *toggledBackend) setDown(down bool) {
+	b.Lock()
+	defer b.Unlock()
+	b.down = down
+}
+
+func (b *toggledBackend) ping() error {
+	b.Lock()
+	defer b.Unlock()
+	if b.down {
+		return errors.New("no hosts available")
+	}
+	return nil
+}
+
+func (b *toggledBackend) WriteSpan(ctx context.Context, span *model.Span) error {
+	b.Lock()
+	defer b.Unlock()
+	if b.down {
+		return errWriteFailed
+	}
+	b.saved = append(b.saved, span)
+	return nil
+}
+
+func (b *toggledBackend) getSaved() []*model.Span {
+	b.Lock()
+	defer b.Unlock()
+	return b.saved
+}
+
+func testSpan(spanID uint64) *model.Span {
+	return &model.Span{TraceID: model.TraceID{Low: 1}, SpanID: model.SpanID(spanID)}
+}
+
+func TestHealthAwareWriterBuffersWhileDown(t *testing.T) {
+	mb := metrics.NewLocalFactory(time.Hour)
+	backend := &toggledBackend{}
+	// the storage is only pinged when the test calls check
+	w := NewHealthAwareWriter(backend, backend.ping, time.Hour, 2, mb, zap.NewNop())
+	defer w.Close()
+
+	require.NoError(t, w.WriteSpan(context.Background(), testSpan(1)))
+	assert.Equal(t, []*model.Span{testSpan(1)}, backend.getSaved())
+	assert.NoError(t, w.Probe())
+
+	backend.setDown(true)
+	w.check()
+	assert.Equal(t, ErrStorageDown, w.Probe())
+	_, gauges := mb.Snapshot()
+	assert.EqualValues(t, 0, gauges["storage.up"])
+
+	assert.NoError(t, w.WriteSpan(context.Background(), testSpan(2)))
+	assert.NoError(t, w.WriteSpan(context.Background(), testSpan(3)))
+	assert.Equal(t, ErrStorageDown, w.WriteSpan(context.Background(), testSpan(4)), "the buffer is full")
+	assert.Len(t, backend.getSaved(), 1, "the spans are buffered while the storage is down")
+
+	backend.setDown(false)
+	w.check()
+	assert.NoError(t, w.Probe())
+	assert.Equal(t, []*model.Span{testSpan(1), testSpan(2), testSpan(3)}, backend.getSaved())
+	counters, gauges := mb.Snapshot()
+	assert.EqualValues(t, 1, gauges["storage.up"])
+	assert.EqualValues(t, 2, counters["spans.buffered"])
+	assert.EqualValues(t, 1, counters["spans.buffer-dropped"])
+
+	require.NoError(t, w.WriteSpan(context.Background(), testSpan(5)))
+	assert.Len(t, backend.getSaved(), 4, "the spans are written again once the storage is up")
+}
+
+// failingFlushBackend answers pings but fails the first write
+type failingFlushBackend struct {
+	flakyWriter
+}
+
+func (b *failingFlushBackend) ping() error {
+	return nil
+}
+
+func TestHealthAwareWriterFlushFailure(t *testing.T) {
+	mb := metrics.NewLocalFactory(time.Hour)
+	backend := &failingFlushBackend{flakyWriter{failures: 1}}
+	ping := errors.New("no hosts available")
+	w := NewHealthAwareWriter(backend, func() error { return ping }, time.Hour, 10, mb, zap.NewNop())
+	defer w.Close()
+
+	w.check()
+	require.NoError(t, w.WriteSpan(context.Background(), testSpan(1)))
+	require.NoError(t, w.WriteSpan(context.Background(), testSpan(2)))
+
+	ping = nil
+	w.check()
+	assert.Equal(t, ErrStorageDown, w.Probe(), "the storage stays down when the buffered spans cannot be written")
+	assert.Empty(t, backend.getSaved())
+
+	w.check()
+	assert.NoError(t, w.Probe())
+	assert.Equal(t, []*model.Span{testSpan(1), testSpan(2)}, backend.getSaved(), "the spans are written in order")
+}
+
+func TestHealthAwareWriterPings(t *testing.T) {
+	mb := metrics.NewLocalFactory(time.Hour)
+	backend := &toggledBackend{down: true}
+	w := NewHealthAwareWriter(backend, backend.ping, time.Millisecond, 10, mb, zap.NewNop())
+	defer w.Close()
+
+	for i := 0; i < 1000 && w.Probe() == nil; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	require.Equal(t, ErrStorageDown, w.Probe())
+	require.NoError(t, w.WriteSpan(context.Background(), testSpan(1)))
+
+	backend.setDown(false)
+	for i := 0; i < 1000 && w.Probe() != nil; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	require.NoError(t, w.Probe())
+	assert.Equal(t, []*model.Span{testSpan(1)}, backend.getSaved())
+}
+
+func TestHealthAwareWriterClose(t *testing.T) {
+	mb := metrics.NewLocalFactory(time.Hour)
+	writer := &flakyWriter{}
+	ping := errors.New("no hosts available")
+	w := NewHealthAwareWriter(writer, func() error { return ping }, time.Hour, 10, mb, zap.NewNop())
+
+	w.check()
+	require.NoError(t, w.WriteSpan(context.Background(), testSpan(1)))
+	require.NoError(t, w.Close())
+	assert.True(t, writer.closed)
+	assert.Empty(t, writer.getSaved())
+	counters, _ := mb.Snapshot()
+	assert.EqualValues(t, 1, counters["spans.buffer-dropped"], "the spans still buffered are dropped")
+}
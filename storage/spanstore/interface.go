@@ -15,15 +15,16 @@
 package spanstore
 
 import (
+	"context"
 	"errors"
 	"time"
 
 	"github.com/uber/jaeger/model"
 )
 
-// Writer writes spans to storage.
+// Writer writes spans to storage. WriteSpan gives up on the write and returns an error when ctx is cancelled.
 type Writer interface {
-	WriteSpan(span *model.Span) error
+	WriteSpan(ctx context.Context, span *model.Span) error
 }
 
 var (
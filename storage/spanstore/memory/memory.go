@@ -15,7 +15,9 @@
 package memory
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"sync"
 	"time"
 
@@ -26,24 +28,39 @@ import (
 
 var errTraceNotFound = errors.New("Trace was not found")
 
-// Store is an unbounded in-memory store of traces
+// Store is an in-memory store of traces. When bounded, it keeps at most maxTraces traces
+// and evicts the oldest trace first.
 type Store struct {
-	// TODO: make this a bounded memory store
 	sync.RWMutex
 	traces     map[model.TraceID]*model.Trace
 	services   map[string]struct{}
 	operations map[string]map[string]struct{}
 	deduper    adjuster.Adjuster
+	maxTraces  int
+	ids        []*model.TraceID // ring buffer of trace IDs in insertion order, used when maxTraces > 0
+	index      int
 }
 
-// NewStore creates an in-memory store
+// NewStore creates an unbounded in-memory store
 func NewStore() *Store {
+	store, _ := NewStoreWithMaxTraces(0)
+	return store
+}
+
+// NewStoreWithMaxTraces creates an in-memory store that keeps at most maxTraces traces.
+// A maxTraces of 0 means the store is unbounded, a negative maxTraces is an error.
+func NewStoreWithMaxTraces(maxTraces int) (*Store, error) {
+	if maxTraces < 0 {
+		return nil, fmt.Errorf("invalid maximum number of traces %d, must not be negative", maxTraces)
+	}
 	return &Store{
 		traces:     map[model.TraceID]*model.Trace{},
 		services:   map[string]struct{}{},
 		operations: map[string]map[string]struct{}{},
 		deduper:    adjuster.SpanIDDeduper(),
-	}
+		maxTraces:  maxTraces,
+		ids:        make([]*model.TraceID, maxTraces),
+	}, nil
 }
 
 // GetDependencies returns dependencies between services
@@ -103,7 +120,7 @@ func (m *Store) traceIsBetweenStartAndEnd(startTs, endTs time.Time, trace *model
 }
 
 // WriteSpan writes the given span
-func (m *Store) WriteSpan(span *model.Span) error {
+func (m *Store) WriteSpan(ctx context.Context, span *model.Span) error {
 	m.Lock()
 	defer m.Unlock()
 	if _, ok := m.operations[span.Process.ServiceName]; !ok {
@@ -113,12 +130,25 @@ func (m *Store) WriteSpan(span *model.Span) error {
 	m.services[span.Process.ServiceName] = struct{}{}
 	if _, ok := m.traces[span.TraceID]; !ok {
 		m.traces[span.TraceID] = &model.Trace{}
+		if m.maxTraces > 0 {
+			m.evictOldestAndTrack(span.TraceID)
+		}
 	}
 	m.traces[span.TraceID].Spans = append(m.traces[span.TraceID].Spans, span)
 
 	return nil
 }
 
+// evictOldestAndTrack records the new trace ID in the ring buffer, removing
+// the trace whose ID it overwrites.
+func (m *Store) evictOldestAndTrack(traceID model.TraceID) {
+	if oldID := m.ids[m.index]; oldID != nil {
+		delete(m.traces, *oldID)
+	}
+	m.ids[m.index] = &traceID
+	m.index = (m.index + 1) % m.maxTraces
+}
+
 // GetTrace gets a trace
 func (m *Store) GetTrace(traceID model.TraceID) (*model.Trace, error) {
 	m.RLock()
@@ -15,10 +15,12 @@
 package memory
 
 import (
+	"context"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/uber/jaeger/model"
 	"github.com/uber/jaeger/storage/spanstore"
@@ -133,7 +135,7 @@ var childSpan2_1 = &model.Span{
 
 func withPopulatedMemoryStore(f func(store *Store)) {
 	memStore := NewStore()
-	memStore.WriteSpan(testingSpan)
+	memStore.WriteSpan(context.Background(), testingSpan)
 	f(memStore)
 }
 func withMemoryStore(f func(store *Store)) {
@@ -150,10 +152,10 @@ func TestStoreGetEmptyDependencies(t *testing.T) {
 
 func TestStoreGetDependencies(t *testing.T) {
 	withMemoryStore(func(store *Store) {
-		assert.NoError(t, store.WriteSpan(testingSpan))
-		assert.NoError(t, store.WriteSpan(childSpan1))
-		assert.NoError(t, store.WriteSpan(childSpan2))
-		assert.NoError(t, store.WriteSpan(childSpan2_1))
+		assert.NoError(t, store.WriteSpan(context.Background(), testingSpan))
+		assert.NoError(t, store.WriteSpan(context.Background(), childSpan1))
+		assert.NoError(t, store.WriteSpan(context.Background(), childSpan2))
+		assert.NoError(t, store.WriteSpan(context.Background(), childSpan2_1))
 		links, err := store.GetDependencies(time.Now(), time.Hour)
 		assert.NoError(t, err)
 		assert.Empty(t, links)
@@ -170,7 +172,7 @@ func TestStoreGetDependencies(t *testing.T) {
 
 func TestStoreWriteSpan(t *testing.T) {
 	withMemoryStore(func(store *Store) {
-		err := store.WriteSpan(testingSpan)
+		err := store.WriteSpan(context.Background(), testingSpan)
 		assert.NoError(t, err)
 	})
 }
@@ -304,3 +306,35 @@ func TestStoreGetTrace(t *testing.T) {
 		})
 	}
 }
+
+func TestStoreWithMaxTraces(t *testing.T) {
+	store, err := NewStoreWithMaxTraces(2)
+	require.NoError(t, err)
+	for i := uint64(1); i <= 3; i++ {
+		span := &model.Span{
+			TraceID:       model.TraceID{Low: i},
+			SpanID:        model.SpanID(i),
+			Process:       &model.Process{ServiceName: "serviceName"},
+			OperationName: "operationName",
+		}
+		assert.NoError(t, store.WriteSpan(context.Background(), span))
+		// a second span of the same trace must not count against the limit
+		assert.NoError(t, store.WriteSpan(context.Background(), span))
+	}
+
+	trace, err := store.GetTrace(model.TraceID{Low: 1})
+	assert.EqualError(t, err, errTraceNotFound.Error())
+	assert.Nil(t, trace)
+
+	for i := uint64(2); i <= 3; i++ {
+		trace, err := store.GetTrace(model.TraceID{Low: i})
+		assert.NoError(t, err)
+		assert.Len(t, trace.Spans, 2)
+	}
+}
+
+func TestStoreWithNegativeMaxTraces(t *testing.T) {
+	store, err := NewStoreWithMaxTraces(-1)
+	assert.EqualError(t, err, "invalid maximum number of traces -1, must not be negative")
+	assert.Nil(t, store)
+}
@@ -14,6 +14,7 @@
 
 package mocks
 
+import context "context"
 import mock "github.com/stretchr/testify/mock"
 import model "github.com/uber/jaeger/model"
 import spanstore "github.com/uber/jaeger/storage/spanstore"
@@ -23,13 +24,13 @@ type Writer struct {
 	mock.Mock
 }
 
-// WriteSpan provides a mock function with given fields: span
-func (_m *Writer) WriteSpan(span *model.Span) error {
-	ret := _m.Called(span)
+// WriteSpan provides a mock function with given fields: ctx, span
+func (_m *Writer) WriteSpan(ctx context.Context, span *model.Span) error {
+	ret := _m.Called(ctx, span)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(*model.Span) error); ok {
-		r0 = rf(span)
+	if rf, ok := ret.Get(0).(func(context.Context, *model.Span) error); ok {
+		r0 = rf(ctx, span)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -15,6 +15,8 @@
 package spanstore
 
 import (
+	"context"
+
 	"github.com/uber/jaeger/model"
 	"github.com/uber/jaeger/pkg/multierror"
 )
@@ -32,10 +34,10 @@ func NewMultiplexWriter(spanWriters ...Writer) *MultiplexWriter {
 }
 
 // WriteSpan calls WriteSpan on each span writer. It will sum up failures, it is not transactional
-func (c *MultiplexWriter) WriteSpan(span *model.Span) error {
+func (c *MultiplexWriter) WriteSpan(ctx context.Context, span *model.Span) error {
 	var errors []error
 	for _, writer := range c.spanWriters {
-		if err := writer.WriteSpan(span); err != nil {
+		if err := writer.WriteSpan(ctx, span); err != nil {
 			errors = append(errors, err)
 		}
 	}
@@ -0,0 +1,52 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"math/rand"
+
+	"go.uber.org/zap"
+
+	"github.com/uber/jaeger/model"
+)
+
+// NoopWriter is a span Writer that discards spans, logging a fraction of them
+type NoopWriter struct {
+	logger      *zap.Logger
+	logFraction float64
+	random      func() float64
+}
+
+// NewNoopWriter creates a NoopWriter that logs each span it discards with probability logFraction
+func NewNoopWriter(logger *zap.Logger, logFraction float64) *NoopWriter {
+	return &NoopWriter{
+		logger:      logger,
+		logFraction: logFraction,
+		random:      rand.Float64,
+	}
+}
+
+// WriteSpan discards the span and never fails
+func (w *NoopWriter) WriteSpan(ctx context.Context, span *model.Span) error {
+	if w.logFraction > 0 && w.random() < w.logFraction {
+		w.logger.Info("Discarding span",
+			zap.String("trace-id", span.TraceID.String()),
+			zap.String("span-id", span.SpanID.String()),
+			zap.String("service", span.Process.ServiceName),
+			zap.String("operation", span.OperationName))
+	}
+	return nil
+}
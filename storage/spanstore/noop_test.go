@@ -0,0 +1,59 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/pkg/testutils"
+)
+
+func TestNoopWriter(t *testing.T) {
+	span := &model.Span{
+		TraceID:       model.TraceID{Low: 1},
+		SpanID:        model.SpanID(2),
+		OperationName: "operation",
+		Process:       &model.Process{ServiceName: "service"},
+	}
+	testCases := []struct {
+		caption     string
+		logFraction float64
+		random      float64
+		logged      bool
+	}{
+		{caption: "logging disabled", logFraction: 0, random: 0},
+		{caption: "not sampled", logFraction: 0.1, random: 0.5},
+		{caption: "sampled", logFraction: 0.1, random: 0.05, logged: true},
+		{caption: "all logged", logFraction: 1, random: 0.99, logged: true},
+	}
+	for _, tc := range testCases {
+		logger, logBuffer := testutils.NewLogger()
+		w := NewNoopWriter(logger, tc.logFraction)
+		w.random = func() float64 { return tc.random }
+
+		assert.NoError(t, w.WriteSpan(context.Background(), span), tc.caption)
+		if tc.logged {
+			assert.Equal(t, "Discarding span", logBuffer.JSONLine(0)["msg"], tc.caption)
+			assert.Equal(t, "service", logBuffer.JSONLine(0)["service"], tc.caption)
+			assert.Equal(t, "operation", logBuffer.JSONLine(0)["operation"], tc.caption)
+		} else {
+			assert.Empty(t, logBuffer.Lines(), tc.caption)
+		}
+	}
+}
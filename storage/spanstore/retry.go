@@ -0,0 +1,137 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/pkg/queue"
+)
+
+// RetryWriter is a span Writer that retries failed writes in the background, so that a failing
+// storage does not hold up the callers of WriteSpan
+type RetryWriter struct {
+	writer  Writer
+	logger  *zap.Logger
+	retries int
+	backoff time.Duration
+	queue   *queue.BoundedQueue
+	// ctx is the context of the retries, it is cancelled by Close
+	ctx     context.Context
+	cancel  context.CancelFunc
+	retried metrics.Counter
+	failed  metrics.Counter
+}
+
+type retryItem struct {
+	span *model.Span
+	err  error
+}
+
+// NewRetryWriter creates a RetryWriter. A span that writer fails to save is retried up to retries
+// times by one of workers goroutines, waiting backoff before the first retry and twice as long before
+// every next one. At most queueSize spans wait to be retried, further failed spans are given up on.
+// Spans that are given up on are counted in the spans.write-failed counter, retries in spans.write-retries.
+func NewRetryWriter(
+	writer Writer,
+	retries int,
+	backoff time.Duration,
+	workers int,
+	queueSize int,
+	metricsFactory metrics.Factory,
+	logger *zap.Logger,
+) *RetryWriter {
+	failed := metricsFactory.Counter("spans.write-failed", nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &RetryWriter{
+		writer:  writer,
+		logger:  logger,
+		retries: retries,
+		backoff: backoff,
+		queue: queue.NewBoundedQueue(queueSize, func(item interface{}) {
+			failed.Inc(1)
+			logger.Error("Failed to save span, the retry queue is full", zap.Error(item.(*retryItem).err))
+		}),
+		ctx:     ctx,
+		cancel:  cancel,
+		retried: metricsFactory.Counter("spans.write-retries", nil),
+		failed:  failed,
+	}
+	w.queue.StartConsumers(workers, func(item interface{}) {
+		w.retry(item.(*retryItem))
+	})
+	return w
+}
+
+// WriteSpan writes the span, if that fails the span is queued to be retried and no error is returned.
+// An error is only returned if the span cannot be queued. The retries are not bound to ctx, which
+// usually ends before they do, they are cancelled by Close instead.
+func (w *RetryWriter) WriteSpan(ctx context.Context, span *model.Span) error {
+	err := w.writer.WriteSpan(ctx, span)
+	if err == nil {
+		return nil
+	}
+	if !w.queue.Produce(&retryItem{span: span, err: err}) {
+		return err
+	}
+	return nil
+}
+
+func (w *RetryWriter) retry(item *retryItem) {
+	backoff := w.backoff
+	for i := 0; i < w.retries; i++ {
+		select {
+		case <-time.After(backoff):
+		case <-w.ctx.Done():
+			w.giveUp(item)
+			return
+		}
+		w.retried.Inc(1)
+		if item.err = w.writer.WriteSpan(w.ctx, item.span); item.err == nil {
+			return
+		}
+		backoff *= 2
+	}
+	w.giveUp(item)
+}
+
+func (w *RetryWriter) giveUp(item *retryItem) {
+	w.failed.Inc(1)
+	w.logger.Error("Failed to save span after retries",
+		zap.String("trace-id", item.span.TraceID.String()),
+		zap.String("span-id", item.span.SpanID.String()),
+		zap.Int("retries", w.retries),
+		zap.Error(item.err))
+}
+
+// Close cancels the retries in progress, gives up on the spans waiting to be retried and closes
+// the underlying writer if it supports it.
+func (w *RetryWriter) Close() error {
+	w.cancel()
+	w.queue.Stop()
+	if queued := w.queue.Size(); queued > 0 {
+		w.failed.Inc(int64(queued))
+	}
+	if closer, ok := w.writer.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
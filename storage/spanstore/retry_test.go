@@ -0,0 +1,167 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/uber/jaeger/model"
+)
+
+var errWriteFailed = errors.New("write failed")
+
+// flakyWriter fails the first failures writes and then succeeds
+type flakyWriter struct {
+	sync.Mutex
+	failures int
+	writes   int
+	saved    []*model.Span
+	closed   bool
+}
+
+func (w *flakyWriter) WriteSpan(ctx context.Context, span *model.Span) error {
+	w.Lock()
+	defer w.Unlock()
+	w.writes++
+	if w.writes <= w.failures {
+		return errWriteFailed
+	}
+	w.saved = append(w.saved, span)
+	return nil
+}
+
+func (w *flakyWriter) getSaved() []*model.Span {
+	w.Lock()
+	defer w.Unlock()
+	return w.saved
+}
+
+func (w *flakyWriter) Close() error {
+	w.closed = true
+	return nil
+}
+
+func waitForCounter(t *testing.T, mb *metrics.LocalFactory, name string, value int64) {
+	for i := 0; i < 1000; i++ {
+		if counters, _ := mb.Snapshot(); counters[name] == value {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	counters, _ := mb.Snapshot()
+	assert.Equal(t, value, counters[name], name)
+}
+
+func TestRetryWriterEventualSuccess(t *testing.T) {
+	mb := metrics.NewLocalFactory(time.Hour)
+	writer := &flakyWriter{failures: 2}
+	w := NewRetryWriter(writer, 3, time.Millisecond, 1, 10, mb, zap.NewNop())
+
+	span := &model.Span{TraceID: model.TraceID{Low: 1}, SpanID: model.SpanID(1)}
+	assert.NoError(t, w.WriteSpan(context.Background(), span), "the failed write is retried in the background")
+	waitForCounter(t, mb, "spans.write-retries", 2)
+	for i := 0; i < 1000 && len(writer.getSaved()) == 0; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, []*model.Span{span}, writer.getSaved())
+
+	require.NoError(t, w.Close())
+	assert.True(t, writer.closed)
+	counters, _ := mb.Snapshot()
+	assert.EqualValues(t, 0, counters["spans.write-failed"])
+}
+
+func TestRetryWriterGivesUp(t *testing.T) {
+	mb := metrics.NewLocalFactory(time.Hour)
+	writer := &flakyWriter{failures: 10}
+	w := NewRetryWriter(writer, 3, time.Millisecond, 1, 10, mb, zap.NewNop())
+	defer w.Close()
+
+	assert.NoError(t, w.WriteSpan(context.Background(), &model.Span{}))
+	waitForCounter(t, mb, "spans.write-failed", 1)
+	counters, _ := mb.Snapshot()
+	assert.EqualValues(t, 3, counters["spans.write-retries"])
+	assert.Empty(t, writer.getSaved())
+}
+
+func TestRetryWriterQueueFull(t *testing.T) {
+	mb := metrics.NewLocalFactory(time.Hour)
+	writer := &flakyWriter{failures: 10}
+	// without workers the first failed span waits in the queue until the writer is closed
+	w := NewRetryWriter(writer, 3, time.Millisecond, 0, 1, mb, zap.NewNop())
+
+	assert.NoError(t, w.WriteSpan(context.Background(), &model.Span{}))
+	assert.Equal(t, errWriteFailed, w.WriteSpan(context.Background(), &model.Span{}))
+	counters, _ := mb.Snapshot()
+	assert.EqualValues(t, 1, counters["spans.write-failed"])
+
+	require.NoError(t, w.Close())
+	counters, _ = mb.Snapshot()
+	assert.EqualValues(t, 2, counters["spans.write-failed"], "queued spans are given up on when closing")
+}
+
+func TestRetryWriterCloseStopsRetrying(t *testing.T) {
+	mb := metrics.NewLocalFactory(time.Hour)
+	writer := &flakyWriter{failures: 10}
+	w := NewRetryWriter(writer, 3, time.Hour, 1, 10, mb, zap.NewNop())
+
+	assert.NoError(t, w.WriteSpan(context.Background(), &model.Span{}))
+	for i := 0; i < 1000 && w.queue.Size() > 0; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	require.NoError(t, w.Close())
+	counters, _ := mb.Snapshot()
+	assert.EqualValues(t, 1, counters["spans.write-failed"])
+	assert.EqualValues(t, 0, counters["spans.write-retries"])
+}
+
+// hangingWriter fails the first write and blocks every next one until its context is cancelled
+type hangingWriter struct {
+	sync.Mutex
+	writes int
+}
+
+func (w *hangingWriter) WriteSpan(ctx context.Context, span *model.Span) error {
+	w.Lock()
+	w.writes++
+	first := w.writes == 1
+	w.Unlock()
+	if first {
+		return errWriteFailed
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestRetryWriterCloseCancelsRetry(t *testing.T) {
+	mb := metrics.NewLocalFactory(time.Hour)
+	w := NewRetryWriter(&hangingWriter{}, 3, time.Millisecond, 1, 10, mb, zap.NewNop())
+
+	assert.NoError(t, w.WriteSpan(context.Background(), &model.Span{}))
+	waitForCounter(t, mb, "spans.write-retries", 1)
+	require.NoError(t, w.Close())
+	counters, _ := mb.Snapshot()
+	assert.EqualValues(t, 1, counters["spans.write-failed"])
+	assert.EqualValues(t, 1, counters["spans.write-retries"], "the cancelled retry is not retried again")
+}
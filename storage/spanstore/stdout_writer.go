@@ -0,0 +1,84 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/uber/jaeger/model"
+)
+
+// StdoutWriter is a span Writer that prints the spans as indented JSON instead of saving them, to debug
+// the collector locally without a span storage
+type StdoutWriter struct {
+	lock    sync.Mutex
+	encoder *json.Encoder
+}
+
+// debugSpan is the JSON printed by StdoutWriter, which only has the fields useful to tell the spans apart
+type debugSpan struct {
+	TraceID       string                 `json:"traceID"`
+	SpanID        string                 `json:"spanID"`
+	ParentSpanID  string                 `json:"parentSpanID,omitempty"`
+	Service       string                 `json:"service"`
+	OperationName string                 `json:"operationName"`
+	StartTime     time.Time              `json:"startTime"`
+	Duration      string                 `json:"duration"`
+	Tags          map[string]interface{} `json:"tags,omitempty"`
+	Logs          int                    `json:"logs,omitempty"`
+}
+
+// NewStdoutWriter creates a StdoutWriter that prints the spans to out, typically os.Stdout
+func NewStdoutWriter(out io.Writer) *StdoutWriter {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return &StdoutWriter{encoder: encoder}
+}
+
+// WriteSpan prints the span, it fails if out cannot be written
+func (w *StdoutWriter) WriteSpan(ctx context.Context, span *model.Span) error {
+	s := debugSpan{
+		TraceID:       span.TraceID.String(),
+		SpanID:        span.SpanID.String(),
+		OperationName: span.OperationName,
+		StartTime:     span.StartTime.UTC(),
+		Duration:      span.Duration.String(),
+		Logs:          len(span.Logs),
+	}
+	if span.ParentSpanID != 0 {
+		s.ParentSpanID = span.ParentSpanID.String()
+	}
+	if span.Process != nil {
+		s.Service = span.Process.ServiceName
+	}
+	if len(span.Tags) > 0 {
+		s.Tags = make(map[string]interface{}, len(span.Tags))
+		for i := range span.Tags {
+			tag := &span.Tags[i]
+			if tag.VType == model.BinaryType {
+				s.Tags[tag.Key] = tag.AsString()
+			} else {
+				s.Tags[tag.Key] = tag.Value()
+			}
+		}
+	}
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.encoder.Encode(s)
+}
@@ -0,0 +1,91 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/uber/jaeger/model"
+)
+
+func TestStdoutWriter(t *testing.T) {
+	out := &bytes.Buffer{}
+	w := NewStdoutWriter(out)
+	span := &model.Span{
+		TraceID:       model.TraceID{Low: 1},
+		SpanID:        model.SpanID(2),
+		ParentSpanID:  model.SpanID(1),
+		OperationName: "GET /users",
+		StartTime:     time.Unix(1500000000, 0),
+		Duration:      1500 * time.Millisecond,
+		Tags: model.KeyValues{
+			model.String("http.method", "GET"),
+			model.Int64("http.status_code", 200),
+			model.Bool("error", false),
+			model.Binary("payload", []byte{0xca, 0xfe}),
+		},
+		Logs:    []model.Log{{Timestamp: time.Unix(1500000000, 0)}},
+		Process: &model.Process{ServiceName: "frontend"},
+	}
+	assert.NoError(t, w.WriteSpan(context.Background(), span))
+	assert.NoError(t, w.WriteSpan(context.Background(), &model.Span{
+		TraceID: model.TraceID{Low: 3},
+		SpanID:  model.SpanID(3),
+		Process: &model.Process{ServiceName: "backend"},
+	}))
+	assert.Equal(t, `{
+  "traceID": "1",
+  "spanID": "2",
+  "parentSpanID": "1",
+  "service": "frontend",
+  "operationName": "GET /users",
+  "startTime": "2017-07-14T02:40:00Z",
+  "duration": "1.5s",
+  "tags": {
+    "error": false,
+    "http.method": "GET",
+    "http.status_code": 200,
+    "payload": "cafe"
+  },
+  "logs": 1
+}
+{
+  "traceID": "3",
+  "spanID": "3",
+  "service": "backend",
+  "operationName": "",
+  "startTime": "0001-01-01T00:00:00Z",
+  "duration": "0s"
+}
+`, out.String())
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("broken pipe")
+}
+
+func TestStdoutWriterError(t *testing.T) {
+	w := NewStdoutWriter(failingWriter{})
+	err := w.WriteSpan(context.Background(), &model.Span{Process: &model.Process{ServiceName: "service"}})
+	assert.EqualError(t, err, "broken pipe")
+}
@@ -0,0 +1,59 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+
+	"github.com/uber/jaeger/model"
+)
+
+// TagRoutingWriter is a span Writer that saves each span into the Writer selected by the value of a span tag
+type TagRoutingWriter struct {
+	tag           string
+	spanWriters   map[string]Writer
+	defaultWriter Writer
+}
+
+// NewTagRoutingWriter creates a TagRoutingWriter. Spans without the tag, or with a value that has no
+// Writer in spanWriters, are saved by defaultWriter.
+func NewTagRoutingWriter(tag string, spanWriters map[string]Writer, defaultWriter Writer) *TagRoutingWriter {
+	return &TagRoutingWriter{
+		tag:           tag,
+		spanWriters:   spanWriters,
+		defaultWriter: defaultWriter,
+	}
+}
+
+// WriteSpan calls WriteSpan on the span writer selected by the span's tag, which is looked up
+// in the span tags first and then in the process tags.
+func (w *TagRoutingWriter) WriteSpan(ctx context.Context, span *model.Span) error {
+	if writer, ok := w.spanWriters[w.tagValue(span)]; ok {
+		return writer.WriteSpan(ctx, span)
+	}
+	return w.defaultWriter.WriteSpan(ctx, span)
+}
+
+func (w *TagRoutingWriter) tagValue(span *model.Span) string {
+	if kv, ok := span.Tags.FindByKey(w.tag); ok {
+		return kv.AsString()
+	}
+	if span.Process != nil {
+		if kv, ok := span.Process.Tags.FindByKey(w.tag); ok {
+			return kv.AsString()
+		}
+	}
+	return ""
+}
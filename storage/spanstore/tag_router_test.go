@@ -0,0 +1,59 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/uber/jaeger/model"
+	. "github.com/uber/jaeger/storage/spanstore"
+)
+
+type recordingWriteSpanStore struct {
+	spans []*model.Span
+}
+
+func (r *recordingWriteSpanStore) WriteSpan(ctx context.Context, span *model.Span) error {
+	r.spans = append(r.spans, span)
+	return nil
+}
+
+func TestTagRoutingWriter(t *testing.T) {
+	acme := &recordingWriteSpanStore{}
+	initech := &recordingWriteSpanStore{}
+	primary := &recordingWriteSpanStore{}
+	w := NewTagRoutingWriter("tenant", map[string]Writer{"acme": acme, "initech": initech}, primary)
+
+	spanTag := &model.Span{Tags: model.KeyValues{model.String("tenant", "acme")}}
+	processTag := &model.Span{Process: &model.Process{Tags: model.KeyValues{model.String("tenant", "initech")}}}
+	unknownTenant := &model.Span{Tags: model.KeyValues{model.String("tenant", "hooli")}}
+	noTag := &model.Span{Process: &model.Process{ServiceName: "svc"}}
+	for _, span := range []*model.Span{spanTag, processTag, unknownTenant, noTag} {
+		assert.NoError(t, w.WriteSpan(context.Background(), span))
+	}
+
+	assert.Equal(t, []*model.Span{spanTag}, acme.spans)
+	assert.Equal(t, []*model.Span{processTag}, initech.spans)
+	assert.Equal(t, []*model.Span{unknownTenant, noTag}, primary.spans)
+}
+
+func TestTagRoutingWriterError(t *testing.T) {
+	w := NewTagRoutingWriter("tenant", map[string]Writer{"acme": &errProneWriteSpanStore{}}, &noopWriteSpanStore{})
+	assert.Equal(t, errIWillAlwaysFail, w.WriteSpan(context.Background(), &model.Span{Tags: model.KeyValues{model.String("tenant", "acme")}}))
+	assert.NoError(t, w.WriteSpan(context.Background(), &model.Span{}))
+}
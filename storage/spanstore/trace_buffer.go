@@ -0,0 +1,222 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/pkg/clock"
+)
+
+const (
+	// DefaultTraceBufferMaxSpans is the default number of spans held by a TraceBufferWriter
+	DefaultTraceBufferMaxSpans = 100000
+	// DefaultTraceBufferWorkers is the default number of goroutines writing the traces of a TraceBufferWriter
+	DefaultTraceBufferWorkers = 10
+)
+
+// TraceBufferWriter is a span Writer that holds the spans for a short window before writing them, grouped
+// by trace, so that the storage receives the spans of a trace that arrive within the window together.
+// The grouping is best-effort: spans arriving after their trace was written start a new group.
+type TraceBufferWriter struct {
+	writer   Writer
+	window   time.Duration
+	maxSpans int
+	workers  int
+	clock    clock.Clock
+	logger   *zap.Logger
+	stop     chan struct{}
+	done     chan struct{}
+	spans    metrics.Gauge
+	evicted  metrics.Counter
+	failures metrics.Counter
+
+	mux sync.Mutex
+	// traces are the buffered traces by ID, queue holds them in the order they were first seen
+	traces   map[model.TraceID]*bufferedTrace
+	queue    []*bufferedTrace
+	buffered int
+}
+
+type bufferedTrace struct {
+	firstSeen time.Time
+	spans     []*model.Span
+}
+
+// NewTraceBufferWriter creates a TraceBufferWriter that writes the spans of a trace once window has passed
+// on clock since its first span was buffered, checking every window, so a trace is held for up to twice window.
+// The traces are written concurrently by up to workers goroutines, the spans of a trace one after the other.
+// At most maxSpans spans are buffered, when it is full the oldest traces are written early to make room,
+// which is counted in the trace-buffer.evicted counter. The number of buffered spans is reported in the
+// trace-buffer.spans gauge and the buffered spans that failed to be written in trace-buffer.write-failed.
+func NewTraceBufferWriter(
+	writer Writer,
+	window time.Duration,
+	maxSpans int,
+	workers int,
+	clock clock.Clock,
+	metricsFactory metrics.Factory,
+	logger *zap.Logger,
+) *TraceBufferWriter {
+	w := &TraceBufferWriter{
+		writer:   writer,
+		window:   window,
+		maxSpans: maxSpans,
+		workers:  workers,
+		clock:    clock,
+		logger:   logger,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+		spans:    metricsFactory.Gauge("trace-buffer.spans", nil),
+		evicted:  metricsFactory.Counter("trace-buffer.evicted", nil),
+		failures: metricsFactory.Counter("trace-buffer.write-failed", nil),
+		traces:   make(map[model.TraceID]*bufferedTrace),
+	}
+	go w.flushEvery(window)
+	return w
+}
+
+func (w *TraceBufferWriter) flushEvery(interval time.Duration) {
+	defer close(w.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			w.write(w.popExpired(w.clock.Now()))
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+// WriteSpan buffers the span with the other spans of its trace and returns no error. If the buffer is
+// full the oldest traces are written before WriteSpan returns, which holds up the caller while the storage
+// is slower than the spans arrive. The buffered spans are not written with ctx, which usually ends before
+// they are.
+func (w *TraceBufferWriter) WriteSpan(ctx context.Context, span *model.Span) error {
+	w.mux.Lock()
+	trace, ok := w.traces[span.TraceID]
+	if !ok {
+		trace = &bufferedTrace{firstSeen: w.clock.Now()}
+		w.traces[span.TraceID] = trace
+		w.queue = append(w.queue, trace)
+	}
+	trace.spans = append(trace.spans, span)
+	w.buffered++
+	var evicted []*bufferedTrace
+	for w.buffered > w.maxSpans {
+		evicted = append(evicted, w.pop())
+	}
+	w.spans.Update(int64(w.buffered))
+	w.mux.Unlock()
+	if len(evicted) > 0 {
+		w.evicted.Inc(int64(len(evicted)))
+		w.write(evicted)
+	}
+	return nil
+}
+
+// popExpired removes the traces first seen at least window before now from the buffer
+func (w *TraceBufferWriter) popExpired(now time.Time) []*bufferedTrace {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	var expired []*bufferedTrace
+	for len(w.queue) > 0 && now.Sub(w.queue[0].firstSeen) >= w.window {
+		expired = append(expired, w.pop())
+	}
+	w.spans.Update(int64(w.buffered))
+	return expired
+}
+
+// pop removes the oldest trace from the buffer, the lock must be held
+func (w *TraceBufferWriter) pop() *bufferedTrace {
+	trace := w.queue[0]
+	w.queue[0] = nil
+	w.queue = w.queue[1:]
+	delete(w.traces, trace.spans[0].TraceID)
+	w.buffered -= len(trace.spans)
+	return trace
+}
+
+// write writes the traces with up to workers goroutines and returns once they are all written
+func (w *TraceBufferWriter) write(traces []*bufferedTrace) {
+	if len(traces) == 1 || w.workers <= 1 {
+		for _, trace := range traces {
+			w.writeTrace(trace)
+		}
+		return
+	}
+	pending := make(chan *bufferedTrace)
+	var wg sync.WaitGroup
+	for i := 0; i < w.workers && i < len(traces); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for trace := range pending {
+				w.writeTrace(trace)
+			}
+		}()
+	}
+	for _, trace := range traces {
+		pending <- trace
+	}
+	close(pending)
+	wg.Wait()
+}
+
+func (w *TraceBufferWriter) writeTrace(trace *bufferedTrace) {
+	for _, span := range trace.spans {
+		if err := w.writer.WriteSpan(context.Background(), span); err != nil {
+			w.failures.Inc(1)
+			w.logger.Error("Failed to save buffered span",
+				zap.String("trace-id", span.TraceID.String()),
+				zap.String("span-id", span.SpanID.String()),
+				zap.Error(err))
+		}
+	}
+}
+
+// Flush writes all the buffered spans, grouped by trace, and returns how many there were
+func (w *TraceBufferWriter) Flush() int {
+	w.mux.Lock()
+	traces := w.queue
+	flushed := w.buffered
+	w.queue = nil
+	w.traces = make(map[model.TraceID]*bufferedTrace)
+	w.buffered = 0
+	w.spans.Update(0)
+	w.mux.Unlock()
+	w.write(traces)
+	return flushed
+}
+
+// Close writes all the buffered spans and closes the underlying writer if it supports it
+func (w *TraceBufferWriter) Close() error {
+	close(w.stop)
+	<-w.done
+	w.Flush()
+	if closer, ok := w.writer.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/uber/jaeger/model"
+	"github.com/uber/jaeger/pkg/clock"
+)
+
+func traceSpan(traceID, spanID uint64) *model.Span {
+	return &model.Span{TraceID: model.TraceID{Low: traceID}, SpanID: model.SpanID(spanID)}
+}
+
+func spanIDs(spans []*model.Span) [][2]uint64 {
+	ids := make([][2]uint64, len(spans))
+	for i, span := range spans {
+		ids[i] = [2]uint64{span.TraceID.Low, uint64(span.SpanID)}
+	}
+	return ids
+}
+
+func TestTraceBufferWriterFlushesTracesTogether(t *testing.T) {
+	mb := metrics.NewLocalFactory(time.Hour)
+	backend := &toggledBackend{}
+	w := NewTraceBufferWriter(backend, 20*time.Millisecond, DefaultTraceBufferMaxSpans, 1, clock.System, mb, zap.NewNop())
+	defer w.Close()
+
+	// the spans of two traces arrive interleaved
+	for _, span := range []*model.Span{traceSpan(1, 1), traceSpan(2, 1), traceSpan(1, 2), traceSpan(2, 2), traceSpan(1, 3)} {
+		require.NoError(t, w.WriteSpan(context.Background(), span))
+	}
+	assert.Empty(t, backend.getSaved(), "the spans are held for the window")
+	_, gauges := mb.Snapshot()
+	assert.EqualValues(t, 5, gauges["trace-buffer.spans"])
+
+	for i := 0; i < 1000 && len(backend.getSaved()) < 5; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, [][2]uint64{{1, 1}, {1, 2}, {1, 3}, {2, 1}, {2, 2}}, spanIDs(backend.getSaved()))
+	_, gauges = mb.Snapshot()
+	assert.EqualValues(t, 0, gauges["trace-buffer.spans"])
+}
+
+func TestTraceBufferWriterExpiry(t *testing.T) {
+	backend := &toggledBackend{}
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	// the traces are only flushed when the test pops them
+	w := NewTraceBufferWriter(backend, time.Hour, DefaultTraceBufferMaxSpans, 1, clock, metrics.NullFactory, zap.NewNop())
+
+	require.NoError(t, w.WriteSpan(context.Background(), traceSpan(1, 1)))
+	clock.now = clock.now.Add(time.Minute)
+	assert.Empty(t, w.popExpired(clock.Now()), "the trace is held until the window has passed")
+
+	clock.now = clock.now.Add(time.Hour)
+	expired := w.popExpired(clock.Now())
+	require.Len(t, expired, 1)
+	assert.Equal(t, [][2]uint64{{1, 1}}, spanIDs(expired[0].spans))
+
+	// a span of the trace arriving after it was flushed starts a new group
+	require.NoError(t, w.WriteSpan(context.Background(), traceSpan(1, 2)))
+	require.NoError(t, w.Close())
+	assert.Equal(t, [][2]uint64{{1, 2}}, spanIDs(backend.getSaved()))
+}
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func TestTraceBufferWriterWorkers(t *testing.T) {
+	writer := &slowWriter{delay: 20 * time.Millisecond}
+	w := NewTraceBufferWriter(writer, time.Hour, DefaultTraceBufferMaxSpans, 4, clock.System, metrics.NullFactory, zap.NewNop())
+	defer w.Close()
+
+	for _, span := range []*model.Span{traceSpan(1, 1), traceSpan(2, 1), traceSpan(3, 1), traceSpan(4, 1), traceSpan(1, 2)} {
+		require.NoError(t, w.WriteSpan(context.Background(), span))
+	}
+	assert.Equal(t, 5, w.Flush())
+	assert.EqualValues(t, 5, atomic.LoadInt64(&writer.writes), "Flush returns once all the traces are written")
+	assert.True(t, atomic.LoadInt64(&writer.max) > 1, "the traces are written concurrently")
+	assert.True(t, atomic.LoadInt64(&writer.max) <= 4, "at most workers traces are written at a time")
+}
+
+func TestTraceBufferWriterMaxSpans(t *testing.T) {
+	mb := metrics.NewLocalFactory(time.Hour)
+	backend := &toggledBackend{}
+	w := NewTraceBufferWriter(backend, time.Hour, 3, 1, clock.System, mb, zap.NewNop())
+
+	for _, span := range []*model.Span{traceSpan(1, 1), traceSpan(2, 1), traceSpan(1, 2)} {
+		require.NoError(t, w.WriteSpan(context.Background(), span))
+	}
+	assert.Empty(t, backend.getSaved())
+
+	// the oldest trace is written to make room
+	require.NoError(t, w.WriteSpan(context.Background(), traceSpan(3, 1)))
+	assert.Equal(t, [][2]uint64{{1, 1}, {1, 2}}, spanIDs(backend.getSaved()))
+	counters, gauges := mb.Snapshot()
+	assert.EqualValues(t, 1, counters["trace-buffer.evicted"])
+	assert.EqualValues(t, 2, gauges["trace-buffer.spans"])
+
+	require.NoError(t, w.Close())
+	assert.Equal(t, [][2]uint64{{1, 1}, {1, 2}, {2, 1}, {3, 1}}, spanIDs(backend.getSaved()), "Close writes the buffered spans")
+	_, gauges = mb.Snapshot()
+	assert.EqualValues(t, 0, gauges["trace-buffer.spans"])
+}
+
+func TestTraceBufferWriterFlush(t *testing.T) {
+	mb := metrics.NewLocalFactory(time.Hour)
+	backend := &toggledBackend{}
+	w := NewTraceBufferWriter(backend, time.Hour, DefaultTraceBufferMaxSpans, 1, clock.System, mb, zap.NewNop())
+	defer w.Close()
+
+	assert.Equal(t, 0, w.Flush())
+	for _, span := range []*model.Span{traceSpan(1, 1), traceSpan(2, 1), traceSpan(1, 2)} {
+		require.NoError(t, w.WriteSpan(context.Background(), span))
+	}
+	assert.Equal(t, 3, w.Flush())
+	assert.Equal(t, [][2]uint64{{1, 1}, {1, 2}, {2, 1}}, spanIDs(backend.getSaved()))
+	_, gauges := mb.Snapshot()
+	assert.EqualValues(t, 0, gauges["trace-buffer.spans"])
+
+	// the spans buffered after a flush are grouped again
+	require.NoError(t, w.WriteSpan(context.Background(), traceSpan(1, 3)))
+	assert.Equal(t, 1, w.Flush())
+	assert.Len(t, backend.getSaved(), 4)
+}
+
+func TestTraceBufferWriterWriteFailed(t *testing.T) {
+	mb := metrics.NewLocalFactory(time.Hour)
+	backend := &toggledBackend{down: true}
+	w := NewTraceBufferWriter(backend, time.Hour, DefaultTraceBufferMaxSpans, 1, clock.System, mb, zap.NewNop())
+
+	require.NoError(t, w.WriteSpan(context.Background(), traceSpan(1, 1)))
+	require.NoError(t, w.WriteSpan(context.Background(), traceSpan(1, 2)))
+	require.NoError(t, w.Close())
+
+	counters, _ := mb.Snapshot()
+	assert.EqualValues(t, 2, counters["trace-buffer.write-failed"])
+}
+
+func TestTraceBufferWriterClosesWriter(t *testing.T) {
+	writer := &slowWriter{}
+	w := NewTraceBufferWriter(writer, time.Hour, DefaultTraceBufferMaxSpans, 1, clock.System, metrics.NullFactory, zap.NewNop())
+	require.NoError(t, w.Close())
+	assert.True(t, writer.closed)
+}